@@ -0,0 +1,226 @@
+package mdocx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// typeDescription is the human-readable name of the file type.
+const typeDescription = "MDOCX document"
+
+// FileAssociation describes an application that opens MDOCX containers, for
+// generating the snippets that register FileExtension and MediaType with the
+// desktop: a freedesktop.org desktop entry and shared MIME-info package, an
+// Info.plist fragment for macOS, and a Windows registry script.
+type FileAssociation struct {
+	// AppName is the display name of the application. Required.
+	AppName string
+	// AppID is the reverse-DNS identifier of the application, such as
+	// "com.example.Viewer". It is used as the Windows ProgID prefix. Required.
+	AppID string
+	// Command is the path of the executable that opens a file given as its
+	// only argument. Required for DesktopEntry and RegistryScript.
+	Command string
+	// Icon is the icon shown for MDOCX files: an icon name for DesktopEntry
+	// and an .ico path for RegistryScript. Optional.
+	Icon string
+	// Editor reports whether the application edits containers rather than
+	// only viewing them.
+	Editor bool
+}
+
+// check reports an ErrValidation if a required field is empty.
+func (a FileAssociation) check(needCommand bool) error {
+	switch {
+	case a.AppName == "":
+		return fmt.Errorf("%w: FileAssociation.AppName is empty", ErrValidation)
+	case a.AppID == "":
+		return fmt.Errorf("%w: FileAssociation.AppID is empty", ErrValidation)
+	case needCommand && a.Command == "":
+		return fmt.Errorf("%w: FileAssociation.Command is empty", ErrValidation)
+	}
+	return nil
+}
+
+// progID returns the Windows ProgID of the file type for a.
+func (a FileAssociation) progID() string {
+	return a.AppID + ".mdocx"
+}
+
+// SharedMIMEInfo returns a freedesktop.org shared MIME-info package defining
+// MediaType, with MediaTypeFallback as an alias, the FileExtension glob, and
+// the Magic signature. Install it with xdg-mime install. It does not depend on
+// the application.
+func SharedMIMEInfo() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString("<mime-info xmlns=\"http://www.freedesktop.org/standards/shared-mime-info\">\n")
+	fmt.Fprintf(&b, "  <mime-type type=\"%s\">\n", MediaType)
+	fmt.Fprintf(&b, "    <comment>%s</comment>\n", typeDescription)
+	fmt.Fprintf(&b, "    <alias type=\"%s\"/>\n", MediaTypeFallback)
+	fmt.Fprintf(&b, "    <glob pattern=\"*%s\"/>\n", FileExtension)
+	b.WriteString("    <magic priority=\"50\">\n")
+	fmt.Fprintf(&b, "      <match type=\"string\" offset=\"0\" value=\"%s\"/>\n", cEscape(Magic[:]))
+	b.WriteString("    </magic>\n")
+	b.WriteString("  </mime-type>\n")
+	b.WriteString("</mime-info>\n")
+	return b.String()
+}
+
+// DesktopEntry returns a freedesktop.org desktop entry that lists a as a
+// handler for MediaType and MediaTypeFallback. Install it as
+// AppID + ".desktop" in an applications directory.
+func (a FileAssociation) DesktopEntry() (string, error) {
+	if err := a.check(true); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	fmt.Fprintf(&b, "Name=%s\n", desktopEscape(a.AppName))
+	fmt.Fprintf(&b, "Exec=%s %%f\n", execQuote(a.Command))
+	if a.Icon != "" {
+		fmt.Fprintf(&b, "Icon=%s\n", desktopEscape(a.Icon))
+	}
+	fmt.Fprintf(&b, "MimeType=%s;%s;\n", MediaType, MediaTypeFallback)
+	b.WriteString("Terminal=false\n")
+	return b.String(), nil
+}
+
+// InfoPlist returns the CFBundleDocumentTypes and UTImportedTypeDeclarations
+// keys that declare TypeIdentifier and claim it for a, to be placed in the
+// top-level dictionary of the application's Info.plist.
+func (a FileAssociation) InfoPlist() (string, error) {
+	if err := a.check(false); err != nil {
+		return "", err
+	}
+	role := "Viewer"
+	if a.Editor {
+		role = "Editor"
+	}
+	var b strings.Builder
+	b.WriteString("<key>CFBundleDocumentTypes</key>\n")
+	b.WriteString("<array>\n")
+	b.WriteString("\t<dict>\n")
+	plistString(&b, 2, "CFBundleTypeName", typeDescription)
+	plistString(&b, 2, "CFBundleTypeRole", role)
+	plistString(&b, 2, "LSHandlerRank", "Owner")
+	plistArray(&b, 2, "LSItemContentTypes", TypeIdentifier)
+	b.WriteString("\t</dict>\n")
+	b.WriteString("</array>\n")
+	b.WriteString("<key>UTImportedTypeDeclarations</key>\n")
+	b.WriteString("<array>\n")
+	b.WriteString("\t<dict>\n")
+	plistString(&b, 2, "UTTypeIdentifier", TypeIdentifier)
+	plistString(&b, 2, "UTTypeDescription", typeDescription)
+	plistArray(&b, 2, "UTTypeConformsTo", "public.data", "public.composite-content")
+	b.WriteString("\t\t<key>UTTypeTagSpecification</key>\n")
+	b.WriteString("\t\t<dict>\n")
+	plistArray(&b, 3, "public.filename-extension", strings.TrimPrefix(FileExtension, "."))
+	plistArray(&b, 3, "public.mime-type", MediaType, MediaTypeFallback)
+	b.WriteString("\t\t</dict>\n")
+	b.WriteString("\t</dict>\n")
+	b.WriteString("</array>\n")
+	return b.String(), nil
+}
+
+// RegistryScript returns a Windows registry script (.reg) that registers
+// FileExtension and MediaType for the current user and opens them with
+// a.Command. Its lines end in CRLF, as regedit expects.
+func (a FileAssociation) RegistryScript() (string, error) {
+	if err := a.check(true); err != nil {
+		return "", err
+	}
+	const classes = `HKEY_CURRENT_USER\Software\Classes\`
+	prog := a.progID()
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		fmt.Fprintf(&b, format, args...)
+		b.WriteString("\r\n")
+	}
+	line("Windows Registry Editor Version 5.00")
+	line("")
+	line("[%s%s]", classes, FileExtension)
+	line("@=%s", regQuote(prog))
+	line(`"Content Type"=%s`, regQuote(MediaType))
+	line(`"PerceivedType"="document"`)
+	line("")
+	line("[%s%s]", classes, prog)
+	line("@=%s", regQuote(typeDescription))
+	if a.Icon != "" {
+		line("")
+		line(`[%s%s\DefaultIcon]`, classes, prog)
+		line("@=%s", regQuote(a.Icon))
+	}
+	line("")
+	line(`[%s%s\shell\open\command]`, classes, prog)
+	line("@=%s", regQuote(`"`+a.Command+`" "%1"`))
+	line("")
+	line(`[%sMIME\Database\Content Type\%s]`, classes, MediaType)
+	line(`"Extension"=%s`, regQuote(FileExtension))
+	return b.String(), nil
+}
+
+// cEscape returns b with bytes outside printable ASCII written as C escapes.
+func cEscape(b []byte) string {
+	var s strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '\r':
+			s.WriteString(`\r`)
+		case c == '\n':
+			s.WriteString(`\n`)
+		case c == '\\':
+			s.WriteString(`\\`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&s, `\x%02x`, c)
+		default:
+			s.WriteByte(c)
+		}
+	}
+	return s.String()
+}
+
+// desktopEscape escapes a desktop entry string value.
+func desktopEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s)
+}
+
+// execQuote quotes a program path for the Exec key of a desktop entry if it
+// contains characters that are reserved there.
+func execQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\n\"'\\><~|&;$*?#()`%") {
+		return s
+	}
+	q := strings.NewReplacer(`"`, `\"`, "`", "\\`", `$`, `\$`, `\`, `\\`, `%`, `%%`).Replace(s)
+	return desktopEscape(`"` + q + `"`)
+}
+
+// regQuote returns s as a quoted registry script string.
+func regQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// plistString writes a key and string value indented by depth tabs.
+func plistString(b *strings.Builder, depth int, key, value string) {
+	ind := strings.Repeat("\t", depth)
+	fmt.Fprintf(b, "%s<key>%s</key>\n%s<string>%s</string>\n", ind, xmlEscape(key), ind, xmlEscape(value))
+}
+
+// plistArray writes a key and array of strings indented by depth tabs.
+func plistArray(b *strings.Builder, depth int, key string, values ...string) {
+	ind := strings.Repeat("\t", depth)
+	fmt.Fprintf(b, "%s<key>%s</key>\n%s<array>\n", ind, xmlEscape(key), ind)
+	for _, v := range values {
+		fmt.Fprintf(b, "%s\t<string>%s</string>\n", ind, xmlEscape(v))
+	}
+	fmt.Fprintf(b, "%s</array>\n", ind)
+}
+
+// xmlEscape returns s with XML special characters escaped.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
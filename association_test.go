@@ -0,0 +1,109 @@
+package mdocx
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSharedMIMEInfo(t *testing.T) {
+	s := SharedMIMEInfo()
+	var info struct {
+		MIMEType struct {
+			Type  string `xml:"type,attr"`
+			Alias struct {
+				Type string `xml:"type,attr"`
+			} `xml:"alias"`
+			Glob struct {
+				Pattern string `xml:"pattern,attr"`
+			} `xml:"glob"`
+			Match struct {
+				Value string `xml:"value,attr"`
+			} `xml:"magic>match"`
+		} `xml:"mime-type"`
+	}
+	if err := xml.Unmarshal([]byte(s), &info); err != nil {
+		t.Fatal(err)
+	}
+	mt := info.MIMEType
+	if mt.Type != MediaType || mt.Alias.Type != MediaTypeFallback || mt.Glob.Pattern != "*.mdocx" {
+		t.Fatalf("got %+v", mt)
+	}
+	if mt.Match.Value != `MDOCX\r\n\x1a` {
+		t.Fatalf("magic = %q", mt.Match.Value)
+	}
+}
+
+func TestFileAssociation(t *testing.T) {
+	a := FileAssociation{AppName: "Doc Viewer", AppID: "com.example.Viewer", Command: `/opt/doc viewer/bin/view`, Icon: "doc-viewer"}
+
+	entry, err := a.DesktopEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[Desktop Entry]\n",
+		"Name=Doc Viewer\n",
+		`Exec="/opt/doc viewer/bin/view" %f` + "\n",
+		"Icon=doc-viewer\n",
+		"MimeType=application/vnd.mdocx;application/x-mdocx;\n",
+	} {
+		if !strings.Contains(entry, want) {
+			t.Errorf("desktop entry lacks %q:\n%s", want, entry)
+		}
+	}
+	if got := execQuote(`C:\a$b`); got != `"C:\\\\a\\$b"` {
+		t.Errorf("execQuote = %s", got)
+	}
+
+	plist, err := a.InfoPlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys struct {
+		Items []string `xml:",any"`
+	}
+	if err := xml.Unmarshal([]byte("<dict>"+plist+"</dict>"), &keys); err != nil {
+		t.Fatalf("plist fragment is not well-formed: %v\n%s", err, plist)
+	}
+	for _, want := range []string{"<string>" + TypeIdentifier + "</string>", "<string>Viewer</string>", "<string>mdocx</string>", "<string>application/vnd.mdocx</string>"} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist lacks %q", want)
+		}
+	}
+	a.Editor = true
+	if plist, _ := a.InfoPlist(); !strings.Contains(plist, "<string>Editor</string>") {
+		t.Error("editor role missing")
+	}
+
+	a.Command = `C:\Program Files\Viewer\view.exe`
+	a.Icon = `C:\Program Files\Viewer\doc.ico`
+	reg, err := a.RegistryScript()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Windows Registry Editor Version 5.00\r\n",
+		"[HKEY_CURRENT_USER\\Software\\Classes\\.mdocx]\r\n@=\"com.example.Viewer.mdocx\"\r\n\"Content Type\"=\"application/vnd.mdocx\"\r\n",
+		"[HKEY_CURRENT_USER\\Software\\Classes\\com.example.Viewer.mdocx\\DefaultIcon]\r\n@=\"C:\\\\Program Files\\\\Viewer\\\\doc.ico\"\r\n",
+		"@=\"\\\"C:\\\\Program Files\\\\Viewer\\\\view.exe\\\" \\\"%1\\\"\"\r\n",
+		"[HKEY_CURRENT_USER\\Software\\Classes\\MIME\\Database\\Content Type\\application/vnd.mdocx]\r\n\"Extension\"=\".mdocx\"\r\n",
+	} {
+		if !strings.Contains(reg, want) {
+			t.Errorf("registry script lacks %q:\n%s", want, reg)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(reg, "\r\n", ""), "\n") {
+		t.Error("registry script has bare LF line endings")
+	}
+
+	for _, bad := range []FileAssociation{{AppID: "x", Command: "y"}, {AppName: "x", Command: "y"}, {AppName: "x", AppID: "y"}} {
+		if _, err := bad.RegistryScript(); !errors.Is(err, ErrValidation) {
+			t.Errorf("%+v: got %v", bad, err)
+		}
+	}
+	if _, err := (FileAssociation{AppName: "x", AppID: "y"}).InfoPlist(); err != nil {
+		t.Errorf("InfoPlist must not need Command: %v", err)
+	}
+}
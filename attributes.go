@@ -0,0 +1,217 @@
+package mdocx
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Attributes provides typed access to the string attribute maps of
+// MarkdownFile and MediaItem. Values are stored in canonical string forms:
+//   - bool: "true" or "false" (parsing also accepts 1, 0, t, f, and case variants)
+//   - int: base-10 integer
+//   - time: RFC 3339 (parsing also accepts a plain YYYY-MM-DD date)
+//   - string list: JSON array of strings
+//
+// Getters return an error wrapping ErrNotFound if the key is absent and
+// ErrValidation if the value cannot be parsed. Setters allocate the map if it
+// is nil, so convert and assign back:
+//
+//	attrs := mdocx.Attributes(f.Attributes)
+//	attrs.SetBool("draft", true)
+//	f.Attributes = attrs
+type Attributes map[string]string
+
+// String returns the raw value of key.
+func (a Attributes) String(key string) (string, error) {
+	v, ok := a[key]
+	if !ok {
+		return "", fmt.Errorf("%w: attribute %q", ErrNotFound, key)
+	}
+	return v, nil
+}
+
+// Bool returns the value of key parsed as a boolean.
+func (a Attributes) Bool(key string) (bool, error) {
+	v, err := a.String(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, attrParseError(key, AttrBool, v)
+	}
+	return b, nil
+}
+
+// Int returns the value of key parsed as a base-10 integer.
+func (a Attributes) Int(key string) (int64, error) {
+	v, err := a.String(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, attrParseError(key, AttrInt, v)
+	}
+	return n, nil
+}
+
+// Time returns the value of key parsed as an RFC 3339 timestamp or a
+// YYYY-MM-DD date (midnight UTC).
+func (a Attributes) Time(key string) (time.Time, error) {
+	v, err := a.String(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, ok := parseAttrTime(v)
+	if !ok {
+		return time.Time{}, attrParseError(key, AttrTime, v)
+	}
+	return t, nil
+}
+
+// Strings returns the value of key parsed as a JSON array of strings.
+func (a Attributes) Strings(key string) ([]string, error) {
+	v, err := a.String(key)
+	if err != nil {
+		return nil, err
+	}
+	var list []string
+	if err := json.Unmarshal([]byte(v), &list); err != nil {
+		return nil, attrParseError(key, AttrStrings, v)
+	}
+	return list, nil
+}
+
+// SetString sets key to v.
+func (a *Attributes) SetString(key, v string) {
+	if *a == nil {
+		*a = make(Attributes)
+	}
+	(*a)[key] = v
+}
+
+// SetBool sets key to "true" or "false".
+func (a *Attributes) SetBool(key string, v bool) { a.SetString(key, strconv.FormatBool(v)) }
+
+// SetInt sets key to the base-10 form of v.
+func (a *Attributes) SetInt(key string, v int64) { a.SetString(key, strconv.FormatInt(v, 10)) }
+
+// SetTime sets key to the RFC 3339 form of v, with sub-second precision only
+// when v has it.
+func (a *Attributes) SetTime(key string, v time.Time) { a.SetString(key, v.Format(time.RFC3339Nano)) }
+
+// SetStrings sets key to a JSON array holding v.
+func (a *Attributes) SetStrings(key string, v []string) {
+	if v == nil {
+		v = []string{}
+	}
+	b, _ := json.Marshal(v) // marshaling a []string cannot fail
+	a.SetString(key, string(b))
+}
+
+// AttrType is the value type of an attribute in an AttrSchema.
+type AttrType int
+
+// Attribute value types.
+const (
+	AttrString AttrType = iota
+	AttrBool
+	AttrInt
+	AttrTime
+	AttrStrings
+)
+
+// String returns the name of the type.
+func (t AttrType) String() string {
+	switch t {
+	case AttrString:
+		return "string"
+	case AttrBool:
+		return "bool"
+	case AttrInt:
+		return "int"
+	case AttrTime:
+		return "time"
+	case AttrStrings:
+		return "string list"
+	}
+	return "AttrType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// AttrSchema declares the expected value types of attribute keys. Keys that are
+// not registered are not checked. Pass a schema to WithAttrSchema to have
+// Encode and EncodeStream reject files and items whose attributes do not match.
+type AttrSchema map[string]AttrType
+
+// Register declares key as having type t and returns s for chaining.
+// It allocates s if it is nil.
+func (s *AttrSchema) Register(key string, t AttrType) *AttrSchema {
+	if *s == nil {
+		*s = make(AttrSchema)
+	}
+	(*s)[key] = t
+	return s
+}
+
+// Validate checks every registered key present in attrs. It returns an error
+// wrapping ErrValidation for the first mismatch, in key order.
+func (s AttrSchema) Validate(attrs map[string]string) error {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		if _, ok := s[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	a := Attributes(attrs)
+	for _, k := range keys {
+		var err error
+		switch s[k] {
+		case AttrBool:
+			_, err = a.Bool(k)
+		case AttrInt:
+			_, err = a.Int(k)
+		case AttrTime:
+			_, err = a.Time(k)
+		case AttrStrings:
+			_, err = a.Strings(k)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateDocument applies s to the attributes of every file and item in doc.
+func (s AttrSchema) validateDocument(doc *Document) error {
+	for _, f := range doc.Markdown.Files {
+		if err := s.Validate(f.Attributes); err != nil {
+			return fmt.Errorf("markdown file %q: %w", f.Path, err)
+		}
+	}
+	for _, it := range doc.Media.Items {
+		if err := s.Validate(it.Attributes); err != nil {
+			return fmt.Errorf("media item %q: %w", it.ID, err)
+		}
+	}
+	return nil
+}
+
+func attrParseError(key string, t AttrType, v string) error {
+	return fmt.Errorf("%w: attribute %q is not a valid %s: %q", ErrValidation, key, t, v)
+}
+
+// parseAttrTime parses an RFC 3339 timestamp or a YYYY-MM-DD date.
+func parseAttrTime(v string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,64 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAttributesRoundTrip(t *testing.T) {
+	var a Attributes
+	a.SetBool("draft", true)
+	a.SetInt("order", -42)
+	when := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	a.SetTime("updated", when)
+	a.SetStrings("tags", []string{"a", "b,c"})
+
+	if v, err := a.Bool("draft"); err != nil || !v {
+		t.Fatalf("Bool: %v %v", v, err)
+	}
+	if v, err := a.Int("order"); err != nil || v != -42 {
+		t.Fatalf("Int: %v %v", v, err)
+	}
+	if v, err := a.Time("updated"); err != nil || !v.Equal(when) || a["updated"] != "2024-02-03T04:05:06Z" {
+		t.Fatalf("Time: %v %v (%q)", v, err, a["updated"])
+	}
+	if v, err := a.Strings("tags"); err != nil || !reflect.DeepEqual(v, []string{"a", "b,c"}) {
+		t.Fatalf("Strings: %v %v", v, err)
+	}
+	a.SetString("date", "2024-01-10")
+	if v, err := a.Time("date"); err != nil || v.Day() != 10 {
+		t.Fatalf("date-only Time: %v %v", v, err)
+	}
+
+	if _, err := a.Bool("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	a.SetString("bad", "yes please")
+	if _, err := a.Bool("bad"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+}
+
+func TestAttrSchema(t *testing.T) {
+	var s AttrSchema
+	s.Register("draft", AttrBool).Register("tags", AttrStrings)
+	if err := s.Validate(map[string]string{"draft": "false", "tags": `["x"]`, "other": "anything"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(map[string]string{"tags": "x, y"}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+
+	doc := sampleDoc()
+	doc.Media.Items[0].Attributes = map[string]string{"draft": "maybe"}
+	err := Encode(&bytes.Buffer{}, doc, WithAttrSchema(s))
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation from Encode, got %v", err)
+	}
+	if err := Encode(&bytes.Buffer{}, sampleDoc(), WithAttrSchema(s)); err != nil {
+		t.Fatal(err)
+	}
+}
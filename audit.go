@@ -0,0 +1,94 @@
+package mdocx
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AuditMetadataKey is the metadata key under which the audit log is stored.
+// Scrub and the rehash command of the mdocx tool append to a log that exists;
+// EditSession cannot, since it does not rewrite the metadata.
+const AuditMetadataKey = "audit"
+
+// AuditEvent records one modification of a container.
+type AuditEvent struct {
+	// Tool identifies the program or user agent that made the change.
+	Tool string `json:"tool"`
+	// Time is when the change was recorded.
+	Time time.Time `json:"time"`
+	// Operation is a short human-readable summary of the change.
+	Operation string `json:"operation"`
+	// Before is the Fingerprint of the document before the change, or ""
+	// for a newly created document.
+	Before string `json:"before,omitempty"`
+	// After is the Fingerprint of the document after the change.
+	After string `json:"after"`
+}
+
+// AuditLog returns the events recorded in d's metadata, oldest first.
+// It returns an error wrapping ErrValidation if the log is malformed.
+func (d *Document) AuditLog() ([]AuditEvent, error) {
+	raw, ok := d.Metadata[AuditMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	if events, ok := raw.([]AuditEvent); ok {
+		return append([]AuditEvent(nil), events...), nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: audit log: %v", ErrValidation, err)
+	}
+	var events []AuditEvent
+	if err := json.Unmarshal(b, &events); err != nil {
+		return nil, fmt.Errorf("%w: audit log: %v", ErrValidation, err)
+	}
+	return events, nil
+}
+
+// AppendAuditEvent appends e to d's audit log. Record the fingerprint before
+// modifying d and pass it as e.Before; After is filled with the current
+// fingerprint and Time with the current time when they are empty.
+//
+//	before := doc.Fingerprint()
+//	// ... modify doc ...
+//	err := doc.AppendAuditEvent(mdocx.AuditEvent{Tool: "mytool/1.2", Operation: "replace logo", Before: before})
+func (d *Document) AppendAuditEvent(e AuditEvent) error {
+	events, err := d.AuditLog()
+	if err != nil {
+		return err
+	}
+	if e.After == "" {
+		e.After = d.Fingerprint()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if d.Metadata == nil {
+		d.Metadata = make(map[string]any)
+	}
+	d.Metadata[AuditMetadataKey] = append(events, e)
+	return nil
+}
+
+// VerifyAuditLog checks that the audit log forms an unbroken chain: each
+// event's Before matches the previous event's After, and the last event's
+// After matches the current content. A mismatch means the container was
+// modified without recording an event. An empty log verifies trivially.
+// Failures wrap ErrValidation.
+func (d *Document) VerifyAuditLog() error {
+	events, err := d.AuditLog()
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(events); i++ {
+		if events[i].Before != events[i-1].After {
+			return fmt.Errorf("%w: audit event %d (%s) does not follow event %d", ErrValidation, i, events[i].Operation, i-1)
+		}
+	}
+	if n := len(events); n > 0 && events[n-1].After != d.Fingerprint() {
+		return fmt.Errorf("%w: content changed after the last audit event", ErrValidation)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	doc := sampleDoc()
+	if err := doc.AppendAuditEvent(AuditEvent{Tool: "test", Operation: "create"}); err != nil {
+		t.Fatal(err)
+	}
+	before := doc.Fingerprint()
+	doc.Markdown.Files[0].Content = append(doc.Markdown.Files[0].Content, "\nMore.\n"...)
+	if err := doc.VerifyAuditLog(); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected unrecorded change to be detected, got %v", err)
+	}
+	if err := doc.AppendAuditEvent(AuditEvent{Tool: "test", Operation: "append text", Before: before}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.VerifyAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := dec.AuditLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Operation != "append text" || events[1].Before != before || events[0].Time.IsZero() {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if err := dec.VerifyAuditLog(); err != nil {
+		t.Fatalf("decoded log does not verify: %v", err)
+	}
+
+	dec.Metadata[AuditMetadataKey] = "not a list"
+	if _, err := dec.AuditLog(); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+}
+
+func TestContentDigestOrderIndependent(t *testing.T) {
+	a := sampleDoc()
+	b := sampleDoc()
+	b.Markdown.Files[0], b.Markdown.Files[1] = b.Markdown.Files[1], b.Markdown.Files[0]
+	b.Media.Items[0].SHA256 = b.Media.Items[0].computedSHA256()
+	if contentDigest(a) != contentDigest(b) {
+		t.Fatal("digest depends on file order or stored hashes")
+	}
+	b.Media.Items[0].Attributes = map[string]string{"alt": "x"}
+	if contentDigest(a) == contentDigest(b) {
+		t.Fatal("digest ignores attributes")
+	}
+}
@@ -0,0 +1,240 @@
+package mdocx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bagConfig holds configuration options for ExportBagIt.
+type bagConfig struct {
+	date time.Time
+	info [][2]string
+}
+
+// BagOption is a functional option for configuring ExportBagIt.
+type BagOption func(*bagConfig)
+
+// WithBaggingDate sets the Bagging-Date recorded in bag-info.txt.
+// The default is the current date.
+func WithBaggingDate(t time.Time) BagOption {
+	return func(c *bagConfig) { c.date = t }
+}
+
+// WithBagInfo adds a label/value line to bag-info.txt, after the lines derived
+// from metadata. It may be given several times, including for the same label.
+func WithBagInfo(label, value string) BagOption {
+	return func(c *bagConfig) { c.info = append(c.info, [2]string{label, value}) }
+}
+
+// ExportBagIt writes doc as a BagIt 1.0 bag (RFC 8493) rooted at dir.
+//
+// The payload directory mirrors the container layout: Markdown files are written
+// to data/<Path> and media items to data/<Path>, or data/media/<ID> when Path is
+// empty. The bag also contains bagit.txt, manifest-sha256.txt, bag-info.txt,
+// metadata.json (when doc has metadata), and tagmanifest-sha256.txt.
+//
+// bag-info.txt carries Bagging-Date, Payload-Oxum, and one line per scalar or
+// string-array metadata key, with the key converted to a BagIt label
+// ("created_at" becomes "Created-At"). The "description" key maps to the
+// reserved External-Description label. Keys that would shadow the generated
+// labels are skipped, and nested metadata is only kept in metadata.json.
+//
+// dir is created if needed and must be empty. The document is validated first;
+// invalid documents are rejected with ErrValidation before anything is written.
+func ExportBagIt(doc *Document, dir string, opts ...BagOption) error {
+	cfg := bagConfig{date: time.Now()}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if err := validateDocument(doc, noLimits(), true); err != nil {
+		return err
+	}
+
+	payload, err := bagPayload(doc)
+	if err != nil {
+		return err
+	}
+	if err := ensureEmptyDir(dir); err != nil {
+		return err
+	}
+
+	var manifest strings.Builder
+	var octets int64
+	for _, p := range payload {
+		if err := writeBagFile(dir, p.name, p.data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(p.data)
+		fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(sum[:]), encodeBagPath(p.name))
+		octets += int64(len(p.data))
+	}
+
+	tags := []bagFile{
+		{"bagit.txt", []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")},
+		{"manifest-sha256.txt", []byte(manifest.String())},
+		{"bag-info.txt", bagInfo(doc.Metadata, cfg, octets, len(payload))},
+	}
+	if doc.Metadata != nil {
+		mb, err := json.MarshalIndent(doc.Metadata, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%w: metadata JSON: %v", ErrValidation, err)
+		}
+		tags = append(tags, bagFile{"metadata.json", append(mb, '\n')})
+	}
+	var tagManifest strings.Builder
+	for _, t := range tags {
+		if err := writeBagFile(dir, t.name, t.data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(t.data)
+		fmt.Fprintf(&tagManifest, "%s  %s\n", hex.EncodeToString(sum[:]), t.name)
+	}
+	return writeBagFile(dir, "tagmanifest-sha256.txt", []byte(tagManifest.String()))
+}
+
+// bagFile is a file to be written into a bag, named by its slash path.
+type bagFile struct {
+	name string
+	data []byte
+}
+
+// bagPayload lays out the payload files of doc under data/, sorted by name.
+// It fails if two parts map to the same payload path.
+func bagPayload(doc *Document) ([]bagFile, error) {
+	files := make([]bagFile, 0, len(doc.Markdown.Files)+len(doc.Media.Items))
+	seen := make(map[string]struct{}, cap(files))
+	add := func(p string, data []byte) error {
+		if _, dup := seen[p]; dup {
+			return fmt.Errorf("%w: payload path %q is used more than once", ErrValidation, p)
+		}
+		seen[p] = struct{}{}
+		files = append(files, bagFile{"data/" + p, data})
+		return nil
+	}
+	for _, f := range doc.Markdown.Files {
+		if err := add(f.Path, f.Content); err != nil {
+			return nil, err
+		}
+	}
+	for _, it := range doc.Media.Items {
+		p := it.Path
+		if p == "" {
+			p = "media/" + it.ID
+			if err := validateContainerPath(p); err != nil {
+				return nil, fmt.Errorf("%w: media item %q cannot be stored by ID: %v", ErrValidation, it.ID, err)
+			}
+		}
+		if err := add(p, it.Data); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files, nil
+}
+
+// bagInfo renders bag-info.txt.
+func bagInfo(meta map[string]any, cfg bagConfig, octets int64, streams int) []byte {
+	var b strings.Builder
+	line := func(label, value string) {
+		// Continuation lines start with whitespace (RFC 8493 section 2.2.2).
+		value = strings.ReplaceAll(strings.ReplaceAll(value, "\r\n", "\n"), "\n", "\n  ")
+		fmt.Fprintf(&b, "%s: %s\n", label, value)
+	}
+	line("Bagging-Date", cfg.date.Format("2006-01-02"))
+	line("Payload-Oxum", strconv.FormatInt(octets, 10)+"."+strconv.Itoa(streams))
+
+	keys := make([]string, 0, len(meta))
+	for k := range meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		label := bagLabel(k)
+		if label == "" || label == "Bagging-Date" || label == "Payload-Oxum" {
+			continue
+		}
+		switch v := meta[k].(type) {
+		case string:
+			line(label, v)
+		case bool, float64, json.Number:
+			line(label, fmt.Sprint(v))
+		case []any:
+			for _, e := range v {
+				if s, ok := e.(string); ok {
+					line(label, s)
+				}
+			}
+		case []string:
+			for _, s := range v {
+				line(label, s)
+			}
+		}
+	}
+	for _, kv := range cfg.info {
+		line(kv[0], kv[1])
+	}
+	return []byte(b.String())
+}
+
+// bagLabel converts a metadata key to a BagIt label. Keys that would produce an
+// invalid label (empty, or containing colons or whitespace) yield "".
+func bagLabel(key string) string {
+	if key == "description" {
+		return "External-Description"
+	}
+	parts := strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' })
+	for i, p := range parts {
+		if strings.ContainsFunc(p, func(r rune) bool { return r == ':' || unicode.IsSpace(r) || unicode.IsControl(r) }) {
+			return ""
+		}
+		r, n := utf8.DecodeRuneInString(p)
+		parts[i] = string(unicode.ToUpper(r)) + p[n:]
+	}
+	return strings.Join(parts, "-")
+}
+
+// encodeBagPath percent-encodes the characters RFC 8493 requires to be escaped in
+// manifest file paths.
+func encodeBagPath(p string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(p)
+}
+
+// ensureEmptyDir creates dir if it does not exist and fails if it is not empty.
+func ensureEmptyDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); !errors.Is(err, io.EOF) {
+		if err == nil {
+			return fmt.Errorf("mdocx: directory %s is not empty", dir)
+		}
+		return err
+	}
+	return nil
+}
+
+// writeBagFile writes data to the slash path name under dir, creating parents.
+func writeBagFile(dir, name string, data []byte) error {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
@@ -0,0 +1,94 @@
+package mdocx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportBagIt(t *testing.T) {
+	doc := sampleDoc()
+	doc.Metadata = map[string]any{"title": "Sample", "description": "Line one\nLine two", "tags": []any{"a", "b"}, "nested": map[string]any{"x": 1}}
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "raw", Data: []byte("raw")})
+	dir := filepath.Join(t.TempDir(), "bag")
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	if err := ExportBagIt(doc, dir, WithBaggingDate(date), WithBagInfo("Source-Organization", "Example")); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if got := read("data/assets/logo.png"); got != "\x01\x02\x03" {
+		t.Fatalf("media payload: %q", got)
+	}
+	if got := read("data/media/raw"); got != "raw" {
+		t.Fatalf("ID-only media payload: %q", got)
+	}
+	sum := sha256.Sum256([]byte("raw"))
+	if !strings.Contains(read("manifest-sha256.txt"), hex.EncodeToString(sum[:])+"  data/media/raw\n") {
+		t.Fatalf("manifest missing entry:\n%s", read("manifest-sha256.txt"))
+	}
+	info := read("bag-info.txt")
+	for _, want := range []string{
+		"Bagging-Date: 2024-05-01\n",
+		"Payload-Oxum: ",
+		"External-Description: Line one\n  Line two\n",
+		"Tags: a\nTags: b\n",
+		"Title: Sample\n",
+		"Source-Organization: Example\n",
+	} {
+		if !strings.Contains(info, want) {
+			t.Fatalf("bag-info.txt missing %q:\n%s", want, info)
+		}
+	}
+	if strings.Contains(info, "Nested") {
+		t.Fatalf("nested metadata must not be flattened:\n%s", info)
+	}
+	tm := read("tagmanifest-sha256.txt")
+	for _, name := range []string{"bagit.txt", "manifest-sha256.txt", "bag-info.txt", "metadata.json"} {
+		if !strings.Contains(tm, "  "+name+"\n") {
+			t.Fatalf("tag manifest missing %s:\n%s", name, tm)
+		}
+	}
+
+	if err := ExportBagIt(doc, dir); err == nil {
+		t.Fatal("expected error for non-empty directory")
+	}
+}
+
+func TestExportBagItCollision(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].Path = "docs/index.md"
+	err := ExportBagIt(doc, t.TempDir())
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+}
+
+func TestBagLabel(t *testing.T) {
+	cases := map[string]string{
+		"title":       "Title",
+		"created_at":  "Created-At",
+		"description": "External-Description",
+		"bad key":     "",
+		"a:b":         "",
+		"__":          "",
+		"éditeur_nom": "Éditeur-Nom",
+		"日付":          "日付",
+	}
+	for in, want := range cases {
+		if got := bagLabel(in); got != want {
+			t.Fatalf("bagLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
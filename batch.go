@@ -0,0 +1,171 @@
+package mdocx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// BatchResult is the outcome of validating a single container in a batch.
+type BatchResult struct {
+	// Path is the file system path of the container.
+	Path string `json:"path"`
+	// Valid reports whether the container decoded and validated successfully.
+	Valid bool `json:"valid"`
+	// Error is the decode or validation error message, if any.
+	Error string `json:"error,omitempty"`
+	// ErrorKind classifies Error (e.g. "invalid_magic", "limit_exceeded", "io").
+	ErrorKind string `json:"error_kind,omitempty"`
+	// Err is the error Error describes, for errors.Is and errors.As. It is
+	// not marshaled.
+	Err error `json:"-"`
+}
+
+// BatchReport aggregates the results of validating many containers.
+type BatchReport struct {
+	Total   int           `json:"total"`
+	Valid   int           `json:"valid"`
+	Invalid int           `json:"invalid"`
+	Results []BatchResult `json:"results"`
+}
+
+// ValidateGlob decodes and validates every file matching pattern (see filepath.Glob)
+// using up to workers concurrent decoders, and returns an aggregated report with
+// results sorted by path.
+//
+// If workers is zero or negative, GOMAXPROCS workers are used. Zero values in
+// limits are replaced with safe defaults. If ctx is cancelled, ValidateGlob stops
+// scheduling files and returns the results gathered so far together with ctx.Err().
+// A malformed pattern is reported as filepath.ErrBadPattern.
+//
+// opts, if any, are passed to Validate for each decoded document, to enforce
+// optional invariants with WithValidateChecks or to report every problem with
+// WithAllErrors.
+func ValidateGlob(ctx context.Context, pattern string, limits Limits, workers int, opts ...ValidateOption) (*BatchReport, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return ValidateFiles(ctx, paths, limits, workers, opts...)
+}
+
+// ValidateFiles is like ValidateGlob but validates an explicit list of paths.
+func ValidateFiles(ctx context.Context, paths []string, limits Limits, workers int, opts ...ValidateOption) (*BatchReport, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	results := make([]BatchResult, len(sorted))
+	done := make([]bool, len(sorted))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(sorted); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = validateFile(sorted[i], limits, opts)
+				done[i] = true
+			}
+		}()
+	}
+	var ctxErr error
+schedule:
+	for i := range sorted {
+		select {
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+			break schedule
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	rep := &BatchReport{Results: make([]BatchResult, 0, len(sorted))}
+	for i, r := range results {
+		if !done[i] {
+			continue
+		}
+		rep.Results = append(rep.Results, r)
+		if r.Valid {
+			rep.Valid++
+		} else {
+			rep.Invalid++
+		}
+	}
+	rep.Total = len(rep.Results)
+	return rep, ctxErr
+}
+
+// validateFile decodes the container at path and reports the outcome.
+func validateFile(path string, limits Limits, opts []ValidateOption) BatchResult {
+	f, err := os.Open(path)
+	if err != nil {
+		return BatchResult{Path: path, Error: err.Error(), ErrorKind: errorKind(err), Err: err}
+	}
+	defer f.Close()
+	return ValidateReader(bufio.NewReader(f), path, limits, opts...)
+}
+
+// ValidateReader decodes and validates the container read from r the way
+// ValidateFiles does each file, for input that is not a file, such as
+// standard input. name is reported as the result's Path.
+func ValidateReader(r io.Reader, name string, limits Limits, opts ...ValidateOption) BatchResult {
+	res := BatchResult{Path: name}
+	doc, err := Decode(r, WithReadLimits(limits))
+	if err == nil && len(opts) > 0 {
+		// Decode has verified the hashes already.
+		err = Validate(doc, append([]ValidateOption{WithValidateLimits(limits), WithValidateHashes(false)}, opts...)...)
+	}
+	if err != nil {
+		res.Error, res.ErrorKind, res.Err = err.Error(), errorKind(err), err
+		return res
+	}
+	res.Valid = true
+	return res
+}
+
+// errorKind returns a stable, machine-readable classification of err.
+func errorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInvalidMagic):
+		return "invalid_magic"
+	case errors.Is(err, ErrUnsupportedVersion):
+		return "unsupported_version"
+	case errors.Is(err, ErrInvalidHeader):
+		return "invalid_header"
+	case errors.Is(err, ErrInvalidSection):
+		return "invalid_section"
+	case errors.Is(err, ErrInvalidPayload):
+		return "invalid_payload"
+	case errors.Is(err, ErrCorrupted):
+		return "corrupted"
+	case errors.Is(err, ErrLimitExceeded):
+		return "limit_exceeded"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "truncated"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		var pe *os.PathError
+		if errors.As(err, &pe) {
+			return "io"
+		}
+		return "other"
+	}
+}
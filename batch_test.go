@@ -0,0 +1,100 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateGlob(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("ok%d.mdocx", i)), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.mdocx"), []byte("not an mdocx file at all, but long enough"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "short.mdocx"), buf.Bytes()[:40], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := ValidateGlob(context.Background(), filepath.Join(dir, "*.mdocx"), Limits{}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Total != 7 || rep.Valid != 5 || rep.Invalid != 2 {
+		t.Fatalf("unexpected totals: %+v", rep)
+	}
+	if rep.Results[0].Path != filepath.Join(dir, "bad.mdocx") || rep.Results[0].ErrorKind != "invalid_magic" {
+		t.Fatalf("unexpected first result: %+v", rep.Results[0])
+	}
+	if rep.Results[len(rep.Results)-1].ErrorKind != "truncated" {
+		t.Fatalf("unexpected last result: %+v", rep.Results[len(rep.Results)-1])
+	}
+	if _, err := json.Marshal(rep); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err = ValidateGlob(context.Background(), filepath.Join(dir, "ok*.mdocx"), Limits{MaxMediaItems: 0, MaxSingleMediaSize: 1}, 0)
+	if err != nil || rep.Invalid != 5 || rep.Results[0].ErrorKind != "limit_exceeded" {
+		t.Fatalf("expected limit failures, got %+v, %v", rep, err)
+	}
+}
+
+func TestValidateFiles_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rep, err := ValidateFiles(ctx, []string{"a", "b"}, Limits{}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if rep == nil || rep.Total > 2 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+}
+
+func TestValidateGlob_BadPattern(t *testing.T) {
+	if _, err := ValidateGlob(context.Background(), "[", Limits{}, 1); !errors.Is(err, filepath.ErrBadPattern) {
+		t.Fatalf("expected ErrBadPattern, got %v", err)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	if errorKind(nil) != "" || errorKind(errors.New("x")) != "other" {
+		t.Fatal("unexpected kind")
+	}
+	if _, err := os.Open(filepath.Join(t.TempDir(), "missing")); errorKind(err) != "io" {
+		t.Fatalf("expected io kind for %v", err)
+	}
+	if errorKind(fmt.Errorf("%w: x", ErrValidation)) != "validation" {
+		t.Fatal("expected validation kind")
+	}
+}
+
+func TestValidateReader(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].MediaRefs = []string{"missing"}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if res := ValidateReader(bytes.NewReader(buf.Bytes()), "-", Limits{}); !res.Valid || res.Path != "-" {
+		t.Fatalf("without checks: %+v", res)
+	}
+	res := ValidateReader(bytes.NewReader(buf.Bytes()), "-", Limits{}, WithValidateChecks(CheckMediaRefs), WithAllErrors())
+	var errs ValidationErrors
+	if res.Valid || res.ErrorKind != "validation" || !errors.As(res.Err, &errs) || len(errs) != 1 {
+		t.Fatalf("with checks: %+v", res)
+	}
+}
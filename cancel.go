@@ -0,0 +1,127 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pierrec/lz4/v4"
+)
+
+// cancelChunkSize is the amount of data compressed, decompressed, or written
+// between two checks of a cancelable context.
+const cancelChunkSize = 1 << 20
+
+// contextReader is an io.Reader that fails with ctx's error once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > cancelChunkSize {
+		p = p[:cancelChunkSize]
+	}
+	return cr.r.Read(p)
+}
+
+// contextWriter is an io.Writer that splits writes into chunks and fails with
+// ctx's error once ctx is done.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw contextWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		if err := cw.ctx.Err(); err != nil {
+			return n, err
+		}
+		m, err := cw.w.Write(p[:min(len(p), cancelChunkSize)])
+		n += m
+		if err != nil {
+			return n, err
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// compressPayloadContext is compressPayload checking ctx between chunks of
+// input. With a context that is never canceled it is compressPayload.
+func compressPayloadContext(ctx context.Context, comp Compression, gobBytes []byte, t codecTuning) (uint16, []byte, error) {
+	if ctx.Done() == nil || comp == CompNone {
+		return compressPayload(comp, gobBytes, t)
+	}
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 8))
+	cw, err := newCompressWriter(comp, &buf, t)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err := (contextWriter{ctx, cw}).Write(gobBytes); err != nil {
+		_ = cw.Close()
+		return 0, nil, err
+	}
+	if err := cw.Close(); err != nil {
+		return 0, nil, err
+	}
+	payload := buf.Bytes()
+	binary.LittleEndian.PutUint64(payload[:8], uint64(len(gobBytes)))
+	return uint16(comp) | sectionFlagHasUncompressedLen, payload, nil
+}
+
+// decompressContext decompresses in, which must expand to expected bytes,
+// checking ctx between chunks of output.
+func decompressContext(ctx context.Context, comp Compression, in []byte, expected uint64) ([]byte, error) {
+	var r io.Reader
+	switch comp {
+	case CompZIP:
+		rc, err := zipOpenPayload(in, expected)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		r = rc
+	case CompZSTD:
+		dec, err := newZstdReader()
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		if err := dec.Reset(bytes.NewReader(in)); err != nil {
+			return nil, err
+		}
+		r = dec
+	case CompLZ4:
+		r = lz4.NewReader(bytes.NewReader(in))
+	case CompBR:
+		r = brotli.NewReader(bytes.NewReader(in))
+	case CompXZ:
+		xr, err := newXZReader(in, expected)
+		if err != nil {
+			return nil, err
+		}
+		r = xr
+	default:
+		return nil, fmt.Errorf("%w: unknown compression %d", ErrInvalidPayload, comp)
+	}
+	b, err := readAll(contextReader{ctx, io.LimitReader(r, int64(expected)+1)})
+	if err != nil {
+		if comp == CompZIP {
+			err = zipError(err)
+		}
+		return nil, err
+	}
+	if uint64(len(b)) > expected {
+		return nil, fmt.Errorf("%w: payload expanded beyond expected size", ErrInvalidPayload)
+	}
+	return b, nil
+}
@@ -0,0 +1,112 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestEncodeDecodeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, comp := range []Compression{CompNone, CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ} {
+		doc := sampleDoc()
+		doc.Media.Items[0].Data = bytes.Repeat([]byte("media "), cancelChunkSize/2)
+		var buf bytes.Buffer
+		if err := EncodeContext(ctx, &buf, doc, WithMarkdownCompression(comp), WithMediaCompression(comp)); err != nil {
+			t.Fatalf("comp %d: %v", comp, err)
+		}
+		got, err := DecodeContext(ctx, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("comp %d: %v", comp, err)
+		}
+		if !bytes.Equal(got.Media.Items[0].Data, doc.Media.Items[0].Data) {
+			t.Fatalf("comp %d: media data mismatch", comp)
+		}
+	}
+}
+
+// cancelAfter cancels a context once n bytes have passed through it.
+type cancelAfter struct {
+	n      int
+	cancel context.CancelFunc
+	r      io.Reader
+	w      io.Writer
+}
+
+func (c *cancelAfter) count(n int) {
+	if c.n -= n; c.n <= 0 {
+		c.cancel()
+	}
+}
+
+func (c *cancelAfter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count(n)
+	return n, err
+}
+
+func (c *cancelAfter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.count(n)
+	return n, err
+}
+
+func TestContextCanceled(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].Data = bytes.Repeat([]byte{7}, 3*cancelChunkSize)
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := EncodeContext(ctx, io.Discard, sampleDoc()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EncodeContext: expected context.Canceled, got %v", err)
+	}
+	if _, err := DecodeContext(ctx, bytes.NewReader(buf.Bytes())); !errors.Is(err, context.Canceled) {
+		t.Fatalf("DecodeContext: expected context.Canceled, got %v", err)
+	}
+
+	// Cancellation while the media payload is written or read.
+	ctx, cancel = context.WithCancel(context.Background())
+	w := &cancelAfter{n: cancelChunkSize, cancel: cancel, w: io.Discard}
+	if err := EncodeContext(ctx, w, doc, WithMediaCompression(CompNone)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("EncodeContext: expected context.Canceled, got %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	r := &cancelAfter{n: cancelChunkSize, cancel: cancel, r: bytes.NewReader(buf.Bytes())}
+	if _, err := DecodeContext(ctx, r); !errors.Is(err, context.Canceled) {
+		t.Fatalf("DecodeContext: expected context.Canceled, got %v", err)
+	}
+
+	// Cancellation during decompression.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	src := bytes.Repeat([]byte{7}, 4*cancelChunkSize)
+	_, payload, err := compressPayload(CompZSTD, src, defaultCodecTuning())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	readAll = func(r io.Reader) ([]byte, error) {
+		return io.ReadAll(readerFunc(func(p []byte) (int, error) {
+			if n++; n == 2 {
+				cancel()
+			}
+			return r.Read(p)
+		}))
+	}
+	defer func() { readAll = io.ReadAll }()
+	_, err = decompressPayloadContext(ctx, CompZSTD, uint16(CompZSTD)|sectionFlagHasUncompressedLen, payload, uint64(len(src)))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("decompression: expected context.Canceled, got %v", err)
+	}
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
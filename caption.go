@@ -0,0 +1,57 @@
+package mdocx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AltTextAttr is the media attribute key holding an image's alternative text.
+const AltTextAttr = "alt"
+
+// Captioner returns alternative text describing an image media item, such as
+// the output of a vision model. Returning "" leaves the item unchanged.
+type Captioner func(ctx context.Context, item *MediaItem) (string, error)
+
+// CaptionImages returns a Transform that calls caption for every image media
+// item whose AltTextAttr attribute is missing or blank, and stores the result
+// in that attribute. An item is an image if its MIMEType starts with "image/",
+// or, when MIMEType is empty, if its data sniffs as an image.
+//
+// Items are captioned sequentially. The first error stops the transform and
+// is returned, wrapped with the item ID; items captioned before it keep their
+// new attribute. ctx is passed to every call.
+func CaptionImages(ctx context.Context, caption Captioner) Transform {
+	return func(doc *Document) error {
+		for i := range doc.Media.Items {
+			it := &doc.Media.Items[i]
+			if strings.TrimSpace(it.Attributes[AltTextAttr]) != "" || !isImage(it) {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			alt, err := caption(ctx, it)
+			if err != nil {
+				return fmt.Errorf("caption media item %q: %w", it.ID, err)
+			}
+			if alt = strings.TrimSpace(alt); alt == "" {
+				continue
+			}
+			attrs := Attributes(it.Attributes)
+			attrs.SetString(AltTextAttr, alt)
+			it.Attributes = attrs
+		}
+		return nil
+	}
+}
+
+// isImage reports whether it holds an image, by MIME type or content sniffing.
+func isImage(it *MediaItem) bool {
+	mt := it.MIMEType
+	if mt == "" {
+		mt = http.DetectContentType(it.Data)
+	}
+	return strings.HasPrefix(strings.ToLower(mt), "image/")
+}
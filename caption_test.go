@@ -0,0 +1,48 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCaptionImages(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "sniffed", Data: []byte("\x89PNG\r\n\x1a\n0000")},
+		MediaItem{ID: "described", MIMEType: "image/jpeg", Data: []byte{1}, Attributes: map[string]string{"alt": "Already there"}},
+		MediaItem{ID: "audio", MIMEType: "audio/mpeg", Data: []byte{2}},
+	)
+	var called []string
+	caption := func(_ context.Context, it *MediaItem) (string, error) {
+		called = append(called, it.ID)
+		return " A picture of " + it.ID + " ", nil
+	}
+	if err := Encode(&bytes.Buffer{}, doc, WithTransforms(CaptionImages(context.Background(), caption))); err != nil {
+		t.Fatal(err)
+	}
+	if len(called) != 2 || called[0] != "logo" || called[1] != "sniffed" {
+		t.Fatalf("captioner called for %v", called)
+	}
+	if got := doc.Media.Items[0].Attributes[AltTextAttr]; got != "A picture of logo" {
+		t.Fatalf("alt = %q", got)
+	}
+	if got := doc.Media.Items[2].Attributes[AltTextAttr]; got != "Already there" {
+		t.Fatalf("existing alt overwritten: %q", got)
+	}
+}
+
+func TestCaptionImagesError(t *testing.T) {
+	boom := errors.New("model unavailable")
+	tr := CaptionImages(context.Background(), func(context.Context, *MediaItem) (string, error) { return "", boom })
+	if err := tr(sampleDoc()); !errors.Is(err, boom) {
+		t.Fatalf("expected captioner error, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tr = CaptionImages(ctx, func(context.Context, *MediaItem) (string, error) { return "x", nil })
+	if err := tr(sampleDoc()); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package mdocx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CatalogVersion is the catalog format version written by EncodeCatalog.
+const CatalogVersion = 1
+
+// Catalog is a lightweight index of a library of containers, stored as JSON
+// alongside them. Entries identify each container by path and fingerprint and
+// carry the fields needed to list and search the library without opening every
+// container.
+type Catalog struct {
+	// Version is the catalog format version (CatalogVersion).
+	Version int `json:"version"`
+	// Entries lists the containers, one per path.
+	Entries []CatalogEntry `json:"entries"`
+}
+
+// CatalogEntry describes one container in a Catalog.
+type CatalogEntry struct {
+	// Path locates the container file, typically relative to the catalog file,
+	// using forward slashes.
+	Path string `json:"path"`
+	// Fingerprint is the container's Document.Fingerprint.
+	Fingerprint string `json:"fingerprint"`
+	// Title is the container's "title" metadata.
+	Title string `json:"title,omitempty"`
+	// Tags are the container's "tags" metadata.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// NewCatalogEntry returns the catalog entry for doc stored at path.
+func NewCatalogEntry(path string, doc *Document) CatalogEntry {
+	e := CatalogEntry{Path: path, Fingerprint: doc.Fingerprint()}
+	e.Title, _ = doc.Metadata["title"].(string)
+	switch tags := doc.Metadata["tags"].(type) {
+	case []string:
+		e.Tags = slices.Clone(tags)
+	case []any:
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				e.Tags = append(e.Tags, s)
+			}
+		}
+	}
+	return e
+}
+
+// CatalogDir walks dir recursively and returns a catalog of every file with an
+// ".mdocx" extension, with paths relative to dir. The first file that fails to
+// open or decode stops the walk and its error is returned, annotated with the
+// file path.
+func CatalogDir(dir string, opts ...ReadOption) (*Catalog, error) {
+	c := &Catalog{Version: CatalogVersion}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), FileExtension) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		doc, err := decodeFile(p, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		c.Entries = append(c.Entries, NewCatalogEntry(filepath.ToSlash(rel), doc))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.sort()
+	return c, nil
+}
+
+// decodeFile decodes the container file at p.
+func decodeFile(p string, opts []ReadOption) (*Document, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f, opts...)
+}
+
+// Put adds e to the catalog, replacing any entry with the same Path.
+func (c *Catalog) Put(e CatalogEntry) {
+	for i := range c.Entries {
+		if c.Entries[i].Path == e.Path {
+			c.Entries[i] = e
+			return
+		}
+	}
+	c.Entries = append(c.Entries, e)
+	c.sort()
+}
+
+// Remove removes the entry with the given path and reports whether it existed.
+func (c *Catalog) Remove(path string) bool {
+	for i := range c.Entries {
+		if c.Entries[i].Path == path {
+			c.Entries = slices.Delete(c.Entries, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the entries whose fingerprint is fp or starts with fp.
+// Several entries match when the same content is stored at several paths.
+func (c *Catalog) Lookup(fp string) []CatalogEntry {
+	var out []CatalogEntry
+	for _, e := range c.Entries {
+		if fp != "" && strings.HasPrefix(e.Fingerprint, fp) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// CatalogQuery selects catalog entries. Zero fields match every entry.
+type CatalogQuery struct {
+	// Text must occur, case-insensitively, in the entry's title or path.
+	Text string
+	// Tags must all be present on the entry (compared case-insensitively).
+	Tags []string
+}
+
+// Search returns the entries matching q, in catalog order.
+func (c *Catalog) Search(q CatalogQuery) []CatalogEntry {
+	text := strings.ToLower(q.Text)
+	var out []CatalogEntry
+	for _, e := range c.Entries {
+		if text != "" && !strings.Contains(strings.ToLower(e.Title), text) && !strings.Contains(strings.ToLower(e.Path), text) {
+			continue
+		}
+		if !slices.ContainsFunc(q.Tags, func(want string) bool {
+			return !slices.ContainsFunc(e.Tags, func(t string) bool { return strings.EqualFold(t, want) })
+		}) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (c *Catalog) sort() {
+	sort.Slice(c.Entries, func(i, j int) bool { return c.Entries[i].Path < c.Entries[j].Path })
+}
+
+// EncodeCatalog writes c to w as indented JSON with entries sorted by path.
+// A zero Version is written as CatalogVersion.
+func EncodeCatalog(w io.Writer, c *Catalog) error {
+	out := *c
+	if out.Version == 0 {
+		out.Version = CatalogVersion
+	}
+	out.Entries = slices.Clone(c.Entries)
+	if out.Entries == nil {
+		out.Entries = []CatalogEntry{}
+	}
+	out.sort()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// DecodeCatalog reads a catalog written by EncodeCatalog. It returns
+// ErrUnsupportedVersion for catalogs of another version and ErrValidation for
+// entries without a path or fingerprint, or with a duplicate path.
+func DecodeCatalog(r io.Reader) (*Catalog, error) {
+	var c Catalog
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("%w: catalog: %v", ErrValidation, err)
+	}
+	if c.Version != CatalogVersion {
+		return nil, fmt.Errorf("%w: catalog version %d", ErrUnsupportedVersion, c.Version)
+	}
+	seen := make(map[string]struct{}, len(c.Entries))
+	for i, e := range c.Entries {
+		if e.Path == "" || e.Fingerprint == "" {
+			return nil, fmt.Errorf("%w: catalog entry %d needs a path and fingerprint", ErrValidation, i)
+		}
+		if _, dup := seen[e.Path]; dup {
+			return nil, fmt.Errorf("%w: duplicate catalog path %q", ErrValidation, e.Path)
+		}
+		seen[e.Path] = struct{}{}
+	}
+	return &c, nil
+}
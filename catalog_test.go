@@ -0,0 +1,83 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	dir := t.TempDir()
+	a := sampleDoc()
+	b := sampleDoc()
+	b.Metadata = map[string]any{"title": "Field Guide", "tags": []any{"Birds", "guide"}}
+	for name, doc := range map[string]*Document{"a.mdocx": a, "sub/b.MDOCX": b} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, doc); err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := CatalogDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Entries) != 2 || c.Entries[1].Path != "sub/b.MDOCX" || c.Entries[1].Fingerprint != b.Fingerprint() {
+		t.Fatalf("unexpected catalog: %+v", c)
+	}
+	if got := c.Search(CatalogQuery{Tags: []string{"birds"}}); len(got) != 1 || got[0].Title != "Field Guide" {
+		t.Fatalf("tag search: %+v", got)
+	}
+	if got := c.Search(CatalogQuery{Text: "exam"}); len(got) != 1 || got[0].Path != "a.mdocx" {
+		t.Fatalf("text search: %+v", got)
+	}
+	if got := c.Search(CatalogQuery{Text: "guide", Tags: []string{"a"}}); len(got) != 0 {
+		t.Fatalf("combined search: %+v", got)
+	}
+	if got := c.Lookup(a.Fingerprint()[:12]); len(got) != 1 || got[0].Path != "a.mdocx" {
+		t.Fatalf("Lookup: %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeCatalog(&buf, c); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := DecodeCatalog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dec, c) {
+		t.Fatalf("round trip: %+v != %+v", dec, c)
+	}
+
+	c.Put(CatalogEntry{Path: "a.mdocx", Fingerprint: "00", Title: "Renamed"})
+	if len(c.Entries) != 2 || c.Entries[0].Title != "Renamed" {
+		t.Fatalf("Put: %+v", c.Entries)
+	}
+	if !c.Remove("a.mdocx") || c.Remove("a.mdocx") || len(c.Entries) != 1 {
+		t.Fatalf("Remove: %+v", c.Entries)
+	}
+}
+
+func TestDecodeCatalogErrors(t *testing.T) {
+	for in, want := range map[string]error{
+		`{"version":2,"entries":[]}`:                   ErrUnsupportedVersion,
+		`{"version":1,"entries":[{"path":"a.mdocx"}]}`: ErrValidation,
+		`{"version":1,"entries":[{"path":"a","fingerprint":"1"},{"path":"a","fingerprint":"2"}]}`: ErrValidation,
+		`[`: ErrValidation,
+	} {
+		if _, err := DecodeCatalog(strings.NewReader(in)); !errors.Is(err, want) {
+			t.Errorf("DecodeCatalog(%s) = %v, want %v", in, err, want)
+		}
+	}
+}
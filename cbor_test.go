@@ -0,0 +1,200 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCBOREncoding(t *testing.T) {
+	// {"BundleVersion": 1, "Files": [{"Path": "a.md", "Content": h'6869'}]}
+	want := "a2" + "6d" + hex.EncodeToString([]byte("BundleVersion")) + "01" +
+		"65" + hex.EncodeToString([]byte("Files")) + "81" +
+		"a2" + "64" + hex.EncodeToString([]byte("Path")) + "64" + hex.EncodeToString([]byte("a.md")) +
+		"67" + hex.EncodeToString([]byte("Content")) + "426869"
+	b := MarkdownBundle{BundleVersion: VersionV1, Files: []MarkdownFile{{Path: "a.md", Content: []byte("hi")}}}
+	got := cborEncodeMarkdown(b)
+	if hex.EncodeToString(got) != want {
+		t.Fatalf("cborEncodeMarkdown = %x\nwant %s", got, want)
+	}
+	dec, err := decodeMarkdownCBOR(got, 10)
+	if err != nil || !reflect.DeepEqual(dec, b) {
+		t.Fatalf("decodeMarkdownCBOR = %+v, %v", dec, err)
+	}
+
+	// The streamed head and elements make the same bytes.
+	head := cborMarkdownHead("", 1)
+	if s := append(head.prefix(0), cborMarkdownFile(b.Files[0])...); !bytes.Equal(s, got) {
+		t.Fatalf("streamed = %x", s)
+	}
+}
+
+func TestPayloadFormatCBOR(t *testing.T) {
+	for _, comp := range []Compression{CompNone, CompZSTD} {
+		doc := sampleDoc()
+		doc.Media.Items[0].Attributes = map[string]string{"alt": "Logo", "title": "The logo"}
+		opts := []WriteOption{WithMarkdownCompression(comp), WithMediaCompression(comp), WithPayloadFormat(PayloadCBOR)}
+		var buf bytes.Buffer
+		if err := Encode(&buf, doc, append(opts, WithIndex(true), WithChecksum(true))...); err != nil {
+			t.Fatalf("%v: %v", comp, err)
+		}
+		data := buf.Bytes()
+		h, err := DecodeHeader(bytes.NewReader(data))
+		if err != nil || h.PayloadFormat() != PayloadCBOR {
+			t.Fatalf("%v: DecodeHeader = %+v, %v", comp, h, err)
+		}
+		if comp == CompNone && !bytes.Contains(data, []byte("MIMEType")) {
+			t.Errorf("%v: no CBOR field names in the container", comp)
+		}
+
+		got, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%v: %v", comp, err)
+		}
+		if !reflect.DeepEqual(got.Markdown, doc.Markdown) || !reflect.DeepEqual(got.Media, doc.Media) {
+			t.Fatalf("%v: Decode = %+v", comp, got)
+		}
+
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%v: NewReader: %v", comp, err)
+		}
+		rc, err := r.OpenMedia("logo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := io.ReadAll(rc); err != nil || !bytes.Equal(b, doc.Media.Items[0].Data) {
+			t.Errorf("%v: Reader.OpenMedia = %x, %v", comp, b, err)
+		}
+
+		m, err := newMapped(data, newReadConfig(nil))
+		if err != nil {
+			t.Fatalf("%v: newMapped: %v", comp, err)
+		}
+		if info := m.Media(); len(info) != 1 || info[0].Attributes["alt"] != "Logo" {
+			t.Errorf("%v: Mapped.Media = %+v", comp, info)
+		}
+
+		var sunk []MediaItem
+		if err := DecodeInto(bytes.NewReader(data), SinkFuncs{OnMediaItem: func(it MediaItem) error {
+			sunk = append(sunk, it)
+			return nil
+		}}); err != nil || len(sunk) != 1 || !bytes.Equal(sunk[0].Data, doc.Media.Items[0].Data) {
+			t.Errorf("%v: DecodeInto = %+v, %v", comp, sunk, err)
+		}
+
+		filtered, err := Decode(bytes.NewReader(data), WithMediaFilter(func(id, path, mimeType string, size int64) bool { return size == 3 }))
+		if err != nil || !reflect.DeepEqual(filtered.Media, doc.Media) {
+			t.Errorf("%v: filtered Decode = %+v, %v", comp, filtered.Media, err)
+		}
+
+		// EncodeStream and Writer write what Encode does.
+		var want bytes.Buffer
+		if err := Encode(&want, doc, opts...); err != nil {
+			t.Fatal(err)
+		}
+		hdr := StreamHeader{Metadata: doc.Metadata, RootPath: doc.Markdown.RootPath}
+		files, media := feed(doc)
+		var streamed bytes.Buffer
+		if err := EncodeStream(context.Background(), &streamed, hdr, files, media, opts...); err != nil {
+			t.Fatalf("%v: EncodeStream: %v", comp, err)
+		}
+		var written bytes.Buffer
+		w, err := NewWriter(&written, hdr, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range doc.Markdown.Files {
+			if err := w.AddMarkdownFile(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.AddMediaItem(doc.Media.Items[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if comp == CompNone && (!bytes.Equal(streamed.Bytes(), want.Bytes()) || !bytes.Equal(written.Bytes(), want.Bytes())) {
+			t.Errorf("%v: EncodeStream or Writer output differs from Encode", comp)
+		}
+		if dec, err := Decode(&written); err != nil || !reflect.DeepEqual(dec.Media, doc.Media) {
+			t.Errorf("%v: Writer output: %v", comp, err)
+		}
+
+		// An edit keeps the format.
+		f := &memFile{b: bytes.Clone(data)}
+		s, err := OpenEditSession(f)
+		if err != nil {
+			t.Fatalf("%v: OpenEditSession: %v", comp, err)
+		}
+		edited := MediaItem{ID: "logo", Path: "assets/logo.png", MIMEType: "image/png", Data: make([]byte, 200)}
+		rand.Read(edited.Data)
+		if err := s.ReplaceMedia(edited); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ReplaceFile(MarkdownFile{Path: "docs/notes.md", Content: []byte("Edited notes, now longer\n")}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Commit(); err != nil {
+			t.Fatalf("%v: Commit: %v", comp, err)
+		}
+		r, err = NewReader(bytes.NewReader(f.b), int64(len(f.b)))
+		if err != nil {
+			t.Fatalf("%v: NewReader after Commit: %v", comp, err)
+		}
+		if rc, err = r.OpenMedia("logo"); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(rc); !bytes.Equal(b, edited.Data) || string(r.Markdown().Files[1].Content) != "Edited notes, now longer\n" {
+			t.Errorf("%v: edit not applied", comp)
+		}
+	}
+}
+
+func TestPayloadFormatCBORRejects(t *testing.T) {
+	if err := Encode(io.Discard, sampleDoc(), WithPayloadFormat(PayloadFormat(9))); !errors.Is(err, ErrValidation) {
+		t.Errorf("unknown format: %v", err)
+	}
+
+	b := cborEncodeMarkdown(MarkdownBundle{BundleVersion: VersionV1, Files: []MarkdownFile{{Path: "a.md"}}})
+	text := func(s string) string { return hex.EncodeToString(append([]byte{0x60 | byte(len(s))}, s...)) }
+	for name, tc := range map[string]struct {
+		hex string
+		ok  bool
+	}{
+		"valid":            {hex: hex.EncodeToString(b), ok: true},
+		"unknown key":      {hex: "a2" + text("Extra") + "a1" + text("x") + "80" + text("BundleVersion") + "01", ok: true},
+		"trailing byte":    {hex: hex.EncodeToString(b) + "00"},
+		"duplicate key":    {hex: "a2" + text("RootPath") + text("a") + text("RootPath") + text("b")},
+		"indefinite map":   {hex: "bf" + text("BundleVersion") + "01ff"},
+		"tag":              {hex: "a1" + text("Extra") + "c001"},
+		"wrong type":       {hex: "a1" + text("RootPath") + "01"},
+		"invalid UTF-8":    {hex: "a1" + text("RootPath") + "61ff"},
+		"truncated":        {hex: hex.EncodeToString(b[:len(b)-1])},
+		"too long":         {hex: "a1" + text("RootPath") + "7a7fffffff"},
+		"version overflow": {hex: "a1" + text("BundleVersion") + "1a00010000"},
+	} {
+		data, _ := hex.DecodeString(tc.hex)
+		_, err := decodeMarkdownCBOR(data, 10)
+		if tc.ok != (err == nil) || (err != nil && !errors.Is(err, ErrInvalidPayload)) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// A SHA256 that is not 32 bytes.
+	data, _ := hex.DecodeString("a1" + text("Items") + "81a1" + text("SHA256") + "43010203")
+	if _, err := decodeMediaCBOR(data, 10); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("short SHA256: %v", err)
+	}
+	// Limits are reported as such.
+	many := cborEncodeMedia(MediaBundle{BundleVersion: VersionV1, Items: make([]MediaItem, 3)})
+	if _, err := decodeMediaCBOR(many, 2); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("too many items: %v", err)
+	}
+}
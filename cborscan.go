@@ -0,0 +1,446 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// errCBORScan marks a CBOR stream that is malformed or does not have the shape
+// of an MDOCX bundle (rfc.md §7.6).
+var errCBORScan = errors.New("unexpected CBOR layout")
+
+// cborMaxDepth bounds the nesting of unknown fields the scanner skips.
+const cborMaxDepth = 32
+
+// cborScanner reads a CBOR stream from an io.ReaderAt without materializing
+// byte strings, so that media data can be located rather than copied. It
+// accepts definite lengths only, and no tags.
+type cborScanner struct {
+	readAhead
+}
+
+func newCBORScanner(r io.ReaderAt, size int64) *cborScanner {
+	return &cborScanner{readAhead{r: r, end: size}}
+}
+
+// head reads the initial byte and argument of an item.
+func (s *cborScanner) head() (major byte, arg uint64, err error) {
+	b, err := s.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	major, ai := b>>5, b&0x1f
+	switch {
+	case ai < 24:
+		return major, uint64(ai), nil
+	case ai > 27:
+		return 0, 0, errCBORScan // reserved, or an indefinite length
+	}
+	for range 1 << (ai - 24) {
+		c, err := s.byte()
+		if err != nil {
+			return 0, 0, err
+		}
+		arg = arg<<8 | uint64(c)
+	}
+	return major, arg, nil
+}
+
+// expect reads the head of an item of the given major type and returns its
+// argument. For strings and containers the argument is bounded by the
+// remaining stream length, since every byte or element occupies at least one
+// byte.
+func (s *cborScanner) expect(major byte) (uint64, error) {
+	m, arg, err := s.head()
+	if err != nil {
+		return 0, err
+	}
+	if m != major {
+		return 0, errCBORScan
+	}
+	if major != cborUint && arg > uint64(s.end-s.off) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return arg, nil
+}
+
+func (s *cborScanner) uint() (uint64, error) { return s.expect(cborUint) }
+
+func (s *cborScanner) text() (string, error) {
+	n, err := s.expect(cborText)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	for i := range b {
+		if b[i], err = s.byte(); err != nil {
+			return "", err
+		}
+	}
+	if !utf8.Valid(b) {
+		return "", errCBORScan
+	}
+	return string(b), nil
+}
+
+// bytesAt skips a byte string and returns its location.
+func (s *cborScanner) bytesAt() (off, n int64, err error) {
+	l, err := s.expect(cborBytes)
+	if err != nil {
+		return 0, 0, err
+	}
+	off = s.off
+	s.off += int64(l)
+	return off, int64(l), nil
+}
+
+// bytes reads a byte string.
+func (s *cborScanner) bytes() ([]byte, error) {
+	off, n, err := s.bytesAt()
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	if read, err := s.r.ReadAt(b, off); read < len(b) {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *cborScanner) sha256(dst *[32]byte) error {
+	b, err := s.bytes()
+	if err != nil {
+		return err
+	}
+	if len(b) != len(dst) {
+		return errCBORScan
+	}
+	copy(dst[:], b)
+	return nil
+}
+
+func (s *cborScanner) strings() ([]string, error) {
+	n, err := s.expect(cborArray)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, n)
+	for i := range out {
+		if out[i], err = s.text(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (s *cborScanner) attrs() (map[string]string, error) {
+	n, err := s.expect(cborMap)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, n)
+	for range n {
+		k, err := s.text()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := out[k]; dup {
+			return nil, errCBORScan
+		}
+		if out[k], err = s.text(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// skip skips an item of any type.
+func (s *cborScanner) skip(depth int) error {
+	if depth > cborMaxDepth {
+		return errCBORScan
+	}
+	major, arg, err := s.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		if arg > uint64(s.end-s.off) {
+			return io.ErrUnexpectedEOF
+		}
+		s.off += int64(arg)
+	case cborArray, cborMap:
+		if arg > uint64(s.end-s.off) {
+			return io.ErrUnexpectedEOF
+		}
+		if major == cborMap {
+			arg *= 2
+		}
+		for range arg {
+			if err := s.skip(depth + 1); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return errCBORScan
+	}
+	return nil
+}
+
+// walkMap reads a map with text keys, calling visit for each key. If visit
+// returns false the value is skipped. Duplicate keys are rejected.
+func (s *cborScanner) walkMap(visit func(key string) (bool, error)) error {
+	n, err := s.expect(cborMap)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, n)
+	for range n {
+		k, err := s.text()
+		if err != nil {
+			return err
+		}
+		if _, dup := seen[k]; dup {
+			return errCBORScan
+		}
+		seen[k] = struct{}{}
+		ok, err := visit(k)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := s.skip(1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// list reads an array of at most maxN elements, calling elem for each.
+func (s *cborScanner) list(maxN int, limit string, elem func() error) (int, error) {
+	n, err := s.expect(cborArray)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(maxN) {
+		return 0, exceeds(limit, "too many elements")
+	}
+	for range n {
+		if err := elem(); err != nil {
+			return 0, err
+		}
+	}
+	return int(n), nil
+}
+
+// done checks that the whole stream was read.
+func (s *cborScanner) done() error {
+	if s.off != s.end {
+		return errCBORScan
+	}
+	return nil
+}
+
+// bundleVersion reads a BundleVersion value.
+func (s *cborScanner) bundleVersion() (uint16, error) {
+	v, err := s.uint()
+	if err != nil {
+		return 0, err
+	}
+	if v > 0xffff {
+		return 0, errCBORScan
+	}
+	return uint16(v), nil
+}
+
+// cborError wraps a scanner error for the named structure, keeping limit
+// errors as they are.
+func cborError(what string, err error) error {
+	if err == nil || errors.Is(err, ErrLimitExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %s CBOR: %v", ErrInvalidPayload, what, err)
+}
+
+// scanMediaCBOR scans a CBOR-encoded MediaBundle held in r[0:size] and returns
+// the location of each item's Data without copying it, as scanMediaGob does
+// for gob.
+func scanMediaCBOR(r io.ReaderAt, size int64, maxItems int) (*mediaIndex, error) {
+	s := newCBORScanner(r, size)
+	idx := &mediaIndex{}
+	err := s.walkMap(func(key string) (bool, error) {
+		var err error
+		switch key {
+		case "BundleVersion":
+			idx.bundleVersion, err = s.bundleVersion()
+		case "Items":
+			_, err = s.list(maxItems, "MaxMediaItems", func() error {
+				e, err := s.scanMediaItem(false)
+				idx.items = append(idx.items, e)
+				return err
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err == nil {
+		err = s.done()
+	}
+	if err != nil {
+		return nil, cborError("media", err)
+	}
+	return idx, nil
+}
+
+// scanMediaItem reads a MediaItem, or if indexed is set, a footer index item
+// with Offset and Length in place of Data.
+func (s *cborScanner) scanMediaItem(indexed bool) (mediaEntry, error) {
+	var e mediaEntry
+	err := s.walkMap(func(key string) (bool, error) {
+		var err error
+		var v uint64
+		switch {
+		case indexed && key == "Data", !indexed && (key == "Offset" || key == "Length"):
+			return false, nil
+		}
+		switch key {
+		case "ID":
+			e.ID, err = s.text()
+		case "Path":
+			e.Path, err = s.text()
+		case "MIMEType":
+			e.MIMEType, err = s.text()
+		case "Data":
+			e.dataOff, e.dataLen, err = s.bytesAt()
+		case "SHA256":
+			err = s.sha256(&e.SHA256)
+		case "Attributes":
+			e.Attributes, err = s.attrs()
+		case "Offset", "Length":
+			if v, err = s.uint(); err == nil && v > 1<<62 {
+				err = errCBORScan
+			}
+			if key == "Offset" {
+				e.dataOff = int64(v)
+			} else {
+				e.dataLen = int64(v)
+			}
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return e, err
+}
+
+// decodeMarkdownCBOR decodes a CBOR-encoded MarkdownBundle.
+func decodeMarkdownCBOR(data []byte, maxFiles int) (MarkdownBundle, error) {
+	s := newCBORScanner(bytes.NewReader(data), int64(len(data)))
+	var b MarkdownBundle
+	err := s.walkMap(func(key string) (bool, error) {
+		var err error
+		switch key {
+		case "BundleVersion":
+			b.BundleVersion, err = s.bundleVersion()
+		case "RootPath":
+			b.RootPath, err = s.text()
+		case "Files":
+			_, err = s.list(maxFiles, "MaxMarkdownFiles", func() error {
+				f, err := s.markdownFile()
+				b.Files = append(b.Files, f)
+				return err
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err == nil {
+		err = s.done()
+	}
+	return b, cborError("markdown", err)
+}
+
+func (s *cborScanner) markdownFile() (MarkdownFile, error) {
+	var f MarkdownFile
+	err := s.walkMap(func(key string) (bool, error) {
+		var err error
+		switch key {
+		case "Path":
+			f.Path, err = s.text()
+		case "Content":
+			f.Content, err = s.bytes()
+		case "MediaRefs":
+			f.MediaRefs, err = s.strings()
+		case "Attributes":
+			f.Attributes, err = s.attrs()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	return f, err
+}
+
+// decodeMediaCBOR decodes a CBOR-encoded MediaBundle. Item data is sliced
+// from data, not copied.
+func decodeMediaCBOR(data []byte, maxItems int) (MediaBundle, error) {
+	idx, err := scanMediaCBOR(bytes.NewReader(data), int64(len(data)), maxItems)
+	if err != nil {
+		return MediaBundle{}, err
+	}
+	b := MediaBundle{BundleVersion: idx.bundleVersion, Items: make([]MediaItem, len(idx.items))}
+	for i, e := range idx.items {
+		b.Items[i] = MediaItem{ID: e.ID, Path: e.Path, MIMEType: e.MIMEType, SHA256: e.SHA256, Attributes: e.Attributes}
+		if e.dataLen > 0 {
+			b.Items[i].Data = data[e.dataOff : e.dataOff+e.dataLen : e.dataOff+e.dataLen]
+		}
+	}
+	return b, nil
+}
+
+// decodeIndexCBOR decodes a CBOR-encoded footer index.
+func decodeIndexCBOR(data []byte, maxItems int) (footerIndex, error) {
+	s := newCBORScanner(bytes.NewReader(data), int64(len(data)))
+	var idx footerIndex
+	err := s.walkMap(func(key string) (bool, error) {
+		var err error
+		switch key {
+		case "MarkdownOffset":
+			idx.MarkdownOffset, err = s.uint()
+		case "MediaOffset":
+			idx.MediaOffset, err = s.uint()
+		case "MediaBundleVersion":
+			idx.MediaBundleVersion, err = s.bundleVersion()
+		case "Items":
+			_, err = s.list(maxItems, "MaxMediaItems", func() error {
+				e, err := s.scanMediaItem(true)
+				idx.Items = append(idx.Items, indexItem{
+					ID:         e.ID,
+					Path:       e.Path,
+					MIMEType:   e.MIMEType,
+					SHA256:     e.SHA256,
+					Attributes: e.Attributes,
+					Offset:     uint64(e.dataOff),
+					Length:     uint64(e.dataLen),
+				})
+				return err
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err == nil {
+		err = s.done()
+	}
+	return idx, cborError("footer index", err)
+}
@@ -0,0 +1,221 @@
+package mdocx
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// CBOR major types (RFC 8949 §3.1).
+const (
+	cborUint   = 0
+	cborNeg    = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborBuf accumulates CBOR-encoded items. Only definite lengths and the
+// shortest argument encodings are written.
+type cborBuf []byte
+
+// head appends the initial byte and argument of an item of the given major
+// type.
+func (b *cborBuf) head(major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		*b = append(*b, m|byte(n))
+	case n <= 0xff:
+		*b = append(*b, m|24, byte(n))
+	case n <= 0xffff:
+		*b = binary.BigEndian.AppendUint16(append(*b, m|25), uint16(n))
+	case n <= 0xffffffff:
+		*b = binary.BigEndian.AppendUint32(append(*b, m|26), uint32(n))
+	default:
+		*b = binary.BigEndian.AppendUint64(append(*b, m|27), n)
+	}
+}
+
+func (b *cborBuf) uint(n uint64) { b.head(cborUint, n) }
+
+func (b *cborBuf) text(s string) {
+	b.head(cborText, uint64(len(s)))
+	*b = append(*b, s...)
+}
+
+func (b *cborBuf) bytes(p []byte) {
+	b.head(cborBytes, uint64(len(p)))
+	*b = append(*b, p...)
+}
+
+// cborMapBuilder collects the fields of a map whose size is known only once
+// they are all added. Fields with zero values are left out, as in gob.
+type cborMapBuilder struct {
+	n    int
+	body cborBuf
+}
+
+func (m *cborMapBuilder) key(k string) *cborBuf {
+	m.n++
+	m.body.text(k)
+	return &m.body
+}
+
+func (m *cborMapBuilder) uint(k string, v uint64) {
+	if v != 0 {
+		m.key(k).uint(v)
+	}
+}
+
+func (m *cborMapBuilder) text(k, v string) {
+	if v != "" {
+		m.key(k).text(v)
+	}
+}
+
+func (m *cborMapBuilder) bytes(k string, v []byte) {
+	if len(v) > 0 {
+		m.key(k).bytes(v)
+	}
+}
+
+func (m *cborMapBuilder) sha256(k string, v [32]byte) {
+	if v != ([32]byte{}) {
+		m.key(k).bytes(v[:])
+	}
+}
+
+func (m *cborMapBuilder) strings(k string, v []string) {
+	if len(v) == 0 {
+		return
+	}
+	b := m.key(k)
+	b.head(cborArray, uint64(len(v)))
+	for _, s := range v {
+		b.text(s)
+	}
+}
+
+// attrs adds a map of strings with its keys sorted, so that equal maps encode
+// equally.
+func (m *cborMapBuilder) attrs(k string, v map[string]string) {
+	if len(v) == 0 {
+		return
+	}
+	b := m.key(k)
+	b.head(cborMap, uint64(len(v)))
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		b.text(key)
+		b.text(v[key])
+	}
+}
+
+// encoded returns the encoded map.
+func (m *cborMapBuilder) encoded() cborBuf {
+	var b cborBuf
+	b.head(cborMap, uint64(m.n))
+	return append(b, m.body...)
+}
+
+// cborMarkdownFile returns the CBOR encoding of f.
+func cborMarkdownFile(f MarkdownFile) []byte {
+	var m cborMapBuilder
+	m.text("Path", f.Path)
+	m.bytes("Content", f.Content)
+	m.strings("MediaRefs", f.MediaRefs)
+	m.attrs("Attributes", f.Attributes)
+	return m.encoded()
+}
+
+// cborMediaItem returns the CBOR encoding of it.
+func cborMediaItem(it MediaItem) []byte {
+	var m cborMapBuilder
+	m.text("ID", it.ID)
+	m.text("Path", it.Path)
+	m.text("MIMEType", it.MIMEType)
+	m.bytes("Data", it.Data)
+	m.sha256("SHA256", it.SHA256)
+	m.attrs("Attributes", it.Attributes)
+	return m.encoded()
+}
+
+// cborBundleHead is the encoded start of a bundle map whose last field, the
+// array of files or items, is written element by element. Nothing follows
+// the last element.
+type cborBundleHead []byte
+
+func (h cborBundleHead) size(elems int64) int64    { return int64(len(h)) + elems }
+func (h cborBundleHead) prefix(elems int64) []byte { return h }
+func (h cborBundleHead) trailer() []byte           { return nil }
+
+// cborMarkdownHead returns the CBOR head of a MarkdownBundle with count files.
+func cborMarkdownHead(rootPath string, count int) cborBundleHead {
+	var m cborMapBuilder
+	m.uint("BundleVersion", uint64(VersionV1))
+	m.text("RootPath", rootPath)
+	m.key("Files").head(cborArray, uint64(count))
+	return cborBundleHead(m.encoded())
+}
+
+// cborMediaHead returns the CBOR head of a MediaBundle with count items.
+func cborMediaHead(count int) cborBundleHead {
+	var m cborMapBuilder
+	m.uint("BundleVersion", uint64(VersionV1))
+	m.key("Items").head(cborArray, uint64(count))
+	return cborBundleHead(m.encoded())
+}
+
+// cborEncodeMarkdown returns the CBOR encoding of b.
+func cborEncodeMarkdown(b MarkdownBundle) []byte {
+	var m cborMapBuilder
+	m.uint("BundleVersion", uint64(b.BundleVersion))
+	m.text("RootPath", b.RootPath)
+	arr := m.key("Files")
+	arr.head(cborArray, uint64(len(b.Files)))
+	for _, f := range b.Files {
+		*arr = append(*arr, cborMarkdownFile(f)...)
+	}
+	return m.encoded()
+}
+
+// cborEncodeMedia returns the CBOR encoding of b.
+func cborEncodeMedia(b MediaBundle) []byte {
+	var m cborMapBuilder
+	m.uint("BundleVersion", uint64(b.BundleVersion))
+	arr := m.key("Items")
+	arr.head(cborArray, uint64(len(b.Items)))
+	for _, it := range b.Items {
+		*arr = append(*arr, cborMediaItem(it)...)
+	}
+	return m.encoded()
+}
+
+// cborEncodeIndex returns the CBOR encoding of idx.
+func cborEncodeIndex(idx footerIndex) []byte {
+	var m cborMapBuilder
+	m.uint("MarkdownOffset", idx.MarkdownOffset)
+	m.uint("MediaOffset", idx.MediaOffset)
+	m.uint("MediaBundleVersion", uint64(idx.MediaBundleVersion))
+	arr := m.key("Items")
+	arr.head(cborArray, uint64(len(idx.Items)))
+	for _, it := range idx.Items {
+		var e cborMapBuilder
+		e.text("ID", it.ID)
+		e.text("Path", it.Path)
+		e.text("MIMEType", it.MIMEType)
+		e.sha256("SHA256", it.SHA256)
+		e.attrs("Attributes", it.Attributes)
+		e.uint("Offset", it.Offset)
+		e.uint("Length", it.Length)
+		*arr = append(*arr, e.encoded()...)
+	}
+	return m.encoded()
+}
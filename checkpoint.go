@@ -0,0 +1,250 @@
+package mdocx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Names of the files kept in a checkpoint directory (see WithCheckpointDir).
+const (
+	checkpointJournal       = "checkpoint.jsonl"
+	checkpointMarkdownSpool = "markdown.spool"
+	checkpointMediaSpool    = "media.spool"
+)
+
+// WithCheckpointDir makes EncodeStream and Writer keep their spool files in
+// dir, together with a journal that is synced after every Markdown file and
+// media item, so that a long packing job that is interrupted can resume where
+// it stopped instead of starting over.
+//
+// If dir holds a checkpoint, EncodeStream and NewWriter resume from it: the
+// files and items it records are kept, and anything added after the last
+// recorded one is discarded. The StreamHeader must be the one the checkpoint
+// was started with, and the WriteOption values should be the same. When
+// resuming, EncodeStream skips files and items it receives whose path or ID
+// the checkpoint already holds; Writer users can check with
+// Writer.HasMarkdownFile and Writer.HasMediaItem. Otherwise a new checkpoint
+// is started.
+//
+// The checkpoint is deleted when the container has been written, or by
+// Writer.Abort, and kept if encoding fails or is canceled. dir must exist and
+// must not be shared by concurrent encodes. Syncing after every file and item
+// costs throughput when they are small.
+func WithCheckpointDir(dir string) WriteOption {
+	return func(c *writeConfig) { c.checkpointDir = dir }
+}
+
+// checkpoint is the journal of a streamState with a checkpoint directory.
+type checkpoint struct {
+	dir     string
+	journal *os.File
+	// files and items hold the paths and IDs recorded before the checkpoint
+	// was resumed.
+	files map[string]struct{}
+	items map[string]struct{}
+}
+
+// checkpointRecord is one line of the journal. The first line holds Header;
+// each later one records a file or item and the spool sizes after it.
+type checkpointRecord struct {
+	Header      *checkpointHeader `json:"header,omitempty"`
+	File        *MarkdownFile     `json:"file,omitempty"`
+	ItemID      string            `json:"item_id,omitempty"`
+	ItemPath    string            `json:"item_path,omitempty"`
+	MarkdownEnd int64             `json:"markdown_end"`
+	MediaEnd    int64             `json:"media_end"`
+}
+
+type checkpointHeader struct {
+	Metadata []byte `json:"metadata,omitempty"`
+	RootPath string `json:"root_path,omitempty"`
+	NoMedia  bool   `json:"no_media,omitempty"`
+	// Format is the PayloadFormat of the spooled elements.
+	Format PayloadFormat `json:"format,omitempty"`
+}
+
+// openCheckpoint opens or creates the checkpoint in dir and the spool files
+// it holds, replaying the journal into st.
+func (st *streamState) openCheckpoint(dir string) (err error) {
+	journal, err := os.OpenFile(filepath.Join(dir, checkpointJournal), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = journal.Close()
+		}
+	}()
+	var records []checkpointRecord
+	var valid int64
+	br := bufio.NewReader(journal)
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// A partial last line was being written when the job stopped.
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var rec checkpointRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("%w: checkpoint journal at offset %d: %v", ErrCorrupted, valid, err)
+		}
+		records = append(records, rec)
+		valid += int64(len(line))
+	}
+
+	hdr := checkpointHeader{Metadata: st.metadataBytes, RootPath: st.hdr.RootPath, NoMedia: st.hdr.NoMedia, Format: st.cfg.format}
+	ck := &checkpoint{dir: dir, journal: journal, files: make(map[string]struct{}), items: make(map[string]struct{})}
+	var mdEnd, mediaEnd int64
+	if len(records) == 0 {
+		valid = 0
+	} else {
+		h := records[0].Header
+		if h == nil || !bytes.Equal(h.Metadata, hdr.Metadata) || h.RootPath != hdr.RootPath || h.NoMedia != hdr.NoMedia {
+			return fmt.Errorf("%w: StreamHeader does not match the checkpoint in %s", ErrValidation, dir)
+		}
+		if h.Format != hdr.Format {
+			return fmt.Errorf("%w: checkpoint in %s was started with payload format %v", ErrValidation, dir, h.Format)
+		}
+		records = records[1:]
+	}
+	var mdCount, mediaCount int
+	for _, rec := range records {
+		switch {
+		case rec.File != nil:
+			st.seenPaths[rec.File.Path] = struct{}{}
+			ck.files[rec.File.Path] = struct{}{}
+			if st.cfg.checks != 0 {
+				st.refFiles = append(st.refFiles, *rec.File)
+			}
+			mdCount++
+		case rec.ItemID != "":
+			st.seenIDs[rec.ItemID] = struct{}{}
+			ck.items[rec.ItemID] = struct{}{}
+			if st.cfg.checks != 0 {
+				st.refItems = append(st.refItems, MediaItem{ID: rec.ItemID, Path: rec.ItemPath})
+			}
+			mediaCount++
+		default:
+			return fmt.Errorf("%w: checkpoint journal record without file or item", ErrCorrupted)
+		}
+		mdEnd, mediaEnd = rec.MarkdownEnd, rec.MediaEnd
+	}
+
+	if err := journal.Truncate(valid); err != nil {
+		return err
+	}
+	if _, err := journal.Seek(valid, io.SeekStart); err != nil {
+		return err
+	}
+	st.ckpt = ck
+	if valid == 0 {
+		if err := ck.append(checkpointRecord{Header: &hdr}); err != nil {
+			return err
+		}
+	}
+	if st.mdSpool, err = openSpool(filepath.Join(dir, checkpointMarkdownSpool), mdEnd, mdCount); err != nil {
+		return err
+	}
+	if st.mediaSpool, err = openSpool(filepath.Join(dir, checkpointMediaSpool), mediaEnd, mediaCount); err != nil {
+		_ = st.mdSpool.f.Close()
+		return err
+	}
+	return nil
+}
+
+// openSpool opens the spool file at path holding count elements in its
+// first n bytes, discarding anything after them.
+func openSpool(path string, n int64, count int) (*spool, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err == nil && fi.Size() < n {
+		err = fmt.Errorf("%w: spool file %s is shorter than its checkpoint", ErrCorrupted, path)
+	}
+	if err == nil {
+		err = f.Truncate(n)
+	}
+	if err == nil {
+		_, err = f.Seek(n, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &spool{f: f, bw: bufio.NewWriterSize(f, 64<<10), n: n, count: count}, nil
+}
+
+// append writes rec to the journal and syncs it.
+func (ck *checkpoint) append(rec checkpointRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := ck.journal.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return ck.journal.Sync()
+}
+
+// record syncs the spool files and journals rec, a file or item just added.
+// It does nothing without a checkpoint.
+func (st *streamState) record(rec checkpointRecord) error {
+	if st.ckpt == nil {
+		return nil
+	}
+	for _, s := range []*spool{st.mdSpool, st.mediaSpool} {
+		if err := s.bw.Flush(); err != nil {
+			return err
+		}
+		if err := s.f.Sync(); err != nil {
+			return err
+		}
+	}
+	rec.MarkdownEnd, rec.MediaEnd = st.mdSpool.n, st.mediaSpool.n
+	return st.ckpt.append(rec)
+}
+
+// resumedFile reports whether the checkpoint st resumed from holds path.
+func (st *streamState) resumedFile(path string) bool {
+	if st.ckpt == nil {
+		return false
+	}
+	_, ok := st.ckpt.files[path]
+	return ok
+}
+
+// resumedItem reports whether the checkpoint st resumed from holds id.
+func (st *streamState) resumedItem(id string) bool {
+	if st.ckpt == nil {
+		return false
+	}
+	_, ok := st.ckpt.items[id]
+	return ok
+}
+
+// HasMarkdownFile reports whether a Markdown file with path has been added,
+// including by an earlier run whose checkpoint the Writer resumed (see
+// WithCheckpointDir).
+func (w *Writer) HasMarkdownFile(path string) bool {
+	_, ok := w.st.seenPaths[path]
+	return ok
+}
+
+// HasMediaItem reports whether a media item with id has been added, including
+// by an earlier run whose checkpoint the Writer resumed (see
+// WithCheckpointDir).
+func (w *Writer) HasMediaItem(id string) bool {
+	_, ok := w.st.seenIDs[id]
+	return ok
+}
@@ -0,0 +1,101 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "video", Path: "media/video.bin", Data: bytes.Repeat([]byte("video"), 100)})
+	doc.Markdown.Files[1] = MarkdownFile{Path: "docs/notes.md", Content: []byte("[Video](mdocx://media/video)\n"), MediaRefs: []string{"video"}}
+	hdr := StreamHeader{Metadata: doc.Metadata, RootPath: doc.Markdown.RootPath}
+	opts := []WriteOption{WithMarkdownCompression(CompNone), WithMediaCompression(CompNone), WithChecksOnWrite(CheckAll)}
+	var want bytes.Buffer
+	if err := Encode(&want, doc, opts...); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	opts = append(opts, WithCheckpointDir(dir))
+
+	// Interrupt an EncodeStream after one file and one item.
+	ctx, cancel := context.WithCancel(context.Background())
+	files, media := make(chan MarkdownFile), make(chan MediaItem)
+	errc := make(chan error, 1)
+	go func() { errc <- EncodeStream(ctx, new(bytes.Buffer), hdr, files, media, opts...) }()
+	files <- doc.Markdown.Files[0]
+	media <- doc.Media.Items[0]
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("EncodeStream: %v", err)
+	}
+
+	// Leave a partly written item behind, as a crash would.
+	f, err := os.OpenFile(filepath.Join(dir, checkpointMediaSpool), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial item"))
+	f.Close()
+	f, err = os.OpenFile(filepath.Join(dir, checkpointJournal), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"item_id":"vi`))
+	f.Close()
+
+	if _, err := NewWriter(new(bytes.Buffer), StreamHeader{RootPath: "docs/notes.md"}, opts...); !errors.Is(err, ErrValidation) {
+		t.Fatalf("resume with another header: %v", err)
+	}
+
+	// Resume with a Writer and fail, keeping the checkpoint.
+	w, err := NewWriter(new(bytes.Buffer), hdr, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.HasMarkdownFile("docs/index.md") || !w.HasMediaItem("logo") || w.HasMarkdownFile("docs/notes.md") || w.HasMediaItem("video") {
+		t.Fatal("Writer did not resume the checkpoint")
+	}
+	if err := w.AddMediaItem(doc.Media.Items[0]); !errors.Is(err, ErrValidation) {
+		t.Fatalf("adding a resumed item: %v", err)
+	}
+
+	// Resume with EncodeStream, which skips what the checkpoint holds.
+	files2, media2 := feed(doc)
+	var got bytes.Buffer
+	if err := EncodeStream(context.Background(), &got, hdr, files2, media2, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatal("resumed output differs from Encode")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("checkpoint not removed: %v", entries)
+	}
+}
+
+func TestCheckpointAbortAndCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(new(bytes.Buffer), StreamHeader{}, WithCheckpointDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddMarkdownFile(MarkdownFile{Path: "a.md"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Abort()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Abort left %v", entries)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, checkpointJournal), []byte("{}\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWriter(new(bytes.Buffer), StreamHeader{}, WithCheckpointDir(dir)); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("corrupt journal: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// checksumTrailerMagic ends a container with an integrity trailer.
+var checksumTrailerMagic = [8]byte{'M', 'D', 'O', 'C', 'X', 'C', 'R', 'C'}
+
+// checksumTrailerSize is the size of the integrity trailer: the little-endian
+// CRC-32C of everything before it, then checksumTrailerMagic.
+const checksumTrailerSize = 12
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WithChecksum makes Encode end the container with an integrity trailer
+// holding the CRC-32C of everything before it (see rfc.md §7.5). Decode checks
+// the trailer before decoding any section, so a truncated or damaged container
+// fails with an error wrapping ErrCorrupted rather than a gob or decompression
+// error. Readers that do not know the trailer ignore it. EncodeStream, Writer,
+// and Transcode do not write a trailer.
+func WithChecksum(v bool) WriteOption {
+	return func(c *writeConfig) { c.checksum = v }
+}
+
+// checksumWriter passes writes through to w and hashes them.
+type checksumWriter struct {
+	w   io.Writer
+	crc hash.Hash32
+}
+
+func newChecksumWriter(w io.Writer) *checksumWriter {
+	return &checksumWriter{w: w, crc: crc32.New(castagnoli)}
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.crc.Write(p[:n])
+	return n, err
+}
+
+// writeTrailer writes the integrity trailer for the bytes written so far.
+func (c *checksumWriter) writeTrailer() error {
+	var trailer [checksumTrailerSize]byte
+	binary.LittleEndian.PutUint32(trailer[:4], c.crc.Sum32())
+	copy(trailer[4:], checksumTrailerMagic[:])
+	_, err := c.w.Write(trailer[:])
+	return err
+}
+
+// verifyChecksum reads the rest of the container with fixed header h from r,
+// checks it against the integrity trailer, and returns a reader over the bytes
+// that followed the fixed header, without the trailer. Section lengths are
+// checked against the limits of cfg before anything is read.
+func verifyChecksum(r io.Reader, h fixedHeaderV1, cfg readConfig) (io.Reader, error) {
+	limits := cfg.limits
+	var buf bytes.Buffer
+	crc := crc32.New(castagnoli)
+	if err := writeFixedHeader(crc, h); err != nil {
+		return nil, err
+	}
+	tee := io.TeeReader(r, io.MultiWriter(&buf, crc))
+	if _, err := io.CopyN(io.Discard, tee, int64(h.MetadataLength)); err != nil {
+		return nil, truncated(err)
+	}
+	if h.Version == VersionV2 {
+		table, err := readSectionTable(tee, h)
+		if err != nil {
+			return nil, truncated(err)
+		}
+		for _, sh := range table {
+			if maxLen, name := sectionLimit(SectionType(sh.SectionType), limits); sh.PayloadLen > maxLen {
+				return nil, exceeds(name, "section %d too large", sh.SectionType)
+			}
+			if _, err := io.CopyN(io.Discard, tee, int64(sh.PayloadLen)); err != nil {
+				return nil, truncated(err)
+			}
+		}
+	} else {
+		maxLen := map[SectionType]uint64{
+			SectionMarkdown: limits.MaxMarkdownSectionLen,
+			SectionMedia:    limits.MaxMediaSectionLen,
+			SectionIndex:    limits.MaxMediaUncompressed,
+		}
+		limitName := map[SectionType]string{
+			SectionMarkdown: "MaxMarkdownSectionLen",
+			SectionMedia:    "MaxMediaSectionLen",
+			SectionIndex:    "MaxMediaUncompressed",
+		}
+		sections := []SectionType{SectionMarkdown, SectionMedia}
+		if h.HeaderFlags&HeaderFlagIndex != 0 {
+			sections = append(sections, SectionIndex)
+		}
+		for _, typ := range sections {
+			sh, err := nextSectionHeader(tee, limits, cfg.ignoreUnknown)
+			if err != nil {
+				return nil, truncated(err)
+			}
+			if err := validateSectionHeader(sh, typ); err != nil {
+				return nil, err
+			}
+			if sh.PayloadLen > maxLen[typ] {
+				return nil, exceeds(limitName[typ], "section %d too large", typ)
+			}
+			if _, err := io.CopyN(io.Discard, tee, int64(sh.PayloadLen)); err != nil {
+				return nil, truncated(err)
+			}
+		}
+	}
+	if h.HeaderFlags&HeaderFlagIndex != 0 {
+		if _, err := io.CopyN(io.Discard, tee, indexTrailerSize); err != nil {
+			return nil, truncated(err)
+		}
+	}
+
+	if err := checkTrailer(r, crc.Sum32()); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// checkTrailer reads the integrity trailer from r and checks it against the
+// CRC-32C sum of everything before it.
+func checkTrailer(r io.Reader, sum uint32) error {
+	var trailer [checksumTrailerSize]byte
+	if _, err := io.ReadFull(r, trailer[:]); err != nil {
+		return truncated(err)
+	}
+	if !bytes.Equal(trailer[4:], checksumTrailerMagic[:]) {
+		return fmt.Errorf("%w: integrity trailer not found", ErrCorrupted)
+	}
+	if want := binary.LittleEndian.Uint32(trailer[:4]); sum != want {
+		return fmt.Errorf("%w: CRC-32C %08x != expected %08x", ErrCorrupted, sum, want)
+	}
+	return nil
+}
+
+// WithStreamingInput makes Decode check the integrity trailer of a container
+// (see WithChecksum) while decoding it rather than before, for containers read
+// from non-seekable network streams. By default Decode reads the whole
+// container into memory to verify the trailer before decoding any section;
+// with streaming input it holds at most one section in memory at a time, and
+// section lengths are still checked against the limits as soon as each section
+// header arrives. The trailer can then only be checked once the Media section
+// has been read, so damage earlier in the container may be reported as the
+// error it causes, such as ErrInvalidPayload, rather than ErrCorrupted.
+// Truncation is still reported as ErrCorrupted. Containers without a trailer
+// are always decoded as they stream in. Default is false.
+func WithStreamingInput(v bool) ReadOption {
+	return func(c *readConfig) { c.streaming = v }
+}
+
+// crcReader hashes the bytes of a container with an integrity trailer as
+// Decode reads them, for WithStreamingInput.
+type crcReader struct {
+	r   io.Reader
+	crc hash.Hash32
+}
+
+// newCRCReader returns a crcReader over the rest of the container whose
+// fixed header h has already been read from r.
+func newCRCReader(r io.Reader, h fixedHeaderV1) (*crcReader, error) {
+	c := &crcReader{r: r, crc: crc32.New(castagnoli)}
+	if err := writeFixedHeader(c.crc, h); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Read implements io.Reader. The trailer follows every section, so running out
+// of input while reading through c means the container is truncated.
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.crc.Write(p[:n])
+	if err == io.EOF {
+		err = truncated(io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+// verify reads the rest of the container after the Media section: the index
+// section, if h has HeaderFlagIndex, and the integrity trailer, which it
+// checks.
+func (c *crcReader) verify(h fixedHeaderV1, cfg readConfig) error {
+	limits := cfg.limits
+	if h.HeaderFlags&HeaderFlagIndex != 0 {
+		sh, err := nextSectionHeader(c, limits, cfg.ignoreUnknown)
+		if err != nil {
+			return err
+		}
+		if err := validateSectionHeader(sh, SectionIndex); err != nil {
+			return err
+		}
+		if sh.PayloadLen > limits.MaxMediaUncompressed {
+			return exceeds("MaxMediaUncompressed", "section %d too large", SectionIndex)
+		}
+		if _, err := io.CopyN(io.Discard, c, int64(sh.PayloadLen)+indexTrailerSize); err != nil {
+			return err
+		}
+	}
+	return checkTrailer(c.r, c.crc.Sum32())
+}
+
+// truncated wraps an unexpected end of input in ErrCorrupted.
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: truncated: %v", ErrCorrupted, err)
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestChecksumRoundTrip(t *testing.T) {
+	for _, index := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, sampleDoc(), WithChecksum(true), WithIndex(index), WithMediaCompression(CompNone)); err != nil {
+			t.Fatal(err)
+		}
+		b := buf.Bytes()
+		if !bytes.HasSuffix(b, []byte("MDOCXCRC")) {
+			t.Fatal("missing integrity trailer")
+		}
+		doc, err := Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("index %v: %v", index, err)
+		}
+		if doc.Metadata["title"] != "Example" || len(doc.Media.Items) != 1 {
+			t.Fatalf("index %v: decoded %+v", index, doc)
+		}
+		r, err := NewReader(bytes.NewReader(b), int64(len(b)))
+		if err != nil {
+			t.Fatalf("index %v: NewReader: %v", index, err)
+		}
+		rc, err := r.OpenMedia("logo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		if !bytes.Equal(data, []byte{1, 2, 3}) {
+			t.Fatalf("index %v: logo = %v", index, data)
+		}
+	}
+}
+
+func TestChecksumCorrupted(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithChecksum(true), WithIndex(true)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	flipped := bytes.Clone(b)
+	flipped[len(flipped)/2] ^= 0x01
+	if _, err := Decode(bytes.NewReader(flipped)); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("bit flip: expected ErrCorrupted, got %v", err)
+	}
+	for _, n := range []int{1, checksumTrailerSize, len(b) / 2} {
+		if _, err := Decode(bytes.NewReader(b[:len(b)-n])); !errors.Is(err, ErrCorrupted) {
+			t.Fatalf("truncated by %d: expected ErrCorrupted, got %v", n, err)
+		}
+	}
+}
+
+func TestTranscodeDropsChecksum(t *testing.T) {
+	var in, out bytes.Buffer
+	if err := Encode(&in, sampleDoc(), WithChecksum(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Transcode(&in, &out, WithMediaCompression(CompZSTD)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(bytes.NewReader(out.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamingInput(t *testing.T) {
+	for _, index := range []bool{false, true} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, sampleDoc(), WithChecksum(true), WithIndex(index), WithMediaCompression(CompNone)); err != nil {
+			t.Fatal(err)
+		}
+		b := buf.Bytes()
+
+		// Decode stops at the end of the container, leaving what follows.
+		r := &countingReader{r: io.MultiReader(bytes.NewReader(b), bytes.NewReader([]byte("next")))}
+		doc, err := Decode(r, WithStreamingInput(true))
+		if err != nil {
+			t.Fatalf("index %v: %v", index, err)
+		}
+		if len(doc.Media.Items) != 1 || r.n != int64(len(b)) {
+			t.Fatalf("index %v: decoded %d items, read %d of %d bytes", index, len(doc.Media.Items), r.n, len(b))
+		}
+
+		// Damage in the Media section is caught by the trailer before the
+		// section is decoded.
+		if !index {
+			flipped := bytes.Clone(b)
+			flipped[len(flipped)-checksumTrailerSize-1] ^= 0x01
+			if _, err := Decode(&countingReader{r: bytes.NewReader(flipped)}, WithStreamingInput(true)); !errors.Is(err, ErrCorrupted) {
+				t.Fatalf("bit flip: expected ErrCorrupted, got %v", err)
+			}
+		}
+		for _, n := range []int{1, checksumTrailerSize, len(b) / 2} {
+			if _, err := Decode(&countingReader{r: bytes.NewReader(b[:len(b)-n])}, WithStreamingInput(true)); !errors.Is(err, ErrCorrupted) {
+				t.Fatalf("index %v: truncated by %d: expected ErrCorrupted, got %v", index, n, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// catResult is the JSON output of cat.
+type catResult struct {
+	Kind     string `json:"kind"`
+	Path     string `json:"path,omitempty"`
+	ID       string `json:"id,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	// Content holds text; Data holds binary media, base64-encoded.
+	Content *string `json:"content,omitempty"`
+	Data    []byte  `json:"data,omitempty"`
+}
+
+func runCat(c *cli, args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	doc, err := c.openDocument(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	res, ok := lookup(doc, fs.Arg(1))
+	if !ok {
+		return fmt.Errorf("%w: %s: no Markdown file, media path, or media ID %q", mdocx.ErrNotFound, fs.Arg(0), fs.Arg(1))
+	}
+	if c.json {
+		return c.printJSON(res)
+	}
+	if res.Content != nil {
+		_, err = fmt.Fprint(c.stdout, *res.Content)
+	} else {
+		_, err = c.stdout.Write(res.Data)
+	}
+	return err
+}
+
+// lookup finds the Markdown file or media item named by name: a Markdown
+// path, a media path, a media ID, or an mdocx://media/<ID> URI.
+func lookup(doc *mdocx.Document, name string) (catResult, bool) {
+	for _, f := range doc.Markdown.Files {
+		if f.Path == name {
+			s := string(f.Content)
+			return catResult{Kind: mdocx.InventoryMarkdown, Path: f.Path, MIMEType: "text/markdown", Content: &s}, true
+		}
+	}
+	id, isURI := strings.CutPrefix(name, "mdocx://media/")
+	for _, it := range doc.Media.Items {
+		if it.ID != id && (isURI || it.Path != name) {
+			continue
+		}
+		res := catResult{Kind: mdocx.InventoryMedia, Path: it.Path, ID: it.ID, MIMEType: it.MIMEType, Data: it.Data}
+		if strings.HasPrefix(it.MIMEType, "text/") && utf8.Valid(it.Data) {
+			s := string(it.Data)
+			res.Content, res.Data = &s, nil
+		}
+		return res, true
+	}
+	return catResult{}, false
+}
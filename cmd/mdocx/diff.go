@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func runDiff(c *cli, args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	a, err := c.openDocument(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := c.openDocument(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	r := mdocx.Diff(a, b)
+
+	if c.json {
+		// Print empty lists rather than null.
+		if r.Markdown == nil {
+			r.Markdown = []mdocx.DiffEntry{}
+		}
+		if r.Media == nil {
+			r.Media = []mdocx.DiffEntry{}
+		}
+		if r.Metadata == nil {
+			r.Metadata = []mdocx.MetadataDiff{}
+		}
+		return c.printJSON(r)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, e := range r.Markdown {
+		fmt.Fprintf(tw, "%s\tmarkdown\t%s\t%s\n", e.Change, e.Path, diffSizes(e))
+	}
+	for _, e := range r.Media {
+		name := listName(lsEntry{Path: e.Path, ID: e.ID})
+		if e.OldPath != "" {
+			name = e.OldPath + " -> " + name
+		}
+		fmt.Fprintf(tw, "%s\tmedia\t%s\t%s\n", e.Change, name, diffSizes(e))
+	}
+	for _, m := range r.Metadata {
+		var values string
+		switch m.Change {
+		case mdocx.DiffAdded:
+			values = jsonValue(m.New)
+		case mdocx.DiffRemoved:
+			values = jsonValue(m.Old)
+		default:
+			values = jsonValue(m.Old) + " -> " + jsonValue(m.New)
+		}
+		fmt.Fprintf(tw, "%s\tmetadata\t%s\t%s\n", m.Change, m.Key, values)
+	}
+	return tw.Flush()
+}
+
+// diffSizes describes the sizes of a changed entry.
+func diffSizes(e mdocx.DiffEntry) string {
+	switch e.Change {
+	case mdocx.DiffAdded:
+		return fmt.Sprintf("%d bytes", e.NewSize)
+	case mdocx.DiffRemoved:
+		return fmt.Sprintf("%d bytes", e.OldSize)
+	}
+	if e.OldSHA256 == e.NewSHA256 {
+		return "content unchanged"
+	}
+	return fmt.Sprintf("%d -> %d bytes", e.OldSize, e.NewSize)
+}
+
+// jsonValue formats a metadata value as compact JSON.
+func jsonValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
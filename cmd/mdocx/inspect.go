@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// headerFlagNames names the header flags in bit order.
+var headerFlagNames = []struct {
+	flag uint16
+	name string
+}{
+	{mdocx.HeaderFlagMetadataJSON, "metadata-json"},
+	{mdocx.HeaderFlagNoMedia, "no-media"},
+	{mdocx.HeaderFlagIndex, "index"},
+	{mdocx.HeaderFlagChecksum, "checksum"},
+	{mdocx.HeaderFlagCBOR, "cbor"},
+}
+
+// sectionResult describes a section in the output of inspect.
+type sectionResult struct {
+	Compression     string `json:"compression"`
+	PayloadLen      uint64 `json:"payload_len"`
+	UncompressedLen uint64 `json:"uncompressed_len"`
+}
+
+// inspectResult is the JSON output of inspect.
+type inspectResult struct {
+	File           string         `json:"file"`
+	Size           int64          `json:"size"`
+	Version        uint16         `json:"version"`
+	Flags          []string       `json:"flags"`
+	MetadataLength uint32         `json:"metadata_length"`
+	Metadata       map[string]any `json:"metadata,omitempty"`
+	Markdown       sectionResult  `json:"markdown"`
+	Media          sectionResult  `json:"media"`
+}
+
+func runInspect(c *cli, args []string) error {
+	fs := c.flags()
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	f, err := c.open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// DecodeHeader seeks past the Markdown section of a file. The size of
+	// stdin is only known once it has been read to the end.
+	file, isFile := f.(*os.File)
+	cr := &countingReader{r: f}
+	var r io.Reader = cr
+	if isFile {
+		r = file
+	}
+	h, err := mdocx.DecodeHeader(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(path), err)
+	}
+	var size int64
+	if isFile {
+		fi, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		size = fi.Size()
+	} else {
+		if _, err := io.Copy(io.Discard, cr); err != nil {
+			return err
+		}
+		size = cr.n
+	}
+
+	res := inspectResult{
+		File:           path,
+		Size:           size,
+		Version:        h.Version,
+		Flags:          []string{},
+		MetadataLength: h.MetadataLength,
+		Metadata:       h.Metadata,
+		Markdown:       sectionResult{compressionName(h.Markdown.Compression), h.Markdown.PayloadLen, h.Markdown.UncompressedLen},
+		Media:          sectionResult{compressionName(h.Media.Compression), h.Media.PayloadLen, h.Media.UncompressedLen},
+	}
+	for _, fl := range headerFlagNames {
+		if h.Flags&fl.flag != 0 {
+			res.Flags = append(res.Flags, fl.name)
+		}
+	}
+	if c.json {
+		return c.printJSON(res)
+	}
+
+	fmt.Fprintf(c.stdout, "file:      %s (%d bytes)\n", res.File, res.Size)
+	fmt.Fprintf(c.stdout, "version:   %d\n", res.Version)
+	fmt.Fprintf(c.stdout, "flags:     %v\n", res.Flags)
+	for _, s := range []struct {
+		name string
+		sec  sectionResult
+	}{{"markdown", res.Markdown}, {"media", res.Media}} {
+		fmt.Fprintf(c.stdout, "%-10s %s, %d bytes stored, %d bytes uncompressed\n", s.name+":", s.sec.Compression, s.sec.PayloadLen, s.sec.UncompressedLen)
+	}
+	if len(res.Metadata) == 0 {
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "metadata:  %d bytes\n", res.MetadataLength)
+	keys := make([]string, 0, len(res.Metadata))
+	for k := range res.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, _ := json.Marshal(res.Metadata[k])
+		fmt.Fprintf(c.stdout, "  %s: %s\n", k, v)
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// lsEntry is an element of the JSON output of ls.
+type lsEntry struct {
+	Kind     string `json:"kind"`
+	Path     string `json:"path,omitempty"`
+	ID       string `json:"id,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+func runLs(c *cli, args []string) error {
+	fs := c.flags()
+	long := fs.Bool("l", false, "also print kind, size, MIME type, and media ID")
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	doc, err := c.openDocument(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	mimeTypes := make(map[string]string, len(doc.Media.Items))
+	for _, it := range doc.Media.Items {
+		mimeTypes[it.ID] = it.MIMEType
+	}
+	inv := doc.Inventory()
+	entries := make([]lsEntry, len(inv))
+	for i, e := range inv {
+		entries[i] = lsEntry{Kind: e.Kind, Path: e.Path, ID: e.ID, Size: e.Size, SHA256: e.SHA256}
+		if e.Kind == mdocx.InventoryMedia {
+			entries[i].MIMEType = mimeTypes[e.ID]
+		}
+	}
+
+	if c.json {
+		return c.printJSON(entries)
+	}
+	if !*long {
+		for _, e := range entries {
+			fmt.Fprintln(c.stdout, listName(e))
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	for _, e := range entries {
+		mt, id := e.MIMEType, e.ID
+		if e.Kind == mdocx.InventoryMarkdown {
+			mt = "text/markdown"
+		}
+		if id == "" {
+			id = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", e.Kind, e.Size, mt, id, listName(e))
+	}
+	return tw.Flush()
+}
+
+// listName returns the name ls prints for e: its path, or "media/<ID>" for
+// media items without a path, as Extract names them.
+func listName(e lsEntry) string {
+	if e.Path == "" {
+		return "media/" + e.ID
+	}
+	return e.Path
+}
@@ -0,0 +1,238 @@
+// Command mdocx packs, unpacks, inspects, validates, compares, rehashes, and
+// displays MDOCX containers.
+//
+// Usage:
+//
+//	mdocx <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	pack      pack a directory into a container
+//	unpack    extract a container into a directory
+//	inspect   print the header, metadata, and section sizes of a container
+//	validate  check containers and report every problem
+//	ls        list the Markdown files and media items of a container
+//	cat       print a Markdown file or media item of a container
+//	diff      list the differences between two containers
+//	rehash    refresh the stored media hashes of a container
+//	view      read a Markdown file of a container in the terminal
+//
+// Every command accepts -json to print machine-readable output instead of
+// text. A container file argument of "-" reads the container from standard
+// input, so that mdocx can be used in pipelines:
+//
+//	curl -s https://example.com/guide.mdocx | mdocx ls -
+//
+// pack reads a single Markdown file from standard input when its directory is
+// "-", and writes the container to standard output with -o -. The exit status is 0 on success, 1 if a command fails or a container
+// is invalid, and 2 for usage errors.
+//
+// view renders the root Markdown file, or the one named, with ANSI styles,
+// pausing after every screenful, and shows images inline in terminals that
+// support the kitty or iTerm2 image protocols.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// command is a subcommand of mdocx.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+// commands lists the subcommands in the order they are documented.
+var commands = []command{
+	{"pack", "[flags] <dir>", "pack a directory into a container", runPack},
+	{"unpack", "[flags] <file>", "extract a container into a directory", runUnpack},
+	{"inspect", "[flags] <file>", "print the header, metadata, and section sizes of a container", runInspect},
+	{"validate", "[flags] <file>...", "check containers and report every problem", runValidate},
+	{"ls", "[flags] <file>", "list the Markdown files and media items of a container", runLs},
+	{"cat", "[flags] <file> <path>", "print a Markdown file or media item of a container", runCat},
+	{"diff", "[flags] <old> <new>", "list the differences between two containers", runDiff},
+	{"rehash", "[flags] <file>", "refresh the stored media hashes of a container", runRehash},
+	{"view", "[flags] <file> [path]", "read a Markdown file of a container in the terminal", runView},
+}
+
+// cli holds the state of a running command.
+type cli struct {
+	cmd    command
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// json is set by the -json flag of every command.
+	json bool
+	// stdinUsed is set once an argument of "-" has consumed stdin.
+	stdinUsed bool
+}
+
+// errUsage reports a command line error; its usage has already been printed.
+var errUsage = errors.New("usage error")
+
+// errFailed reports a failure whose details have already been printed.
+var errFailed = errors.New("failed")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(&cli{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr}, args[1:])
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			return 2
+		case !errors.Is(err, errFailed):
+			fmt.Fprintf(stderr, "mdocx %s: %v\n", cmd.name, err)
+		}
+		return 1
+	}
+	fmt.Fprintf(stderr, "mdocx: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: mdocx <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun 'mdocx <command> -h' for the flags of a command.\n")
+}
+
+// flags returns the flag set of the command, with the -json flag that every
+// command accepts.
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("mdocx "+c.cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(&c.json, "json", false, "print JSON output")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: mdocx %s %s\n\n%s.\n\nFlags:\n", c.cmd.name, c.cmd.args, strings.ToUpper(c.cmd.summary[:1])+c.cmd.summary[1:])
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args with fs and checks that between minArgs and maxArgs positional
+// arguments remain; maxArgs < 0 means no upper bound.
+func (c *cli) parse(fs *flag.FlagSet, args []string, minArgs, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if n := fs.NArg(); n < minArgs || (maxArgs >= 0 && n > maxArgs) {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// flagError reports an invalid flag value and returns errUsage.
+func (c *cli) flagError(err error) error {
+	fmt.Fprintf(c.stderr, "mdocx %s: %v\n", c.cmd.name, err)
+	return errUsage
+}
+
+// printJSON writes v to stdout as indented JSON.
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// open opens the file at path for reading, or returns stdin if path is "-".
+// stdin can only be read once per command.
+func (c *cli) open(path string) (io.ReadCloser, error) {
+	if path != "-" {
+		return os.Open(path)
+	}
+	if c.stdinUsed {
+		return nil, errors.New("standard input (-) can only be read once")
+	}
+	c.stdinUsed = true
+	return io.NopCloser(c.stdin), nil
+}
+
+// displayName returns the name of the file at path in messages.
+func displayName(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+	return path
+}
+
+// openDocument decodes the container file at path, or stdin if path is "-".
+func (c *cli) openDocument(path string, opts ...mdocx.ReadOption) (*mdocx.Document, error) {
+	f, err := c.open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if path == "-" {
+		// Check any integrity trailer as the container streams in rather
+		// than buffering all of stdin first.
+		opts = append(opts, mdocx.WithStreamingInput(true))
+	}
+	doc, err := mdocx.Decode(f, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", displayName(path), err)
+	}
+	return doc, nil
+}
+
+// compressionNames maps command line names to compression algorithms.
+var compressionNames = map[string]mdocx.Compression{
+	"none": mdocx.CompNone,
+	"zip":  mdocx.CompZIP,
+	"zstd": mdocx.CompZSTD,
+	"lz4":  mdocx.CompLZ4,
+	"br":   mdocx.CompBR,
+	"xz":   mdocx.CompXZ,
+}
+
+// compressionName returns the command line name of c.
+func compressionName(c mdocx.Compression) string {
+	for name, v := range compressionNames {
+		if v == c {
+			return name
+		}
+	}
+	return fmt.Sprintf("unknown(%d)", c)
+}
+
+// parseCompression parses a compression flag value.
+func parseCompression(flagName, s string) (mdocx.Compression, error) {
+	c, ok := compressionNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("-%s: unknown compression %q (want none, zip, zstd, lz4, br, or xz)", flagName, s)
+	}
+	return c, nil
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// runCLI runs the command line args and returns the exit status and output.
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	return runStdin(t, nil, args...)
+}
+
+// runStdin is runCLI with stdin.
+func runStdin(t *testing.T, stdin []byte, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, bytes.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// packSample packs a small directory and returns the path of the container.
+func packSample(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	src := filepath.Join(dir, "site")
+	if err := os.MkdirAll(filepath.Join(src, "img"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"index.md":     "# Home\n\n![logo](img/logo.png)\n",
+		"notes.txt":    "not packed\n",
+		"img/logo.png": "\x89PNG\r\n\x1a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(src, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := filepath.Join(dir, "site.mdocx")
+	code, stdout, stderr := runCLI(t, "pack", "-title", "Site", "-exclude", "*.txt", "-o", out, src)
+	if code != 0 {
+		t.Fatalf("pack: exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "1 markdown files and 1 media items") {
+		t.Errorf("pack output = %q", stdout)
+	}
+	return out
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"frobnicate"},
+		{"ls"},
+		{"cat", "a.mdocx"},
+		{"pack", "-md-compression", "gzip", "dir"},
+		{"inspect", "-nope", "a.mdocx"},
+	} {
+		if code, _, stderr := runCLI(t, args...); code != 2 || stderr == "" {
+			t.Errorf("%q: exit %d, stderr %q; want 2 with usage", args, code, stderr)
+		}
+	}
+	if code, _, stderr := runCLI(t, "help"); code != 0 || !strings.Contains(stderr, "validate") {
+		t.Errorf("help: exit %d, stderr %q", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "ls", "-h"); code != 0 {
+		t.Errorf("ls -h: exit %d, want 0", code)
+	}
+}
+
+func TestLsAndCat(t *testing.T) {
+	file := packSample(t)
+
+	code, stdout, stderr := runCLI(t, "ls", file)
+	if code != 0 || stdout != "index.md\nimg/logo.png\n" {
+		t.Errorf("ls: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCLI(t, "ls", "-json", file)
+	var entries []lsEntry
+	if code != 0 || json.Unmarshal([]byte(stdout), &entries) != nil || len(entries) != 2 {
+		t.Fatalf("ls -json: exit %d, stdout %q", code, stdout)
+	}
+	if e := entries[1]; e.Kind != "media" || e.Path != "img/logo.png" || e.MIMEType != "image/png" || e.Size != 8 {
+		t.Errorf("ls -json media entry = %+v", e)
+	}
+
+	code, stdout, _ = runCLI(t, "cat", file, "index.md")
+	if code != 0 || !strings.HasPrefix(stdout, "# Home\n") {
+		t.Errorf("cat index.md: exit %d, stdout %q", code, stdout)
+	}
+
+	id := entries[1].ID
+	for _, name := range []string{"img/logo.png", id, "mdocx://media/" + id} {
+		code, stdout, _ = runCLI(t, "cat", file, name)
+		if code != 0 || stdout != "\x89PNG\r\n\x1a\n" {
+			t.Errorf("cat %s: exit %d, stdout %q", name, code, stdout)
+		}
+	}
+
+	code, stdout, _ = runCLI(t, "cat", "-json", file, id)
+	var res catResult
+	if code != 0 || json.Unmarshal([]byte(stdout), &res) != nil || res.Kind != "media" || string(res.Data) != "\x89PNG\r\n\x1a\n" {
+		t.Errorf("cat -json: exit %d, stdout %q", code, stdout)
+	}
+
+	if code, _, stderr = runCLI(t, "cat", file, "missing.md"); code != 1 || !strings.Contains(stderr, "missing.md") {
+		t.Errorf("cat missing.md: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	file := packSample(t)
+	code, stdout, stderr := runCLI(t, "inspect", "-json", file)
+	var res inspectResult
+	if code != 0 {
+		t.Fatalf("inspect: exit %d: %s", code, stderr)
+	}
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil {
+		t.Fatalf("inspect -json: %v\n%s", err, stdout)
+	}
+	if res.Version != 1 || res.Media.Compression != "zstd" || res.Markdown.Compression != "zstd" || res.Metadata["title"] != "Site" {
+		t.Errorf("inspect -json = %+v", res)
+	}
+
+	code, stdout, _ = runCLI(t, "inspect", file)
+	if code != 0 || !strings.Contains(stdout, "metadata-json") || !strings.Contains(stdout, `title: "Site"`) {
+		t.Errorf("inspect: exit %d, stdout %q", code, stdout)
+	}
+
+	cbor := filepath.Join(t.TempDir(), "cbor.mdocx")
+	if code, _, stderr := runCLI(t, "pack", "-cbor", "-o", cbor, filepath.Join(filepath.Dir(file), "site")); code != 0 {
+		t.Fatalf("pack -cbor: exit %d: %s", code, stderr)
+	}
+	if code, stdout, _ = runCLI(t, "inspect", cbor); code != 0 || !strings.Contains(stdout, "cbor") {
+		t.Errorf("inspect CBOR: exit %d, stdout %q", code, stdout)
+	}
+}
+
+func TestPackAutoSuggest(t *testing.T) {
+	file := packSample(t)
+	src := filepath.Join(filepath.Dir(file), "site")
+	out := filepath.Join(t.TempDir(), "suggested.mdocx")
+	code, stdout, stderr := runCLI(t, "pack", "-auto-suggest", "-md-compression", "lz4", "-o", out, src)
+	if code != 0 {
+		t.Fatalf("pack -auto-suggest: exit %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "section") || !strings.Contains(stdout, "markdown  lz4") || !strings.Contains(stdout, "packed 1 markdown files") {
+		t.Errorf("pack -auto-suggest output = %q", stdout)
+	}
+
+	code, stdout, _ = runCLI(t, "pack", "-json", "-auto-suggest", "-o", out, src)
+	var res packResult
+	if code != 0 || json.Unmarshal([]byte(stdout), &res) != nil || res.Suggestion == nil || len(res.Suggestion.Trials) != 12 {
+		t.Fatalf("pack -json -auto-suggest: exit %d, stdout %q", code, stdout)
+	}
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h, err := mdocx.DecodeHeader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := compressionName(h.Markdown.Compression) + "," + compressionName(h.Media.Compression); got != res.Suggestion.Markdown+","+res.Suggestion.Media {
+		t.Errorf("packed with %s, suggested %+v", got, res.Suggestion)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	file := packSample(t)
+	if code, stdout, stderr := runCLI(t, "validate", "-checks", "all", file); code != 0 || !strings.Contains(stdout, ": ok") {
+		t.Errorf("validate: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(t.TempDir(), "bad.mdocx")
+	if err := os.WriteFile(bad, data[:len(data)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, _ := runCLI(t, "validate", "-json", "-jobs", "2", file, bad)
+	var rep validateReport
+	if err := json.Unmarshal([]byte(stdout), &rep); err != nil {
+		t.Fatalf("validate -json: %v\n%s", err, stdout)
+	}
+	if code != 1 || rep.Total != 2 || rep.Valid != 1 || rep.Invalid != 1 {
+		t.Fatalf("validate -json: exit %d, report %+v", code, rep)
+	}
+	for _, res := range rep.Results {
+		if res.Valid != (res.Path == file) || res.Valid != (len(res.Problems) == 0) {
+			t.Errorf("validate -json: result %+v", res)
+		}
+		if res.Path == bad && res.ErrorKind != "truncated" {
+			t.Errorf("validate -json: error kind %q", res.ErrorKind)
+		}
+	}
+
+	if code, _, stderr := runCLI(t, "validate", "-checks", "bogus", file); code != 2 || !strings.Contains(stderr, "bogus") {
+		t.Errorf("validate -checks bogus: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	file := packSample(t)
+	dir := t.TempDir()
+
+	code, stdout, stderr := runCLI(t, "unpack", "-dry-run", "-o", dir, file)
+	if code != 0 || !strings.Contains(stdout, "index.md") {
+		t.Errorf("unpack -dry-run: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.md")); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote files: %v", err)
+	}
+
+	if code, _, stderr = runCLI(t, "unpack", "-metadata", "-o", dir, file); code != 0 {
+		t.Fatalf("unpack: exit %d: %s", code, stderr)
+	}
+	for _, name := range []string{"index.md", "img/logo.png", "metadata.json"} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("unpack did not write %s: %v", name, err)
+		}
+	}
+
+	if code, _, _ = runCLI(t, "unpack", "-on-conflict", "error", "-o", dir, file); code != 1 {
+		t.Errorf("unpack over existing files with -on-conflict error: exit %d, want 1", code)
+	}
+
+	// metadata.json follows -on-conflict like the extracted files.
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "metadata.json"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ = runCLI(t, "unpack", "-metadata", "-o", dir, file); code != 1 {
+		t.Errorf("unpack -metadata over an existing metadata.json: exit %d, want 1", code)
+	}
+	if code, stdout, stderr = runCLI(t, "unpack", "-metadata", "-on-conflict", "rename", "-o", dir, file); code != 0 || !strings.Contains(stdout, "metadata-1.json") {
+		t.Errorf("unpack -metadata -on-conflict rename: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "metadata.json")); string(b) != "keep" {
+		t.Errorf("metadata.json was overwritten: %q", b)
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	old := packSample(t)
+	dir := t.TempDir()
+	if code, _, stderr := runCLI(t, "unpack", "-o", dir, old); code != 0 {
+		t.Fatalf("unpack: %s", stderr)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.md"), []byte("# Home v2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.md"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	updated := filepath.Join(t.TempDir(), "new.mdocx")
+	if code, _, stderr := runCLI(t, "pack", "-title", "Site 2", "-o", updated, dir); code != 0 {
+		t.Fatalf("pack: %s", stderr)
+	}
+
+	code, stdout, stderr := runCLI(t, "diff", old, updated)
+	if code != 0 {
+		t.Fatalf("diff: exit %d: %s", code, stderr)
+	}
+	for _, want := range []string{"changed  markdown  index.md", "added    markdown  new.md", `title     "Site" -> "Site 2"`} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("diff output lacks %q:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, _ = runCLI(t, "diff", "-json", old, old)
+	if code != 0 || !strings.Contains(stdout, `"markdown": []`) {
+		t.Errorf("diff -json of identical files: exit %d, stdout %q", code, stdout)
+	}
+}
+
+func TestRehash(t *testing.T) {
+	file := packSample(t)
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := mdocx.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Patch the media bytes the way an external tool would, leaving the hash.
+	doc.Media.Items[0].Data = append(doc.Media.Items[0].Data, 0)
+	if err := mdocx.EncodeFile(file, doc, mdocx.WithVerifyHashesOnWrite(false)); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, _ := runCLI(t, "validate", file); code != 1 {
+		t.Fatalf("validate of patched file: exit %d, want 1", code)
+	}
+
+	if code, _, stderr := runCLI(t, "rehash", "-id", "missing", file); code != 1 || !strings.Contains(stderr, "missing") {
+		t.Errorf("rehash -id missing: exit %d, stderr %q", code, stderr)
+	}
+	code, stdout, stderr := runCLI(t, "rehash", file)
+	if code != 0 || !strings.Contains(stdout, "updated "+doc.Media.Items[0].ID) || !strings.Contains(stdout, "1 of 1 media item hashes updated") {
+		t.Fatalf("rehash: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := runCLI(t, "validate", file); code != 0 || !strings.Contains(stdout, ": ok") {
+		t.Errorf("validate after rehash: exit %d, stdout %q", code, stdout)
+	}
+
+	out := filepath.Join(t.TempDir(), "copy.mdocx")
+	code, stdout, _ = runCLI(t, "rehash", "-json", "-o", out, file)
+	var res rehashResult
+	if code != 0 || json.Unmarshal([]byte(stdout), &res) != nil || len(res.Changed) != 0 || res.MediaItems != 1 {
+		t.Errorf("rehash -json of a current file: exit %d, stdout %q", code, stdout)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("rehash -o did not write the output: %v", err)
+	}
+}
+
+func TestRehashAuditLog(t *testing.T) {
+	file := packSample(t)
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := mdocx.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AppendAuditEvent(mdocx.AuditEvent{Tool: "test", Operation: "create"}); err != nil {
+		t.Fatal(err)
+	}
+	doc.Media.Items[0].SHA256 = [32]byte{1}
+	if err := mdocx.EncodeFile(file, doc, mdocx.WithVerifyHashesOnWrite(false)); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runCLI(t, "rehash", file); code != 0 {
+		t.Fatalf("rehash: exit %d: %s", code, stderr)
+	}
+	f, err = os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mdocx.Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := got.AuditLog()
+	if err != nil || len(events) != 2 || events[1].Tool != "mdocx-rehash" || events[1].Operation != "rehash: 1 media item hashes updated" {
+		t.Fatalf("audit log = %+v, %v", events, err)
+	}
+	if err := got.VerifyAuditLog(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRehashKeepsFormat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "site")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	svg := "<svg xmlns=\"http://www.w3.org/2000/svg\">" + strings.Repeat("<rect/>", 100) + "</svg>"
+	for name, content := range map[string]string{"index.md": "# Home\n\n![icon](icon.svg)\n", "icon.svg": svg} {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packed := filepath.Join(dir, "site.mdocx")
+	if code, _, stderr := runCLI(t, "pack", "-cbor", "-smart-media", "-checksum", "-o", packed, src); code != 0 {
+		t.Fatalf("pack: exit %d: %s", code, stderr)
+	}
+	v2 := filepath.Join(dir, "v2.mdocx")
+	doc := &mdocx.Document{
+		Markdown: mdocx.MarkdownBundle{BundleVersion: mdocx.VersionV1, Files: []mdocx.MarkdownFile{{Path: "index.md", Content: []byte("# V2\n")}}},
+		Media:    mdocx.MediaBundle{BundleVersion: mdocx.VersionV1},
+	}
+	if err := mdocx.EncodeFile(v2, doc, mdocx.WithFormatVersion(mdocx.VersionV2)); err != nil {
+		t.Fatal(err)
+	}
+
+	header := func(file string) *mdocx.Header {
+		t.Helper()
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		h, err := mdocx.DecodeHeader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	for _, file := range []string{packed, v2} {
+		out := filepath.Join(dir, "rehashed.mdocx")
+		if code, _, stderr := runCLI(t, "rehash", "-o", out, file); code != 0 {
+			t.Fatalf("rehash %s: exit %d: %s", file, code, stderr)
+		}
+		before, after := header(file), header(out)
+		if after.Version != before.Version || after.Flags != before.Flags || after.Capabilities != before.Capabilities ||
+			after.Media.Compression != before.Media.Compression || after.Media.ItemCompression != before.Media.ItemCompression {
+			t.Errorf("rehash of %s changed the header\nfrom %+v\n  to %+v", file, before, after)
+		}
+	}
+	if h := header(packed); h.PayloadFormat() != mdocx.PayloadCBOR || !h.Media.ItemCompression {
+		t.Fatalf("packed header = %+v", h)
+	}
+}
+
+func TestStdio(t *testing.T) {
+	code, container, stderr := runStdin(t, []byte("# Piped\n"), "pack", "-title", "Piped", "-")
+	if code != 0 {
+		t.Fatalf("pack -: exit %d: %s", code, stderr)
+	}
+	if !strings.HasPrefix(container, "MDOCX\r\n") {
+		t.Fatalf("pack - wrote %q", container)
+	}
+	in := []byte(container)
+
+	if code, stdout, _ := runStdin(t, in, "ls", "-"); code != 0 || stdout != "index.md\n" {
+		t.Errorf("ls -: exit %d, stdout %q", code, stdout)
+	}
+	if code, stdout, _ := runStdin(t, in, "cat", "-", "index.md"); code != 0 || stdout != "# Piped\n" {
+		t.Errorf("cat -: exit %d, stdout %q", code, stdout)
+	}
+	if code, stdout, _ := runStdin(t, in, "validate", "-"); code != 0 || stdout != "-: ok\n" {
+		t.Errorf("validate -: exit %d, stdout %q", code, stdout)
+	}
+	code, stdout, _ := runStdin(t, in, "inspect", "-json", "-")
+	var res inspectResult
+	if code != 0 || json.Unmarshal([]byte(stdout), &res) != nil || res.Size != int64(len(in)) || res.Metadata["title"] != "Piped" {
+		t.Errorf("inspect -: exit %d, stdout %q", code, stdout)
+	}
+
+	file := packSample(t)
+	if code, stdout, _ := runStdin(t, in, "diff", "-", file); code != 0 || !strings.Contains(stdout, "changed  markdown  index.md") || !strings.Contains(stdout, "added    media     img/logo.png") {
+		t.Errorf("diff - file: exit %d, stdout %q", code, stdout)
+	}
+	if code, _, stderr := runStdin(t, in, "diff", "-", "-"); code != 1 || !strings.Contains(stderr, "only be read once") {
+		t.Errorf("diff - -: exit %d, stderr %q", code, stderr)
+	}
+	if code, _, stderr := runStdin(t, []byte("junk"), "ls", "-"); code != 1 || !strings.Contains(stderr, "<stdin>") {
+		t.Errorf("ls - with junk: exit %d, stderr %q", code, stderr)
+	}
+
+	out := filepath.Join(t.TempDir(), "piped.mdocx")
+	if code, stdout, stderr := runStdin(t, []byte("text"), "pack", "-root", "a/readme.md", "-o", out, "-"); code != 0 || !strings.Contains(stdout, out) {
+		t.Fatalf("pack - -o file: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	if code, stdout, _ := runCLI(t, "ls", out); code != 0 || stdout != "a/readme.md\n" {
+		t.Errorf("ls: exit %d, stdout %q", code, stdout)
+	}
+}
+
+func TestView(t *testing.T) {
+	file := packSample(t)
+	code, stdout, stderr := runCLI(t, "view", file)
+	if code != 0 || stdout != "# Home\n\n[image: logo]\n" {
+		t.Fatalf("view: exit %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	code, stdout, _ = runCLI(t, "view", "-color", "always", "-images", "kitty", file)
+	if code != 0 || !strings.Contains(stdout, "\x1b[1;4mHome\x1b[22;24m") || !strings.Contains(stdout, "\x1b_Gf=100,a=T,r=12,m=0;iVBORw0KGgo=\x1b\\") {
+		t.Errorf("view with styles and kitty images: exit %d, stdout %q", code, stdout)
+	}
+	code, stdout, _ = runCLI(t, "view", "-images", "iterm", file)
+	if code != 0 || !strings.Contains(stdout, "\x1b]1337;File=inline=1;size=8;") {
+		t.Errorf("view with iTerm images: exit %d, stdout %q", code, stdout)
+	}
+	code, stdout, _ = runCLI(t, "view", "-json", file)
+	var res viewResult
+	if code != 0 || json.Unmarshal([]byte(stdout), &res) != nil || res.Path != "index.md" || len(res.Lines) != 3 {
+		t.Errorf("view -json: exit %d, stdout %q", code, stdout)
+	}
+	if code, _, _ := runCLI(t, "view", file, "missing.md"); code != 1 {
+		t.Errorf("view missing.md: exit %d", code)
+	}
+	if code, _, _ := runCLI(t, "view", "-images", "sixel", file); code != 2 {
+		t.Errorf("view -images sixel: exit %d", code)
+	}
+
+	// Wrapping, lists, and escapes from the container.
+	src := "Some *words* that wrap\x1b[2J.\n\n- a\n- b\n\n3. c\n"
+	_, container, _ := runStdin(t, []byte(src), "pack", "-")
+	code, stdout, _ = runStdin(t, []byte(container), "view", "-width", "12", "-")
+	if want := "Some words\nthat\nwrap�[2J.\n\n• a\n• b\n\n3. c\n"; code != 0 || stdout != want {
+		t.Errorf("view -width 12: exit %d, stdout %q, want %q", code, stdout, want)
+	}
+}
+
+func TestPage(t *testing.T) {
+	rows := make([]viewRow, 10)
+	for i := range rows {
+		rows[i].text = fmt.Sprint(i)
+	}
+	var out bytes.Buffer
+	if err := page(&out, strings.NewReader("\nq\n"), rows, 5); err != nil {
+		t.Fatal(err)
+	}
+	s := out.String()
+	if strings.Count(s, "-- more --") != 2 || !strings.Contains(s, "7\n") || strings.Contains(s, "8\n") {
+		t.Errorf("page = %q", s)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// listFlag is a repeatable string flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// packResult is the JSON output of pack.
+type packResult struct {
+	Output        string         `json:"output"`
+	MarkdownFiles int            `json:"markdown_files"`
+	MediaItems    int            `json:"media_items"`
+	Size          int64          `json:"size"`
+	Suggestion    *suggestResult `json:"suggestion,omitempty"`
+}
+
+// suggestResult is the outcome of pack -auto-suggest: the compression of each
+// section and the SuggestCompression trials behind it.
+type suggestResult struct {
+	Markdown string        `json:"markdown"`
+	Media    string        `json:"media"`
+	Trials   []trialResult `json:"trials"`
+}
+
+// trialResult is one mdocx.CompressionTrial.
+type trialResult struct {
+	Section        string  `json:"section"`
+	Compression    string  `json:"compression"`
+	SampleSize     int     `json:"sample_size"`
+	CompressedSize int     `json:"compressed_size"`
+	Ratio          float64 `json:"ratio"`
+	DurationMS     float64 `json:"duration_ms"`
+
+	duration time.Duration
+}
+
+// newSuggestResult returns the trials of s and the algorithms pack uses.
+func newSuggestResult(s *mdocx.CompressionSuggestion, md, media mdocx.Compression) *suggestResult {
+	res := &suggestResult{Markdown: compressionName(md), Media: compressionName(media), Trials: []trialResult{}}
+	for _, sec := range []struct {
+		name   string
+		trials []mdocx.CompressionTrial
+	}{{"markdown", s.Markdown}, {"media", s.Media}} {
+		for _, t := range sec.trials {
+			res.Trials = append(res.Trials, trialResult{
+				Section:        sec.name,
+				Compression:    compressionName(t.Compression),
+				SampleSize:     t.SampleSize,
+				CompressedSize: t.CompressedSize,
+				Ratio:          t.Ratio,
+				DurationMS:     float64(t.Duration) / float64(time.Millisecond),
+				duration:       t.Duration,
+			})
+		}
+	}
+	return res
+}
+
+// print writes the trials as a table, marking the algorithms used.
+func (r *suggestResult) print(w io.Writer) {
+	fmt.Fprintf(w, "%-8s  %-4s  %6s  %10s\n", "section", "comp", "ratio", "time")
+	for _, t := range r.Trials {
+		mark := ""
+		if (t.Section == "markdown" && t.Compression == r.Markdown) || (t.Section == "media" && t.Compression == r.Media) {
+			mark = "  *"
+		}
+		fmt.Fprintf(w, "%-8s  %-4s  %6.3f  %10s%s\n", t.Section, t.Compression, t.Ratio, t.duration.Round(time.Microsecond), mark)
+	}
+}
+
+func runPack(c *cli, args []string) error {
+	fs := c.flags()
+	out := fs.String("o", "", "output file, or - for stdout (default <dir>"+mdocx.FileExtension+", or - if <dir> is -)")
+	root := fs.String("root", "", "container path of the root Markdown file")
+	title := fs.String("title", "", "title metadata")
+	mdComp := fs.String("md-compression", "zstd", "Markdown section compression: none, zip, zstd, lz4, br, or xz")
+	mediaComp := fs.String("media-compression", "zstd", "media section compression: none, zip, zstd, lz4, br, or xz")
+	smart := fs.Bool("smart-media", false, "compress text-like media items one at a time and store the others (see -media-compression)")
+	checksum := fs.Bool("checksum", false, "append a CRC-32C integrity trailer")
+	cbor := fs.Bool("cbor", false, "serialize the bundles as CBOR instead of gob")
+	autoRefs := fs.Bool("auto-media-refs", false, "fill MediaRefs from the media references in Markdown content")
+	suggest := fs.Bool("auto-suggest", false, "trial-compress samples of each section, print the results, and use the suggested algorithms unless -md-compression or -media-compression is set")
+	var include, exclude listFlag
+	fs.Var(&include, "include", "only pack files matching this glob (repeatable)")
+	fs.Var(&exclude, "exclude", "skip files and directories matching this glob (repeatable)")
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	dir := fs.Arg(0)
+	md, err := parseCompression("md-compression", *mdComp)
+	if err != nil {
+		return c.flagError(err)
+	}
+	media, err := parseCompression("media-compression", *mediaComp)
+	if err != nil {
+		return c.flagError(err)
+	}
+	if *out == "" {
+		if dir == "-" {
+			*out = "-"
+		} else {
+			abs, err := filepath.Abs(dir)
+			if err != nil {
+				return err
+			}
+			*out = filepath.Base(abs) + mdocx.FileExtension
+		}
+	}
+
+	var doc *mdocx.Document
+	if dir == "-" {
+		content, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		p := *root
+		if p == "" {
+			p = mdocx.SinglePath
+		}
+		doc = &mdocx.Document{
+			Markdown: mdocx.MarkdownBundle{BundleVersion: mdocx.VersionV1, Files: []mdocx.MarkdownFile{{Path: p, Content: content}}},
+			Media:    mdocx.MediaBundle{BundleVersion: mdocx.VersionV1},
+		}
+	} else {
+		doc, err = mdocx.FromFS(os.DirFS(dir), mdocx.WithInclude(include...), mdocx.WithExclude(exclude...))
+		if err != nil {
+			return err
+		}
+	}
+	doc.Metadata = map[string]any{"created_at": time.Now().UTC().Format(time.RFC3339)}
+	if *title != "" {
+		doc.Metadata["title"] = *title
+	}
+	if *root != "" {
+		doc.Markdown.RootPath = *root
+		doc.Metadata["root"] = *root
+	}
+	var suggestion *suggestResult
+	if *suggest {
+		s, err := mdocx.SuggestCompression(doc)
+		if err != nil {
+			return err
+		}
+		set := make(map[string]bool)
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["md-compression"] {
+			md = s.MarkdownCompression
+		}
+		if !set["media-compression"] {
+			media = s.MediaCompression
+		}
+		suggestion = newSuggestResult(s, md, media)
+		switch {
+		case *out == "-":
+			suggestion.print(c.stderr)
+		case !c.json:
+			suggestion.print(c.stdout)
+		}
+	}
+	opts := []mdocx.WriteOption{
+		mdocx.WithMarkdownCompression(md),
+		mdocx.WithMediaCompression(media),
+		mdocx.WithSmartMediaCompression(*smart),
+		mdocx.WithChecksum(*checksum),
+		mdocx.WithAutoMediaRefs(*autoRefs),
+	}
+	if *cbor {
+		opts = append(opts, mdocx.WithPayloadFormat(mdocx.PayloadCBOR))
+	}
+	if *out == "-" {
+		// The container is the output; there is no room for a summary.
+		return mdocx.Encode(c.stdout, doc, opts...)
+	}
+	if err := mdocx.EncodeFile(*out, doc, opts...); err != nil {
+		return err
+	}
+	fi, err := os.Stat(*out)
+	if err != nil {
+		return err
+	}
+
+	res := packResult{Output: *out, MarkdownFiles: len(doc.Markdown.Files), MediaItems: len(doc.Media.Items), Size: fi.Size(), Suggestion: suggestion}
+	if c.json {
+		return c.printJSON(res)
+	}
+	fmt.Fprintf(c.stdout, "packed %d markdown files and %d media items into %s (%d bytes)\n", res.MarkdownFiles, res.MediaItems, res.Output, res.Size)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// collisionPolicies maps -on-conflict values to collision policies.
+var collisionPolicies = map[string]mdocx.CollisionPolicy{
+	"error":     mdocx.CollisionError,
+	"overwrite": mdocx.CollisionOverwrite,
+	"rename":    mdocx.CollisionRename,
+	"skip":      mdocx.CollisionSkip,
+}
+
+// unpackOp is an element of the JSON output of unpack.
+type unpackOp struct {
+	Action  mdocx.ExtractAction `json:"action"`
+	Source  string              `json:"source"`
+	MediaID string              `json:"media_id,omitempty"`
+	Target  string              `json:"target"`
+	Size    int64               `json:"size"`
+}
+
+func runUnpack(c *cli, args []string) error {
+	fs := c.flags()
+	out := fs.String("o", ".", "output directory")
+	onConflict := fs.String("on-conflict", "error", "what to do with existing files: error, overwrite, rename, or skip")
+	dryRun := fs.Bool("dry-run", false, "print the planned operations without writing")
+	metadata := fs.Bool("metadata", false, "also write the metadata to metadata.json")
+	if err := c.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	policy, ok := collisionPolicies[*onConflict]
+	if !ok {
+		return c.flagError(fmt.Errorf("-on-conflict: unknown value %q (want error, overwrite, rename, or skip)", *onConflict))
+	}
+	doc, err := c.openDocument(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	opts := []mdocx.ExtractOption{mdocx.WithCollisionPolicy(policy), mdocx.WithDryRun(*dryRun)}
+	if *metadata {
+		opts = append(opts, mdocx.WithMetadataFile("metadata.json"))
+	}
+	ops, err := mdocx.Extract(doc, *out, opts...)
+	if err != nil {
+		return err
+	}
+	res := make([]unpackOp, 0, len(ops))
+	for _, op := range ops {
+		res = append(res, unpackOp{Action: op.Action, Source: op.Source, MediaID: op.MediaID, Target: filepath.Join(*out, filepath.FromSlash(op.Target)), Size: op.Size})
+	}
+
+	if c.json {
+		return c.printJSON(res)
+	}
+	for _, op := range res {
+		fmt.Fprintf(c.stdout, "%-9s %s\n", op.Action, op.Target)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// checkNames maps -checks values to optional invariants.
+var checkNames = map[string]mdocx.Check{
+	"media-order":  mdocx.CheckMediaOrder,
+	"media-refs":   mdocx.CheckMediaRefs,
+	"image-links":  mdocx.CheckImageLinks,
+	"orphan-media": mdocx.CheckOrphanMedia,
+	"cross-refs":   mdocx.CheckCrossRefs,
+	"published":    mdocx.CheckPublished,
+	"all":          mdocx.CheckAll,
+}
+
+// parseChecks parses a comma-separated -checks value.
+func parseChecks(s string) (mdocx.Check, error) {
+	var checks mdocx.Check
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		c, ok := checkNames[name]
+		if !ok {
+			return 0, fmt.Errorf("-checks: unknown check %q", name)
+		}
+		checks |= c
+	}
+	return checks, nil
+}
+
+// problem is one validation problem in the output of validate.
+type problem struct {
+	Code    mdocx.ErrorCode `json:"code,omitempty"`
+	Field   string          `json:"field,omitempty"`
+	Path    string          `json:"path,omitempty"`
+	Message string          `json:"message"`
+}
+
+// validateResult is an element of the results of validate -json: the
+// mdocx.BatchResult of a file with the problems found in it.
+type validateResult struct {
+	mdocx.BatchResult
+	Problems []problem `json:"problems,omitempty"`
+}
+
+// validateReport is the JSON output of validate, an mdocx.BatchReport whose
+// results list the problems found.
+type validateReport struct {
+	Total   int              `json:"total"`
+	Valid   int              `json:"valid"`
+	Invalid int              `json:"invalid"`
+	Results []validateResult `json:"results"`
+}
+
+func runValidate(c *cli, args []string) error {
+	fs := c.flags()
+	checksFlag := fs.String("checks", "", "comma-separated optional checks: media-order, media-refs, image-links, orphan-media, cross-refs, all, or published")
+	lang := fs.String("lang", "", "language of the messages, such as de or fr (default English as reported by the library)")
+	jobs := fs.Int("jobs", 0, "number of files to validate at once (default the number of CPUs)")
+	if err := c.parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	checks, err := parseChecks(*checksFlag)
+	if err != nil {
+		return c.flagError(err)
+	}
+	message := func(err error) string {
+		if *lang != "" {
+			return mdocx.LocalizeError(err, *lang)
+		}
+		return err.Error()
+	}
+
+	opts := []mdocx.ValidateOption{mdocx.WithAllErrors(), mdocx.WithValidateChecks(checks)}
+	var paths []string
+	stdin := false
+	for _, path := range fs.Args() {
+		if path == "-" {
+			stdin = true
+		} else {
+			paths = append(paths, path)
+		}
+	}
+	batch, err := mdocx.ValidateFiles(context.Background(), paths, mdocx.Limits{}, *jobs, opts...)
+	if err != nil {
+		return err
+	}
+	if stdin {
+		f, err := c.open("-")
+		if err != nil {
+			return err
+		}
+		batch.Results = append(batch.Results, mdocx.ValidateReader(f, "-", mdocx.Limits{}, opts...))
+		f.Close()
+		sort.SliceStable(batch.Results, func(i, j int) bool { return batch.Results[i].Path < batch.Results[j].Path })
+	}
+
+	rep := validateReport{Results: make([]validateResult, 0, len(batch.Results))}
+	for _, r := range batch.Results {
+		res := validateResult{BatchResult: r}
+		var errs mdocx.ValidationErrors
+		switch {
+		case r.Valid:
+			rep.Valid++
+		case errors.As(r.Err, &errs):
+			for _, e := range errs {
+				res.Problems = append(res.Problems, problem{Code: e.Code, Field: e.Field, Path: e.Path, Message: message(e)})
+			}
+		default:
+			res.Problems = append(res.Problems, problem{Code: mdocx.Code(r.Err), Message: message(r.Err)})
+		}
+		rep.Results = append(rep.Results, res)
+	}
+	rep.Total = len(rep.Results)
+	rep.Invalid = rep.Total - rep.Valid
+
+	if c.json {
+		if err := c.printJSON(rep); err != nil {
+			return err
+		}
+	} else {
+		for _, res := range rep.Results {
+			if res.Valid {
+				fmt.Fprintf(c.stdout, "%s: ok\n", res.Path)
+			}
+			for _, p := range res.Problems {
+				fmt.Fprintf(c.stdout, "%s: %s\n", res.Path, p.Message)
+			}
+		}
+	}
+	if rep.Invalid > 0 {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // converted to PNG for kitty
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/logicossoftware/go-mdocx"
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// viewResult is the JSON output of view: the rendered text without styles or
+// images.
+type viewResult struct {
+	Path  string   `json:"path"`
+	Lines []string `json:"lines"`
+}
+
+// Inline image protocols.
+const (
+	imagesNone  = "none"
+	imagesKitty = "kitty"
+	imagesITerm = "iterm"
+)
+
+// getenv is os.Getenv, replaced in tests.
+var getenv = os.Getenv
+
+func runView(c *cli, args []string) error {
+	fs := c.flags()
+	width := fs.Int("width", 0, "wrap text at this many columns (default $COLUMNS, or 80)")
+	images := fs.String("images", "auto", "inline image protocol: auto, kitty, iterm, or none")
+	color := fs.String("color", "auto", "style text with ANSI escapes: auto, always, or never")
+	noPager := fs.Bool("no-pager", false, "do not pause after every screenful")
+	if err := c.parse(fs, args, 1, 2); err != nil {
+		return err
+	}
+	tty := isTerminal(c.stdout)
+	switch *images {
+	case "auto":
+		*images = imagesNone
+		if tty && !c.json {
+			*images = detectImageProtocol()
+		}
+	case imagesKitty, imagesITerm, imagesNone:
+	default:
+		return c.flagError(fmt.Errorf("-images: unknown protocol %q (want auto, kitty, iterm, or none)", *images))
+	}
+	var style bool
+	switch *color {
+	case "auto":
+		style = tty && getenv("NO_COLOR") == ""
+	case "always", "never":
+		style = *color == "always"
+	default:
+		return c.flagError(fmt.Errorf("-color: unknown mode %q (want auto, always, or never)", *color))
+	}
+	if *width <= 0 {
+		*width = envInt("COLUMNS", 80)
+	}
+
+	doc, err := c.openDocument(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	f, ok := viewedFile(doc, fs.Arg(1))
+	if !ok {
+		return fmt.Errorf("%w: %s: no Markdown file %q", mdocx.ErrNotFound, fs.Arg(0), fs.Arg(1))
+	}
+	height := envInt("LINES", 24)
+	v := &viewer{doc: doc, from: f.Path, width: *width, style: style && !c.json, images: *images, imageRows: min(12, max(height-2, 1))}
+	if c.json {
+		v.images = imagesNone
+	}
+	v.render(f.Content)
+
+	if c.json {
+		res := viewResult{Path: f.Path, Lines: []string{}}
+		for _, r := range v.rows {
+			res.Lines = append(res.Lines, r.text)
+		}
+		return c.printJSON(res)
+	}
+	var keys io.Reader
+	if tty && !*noPager {
+		keys = c.pagerKeys()
+		if cl, ok := keys.(io.Closer); ok {
+			defer cl.Close()
+		}
+	}
+	return page(c.stdout, keys, v.rows, height)
+}
+
+// viewedFile returns the Markdown file at path, or the root file if path is
+// empty.
+func viewedFile(doc *mdocx.Document, path string) (mdocx.MarkdownFile, bool) {
+	if path == "" {
+		path = doc.Markdown.RootPath
+		if path == "" {
+			return doc.Markdown.Files[0], true
+		}
+	}
+	for _, f := range doc.Markdown.Files {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return mdocx.MarkdownFile{}, false
+}
+
+// isTerminal reports whether w is a terminal.
+func isTerminal(w any) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// detectImageProtocol returns the inline image protocol of the terminal, going
+// by the variables terminals set in the environment.
+func detectImageProtocol() string {
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "", getenv("TERM") == "xterm-kitty", getenv("TERM_PROGRAM") == "ghostty":
+		return imagesKitty
+	case getenv("TERM_PROGRAM") == "iTerm.app", getenv("LC_TERMINAL") == "iTerm2", getenv("TERM_PROGRAM") == "WezTerm":
+		return imagesITerm
+	}
+	return imagesNone
+}
+
+// envInt returns the positive integer in the environment variable name, or def.
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(getenv(name)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// pagerKeys returns the terminal the pager reads keys from, or nil if there is
+// none: stdin if it is a terminal that no argument has consumed, or else the
+// controlling terminal.
+func (c *cli) pagerKeys() io.Reader {
+	if !c.stdinUsed && isTerminal(c.stdin) {
+		return c.stdin
+	}
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		return tty
+	}
+	return nil
+}
+
+// viewRow is a row of view output: a line of text, or an image that takes
+// rows terminal rows.
+type viewRow struct {
+	text  string
+	image string
+	rows  int
+}
+
+// page writes rows to w. If keys is not nil it pauses after every screenful of
+// height rows until a line is read from keys, and stops if the line is "q".
+func page(w io.Writer, keys io.Reader, rows []viewRow, height int) error {
+	bw := bufio.NewWriter(w)
+	var kr *bufio.Reader
+	if keys != nil {
+		kr = bufio.NewReader(keys)
+	}
+	used := 0
+	for _, r := range rows {
+		n := max(r.rows, 1)
+		if kr != nil && used > 0 && used+n > height-1 {
+			fmt.Fprint(bw, "\x1b[0m\x1b[7m-- more -- Enter for the next page, q to quit\x1b[0m")
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			line, err := kr.ReadString('\n')
+			if strings.TrimSpace(line) == "q" || err != nil {
+				fmt.Fprintln(bw)
+				return bw.Flush()
+			}
+			// Erase the prompt, which the echoed Enter left a line up.
+			fmt.Fprint(bw, "\x1b[1A\r\x1b[K")
+			used = 0
+		}
+		if r.image != "" {
+			fmt.Fprint(bw, r.image)
+		} else {
+			fmt.Fprint(bw, r.text)
+		}
+		fmt.Fprintln(bw)
+		used += n
+	}
+	return bw.Flush()
+}
+
+// viewer renders a Markdown file of doc as rows of terminal output.
+type viewer struct {
+	doc       *mdocx.Document
+	from      string
+	width     int
+	style     bool
+	images    string
+	imageRows int
+	rows      []viewRow
+	// pending holds the media items of the images met in the current block.
+	pending []*mdocx.MediaItem
+}
+
+// sgr returns s between the SGR escapes on and off, or s alone without styles.
+func (v *viewer) sgr(on, off, s string) string {
+	if !v.style || s == "" {
+		return s
+	}
+	return "\x1b[" + on + "m" + s + "\x1b[" + off + "m"
+}
+
+func (v *viewer) render(src []byte) {
+	src = sanitize(src)
+	lines := strings.Split(string(src), "\n")
+	blocks := mdscan.Blocks(src)
+	for i, b := range blocks {
+		// Items of a tight list are not separated.
+		tight := b.Kind == mdscan.BlockListItem && blocks[max(i-1, 0)].Kind == mdscan.BlockListItem &&
+			b.Line >= 2 && strings.TrimSpace(lines[b.Line-2]) != ""
+		if i > 0 && !tight {
+			v.line("")
+		}
+		switch b.Kind {
+		case mdscan.BlockHeading:
+			marker := v.sgr("2", "22", strings.Repeat("#", b.Level)+" ")
+			on, off := "1", "22"
+			if b.Level == 1 {
+				on, off = "1;4", "22;24"
+			}
+			v.wrap(v.sgr(on, off, v.inline(b.Text)), marker, strings.Repeat(" ", b.Level+1))
+		case mdscan.BlockListItem:
+			marker := "• "
+			if b.Line <= len(lines) {
+				if m, ok := orderedMarker(lines[b.Line-1]); ok {
+					marker = m + " "
+				}
+			}
+			v.wrap(v.inline(b.Text), marker, strings.Repeat(" ", utf8.RuneCountInString(marker)))
+		case mdscan.BlockQuote:
+			bar := v.sgr("2", "22", "│ ")
+			v.wrap(v.sgr("3", "23", v.inline(b.Text)), bar, bar)
+		case mdscan.BlockCode:
+			for _, l := range strings.Split(b.Text, "\n") {
+				v.line("    " + v.sgr("36", "39", l))
+			}
+		case mdscan.BlockTable:
+			v.table(b.Text)
+		default:
+			v.wrap(v.inline(b.Text), "", "")
+		}
+		v.flushImages()
+	}
+}
+
+// sanitize expands tabs in src and replaces the other control characters but
+// newline, so that the container cannot send escapes to the terminal.
+func sanitize(src []byte) []byte {
+	src = bytes.ReplaceAll(src, []byte("\t"), []byte("    "))
+	return bytes.Map(func(r rune) rune {
+		switch {
+		case r == '\n':
+			return r
+		case unicode.IsControl(r):
+			return utf8.RuneError
+		}
+		return r
+	}, src)
+}
+
+// orderedMarker returns the marker of an ordered list item line, such as "1.".
+func orderedMarker(line string) (string, bool) {
+	line = strings.TrimLeft(line, " ")
+	n := 0
+	for n < len(line) && n < 9 && line[n] >= '0' && line[n] <= '9' {
+		n++
+	}
+	if n == 0 || n >= len(line) || (line[n] != '.' && line[n] != ')') {
+		return "", false
+	}
+	return line[:n+1], true
+}
+
+func (v *viewer) line(s string) {
+	v.rows = append(v.rows, viewRow{text: s})
+}
+
+// wrap adds s as lines of at most v.width columns, the first starting with
+// first and the others with rest.
+func (v *viewer) wrap(s, first, rest string) {
+	prefix := first
+	var cur strings.Builder
+	curWidth := 0
+	for _, w := range strings.Fields(s) {
+		ww := visibleWidth(w)
+		if curWidth > 0 && visibleWidth(prefix)+curWidth+1+ww > v.width {
+			v.line(prefix + cur.String())
+			prefix, curWidth = rest, 0
+			cur.Reset()
+		}
+		if curWidth > 0 {
+			cur.WriteByte(' ')
+			curWidth++
+		}
+		cur.WriteString(w)
+		curWidth += ww
+	}
+	if curWidth > 0 || prefix == first {
+		v.line(prefix + cur.String())
+	}
+}
+
+// visibleWidth returns the number of columns s takes, not counting SGR
+// escapes.
+func visibleWidth(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if s[i] == 0x1b {
+			if j := strings.IndexByte(s[i:], 'm'); j >= 0 {
+				i += j + 1
+				continue
+			}
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+func (v *viewer) table(text string) {
+	var rows [][]string
+	var widths []int
+	for _, line := range strings.Split(text, "\n") {
+		cells := mdscan.TableCells(line)
+		for i := range cells {
+			cells[i] = v.inline(cells[i])
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], visibleWidth(cells[i]))
+		}
+		rows = append(rows, cells)
+	}
+	sep := v.sgr("2", "22", " │ ")
+	for r, cells := range rows {
+		var b strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			if r == 0 {
+				cell = v.sgr("1", "22", cell)
+			}
+			b.WriteString(cell)
+			if i < len(cells)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-visibleWidth(cells[i])))
+			}
+		}
+		v.line(b.String())
+		if r == 0 {
+			var rule []string
+			for i := range cells {
+				rule = append(rule, strings.Repeat("─", widths[i]))
+			}
+			v.line(v.sgr("2", "22", strings.Join(rule, "─┼─")))
+		}
+	}
+}
+
+func (v *viewer) inline(s string) string {
+	return mdscan.FormatInline(s, termFormatter{v})
+}
+
+// termFormatter renders inline Markdown with SGR escapes.
+type termFormatter struct {
+	v *viewer
+}
+
+func (f termFormatter) Text(s string) string    { return s }
+func (f termFormatter) Code(code string) string { return f.v.sgr("36", "39", code) }
+
+func (f termFormatter) Span(kind mdscan.SpanKind, inner string) string {
+	switch kind {
+	case mdscan.SpanStrong:
+		return f.v.sgr("1", "22", inner)
+	case mdscan.SpanStrike:
+		return f.v.sgr("9", "29", inner)
+	}
+	return f.v.sgr("3", "23", inner)
+}
+
+func (f termFormatter) Link(text, dest string) string {
+	out := f.v.sgr("4", "24", text)
+	if visibleWidth(text) == 0 || stripSGR(text) != dest {
+		out += " " + f.v.sgr("2", "22", "("+dest+")")
+	}
+	return out
+}
+
+// Image returns a placeholder for the image and queues it for display after
+// the block.
+func (f termFormatter) Image(alt, dest string) string {
+	it, ok := f.v.doc.ResolveMedia(f.v.from, dest)
+	label := alt
+	if label == "" {
+		label = dest
+	}
+	if ok {
+		f.v.pending = append(f.v.pending, it)
+	}
+	return f.v.sgr("2", "22", "[image: "+label+"]")
+}
+
+// stripSGR returns s without SGR escapes.
+func stripSGR(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x1b {
+			if j := strings.IndexByte(s[i:], 'm'); j >= 0 {
+				i += j
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// flushImages adds the images queued by the current block.
+func (v *viewer) flushImages() {
+	for _, it := range v.pending {
+		if seq, ok := v.imageEscape(it); ok {
+			v.rows = append(v.rows, viewRow{image: seq, rows: v.imageRows})
+		}
+	}
+	v.pending = v.pending[:0]
+}
+
+// imageEscape returns the escape sequence that displays it with the viewer's
+// image protocol, or false if it cannot be displayed.
+func (v *viewer) imageEscape(it *mdocx.MediaItem) (string, bool) {
+	switch v.images {
+	case imagesKitty:
+		data := it.Data
+		if it.MIMEType != "image/png" {
+			// kitty takes PNG, so other formats Go decodes are converted.
+			img, _, err := image.Decode(bytes.NewReader(it.Data))
+			if err != nil {
+				return "", false
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return "", false
+			}
+			data = buf.Bytes()
+		}
+		return kittyImage(data, v.imageRows), true
+	case imagesITerm:
+		if !strings.HasPrefix(it.MIMEType, "image/") || it.MIMEType == "image/svg+xml" {
+			return "", false
+		}
+		return fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;height=%d;preserveAspectRatio=1:%s\a",
+			len(it.Data), v.imageRows, base64.StdEncoding.EncodeToString(it.Data)), true
+	}
+	return "", false
+}
+
+// kittyImage returns the kitty graphics protocol commands that display the
+// PNG image png over rows rows, its payload split into chunks of 4096 bytes.
+func kittyImage(png []byte, rows int) string {
+	payload := base64.StdEncoding.EncodeToString(png)
+	var b strings.Builder
+	for first := true; first || payload != ""; first = false {
+		chunk := payload[:min(len(payload), 4096)]
+		payload = payload[len(chunk):]
+		more := 0
+		if payload != "" {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\x1b_Gf=100,a=T,r=%d,m=%d;%s\x1b\\", rows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return b.String()
+}
@@ -3,13 +3,18 @@ package mdocx
 import (
 	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/andybalholm/brotli"
 	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
 )
 
 // Function variables for testing injection.
@@ -28,20 +33,22 @@ var (
 // compressPayload compresses gobBytes using the specified compression algorithm.
 // It returns the section flags (with compression bits set) and the payload bytes.
 // For compressed payloads, the payload includes an 8-byte uncompressed length prefix.
-func compressPayload(comp Compression, gobBytes []byte) (sectionFlags uint16, payload []byte, err error) {
+func compressPayload(comp Compression, gobBytes []byte, t codecTuning) (sectionFlags uint16, payload []byte, err error) {
 	if comp == CompNone {
 		return uint16(CompNone), gobBytes, nil
 	}
 	var compressed []byte
 	switch comp {
 	case CompZIP:
-		compressed, err = zipCompress(gobBytes)
+		compressed, err = zipCompress(gobBytes, t)
 	case CompZSTD:
-		compressed, err = zstdCompress(gobBytes)
+		compressed, err = zstdCompress(gobBytes, t)
 	case CompLZ4:
-		compressed, err = lz4Compress(gobBytes)
+		compressed, err = lz4Compress(gobBytes, t)
 	case CompBR:
-		compressed, err = brotliCompress(gobBytes)
+		compressed, err = brotliCompress(gobBytes, t)
+	case CompXZ:
+		compressed, err = xzCompress(gobBytes)
 	default:
 		return 0, nil, fmt.Errorf("%w: unknown compression %d", ErrInvalidPayload, comp)
 	}
@@ -60,6 +67,12 @@ func compressPayload(comp Compression, gobBytes []byte) (sectionFlags uint16, pa
 // For CompNone, the payload is returned as-is.
 // For all other algorithms, the payload must start with an 8-byte uncompressed length prefix.
 func decompressPayload(comp Compression, sectionFlags uint16, payload []byte, maxUncompressed uint64) ([]byte, error) {
+	return decompressPayloadContext(context.Background(), comp, sectionFlags, payload, maxUncompressed)
+}
+
+// decompressPayloadContext is decompressPayload checking ctx between chunks of
+// output when ctx can be canceled.
+func decompressPayloadContext(ctx context.Context, comp Compression, sectionFlags uint16, payload []byte, maxUncompressed uint64) ([]byte, error) {
 	hasLen := (sectionFlags & sectionFlagHasUncompressedLen) != 0
 	if comp == CompNone {
 		if hasLen {
@@ -81,15 +94,19 @@ func decompressPayload(comp Compression, sectionFlags uint16, payload []byte, ma
 
 	var out []byte
 	var err error
-	switch comp {
-	case CompZIP:
+	switch {
+	case ctx.Done() != nil:
+		out, err = decompressContext(ctx, comp, compressedBytes, uncompressedLen)
+	case comp == CompZIP:
 		out, err = zipDecompress(compressedBytes, uncompressedLen)
-	case CompZSTD:
+	case comp == CompZSTD:
 		out, err = zstdDecompress(compressedBytes, uncompressedLen)
-	case CompLZ4:
+	case comp == CompLZ4:
 		out, err = lz4Decompress(compressedBytes, uncompressedLen)
-	case CompBR:
+	case comp == CompBR:
 		out, err = brotliDecompress(compressedBytes, uncompressedLen)
+	case comp == CompXZ:
+		out, err = xzDecompress(compressedBytes, uncompressedLen)
 	default:
 		return nil, fmt.Errorf("%w: unknown compression %d", ErrInvalidPayload, comp)
 	}
@@ -103,17 +120,20 @@ func decompressPayload(comp Compression, sectionFlags uint16, payload []byte, ma
 }
 
 // zipCompress creates a ZIP archive containing in as "payload.gob".
-func zipCompress(in []byte) ([]byte, error) {
+func zipCompress(in []byte, t codecTuning) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := zipCompressNamed(&buf, "payload.gob", in); err != nil {
+	if err := zipCompressNamed(&buf, "payload.gob", in, t); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
 // zipCompressNamed creates a ZIP archive with a single entry.
-func zipCompressNamed(w io.Writer, name string, in []byte) error {
-	zw := zip.NewWriter(w)
+func zipCompressNamed(w io.Writer, name string, in []byte, t codecTuning) error {
+	zw, err := t.newZipWriter(w)
+	if err != nil {
+		return err
+	}
 	entry, err := zipCreate(zw, name)
 	if err != nil {
 		_ = zipClose(zw)
@@ -129,11 +149,36 @@ func zipCompressNamed(w io.Writer, name string, in []byte) error {
 // zipDecompress extracts the "payload.gob" entry from a ZIP archive.
 // It validates that the archive contains exactly one entry named "payload.gob"
 // and that the uncompressed size matches expected.
+//
+// Archives from other writers may use Zip64 sizes and offsets, which are
+// required for entries of 4 GiB or more, and data descriptors with or without
+// a signature; archive/zip resolves both from the central directory, so
+// expected is always compared with the full 64-bit size. The entry is read to
+// its end so that its CRC-32 is checked.
 func zipDecompress(zipBytes []byte, expected uint64) ([]byte, error) {
-	zr, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	rc, err := zipOpenPayload(zipBytes, expected)
 	if err != nil {
 		return nil, err
 	}
+	defer rc.Close()
+	b, err := readAll(io.LimitReader(rc, int64(expected)+1))
+	if err != nil {
+		return nil, zipError(err)
+	}
+	if uint64(len(b)) > expected {
+		return nil, fmt.Errorf("%w: zip entry expanded beyond expected size", ErrInvalidPayload)
+	}
+	return b, nil
+}
+
+// zipOpenPayload checks the layout of a ZIP payload as described for
+// zipDecompress and opens its entry. Encrypted entries and compression
+// methods other than Store and Deflate are rejected.
+func zipOpenPayload(zipBytes []byte, expected uint64) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	if err != nil {
+		return nil, zipError(err)
+	}
 	if len(zr.File) != 1 {
 		return nil, fmt.Errorf("%w: zip must contain exactly one entry", ErrInvalidPayload)
 	}
@@ -144,25 +189,38 @@ func zipDecompress(zipBytes []byte, expected uint64) ([]byte, error) {
 	if zf.FileInfo().IsDir() {
 		return nil, fmt.Errorf("%w: zip entry must be a file", ErrInvalidPayload)
 	}
+	if zf.Flags&zipFlagEncrypted != 0 {
+		return nil, fmt.Errorf("%w: zip entry must not be encrypted", ErrInvalidPayload)
+	}
+	if zf.Method != zip.Store && zf.Method != zip.Deflate {
+		return nil, fmt.Errorf("%w: unsupported zip compression method %d", ErrInvalidPayload, zf.Method)
+	}
 	// Reject unknown sizes if possible.
 	if zf.UncompressedSize64 != expected {
 		return nil, fmt.Errorf("%w: zip uncompressed size %d != expected %d", ErrInvalidPayload, zf.UncompressedSize64, expected)
 	}
 	rc, err := zipOpen(zf)
 	if err != nil {
-		return nil, err
+		return nil, zipError(err)
 	}
-	defer rc.Close()
-	b, err := readAll(io.LimitReader(rc, int64(expected)))
-	if err != nil {
-		return nil, err
+	return rc, nil
+}
+
+// zipFlagEncrypted is the general purpose flag bit of an encrypted ZIP entry.
+const zipFlagEncrypted = 0x1
+
+// zipError wraps the archive/zip format, checksum, and algorithm errors in
+// ErrInvalidPayload.
+func zipError(err error) error {
+	if errors.Is(err, zip.ErrFormat) || errors.Is(err, zip.ErrChecksum) || errors.Is(err, zip.ErrAlgorithm) {
+		return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
 	}
-	return b, nil
+	return err
 }
 
 // zstdCompress compresses in using the Zstandard algorithm.
-func zstdCompress(in []byte) ([]byte, error) {
-	enc, err := newZstdWriter()
+func zstdCompress(in []byte, t codecTuning) ([]byte, error) {
+	enc, err := t.newZstdEncoder(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -189,17 +247,20 @@ func zstdDecompress(in []byte, expected uint64) ([]byte, error) {
 }
 
 // lz4Compress compresses in using the LZ4 algorithm.
-func lz4Compress(in []byte) ([]byte, error) {
+func lz4Compress(in []byte, t codecTuning) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := lz4CompressTo(&buf, in); err != nil {
+	if err := lz4CompressTo(&buf, in, t); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
 // lz4CompressTo writes LZ4-compressed data to w.
-func lz4CompressTo(w io.Writer, in []byte) error {
-	zw := lz4.NewWriter(w)
+func lz4CompressTo(w io.Writer, in []byte, t codecTuning) error {
+	zw, err := t.newLZ4Writer(w)
+	if err != nil {
+		return err
+	}
 	if _, err := zw.Write(in); err != nil {
 		_ = lz4Close(zw)
 		return err
@@ -222,17 +283,20 @@ func lz4Decompress(in []byte, expected uint64) ([]byte, error) {
 }
 
 // brotliCompress compresses in using the Brotli algorithm.
-func brotliCompress(in []byte) ([]byte, error) {
+func brotliCompress(in []byte, t codecTuning) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := brotliCompressTo(&buf, in); err != nil {
+	if err := brotliCompressTo(&buf, in, t); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
 // brotliCompressTo writes Brotli-compressed data to w.
-func brotliCompressTo(w io.Writer, in []byte) error {
-	bw := brotli.NewWriter(w)
+func brotliCompressTo(w io.Writer, in []byte, t codecTuning) error {
+	bw, err := t.newBrotliWriter(w)
+	if err != nil {
+		return err
+	}
 	if _, err := brotliWrite(bw, in); err != nil {
 		_ = brotliClose(bw)
 		return err
@@ -253,3 +317,282 @@ func brotliDecompress(in []byte, expected uint64) ([]byte, error) {
 	}
 	return b, nil
 }
+
+// xzCompress compresses in as a single XZ stream.
+func xzCompress(in []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	xw, err := xz.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := xw.Write(in); err != nil {
+		_ = xw.Close()
+		return nil, err
+	}
+	if err := xw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xzDecompress decompresses XZ-compressed data.
+// It uses a LimitReader to prevent decompression beyond expected bytes.
+func xzDecompress(in []byte, expected uint64) ([]byte, error) {
+	r, err := newXZReader(in, expected)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readAll(io.LimitReader(r, int64(expected)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(b)) > expected {
+		return nil, fmt.Errorf("%w: xz expanded beyond expected size", ErrInvalidPayload)
+	}
+	return b, nil
+}
+
+// xzMaxDictCap is the largest LZMA2 dictionary accepted for payloads smaller
+// than it; larger payloads may use a dictionary as large as themselves. It
+// matches the dictionary of the strongest xz preset.
+const xzMaxDictCap = 64 << 20
+
+// newXZReader checks the layout of an XZ payload and returns a reader of its
+// decompressed data. The payload must be a single stream whose LZMA2
+// dictionaries are no larger than max(expected, xzMaxDictCap), since the
+// decoder allocates them before reading any data.
+func newXZReader(in []byte, expected uint64) (io.Reader, error) {
+	if err := xzCheckDictCaps(in, max(int64(min(expected, 1<<62)), xzMaxDictCap)); err != nil {
+		return nil, err
+	}
+	return xz.ReaderConfig{SingleStream: true}.NewReader(bytes.NewReader(in))
+}
+
+// xzCheckDictCaps walks the blocks of the single XZ stream in, using the
+// stream index, and rejects LZMA2 dictionaries larger than limit.
+func xzCheckDictCaps(in []byte, limit int64) error {
+	malformed := fmt.Errorf("%w: malformed xz stream", ErrInvalidPayload)
+	if len(in) < 24 || string(in[len(in)-2:]) != "YZ" {
+		return malformed
+	}
+	end := int64(len(in))
+	indexStart := end - 12 - (int64(binary.LittleEndian.Uint32(in[end-8:end-4]))+1)*4
+	if indexStart < 12 || in[indexStart] != 0 {
+		return malformed
+	}
+	index := in[indexStart+1 : end-12]
+	uvarint := func() (uint64, bool) {
+		v, n := binary.Uvarint(index)
+		if n <= 0 {
+			return 0, false
+		}
+		index = index[n:]
+		return v, true
+	}
+	records, ok := uvarint()
+	if !ok {
+		return malformed
+	}
+	off := int64(12)
+	for ; records > 0; records-- {
+		unpadded, ok1 := uvarint()
+		_, ok2 := uvarint()
+		if !ok1 || !ok2 || unpadded > uint64(indexStart-off) {
+			return malformed
+		}
+		if err := xzCheckBlockHeader(in[off:indexStart], limit); err != nil {
+			return err
+		}
+		off += (int64(unpadded) + 3) &^ 3
+	}
+	if off != indexStart {
+		return malformed
+	}
+	return nil
+}
+
+// xzCheckBlockHeader checks the LZMA2 dictionary sizes in the header of the
+// XZ block at the start of block.
+func xzCheckBlockHeader(block []byte, limit int64) error {
+	malformed := fmt.Errorf("%w: malformed xz block header", ErrInvalidPayload)
+	if len(block) < 2 || block[0] == 0 || len(block) < (int(block[0])+1)*4 {
+		return malformed
+	}
+	h := block[2 : (int(block[0])+1)*4-4] // without the size, flags, and CRC32
+	next := func() (uint64, bool) {
+		v, n := binary.Uvarint(h)
+		if n <= 0 {
+			return 0, false
+		}
+		h = h[n:]
+		return v, true
+	}
+	flags := block[1]
+	for _, bit := range []byte{0x40, 0x80} { // compressed and uncompressed sizes
+		if flags&bit != 0 {
+			if _, ok := next(); !ok {
+				return malformed
+			}
+		}
+	}
+	for range flags&0x03 + 1 {
+		id, ok1 := next()
+		size, ok2 := next()
+		if !ok1 || !ok2 || size > uint64(len(h)) {
+			return malformed
+		}
+		props := h[:size]
+		h = h[size:]
+		if id != 0x21 { // LZMA2; the decoder rejects other filters
+			continue
+		}
+		if len(props) != 1 {
+			return malformed
+		}
+		dictCap, err := lzma.DecodeDictCap(props[0])
+		if err != nil {
+			return malformed
+		}
+		if dictCap > limit {
+			return fmt.Errorf("%w: xz dictionary of %d bytes exceeds %d", ErrLimitExceeded, dictCap, limit)
+		}
+	}
+	return nil
+}
+
+// newCompressWriter returns a writer that compresses everything written to it
+// into w using comp. Close flushes the compressed stream but does not close w.
+// For CompZIP the output is a single-entry "payload.gob" archive, as produced by
+// compressPayload. The encoders are configured by t.
+func newCompressWriter(comp Compression, w io.Writer, t codecTuning) (io.WriteCloser, error) {
+	switch comp {
+	case CompNone:
+		return nopWriteCloser{w}, nil
+	case CompZIP:
+		zw, err := t.newZipWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := zipCreate(zw, "payload.gob")
+		if err != nil {
+			_ = zipClose(zw)
+			return nil, err
+		}
+		return &zipEntryWriter{Writer: entry, zw: zw}, nil
+	case CompZSTD:
+		return t.newZstdEncoder(w)
+	case CompLZ4:
+		return t.newLZ4Writer(w)
+	case CompBR:
+		return t.newBrotliWriter(w)
+	case CompXZ:
+		return xz.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("%w: unknown compression %d", ErrInvalidPayload, comp)
+	}
+}
+
+// zipEntryWriter writes a single ZIP entry and closes the archive on Close.
+type zipEntryWriter struct {
+	io.Writer
+	zw *zip.Writer
+}
+
+func (z *zipEntryWriter) Close() error { return zipClose(z.zw) }
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// codecTuning holds the encoder settings of the codecs. Zero values other
+// than brotliQuality select the encoder defaults.
+type codecTuning struct {
+	// level is the section's level set with WithCompressionLevel, if hasLevel
+	// is set. It overrides brotliQuality and lz4Level.
+	level         int
+	hasLevel      bool
+	brotliQuality int
+	// brotliWindow is the base 2 logarithm of the Brotli window size.
+	brotliWindow int
+	lz4BlockSize int
+	// lz4Level is 0 for the fast LZ4 compressor and 1-9 for the high
+	// compression one.
+	lz4Level int
+}
+
+// defaultCodecTuning returns the encoder settings used without tuning options.
+func defaultCodecTuning() codecTuning {
+	return codecTuning{brotliQuality: brotli.DefaultCompression}
+}
+
+// newZipWriter returns a ZIP archive writer writing to w, with the DEFLATE
+// level of t.
+func (t codecTuning) newZipWriter(w io.Writer) (*zip.Writer, error) {
+	zw := zip.NewWriter(w)
+	if t.hasLevel {
+		level := t.level
+		if level < flate.NoCompression || level > flate.BestCompression {
+			return nil, fmt.Errorf("%w: zip level %d out of range 0-9", ErrValidation, level)
+		}
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	return zw, nil
+}
+
+// newZstdEncoder returns a Zstandard encoder writing to w, which may be nil
+// for an encoder used with EncodeAll.
+func (t codecTuning) newZstdEncoder(w io.Writer) (*zstd.Encoder, error) {
+	if !t.hasLevel {
+		if w == nil {
+			return newZstdWriter()
+		}
+		return zstd.NewWriter(w)
+	}
+	if t.level < 1 || t.level > 22 {
+		return nil, fmt.Errorf("%w: zstd level %d out of range 1-22", ErrValidation, t.level)
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(t.level)))
+}
+
+// newBrotliWriter returns a Brotli encoder writing to w.
+func (t codecTuning) newBrotliWriter(w io.Writer) (*brotli.Writer, error) {
+	if t.hasLevel {
+		t.brotliQuality = t.level
+	}
+	if t.brotliQuality < brotli.BestSpeed || t.brotliQuality > brotli.BestCompression {
+		return nil, fmt.Errorf("%w: brotli quality %d out of range %d-%d", ErrValidation, t.brotliQuality, brotli.BestSpeed, brotli.BestCompression)
+	}
+	if t.brotliWindow != 0 && (t.brotliWindow < 10 || t.brotliWindow > 24) {
+		return nil, fmt.Errorf("%w: brotli window %d out of range 10-24", ErrValidation, t.brotliWindow)
+	}
+	return brotli.NewWriterOptions(w, brotli.WriterOptions{Quality: t.brotliQuality, LGWin: t.brotliWindow}), nil
+}
+
+// newLZ4Writer returns an LZ4 frame encoder writing to w.
+func (t codecTuning) newLZ4Writer(w io.Writer) (*lz4.Writer, error) {
+	if t.hasLevel {
+		t.lz4Level = t.level
+	}
+	var opts []lz4.Option
+	if t.lz4BlockSize != 0 {
+		switch lz4.BlockSize(t.lz4BlockSize) {
+		case lz4.Block64Kb, lz4.Block256Kb, lz4.Block1Mb, lz4.Block4Mb:
+		default:
+			return nil, fmt.Errorf("%w: lz4 block size %d is not 64 KiB, 256 KiB, 1 MiB, or 4 MiB", ErrValidation, t.lz4BlockSize)
+		}
+		opts = append(opts, lz4.BlockSizeOption(lz4.BlockSize(t.lz4BlockSize)))
+	}
+	if t.lz4Level != 0 {
+		if t.lz4Level < 1 || t.lz4Level > 9 {
+			return nil, fmt.Errorf("%w: lz4 level %d out of range 0-9", ErrValidation, t.lz4Level)
+		}
+		opts = append(opts, lz4.CompressionLevelOption(lz4.Level1<<(t.lz4Level-1)))
+	}
+	zw := lz4.NewWriter(w)
+	if err := zw.Apply(opts...); err != nil {
+		return nil, err
+	}
+	return zw, nil
+}
@@ -3,13 +3,17 @@ package mdocx
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
+	"hash/crc32"
 	"io"
 	"io/fs"
 	"testing"
 
 	"github.com/andybalholm/brotli"
 	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
 )
 
 func TestZIPDecompressErrors(t *testing.T) {
@@ -64,10 +68,157 @@ func TestZIPDecompressErrors(t *testing.T) {
 	}
 }
 
+// zip64Stored builds a single-entry stored archive the way Zip64 writers lay
+// out large entries: every 32-bit size and offset field is 0xFFFFFFFF and the
+// real values live in Zip64 extra fields and the Zip64 end of central directory.
+func zip64Stored(name string, data []byte) []byte {
+	le := binary.LittleEndian
+	var b []byte
+	crc := crc32.ChecksumIEEE(data)
+	size := uint64(len(data))
+
+	b = le.AppendUint32(b, 0x04034b50)
+	b = le.AppendUint16(b, 45)
+	b = le.AppendUint16(b, 0) // flags
+	b = le.AppendUint16(b, zip.Store)
+	b = le.AppendUint32(b, 0) // time and date
+	b = le.AppendUint32(b, crc)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = le.AppendUint16(b, uint16(len(name)))
+	b = le.AppendUint16(b, 20)
+	b = append(b, name...)
+	b = le.AppendUint16(b, 0x0001)
+	b = le.AppendUint16(b, 16)
+	b = le.AppendUint64(b, size)
+	b = le.AppendUint64(b, size)
+	b = append(b, data...)
+
+	cdOff := uint64(len(b))
+	b = le.AppendUint32(b, 0x02014b50)
+	b = le.AppendUint16(b, 45)
+	b = le.AppendUint16(b, 45)
+	b = le.AppendUint16(b, 0)
+	b = le.AppendUint16(b, zip.Store)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint32(b, crc)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = le.AppendUint16(b, uint16(len(name)))
+	b = le.AppendUint16(b, 28)
+	b = le.AppendUint16(b, 0) // comment
+	b = le.AppendUint16(b, 0) // disk
+	b = le.AppendUint16(b, 0) // internal attributes
+	b = le.AppendUint32(b, 0) // external attributes
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = append(b, name...)
+	b = le.AppendUint16(b, 0x0001)
+	b = le.AppendUint16(b, 24)
+	b = le.AppendUint64(b, size)
+	b = le.AppendUint64(b, size)
+	b = le.AppendUint64(b, 0)
+	cdSize := uint64(len(b)) - cdOff
+
+	eocd64 := uint64(len(b))
+	b = le.AppendUint32(b, 0x06064b50)
+	b = le.AppendUint64(b, 44)
+	b = le.AppendUint16(b, 45)
+	b = le.AppendUint16(b, 45)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint64(b, 1)
+	b = le.AppendUint64(b, 1)
+	b = le.AppendUint64(b, cdSize)
+	b = le.AppendUint64(b, cdOff)
+
+	b = le.AppendUint32(b, 0x07064b50)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint64(b, eocd64)
+	b = le.AppendUint32(b, 1)
+
+	b = le.AppendUint32(b, 0x06054b50)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint16(b, 0xFFFF)
+	b = le.AppendUint16(b, 0xFFFF)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = le.AppendUint32(b, 0xFFFFFFFF)
+	b = le.AppendUint16(b, 0)
+	return b
+}
+
+func TestZIPDecompressZip64(t *testing.T) {
+	data := []byte("zip64 payload")
+	got, err := zipDecompress(zip64Stored("payload.gob", data), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := zipDecompress(zip64Stored("payload.gob", data), uint64(len(data))+1); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("size mismatch: got %v", err)
+	}
+}
+
+func TestZIPDecompressForeignEntries(t *testing.T) {
+	data := []byte("abc")
+	raw := func(h *zip.FileHeader, body []byte) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.CreateRaw(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(body)
+		_ = zw.Close()
+		return buf.Bytes()
+	}
+	header := func() *zip.FileHeader {
+		return &zip.FileHeader{
+			Name:               "payload.gob",
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE(data),
+			CompressedSize64:   uint64(len(data)),
+			UncompressedSize64: uint64(len(data)),
+		}
+	}
+
+	if got, err := zipDecompress(raw(header(), data), 3); err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("stored entry: %q, %v", got, err)
+	}
+
+	h := header()
+	h.CRC32++
+	if _, err := zipDecompress(raw(h, data), 3); !errors.Is(err, ErrInvalidPayload) || !errors.Is(err, zip.ErrChecksum) {
+		t.Fatalf("bad CRC: got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := decompressPayloadContext(ctx, CompZIP, uint16(CompZIP)|sectionFlagHasUncompressedLen, append(binary.LittleEndian.AppendUint64(nil, 3), raw(h, data)...), 3); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("bad CRC with context: got %v", err)
+	}
+
+	h = header()
+	h.Method = 12 // bzip2
+	if _, err := zipDecompress(raw(h, data), 3); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("unsupported method: got %v", err)
+	}
+
+	h = header()
+	h.Flags |= zipFlagEncrypted
+	if _, err := zipDecompress(raw(h, data), 3); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("encrypted entry: got %v", err)
+	}
+
+	if _, err := zipDecompress([]byte("not a zip archive"), 3); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("not an archive: got %v", err)
+	}
+}
+
 func TestDecompressionExpansionGuards(t *testing.T) {
 	in := []byte("hello world")
 
-	zst, err := zstdCompress(in)
+	zst, err := zstdCompress(in, defaultCodecTuning())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -75,7 +226,7 @@ func TestDecompressionExpansionGuards(t *testing.T) {
 		t.Fatal("expected error")
 	}
 
-	lz, err := lz4Compress(in)
+	lz, err := lz4Compress(in, defaultCodecTuning())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -83,20 +234,28 @@ func TestDecompressionExpansionGuards(t *testing.T) {
 		t.Fatal("expected error")
 	}
 
-	br, err := brotliCompress(in)
+	br, err := brotliCompress(in, defaultCodecTuning())
 	if err != nil {
 		t.Fatal(err)
 	}
 	if _, err := brotliDecompress(br, 1); err == nil {
 		t.Fatal("expected error")
 	}
+
+	x, err := xzCompress(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xzDecompress(x, 1); err == nil {
+		t.Fatal("expected error")
+	}
 }
 
 func TestCompressionWrappers_ReturnErrors(t *testing.T) {
 	// zipCompress wrapper error
 	origCreate := zipCreate
 	zipCreate = func(_ *zip.Writer, _ string) (io.Writer, error) { return nil, io.ErrClosedPipe }
-	if _, err := zipCompress([]byte("x")); err == nil {
+	if _, err := zipCompress([]byte("x"), defaultCodecTuning()); err == nil {
 		zipCreate = origCreate
 		t.Fatal("expected error")
 	}
@@ -105,7 +264,7 @@ func TestCompressionWrappers_ReturnErrors(t *testing.T) {
 	// lz4Compress wrapper error
 	origLZ4Close := lz4Close
 	lz4Close = func(_ *lz4.Writer) error { return io.ErrClosedPipe }
-	if _, err := lz4Compress([]byte("x")); err == nil {
+	if _, err := lz4Compress([]byte("x"), defaultCodecTuning()); err == nil {
 		lz4Close = origLZ4Close
 		t.Fatal("expected error")
 	}
@@ -114,7 +273,7 @@ func TestCompressionWrappers_ReturnErrors(t *testing.T) {
 	// brotliCompress wrapper error
 	origBrotliClose := brotliClose
 	brotliClose = func(_ *brotli.Writer) error { return io.ErrClosedPipe }
-	if _, err := brotliCompress([]byte("x")); err == nil {
+	if _, err := brotliCompress([]byte("x"), defaultCodecTuning()); err == nil {
 		brotliClose = origBrotliClose
 		t.Fatal("expected error")
 	}
@@ -134,11 +293,52 @@ func TestDecompressionCorruptStreams(t *testing.T) {
 	if _, err := brotliDecompress([]byte("notbr"), 100); err == nil {
 		t.Fatal("expected error")
 	}
+	// xz: corrupt stream should error
+	if _, err := xzDecompress([]byte("notxz"), 100); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected ErrInvalidPayload, got %v", err)
+	}
+}
+
+func TestXZDictCapGuard(t *testing.T) {
+	in := bytes.Repeat([]byte("archival bundles favour ratio over speed "), 2048)
+	var buf bytes.Buffer
+	xw, err := xz.WriterConfig{BlockSize: 4096}.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xw.Write(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	multi := buf.Bytes()
+	if out, err := xzDecompress(multi, uint64(len(in))); err != nil || !bytes.Equal(out, in) {
+		t.Fatalf("multi-block stream: %v", err)
+	}
+	// The default 8 MiB dictionary is within the limit for any payload but
+	// not within a smaller one.
+	if err := xzCheckDictCaps(multi, 8<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := xzCheckDictCaps(multi, 4096); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("large dictionary: %v", err)
+	}
+
+	for name, bad := range map[string][]byte{
+		"truncated":    multi[:len(multi)-1],
+		"concatenated": append(bytes.Clone(multi), multi...),
+		"trailing":     append(bytes.Clone(multi), 0, 0, 0, 0),
+	} {
+		if _, err := xzDecompress(bad, uint64(len(in))); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
 }
 
 func TestDecompressPayloadLengthMismatch(t *testing.T) {
 	in := []byte("abc")
-	compressed, err := zstdCompress(in)
+	compressed, err := zstdCompress(in, defaultCodecTuning())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -162,3 +362,130 @@ func TestDecompressPayloadBadEnvelope(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestCodecTuning(t *testing.T) {
+	in := bytes.Repeat([]byte("tuning the brotli and lz4 encoders "), 4096)
+	lz4Size := func(t codecTuning) int {
+		b, err := lz4Compress(in, t)
+		if err != nil {
+			panic(err)
+		}
+		return len(b)
+	}
+	if fast, high := lz4Size(defaultCodecTuning()), lz4Size(codecTuning{lz4Level: 9}); high > fast {
+		t.Fatalf("level 9 output %d larger than fast output %d", high, fast)
+	}
+
+	tunings := []struct {
+		comp Compression
+		opts []WriteOption
+	}{
+		{CompBR, []WriteOption{WithBrotliQuality(0)}},
+		{CompBR, []WriteOption{WithBrotliQuality(11), WithBrotliWindow(10)}},
+		{CompLZ4, []WriteOption{WithLZ4BlockSize(64 << 10), WithLZ4Level(9)}},
+		{CompLZ4, []WriteOption{WithLZ4BlockSize(1 << 20), WithLZ4Level(1)}},
+	}
+	for _, tc := range tunings {
+		opts := append([]WriteOption{WithMarkdownCompression(tc.comp), WithMediaCompression(tc.comp)}, tc.opts...)
+		doc := sampleDoc()
+		doc.Markdown.Files[1].Content = in
+		var buf bytes.Buffer
+		if err := Encode(&buf, doc, opts...); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Markdown.Files[1].Content, in) {
+			t.Fatalf("compression %d: content mismatch", tc.comp)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		buf.Reset()
+		err = EncodeContext(ctx, &buf, sampleDoc(), opts...)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Decode(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	invalid := []struct {
+		comp Compression
+		opt  WriteOption
+	}{
+		{CompBR, WithBrotliQuality(12)},
+		{CompBR, WithBrotliQuality(-1)},
+		{CompBR, WithBrotliWindow(9)},
+		{CompBR, WithBrotliWindow(25)},
+		{CompLZ4, WithLZ4BlockSize(128 << 10)},
+		{CompLZ4, WithLZ4Level(10)},
+		{CompLZ4, WithLZ4Level(-1)},
+	}
+	for i, tc := range invalid {
+		err := Encode(io.Discard, sampleDoc(), WithMarkdownCompression(tc.comp), tc.opt)
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("case %d: got %v", i, err)
+		}
+	}
+	// Tuning options for a codec that is not used are ignored.
+	if err := Encode(io.Discard, sampleDoc(), WithBrotliQuality(99), WithLZ4Level(99)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompressionLevel(t *testing.T) {
+	in := bytes.Repeat([]byte("levels trade encode time for ratio; "), 8192)
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = in
+	size := func(comp Compression, level int) int {
+		t.Helper()
+		var buf bytes.Buffer
+		err := Encode(&buf, doc, WithMarkdownCompression(comp), WithMediaCompression(CompNone), WithCompressionLevel(SectionMarkdown, level))
+		if err != nil {
+			t.Fatalf("%s level %d: %v", compressionName(comp), level, err)
+		}
+		got, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil || !bytes.Equal(got.Markdown.Files[1].Content, in) {
+			t.Fatalf("%s level %d: round trip: %v", compressionName(comp), level, err)
+		}
+		return buf.Len()
+	}
+	for _, tc := range []struct {
+		comp      Compression
+		low, high int
+	}{
+		{CompZIP, 0, 9},
+		{CompZSTD, 1, 19},
+		{CompBR, 0, 11},
+		{CompLZ4, 0, 9},
+	} {
+		if low, high := size(tc.comp, tc.low), size(tc.comp, tc.high); high >= low {
+			t.Errorf("%s: level %d output %d not smaller than level %d output %d", compressionName(tc.comp), tc.high, high, tc.low, low)
+		}
+	}
+
+	for _, tc := range []struct {
+		comp  Compression
+		level int
+	}{
+		{CompZIP, -1}, {CompZIP, 10}, {CompZSTD, 0}, {CompZSTD, 23}, {CompBR, 12}, {CompLZ4, 10},
+	} {
+		err := Encode(io.Discard, sampleDoc(), WithMediaCompression(tc.comp), WithCompressionLevel(SectionMedia, tc.level))
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("%s level %d: %v", compressionName(tc.comp), tc.level, err)
+		}
+		files, media := feed(sampleDoc())
+		err = EncodeStream(context.Background(), io.Discard, StreamHeader{}, files, media, WithMediaCompression(tc.comp), WithCompressionLevel(SectionMedia, tc.level))
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("EncodeStream %s level %d: %v", compressionName(tc.comp), tc.level, err)
+		}
+	}
+	// Levels apply to their own section only, and not to CompXZ.
+	if err := Encode(io.Discard, sampleDoc(), WithMediaCompression(CompXZ), WithCompressionLevel(SectionMarkdown, 3), WithCompressionLevel(SectionMedia, 99)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,211 @@
+package mdocx
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const docURIPrefix = "mdocx://doc/"
+
+// MinFingerprintPrefix is the shortest fingerprint prefix accepted in an
+// mdocx://doc/ link.
+const MinFingerprintPrefix = 12
+
+// DocLink is a parsed cross-container link of the form
+// mdocx://doc/<fingerprint>/<path>#<fragment>, where fingerprint is a
+// Document.Fingerprint or a prefix of at least MinFingerprintPrefix characters.
+// An empty path refers to the target container's root file.
+type DocLink struct {
+	Fingerprint string
+	Path        string
+	Fragment    string
+}
+
+// ParseDocLink parses an mdocx://doc/ link. It reports false if uri does not
+// use the scheme or its fingerprint is too short or not lower-case hex.
+func ParseDocLink(uri string) (DocLink, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(uri), docURIPrefix)
+	if !ok {
+		return DocLink{}, false
+	}
+	var l DocLink
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		rest, l.Fragment = rest[:i], rest[i+1:]
+		if f, err := url.PathUnescape(l.Fragment); err == nil {
+			l.Fragment = f
+		}
+	}
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		rest = rest[:i]
+	}
+	l.Fingerprint, l.Path, _ = strings.Cut(rest, "/")
+	if p, err := url.PathUnescape(l.Path); err == nil {
+		l.Path = p
+	}
+	if len(l.Fingerprint) < MinFingerprintPrefix || len(l.Fingerprint) > 64 ||
+		strings.Trim(l.Fingerprint, "0123456789abcdef") != "" {
+		return DocLink{}, false
+	}
+	return l, true
+}
+
+// String formats l as an mdocx://doc/ URI.
+func (l DocLink) String() string {
+	s := docURIPrefix + l.Fingerprint + "/" + (&url.URL{Path: l.Path}).EscapedPath()
+	if l.Fragment != "" {
+		s += "#" + url.PathEscape(l.Fragment)
+	}
+	return s
+}
+
+// LinkTo returns an mdocx://doc/ link to the Markdown file or media item at
+// container path p in d, with an optional fragment. Because the link embeds
+// d's fingerprint, it only resolves to d as long as d's content is unchanged.
+func (d *Document) LinkTo(p, fragment string) string {
+	return DocLink{Fingerprint: d.Fingerprint(), Path: p, Fragment: fragment}.String()
+}
+
+// LinkTarget is what an mdocx://doc/ link resolves to.
+type LinkTarget struct {
+	// Doc is the container the link points into.
+	Doc *Document
+	// File is the target Markdown file, or nil if the link targets media.
+	File *MarkdownFile
+	// Media is the target media item, or nil if the link targets a file.
+	Media *MediaItem
+	// Fragment is the link's fragment, unchecked.
+	Fragment string
+}
+
+// Library is a set of containers between which mdocx://doc/ links are
+// resolved, such as the volumes of a multi-volume publication. Containers are
+// identified by their Fingerprint, computed when they are added; a container
+// must not be modified after it is added.
+//
+// A Library is not safe for concurrent modification.
+type Library struct {
+	docs  map[string]*Document
+	order []string
+}
+
+// NewLibrary returns a Library holding docs.
+func NewLibrary(docs ...*Document) *Library {
+	l := &Library{docs: make(map[string]*Document, len(docs))}
+	for _, d := range docs {
+		l.Add(d)
+	}
+	return l
+}
+
+// Add adds doc to the library and returns its fingerprint. Adding a container
+// with the same content twice keeps the first.
+func (l *Library) Add(doc *Document) string {
+	fp := doc.Fingerprint()
+	if _, ok := l.docs[fp]; !ok {
+		l.docs[fp] = doc
+		l.order = append(l.order, fp)
+	}
+	return fp
+}
+
+// Lookup returns the container whose fingerprint is fp or starts with fp.
+// It fails with ErrNotFound if there is none and with ErrValidation if the
+// prefix matches several containers.
+func (l *Library) Lookup(fp string) (*Document, error) {
+	if d, ok := l.docs[fp]; ok {
+		return d, nil
+	}
+	var found *Document
+	for _, full := range l.order {
+		if strings.HasPrefix(full, fp) {
+			if found != nil {
+				return nil, fmt.Errorf("%w: fingerprint prefix %q is ambiguous", ErrValidation, fp)
+			}
+			found = l.docs[full]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: container %q", ErrNotFound, fp)
+	}
+	return found, nil
+}
+
+// Resolve resolves an mdocx://doc/ link against the library. A link without a
+// path targets the container's root file (RootPath, or its first file). It
+// fails with ErrValidation if uri is not a valid link, and with ErrNotFound if
+// the container or path does not exist. The fragment is not checked.
+func (l *Library) Resolve(uri string) (LinkTarget, error) {
+	link, ok := ParseDocLink(uri)
+	if !ok {
+		return LinkTarget{}, fmt.Errorf("%w: invalid document link %q", ErrValidation, uri)
+	}
+	doc, err := l.Lookup(link.Fingerprint)
+	if err != nil {
+		return LinkTarget{}, err
+	}
+	t := LinkTarget{Doc: doc, Fragment: link.Fragment}
+	p := link.Path
+	if p == "" {
+		p = doc.Markdown.RootPath
+		if p == "" && len(doc.Markdown.Files) > 0 {
+			p = doc.Markdown.Files[0].Path
+		}
+	}
+	for i := range doc.Markdown.Files {
+		if doc.Markdown.Files[i].Path == p {
+			t.File = &doc.Markdown.Files[i]
+			return t, nil
+		}
+	}
+	for i := range doc.Media.Items {
+		if doc.Media.Items[i].Path == p {
+			t.Media = &doc.Media.Items[i]
+			return t, nil
+		}
+	}
+	return LinkTarget{}, fmt.Errorf("%w: path %q in container %s", ErrNotFound, p, link.Fingerprint)
+}
+
+// CheckLinks returns the mdocx://doc/ links in the library's containers that do
+// not resolve, keyed by the fingerprint of the container holding them.
+// Fragments of links to Markdown files are checked against the target's
+// headings and anchors as CheckLinks does; the same options apply.
+// Links within a container are not checked; use CheckLinks for those.
+func (l *Library) CheckLinks(opts ...LinkOption) map[string][]BrokenLink {
+	cfg := linkConfig{slug: GitHubSlug, checkFragments: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	anchors := make(map[*MarkdownFile]map[string]struct{})
+	out := make(map[string][]BrokenLink)
+	for _, fp := range l.order {
+		for _, f := range l.docs[fp].Markdown.Files {
+			for _, link := range l.docs[fp].scanFile(f).Links {
+				if !strings.HasPrefix(link.Dest, docURIPrefix) {
+					continue
+				}
+				bad := func(reason string) {
+					out[fp] = append(out[fp], BrokenLink{File: f.Path, Line: link.Line, Column: link.Column, Dest: link.Dest, Reason: reason})
+				}
+				t, err := l.Resolve(link.Dest)
+				if err != nil {
+					bad(err.Error())
+					continue
+				}
+				if t.File == nil || t.Fragment == "" || !cfg.checkFragments {
+					continue
+				}
+				a, ok := anchors[t.File]
+				if !ok {
+					a = fileAnchors(t.Doc.scanFile(*t.File), cfg.slug)
+					anchors[t.File] = a
+				}
+				if _, ok := a[t.Fragment]; !ok {
+					bad(fmt.Sprintf("no heading or anchor %q in %q", t.Fragment, t.File.Path))
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package mdocx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseDocLink(t *testing.T) {
+	l, ok := ParseDocLink("mdocx://doc/0123456789abcdef/docs/a%20b.md?x=1#Set%20up")
+	if !ok || l.Fingerprint != "0123456789abcdef" || l.Path != "docs/a b.md" || l.Fragment != "Set up" {
+		t.Fatalf("ParseDocLink = %+v, %v", l, ok)
+	}
+	if got := l.String(); got != "mdocx://doc/0123456789abcdef/docs/a%20b.md#Set%20up" {
+		t.Fatalf("String() = %q", got)
+	}
+	for _, bad := range []string{"mdocx://media/x", "mdocx://doc/abc/x.md", "mdocx://doc/0123456789ABCDEF/x.md"} {
+		if _, ok := ParseDocLink(bad); ok {
+			t.Fatalf("ParseDocLink(%q) accepted", bad)
+		}
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	vol2 := sampleDoc()
+	vol2.Markdown.Files[1].Content = []byte("# Setup\n\nPart two.\n")
+	vol1 := sampleDoc()
+	vol1.Metadata["title"] = "Volume 1"
+	vol1.Markdown.Files[0].Content = []byte("# One\n\n" +
+		"[next](" + vol2.LinkTo("docs/notes.md", "setup") + ")\n" +
+		"[root](" + vol2.LinkTo("", "")[:len(docURIPrefix)+MinFingerprintPrefix] + "/)\n" +
+		"[logo](" + vol2.LinkTo("assets/logo.png", "") + ")\n" +
+		"[gone](" + vol2.LinkTo("docs/missing.md", "") + ")\n" +
+		"[frag](" + vol2.LinkTo("docs/notes.md", "nope") + ")\n")
+	lib := NewLibrary(vol1, vol2)
+
+	target, err := lib.Resolve(vol2.LinkTo("docs/notes.md", "setup"))
+	if err != nil || target.Doc != vol2 || target.File == nil || target.File.Path != "docs/notes.md" || target.Fragment != "setup" {
+		t.Fatalf("Resolve = %+v, %v", target, err)
+	}
+	if target, err = lib.Resolve(vol2.LinkTo("", "")); err != nil || target.File.Path != "docs/index.md" {
+		t.Fatalf("root: %+v, %v", target, err)
+	}
+	if target, err = lib.Resolve(vol2.LinkTo("assets/logo.png", "")); err != nil || target.Media == nil {
+		t.Fatalf("media: %+v, %v", target, err)
+	}
+	if _, err := lib.Resolve("mdocx://doc/ffffffffffffffff/x.md"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown container: %v", err)
+	}
+
+	broken := lib.CheckLinks()
+	got := broken[vol1.Fingerprint()]
+	if len(broken) != 1 || len(got) != 2 || got[0].Line != 6 || got[1].Line != 7 {
+		t.Fatalf("CheckLinks = %+v", broken)
+	}
+	if len(lib.CheckLinks(WithFragmentCheck(false))[vol1.Fingerprint()]) != 1 {
+		t.Fatal("fragment check not disabled")
+	}
+}
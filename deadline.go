@@ -0,0 +1,48 @@
+package mdocx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WithMaxDuration bounds the wall-clock time Decode, DecodeContext, and
+// DecodeInto may spend on one container, for callers that cannot pass a
+// context down to them but must bound their worst case. The budget is enforced
+// as a context deadline would be: on every read from the input, between
+// sections and media items, and between chunks of decompressed output. When it
+// runs out they return an error wrapping context.DeadlineExceeded. A
+// DecodeContext deadline that falls earlier still applies. Zero, the default,
+// sets no budget.
+func WithMaxDuration(d time.Duration) ReadOption {
+	return func(c *readConfig) { c.maxDuration = d }
+}
+
+// WithMaxDurationOnWrite bounds the wall-clock time Encode, EncodeContext, and
+// EncodeStream may spend on one container, as WithMaxDuration does for
+// decoding. The budget is checked between sections, between chunks of
+// compressor input, and between chunks written to the output; EncodeStream
+// also checks it while waiting for files and items. Output written before the
+// budget ran out is not removed.
+func WithMaxDurationOnWrite(d time.Duration) WriteOption {
+	return func(c *writeConfig) { c.maxDuration = d }
+}
+
+// withBudget returns ctx with a deadline d from now, or ctx itself if d is
+// not positive. The returned function must be called with the operation's
+// error: it releases the context and marks a deadline error caused by the
+// budget.
+func withBudget(ctx context.Context, d time.Duration) (context.Context, func(err error) error) {
+	if d <= 0 {
+		return ctx, func(err error) error { return err }
+	}
+	budget, cancel := context.WithTimeout(ctx, d)
+	return budget, func(err error) error {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return fmt.Errorf("mdocx: maximum duration of %v exceeded: %w", d, err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,68 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowReader delays every read from r.
+func slowReader(r io.Reader) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		return r.Read(p[:min(len(p), 64)])
+	})
+}
+
+type slowWriter struct{}
+
+func (slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(5 * time.Millisecond)
+	return len(p), nil
+}
+
+func TestMaxDuration(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	isBudget := func(err error) bool {
+		return errors.Is(err, context.DeadlineExceeded) && strings.Contains(err.Error(), "maximum duration")
+	}
+	if _, err := Decode(slowReader(bytes.NewReader(data)), WithMaxDuration(10*time.Millisecond)); !isBudget(err) {
+		t.Errorf("Decode: %v", err)
+	}
+	if err := DecodeInto(slowReader(bytes.NewReader(data)), SinkFuncs{}, WithMaxDuration(10*time.Millisecond)); !isBudget(err) {
+		t.Errorf("DecodeInto: %v", err)
+	}
+	if err := Encode(slowWriter{}, sampleDoc(), WithMaxDurationOnWrite(10*time.Millisecond)); !isBudget(err) {
+		t.Errorf("Encode: %v", err)
+	}
+	// Producers that never finish.
+	files, media := make(chan MarkdownFile), make(chan MediaItem)
+	if err := EncodeStream(context.Background(), io.Discard, StreamHeader{}, files, media, WithMaxDurationOnWrite(10*time.Millisecond)); !isBudget(err) {
+		t.Errorf("EncodeStream: %v", err)
+	}
+
+	// A generous budget changes nothing.
+	doc, err := Decode(bytes.NewReader(data), WithMaxDuration(time.Minute))
+	if err != nil || len(doc.Markdown.Files) != 2 {
+		t.Fatalf("Decode within budget: %v", err)
+	}
+	if err := Encode(io.Discard, doc, WithMaxDurationOnWrite(time.Minute)); err != nil {
+		t.Fatalf("Encode within budget: %v", err)
+	}
+
+	// The caller's own deadline is reported as it is.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := DecodeContext(ctx, slowReader(bytes.NewReader(data)), WithMaxDuration(time.Minute)); !errors.Is(err, context.DeadlineExceeded) || isBudget(err) {
+		t.Errorf("DecodeContext with earlier deadline: %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package mdocx
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
@@ -24,111 +26,336 @@ import (
 // Use ReadOption functions to customize this behavior:
 //   - WithReadLimits(l): set custom size limits
 //   - WithVerifyHashes(false): skip hash verification
+//   - WithReadRateLimit(l): throttle reads from r
+//   - WithMaxDuration(d): bound the time decoding may take
+//   - WithStatsCollector(s): record per-stage timings and sizes in s
+//   - WithChecks(c): enforce optional invariants such as media order
+//   - WithMediaFilter(f): drop media items the caller does not need
+//   - WithRejectHook(fn): report why a container was rejected
+//   - WithSectionOrderTolerance(true): accept sections in any order
+//   - WithIgnoreUnknownSections(true): skip sections of unknown types
+//   - WithStreamingInput(true): check the integrity trailer while decoding
+//   - WithColdTier(r): reunite stubs with media from an EncodeTiered cold container
 //
-// Decode returns ErrInvalidMagic if the file is not an MDOCX file,
-// ErrUnsupportedVersion if the version is not 1, ErrLimitExceeded if
-// any size limit is exceeded, or ErrValidation if the document fails validation.
+// Decode reads containers of both format versions (see WithFormatVersion).
+// It returns ErrInvalidMagic if the file is not an MDOCX file,
+// ErrUnsupportedVersion if the version is not 1 or 2 or a version 2 container
+// needs capabilities it lacks, ErrLimitExceeded if
+// any size limit is exceeded, ErrCorrupted if the container has an integrity
+// trailer that does not match, or ErrValidation if the document fails validation.
 func Decode(r io.Reader, opts ...ReadOption) (*Document, error) {
-	cfg := readConfig{limits: defaultLimits(), verifyHashes: true}
-	for _, opt := range opts {
-		opt(&cfg)
+	return DecodeContext(context.Background(), r, opts...)
+}
+
+// DecodeContext is Decode with cancellation: it checks ctx on every read from
+// r, between sections, and between chunks of decompressed output, and returns
+// ctx's error once ctx is done. Validation of the decoded document is not
+// interrupted.
+func DecodeContext(ctx context.Context, r io.Reader, opts ...ReadOption) (_ *Document, err error) {
+	cfg := newReadConfig(opts)
+	ctx, release := withBudget(ctx, cfg.maxDuration)
+	defer func() { err = release(err) }()
+	r = cfg.input(ctx, r)
+
+	st := cfg.stats
+	if st == nil {
+		st = new(DecodeStats)
+	}
+	*st = DecodeStats{}
+	clock := newStageClock()
+	defer func() { st.Total = clock.total() }()
+	section := "header"
+	if cfg.onReject != nil {
+		defer func() {
+			if err != nil {
+				cfg.onReject(newRejection(err, section))
+			}
+		}()
 	}
-	cfg.limits = cfg.limits.withDefaults()
 
 	h, err := readFixedHeader(r)
 	if err != nil {
 		return nil, err
 	}
-	if h.Magic != Magic {
-		return nil, ErrInvalidMagic
-	}
-	if h.FixedHdrSize != fixedHeaderSizeV1 {
-		return nil, fmt.Errorf("%w: fixed header size %d", ErrInvalidHeader, h.FixedHdrSize)
-	}
-	if h.Version != VersionV1 {
-		return nil, ErrUnsupportedVersion
-	}
-	if h.Reserved0 != 0 || h.Reserved1 != 0 {
-		return nil, fmt.Errorf("%w: reserved must be zero", ErrInvalidHeader)
+	if err := checkFixedHeaderV2(h, cfg.limits); err != nil {
+		return nil, err
 	}
-	if h.MetadataLength > cfg.limits.MaxMetadataLen {
-		return nil, fmt.Errorf("%w: metadata length %d", ErrLimitExceeded, h.MetadataLength)
+	var crc *crcReader
+	if h.HeaderFlags&HeaderFlagChecksum != 0 {
+		section = "checksum"
+		if cfg.streaming {
+			if crc, err = newCRCReader(r, h); err != nil {
+				return nil, err
+			}
+			r = crc
+		} else if r, err = verifyChecksum(r, h, cfg); err != nil {
+			return nil, err
+		}
 	}
+	st.BytesRead = uint64(fixedHeaderSizeV1)
+	clock.lap(&st.Header)
 
+	section = "metadata"
 	var metadata map[string]any
 	if h.MetadataLength > 0 {
 		mb := make([]byte, h.MetadataLength)
 		if _, err := io.ReadFull(r, mb); err != nil {
 			return nil, err
 		}
-		if (h.HeaderFlags & HeaderFlagMetadataJSON) == 0 {
-			return nil, fmt.Errorf("%w: metadata present but METADATA_JSON flag not set", ErrInvalidHeader)
-		}
-		if err := json.Unmarshal(mb, &metadata); err != nil {
+		if metadata, err = parseMetadata(h, mb); err != nil {
 			return nil, err
 		}
-		if metadata == nil {
-			return nil, fmt.Errorf("%w: metadata must be a JSON object", ErrInvalidHeader)
-		}
+		st.MetadataBytes = uint64(h.MetadataLength)
+		st.BytesRead += st.MetadataBytes
 	}
+	clock.lap(&st.Metadata)
 
-	mdSec, err := readSectionHeader(r)
-	if err != nil {
-		return nil, err
+	section = "markdown"
+	var (
+		mdSec, mediaSec         sectionHeaderV1
+		mdPayload, mediaPayload []byte
+		extras                  []ExtraSection
+	)
+	if h.Version == VersionV2 {
+		mdSec, mdPayload, mediaSec, mediaPayload, err = readSectionsV2(r, h, cfg.limits, &extras)
+	} else if cfg.anyOrder {
+		mdSec, mdPayload, mediaSec, mediaPayload, err = readSectionsAnyOrder(r, cfg.limits)
+	} else {
+		mdSec, mdPayload, err = readSection(r, SectionMarkdown, cfg.limits, cfg.ignoreUnknown)
 	}
-	if err := validateSectionHeader(mdSec, SectionMarkdown); err != nil {
+	if err != nil {
 		return nil, err
 	}
-	if mdSec.PayloadLen > cfg.limits.MaxMarkdownSectionLen {
-		return nil, fmt.Errorf("%w: markdown section too large", ErrLimitExceeded)
-	}
-	mdPayload := make([]byte, mdSec.PayloadLen)
-	if _, err := io.ReadFull(r, mdPayload); err != nil {
+	st.BytesRead += 16 + mdSec.PayloadLen
+	st.MarkdownCompressed = mdSec.PayloadLen
+	mdGob, err := decompressPayloadContext(ctx, mdSec.compression(), mdSec.SectionFlags, mdPayload, cfg.limits.MaxMarkdownUncompressed)
+	if err != nil {
 		return nil, err
 	}
-	mdGob, err := decompressPayload(mdSec.compression(), mdSec.SectionFlags, mdPayload, cfg.limits.MaxMarkdownUncompressed)
+	st.MarkdownUncompressed = uint64(len(mdGob))
+	clock.lap(&st.MarkdownDecompress)
+	format := payloadFormat(h.HeaderFlags)
+	markdown, err := format.decodeMarkdown(mdGob, cfg.limits)
 	if err != nil {
 		return nil, err
 	}
-	var markdown MarkdownBundle
-	if err := gobDecode(mdGob, &markdown); err != nil {
+	clock.lap(&st.MarkdownGob)
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	mediaSec, err := readSectionHeader(r)
-	if err != nil {
-		return nil, err
+	section = "media"
+	if !cfg.anyOrder && h.Version == VersionV1 {
+		if mediaSec, mediaPayload, err = readSection(r, SectionMedia, cfg.limits, cfg.ignoreUnknown); err != nil {
+			return nil, err
+		}
 	}
-	if err := validateSectionHeader(mediaSec, SectionMedia); err != nil {
-		return nil, err
+	if crc != nil {
+		section = "checksum"
+		if err := crc.verify(h, cfg); err != nil {
+			return nil, err
+		}
+		section = "media"
 	}
-	if mediaSec.PayloadLen > cfg.limits.MaxMediaSectionLen {
-		return nil, fmt.Errorf("%w: media section too large", ErrLimitExceeded)
+	st.BytesRead += 16 + mediaSec.PayloadLen
+	st.MediaCompressed = mediaSec.PayloadLen
+	noMedia, err := checkNoMedia(h, mediaSec)
+	if err != nil {
+		return nil, err
 	}
 	var media MediaBundle
+	var allMedia []MediaItem // IDs and paths of all items, when a media filter may drop some
 	if mediaSec.PayloadLen == 0 {
 		media = MediaBundle{BundleVersion: VersionV1}
+		clock.lap(&st.MediaDecompress)
 	} else {
-		mediaPayload := make([]byte, mediaSec.PayloadLen)
-		if _, err := io.ReadFull(r, mediaPayload); err != nil {
-			return nil, err
-		}
-		mediaGob, err := decompressPayload(mediaSec.compression(), mediaSec.SectionFlags, mediaPayload, cfg.limits.MaxMediaUncompressed)
+		mediaGob, err := decompressPayloadContext(ctx, mediaSec.compression(), mediaSec.SectionFlags, mediaPayload, cfg.limits.MaxMediaUncompressed)
 		if err != nil {
 			return nil, err
 		}
-		if err := gobDecode(mediaGob, &media); err != nil {
+		st.MediaUncompressed = uint64(len(mediaGob))
+		clock.lap(&st.MediaDecompress)
+		itemComp := mediaSec.SectionFlags&sectionFlagItemCompression != 0
+		if cfg.mediaFilter != nil {
+			if media, allMedia, err = decodeFilteredMedia(mediaGob, format, itemComp, cfg); err != nil {
+				return nil, err
+			}
+		} else if media, err = format.decodeMedia(mediaGob, cfg.limits); err != nil {
 			return nil, err
+		} else if itemComp {
+			if err := unpackItems(ctx, media.Items, cfg.limits); err != nil {
+				return nil, err
+			}
 		}
 	}
+	clock.lap(&st.MediaGob)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	doc := &Document{Metadata: metadata, Markdown: markdown, Media: media}
-	if err := validateDocument(doc, cfg.limits, cfg.verifyHashes); err != nil {
+	section = "document"
+	doc := &Document{Metadata: metadata, Markdown: markdown, Media: media, NoMedia: noMedia, ExtraSections: extras}
+	if err := validateDocumentVerifying(doc, cfg.limits, cfg.verifier()); err != nil {
+		return nil, err
+	}
+	if allMedia != nil {
+		err = (&Document{Metadata: metadata, Markdown: markdown, Media: MediaBundle{Items: allMedia}}).CheckInvariants(cfg.checks)
+	} else {
+		err = doc.CheckInvariants(cfg.checks)
+	}
+	if err != nil {
 		return nil, err
 	}
+	section = "media"
+	if mediaSec.SectionFlags&sectionFlagDuplicateStubs != 0 {
+		if err := resolveDuplicates(doc.Media.Items, cfg.mediaFilter != nil); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.coldTier != nil {
+		if err := doc.ResolveColdTier(cfg.coldTier); err != nil {
+			return nil, err
+		}
+	}
+	clock.lap(&st.Validation)
 	return doc, nil
 }
 
+// newReadConfig returns the read configuration for opts with defaults applied.
+func newReadConfig(opts []ReadOption) readConfig {
+	cfg := readConfig{limits: defaultLimits(), verifyHashes: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.limits = cfg.limits.withDefaults()
+	return cfg
+}
+
+// checkFixedHeader validates the fields of a fixed header against the v1 rules and limits.
+func checkFixedHeader(h fixedHeaderV1, limits Limits) error {
+	if h.Magic != Magic {
+		return ErrInvalidMagic
+	}
+	if h.FixedHdrSize != fixedHeaderSizeV1 {
+		return fmt.Errorf("%w: fixed header size %d", ErrInvalidHeader, h.FixedHdrSize)
+	}
+	if h.Version != VersionV1 {
+		return ErrUnsupportedVersion
+	}
+	if h.Reserved0 != 0 || h.Reserved1 != 0 {
+		return fmt.Errorf("%w: reserved must be zero", ErrInvalidHeader)
+	}
+	if h.MetadataLength > limits.MaxMetadataLen {
+		return exceeds("MaxMetadataLen", "metadata length %d", h.MetadataLength)
+	}
+	return nil
+}
+
+// parseMetadata parses the metadata block mb that followed header h.
+func parseMetadata(h fixedHeaderV1, mb []byte) (map[string]any, error) {
+	if (h.HeaderFlags & HeaderFlagMetadataJSON) == 0 {
+		return nil, fmt.Errorf("%w: metadata present but METADATA_JSON flag not set", ErrInvalidHeader)
+	}
+	var metadata map[string]any
+	if err := json.Unmarshal(mb, &metadata); err != nil {
+		return nil, newMetadataError(mb, err)
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("%w: metadata must be a JSON object", ErrInvalidHeader)
+	}
+	return metadata, nil
+}
+
+// checkNoMedia reports whether header h declares that the container has no media
+// bundle, and rejects a non-empty Media section payload when it does.
+func checkNoMedia(h fixedHeaderV1, mediaSec sectionHeaderV1) (bool, error) {
+	if h.HeaderFlags&HeaderFlagNoMedia == 0 {
+		return false, nil
+	}
+	if mediaSec.PayloadLen != 0 {
+		return true, fmt.Errorf("%w: NO_MEDIA flag set but media payload is not empty", ErrInvalidSection)
+	}
+	return true, nil
+}
+
+// readSection reads a section header of the wanted type and its payload from r,
+// checking them against the section limits of that type. If skipUnknown is
+// set, sections of unknown types before it are skipped.
+func readSection(r io.Reader, want SectionType, limits Limits, skipUnknown bool) (sectionHeaderV1, []byte, error) {
+	sh, err := nextSectionHeader(r, limits, skipUnknown)
+	if err != nil {
+		return sh, nil, err
+	}
+	payload, err := readSectionPayload(r, sh, want, limits)
+	return sh, payload, err
+}
+
+// readSectionPayload checks the section header sh, already read from r, and
+// reads its payload. The uncompressed-length prefix of a compressed payload is
+// checked before the rest is read, and memory grows with the bytes received
+// rather than being allocated for the declared length up front, so a header
+// that lies about its length fails fast on a stream.
+func readSectionPayload(r io.Reader, sh sectionHeaderV1, want SectionType, limits Limits) ([]byte, error) {
+	if err := validateSectionHeader(sh, want); err != nil {
+		return nil, err
+	}
+	maxLen, maxGob, lenLimit, gobLimit := limits.MaxMarkdownSectionLen, limits.MaxMarkdownUncompressed, "MaxMarkdownSectionLen", "MaxMarkdownUncompressed"
+	if want == SectionMedia {
+		maxLen, maxGob, lenLimit, gobLimit = limits.MaxMediaSectionLen, limits.MaxMediaUncompressed, "MaxMediaSectionLen", "MaxMediaUncompressed"
+	}
+	if sh.PayloadLen > maxLen {
+		return nil, exceeds(lenLimit, "%s section too large", sectionName(want))
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(min(sh.PayloadLen, payloadReadChunk)))
+	if sh.hasUncompressedLen() && sh.PayloadLen >= 8 {
+		if _, err := io.CopyN(&buf, r, 8); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if n := binary.LittleEndian.Uint64(buf.Bytes()); n > maxGob {
+			return nil, exceeds(gobLimit, "%s section uncompressed length %d", sectionName(want), n)
+		}
+	}
+	if _, err := io.CopyN(&buf, r, int64(sh.PayloadLen)-int64(buf.Len())); err != nil {
+		if buf.Len() > 0 {
+			err = unexpectedEOF(err)
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// payloadReadChunk bounds the memory readSectionPayload allocates before any
+// payload bytes have arrived.
+const payloadReadChunk = 1 << 20
+
+// sectionName returns the lower-case name of a section type in messages.
+func sectionName(typ SectionType) string {
+	if typ == SectionMarkdown {
+		return "markdown"
+	}
+	return "media"
+}
+
+// unexpectedEOF turns io.EOF, which means the input ended in the middle of a
+// structure, into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeMarkdownPayload decompresses and decodes a Markdown section payload
+// serialized in format f, checking ctx between chunks of decompressed output.
+func decodeMarkdownPayload(ctx context.Context, sh sectionHeaderV1, payload []byte, f PayloadFormat, limits Limits) (MarkdownBundle, error) {
+	mdGob, err := decompressPayloadContext(ctx, sh.compression(), sh.SectionFlags, payload, limits.MaxMarkdownUncompressed)
+	if err != nil {
+		return MarkdownBundle{}, err
+	}
+	return f.decodeMarkdown(mdGob, limits)
+}
+
 // gobDecode deserializes data into out using Go's gob encoding.
 func gobDecode(data []byte, out any) error {
 	dec := gob.NewDecoder(bytes.NewReader(data))
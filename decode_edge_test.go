@@ -3,6 +3,8 @@ package mdocx
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"io"
 	"testing"
 )
 
@@ -160,3 +162,33 @@ func TestDecode_MarkdownDecompressError(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestDecode_StreamLimitsCheckedEarly(t *testing.T) {
+	doc := sampleDoc()
+	doc.Metadata = nil
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMarkdownCompression(CompZSTD)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	mdOff := 32
+
+	// The uncompressed length is checked before the rest of the payload is read.
+	r := &countingReader{r: bytes.NewReader(b)}
+	_, err := Decode(r, WithReadLimits(Limits{MaxMarkdownUncompressed: 1}))
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "MaxMarkdownUncompressed" {
+		t.Fatalf("expected MaxMarkdownUncompressed error, got %v", err)
+	}
+	if want := int64(mdOff + 16 + 8); r.n != want {
+		t.Errorf("read %d bytes, want %d", r.n, want)
+	}
+
+	// A section header declaring a huge payload does not allocate it up front.
+	lying := bytes.Clone(b[:mdOff+16+100])
+	binary.LittleEndian.PutUint64(lying[mdOff+4:mdOff+12], 1<<50)
+	_, err = Decode(&countingReader{r: bytes.NewReader(lying)}, WithReadLimits(Limits{MaxMarkdownSectionLen: 1 << 60}))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Diff change kinds.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffEntry describes a Markdown file or media item that differs between two
+// documents.
+type DiffEntry struct {
+	// Change is DiffAdded, DiffRemoved, or DiffChanged.
+	Change string `json:"change"`
+	// Path is the container path in the new document, or in the old one for
+	// removed entries.
+	Path string `json:"path,omitempty"`
+	// OldPath is the previous path of a media item whose path changed.
+	OldPath string `json:"old_path,omitempty"`
+	// ID is the media item ID (media entries only).
+	ID string `json:"id,omitempty"`
+	// OldSize and OldSHA256 describe the old content; they are zero for added
+	// entries.
+	OldSize   int64  `json:"old_size,omitempty"`
+	OldSHA256 string `json:"old_sha256,omitempty"`
+	// NewSize and NewSHA256 describe the new content; they are zero for removed
+	// entries.
+	NewSize   int64  `json:"new_size,omitempty"`
+	NewSHA256 string `json:"new_sha256,omitempty"`
+}
+
+// MetadataDiff describes a metadata key that differs between two documents.
+type MetadataDiff struct {
+	// Change is DiffAdded, DiffRemoved, or DiffChanged.
+	Change string `json:"change"`
+	Key    string `json:"key"`
+	// Old and New are the values of Key; Old is nil for added keys and New is
+	// nil for removed keys.
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// DiffReport lists the differences between two documents.
+type DiffReport struct {
+	// Markdown lists changed Markdown files, sorted by path.
+	Markdown []DiffEntry `json:"markdown"`
+	// Media lists changed media items, sorted by ID.
+	Media []DiffEntry `json:"media"`
+	// Metadata lists changed metadata keys, sorted by key.
+	Metadata []MetadataDiff `json:"metadata"`
+}
+
+// Empty reports whether r lists no differences.
+func (r DiffReport) Empty() bool {
+	return len(r.Markdown) == 0 && len(r.Media) == 0 && len(r.Metadata) == 0
+}
+
+// Diff compares the old document a with the new document b. Markdown files
+// are matched by path and reported as changed when their content differs.
+// Media items are matched by ID and reported as changed when their content or
+// path differs. Metadata keys are reported as changed when their values differ
+// as JSON. Compression, order, and stored SHA256 fields are ignored; content
+// hashes are computed from the data. A nil document compares as empty.
+func Diff(a, b *Document) DiffReport {
+	if a == nil {
+		a = &Document{}
+	}
+	if b == nil {
+		b = &Document{}
+	}
+	var r DiffReport
+
+	oldFiles := make(map[string]MarkdownFile, len(a.Markdown.Files))
+	for _, f := range a.Markdown.Files {
+		oldFiles[f.Path] = f
+	}
+	newFiles := make(map[string]bool, len(b.Markdown.Files))
+	for _, f := range b.Markdown.Files {
+		newFiles[f.Path] = true
+		e := DiffEntry{Path: f.Path, NewSize: int64(len(f.Content)), NewSHA256: hexSHA256(f.Content)}
+		old, ok := oldFiles[f.Path]
+		switch {
+		case !ok:
+			e.Change = DiffAdded
+		case !bytes.Equal(old.Content, f.Content):
+			e.Change = DiffChanged
+			e.OldSize, e.OldSHA256 = int64(len(old.Content)), hexSHA256(old.Content)
+		default:
+			continue
+		}
+		r.Markdown = append(r.Markdown, e)
+	}
+	for _, f := range a.Markdown.Files {
+		if !newFiles[f.Path] {
+			r.Markdown = append(r.Markdown, DiffEntry{Change: DiffRemoved, Path: f.Path, OldSize: int64(len(f.Content)), OldSHA256: hexSHA256(f.Content)})
+		}
+	}
+	sort.SliceStable(r.Markdown, func(i, j int) bool { return r.Markdown[i].Path < r.Markdown[j].Path })
+
+	oldItems := make(map[string]MediaItem, len(a.Media.Items))
+	for _, it := range a.Media.Items {
+		oldItems[it.ID] = it
+	}
+	newItems := make(map[string]bool, len(b.Media.Items))
+	for _, it := range b.Media.Items {
+		newItems[it.ID] = true
+		e := DiffEntry{Path: it.Path, ID: it.ID, NewSize: int64(len(it.Data)), NewSHA256: hexSHA256(it.Data)}
+		old, ok := oldItems[it.ID]
+		switch {
+		case !ok:
+			e.Change = DiffAdded
+		case !bytes.Equal(old.Data, it.Data) || old.Path != it.Path:
+			e.Change = DiffChanged
+			e.OldSize, e.OldSHA256 = int64(len(old.Data)), hexSHA256(old.Data)
+			if old.Path != it.Path {
+				e.OldPath = old.Path
+			}
+		default:
+			continue
+		}
+		r.Media = append(r.Media, e)
+	}
+	for _, it := range a.Media.Items {
+		if !newItems[it.ID] {
+			r.Media = append(r.Media, DiffEntry{Change: DiffRemoved, Path: it.Path, ID: it.ID, OldSize: int64(len(it.Data)), OldSHA256: hexSHA256(it.Data)})
+		}
+	}
+	sort.SliceStable(r.Media, func(i, j int) bool { return r.Media[i].ID < r.Media[j].ID })
+
+	for k, nv := range b.Metadata {
+		ov, ok := a.Metadata[k]
+		switch {
+		case !ok:
+			r.Metadata = append(r.Metadata, MetadataDiff{Change: DiffAdded, Key: k, New: nv})
+		case !sameJSON(ov, nv):
+			r.Metadata = append(r.Metadata, MetadataDiff{Change: DiffChanged, Key: k, Old: ov, New: nv})
+		}
+	}
+	for k, ov := range a.Metadata {
+		if _, ok := b.Metadata[k]; !ok {
+			r.Metadata = append(r.Metadata, MetadataDiff{Change: DiffRemoved, Key: k, Old: ov})
+		}
+	}
+	sort.Slice(r.Metadata, func(i, j int) bool { return r.Metadata[i].Key < r.Metadata[j].Key })
+	return r
+}
+
+// hexSHA256 returns the lowercase hex SHA-256 of b.
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// sameJSON reports whether x and y marshal to the same JSON, so that a value
+// set in Go compares equal to the same value decoded from a container.
+// Values that cannot be marshaled are compared with reflect.DeepEqual.
+func sameJSON(x, y any) bool {
+	bx, errx := json.Marshal(x)
+	by, erry := json.Marshal(y)
+	if errx != nil || erry != nil {
+		return reflect.DeepEqual(x, y)
+	}
+	return bytes.Equal(bx, by)
+}
@@ -0,0 +1,90 @@
+package mdocx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := &Document{
+		Metadata: map[string]any{"title": "Guide", "version": 1, "tags": []string{"a"}},
+		Markdown: MarkdownBundle{Files: []MarkdownFile{
+			{Path: "index.md", Content: []byte("# Guide\n")},
+			{Path: "old.md", Content: []byte("gone\n")},
+			{Path: "same.md", Content: []byte("same\n")},
+		}},
+		Media: MediaBundle{Items: []MediaItem{
+			{ID: "logo", Path: "logo.png", Data: []byte{1}},
+			{ID: "moved", Path: "a.png", Data: []byte{2}},
+			{ID: "drop", Data: []byte{3}},
+		}},
+	}
+	b := &Document{
+		Metadata: map[string]any{"title": "Guide", "version": 2, "tags": []any{"a"}, "lang": "en"},
+		Markdown: MarkdownBundle{Files: []MarkdownFile{
+			{Path: "same.md", Content: []byte("same\n")},
+			{Path: "new.md", Content: []byte("hello\n")},
+			{Path: "index.md", Content: []byte("# Guide v2\n")},
+		}},
+		Media: MediaBundle{Items: []MediaItem{
+			{ID: "moved", Path: "b.png", Data: []byte{2}},
+			{ID: "logo", Path: "logo.png", Data: []byte{1, 1}},
+			{ID: "extra", Data: []byte{4}},
+		}},
+	}
+	r := Diff(a, b)
+
+	var md []string
+	for _, e := range r.Markdown {
+		md = append(md, e.Change+" "+e.Path)
+	}
+	if got, want := strings.Join(md, "|"), "changed index.md|added new.md|removed old.md"; got != want {
+		t.Errorf("markdown = %s, want %s", got, want)
+	}
+	if e := r.Markdown[0]; e.OldSize != 8 || e.NewSize != 11 || e.OldSHA256 == e.NewSHA256 || e.OldSHA256 == "" {
+		t.Errorf("changed entry = %+v", e)
+	}
+
+	var media []string
+	for _, e := range r.Media {
+		media = append(media, e.Change+" "+e.ID+" "+e.OldPath)
+	}
+	if got, want := strings.Join(media, "|"), "removed drop |added extra |changed logo |changed moved a.png"; got != want {
+		t.Errorf("media = %s, want %s", got, want)
+	}
+
+	var meta []string
+	for _, m := range r.Metadata {
+		meta = append(meta, m.Change+" "+m.Key)
+	}
+	if got, want := strings.Join(meta, "|"), "added lang|changed version"; got != want {
+		t.Errorf("metadata = %s, want %s", got, want)
+	}
+
+	if !Diff(a, a).Empty() || r.Empty() {
+		t.Error("Empty is wrong")
+	}
+	if r := Diff(nil, b); len(r.Markdown) != 3 || len(r.Media) != 3 || len(r.Metadata) != 4 {
+		t.Errorf("Diff(nil, b) = %+v", r)
+	}
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	doc := &Document{
+		Metadata: map[string]any{"title": "T", "n": 3, "nested": map[string]any{"k": []int{1, 2}}},
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, Files: []MarkdownFile{{Path: "a.md", Content: []byte("a")}}},
+		Media:    MediaBundle{BundleVersion: VersionV1, Items: []MediaItem{{ID: "m", Path: "m.bin", Data: []byte{1, 2}}}},
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompZSTD)); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := Diff(doc, decoded); !r.Empty() {
+		t.Errorf("decoded document differs: %+v", r)
+	}
+}
@@ -0,0 +1,131 @@
+package mdocx
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// Fingerprint returns a hex SHA-256 identifying the content of d. It covers
+// metadata (except the audit log), Markdown files, and media items, and is
+// independent of compression and of the order of files and items, so it only
+// changes when content changes. Fingerprints identify containers in audit logs
+// and in mdocx://doc/ links.
+func (d *Document) Fingerprint() string {
+	sum := contentDigest(d, AuditMetadataKey)
+	return hex.EncodeToString(sum[:])
+}
+
+// Digest returns a SHA-256 of the content of d, for deduplication and change
+// detection: two documents have the same digest if they have the same
+// metadata, Markdown files, and media items, however their containers were
+// compressed or indexed. Metadata is hashed as canonical JSON, with sorted
+// keys, so values that encode to the same JSON, such as int 1 and float64 1,
+// hash alike. Files and items are hashed in path and ID order, so reordering
+// them does not change the digest, and SHA256 fields are recomputed from the
+// data rather than trusted.
+//
+// Unlike Fingerprint, Digest covers the audit log, so appending an audit event
+// changes it. It returns an error wrapping ErrValidation if the metadata
+// cannot be encoded as JSON.
+func (d *Document) Digest() ([32]byte, error) {
+	if d.Metadata != nil {
+		if _, err := json.Marshal(d.Metadata); err != nil {
+			return [32]byte{}, fmt.Errorf("%w: metadata: %v", ErrValidation, err)
+		}
+	}
+	return contentDigest(d), nil
+}
+
+// contentDigest returns a SHA-256 over the logical content of doc: metadata
+// (as canonical JSON, without the keys in skipMeta), the Markdown bundle, and
+// the media items. It does not depend on compression, on the order of files or
+// items, or on whether SHA256 fields are populated.
+func contentDigest(doc *Document, skipMeta ...string) [32]byte {
+	h := sha256.New()
+	meta := doc.Metadata
+	if len(skipMeta) > 0 && meta != nil {
+		meta = make(map[string]any, len(doc.Metadata))
+		for k, v := range doc.Metadata {
+			meta[k] = v
+		}
+		for _, k := range skipMeta {
+			delete(meta, k)
+		}
+	}
+	if meta != nil {
+		b, err := json.Marshal(meta)
+		if err != nil {
+			// Unmarshalable metadata cannot be encoded either; hash its absence
+			// so the digest stays defined.
+			b = nil
+		}
+		digestField(h, b)
+	} else {
+		digestField(h, nil)
+	}
+	digestField(h, []byte(doc.Markdown.RootPath))
+
+	files := make([]MarkdownFile, len(doc.Markdown.Files))
+	copy(files, doc.Markdown.Files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	digestUint(h, uint64(len(files)))
+	for _, f := range files {
+		digestField(h, []byte(f.Path))
+		digestField(h, f.Content)
+		digestUint(h, uint64(len(f.MediaRefs)))
+		for _, r := range f.MediaRefs {
+			digestField(h, []byte(r))
+		}
+		digestAttrs(h, f.Attributes)
+	}
+
+	if doc.NoMedia {
+		digestUint(h, 1)
+	} else {
+		digestUint(h, 0)
+	}
+	items := make([]MediaItem, len(doc.Media.Items))
+	copy(items, doc.Media.Items)
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	digestUint(h, uint64(len(items)))
+	for _, it := range items {
+		digestField(h, []byte(it.ID))
+		digestField(h, []byte(it.Path))
+		digestField(h, []byte(it.MIMEType))
+		sum := it.computedSHA256()
+		h.Write(sum[:])
+		digestAttrs(h, it.Attributes)
+	}
+	var out [32]byte
+	h.Sum(out[:0])
+	return out
+}
+
+func digestUint(h hash.Hash, v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	h.Write(b[:])
+}
+
+func digestField(h hash.Hash, b []byte) {
+	digestUint(h, uint64(len(b)))
+	h.Write(b)
+}
+
+func digestAttrs(h hash.Hash, attrs map[string]string) {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	digestUint(h, uint64(len(keys)))
+	for _, k := range keys {
+		digestField(h, []byte(k))
+		digestField(h, []byte(attrs[k]))
+	}
+}
@@ -0,0 +1,53 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDocumentDigest(t *testing.T) {
+	want, err := sampleDoc().Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range [][]WriteOption{
+		{WithMarkdownCompression(CompNone), WithMediaCompression(CompNone)},
+		{WithMarkdownCompression(CompBR), WithMediaCompression(CompLZ4), WithIndex(true), WithChecksum(true)},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, sampleDoc(), opts...); err != nil {
+			t.Fatal(err)
+		}
+		doc, err := Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := doc.Digest(); err != nil || got != want {
+			t.Errorf("decoded digest = %x, %v; want %x", got, err, want)
+		}
+	}
+
+	doc := sampleDoc()
+	doc.Markdown.Files[0], doc.Markdown.Files[1] = doc.Markdown.Files[1], doc.Markdown.Files[0]
+	if got, _ := doc.Digest(); got != want {
+		t.Error("reordering files changed the digest")
+	}
+	doc.Markdown.Files[0].Content = []byte("changed")
+	if got, _ := doc.Digest(); got == want {
+		t.Error("changing content kept the digest")
+	}
+	doc = sampleDoc()
+	if err := doc.AppendAuditEvent(AuditEvent{Tool: "test", Operation: "touch"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := doc.Digest(); got == want {
+		t.Error("appending an audit event kept the digest")
+	}
+
+	doc.Metadata["bad"] = math.NaN()
+	if _, err := doc.Digest(); !errors.Is(err, ErrValidation) {
+		t.Errorf("NaN metadata: %v", err)
+	}
+}
@@ -13,7 +13,7 @@
 //   - A Media bundle section containing zero or more media items
 //
 // Payloads are serialized using Go's encoding/gob and optionally compressed using
-// ZIP, Zstandard, LZ4, or Brotli compression.
+// ZIP, Zstandard, LZ4, Brotli, or XZ compression.
 //
 // # Basic Usage
 //
@@ -0,0 +1,182 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"maps"
+)
+
+// DuplicateOfAttr is the media item attribute of the stubs Encode writes with
+// DuplicatesDedupe for items whose data another item holds. Its value is the
+// ID of that item. It has this meaning only in containers whose Media section
+// is marked as holding stubs (rfc.md §5.2.5); elsewhere it is an ordinary
+// attribute.
+const DuplicateOfAttr = "duplicate_of"
+
+// DuplicatePolicy selects what Encode does with media items that have the
+// same data under different IDs (see WithDuplicateMedia).
+type DuplicatePolicy uint8
+
+const (
+	// DuplicatesAllow writes every item with its data, the default.
+	DuplicatesAllow DuplicatePolicy = iota
+	// DuplicatesWarn writes every item with its data and reports each group
+	// of duplicates.
+	DuplicatesWarn
+	// DuplicatesDedupe stores the data once: later items with the same data
+	// are written as stubs with no data and the DuplicateOfAttr attribute
+	// naming the first item. Each group is reported. Items that already have
+	// the DuplicateOfAttr attribute are an ErrValidation.
+	DuplicatesDedupe
+	// DuplicatesError makes Encode fail with ErrValidation.
+	DuplicatesError
+)
+
+// DuplicateMedia describes media items with identical data.
+type DuplicateMedia struct {
+	// ID is the first item with the data, in bundle order.
+	ID string
+	// Duplicates lists the IDs of the later items with the same data.
+	Duplicates []string
+	// Size is the length of the data in bytes.
+	Size int64
+}
+
+// WithDuplicateMedia sets what Encode does when media items share identical
+// data under different IDs; see DuplicatePolicy. report, if not nil, is called
+// with each group of duplicates under DuplicatesWarn and DuplicatesDedupe,
+// before anything is written. Items without data are never duplicates.
+//
+// Decode restores the items of a deduplicated container, sharing the data of
+// the item that holds it; DecodeInto, Reader, and Mapped return the stubs as
+// stored. The default is DuplicatesAllow.
+func WithDuplicateMedia(p DuplicatePolicy, report func(DuplicateMedia)) WriteOption {
+	return func(c *writeConfig) { c.duplicates, c.onDuplicate = p, report }
+}
+
+// DuplicateMedia returns the groups of media items of d with identical,
+// non-empty data, in bundle order of their first items. The data is hashed:
+// stored SHA256 values may not have been verified, so they are not used.
+func (d *Document) DuplicateMedia() []DuplicateMedia {
+	var groups []DuplicateMedia
+	first := make(map[[32]byte]int)
+	for _, it := range d.Media.Items {
+		if len(it.Data) == 0 {
+			continue
+		}
+		sum := sha256.Sum256(it.Data)
+		g, ok := first[sum]
+		if !ok {
+			first[sum] = len(groups)
+			groups = append(groups, DuplicateMedia{ID: it.ID, Size: int64(len(it.Data))})
+			continue
+		}
+		groups[g].Duplicates = append(groups[g].Duplicates, it.ID)
+	}
+	out := groups[:0]
+	for _, g := range groups {
+		if len(g.Duplicates) > 0 {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// applyDuplicatePolicy returns the media items Encode writes for doc under
+// the configured DuplicatePolicy, and whether any of them is a stub.
+func (c writeConfig) applyDuplicatePolicy(doc *Document) ([]MediaItem, bool, error) {
+	if c.duplicates == DuplicatesAllow {
+		return doc.Media.Items, false, nil
+	}
+	if c.duplicates > DuplicatesError {
+		return nil, false, fmt.Errorf("%w: unknown duplicate policy %d", ErrValidation, c.duplicates)
+	}
+	if c.duplicates == DuplicatesDedupe {
+		for _, it := range doc.Media.Items {
+			if _, ok := it.Attributes[DuplicateOfAttr]; ok {
+				return nil, false, fmt.Errorf("%w: media item %q: attribute %s is reserved for stubs", ErrValidation, it.ID, DuplicateOfAttr)
+			}
+		}
+	}
+	groups := doc.DuplicateMedia()
+	if len(groups) == 0 {
+		return doc.Media.Items, false, nil
+	}
+	if c.duplicates == DuplicatesError {
+		return nil, false, fmt.Errorf("%w: media items %q and %q have identical data", ErrValidation, groups[0].ID, groups[0].Duplicates[0])
+	}
+	if c.onDuplicate != nil {
+		for _, g := range groups {
+			c.onDuplicate(g)
+		}
+	}
+	if c.duplicates == DuplicatesWarn {
+		return doc.Media.Items, false, nil
+	}
+
+	byID := make(map[string]int, len(doc.Media.Items))
+	for i, it := range doc.Media.Items {
+		byID[it.ID] = i
+	}
+	items := append([]MediaItem(nil), doc.Media.Items...)
+	stubs := false
+	for _, g := range groups {
+		held := doc.Media.Items[byID[g.ID]]
+		for _, id := range g.Duplicates {
+			it := items[byID[id]]
+			// Only stub items whose bytes match, not just their hashes.
+			if !bytes.Equal(it.Data, held.Data) {
+				continue
+			}
+			stub := MediaItem{ID: it.ID, Path: it.Path, MIMEType: it.MIMEType, Attributes: maps.Clone(it.Attributes)}
+			(*Attributes)(&stub.Attributes).SetString(DuplicateOfAttr, g.ID)
+			items[byID[id]] = stub
+			stubs = true
+		}
+	}
+	return items, stubs, nil
+}
+
+// resolveDuplicates gives the stubs written by DuplicatesDedupe the data and
+// SHA256 of the items they name and removes DuplicateOfAttr. Callers only use
+// it for Media sections marked as holding stubs. Stubs whose item is missing
+// are left as they are if filtered is set, when WithMediaFilter may have
+// dropped it, and are an error wrapping ErrValidation otherwise.
+func resolveDuplicates(items []MediaItem, filtered bool) error {
+	var byID map[string]int
+	var stubs [][2]int // stub index, index of the item holding its data
+	for i, it := range items {
+		target, ok := it.Attributes[DuplicateOfAttr]
+		if !ok {
+			continue
+		}
+		if byID == nil {
+			byID = make(map[string]int, len(items))
+			for j, it := range items {
+				byID[it.ID] = j
+			}
+		}
+		j, found := byID[target]
+		if !found && filtered {
+			continue
+		}
+		if !found || len(it.Data) != 0 {
+			return fmt.Errorf("%w: media item %q: invalid %s attribute", ErrValidation, it.ID, DuplicateOfAttr)
+		}
+		if _, stub := items[j].Attributes[DuplicateOfAttr]; stub || len(items[j].Data) == 0 {
+			return fmt.Errorf("%w: media item %q: invalid %s attribute", ErrValidation, it.ID, DuplicateOfAttr)
+		}
+		stubs = append(stubs, [2]int{i, j})
+	}
+	for _, s := range stubs {
+		it, held := &items[s[0]], items[s[1]]
+		it.Data, it.SHA256 = held.Data, itemSum(held.SHA256, held.Data)
+		it.Attributes = maps.Clone(it.Attributes)
+		delete(it.Attributes, DuplicateOfAttr)
+		if len(it.Attributes) == 0 {
+			it.Attributes = nil
+		}
+	}
+	return nil
+}
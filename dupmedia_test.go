@@ -0,0 +1,134 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// dupDoc returns sampleDoc with two more items, one sharing the logo's data.
+func dupDoc() *Document {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "logo-copy", Path: "assets/copy.png", MIMEType: "image/png", Data: []byte{1, 2, 3}, Attributes: map[string]string{"alt": "Copy"}},
+		MediaItem{ID: "other", MIMEType: "application/octet-stream", Data: []byte{9}},
+	)
+	return doc
+}
+
+func TestDuplicateMedia(t *testing.T) {
+	want := []DuplicateMedia{{ID: "logo", Duplicates: []string{"logo-copy"}, Size: 3}}
+	if got := dupDoc().DuplicateMedia(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DuplicateMedia = %+v", got)
+	}
+	if got := sampleDoc().DuplicateMedia(); len(got) != 0 {
+		t.Fatalf("DuplicateMedia without duplicates = %+v", got)
+	}
+	// Stored hashes are not trusted.
+	forged := dupDoc()
+	forged.Media.Items[0].SHA256 = sha256.Sum256([]byte{9})
+	forged.Media.Items[1].SHA256 = forged.Media.Items[0].SHA256
+	if got := forged.DuplicateMedia(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DuplicateMedia with forged hashes = %+v", got)
+	}
+
+	var allowed, warned, deduped bytes.Buffer
+	if err := Encode(&allowed, dupDoc(), WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	var reported []DuplicateMedia
+	report := func(g DuplicateMedia) { reported = append(reported, g) }
+	if err := Encode(&warned, dupDoc(), WithMediaCompression(CompNone), WithDuplicateMedia(DuplicatesWarn, report)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(warned.Bytes(), allowed.Bytes()) || !reflect.DeepEqual(reported, want) {
+		t.Fatalf("DuplicatesWarn: reported %+v", reported)
+	}
+
+	doc := dupDoc()
+	if err := Encode(&deduped, doc, WithMediaCompression(CompNone), WithDuplicateMedia(DuplicatesDedupe, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Media.Items[2].Data == nil {
+		t.Fatal("Encode modified the document's items")
+	}
+	if deduped.Len() >= allowed.Len() {
+		t.Errorf("deduplicated container is %d bytes, not smaller than %d", deduped.Len(), allowed.Len())
+	}
+	data := deduped.Bytes()
+
+	// Decode restores the stub; a Reader returns it as stored.
+	got, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Media, doc.Media) {
+		t.Fatalf("Decode = %+v\nwant %+v", got.Media, doc.Media)
+	}
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := r.Media()[1]; info.Size != 0 || info.Attributes[DuplicateOfAttr] != "logo" {
+		t.Fatalf("Reader stub = %+v", info)
+	}
+
+	var v2 bytes.Buffer
+	if err := Encode(&v2, dupDoc(), WithFormatVersion(VersionV2), WithDuplicateMedia(DuplicatesDedupe, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := DecodeHeader(bytes.NewReader(v2.Bytes())); err != nil || h.Capabilities&CapabilityDuplicateStubs == 0 {
+		t.Fatalf("v2 header = %+v, %v", h, err)
+	}
+
+	if err := Encode(io.Discard, dupDoc(), WithDuplicateMedia(DuplicatesError, nil)); !errors.Is(err, ErrValidation) {
+		t.Errorf("DuplicatesError: %v", err)
+	}
+	if err := Encode(io.Discard, dupDoc(), WithDuplicateMedia(DuplicatePolicy(9), nil)); !errors.Is(err, ErrValidation) {
+		t.Errorf("unknown policy: %v", err)
+	}
+}
+
+func TestResolveDuplicatesRejects(t *testing.T) {
+	stub := func(target string, data []byte) MediaItem {
+		return MediaItem{ID: "stub", Data: data, Attributes: map[string]string{DuplicateOfAttr: target}}
+	}
+	held := MediaItem{ID: "held", Data: []byte("data")}
+	for name, items := range map[string][]MediaItem{
+		"missing":   {stub("nope", nil)},
+		"with data": {held, stub("held", []byte("x"))},
+		"chained":   {held, {ID: "a", Attributes: map[string]string{DuplicateOfAttr: "held"}}, stub("a", nil)},
+	} {
+		if err := resolveDuplicates(items, false); !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	// A media filter may have dropped the item.
+	items := []MediaItem{stub("nope", nil)}
+	if err := resolveDuplicates(items, true); err != nil || items[0].Attributes[DuplicateOfAttr] != "nope" {
+		t.Errorf("filtered: %v", err)
+	}
+}
+
+func TestDuplicateOfAttrWithoutStubs(t *testing.T) {
+	// Without DuplicatesDedupe the attribute is the application's own.
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "b", Data: []byte("2"), Attributes: map[string]string{DuplicateOfAttr: "a"}})
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it := got.Media.Items[1]; string(it.Data) != "2" || it.Attributes[DuplicateOfAttr] != "a" {
+		t.Fatalf("item = %+v", it)
+	}
+	if err := Encode(io.Discard, doc, WithDuplicateMedia(DuplicatesDedupe, nil)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("DuplicatesDedupe with the attribute set: %v", err)
+	}
+}
@@ -0,0 +1,655 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+)
+
+// EditSession edits a container in place, rewriting only the sections an edit
+// touches, so that replacing one file or image in a multi-gigabyte container
+// does not decode and re-encode all of it.
+//
+// ReplaceFile and ReplaceMedia record edits; Commit writes them:
+//   - A Markdown edit rewrites the Markdown section. If its length changes,
+//     the Media section is moved as raw bytes, without being decoded.
+//   - A media edit rewrites the Media section, streaming the unchanged items
+//     from the container one at a time through a spool file (see
+//     WithSpoolDir). The Markdown section is left as it is. Stubs written by
+//     DuplicatesDedupe stay stubs, except those of a replaced item, which
+//     are given its old data. In a section whose items are compressed on
+//     their own (see WithSmartMediaCompression), unchanged items are copied
+//     as stored, and replaced items are compressed as Encode would, with the
+//     algorithm of the other compressed items.
+//
+// Each section keeps its compression. A footer index (see WithIndex) is
+// rewritten with the new offsets, and an integrity trailer (see WithChecksum)
+// is recomputed, which reads the container once. The metadata and header are
+// never rewritten, so a commit cannot be recorded in the audit log, which is
+// kept in the metadata (see AppendAuditEvent): VerifyAuditLog reports a
+// committed change to an audited container as unrecorded. Edit audited
+// containers with Decode, AppendAuditEvent, and Encode instead.
+//
+// A session opened with OpenEditSession commits in place, which is not
+// atomic: if Commit fails part way, the container is left damaged, and a
+// Reader or Mapped open on the same file keeps the offsets it read when it was
+// opened, so it may return wrong data or fail after a Commit, or see torn
+// sections during one. A session opened with OpenEditFile commits to a copy of
+// the file instead and renames it over the file, as EncodeFile does: a failed
+// Commit leaves the file as it was, and Readers and Mapped views opened before
+// a Commit keep reading the container as it was when they were opened. Open
+// them again to see the edits. The copy costs a pass over the file, but as in
+// place, only the sections an edit touches are decoded and encoded.
+//
+// An EditSession is not safe for concurrent use, and nothing else may write to
+// the underlying file while it is open.
+type EditSession struct {
+	rws io.ReadWriteSeeker
+	// path is the file of a session opened with OpenEditFile, whose rws is
+	// an *os.File.
+	path   string
+	opts   []ReadOption
+	size   int64
+	flags  uint16
+	format PayloadFormat
+	r      *Reader
+	// footer is the container's footer index, if it has one.
+	footer *footerIndex
+
+	mdOff, mediaOff, mediaEnd int64
+	mdSec, mediaSec           sectionHeaderV1
+
+	markdown MarkdownBundle
+	mdDirty  bool
+	media    map[string]MediaItem
+}
+
+// OpenEditSession opens the container in rws for editing. It reads the
+// header, metadata, Markdown bundle, and media index, as NewReader does, but no
+// media data. opts are the ReadOption values used to parse the container,
+// initially and again after each Commit.
+func OpenEditSession(rws io.ReadWriteSeeker, opts ...ReadOption) (*EditSession, error) {
+	s := &EditSession{rws: rws, opts: opts}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// OpenEditFile opens the container in the file at path for editing, as
+// OpenEditSession does. Commit writes the edits to a temporary file in the
+// same directory, syncs it, and renames it over path, so that readers of the
+// file never see a partial container. The replaced file keeps its permissions.
+// Close the session when done.
+func OpenEditFile(path string, opts ...ReadOption) (*EditSession, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &EditSession{rws: f, path: path, opts: opts}
+	if err := s.load(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close closes the file of a session opened with OpenEditFile, discarding any
+// pending edits. It does nothing for a session opened with OpenEditSession.
+func (s *EditSession) Close() error {
+	if s.path == "" {
+		return nil
+	}
+	return s.rws.(*os.File).Close()
+}
+
+// load parses the container and discards any pending edits.
+func (s *EditSession) load() error {
+	size, err := s.rws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	ra := seekReaderAt{s.rws}
+	r, err := NewReader(ra, size, s.opts...)
+	if err != nil {
+		return err
+	}
+	// NewReader has validated the layout, so the section headers are where
+	// the lengths before them put them.
+	h, err := readFixedHeader(io.NewSectionReader(ra, 0, size))
+	if err != nil {
+		return err
+	}
+	mdOff := int64(fixedHeaderSizeV1) + int64(h.MetadataLength)
+	mdSec, err := readSectionHeader(io.NewSectionReader(ra, mdOff, 16))
+	if err != nil {
+		return err
+	}
+	mediaOff := mdOff + 16 + int64(mdSec.PayloadLen)
+	mediaSec, err := readSectionHeader(io.NewSectionReader(ra, mediaOff, 16))
+	if err != nil {
+		return err
+	}
+	var footer *footerIndex
+	if h.HeaderFlags&HeaderFlagIndex != 0 {
+		end := size
+		if h.HeaderFlags&HeaderFlagChecksum != 0 {
+			end -= checksumTrailerSize
+		}
+		if footer, err = readIndex(ra, end, payloadFormat(h.HeaderFlags), newReadConfig(s.opts).limits); err != nil {
+			return err
+		}
+	}
+	*s = EditSession{
+		rws:      s.rws,
+		path:     s.path,
+		opts:     s.opts,
+		size:     size,
+		flags:    h.HeaderFlags,
+		format:   payloadFormat(h.HeaderFlags),
+		r:        r,
+		footer:   footer,
+		mdOff:    mdOff,
+		mediaOff: mediaOff,
+		mediaEnd: mediaOff + 16 + int64(mediaSec.PayloadLen),
+		mdSec:    mdSec,
+		mediaSec: mediaSec,
+		markdown: r.Markdown(),
+		media:    make(map[string]MediaItem),
+	}
+	return nil
+}
+
+// Markdown returns the Markdown bundle with pending edits applied. The
+// returned bundle must not be modified.
+func (s *EditSession) Markdown() MarkdownBundle {
+	return s.markdown
+}
+
+// Media lists the media items in bundle order, with pending edits applied.
+func (s *EditSession) Media() []MediaInfo {
+	out := s.r.Media()
+	for i, info := range out {
+		if it, ok := s.media[info.ID]; ok {
+			out[i] = MediaInfo{ID: it.ID, Path: it.Path, MIMEType: it.MIMEType, Size: int64(len(it.Data)), SHA256: it.SHA256, Attributes: it.Attributes}
+		}
+	}
+	return out
+}
+
+// OpenMedia returns a reader over the stored data of the media item with the
+// given ID, as Reader.OpenMedia does. Pending edits are not reflected.
+func (s *EditSession) OpenMedia(id string) (io.ReadCloser, error) {
+	return s.r.OpenMedia(id)
+}
+
+// ReplaceFile replaces the Markdown file with f's Path. It returns an error
+// wrapping ErrNotFound if there is no such file. f is validated by Commit.
+func (s *EditSession) ReplaceFile(f MarkdownFile) error {
+	for i, old := range s.markdown.Files {
+		if old.Path != f.Path {
+			continue
+		}
+		if !s.mdDirty {
+			s.markdown.Files = append([]MarkdownFile(nil), s.markdown.Files...)
+			s.mdDirty = true
+		}
+		s.markdown.Files[i] = f
+		return nil
+	}
+	return fmt.Errorf("%w: markdown file %q", ErrNotFound, f.Path)
+}
+
+// ReplaceMedia replaces the media item with it.ID, keeping its position in the
+// bundle. It returns an error wrapping ErrNotFound if there is no such item.
+// As with Encode, a zero SHA256 is computed from the data; a non-zero one is
+// checked by Commit.
+func (s *EditSession) ReplaceMedia(it MediaItem) error {
+	if _, ok := s.r.byID[it.ID]; !ok {
+		return fmt.Errorf("%w: media item %q", ErrNotFound, it.ID)
+	}
+	if it.SHA256 == ([32]byte{}) {
+		it.SHA256 = it.computedSHA256()
+	}
+	s.media[it.ID] = it
+	return nil
+}
+
+// Commit validates the pending edits and writes them to the container, then
+// reopens it. It uses the WithWriteLimits, WithVerifyHashesOnWrite,
+// WithSpoolDir, and codec tuning options from opts; other WriteOption values
+// are ignored. If the container shrinks, the underlying file must have a
+// Truncate(size int64) error method, as *os.File does; otherwise Commit
+// returns an error wrapping errors.ErrUnsupported before writing anything.
+//
+// For a session opened with OpenEditFile, Commit also holds the lock of the
+// file (see LockFile) while it writes, and returns an error wrapping ErrLocked
+// if someone else holds it. A caller that already holds the lock passes it
+// with WithLock.
+func (s *EditSession) Commit(opts ...WriteOption) error {
+	if !s.mdDirty && len(s.media) == 0 {
+		return nil
+	}
+	if s.path != "" {
+		return s.commitFile(opts)
+	}
+	if err := s.commit(opts); err != nil {
+		return err
+	}
+	return s.load()
+}
+
+// commitFile commits the pending edits to a copy of the session's file and
+// renames the copy over it.
+func (s *EditSession) commitFile(opts []WriteOption) (err error) {
+	cfg := newWriteConfig(opts)
+	if cfg.lock != nil {
+		if !cfg.lock.covers(s.path) {
+			return fmt.Errorf("%w: lock for %s is not held", ErrLocked, s.path)
+		}
+	} else {
+		l, err := LockFile(s.path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if uerr := l.Unlock(); err == nil {
+				err = uerr
+			}
+		}()
+	}
+
+	old := s.rws.(*os.File)
+	fi, err := old.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			s.rws = old
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := old.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, old); err != nil {
+		return err
+	}
+	s.rws = tmp
+	if err := s.commit(opts); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(fi.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	renamed = true
+	_ = old.Close()
+	return s.load()
+}
+
+// commit writes the pending edits to s.rws in place.
+func (s *EditSession) commit(opts []WriteOption) error {
+	cfg := newWriteConfig(opts)
+	if err := s.validate(cfg); err != nil {
+		return err
+	}
+
+	mdSection, err := s.encodeMarkdown(cfg)
+	if err != nil {
+		return err
+	}
+	mdLen := s.mediaOff - s.mdOff
+	if mdSection != nil {
+		mdLen = int64(len(mdSection))
+	}
+	newMediaOff := s.mdOff + mdLen
+
+	var mediaSection *spool
+	mediaLen := s.mediaEnd - s.mediaOff
+	var footer *footerIndex
+	if s.footer != nil {
+		idx := *s.footer
+		footer = &idx
+	}
+	if len(s.media) > 0 {
+		var scan *mediaIndex
+		if mediaSection, scan, err = s.encodeMedia(cfg); err != nil {
+			return err
+		}
+		defer mediaSection.remove()
+		mediaLen = mediaSection.n
+		if footer != nil {
+			footer.setItems(scan)
+		}
+	}
+
+	end := newMediaOff + mediaLen
+	var index []byte
+	if footer != nil {
+		footer.MediaOffset = uint64(newMediaOff)
+		if index, err = footer.encode(s.format, uint64(end)); err != nil {
+			return err
+		}
+		end += int64(len(index))
+	}
+	newSize := end
+	if s.flags&HeaderFlagChecksum != 0 {
+		newSize += checksumTrailerSize
+	}
+	t, canTruncate := s.rws.(interface{ Truncate(size int64) error })
+	if newSize < s.size && !canTruncate {
+		return fmt.Errorf("%w: container would shrink but %T cannot be truncated", errors.ErrUnsupported, s.rws)
+	}
+
+	if mediaSection == nil && newMediaOff != s.mediaOff {
+		if err := moveBytes(s.rws, newMediaOff, s.mediaOff, mediaLen); err != nil {
+			return err
+		}
+	}
+	if mdSection != nil {
+		if err := writeAt(s.rws, s.mdOff, mdSection); err != nil {
+			return err
+		}
+	}
+	if mediaSection != nil {
+		f, err := mediaSection.rewind()
+		if err != nil {
+			return err
+		}
+		if _, err := s.rws.Seek(newMediaOff, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(s.rws, f, mediaLen); err != nil {
+			return err
+		}
+	}
+	if err := writeAt(s.rws, newMediaOff+mediaLen, index); err != nil {
+		return err
+	}
+	if s.flags&HeaderFlagChecksum != 0 {
+		if err := s.writeChecksum(end); err != nil {
+			return err
+		}
+	}
+	if newSize < s.size {
+		if err := t.Truncate(newSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the container as it will be after the pending edits,
+// verifying the hashes of replaced media items only.
+func (s *EditSession) validate(cfg writeConfig) error {
+	doc := &Document{
+		Markdown: s.markdown,
+		Media:    MediaBundle{BundleVersion: VersionV1, Items: make([]MediaItem, len(s.r.items))},
+	}
+	for i, e := range s.r.items {
+		it, ok := s.media[e.ID]
+		if !ok {
+			it = MediaItem{ID: e.ID, Path: e.Path}
+		}
+		doc.Media.Items[i] = it
+		if _, ok := it.Attributes[DuplicateOfAttr]; ok && s.mediaSec.SectionFlags&sectionFlagDuplicateStubs != 0 {
+			return fmt.Errorf("%w: media item %q: attribute %s is reserved for stubs", ErrValidation, it.ID, DuplicateOfAttr)
+		}
+	}
+	return validateDocumentVerifying(doc, cfg.limits, func(id string) bool {
+		_, ok := s.media[id]
+		return ok && cfg.verifyHashes
+	})
+}
+
+// encodeMarkdown returns the new Markdown section, or nil if it is unchanged.
+func (s *EditSession) encodeMarkdown(cfg writeConfig) ([]byte, error) {
+	if !s.mdDirty {
+		return nil, nil
+	}
+	gob, err := s.format.encodeMarkdown(s.markdown)
+	if err != nil {
+		return nil, err
+	}
+	flags, payload, err := compressPayload(s.mdSec.compression(), gob, cfg.sectionCodecs(SectionMarkdown))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeSectionHeader(&buf, sectionHeaderV1{SectionType: uint16(SectionMarkdown), SectionFlags: flags, PayloadLen: uint64(len(payload))}); err != nil {
+		return nil, err
+	}
+	buf.Write(payload)
+	return buf.Bytes(), nil
+}
+
+// encodeMedia writes the new Media section to a spool file, reading unchanged
+// items from the container one at a time, and returns it with the index of the
+// new Media bundle.
+func (s *EditSession) encodeMedia(cfg writeConfig) (_ *spool, _ *mediaIndex, err error) {
+	elems, err := newSpool(cfg.spoolDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer elems.remove()
+	enc := newElementEncoder(s.format, cborMediaItem)
+	itemComp := s.mediaSec.SectionFlags&sectionFlagItemCompression != 0
+	packComp := s.itemAlgorithm()
+	for i := range s.r.items {
+		it, fresh, err := s.mediaItem(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		if fresh && itemComp {
+			packed, err := packItems(context.Background(), []MediaItem{it}, packComp, cfg.sectionCodecs(SectionMedia))
+			if err != nil {
+				return nil, nil, err
+			}
+			it = packed[0]
+		}
+		b, err := enc.encode(it)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := elems.add(b); err != nil {
+			return nil, nil, err
+		}
+	}
+	head, err := s.format.mediaHead(elems.count)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Write the bundle uncompressed first, to index it.
+	plain, err := newSpool(cfg.spoolDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			plain.remove()
+		}
+	}()
+	keep := s.mediaSec.SectionFlags & (sectionFlagItemCompression | sectionFlagDuplicateStubs)
+	if err := writeSpooledSection(plain, SectionMedia, CompNone, head, elems, keep, cfg); err != nil {
+		return nil, nil, err
+	}
+	if err := plain.bw.Flush(); err != nil {
+		return nil, nil, err
+	}
+	scan, err := s.format.scanMedia(io.NewSectionReader(plain.f, 16, plain.n-16), plain.n-16, cfg.limits.MaxMediaItems)
+	if err != nil {
+		return nil, nil, err
+	}
+	comp := s.mediaSec.compression()
+	if comp == CompNone {
+		return plain, scan, nil
+	}
+	plain.remove()
+	sec, err := newSpool(cfg.spoolDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeSpooledSection(sec, SectionMedia, comp, head, elems, keep, cfg); err != nil {
+		sec.remove()
+		return nil, nil, err
+	}
+	return sec, scan, nil
+}
+
+// mediaItem returns the i-th media item as Commit writes it, with its data
+// read from the container unless it is replaced. Items compressed on their own
+// (see WithSmartMediaCompression) keep their stored data and
+// ItemCompressionAttr. fresh reports whether the data is new to the Media
+// section, and so still to be compressed if its items are compressed on their
+// own.
+func (s *EditSession) mediaItem(i int) (_ MediaItem, fresh bool, _ error) {
+	e := s.r.items[i]
+	if it, ok := s.media[e.ID]; ok {
+		return it, true, nil
+	}
+	it := MediaItem{ID: e.ID, Path: e.Path, MIMEType: e.MIMEType, SHA256: e.SHA256, Attributes: e.Attributes}
+	src := i
+	if j, ok := s.stubOfReplaced(e); ok {
+		it.Attributes = maps.Clone(it.Attributes)
+		delete(it.Attributes, DuplicateOfAttr)
+		if len(it.Attributes) == 0 {
+			it.Attributes = nil
+		}
+		it.SHA256, src, fresh = s.r.items[j].SHA256, j, true
+	} else if e.packed != CompNone {
+		it.Attributes = maps.Clone(it.Attributes)
+		if it.Attributes == nil {
+			it.Attributes = make(map[string]string, 1)
+		}
+		it.Attributes[ItemCompressionAttr] = itemCompressionNames[e.packed]
+		it.Data = make([]byte, e.dataLen)
+		if _, err := s.r.media.ReadAt(it.Data, e.dataOff); err != nil {
+			return it, false, unexpectedEOF(err)
+		}
+		return it, false, nil
+	}
+	rc, err := s.r.open(src)
+	if err != nil {
+		return it, false, err
+	}
+	it.Data, err = io.ReadAll(rc)
+	return it, fresh, err
+}
+
+// itemAlgorithm returns the algorithm new items are compressed with in a
+// Media section whose items are compressed on their own: that of its
+// compressed items, or CompZSTD, the default of WithMediaCompression, if it
+// has none.
+func (s *EditSession) itemAlgorithm() Compression {
+	for _, e := range s.r.items {
+		if e.packed != CompNone {
+			return e.packed
+		}
+	}
+	return CompZSTD
+}
+
+// stubOfReplaced reports whether e is a stub written by DuplicatesDedupe for
+// an item with a pending replacement, and if so returns the index of that item,
+// which holds the stub's data until the commit.
+func (s *EditSession) stubOfReplaced(e mediaEntry) (int, bool) {
+	if s.mediaSec.SectionFlags&sectionFlagDuplicateStubs == 0 {
+		return 0, false
+	}
+	target, ok := e.Attributes[DuplicateOfAttr]
+	if !ok {
+		return 0, false
+	}
+	if _, ok := s.media[target]; !ok {
+		return 0, false
+	}
+	j, ok := s.r.byID[target]
+	return j, ok
+}
+
+// writeChecksum writes the integrity trailer for the first end bytes of the
+// container.
+func (s *EditSession) writeChecksum(end int64) error {
+	if _, err := s.rws.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	crc := crc32.New(castagnoli)
+	if _, err := io.CopyN(crc, s.rws, end); err != nil {
+		return err
+	}
+	var trailer [checksumTrailerSize]byte
+	binary.LittleEndian.PutUint32(trailer[:4], crc.Sum32())
+	copy(trailer[4:], checksumTrailerMagic[:])
+	return writeAt(s.rws, end, trailer[:])
+}
+
+// seekReaderAt adapts an io.ReadSeeker to io.ReaderAt for sequential use.
+type seekReaderAt struct {
+	rs io.ReadSeeker
+}
+
+func (r seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r.rs, p)
+}
+
+// writeAt writes b at offset off of ws.
+func writeAt(ws io.WriteSeeker, off int64, b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if _, err := ws.Seek(off, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := ws.Write(b)
+	return err
+}
+
+// moveChunk is the buffer size moveBytes copies with.
+const moveChunk = 1 << 20
+
+// moveBytes copies the n bytes at src in rws to dst, which may overlap them.
+func moveBytes(rws io.ReadWriteSeeker, dst, src, n int64) error {
+	buf := make([]byte, min(n, moveChunk))
+	ra := seekReaderAt{rws}
+	for done := int64(0); done < n; {
+		k := min(n-done, moveChunk)
+		// Copy back to front when moving towards the end, so that no byte is
+		// overwritten before it has been read.
+		off := done
+		if dst > src {
+			off = n - done - k
+		}
+		if _, err := ra.ReadAt(buf[:k], src+off); err != nil {
+			return err
+		}
+		if err := writeAt(rws, dst+off, buf[:k]); err != nil {
+			return err
+		}
+		done += k
+	}
+	return nil
+}
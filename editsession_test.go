@@ -0,0 +1,489 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// memFile is an in-memory io.ReadWriteSeeker that cannot be truncated.
+type memFile struct {
+	b   []byte
+	off int64
+}
+
+func (m *memFile) Read(p []byte) (int, error) {
+	if m.off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.b[m.off:])
+	m.off += int64(n)
+	return n, nil
+}
+
+func (m *memFile) Write(p []byte) (int, error) {
+	if end := m.off + int64(len(p)); end > int64(len(m.b)) {
+		m.b = append(m.b, make([]byte, end-int64(len(m.b)))...)
+	}
+	n := copy(m.b[m.off:], p)
+	m.off += int64(n)
+	return n, nil
+}
+
+func (m *memFile) Seek(off int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		off += m.off
+	case io.SeekEnd:
+		off += int64(len(m.b))
+	}
+	m.off = off
+	return off, nil
+}
+
+func TestEditSession(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []WriteOption
+	}{
+		{"zstd", nil},
+		{"plain", []WriteOption{WithMarkdownCompression(CompNone), WithMediaCompression(CompNone)}},
+		{"index+checksum", []WriteOption{WithMediaCompression(CompNone), WithIndex(true), WithChecksum(true)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := sampleDoc()
+			doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "clip", Path: "media/clip.bin", MIMEType: "application/octet-stream", Data: bytes.Repeat([]byte("x"), 4096)})
+			f, err := os.OpenFile(writeTempContainer(t, doc, tc.opts...), os.O_RDWR, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			s, err := OpenEditSession(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			check := func() {
+				t.Helper()
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					t.Fatal(err)
+				}
+				got, err := Decode(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got.Markdown, doc.Markdown) || !reflect.DeepEqual(got.Media, doc.Media) || !reflect.DeepEqual(got.Metadata, doc.Metadata) {
+					t.Fatalf("decoded %+v, want %+v", got, doc)
+				}
+				fi, err := f.Stat()
+				if err != nil {
+					t.Fatal(err)
+				}
+				r, err := NewReader(f, fi.Size())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(r.Media(), s.Media()) {
+					t.Fatalf("Media() = %+v, want %+v", s.Media(), r.Media())
+				}
+			}
+
+			// A longer Markdown file moves the Media section.
+			notes := MarkdownFile{Path: "docs/notes.md", Content: bytes.Repeat([]byte("More notes\n"), 200)}
+			if err := s.ReplaceFile(notes); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			doc.Markdown.Files[1] = notes
+			check()
+
+			// A smaller media item shrinks the container.
+			clip := MediaItem{ID: "clip", Path: "media/clip.txt", MIMEType: "text/plain", Data: []byte("short")}
+			if err := s.ReplaceMedia(clip); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			clip.SHA256 = clip.computedSHA256()
+			doc.Media.Items[1] = clip
+			check()
+
+			// Both at once, shrinking the Markdown section.
+			notes.Content = []byte("Short\n")
+			logo := MediaItem{ID: "logo", Path: "assets/logo.png", MIMEType: "image/png", Data: []byte{9, 8, 7, 6}}
+			if err := s.ReplaceFile(notes); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.ReplaceMedia(logo); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			logo.SHA256 = logo.computedSHA256()
+			doc.Markdown.Files[1], doc.Media.Items[0] = notes, logo
+			check()
+		})
+	}
+}
+
+func TestEditSession_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithIndex(true)); err != nil {
+		t.Fatal(err)
+	}
+	orig := bytes.Clone(buf.Bytes())
+	m := &memFile{b: buf.Bytes()}
+	s, err := OpenEditSession(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplaceFile(MarkdownFile{Path: "missing.md"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReplaceFile: %v", err)
+	}
+	if err := s.ReplaceMedia(MediaItem{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ReplaceMedia: %v", err)
+	}
+
+	if err := s.ReplaceMedia(MediaItem{ID: "logo", Data: []byte{1}, SHA256: [32]byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); !errors.Is(err, ErrValidation) {
+		t.Errorf("Commit with bad hash: %v", err)
+	}
+	if err := s.ReplaceMedia(MediaItem{ID: "logo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Commit shrinking a memFile: %v", err)
+	}
+	if !bytes.Equal(m.b, orig) {
+		t.Error("failed Commit modified the container")
+	}
+
+	// Growing needs no truncation.
+	data := make([]byte, 100)
+	rand.Read(data)
+	if err := s.ReplaceMedia(MediaItem{ID: "logo", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(m.b), int64(len(m.b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Media()[0].Size; got != 100 {
+		t.Errorf("logo size = %d", got)
+	}
+}
+
+func TestEditSessionAuditLog(t *testing.T) {
+	// Commit does not rewrite the metadata, so it cannot extend the audit
+	// log; the unrecorded change is detected rather than hidden.
+	doc := sampleDoc()
+	if err := doc.AppendAuditEvent(AuditEvent{Tool: "test", Operation: "create"}); err != nil {
+		t.Fatal(err)
+	}
+	m := &memFile{}
+	if err := Encode(m, doc); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenEditSession(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplaceFile(MarkdownFile{Path: "docs/notes.md", Content: []byte("Some notes, edited at length\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(bytes.NewReader(m.b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events, _ := got.AuditLog(); len(events) != 1 {
+		t.Fatalf("audit log has %d events, want 1", len(events))
+	}
+	if err := got.VerifyAuditLog(); !errors.Is(err, ErrValidation) {
+		t.Fatalf("VerifyAuditLog after Commit: %v", err)
+	}
+}
+
+func TestEditSessionOpenReaders(t *testing.T) {
+	// Commit writes in place, so a Reader opened before it is stale.
+	doc := sampleDoc()
+	path := writeTempContainer(t, doc, WithMarkdownCompression(CompNone), WithMediaCompression(CompNone))
+	rf, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	open := func() *Reader {
+		t.Helper()
+		fi, err := rf.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(rf, fi.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	read := func(r *Reader) ([]byte, error) {
+		rc, err := r.OpenMedia("logo")
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	old := open()
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := OpenEditSession(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplaceFile(MarkdownFile{Path: "docs/notes.md", Content: []byte("Longer notes, which move the Media section\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := read(old); err == nil && bytes.Equal(data, doc.Media.Items[0].Data) {
+		t.Fatal("a Reader opened before Commit read the moved item")
+	}
+	if data, err := read(open()); err != nil || !bytes.Equal(data, doc.Media.Items[0].Data) {
+		t.Fatalf("reopened Reader: %q, %v", data, err)
+	}
+}
+
+func TestEditSessionDuplicateStubs(t *testing.T) {
+	m := &memFile{}
+	if err := Encode(m, dupDoc(), WithMediaCompression(CompNone), WithDuplicateMedia(DuplicatesDedupe, nil)); err != nil {
+		t.Fatal(err)
+	}
+	edit := func(it MediaItem) error {
+		t.Helper()
+		s, err := OpenEditSession(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.ReplaceMedia(it); err != nil {
+			t.Fatal(err)
+		}
+		return s.Commit()
+	}
+	check := func(want map[string]string) {
+		t.Helper()
+		got, err := Decode(bytes.NewReader(m.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, it := range got.Media.Items {
+			if w, ok := want[it.ID]; ok && string(it.Data) != w {
+				t.Errorf("%s: data %q, want %q", it.ID, it.Data, w)
+			}
+			if _, ok := it.Attributes[DuplicateOfAttr]; ok {
+				t.Errorf("%s: attributes %v", it.ID, it.Attributes)
+			}
+		}
+	}
+
+	// Editing another item keeps the stub.
+	if err := edit(MediaItem{ID: "other", MIMEType: "application/octet-stream", Data: []byte("edited at some length")}); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]string{"logo-copy": "\x01\x02\x03", "other": "edited at some length"})
+	r, err := NewReader(bytes.NewReader(m.b), int64(len(m.b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := r.Media()[1]; info.Size != 0 || info.Attributes[DuplicateOfAttr] != "logo" {
+		t.Fatalf("stub after Commit = %+v", info)
+	}
+
+	// Replacing the item holding the data gives its stubs the old data.
+	if err := edit(MediaItem{ID: "logo", Path: "assets/logo.png", MIMEType: "image/png", Data: []byte("a new, longer logo")}); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]string{"logo": "a new, longer logo", "logo-copy": "\x01\x02\x03"})
+
+	if err := edit(MediaItem{ID: "other", Attributes: map[string]string{DuplicateOfAttr: "logo"}}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Commit of an item with %s: %v", DuplicateOfAttr, err)
+	}
+}
+
+func TestEditSessionItemCompression(t *testing.T) {
+	doc := sampleDoc()
+	text := bytes.Repeat([]byte("compressible text "), 200)
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "notes", MIMEType: "text/plain", Data: text},
+		MediaItem{ID: "log", MIMEType: "text/plain", Data: []byte("short")},
+	)
+	path := writeTempContainer(t, doc, WithSmartMediaCompression(true))
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s, err := OpenEditSession(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := bytes.Repeat([]byte("edited log line "), 100)
+	if err := s.ReplaceMedia(MediaItem{ID: "log", MIMEType: "text/plain", Data: edited}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	h, err := DecodeHeader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Media.ItemCompression {
+		t.Fatalf("header after Commit = %+v", h)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(f, fi.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range r.items[1:] {
+		if e.packed != CompZSTD || e.dataLen >= e.size {
+			t.Errorf("%s: stored %d of %d bytes with %v", e.ID, e.dataLen, e.size, e.packed)
+		}
+	}
+	got, err := DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Media.Items[1].Data, text) || !bytes.Equal(got.Media.Items[2].Data, edited) {
+		t.Fatal("items changed by Commit")
+	}
+	if _, ok := got.Media.Items[2].Attributes[ItemCompressionAttr]; ok {
+		t.Errorf("decoded item has %s", ItemCompressionAttr)
+	}
+}
+
+func TestEditFile(t *testing.T) {
+	// Commit renames a new file into place, so readers opened before it keep
+	// the old container.
+	doc := sampleDoc()
+	path := writeTempContainer(t, doc, WithMarkdownCompression(CompNone), WithMediaCompression(CompNone))
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	rf, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+	fi, err := rf.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(rf, fi.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := OpenEditFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	notes := []byte("Longer notes, which move the Media section\n")
+	if err := s.ReplaceFile(MarkdownFile{Path: "docs/notes.md", Content: notes}); err != nil {
+		t.Fatal(err)
+	}
+	logo := []byte("a longer logo")
+	if err := s.ReplaceMedia(MediaItem{ID: "logo", Path: "assets/logo.png", MIMEType: "image/png", Data: logo}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A held lock stops the commit before anything is written.
+	l, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Commit with the lock held elsewhere: %v", err)
+	}
+	if err := s.Commit(WithLock(l)); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	sr, err := m.OpenMedia("logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(sr); err != nil || !bytes.Equal(data, doc.Media.Items[0].Data) {
+		t.Errorf("Mapped opened before Commit: %q, %v", data, err)
+	}
+	rc, err := r.OpenMedia("logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(rc); err != nil || !bytes.Equal(data, doc.Media.Items[0].Data) {
+		t.Errorf("Reader opened before Commit: %q, %v", data, err)
+	}
+
+	got, err := DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Markdown.Files[1].Content, notes) || !bytes.Equal(got.Media.Items[0].Data, logo) {
+		t.Error("Commit did not write the edits")
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("permissions after Commit: %v, %v", fi.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Commit left %d files behind", len(entries)-1)
+	}
+
+	// The session goes on with the new file.
+	if err := s.ReplaceFile(MarkdownFile{Path: "docs/notes.md", Content: []byte("Short\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := DecodeFile(path); err != nil || string(got.Markdown.Files[1].Content) != "Short\n" {
+		t.Fatalf("second Commit: %v", err)
+	}
+}
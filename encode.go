@@ -2,6 +2,7 @@ package mdocx
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"encoding/json"
 	"fmt"
@@ -14,7 +15,8 @@ var (
 	gobEncodeMedia    = func(v MediaBundle) ([]byte, error) { return gobEncode(v) }
 )
 
-// Encode writes doc to w using the MDOCX v1 container format.
+// Encode writes doc to w using the MDOCX v1 container format, or v2 with
+// WithFormatVersion.
 //
 // The document is validated before writing. Validation includes checking that:
 //   - BundleVersion fields are set to VersionV1
@@ -30,26 +32,175 @@ var (
 //
 // Use WriteOption functions to customize this behavior:
 //   - WithAutoPopulateSHA256(false): don't modify doc
+//   - WithAutoMediaRefs(true): fill in MarkdownFile.MediaRefs from content
 //   - WithMarkdownCompression(comp): change Markdown section compression
 //   - WithMediaCompression(comp): change Media section compression
+//   - WithBrotliQuality(q), WithBrotliWindow(bits): tune CompBR
+//   - WithLZ4BlockSize(n), WithLZ4Level(l): tune CompLZ4
+//   - WithCompressionLevel(section, level): set a section's compression level
 //   - WithWriteLimits(l): set custom size limits
 //   - WithVerifyHashesOnWrite(false): skip hash verification
+//   - WithOutputSHA256(&sum): record the SHA-256 of the written container
+//   - WithAttrSchema(s): check file and item attributes against a schema
+//   - WithTransforms(t...): modify the document (in place) before validation
+//   - WithQuota(n): fail with *QuotaError instead of writing more than n bytes
+//   - WithWriteRateLimit(l): throttle writes to w
+//   - WithChecksOnWrite(c): enforce optional invariants such as media order
+//   - WithIndex(true): append a footer index for fast random access
+//   - WithChecksum(true): append a CRC-32C integrity trailer
+//   - WithPayloadFormat(PayloadCBOR): serialize the bundles as CBOR
+//   - WithMaxDurationOnWrite(d): bound the time encoding may take
+//   - WithFormatVersion(VersionV2): write a section table (rfc.md §12.1)
+//   - WithExtraSection(typ, payload): add an application-defined section
+//   - WithDuplicateMedia(p, report): allow, report, dedupe, or reject items with identical data
 func Encode(w io.Writer, doc *Document, opts ...WriteOption) error {
-	cfg := writeConfig{
-		limits:           defaultLimits(),
-		verifyHashes:     true,
-		autoPopulate:     true,
-		mdCompression:    CompZSTD,
-		mediaCompression: CompZSTD,
-	}
-	for _, opt := range opts {
-		opt(&cfg)
-	}
-	cfg.limits = cfg.limits.withDefaults()
+	return EncodeContext(context.Background(), w, doc, opts...)
+}
+
+// EncodeContext is Encode with cancellation: it checks ctx between sections,
+// between chunks of compressor input, and between chunks written to w, and
+// returns ctx's error once ctx is done. Output written before cancellation is
+// not removed. With a cancelable context, sections are compressed in a
+// streaming fashion, so the compressed bytes may differ from Encode's while
+// decoding to the same document.
+func EncodeContext(ctx context.Context, w io.Writer, doc *Document, opts ...WriteOption) (err error) {
+	cfg := newWriteConfig(opts)
 	if doc == nil {
 		return fmt.Errorf("%w: document is nil", ErrValidation)
 	}
+	ctx, release := withBudget(ctx, cfg.maxDuration)
+	defer func() { err = release(err) }()
+	w, done := cfg.outputWriter(ctx, w)
+	defer func() { done(err) }()
+
+	parts, err := prepareEncode(ctx, doc, cfg)
+	if err != nil {
+		return err
+	}
+	if err := cfg.checkQuota(parts.size()); err != nil {
+		return err
+	}
+	var sum *checksumWriter
+	if parts.headerFlags&HeaderFlagChecksum != 0 {
+		sum = newChecksumWriter(w)
+		w = sum
+	}
+
+	h := fixedHeaderV1{
+		Magic:          Magic,
+		Version:        VersionV1,
+		HeaderFlags:    parts.headerFlags,
+		FixedHdrSize:   fixedHeaderSizeV1,
+		MetadataLength: uint32(len(parts.metadata)),
+		Reserved0:      0,
+		Reserved1:      0,
+	}
+	mdHeader := sectionHeaderV1{
+		SectionType:  uint16(SectionMarkdown),
+		SectionFlags: parts.mdFlags,
+		PayloadLen:   uint64(len(parts.mdPayload)),
+		Reserved:     0,
+	}
+	mediaHeader := sectionHeaderV1{
+		SectionType:  uint16(SectionMedia),
+		SectionFlags: parts.mediaFlags,
+		PayloadLen:   uint64(len(parts.mediaPayload)),
+		Reserved:     0,
+	}
+	if parts.version == VersionV2 {
+		h.Reserved1 = parts.capabilities()
+		table := []sectionHeaderV1{mdHeader, mediaHeader}
+		for _, s := range parts.extras {
+			table = append(table, s.sectionHeader())
+		}
+		if err := writeSectionTable(w, h, parts.metadata, table); err != nil {
+			return err
+		}
+		if _, err := w.Write(parts.mdPayload); err != nil {
+			return err
+		}
+		if _, err := w.Write(parts.mediaPayload); err != nil {
+			return err
+		}
+		for _, s := range parts.extras {
+			if _, err := w.Write(s.Payload); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := writeFixedHeader(w, h); err != nil {
+			return err
+		}
+		if len(parts.metadata) > 0 {
+			if _, err := w.Write(parts.metadata); err != nil {
+				return err
+			}
+		}
+		if err := writeSectionHeader(w, mdHeader); err != nil {
+			return err
+		}
+		if _, err := w.Write(parts.mdPayload); err != nil {
+			return err
+		}
+		if err := writeSectionHeader(w, mediaHeader); err != nil {
+			return err
+		}
+		if _, err := w.Write(parts.mediaPayload); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(parts.index); err != nil {
+		return err
+	}
+	if sum != nil {
+		return sum.writeTrailer()
+	}
+	return nil
+}
 
+// encodedParts holds the serialized pieces of a container, ready to be written.
+type encodedParts struct {
+	metadata     []byte
+	headerFlags  uint16
+	mdGobLen     uint64
+	mdFlags      uint16
+	mdPayload    []byte
+	mediaGobLen  uint64
+	mediaFlags   uint16
+	mediaPayload []byte
+	// index is the footer index section and trailer, if any.
+	index []byte
+	// version is the format version; extras are only written in version 2.
+	version uint16
+	extras  []ExtraSection
+}
+
+// size returns the number of bytes the container occupies when written.
+func (p *encodedParts) size() uint64 {
+	n := uint64(fixedHeaderSizeV1) + uint64(len(p.metadata)) + 2*16 + uint64(len(p.mdPayload)) + uint64(len(p.mediaPayload)) + uint64(len(p.index))
+	for _, s := range p.extras {
+		n += 16 + uint64(len(s.Payload))
+	}
+	if p.headerFlags&HeaderFlagChecksum != 0 {
+		n += checksumTrailerSize
+	}
+	return n
+}
+
+// prepareEncode runs the transforms, populates hashes, validates doc, and
+// serializes and compresses its parts as configured by cfg, checking ctx
+// between steps.
+func prepareEncode(ctx context.Context, doc *Document, cfg writeConfig) (*encodedParts, error) {
+	for _, t := range cfg.transforms {
+		if err := t(doc); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.autoMediaRefs {
+		if err := populateMediaRefs(doc); err != nil {
+			return nil, err
+		}
+	}
 	if cfg.autoPopulate {
 		for i := range doc.Media.Items {
 			if doc.Media.Items[i].SHA256 == ([32]byte{}) {
@@ -59,83 +210,125 @@ func Encode(w io.Writer, doc *Document, opts ...WriteOption) error {
 	}
 
 	if err := validateDocument(doc, cfg.limits, cfg.verifyHashes); err != nil {
-		return err
+		return nil, err
 	}
-
-	var metadataBytes []byte
-	var headerFlags uint16
-	if doc.Metadata != nil {
-		b, err := json.Marshal(doc.Metadata)
-		if err != nil {
-			return err
-		}
-		if len(b) > int(cfg.limits.MaxMetadataLen) {
-			return fmt.Errorf("%w: metadata too large", ErrLimitExceeded)
-		}
-		metadataBytes = b
-		headerFlags |= HeaderFlagMetadataJSON
+	if err := cfg.attrSchema.validateDocument(doc); err != nil {
+		return nil, err
 	}
-
-	mdGob, err := gobEncodeMarkdown(doc.Markdown)
-	if err != nil {
-		return err
+	if err := doc.CheckInvariants(cfg.checks); err != nil {
+		return nil, err
 	}
-	mediaGob, err := gobEncodeMedia(doc.Media)
+	items, stubs, err := cfg.applyDuplicatePolicy(doc)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	mdFlags, mdPayload, err := compressPayload(cfg.mdCompression, mdGob)
-	if err != nil {
-		return err
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	mediaFlags, mediaPayload, err := compressPayload(cfg.mediaCompression, mediaGob)
+
+	var p encodedParts
+	if p.metadata, p.headerFlags, err = encodeMetadata(doc.Metadata, cfg.limits); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.format.check(); err != nil {
+		return nil, err
+	}
+	if p.extras, err = cfg.extrasFor(doc); err != nil {
+		return nil, err
+	}
+	if p.version, err = cfg.formatVersion(len(p.extras)); err != nil {
+		return nil, err
+	}
+	p.headerFlags |= cfg.format.headerFlags()
+	mdGob, err := cfg.format.encodeMarkdown(doc.Markdown)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var mediaGob []byte
+	media := doc.Media
+	media.Items = items
+	if doc.NoMedia {
+		p.headerFlags |= HeaderFlagNoMedia
+	} else if cfg.smartMedia && cfg.mediaCompression != CompNone {
+		if media.Items, err = packItems(ctx, media.Items, cfg.mediaCompression, cfg.sectionCodecs(SectionMedia)); err != nil {
+			return nil, err
+		}
+		if mediaGob, err = cfg.format.encodeMedia(media); err != nil {
+			return nil, err
+		}
+	} else if mediaGob, err = cfg.format.encodeMedia(media); err != nil {
+		return nil, err
 	}
+	p.mdGobLen, p.mediaGobLen = uint64(len(mdGob)), uint64(len(mediaGob))
 
-	h := fixedHeaderV1{
-		Magic:          Magic,
-		Version:        VersionV1,
-		HeaderFlags:    headerFlags,
-		FixedHdrSize:   fixedHeaderSizeV1,
-		MetadataLength: uint32(len(metadataBytes)),
-		Reserved0:      0,
-		Reserved1:      0,
+	if p.mdFlags, p.mdPayload, err = compressPayloadContext(ctx, cfg.mdCompression, mdGob, cfg.sectionCodecs(SectionMarkdown)); err != nil {
+		return nil, err
 	}
-	if err := writeFixedHeader(w, h); err != nil {
-		return err
+	switch {
+	case doc.NoMedia:
+	case cfg.smartMedia && cfg.mediaCompression != CompNone:
+		p.mediaFlags, p.mediaPayload = sectionFlagItemCompression, mediaGob
+	default:
+		if p.mediaFlags, p.mediaPayload, err = compressPayloadContext(ctx, cfg.mediaCompression, mediaGob, cfg.sectionCodecs(SectionMedia)); err != nil {
+			return nil, err
+		}
 	}
-	if len(metadataBytes) > 0 {
-		if _, err := w.Write(metadataBytes); err != nil {
-			return err
+	if stubs {
+		p.mediaFlags |= sectionFlagDuplicateStubs
+	}
+	if cfg.index {
+		p.headerFlags |= HeaderFlagIndex
+		if p.index, err = encodeIndex(&p, mediaGob, cfg.limits); err != nil {
+			return nil, err
 		}
 	}
+	if cfg.checksum {
+		p.headerFlags |= HeaderFlagChecksum
+	}
+	return &p, nil
+}
 
-	mdHeader := sectionHeaderV1{
-		SectionType:  uint16(SectionMarkdown),
-		SectionFlags: mdFlags,
-		PayloadLen:   uint64(len(mdPayload)),
-		Reserved:     0,
+// checkQuota returns a *QuotaError if needed bytes exceed the WithQuota quota.
+func (c writeConfig) checkQuota(needed uint64) error {
+	if c.quota != nil && needed > *c.quota {
+		return &QuotaError{Needed: needed, Remaining: *c.quota}
 	}
-	if err := writeSectionHeader(w, mdHeader); err != nil {
-		return err
+	return nil
+}
+
+// encodeMetadata serializes metadata as JSON and returns it with the header
+// flags it requires. Nil metadata yields no bytes and no flags.
+func encodeMetadata(metadata map[string]any, limits Limits) ([]byte, uint16, error) {
+	if metadata == nil {
+		return nil, 0, nil
 	}
-	if _, err := w.Write(mdPayload); err != nil {
-		return err
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(b) > int(limits.MaxMetadataLen) {
+		return nil, 0, exceeds("MaxMetadataLen", "metadata too large")
 	}
+	return b, HeaderFlagMetadataJSON, nil
+}
 
-	mediaHeader := sectionHeaderV1{
-		SectionType:  uint16(SectionMedia),
-		SectionFlags: mediaFlags,
-		PayloadLen:   uint64(len(mediaPayload)),
-		Reserved:     0,
+// newWriteConfig returns the write configuration for opts with defaults applied.
+func newWriteConfig(opts []WriteOption) writeConfig {
+	cfg := writeConfig{
+		limits:           defaultLimits(),
+		verifyHashes:     true,
+		autoPopulate:     true,
+		mdCompression:    CompZSTD,
+		mediaCompression: CompZSTD,
+		codecs:           defaultCodecTuning(),
 	}
-	if err := writeSectionHeader(w, mediaHeader); err != nil {
-		return err
+	for _, opt := range opts {
+		opt(&cfg)
 	}
-	_, err = w.Write(mediaPayload)
-	return err
+	cfg.limits = cfg.limits.withDefaults()
+	return cfg
 }
 
 // gobEncode serializes v using Go's gob encoding.
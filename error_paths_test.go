@@ -36,7 +36,7 @@ func TestCompressHelpers_ErrorPaths(t *testing.T) {
 	// zip Create error via injection
 	origCreate := zipCreate
 	zipCreate = func(_ *zip.Writer, _ string) (io.Writer, error) { return nil, io.ErrClosedPipe }
-	if err := zipCompressNamed(io.Discard, "payload.gob", []byte("x")); err == nil {
+	if err := zipCompressNamed(io.Discard, "payload.gob", []byte("x"), defaultCodecTuning()); err == nil {
 		zipCreate = origCreate
 		t.Fatal("expected error")
 	}
@@ -45,7 +45,7 @@ func TestCompressHelpers_ErrorPaths(t *testing.T) {
 	// zip entry.Write error branch: make Create succeed but return a writer that errors on Write.
 	origCreate = zipCreate
 	zipCreate = func(_ *zip.Writer, _ string) (io.Writer, error) { return errWriter{}, nil }
-	if err := zipCompressNamed(io.Discard, "payload.gob", []byte("x")); err == nil {
+	if err := zipCompressNamed(io.Discard, "payload.gob", []byte("x"), defaultCodecTuning()); err == nil {
 		zipCreate = origCreate
 		t.Fatal("expected error")
 	}
@@ -54,24 +54,24 @@ func TestCompressHelpers_ErrorPaths(t *testing.T) {
 	// zip Close error via injection
 	origClose := zipClose
 	zipClose = func(_ *zip.Writer) error { return io.ErrClosedPipe }
-	if err := zipCompressNamed(io.Discard, "payload.gob", []byte("x")); err == nil {
+	if err := zipCompressNamed(io.Discard, "payload.gob", []byte("x"), defaultCodecTuning()); err == nil {
 		zipClose = origClose
 		t.Fatal("expected error")
 	}
 	zipClose = origClose
 
 	// zip write error
-	if err := zipCompressNamed(errWriter{}, "payload.gob", []byte("x")); err == nil {
+	if err := zipCompressNamed(errWriter{}, "payload.gob", []byte("x"), defaultCodecTuning()); err == nil {
 		t.Fatal("expected error")
 	}
 	// lz4 write error
-	if err := lz4CompressTo(errWriter{}, []byte("x")); err == nil {
+	if err := lz4CompressTo(errWriter{}, []byte("x"), defaultCodecTuning()); err == nil {
 		t.Fatal("expected error")
 	}
 	// lz4 Close error via injection
 	origLZ4Close := lz4Close
 	lz4Close = func(_ *lz4.Writer) error { return io.ErrClosedPipe }
-	if err := lz4CompressTo(io.Discard, []byte("x")); err == nil {
+	if err := lz4CompressTo(io.Discard, []byte("x"), defaultCodecTuning()); err == nil {
 		lz4Close = origLZ4Close
 		t.Fatal("expected error")
 	}
@@ -80,7 +80,7 @@ func TestCompressHelpers_ErrorPaths(t *testing.T) {
 	// brotli write error
 	origBrotliWrite := brotliWrite
 	brotliWrite = func(_ *brotli.Writer, _ []byte) (int, error) { return 0, io.ErrClosedPipe }
-	if err := brotliCompressTo(io.Discard, []byte("x")); err == nil {
+	if err := brotliCompressTo(io.Discard, []byte("x"), defaultCodecTuning()); err == nil {
 		brotliWrite = origBrotliWrite
 		t.Fatal("expected error")
 	}
@@ -88,7 +88,7 @@ func TestCompressHelpers_ErrorPaths(t *testing.T) {
 	// brotli Close error via injection
 	origBrotliClose := brotliClose
 	brotliClose = func(_ *brotli.Writer) error { return io.ErrClosedPipe }
-	if err := brotliCompressTo(io.Discard, []byte("x")); err == nil {
+	if err := brotliCompressTo(io.Discard, []byte("x"), defaultCodecTuning()); err == nil {
 		brotliClose = origBrotliClose
 		t.Fatal("expected error")
 	}
@@ -113,7 +113,7 @@ func TestZstdConstructorInjection(t *testing.T) {
 	}()
 
 	newZstdWriter = func() (*zstd.Encoder, error) { return nil, io.ErrClosedPipe }
-	if _, err := zstdCompress([]byte("x")); err == nil {
+	if _, err := zstdCompress([]byte("x"), defaultCodecTuning()); err == nil {
 		t.Fatal("expected error")
 	}
 
@@ -125,7 +125,7 @@ func TestZstdConstructorInjection(t *testing.T) {
 }
 
 func TestZIPDecompress_InjectionErrorPaths(t *testing.T) {
-	z, err := zipCompress([]byte("abc"))
+	z, err := zipCompress([]byte("abc"), defaultCodecTuning())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -150,7 +150,7 @@ func TestCompressPayload_UnderlyingError(t *testing.T) {
 	origW := newZstdWriter
 	defer func() { newZstdWriter = origW }()
 	newZstdWriter = func() (*zstd.Encoder, error) { return nil, io.ErrClosedPipe }
-	_, _, err := compressPayload(CompZSTD, []byte("x"))
+	_, _, err := compressPayload(CompZSTD, []byte("x"), defaultCodecTuning())
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -0,0 +1,297 @@
+package mdocx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrorCode is a stable, language-independent identifier of a kind of error.
+// Tools that show errors to end users can translate by code instead of
+// parsing English messages. See Code and LocalizeError.
+type ErrorCode string
+
+// Codes of the sentinel errors.
+const (
+	CodeInvalidMagic       ErrorCode = "invalid_magic"
+	CodeUnsupportedVersion ErrorCode = "unsupported_version"
+	CodeInvalidHeader      ErrorCode = "invalid_header"
+	CodeInvalidSection     ErrorCode = "invalid_section"
+	CodeInvalidMetadata    ErrorCode = "invalid_metadata"
+	CodeInvalidPayload     ErrorCode = "invalid_payload"
+	CodeLimitExceeded      ErrorCode = "limit_exceeded"
+	CodeValidation         ErrorCode = "validation"
+	CodeNotFound           ErrorCode = "not_found"
+	CodeCorrupted          ErrorCode = "corrupted"
+	CodeLocked             ErrorCode = "locked"
+)
+
+// Codes of the problems reported as a *ValidationError.
+const (
+	CodeNilDocument      ErrorCode = "nil_document"
+	CodeBundleVersion    ErrorCode = "bundle_version"
+	CodeNoMarkdownFiles  ErrorCode = "no_markdown_files"
+	CodeInvalidPath      ErrorCode = "invalid_path"
+	CodeDuplicatePath    ErrorCode = "duplicate_path"
+	CodeInvalidUTF8      ErrorCode = "invalid_utf8"
+	CodeMediaWithNoMedia ErrorCode = "media_with_no_media"
+	CodeEmptyID          ErrorCode = "empty_id"
+	CodeDuplicateID      ErrorCode = "duplicate_id"
+	CodeHashMismatch     ErrorCode = "hash_mismatch"
+	CodeMediaOrder       ErrorCode = "media_order"
+	CodeUnknownMediaRef  ErrorCode = "unknown_media_ref"
+	CodeUnresolvedImage  ErrorCode = "unresolved_image"
+	CodeOrphanMedia      ErrorCode = "orphan_media"
+	CodeNotFinal         ErrorCode = "not_final"
+)
+
+// sentinelCodes maps the sentinel errors to their codes, most specific first.
+var sentinelCodes = []struct {
+	err  error
+	code ErrorCode
+}{
+	{ErrInvalidMagic, CodeInvalidMagic},
+	{ErrUnsupportedVersion, CodeUnsupportedVersion},
+	{ErrInvalidHeader, CodeInvalidHeader},
+	{ErrInvalidSection, CodeInvalidSection},
+	{ErrInvalidPayload, CodeInvalidPayload},
+	{ErrCorrupted, CodeCorrupted},
+	{ErrLimitExceeded, CodeLimitExceeded},
+	{ErrValidation, CodeValidation},
+	{ErrNotFound, CodeNotFound},
+	{ErrLocked, CodeLocked},
+}
+
+// Code returns the code of err: the Code of the first *ValidationError in its
+// chain, CodeInvalidMetadata for a *MetadataError, or else the code of the
+// first sentinel error it matches. It returns "" for nil and for errors not
+// from this package.
+func Code(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) && ve.Code != "" {
+		return ve.Code
+	}
+	var me *MetadataError
+	if errors.As(err, &me) {
+		return CodeInvalidMetadata
+	}
+	for _, s := range sentinelCodes {
+		if errors.Is(err, s.err) {
+			return s.code
+		}
+	}
+	return ""
+}
+
+var (
+	errorMessagesMu sync.RWMutex
+	errorMessages   = map[string]map[ErrorCode]string{
+		"en": {
+			CodeInvalidMagic:       "not an MDOCX file",
+			CodeUnsupportedVersion: "unsupported MDOCX version",
+			CodeInvalidHeader:      "invalid file header",
+			CodeInvalidSection:     "invalid section header",
+			CodeInvalidMetadata:    "invalid metadata",
+			CodeInvalidPayload:     "damaged section data",
+			CodeLimitExceeded:      "limit exceeded",
+			CodeValidation:         "invalid document",
+			CodeNotFound:           "not found",
+			CodeCorrupted:          "the file is truncated or corrupted",
+			CodeLocked:             "the file is locked by another program",
+			CodeNilDocument:        "no document",
+			CodeBundleVersion:      "unsupported bundle version",
+			CodeNoMarkdownFiles:    "the document has no Markdown files",
+			CodeInvalidPath:        "invalid path {value}",
+			CodeDuplicatePath:      "duplicate path {value}",
+			CodeInvalidUTF8:        "the text is not valid UTF-8",
+			CodeMediaWithNoMedia:   "media is not allowed in a document without media",
+			CodeEmptyID:            "missing media ID",
+			CodeDuplicateID:        "duplicate media ID {value}",
+			CodeHashMismatch:       "the checksum does not match the data",
+			CodeMediaOrder:         "media item {value} is out of order",
+			CodeUnknownMediaRef:    "reference to unknown media {value}",
+			CodeUnresolvedImage:    "image {value} not found",
+			CodeOrphanMedia:        "media item {value} is not used",
+			CodeNotFinal:           "the file has status {value}, not final",
+		},
+		"de": {
+			CodeInvalidMagic:       "keine MDOCX-Datei",
+			CodeUnsupportedVersion: "nicht unterstützte MDOCX-Version",
+			CodeInvalidHeader:      "ungültiger Dateikopf",
+			CodeInvalidSection:     "ungültiger Abschnittskopf",
+			CodeInvalidMetadata:    "ungültige Metadaten",
+			CodeInvalidPayload:     "beschädigte Abschnittsdaten",
+			CodeLimitExceeded:      "Grenzwert überschritten",
+			CodeValidation:         "ungültiges Dokument",
+			CodeNotFound:           "nicht gefunden",
+			CodeCorrupted:          "die Datei ist abgeschnitten oder beschädigt",
+			CodeLocked:             "die Datei ist von einem anderen Programm gesperrt",
+			CodeNilDocument:        "kein Dokument",
+			CodeBundleVersion:      "nicht unterstützte Bündelversion",
+			CodeNoMarkdownFiles:    "das Dokument enthält keine Markdown-Dateien",
+			CodeInvalidPath:        "ungültiger Pfad {value}",
+			CodeDuplicatePath:      "doppelter Pfad {value}",
+			CodeInvalidUTF8:        "der Text ist kein gültiges UTF-8",
+			CodeMediaWithNoMedia:   "Medien sind in einem Dokument ohne Medien nicht erlaubt",
+			CodeEmptyID:            "fehlende Medien-ID",
+			CodeDuplicateID:        "doppelte Medien-ID {value}",
+			CodeHashMismatch:       "die Prüfsumme stimmt nicht mit den Daten überein",
+			CodeMediaOrder:         "Medienelement {value} ist falsch eingeordnet",
+			CodeUnknownMediaRef:    "Verweis auf unbekanntes Medium {value}",
+			CodeUnresolvedImage:    "Bild {value} nicht gefunden",
+			CodeOrphanMedia:        "Medienelement {value} wird nicht verwendet",
+			CodeNotFinal:           "die Datei hat den Status {value} statt final",
+		},
+		"fr": {
+			CodeInvalidMagic:       "ce n'est pas un fichier MDOCX",
+			CodeUnsupportedVersion: "version MDOCX non prise en charge",
+			CodeInvalidHeader:      "en-tête de fichier non valide",
+			CodeInvalidSection:     "en-tête de section non valide",
+			CodeInvalidMetadata:    "métadonnées non valides",
+			CodeInvalidPayload:     "données de section endommagées",
+			CodeLimitExceeded:      "limite dépassée",
+			CodeValidation:         "document non valide",
+			CodeNotFound:           "introuvable",
+			CodeCorrupted:          "le fichier est tronqué ou corrompu",
+			CodeLocked:             "le fichier est verrouillé par un autre programme",
+			CodeNilDocument:        "aucun document",
+			CodeBundleVersion:      "version de lot non prise en charge",
+			CodeNoMarkdownFiles:    "le document ne contient aucun fichier Markdown",
+			CodeInvalidPath:        "chemin non valide {value}",
+			CodeDuplicatePath:      "chemin en double {value}",
+			CodeInvalidUTF8:        "le texte n'est pas en UTF-8 valide",
+			CodeMediaWithNoMedia:   "les médias ne sont pas autorisés dans un document sans médias",
+			CodeEmptyID:            "identifiant de média manquant",
+			CodeDuplicateID:        "identifiant de média en double {value}",
+			CodeHashMismatch:       "la somme de contrôle ne correspond pas aux données",
+			CodeMediaOrder:         "le média {value} n'est pas à sa place",
+			CodeUnknownMediaRef:    "référence à un média inconnu {value}",
+			CodeUnresolvedImage:    "image {value} introuvable",
+			CodeOrphanMedia:        "le média {value} n'est pas utilisé",
+			CodeNotFinal:           "le fichier a le statut {value} au lieu de final",
+		},
+		"es": {
+			CodeInvalidMagic:       "no es un archivo MDOCX",
+			CodeUnsupportedVersion: "versión de MDOCX no compatible",
+			CodeInvalidHeader:      "encabezado de archivo no válido",
+			CodeInvalidSection:     "encabezado de sección no válido",
+			CodeInvalidMetadata:    "metadatos no válidos",
+			CodeInvalidPayload:     "datos de sección dañados",
+			CodeLimitExceeded:      "límite superado",
+			CodeValidation:         "documento no válido",
+			CodeNotFound:           "no encontrado",
+			CodeCorrupted:          "el archivo está truncado o dañado",
+			CodeLocked:             "el archivo está bloqueado por otro programa",
+			CodeNilDocument:        "no hay documento",
+			CodeBundleVersion:      "versión de paquete no compatible",
+			CodeNoMarkdownFiles:    "el documento no tiene archivos Markdown",
+			CodeInvalidPath:        "ruta no válida {value}",
+			CodeDuplicatePath:      "ruta duplicada {value}",
+			CodeInvalidUTF8:        "el texto no es UTF-8 válido",
+			CodeMediaWithNoMedia:   "no se permiten medios en un documento sin medios",
+			CodeEmptyID:            "falta el ID del medio",
+			CodeDuplicateID:        "ID de medio duplicado {value}",
+			CodeHashMismatch:       "la suma de comprobación no coincide con los datos",
+			CodeMediaOrder:         "el medio {value} está fuera de orden",
+			CodeUnknownMediaRef:    "referencia a un medio desconocido {value}",
+			CodeUnresolvedImage:    "no se encontró la imagen {value}",
+			CodeOrphanMedia:        "el medio {value} no se usa",
+			CodeNotFinal:           "el archivo tiene el estado {value} en lugar de final",
+		},
+	}
+)
+
+// RegisterErrorMessages adds or replaces the messages LocalizeError uses for
+// the language lang, a BCP 47 tag such as "pt" or "pt-BR". A message may
+// contain "{value}", which is replaced by ValidationError.Value in quotes.
+// Codes without a message fall back to English. It is safe for concurrent use.
+func RegisterErrorMessages(lang string, msgs map[ErrorCode]string) error {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	if lang == "" {
+		return fmt.Errorf("%w: language tag is empty", ErrValidation)
+	}
+	errorMessagesMu.Lock()
+	defer errorMessagesMu.Unlock()
+	m := make(map[ErrorCode]string, len(errorMessages[lang])+len(msgs))
+	for code, msg := range errorMessages[lang] {
+		m[code] = msg
+	}
+	for code, msg := range msgs {
+		m[code] = msg
+	}
+	errorMessages[lang] = m
+	return nil
+}
+
+// errorMessage returns the message for code in lang, trying the full tag,
+// then its primary language, then English.
+func errorMessage(code ErrorCode, lang string) (string, bool) {
+	lang = strings.ToLower(strings.ReplaceAll(lang, "_", "-"))
+	base, _, _ := strings.Cut(lang, "-")
+	errorMessagesMu.RLock()
+	defer errorMessagesMu.RUnlock()
+	for _, tag := range []string{lang, base, "en"} {
+		if msg, ok := errorMessages[tag][code]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// LocalizeError returns a message for err in the language lang, for display
+// to end users. Built-in messages exist for English ("en"), German ("de"),
+// French ("fr"), and Spanish ("es"); see RegisterErrorMessages for others.
+//
+// A *ValidationError is described by its translated Code, preceded by its
+// Field and Path; ValidationErrors are described one per line. Other errors
+// from this package are described by the translated code of their sentinel
+// error, and details such as offsets are dropped. Errors without a code are
+// returned as err.Error().
+func LocalizeError(err error, lang string) string {
+	if err == nil {
+		return ""
+	}
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		lines := make([]string, len(errs))
+		for i, e := range errs {
+			lines[i] = LocalizeError(e, lang)
+		}
+		return strings.Join(lines, "\n")
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) && ve.Code != "" {
+		msg, ok := errorMessage(ve.Code, lang)
+		if !ok {
+			return err.Error()
+		}
+		switch {
+		case ve.Code == CodeLimitExceeded && ve.Value != "":
+			msg += " (" + ve.Value + ")"
+		case ve.Value != "":
+			msg = strings.ReplaceAll(msg, "{value}", strconv.Quote(ve.Value))
+		default:
+			msg = strings.TrimSpace(strings.ReplaceAll(msg, "{value}", ""))
+		}
+		switch {
+		case ve.Field != "" && ve.Path != "":
+			return fmt.Sprintf("%s (%s): %s", ve.Field, ve.Path, msg)
+		case ve.Field != "":
+			return ve.Field + ": " + msg
+		}
+		return msg
+	}
+	if msg, ok := errorMessage(Code(err), lang); ok {
+		var le *LimitError
+		if errors.As(err, &le) && le.Limit != "" {
+			msg += " (" + le.Limit + ")"
+		}
+		return msg
+	}
+	return err.Error()
+}
@@ -0,0 +1,88 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	if Code(nil) != "" || Code(errors.New("other")) != "" {
+		t.Fatal("unexpected code for foreign error")
+	}
+	if got := Code(fmt.Errorf("%w: bad", ErrInvalidMagic)); got != CodeInvalidMagic {
+		t.Fatalf("got %q", got)
+	}
+	if got := Code(exceeds("MaxMediaItems", "too many")); got != CodeLimitExceeded {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := Decode(bytes.NewReader([]byte("not a container at all, really not"))); Code(err) != CodeInvalidMagic {
+		t.Fatalf("decode error %v has code %q", err, Code(err))
+	}
+
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, doc.Media.Items[0])
+	doc.Markdown.Files[1].Content = []byte{0xff}
+	err := Validate(doc, WithAllErrors())
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("got %v", err)
+	}
+	if errs[0].Code != CodeInvalidUTF8 || errs[1].Code != CodeDuplicateID || errs[1].Value != "logo" {
+		t.Fatalf("codes: %+v, %+v", errs[0], errs[1])
+	}
+	if Code(err) != CodeInvalidUTF8 {
+		t.Fatalf("Code = %q", Code(err))
+	}
+
+	doc = sampleDoc()
+	doc.Markdown.Files[0].MediaRefs = []string{"gone"}
+	err = Validate(doc, WithValidateChecks(CheckMediaRefs))
+	if Code(err) != CodeUnknownMediaRef {
+		t.Fatalf("invariant error %v has code %q", err, Code(err))
+	}
+}
+
+func TestLocalizeError(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, doc.Media.Items[0])
+	doc.Markdown.Files = append(doc.Markdown.Files, MarkdownFile{Path: "big.md", Content: make([]byte, 1000)})
+	err := Validate(doc, WithAllErrors(), WithValidateLimits(Limits{MaxSingleMarkdownFileSize: 500}))
+	want := "Markdown.Files[2].Content (big.md): Grenzwert überschritten (MaxSingleMarkdownFileSize)\n" +
+		"Media.Items[1].ID (assets/logo.png): doppelte Medien-ID \"logo\""
+	if got := LocalizeError(err, "de-AT"); got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+
+	cases := []struct {
+		err  error
+		lang string
+		want string
+	}{
+		{nil, "de", ""},
+		{fmt.Errorf("read: %w", ErrCorrupted), "fr", "le fichier est tronqué ou corrompu"},
+		{fmt.Errorf("%w: bad", ErrInvalidHeader), "xx", "invalid file header"},
+		{exceeds("MaxMediaItems", "too many"), "es", "límite superado (MaxMediaItems)"},
+		{errors.New("other"), "de", "other"},
+		{&ValidationError{Field: "Markdown.Files", Code: CodeNoMarkdownFiles, Err: ErrValidation}, "en", "Markdown.Files: the document has no Markdown files"},
+	}
+	for _, c := range cases {
+		if got := LocalizeError(c.err, c.lang); got != c.want {
+			t.Errorf("LocalizeError(%v, %q) = %q, want %q", c.err, c.lang, got, c.want)
+		}
+	}
+
+	if err := RegisterErrorMessages("pt_BR", map[ErrorCode]string{CodeNotFound: "não encontrado"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := LocalizeError(ErrNotFound, "pt-BR"); got != "não encontrado" {
+		t.Fatalf("got %q", got)
+	}
+	if got := LocalizeError(ErrLocked, "pt-br"); got != "the file is locked by another program" {
+		t.Fatalf("fallback: got %q", got)
+	}
+	if err := RegisterErrorMessages("", nil); !errors.Is(err, ErrValidation) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -1,6 +1,13 @@
 package mdocx
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 // Sentinel errors returned by Encode and Decode functions.
 // These errors can be checked using errors.Is for programmatic error handling.
@@ -10,7 +17,8 @@ var (
 	ErrInvalidMagic = errors.New("mdocx: invalid magic")
 
 	// ErrUnsupportedVersion indicates the file uses an unsupported format version.
-	// This package only supports VersionV1.
+	// This package reads VersionV1 and, with Decode, DecodeInto, and
+	// DecodeHeader, VersionV2.
 	ErrUnsupportedVersion = errors.New("mdocx: unsupported version")
 
 	// ErrInvalidHeader indicates the fixed header is malformed or contains invalid values.
@@ -32,4 +40,136 @@ var (
 	// ErrValidation indicates document validation failed.
 	// This includes missing required fields, duplicate paths/IDs, invalid paths, or SHA256 mismatches.
 	ErrValidation = errors.New("mdocx: validation failed")
+
+	// ErrNotFound indicates a requested Markdown file or media item does not exist
+	// in the container.
+	ErrNotFound = errors.New("mdocx: not found")
+
+	// ErrCorrupted indicates that a container with an integrity trailer is
+	// truncated or does not match its checksum. See WithChecksum.
+	ErrCorrupted = errors.New("mdocx: container corrupted")
+
+	// ErrLocked indicates that the lock of a container file is held by someone
+	// else. See LockFile.
+	ErrLocked = errors.New("mdocx: file is locked")
+
+	// ErrStop may be returned by a DocumentSink method to end DecodeInto early.
+	// DecodeInto then returns nil. It is never returned as an error.
+	ErrStop = errors.New("mdocx: stop decoding")
 )
+
+// QuotaError is returned by Encode when the encoded container would not fit in
+// the space allowed by WithQuota. Nothing has been written to the destination
+// when it is returned. It matches ErrLimitExceeded with errors.Is.
+type QuotaError struct {
+	// Needed is the encoded size of the container in bytes.
+	Needed uint64
+	// Remaining is the quota that was passed to WithQuota.
+	Remaining uint64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("mdocx: quota exceeded: container needs %d bytes, %d remaining", e.Needed, e.Remaining)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *QuotaError) Unwrap() error { return ErrLimitExceeded }
+
+// LimitError is returned when a container or document exceeds one of the
+// configured Limits. It matches ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	// Limit is the name of the Limits field that was exceeded, such as
+	// "MaxMediaSectionLen".
+	Limit string
+	// Reason describes what exceeded the limit.
+	Reason string
+}
+
+func (e *LimitError) Error() string {
+	return "mdocx: limit exceeded: " + e.Reason
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error { return ErrLimitExceeded }
+
+// exceeds returns a *LimitError for the named Limits field.
+func exceeds(limit, format string, args ...any) error {
+	return &LimitError{Limit: limit, Reason: fmt.Sprintf(format, args...)}
+}
+
+// MetadataError is returned when the metadata block of a container is not
+// valid JSON or not a JSON object. It matches ErrInvalidHeader with errors.Is,
+// and Err with errors.As.
+type MetadataError struct {
+	// Offset is the byte offset of the problem within the metadata block.
+	Offset int64
+	// Line and Column give the 1-based position of Offset. Columns count
+	// bytes.
+	Line, Column int
+	// Snippet is the metadata around Offset, at most metadataSnippetLen bytes,
+	// with control characters and invalid UTF-8 replaced so that it is safe to
+	// show. It starts or ends with "..." where it was truncated.
+	Snippet string
+	// Err is the error from encoding/json.
+	Err error
+}
+
+func (e *MetadataError) Error() string {
+	return fmt.Sprintf("mdocx: invalid metadata JSON at line %d, column %d (offset %d): %v, near %q", e.Line, e.Column, e.Offset, e.Err, e.Snippet)
+}
+
+// Unwrap returns ErrInvalidHeader and Err.
+func (e *MetadataError) Unwrap() []error { return []error{ErrInvalidHeader, e.Err} }
+
+// metadataSnippetLen is the maximum length of MetadataError.Snippet, excluding
+// the truncation marks.
+const metadataSnippetLen = 64
+
+// newMetadataError returns a *MetadataError for the encoding/json error err
+// from parsing the metadata block mb.
+func newMetadataError(mb []byte, err error) *MetadataError {
+	off := int64(len(mb))
+	var syntax *json.SyntaxError
+	var typ *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntax):
+		off = syntax.Offset
+	case errors.As(err, &typ):
+		off = typ.Offset
+	}
+	// encoding/json reports the offset just past the offending byte.
+	off = min(max(off-1, 0), int64(len(mb)))
+	e := &MetadataError{Offset: off, Line: 1, Column: 1, Err: err}
+	for _, b := range mb[:off] {
+		if b == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+
+	start := max(int(off)-metadataSnippetLen/2, 0)
+	end := min(start+metadataSnippetLen, len(mb))
+	start = max(end-metadataSnippetLen, 0)
+	for start > 0 && !utf8.RuneStart(mb[start]) {
+		start++
+	}
+	for end < len(mb) && !utf8.RuneStart(mb[end]) {
+		end--
+	}
+	snippet := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, strings.ToValidUTF8(string(mb[start:end]), "\uFFFD"))
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(mb) {
+		snippet += "..."
+	}
+	e.Snippet = snippet
+	return e
+}
@@ -7,7 +7,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/logicossoftware/go-mdocx"
 )
@@ -15,12 +14,26 @@ import (
 func main() {
 	var inPath string
 	var outDir string
+	var onConflict string
+	var dryRun bool
 	flag.StringVar(&inPath, "in", "", "input .mdocx file")
 	flag.StringVar(&outDir, "out", "out", "output directory")
+	flag.StringVar(&onConflict, "on-conflict", "error", "what to do with existing files: error, overwrite, rename, skip")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the planned operations without writing")
 	flag.Parse()
 	if inPath == "" {
 		log.Fatal("-in is required")
 	}
+	policies := map[string]mdocx.CollisionPolicy{
+		"error":     mdocx.CollisionError,
+		"overwrite": mdocx.CollisionOverwrite,
+		"rename":    mdocx.CollisionRename,
+		"skip":      mdocx.CollisionSkip,
+	}
+	policy, ok := policies[onConflict]
+	if !ok {
+		log.Fatalf("unknown -on-conflict value %q", onConflict)
+	}
 
 	f, err := os.Open(inPath)
 	if err != nil {
@@ -33,11 +46,15 @@ func main() {
 		log.Fatalf("decode: %v", err)
 	}
 
-	if err := os.MkdirAll(outDir, 0o755); err != nil {
-		log.Fatalf("mkdir: %v", err)
+	ops, err := mdocx.Extract(doc, outDir, mdocx.WithCollisionPolicy(policy), mdocx.WithDryRun(dryRun))
+	if err != nil {
+		log.Fatalf("extract: %v", err)
+	}
+	for _, op := range ops {
+		fmt.Printf("%-9s %s\n", op.Action, filepath.Join(outDir, filepath.FromSlash(op.Target)))
 	}
 
-	if doc.Metadata != nil {
+	if doc.Metadata != nil && !dryRun {
 		b, err := json.MarshalIndent(doc.Metadata, "", "  ")
 		if err != nil {
 			log.Fatalf("metadata json: %v", err)
@@ -48,30 +65,4 @@ func main() {
 		}
 		fmt.Printf("wrote %s\n", p)
 	}
-
-	for _, mf := range doc.Markdown.Files {
-		p := filepath.Join(outDir, filepath.FromSlash(mf.Path))
-		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
-			log.Fatalf("mkdir: %v", err)
-		}
-		if err := os.WriteFile(p, mf.Content, 0o644); err != nil {
-			log.Fatalf("write markdown: %v", err)
-		}
-		fmt.Printf("wrote %s\n", p)
-	}
-
-	for _, mi := range doc.Media.Items {
-		containerPath := mi.Path
-		if strings.TrimSpace(containerPath) == "" {
-			containerPath = "media/" + mi.ID
-		}
-		p := filepath.Join(outDir, filepath.FromSlash(containerPath))
-		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
-			log.Fatalf("mkdir: %v", err)
-		}
-		if err := os.WriteFile(p, mi.Data, 0o644); err != nil {
-			log.Fatalf("write media: %v", err)
-		}
-		fmt.Printf("wrote %s\n", p)
-	}
 }
@@ -434,6 +434,8 @@ func compressionName(c mdocx.Compression) string {
 		return "lz4"
 	case mdocx.CompBR:
 		return "brotli"
+	case mdocx.CompXZ:
+		return "xz"
 	default:
 		return "unknown"
 	}
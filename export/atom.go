@@ -0,0 +1,183 @@
+package export
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// AtomOptions configures Atom.
+type AtomOptions struct {
+	// BaseURL is the absolute URL the bundle is served under. Entry links are
+	// formed by resolving each Markdown path against it. Required.
+	BaseURL string
+	// ID is the feed ID. Defaults to BaseURL.
+	ID string
+	// Title is the feed title. Defaults to metadata "title", then BaseURL.
+	Title string
+	// Author is the feed author name. Defaults to metadata "creator".
+	Author string
+	// Filter selects the Markdown files that become entries.
+	// If nil, every file is an entry.
+	Filter func(mdocx.MarkdownFile) bool
+	// Updated is the timestamp used for entries without an "updated" or "date"
+	// attribute. Defaults to metadata "created_at", then the current time.
+	Updated time.Time
+	// MaxEntries caps the number of entries, newest first. Zero means no cap.
+	MaxEntries int
+}
+
+// Atom writes doc as an Atom 1.0 feed (RFC 4287) with one entry per selected
+// Markdown file.
+//
+// Each entry takes its title from the file's "title" attribute or first heading,
+// its timestamp from the "updated" or "date" attribute (RFC 3339 or YYYY-MM-DD),
+// and its summary from the "summary" attribute. The Markdown source is included
+// as text/markdown content. Entries are ordered newest first; ties keep bundle
+// order.
+func Atom(w io.Writer, doc *mdocx.Document, opts AtomOptions) error {
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil {
+		return err
+	}
+	if !base.IsAbs() {
+		return errors.New("export: AtomOptions.BaseURL must be an absolute URL")
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		base.RawPath = ""
+	}
+	fallback := opts.Updated
+	if fallback.IsZero() {
+		if t, ok := parseFeedTime(metaString(doc, "created_at")); ok {
+			fallback = t
+		} else {
+			fallback = time.Now()
+		}
+	}
+
+	feed := atomFeed{
+		ID:    firstNonEmpty(opts.ID, base.String()),
+		Title: firstNonEmpty(opts.Title, metaString(doc, "title"), base.String()),
+		Links: []atomLink{{Rel: "alternate", Href: base.String()}},
+	}
+	if a := firstNonEmpty(opts.Author, metaString(doc, "creator")); a != "" {
+		feed.Author = &atomPerson{Name: a}
+	}
+	var stamps []time.Time
+	for _, f := range doc.Markdown.Files {
+		if opts.Filter != nil && !opts.Filter(f) {
+			continue
+		}
+		updated := fallback
+		attrs := mdocx.Attributes(f.Attributes)
+		for _, key := range []string{"updated", "date"} {
+			if t, err := attrs.Time(key); err == nil {
+				updated = t
+				break
+			}
+		}
+		href := base.ResolveReference(&url.URL{Path: f.Path}).String()
+		e := atomEntry{
+			ID:      href,
+			Title:   fileTitle(doc, f),
+			Links:   []atomLink{{Rel: "alternate", Href: href}},
+			Content: &atomText{Type: "text/markdown", Body: string(f.Content)},
+		}
+		if s := f.Attributes["summary"]; s != "" {
+			e.Summary = &atomText{Type: "text", Body: s}
+		}
+		feed.Entries = append(feed.Entries, e)
+		stamps = append(stamps, updated)
+	}
+
+	order := make([]int, len(feed.Entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return stamps[order[i]].After(stamps[order[j]]) })
+	if opts.MaxEntries > 0 && len(order) > opts.MaxEntries {
+		order = order[:opts.MaxEntries]
+	}
+	entries := make([]atomEntry, len(order))
+	newest := fallback
+	for i, idx := range order {
+		entries[i] = feed.Entries[idx]
+		entries[i].Updated = stamps[idx].UTC().Format(time.RFC3339)
+		if i == 0 {
+			newest = stamps[idx]
+		}
+	}
+	feed.Entries = entries
+	feed.Updated = newest.UTC().Format(time.RFC3339)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link"`
+	Summary *atomText  `xml:"summary,omitempty"`
+	Content *atomText  `xml:"content,omitempty"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+// parseFeedTime parses an RFC 3339 timestamp or a YYYY-MM-DD date.
+func parseFeedTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
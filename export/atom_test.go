@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func TestAtom(t *testing.T) {
+	var buf bytes.Buffer
+	err := Atom(&buf, sampleDoc(), AtomOptions{
+		BaseURL: "https://example.com/bundle",
+		Filter:  func(f mdocx.MarkdownFile) bool { return strings.HasPrefix(f.Path, "news/") },
+		Updated: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if feed.Title != "Changelog" || feed.Author == nil || feed.Author.Name != "Release Team" {
+		t.Fatalf("unexpected feed header: %+v", feed)
+	}
+	if feed.Updated != "2024-03-01T12:00:00Z" {
+		t.Fatalf("feed updated: %s", feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(feed.Entries))
+	}
+	e := feed.Entries[0]
+	if e.Title != "Version 2" || e.Links[0].Href != "https://example.com/bundle/news/v2.md" || e.Summary == nil || e.Summary.Body != "Faster" {
+		t.Fatalf("unexpected first entry: %+v", e)
+	}
+	if feed.Entries[1].Title != "Version 1" || feed.Entries[1].Updated != "2024-01-10T00:00:00Z" {
+		t.Fatalf("unexpected second entry: %+v", feed.Entries[1])
+	}
+}
+
+func TestAtomMaxEntriesAndBaseURL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Atom(&buf, sampleDoc(), AtomOptions{BaseURL: "/relative"}); err == nil {
+		t.Fatal("expected error for relative BaseURL")
+	}
+	buf.Reset()
+	if err := Atom(&buf, sampleDoc(), AtomOptions{BaseURL: "https://example.com/", MaxEntries: 1, Updated: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Title != "Version 2" {
+		t.Fatalf("unexpected entries: %+v", feed.Entries)
+	}
+}
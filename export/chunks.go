@@ -0,0 +1,265 @@
+package export
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/logicossoftware/go-mdocx"
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// Default chunk sizes, in characters.
+const (
+	DefaultChunkSize    = 2000
+	DefaultChunkOverlap = 200
+)
+
+// ChunkOptions configures ToChunks.
+type ChunkOptions struct {
+	// MaxChars is the maximum chunk length in characters (runes), excluding
+	// overlap carried over from the previous chunk. Defaults to DefaultChunkSize.
+	MaxChars int
+	// Overlap is the number of trailing characters of a chunk repeated at the
+	// start of the next chunk of the same section. Defaults to
+	// DefaultChunkOverlap; set it negative to disable overlap.
+	Overlap int
+	// Files lists the Markdown paths to chunk, in order. If empty, the root file
+	// is chunked first, followed by the others in bundle order.
+	Files []string
+	// ExcludeCode drops code blocks instead of keeping them verbatim.
+	ExcludeCode bool
+	// Slug converts heading text to an anchor. Defaults to mdocx.GitHubSlug.
+	Slug func(string) string
+}
+
+// Chunk is a span of text from one section of a Markdown file.
+type Chunk struct {
+	// Path is the container path of the source Markdown file.
+	Path string `json:"path"`
+	// Headings is the heading trail of the section, outermost first.
+	Headings []string `json:"headings,omitempty"`
+	// Anchor is the fragment identifier of the innermost heading, or "" for
+	// text before the first heading.
+	Anchor string `json:"anchor,omitempty"`
+	// StartLine and EndLine are the 1-based source lines covered by the chunk,
+	// excluding overlap.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Text is the chunk text with Markdown formatting removed and images replaced
+	// by "[Image: alt]".
+	Text string `json:"text"`
+	// MediaIDs lists the media items referenced in the chunk, in order of first
+	// reference.
+	MediaIDs []string `json:"media_ids,omitempty"`
+}
+
+// ToChunks splits the Markdown content of doc into overlapping text chunks for
+// embedding and retrieval. Chunks never span sections: every heading starts a
+// new chunk, and the heading trail and anchor of the section are recorded as
+// provenance. Paragraphs longer than MaxChars are split at word boundaries.
+//
+// It returns an error wrapping mdocx.ErrNotFound if opts.Files names a path that
+// is not in the document.
+func ToChunks(doc *mdocx.Document, opts ChunkOptions) ([]Chunk, error) {
+	if opts.MaxChars == 0 {
+		opts.MaxChars = DefaultChunkSize
+	}
+	if opts.Overlap == 0 {
+		opts.Overlap = DefaultChunkOverlap
+	}
+	if opts.Overlap < 0 {
+		opts.Overlap = 0
+	}
+	if opts.MaxChars < 0 || opts.Overlap >= opts.MaxChars {
+		return nil, errors.New("export: ChunkOptions.Overlap must be smaller than MaxChars")
+	}
+	if opts.Slug == nil {
+		opts.Slug = mdocx.GitHubSlug
+	}
+	files, err := selectFiles(doc, opts.Files)
+	if err != nil {
+		return nil, err
+	}
+	var out []Chunk
+	for _, f := range files {
+		out = append(out, chunkFile(doc, f, opts)...)
+	}
+	return out, nil
+}
+
+// chunkUnit is a piece of text that is never split further.
+type chunkUnit struct {
+	text  string
+	line  int
+	media []string
+}
+
+// chunkFile chunks a single Markdown file.
+func chunkFile(doc *mdocx.Document, f mdocx.MarkdownFile, opts ChunkOptions) []Chunk {
+	var (
+		out    []Chunk
+		trail  []string
+		levels []int
+		anchor string
+		units  []chunkUnit
+		counts = map[string]int{}
+	)
+	flush := func() {
+		out = append(out, packUnits(f.Path, trail, anchor, units, opts)...)
+		units = nil
+	}
+	for _, b := range mdscan.BlocksExt(f.Content, flavorExt(doc, f)) {
+		if b.Kind == mdscan.BlockHeading {
+			flush()
+			text := mdscan.InlineText(b.Text)
+			for len(levels) > 0 && levels[len(levels)-1] >= b.Level {
+				levels, trail = levels[:len(levels)-1], trail[:len(trail)-1]
+			}
+			levels, trail = append(levels, b.Level), append(append([]string(nil), trail...), text)
+			anchor = b.ID
+			if anchor == "" {
+				anchor = opts.Slug(text)
+				if n := counts[anchor]; n > 0 {
+					counts[anchor] = n + 1
+					anchor += "-" + strconv.Itoa(n)
+				} else {
+					counts[anchor] = 1
+				}
+			}
+			units = append(units, chunkUnit{text: text, line: b.Line})
+			continue
+		}
+		if b.Kind == mdscan.BlockCode {
+			if opts.ExcludeCode {
+				continue
+			}
+			units = append(units, splitUnit(chunkUnit{text: b.Text, line: b.Line}, opts.MaxChars)...)
+			continue
+		}
+		var media []string
+		for _, l := range mdscan.Scan([]byte(b.Text)).Links {
+			if it, ok := doc.ResolveMedia(f.Path, l.Dest); ok && !contains(media, it.ID) {
+				media = append(media, it.ID)
+			}
+		}
+		text := mdscan.ReplaceImages(b.Text, func(alt, _ string) string {
+			return `\[Image: ` + mdscan.InlineText(alt) + `\]`
+		})
+		if b.Kind == mdscan.BlockTable {
+			rows := strings.Split(text, "\n")
+			for i, row := range rows {
+				rows[i] = strings.Join(mdscan.TableCells(row), " | ")
+			}
+			text = strings.Join(rows, "\n")
+		}
+		lines := strings.Split(text, "\n")
+		for i := range lines {
+			lines[i] = mdscan.InlineText(lines[i])
+		}
+		sep := " "
+		if b.Kind == mdscan.BlockTable {
+			sep = "\n"
+		}
+		text = strings.Join(lines, sep)
+		if b.Kind == mdscan.BlockListItem {
+			text = "- " + text
+		}
+		u := chunkUnit{text: text, line: b.Line, media: media}
+		units = append(units, splitUnit(u, opts.MaxChars)...)
+	}
+	flush()
+	return out
+}
+
+// packUnits groups the units of one section into chunks of at most MaxChars
+// characters plus overlap.
+func packUnits(path string, trail []string, anchor string, units []chunkUnit, opts ChunkOptions) []Chunk {
+	var out []Chunk
+	var cur *Chunk
+	var size int
+	overlap := ""
+	for _, u := range units {
+		n := utf8.RuneCountInString(u.text)
+		if cur != nil && size+2+n > opts.MaxChars {
+			overlap = tail(cur.Text, opts.Overlap)
+			out = append(out, *cur)
+			cur = nil
+		}
+		if cur == nil {
+			cur = &Chunk{Path: path, Headings: trail, Anchor: anchor, StartLine: u.line}
+			if overlap != "" {
+				cur.Text = overlap + "\n\n"
+			}
+			size = 0
+		} else {
+			cur.Text += "\n\n"
+			size += 2
+		}
+		cur.Text += u.text
+		size += n
+		cur.EndLine = u.line + strings.Count(u.text, "\n")
+		for _, id := range u.media {
+			if !contains(cur.MediaIDs, id) {
+				cur.MediaIDs = append(cur.MediaIDs, id)
+			}
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
+
+// splitUnit splits u at word boundaries into pieces of at most max characters.
+func splitUnit(u chunkUnit, max int) []chunkUnit {
+	if utf8.RuneCountInString(u.text) <= max {
+		return []chunkUnit{u}
+	}
+	var out []chunkUnit
+	rest := []rune(u.text)
+	for len(rest) > max {
+		cut := max
+		for i := max; i > max/2; i-- {
+			if rest[i] == ' ' || rest[i] == '\n' {
+				cut = i
+				break
+			}
+		}
+		out = append(out, chunkUnit{text: strings.TrimSpace(string(rest[:cut])), line: u.line, media: u.media})
+		rest = []rune(strings.TrimLeft(string(rest[cut:]), " \n"))
+	}
+	if len(rest) > 0 {
+		out = append(out, chunkUnit{text: string(rest), line: u.line, media: u.media})
+	}
+	return out
+}
+
+// tail returns at most n trailing characters of s, starting at a word boundary
+// when one is available.
+func tail(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 {
+		return ""
+	}
+	if len(r) <= n {
+		return s
+	}
+	t := r[len(r)-n:]
+	for i, c := range t {
+		if c == ' ' || c == '\n' {
+			return strings.TrimSpace(string(t[i:]))
+		}
+	}
+	return string(t)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
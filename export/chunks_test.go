@@ -0,0 +1,53 @@
+package export
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToChunks(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("# Overview\n\nIntro with ![logo](assets/logo.png).\n\n## Details\n\n" +
+		strings.Repeat("word ", 30) + "\n\n" + strings.Repeat("more ", 30) + "\n\n## Details\n\nAgain.\n")
+	chunks, err := ToChunks(doc, ChunkOptions{Files: []string{"index.md"}, MaxChars: 200, Overlap: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("expected 4 chunks, got %d: %+v", len(chunks), chunks)
+	}
+	first := chunks[0]
+	if first.Text != "Overview\n\nIntro with [Image: logo]." || first.Anchor != "overview" || !reflect.DeepEqual(first.MediaIDs, []string{"logo"}) {
+		t.Fatalf("unexpected first chunk: %+v", first)
+	}
+	if !reflect.DeepEqual(chunks[1].Headings, []string{"Overview", "Details"}) || chunks[1].StartLine != 5 {
+		t.Fatalf("unexpected provenance: %+v", chunks[1])
+	}
+	if !strings.HasPrefix(chunks[2].Text, "word word") || !strings.Contains(chunks[2].Text, "more") {
+		t.Fatalf("expected overlap from previous chunk: %q", chunks[2].Text)
+	}
+	if chunks[3].Anchor != "details-1" || chunks[3].Text != "Details\n\nAgain." {
+		t.Fatalf("unexpected last chunk: %+v", chunks[3])
+	}
+}
+
+func TestToChunksSplitsLongParagraphs(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte(strings.Repeat("abcdefghi ", 50))
+	chunks, err := ToChunks(doc, ChunkOptions{Files: []string{"index.md"}, MaxChars: 100, Overlap: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 5 {
+		t.Fatalf("expected 5 chunks, got %d", len(chunks))
+	}
+	for _, c := range chunks {
+		if n := len([]rune(c.Text)); n > 100 {
+			t.Fatalf("chunk too long (%d): %q", n, c.Text)
+		}
+	}
+	if _, err := ToChunks(doc, ChunkOptions{MaxChars: 10, Overlap: 10}); err == nil {
+		t.Fatal("expected error for overlap >= size")
+	}
+}
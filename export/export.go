@@ -0,0 +1,60 @@
+// Package export converts MDOCX documents into formats consumed by other tools,
+// such as feed readers, speech synthesizers, and retrieval pipelines.
+//
+// Exporters operate on a decoded *mdocx.Document and never modify it.
+package export
+
+import (
+	"github.com/logicossoftware/go-mdocx"
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// metaString returns the string metadata value for key, or "".
+func metaString(doc *mdocx.Document, key string) string {
+	s, _ := doc.Metadata[key].(string)
+	return s
+}
+
+// rootPath returns the primary Markdown path: Markdown.RootPath, then
+// metadata "root", then "".
+func rootPath(doc *mdocx.Document) string {
+	if doc.Markdown.RootPath != "" {
+		return doc.Markdown.RootPath
+	}
+	return metaString(doc, "root")
+}
+
+// readingOrder returns the Markdown files with the root file first and the rest
+// in bundle order.
+func readingOrder(doc *mdocx.Document) []mdocx.MarkdownFile {
+	root := rootPath(doc)
+	out := make([]mdocx.MarkdownFile, 0, len(doc.Markdown.Files))
+	for _, f := range doc.Markdown.Files {
+		if f.Path == root {
+			out = append(out, f)
+		}
+	}
+	for _, f := range doc.Markdown.Files {
+		if f.Path != root {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// flavorExt returns the Markdown extensions of the flavor of f.
+func flavorExt(doc *mdocx.Document, f mdocx.MarkdownFile) mdscan.Extensions {
+	return mdscan.FlavorExtensions(string(doc.FileFlavor(f)))
+}
+
+// fileTitle returns the "title" attribute of f, the plain text of its first
+// heading, or its path.
+func fileTitle(doc *mdocx.Document, f mdocx.MarkdownFile) string {
+	if t := f.Attributes["title"]; t != "" {
+		return t
+	}
+	if hs := mdscan.ScanExt(f.Content, flavorExt(doc, f)).Headings; len(hs) > 0 {
+		return mdscan.InlineText(hs[0].Text)
+	}
+	return f.Path
+}
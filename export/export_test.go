@@ -0,0 +1,39 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// sampleDoc returns a small document shared by the exporter tests.
+func sampleDoc() *mdocx.Document {
+	return &mdocx.Document{
+		Metadata: map[string]any{"title": "Changelog", "creator": "Release Team", "root": "index.md"},
+		Markdown: mdocx.MarkdownBundle{
+			BundleVersion: mdocx.VersionV1,
+			Files: []mdocx.MarkdownFile{
+				{Path: "news/v1.md", Content: []byte("# Version 1\n\nFirst *release*.\n"), Attributes: map[string]string{"date": "2024-01-10"}},
+				{Path: "index.md", Content: []byte("# Overview\n\nSee ![logo](assets/logo.png) and the [news](news/v2.md).\n")},
+				{Path: "news/v2.md", Content: []byte("Second release.\n"), Attributes: map[string]string{"updated": "2024-03-01T12:00:00Z", "title": "Version 2", "summary": "Faster"}},
+			},
+		},
+		Media: mdocx.MediaBundle{
+			BundleVersion: mdocx.VersionV1,
+			Items: []mdocx.MediaItem{
+				{ID: "logo", Path: "assets/logo.png", MIMEType: "image/png", Data: []byte{1, 2, 3}, Attributes: map[string]string{"alt": "Project logo"}},
+			},
+		},
+	}
+}
+
+func TestReadingOrder(t *testing.T) {
+	doc := sampleDoc()
+	files := readingOrder(doc)
+	if files[0].Path != "index.md" || files[1].Path != "news/v1.md" || files[2].Path != "news/v2.md" {
+		t.Fatalf("unexpected order: %s, %s, %s", files[0].Path, files[1].Path, files[2].Path)
+	}
+	if got := fileTitle(doc, files[0]); got != "Overview" {
+		t.Fatalf("title: %q", got)
+	}
+}
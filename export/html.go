@@ -0,0 +1,261 @@
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/logicossoftware/go-mdocx"
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// HTMLRenderer renders a Markdown file to an HTML fragment, which is written
+// to the page unescaped.
+type HTMLRenderer func(f mdocx.MarkdownFile) ([]byte, error)
+
+// HTMLOptions configures HTML.
+type HTMLOptions struct {
+	// Files lists the Markdown paths to render, in order. If empty, the root
+	// file is rendered first, followed by the others in bundle order.
+	Files []string
+	// Title is the page title. Defaults to metadata "title", then the title of
+	// the first file.
+	Title string
+	// Lang is the lang attribute of the page. Defaults to metadata "language",
+	// then "lang"; omitted if none is set.
+	Lang string
+	// Render renders each file. The file's Content has its image references to
+	// media items already replaced by data: URIs. If nil, a built-in renderer
+	// that handles headings, paragraphs, lists, block quotes, code blocks,
+	// tables, and common inline Markdown is used; it escapes raw HTML and
+	// replaces link and image URLs other than http, https, mailto, relative,
+	// and raster data:image URLs with "#".
+	Render HTMLRenderer
+	// Style is the CSS of the page. Defaults to a small style sheet for
+	// readable text and scaled images.
+	Style string
+}
+
+// defaultHTMLStyle is the page CSS used when HTMLOptions.Style is empty.
+const defaultHTMLStyle = `body{max-width:50em;margin:2em auto;padding:0 1em;font-family:system-ui,sans-serif;line-height:1.5}
+img{max-width:100%}
+pre{overflow-x:auto;padding:.5em;background:#f5f5f5}
+table{border-collapse:collapse}
+th,td{border:1px solid #ccc;padding:.25em .5em}
+blockquote{margin-left:0;padding-left:1em;border-left:3px solid #ccc;color:#555}
+section+section{border-top:1px solid #ccc;margin-top:2em}`
+
+// HTML writes doc as a single self-contained HTML page for viewing in a
+// browser without any other files. Each Markdown file is rendered into its own
+// <section>, preceded by a table of contents when there is more than one, and
+// every image that references a media item of doc is inlined as a data: URI.
+// The built-in renderer also inlines links to media items and turns relative
+// links to rendered Markdown files into links within the page.
+//
+// It returns an error wrapping mdocx.ErrNotFound if opts.Files names a path that
+// is not in the document.
+func HTML(w io.Writer, doc *mdocx.Document, opts HTMLOptions) error {
+	files, err := selectFiles(doc, opts.Files)
+	if err != nil {
+		return err
+	}
+	sections := make(map[string]string, len(files))
+	for _, f := range files {
+		sections[f.Path] = sectionID(f.Path)
+	}
+	title := firstNonEmpty(opts.Title, metaString(doc, "title"))
+	if title == "" && len(files) > 0 {
+		title = fileTitle(doc, files[0])
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<!DOCTYPE html>\n<html")
+	if lang := firstNonEmpty(opts.Lang, metaString(doc, "language"), metaString(doc, "lang")); lang != "" {
+		fmt.Fprintf(bw, ` lang="%s"`, html.EscapeString(lang))
+	}
+	bw.WriteString(">\n<head>\n<meta charset=\"utf-8\">\n")
+	bw.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1">` + "\n")
+	fmt.Fprintf(bw, "<title>%s</title>\n", html.EscapeString(title))
+	if a := metaString(doc, "creator"); a != "" {
+		fmt.Fprintf(bw, "<meta name=\"author\" content=\"%s\">\n", html.EscapeString(a))
+	}
+	fmt.Fprintf(bw, "<style>\n%s\n</style>\n</head>\n<body>\n", strings.ReplaceAll(firstNonEmpty(opts.Style, defaultHTMLStyle), "</", `<\/`))
+	if len(files) > 1 {
+		bw.WriteString("<nav>\n<ul>\n")
+		for _, f := range files {
+			fmt.Fprintf(bw, "<li><a href=\"#%s\">%s</a></li>\n", html.EscapeString(sections[f.Path]), html.EscapeString(fileTitle(doc, f)))
+		}
+		bw.WriteString("</ul>\n</nav>\n")
+	}
+	for _, f := range files {
+		f.Content = inlineImages(doc, f)
+		var body []byte
+		if opts.Render != nil {
+			if body, err = opts.Render(f); err != nil {
+				return fmt.Errorf("export: render %s: %w", f.Path, err)
+			}
+		} else {
+			body = renderHTML(doc, f, func(dest string) string { return htmlLinkURL(doc, f.Path, dest, sections) })
+		}
+		fmt.Fprintf(bw, "<section id=\"%s\">\n", html.EscapeString(sections[f.Path]))
+		bw.Write(body)
+		bw.WriteString("</section>\n")
+	}
+	bw.WriteString("</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// sectionID returns the id of the <section> of the Markdown file at p.
+func sectionID(p string) string {
+	return "file-" + mdocx.GitHubSlug(strings.NewReplacer("/", "-", ".", "-").Replace(p))
+}
+
+// dataURI returns item's data as a data: URI.
+func dataURI(item *mdocx.MediaItem) string {
+	mime := item.MIMEType
+	if mime == "" {
+		mime = "application/octet-stream"
+	}
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(item.Data)
+}
+
+// inlineImages returns the content of f with the destinations of images that
+// reference media items of doc replaced by data: URIs. Fenced code is left
+// alone, and HTML <img> tags become Markdown images.
+func inlineImages(doc *mdocx.Document, f mdocx.MarkdownFile) []byte {
+	rewrite := func(s string) string {
+		return mdscan.ReplaceImages(s, func(alt, dest string) string {
+			if item, ok := doc.ResolveMedia(f.Path, dest); ok {
+				dest = dataURI(item)
+			}
+			return "![" + alt + "](<" + dest + ">)"
+		})
+	}
+	var b strings.Builder
+	last := 0
+	for _, fence := range mdscan.Fences(f.Content) {
+		b.WriteString(rewrite(string(f.Content[last:fence.Start])))
+		b.Write(f.Content[fence.Start:fence.End])
+		last = fence.End
+	}
+	b.WriteString(rewrite(string(f.Content[last:])))
+	return []byte(b.String())
+}
+
+// htmlLinkURL returns the URL written for the link destination dest in the
+// Markdown file at from: a data: URI for media items, a fragment for rendered
+// Markdown files, and dest itself otherwise.
+func htmlLinkURL(doc *mdocx.Document, from, dest string, sections map[string]string) string {
+	if item, ok := doc.ResolveMedia(from, dest); ok {
+		return dataURI(item)
+	}
+	target, frag, _ := strings.Cut(dest, "#")
+	if target == "" || strings.HasPrefix(target, "/") || strings.Contains(target, ":") {
+		return dest
+	}
+	target, err := url.PathUnescape(target)
+	if err != nil {
+		return dest
+	}
+	id, ok := sections[path.Join(path.Dir(from), target)]
+	if !ok {
+		return dest
+	}
+	if frag != "" {
+		return "#" + frag
+	}
+	return "#" + id
+}
+
+// safeSchemes are the URL schemes the built-in renderer writes as they are.
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// safeDataImages are the data: URL prefixes the built-in renderer writes as
+// they are: raster images only, since SVG can carry script.
+var safeDataImages = []string{"data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp", "data:image/avif", "data:image/bmp"}
+
+// safeURL returns u if it is relative, a fragment, or has a safe scheme or
+// raster data:image type, and "#" otherwise.
+func safeURL(u string) string {
+	// Browsers ignore leading spaces and controls, and tabs and newlines
+	// anywhere in a URL.
+	s := strings.TrimLeftFunc(u, func(r rune) bool { return r <= ' ' })
+	s = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(s)
+	i := strings.IndexAny(s, ":/?#")
+	if i < 0 || s[i] != ':' {
+		return u
+	}
+	scheme := strings.ToLower(s[:i])
+	if safeSchemes[scheme] {
+		return u
+	}
+	if scheme == "data" {
+		lower := strings.ToLower(s)
+		for _, p := range safeDataImages {
+			if rest, ok := strings.CutPrefix(lower, p); ok && (strings.HasPrefix(rest, ";") || strings.HasPrefix(rest, ",")) {
+				return u
+			}
+		}
+	}
+	return "#"
+}
+
+// renderHTML is the built-in HTMLRenderer. link maps link and image
+// destinations to URLs; those that are not safe to follow become "#".
+func renderHTML(doc *mdocx.Document, f mdocx.MarkdownFile, link func(dest string) string) []byte {
+	var b strings.Builder
+	inline := func(s string) string {
+		return mdscan.InlineHTML(s, func(dest string) string { return safeURL(link(dest)) })
+	}
+	inList := false
+	for _, blk := range mdscan.BlocksExt(f.Content, flavorExt(doc, f)) {
+		if inList && blk.Kind != mdscan.BlockListItem {
+			b.WriteString("</ul>\n")
+			inList = false
+		}
+		switch blk.Kind {
+		case mdscan.BlockHeading:
+			id := firstNonEmpty(blk.ID, mdocx.GitHubSlug(mdscan.InlineText(blk.Text)))
+			fmt.Fprintf(&b, "<h%d id=\"%s\">%s</h%d>\n", blk.Level, html.EscapeString(id), inline(blk.Text), blk.Level)
+		case mdscan.BlockListItem:
+			if !inList {
+				b.WriteString("<ul>\n")
+				inList = true
+			}
+			fmt.Fprintf(&b, "<li>%s</li>\n", inline(blk.Text))
+		case mdscan.BlockQuote:
+			fmt.Fprintf(&b, "<blockquote><p>%s</p></blockquote>\n", inline(blk.Text))
+		case mdscan.BlockCode:
+			b.WriteString("<pre><code")
+			if lang, _, _ := strings.Cut(blk.Info, " "); lang != "" {
+				fmt.Fprintf(&b, ` class="language-%s"`, html.EscapeString(lang))
+			}
+			fmt.Fprintf(&b, ">%s\n</code></pre>\n", html.EscapeString(blk.Text))
+		case mdscan.BlockTable:
+			b.WriteString("<table>\n")
+			for i, row := range strings.Split(blk.Text, "\n") {
+				cell := "td"
+				if i == 0 {
+					cell = "th"
+				}
+				b.WriteString("<tr>")
+				for _, c := range mdscan.TableCells(row) {
+					fmt.Fprintf(&b, "<%s>%s</%s>", cell, inline(c), cell)
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</table>\n")
+		default:
+			fmt.Fprintf(&b, "<p>%s</p>\n", inline(blk.Text))
+		}
+	}
+	if inList {
+		b.WriteString("</ul>\n")
+	}
+	return []byte(b.String())
+}
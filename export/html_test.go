@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func TestHTML(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[2].Content = []byte("Second *release* & more.\n\n- a\n- b\n\n```go\n![no](../assets/logo.png)\n```\n\n| k | v |\n|---|---|\n| x | <y> |\n\n<script>alert(1)</script>\n")
+	var buf bytes.Buffer
+	if err := HTML(&buf, doc, HTMLOptions{Lang: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>\n<html lang=\"en\">",
+		"<title>Changelog</title>",
+		`<meta name="author" content="Release Team">`,
+		`<li><a href="#file-index-md">Overview</a></li>`,
+		`<section id="file-news-v2-md">`,
+		`<h1 id="overview">Overview</h1>`,
+		`<p>See <img src="data:image/png;base64,AQID" alt="logo"> and the <a href="#file-news-v2-md">news</a>.</p>`,
+		"<p>Second <em>release</em> &amp; more.</p>",
+		"<ul>\n<li>a</li>\n<li>b</li>\n</ul>",
+		"<pre><code class=\"language-go\">![no](../assets/logo.png)\n</code></pre>",
+		"<tr><th>k</th><th>v</th></tr>\n<tr><td>x</td><td>&lt;y&gt;</td></tr>",
+		"<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, `id="file-index-md"`) > strings.Index(out, `id="file-news-v1-md"`) {
+		t.Fatal("root file is not rendered first")
+	}
+}
+
+func TestHTMLCustomRenderer(t *testing.T) {
+	doc := sampleDoc()
+	var got []string
+	render := func(f mdocx.MarkdownFile) ([]byte, error) {
+		got = append(got, string(f.Content))
+		return []byte("<div>custom</div>\n"), nil
+	}
+	var buf bytes.Buffer
+	err := HTML(&buf, doc, HTMLOptions{Files: []string{"index.md"}, Title: "T", Render: render, Style: "p{}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !strings.Contains(got[0], "![logo](<data:image/png;base64,AQID>)") {
+		t.Fatalf("renderer input: %q", got)
+	}
+	out := buf.String()
+	for _, want := range []string{"<title>T</title>", "<style>\np{}\n</style>", "<div>custom</div>"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<nav>") {
+		t.Fatal("single file page has a table of contents")
+	}
+
+	boom := errors.New("boom")
+	err = HTML(&buf, doc, HTMLOptions{Render: func(mdocx.MarkdownFile) ([]byte, error) { return nil, boom }})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected renderer error, got %v", err)
+	}
+	if err := HTML(&buf, doc, HTMLOptions{Files: []string{"missing.md"}}); !errors.Is(err, mdocx.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHTMLUnsafeURLs(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[2].Content = []byte("[a](javascript:alert(1)) [b](<java\tscript:x>) [c](https://e.com) [d](mailto:x@e.com) [e](#top) [f](other/page.html)\n\n" +
+		"![g](data:image/svg+xml;base64,AAAA) ![h](data:image/gif;base64,AAAA) ![i](VBScript:x)\n")
+	var buf bytes.Buffer
+	if err := HTML(&buf, doc, HTMLOptions{Files: []string{"news/v2.md"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<p><a href="#">a</a> <a href="#">b</a> <a href="https://e.com">c</a> <a href="mailto:x@e.com">d</a> <a href="#top">e</a> <a href="other/page.html">f</a></p>` + "\n" +
+		`<p><img src="#" alt="g"> <img src="data:image/gif;base64,AAAA" alt="h"> <img src="#" alt="i"></p>`
+	if out := buf.String(); !strings.Contains(out, want) {
+		t.Fatalf("output missing %q:\n%s", want, out)
+	}
+}
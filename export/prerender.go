@@ -0,0 +1,107 @@
+package export
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/logicossoftware/go-mdocx"
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// Renderer renders the source of a code fence to an SVG image.
+type Renderer func(ctx context.Context, source string) (svg []byte, err error)
+
+// PreRenderOptions configures PreRender. A nil Renderer leaves its fences
+// alone.
+type PreRenderOptions struct {
+	// Math renders LaTeX math in "math" and "latex" fences.
+	Math Renderer
+	// Mermaid renders "mermaid" fences.
+	Mermaid Renderer
+	// PlantUML renders "plantuml" and "puml" fences.
+	PlantUML Renderer
+}
+
+// renderer returns the kind of fence and its renderer for the first word of
+// an info string.
+func (o PreRenderOptions) renderer(info string) (string, Renderer) {
+	lang, _, _ := strings.Cut(info, " ")
+	switch strings.ToLower(lang) {
+	case "math", "latex":
+		return "math", o.Math
+	case "mermaid":
+		return "mermaid", o.Mermaid
+	case "plantuml", "puml":
+		return "plantuml", o.PlantUML
+	}
+	return "", nil
+}
+
+// PreRender returns a copy of doc in which the math and diagram fences that
+// opts has a renderer for are replaced by images of their SVG rendering, so
+// that exported HTML or EPUB needs no client-side JavaScript. doc is not
+// modified.
+//
+// Each rendering is added as an "image/svg+xml" media item with the ID
+// "render-<kind>-<hash>" and the path "rendered/<ID>.svg", where kind is
+// "math", "mermaid", or "plantuml" and hash is derived from the kind and the
+// fence content, so identical fences share one item and repeated exports
+// produce the same IDs. The fence is replaced by an image linking to
+// mdocx://media/<ID>, and the item's alt attribute (mdocx.AltTextAttr) holds
+// the fence source.
+func PreRender(ctx context.Context, doc *mdocx.Document, opts PreRenderOptions) (*mdocx.Document, error) {
+	out := *doc
+	out.Markdown.Files = make([]mdocx.MarkdownFile, len(doc.Markdown.Files))
+	out.Media.Items = append([]mdocx.MediaItem(nil), doc.Media.Items...)
+	ids := make(map[string]struct{}, len(out.Media.Items))
+	for _, it := range out.Media.Items {
+		ids[it.ID] = struct{}{}
+	}
+
+	for i, f := range doc.Markdown.Files {
+		var b strings.Builder
+		last := 0
+		for _, fence := range mdscan.Fences(f.Content) {
+			kind, render := opts.renderer(fence.Info)
+			if render == nil {
+				continue
+			}
+			id := renderID(kind, fence.Code)
+			if _, ok := ids[id]; !ok {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				svg, err := render(ctx, fence.Code)
+				if err != nil {
+					return nil, fmt.Errorf("export: render %s fence at %s:%d: %w", kind, f.Path, fence.Line, err)
+				}
+				out.Media.Items = append(out.Media.Items, mdocx.MediaItem{
+					ID:         id,
+					Path:       "rendered/" + id + ".svg",
+					MIMEType:   "image/svg+xml",
+					Data:       svg,
+					Attributes: map[string]string{mdocx.AltTextAttr: fence.Code},
+				})
+				ids[id] = struct{}{}
+			}
+			b.Write(f.Content[last:fence.Start])
+			fmt.Fprintf(&b, "%s![%s](mdocx://media/%s)\n", fence.Indent, kind, id)
+			last = fence.End
+		}
+		if last > 0 {
+			b.Write(f.Content[last:])
+			f.Content = []byte(b.String())
+		}
+		out.Markdown.Files[i] = f
+	}
+	return &out, nil
+}
+
+// renderID returns the media ID of the rendering of a fence of the given kind.
+func renderID(kind, source string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + source))
+	return "render-" + kind + "-" + hex.EncodeToString(sum[:8])
+}
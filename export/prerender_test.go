@@ -0,0 +1,55 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func TestPreRender(t *testing.T) {
+	src := "# Eq\n\n```math\nE = mc^2\n```\n\n```go\nx := 1\n```\n\n- item\n\n  ```mermaid\n  graph TD\n  ```\n\n```math\nE = mc^2\n```\n"
+	doc := &mdocx.Document{
+		Markdown: mdocx.MarkdownBundle{BundleVersion: mdocx.VersionV1, Files: []mdocx.MarkdownFile{{Path: "index.md", Content: []byte(src)}}},
+		Media:    mdocx.MediaBundle{BundleVersion: mdocx.VersionV1},
+	}
+	calls := 0
+	svg := func(ctx context.Context, source string) ([]byte, error) {
+		calls++
+		return []byte("<svg>" + source + "</svg>"), nil
+	}
+	out, err := PreRender(context.Background(), doc, PreRenderOptions{Math: svg, Mermaid: svg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.Markdown.Files[0].Content) != src {
+		t.Fatal("PreRender modified the input document")
+	}
+	if calls != 2 || len(out.Media.Items) != 2 {
+		t.Fatalf("%d renders, %d items; want 2 each", calls, len(out.Media.Items))
+	}
+	math, mermaid := out.Media.Items[0], out.Media.Items[1]
+	if !strings.HasPrefix(math.ID, "render-math-") || math.Path != "rendered/"+math.ID+".svg" || math.Attributes[mdocx.AltTextAttr] != "E = mc^2" {
+		t.Fatalf("unexpected math item: %+v", math)
+	}
+	if string(mermaid.Data) != "<svg>graph TD</svg>" {
+		t.Fatalf("mermaid source = %q", mermaid.Data)
+	}
+	want := "# Eq\n\n![math](mdocx://media/" + math.ID + ")\n\n```go\nx := 1\n```\n\n- item\n\n  ![mermaid](mdocx://media/" + mermaid.ID + ")\n\n![math](mdocx://media/" + math.ID + ")\n"
+	if got := string(out.Markdown.Files[0].Content); got != want {
+		t.Fatalf("content:\n%s\nwant:\n%s", got, want)
+	}
+
+	again, err := PreRender(context.Background(), doc, PreRenderOptions{Math: svg, Mermaid: svg})
+	if err != nil || again.Media.Items[0].ID != math.ID {
+		t.Fatalf("IDs are not deterministic: %v", err)
+	}
+
+	boom := errors.New("boom")
+	_, err = PreRender(context.Background(), doc, PreRenderOptions{Mermaid: func(context.Context, string) ([]byte, error) { return nil, boom }})
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "index.md:13") {
+		t.Fatalf("expected wrapped render error, got %v", err)
+	}
+}
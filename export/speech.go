@@ -0,0 +1,239 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/logicossoftware/go-mdocx"
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// SpeechFormat selects the output format of Speech.
+type SpeechFormat int
+
+const (
+	// SpeechText produces plain text with one paragraph per block.
+	SpeechText SpeechFormat = iota
+	// SpeechSSML produces an SSML 1.1 document.
+	SpeechSSML
+)
+
+// SpeechOptions configures Speech.
+type SpeechOptions struct {
+	// Format selects plain text or SSML output.
+	Format SpeechFormat
+	// Files lists the Markdown paths to read, in order. If empty, the root file
+	// is read first, followed by the others in bundle order.
+	Files []string
+	// Lang is the xml:lang of the SSML document. Defaults to metadata
+	// "language", then "lang"; omitted if none is set.
+	Lang string
+	// MediaText returns the text read in place of an image. item is nil when the
+	// image does not reference a media item of the document. If nil, images are
+	// read as "Image: " followed by the alt text, the item's "alt" attribute, or
+	// nothing.
+	MediaText func(alt string, item *mdocx.MediaItem) string
+	// IncludeCode reads code blocks verbatim instead of announcing them.
+	IncludeCode bool
+}
+
+// Speech writes the Markdown content of doc in reading order as text suitable
+// for speech synthesis. Formatting markers, link destinations, and HTML tags are
+// removed, tables are read row by row, and images are replaced by placeholders.
+// In SSML output each image that references a media item is preceded by a
+// <mark name="media:ID"/> so audio can be aligned with the media.
+//
+// It returns an error wrapping mdocx.ErrNotFound if opts.Files names a path that
+// is not in the document.
+func Speech(w io.Writer, doc *mdocx.Document, opts SpeechOptions) error {
+	files, err := selectFiles(doc, opts.Files)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	ssml := opts.Format == SpeechSSML
+	if ssml {
+		lang := firstNonEmpty(opts.Lang, metaString(doc, "language"), metaString(doc, "lang"))
+		bw.WriteString(xml.Header)
+		bw.WriteString(`<speak version="1.1" xmlns="http://www.w3.org/2001/10/synthesis"`)
+		if lang != "" {
+			fmt.Fprintf(bw, ` xml:lang="%s"`, xmlEscape(lang))
+		}
+		bw.WriteString(">\n")
+	}
+	first := true
+	for fi, f := range files {
+		if ssml && fi > 0 {
+			bw.WriteString(`<break time="1s"/>` + "\n")
+		}
+		for _, b := range mdscan.BlocksExt(f.Content, flavorExt(doc, f)) {
+			var texts []string
+			switch b.Kind {
+			case mdscan.BlockCode:
+				if opts.IncludeCode {
+					texts = []string{b.Text}
+				} else {
+					texts = []string{"Code block omitted."}
+				}
+			case mdscan.BlockTable:
+				for _, row := range strings.Split(b.Text, "\n") {
+					texts = append(texts, strings.Join(mdscan.TableCells(row), ", "))
+				}
+			default:
+				texts = []string{b.Text}
+			}
+			if ssml {
+				writeSSMLBlock(bw, doc, f.Path, b, texts, opts)
+				continue
+			}
+			if !first {
+				bw.WriteString("\n")
+			}
+			first = false
+			for _, t := range texts {
+				if b.Kind != mdscan.BlockCode {
+					t = speakInline(doc, f.Path, t, opts, false)
+				}
+				bw.WriteString(t)
+				bw.WriteString("\n")
+			}
+		}
+	}
+	if ssml {
+		bw.WriteString("</speak>\n")
+	}
+	return bw.Flush()
+}
+
+// writeSSMLBlock writes one block as SSML paragraph markup.
+func writeSSMLBlock(bw *bufio.Writer, doc *mdocx.Document, from string, b mdscan.Block, texts []string, opts SpeechOptions) {
+	bw.WriteString("<p>")
+	for i, t := range texts {
+		if b.Kind == mdscan.BlockCode {
+			t = xmlEscape(t)
+		} else {
+			t = speakInline(doc, from, t, opts, true)
+		}
+		switch {
+		case b.Kind == mdscan.BlockHeading:
+			fmt.Fprintf(bw, `<emphasis level="strong">%s</emphasis>`, t)
+		case len(texts) > 1:
+			if i > 0 {
+				bw.WriteString(" ")
+			}
+			fmt.Fprintf(bw, "<s>%s</s>", t)
+		default:
+			bw.WriteString(t)
+		}
+	}
+	bw.WriteString("</p>\n")
+	if b.Kind == mdscan.BlockHeading {
+		bw.WriteString(`<break strength="strong"/>` + "\n")
+	}
+}
+
+// Private-use runes bracket image placeholders while inline Markdown is reduced
+// to plain text.
+const (
+	placeholderOpen  = '\uE000'
+	placeholderClose = '\uE001'
+)
+
+// speakInline converts inline Markdown to plain text, substituting image
+// placeholders. When ssml is set the result is XML-escaped and images that
+// reference media items are preceded by a mark element.
+func speakInline(doc *mdocx.Document, from, s string, opts SpeechOptions, ssml bool) string {
+	type image struct {
+		alt  string
+		item *mdocx.MediaItem
+	}
+	var images []image
+	s = mdscan.ReplaceImages(s, func(alt, dest string) string {
+		item, _ := doc.ResolveMedia(from, dest)
+		images = append(images, image{alt: mdscan.InlineText(alt), item: item})
+		return string(placeholderOpen) + strconv.Itoa(len(images)-1) + string(placeholderClose)
+	})
+	s = strings.Join(strings.Fields(mdscan.InlineText(s)), " ")
+	if ssml {
+		s = xmlEscape(s)
+	}
+	if len(images) == 0 {
+		return s
+	}
+	var b strings.Builder
+	for {
+		open := strings.IndexRune(s, placeholderOpen)
+		if open < 0 {
+			break
+		}
+		end := strings.IndexRune(s[open:], placeholderClose)
+		if end < 0 {
+			break
+		}
+		n, err := strconv.Atoi(s[open+len(string(placeholderOpen)) : open+end])
+		b.WriteString(s[:open])
+		s = s[open+end+len(string(placeholderClose)):]
+		if err != nil || n >= len(images) {
+			continue
+		}
+		img := images[n]
+		text := mediaText(img.alt, img.item, opts)
+		if ssml {
+			if img.item != nil {
+				fmt.Fprintf(&b, `<mark name="media:%s"/>`, xmlEscape(img.item.ID))
+			}
+			text = xmlEscape(text)
+		}
+		b.WriteString(text)
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// mediaText returns the placeholder text for an image.
+func mediaText(alt string, item *mdocx.MediaItem, opts SpeechOptions) string {
+	if opts.MediaText != nil {
+		return opts.MediaText(alt, item)
+	}
+	if alt == "" && item != nil {
+		alt = item.Attributes["alt"]
+	}
+	if alt == "" {
+		return "Image."
+	}
+	return "Image: " + alt + "."
+}
+
+// selectFiles returns the named Markdown files in order, or the reading order
+// if paths is empty.
+func selectFiles(doc *mdocx.Document, paths []string) ([]mdocx.MarkdownFile, error) {
+	if len(paths) == 0 {
+		return readingOrder(doc), nil
+	}
+	out := make([]mdocx.MarkdownFile, 0, len(paths))
+	for _, p := range paths {
+		found := false
+		for _, f := range doc.Markdown.Files {
+			if f.Path == p {
+				out = append(out, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: markdown file %q", mdocx.ErrNotFound, p)
+		}
+	}
+	return out, nil
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
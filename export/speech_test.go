@@ -0,0 +1,56 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func TestSpeechText(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = append(doc.Markdown.Files[1].Content, "\n```\ncode()\n```\n\n| Name | Value |\n|---|---|\n| a | 1 |\n"...)
+	var buf bytes.Buffer
+	if err := Speech(&buf, doc, SpeechOptions{Files: []string{"index.md"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Overview\n\nSee Image: logo. and the news.\n\nCode block omitted.\n\nName, Value\na, 1\n"
+	if buf.String() != want {
+		t.Fatalf("got %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestSpeechSSML(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("# A & B\n\n![](assets/logo.png) and ![x](https://example.com/x.png)\n")
+	var buf bytes.Buffer
+	err := Speech(&buf, doc, SpeechOptions{Format: SpeechSSML, Lang: "en-GB", Files: []string{"index.md", "news/v2.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if err := xml.Unmarshal(buf.Bytes(), new(struct{})); err != nil {
+		t.Fatalf("invalid SSML: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		`xml:lang="en-GB"`,
+		`<emphasis level="strong">A &amp; B</emphasis>`,
+		`<mark name="media:logo"/>Image: Project logo. and Image: x.`,
+		`<break time="1s"/>`,
+		`<p>Second release.</p>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("SSML missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSpeechUnknownFile(t *testing.T) {
+	err := Speech(&bytes.Buffer{}, sampleDoc(), SpeechOptions{Files: []string{"missing.md"}})
+	if !errors.Is(err, mdocx.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
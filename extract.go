@@ -0,0 +1,270 @@
+package mdocx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// CollisionPolicy selects what Extract does when a target file already exists,
+// or when two parts of the document map to the same target.
+type CollisionPolicy int
+
+const (
+	// CollisionError fails before anything is written (the default).
+	CollisionError CollisionPolicy = iota
+	// CollisionOverwrite replaces the existing file. A symbolic link is
+	// replaced, not followed.
+	CollisionOverwrite
+	// CollisionRename writes to the first free name with a numeric suffix
+	// before the extension ("logo.png" becomes "logo-1.png").
+	CollisionRename
+	// CollisionSkip leaves the existing file and does not extract the part.
+	CollisionSkip
+)
+
+// ExtractAction describes what Extract does with one part of a document.
+type ExtractAction string
+
+// Extract actions.
+const (
+	ExtractCreate    ExtractAction = "create"
+	ExtractOverwrite ExtractAction = "overwrite"
+	ExtractRename    ExtractAction = "rename"
+	ExtractSkip      ExtractAction = "skip"
+)
+
+// ExtractOp is one planned or performed file operation of Extract.
+type ExtractOp struct {
+	// Source is the container path of the part: the Markdown file path, the
+	// media item Path, or "media/<ID>" for media items without a Path. It is
+	// "" for the metadata file (see WithMetadataFile).
+	Source string
+	// MediaID is the media item ID, or "" for Markdown files.
+	MediaID string
+	// Target is the file path the part is (or would be) written to, relative
+	// to the extraction directory and using forward slashes. For
+	// ExtractRename it is the renamed path; for ExtractSkip it is the path
+	// that was left untouched.
+	Target string
+	// Action is what happens to Target.
+	Action ExtractAction
+	// Size is the number of bytes written, or that would be written.
+	Size int64
+}
+
+// extractConfig holds configuration options for Extract.
+type extractConfig struct {
+	policy       CollisionPolicy
+	dryRun       bool
+	metadataFile string
+}
+
+// ExtractOption is a functional option for configuring Extract.
+type ExtractOption func(*extractConfig)
+
+// WithCollisionPolicy sets how Extract handles existing target files.
+// The default is CollisionError.
+func WithCollisionPolicy(p CollisionPolicy) ExtractOption {
+	return func(c *extractConfig) { c.policy = p }
+}
+
+// WithDryRun makes Extract plan its file operations and return them without
+// touching the target directory.
+func WithDryRun(v bool) ExtractOption {
+	return func(c *extractConfig) { c.dryRun = v }
+}
+
+// WithMetadataFile makes Extract also write the document's metadata, as
+// indented JSON, to name below the extraction directory. The file is planned
+// with the others and subject to the same CollisionPolicy. Nothing is written
+// for a document without metadata.
+func WithMetadataFile(name string) ExtractOption {
+	return func(c *extractConfig) { c.metadataFile = name }
+}
+
+// Extract writes the Markdown files and media items of doc below dir.
+// Markdown files are written at their container paths and media items at their
+// Path, or media/<ID> when Path is empty. dir is created if needed.
+//
+// All operations are planned before anything is written, so a collision under
+// CollisionError fails with an error wrapping fs.ErrExist and leaves dir
+// untouched. So does a part whose path would need a directory where another
+// part or an existing file is, under any policy, and one that would overwrite
+// a directory. Extract returns the operations in document order: Markdown files
+// first, then media items, then the metadata file. With WithDryRun(true) it
+// only returns the plan.
+//
+// The document is validated first; invalid documents are rejected with
+// ErrValidation.
+func Extract(doc *Document, dir string, opts ...ExtractOption) ([]ExtractOp, error) {
+	var cfg extractConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if err := validateDocument(doc, noLimits(), true); err != nil {
+		return nil, err
+	}
+
+	ops := make([]ExtractOp, 0, len(doc.Markdown.Files)+len(doc.Media.Items))
+	data := make([][]byte, 0, cap(ops))
+	planned := make(map[string]struct{}, cap(ops))
+	add := func(source, id string, b []byte) error {
+		op, err := planExtract(dir, source, cfg.policy, planned)
+		if err != nil {
+			return err
+		}
+		op.MediaID, op.Size = id, int64(len(b))
+		ops = append(ops, op)
+		data = append(data, b)
+		return nil
+	}
+	for _, f := range doc.Markdown.Files {
+		if err := add(f.Path, "", f.Content); err != nil {
+			return nil, err
+		}
+	}
+	for _, it := range doc.Media.Items {
+		source := it.Path
+		if source == "" {
+			source = "media/" + it.ID
+			if err := validateContainerPath(source); err != nil {
+				return nil, fmt.Errorf("%w: media item %q cannot be stored by ID: %v", ErrValidation, it.ID, err)
+			}
+		}
+		if err := add(source, it.ID, it.Data); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.metadataFile != "" && doc.Metadata != nil {
+		b, err := json.MarshalIndent(doc.Metadata, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("%w: metadata: %v", ErrValidation, err)
+		}
+		if err := add(cfg.metadataFile, "", b); err != nil {
+			return nil, err
+		}
+		ops[len(ops)-1].Source = ""
+	}
+	if cfg.dryRun {
+		return ops, nil
+	}
+
+	for i, op := range ops {
+		if op.Action == ExtractSkip {
+			continue
+		}
+		p := filepath.Join(dir, filepath.FromSlash(op.Target))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return ops[:i], err
+		}
+		// Remove an overwritten file rather than writing through it, which
+		// would follow a symbolic link out of dir.
+		if op.Action == ExtractOverwrite {
+			if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return ops[:i], err
+			}
+		}
+		if err := writeNewFile(p, data[i]); err != nil {
+			return ops[:i], err
+		}
+	}
+	return ops, nil
+}
+
+// writeNewFile writes data to the file p, which must not exist, not even as a
+// symbolic link.
+func writeNewFile(p string, data []byte) error {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// planExtract decides the target and action for the part at source, given the
+// targets already planned, and records the chosen target in planned. Planned
+// targets are recorded under their path, and their parent directories under
+// the directory path followed by a slash, so that a part cannot become both a
+// file and a directory of another part.
+func planExtract(dir, source string, policy CollisionPolicy, planned map[string]struct{}) (ExtractOp, error) {
+	op := ExtractOp{Source: source, Target: source, Action: ExtractCreate}
+	if !filepath.IsLocal(filepath.FromSlash(source)) {
+		return op, fmt.Errorf("%w: path %q cannot be extracted on this system", ErrValidation, source)
+	}
+	// Every parent of the target must be a directory, or not exist yet.
+	for d := path.Dir(source); d != "."; d = path.Dir(d) {
+		if _, ok := planned[d]; ok {
+			return op, &fs.PathError{Op: "extract", Path: source, Err: fs.ErrExist}
+		}
+		fi, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(d)))
+		if err == nil && !fi.IsDir() {
+			return op, &fs.PathError{Op: "extract", Path: source, Err: fs.ErrExist}
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return op, err
+		}
+	}
+	// stat reports whether p is taken, and whether it is a directory.
+	stat := func(p string) (exists, isDir bool, err error) {
+		if _, ok := planned[p]; ok {
+			return true, false, nil
+		}
+		if _, ok := planned[p+"/"]; ok {
+			return true, true, nil
+		}
+		fi, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(p)))
+		if err == nil {
+			return true, fi.IsDir(), nil
+		}
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	exists, isDir, err := stat(source)
+	if err != nil {
+		return op, err
+	}
+	if exists {
+		switch policy {
+		case CollisionOverwrite:
+			if _, dup := planned[source]; dup || isDir {
+				// Two parts of the same document cannot both be kept, and a
+				// directory cannot be replaced by a file.
+				return op, &fs.PathError{Op: "extract", Path: source, Err: fs.ErrExist}
+			}
+			op.Action = ExtractOverwrite
+		case CollisionRename:
+			ext := path.Ext(source)
+			base := strings.TrimSuffix(source, ext)
+			for n := 1; exists; n++ {
+				op.Target = base + "-" + strconv.Itoa(n) + ext
+				if exists, _, err = stat(op.Target); err != nil {
+					return op, err
+				}
+			}
+			op.Action = ExtractRename
+		case CollisionSkip:
+			op.Action = ExtractSkip
+			return op, nil
+		default:
+			return op, &fs.PathError{Op: "extract", Path: source, Err: fs.ErrExist}
+		}
+	}
+	planned[op.Target] = struct{}{}
+	for d := path.Dir(op.Target); d != "."; d = path.Dir(d) {
+		planned[d+"/"] = struct{}{}
+	}
+	return op, nil
+}
@@ -0,0 +1,189 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+	doc := sampleDoc()
+	ops, err := Extract(doc, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"docs/index.md", "docs/notes.md", "assets/logo.png"}
+	if len(ops) != len(want) {
+		t.Fatalf("got %d ops", len(ops))
+	}
+	for i, op := range ops {
+		if op.Target != want[i] || op.Action != ExtractCreate {
+			t.Fatalf("op %d: %+v", i, op)
+		}
+	}
+	if ops[2].MediaID != "logo" || ops[2].Size != 3 {
+		t.Fatalf("media op: %+v", ops[2])
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "assets", "logo.png"))
+	if err != nil || !reflect.DeepEqual(got, doc.Media.Items[0].Data) {
+		t.Fatalf("logo = %v, %v", got, err)
+	}
+
+	// A second extraction collides with every file.
+	if _, err := Extract(doc, dir); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected fs.ErrExist, got %v", err)
+	}
+	ops, err = Extract(doc, dir, WithCollisionPolicy(CollisionRename), WithDryRun(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops[0].Action != ExtractRename || ops[0].Target != "docs/index-1.md" {
+		t.Fatalf("rename op: %+v", ops[0])
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docs", "index-1.md")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal("dry run wrote files")
+	}
+	if _, err := Extract(doc, dir, WithCollisionPolicy(CollisionRename)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "assets", "logo-1.png")); err != nil {
+		t.Fatal(err)
+	}
+
+	ops, err = Extract(doc, dir, WithCollisionPolicy(CollisionSkip))
+	if err != nil || ops[1].Action != ExtractSkip {
+		t.Fatalf("skip: %+v, %v", ops, err)
+	}
+
+	doc.Markdown.Files[0].Content = []byte("# Replaced\n")
+	if _, err := Extract(doc, dir, WithCollisionPolicy(CollisionOverwrite)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "docs", "index.md")); string(got) != "# Replaced\n" {
+		t.Fatalf("not overwritten: %q", got)
+	}
+}
+
+func TestExtractInternalCollision(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].Path = "docs/notes.md"
+	if _, err := Extract(doc, t.TempDir(), WithCollisionPolicy(CollisionOverwrite)); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected fs.ErrExist, got %v", err)
+	}
+	ops, err := Extract(doc, t.TempDir(), WithCollisionPolicy(CollisionRename))
+	if err != nil || ops[2].Target != "docs/notes-1.md" {
+		t.Fatalf("rename: %+v, %v", ops, err)
+	}
+}
+
+func TestExtractFileDirectoryCollision(t *testing.T) {
+	empty := func(t *testing.T, dir string) {
+		t.Helper()
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Fatalf("Extract wrote %d entries before failing", len(entries))
+		}
+	}
+	for name, paths := range map[string][2]string{
+		"file first":      {"a", "a/b.md"},
+		"directory first": {"a/b.md", "a"},
+	} {
+		doc := sampleDoc()
+		doc.Markdown.Files[0].Path, doc.Markdown.Files[1].Path = paths[0], paths[1]
+		doc.Markdown.RootPath = ""
+		for _, p := range []CollisionPolicy{CollisionError, CollisionOverwrite} {
+			dir := t.TempDir()
+			if _, err := Extract(doc, dir, WithCollisionPolicy(p)); !errors.Is(err, fs.ErrExist) {
+				t.Fatalf("%s, policy %d: expected fs.ErrExist, got %v", name, p, err)
+			}
+			empty(t, dir)
+		}
+	}
+
+	// A file in the tree where the part needs a directory.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "assets"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Extract(sampleDoc(), dir, WithCollisionPolicy(CollisionOverwrite)); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("existing file: expected fs.ErrExist, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "docs")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Extract wrote docs before failing: %v", err)
+	}
+	// A directory in the tree where the part is a file can be renamed around.
+	dir = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs", "notes.md"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Extract(sampleDoc(), dir, WithCollisionPolicy(CollisionOverwrite)); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("existing directory: expected fs.ErrExist, got %v", err)
+	}
+	ops, err := Extract(sampleDoc(), dir, WithCollisionPolicy(CollisionRename))
+	if err != nil || ops[1].Target != "docs/notes-1.md" {
+		t.Fatalf("rename: %+v, %v", ops, err)
+	}
+}
+
+func TestExtractMetadataFile(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].Path = "metadata.json"
+
+	dir := t.TempDir()
+	if _, err := Extract(doc, dir, WithMetadataFile("metadata.json")); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected fs.ErrExist, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Extract wrote %d entries before failing", len(entries))
+	}
+
+	ops, err := Extract(doc, dir, WithMetadataFile("metadata.json"), WithCollisionPolicy(CollisionRename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := ops[len(ops)-1]
+	if last.Source != "" || last.Target != "metadata-1.json" {
+		t.Fatalf("metadata op = %+v", last)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "metadata-1.json"))
+	if err != nil || !strings.Contains(string(b), `"title"`) {
+		t.Fatalf("metadata file: %q, %v", b, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "metadata.json")); !bytes.Equal(b, doc.Media.Items[0].Data) {
+		t.Fatalf("metadata overwrote the media item: %q", b)
+	}
+}
+
+func TestExtractOverwriteSymlink(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.WriteFile(outside, []byte("outside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "docs", "notes.md")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skip("symbolic links not supported:", err)
+	}
+	doc := sampleDoc()
+	if _, err := Extract(doc, dir, WithCollisionPolicy(CollisionOverwrite)); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(outside); string(b) != "outside" {
+		t.Fatalf("Extract wrote through the link: %q", b)
+	}
+	fi, err := os.Lstat(link)
+	if err != nil || !fi.Mode().IsRegular() {
+		t.Fatalf("docs/notes.md: %v, %v", fi, err)
+	}
+	if b, _ := os.ReadFile(link); !bytes.Equal(b, doc.Markdown.Files[1].Content) {
+		t.Fatalf("docs/notes.md = %q", b)
+	}
+}
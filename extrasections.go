@@ -0,0 +1,53 @@
+package mdocx
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ExtraSection is an application-defined section of a version 2 container,
+// such as a render cache or a search index. Readers that do not know its type
+// skip it.
+type ExtraSection struct {
+	// Type identifies the section. It must be SectionPrivateUse or above.
+	Type uint16
+	// Payload is stored as it is, without compression.
+	Payload []byte
+}
+
+// WithExtraSection makes Encode write a section of the given type holding
+// payload, after the Markdown and Media sections and any Document.ExtraSections.
+// The type must be in the private use range starting at SectionPrivateUse;
+// meaning is up to the application, so applications sharing containers should
+// agree on their types. Extra sections need a version 2 container, which Encode
+// writes unless WithFormatVersion(VersionV1) is given, in which case it
+// returns ErrValidation. Like WithFormatVersion(VersionV2), extra sections
+// cannot be combined with a footer index.
+func WithExtraSection(typ uint16, payload []byte) WriteOption {
+	return func(c *writeConfig) {
+		c.extraSections = append(c.extraSections, ExtraSection{Type: typ, Payload: payload})
+	}
+}
+
+// extrasFor returns the extra sections Encode writes for doc, checked
+// against limits.
+func (c writeConfig) extrasFor(doc *Document) ([]ExtraSection, error) {
+	extras := append(slices.Clip(doc.ExtraSections), c.extraSections...)
+	if len(extras) > maxSectionsV2-2 {
+		return nil, fmt.Errorf("%w: %d extra sections, more than %d", ErrValidation, len(extras), maxSectionsV2-2)
+	}
+	for _, s := range extras {
+		if SectionType(s.Type) < SectionPrivateUse {
+			return nil, fmt.Errorf("%w: extra section type %#x is not in the private use range", ErrValidation, s.Type)
+		}
+		if uint64(len(s.Payload)) > c.limits.MaxMediaSectionLen {
+			return nil, exceeds("MaxMediaSectionLen", "extra section %#x too large", s.Type)
+		}
+	}
+	return extras, nil
+}
+
+// sectionHeader returns the section table entry of s.
+func (s ExtraSection) sectionHeader() sectionHeaderV1 {
+	return sectionHeaderV1{SectionType: s.Type, PayloadLen: uint64(len(s.Payload))}
+}
@@ -0,0 +1,86 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestExtraSections(t *testing.T) {
+	doc := sampleDoc()
+	doc.ExtraSections = []ExtraSection{{Type: 0xF001, Payload: []byte("render cache")}}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithExtraSection(0xFFFF, []byte("search index")), WithChecksum(true)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if h, err := DecodeHeader(bytes.NewReader(data)); err != nil || h.Version != VersionV2 {
+		t.Fatalf("DecodeHeader = %+v, %v", h, err)
+	}
+
+	want := []ExtraSection{{Type: 0xF001, Payload: []byte("render cache")}, {Type: 0xFFFF, Payload: []byte("search index")}}
+	got, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.ExtraSections, want) || !reflect.DeepEqual(got.Media, doc.Media) {
+		t.Fatalf("ExtraSections = %+v", got.ExtraSections)
+	}
+
+	// The decoded document encodes to the same container.
+	var again bytes.Buffer
+	if err := Encode(&again, got, WithChecksum(true)); err != nil || !bytes.Equal(again.Bytes(), data) {
+		t.Fatalf("re-encoded container differs: %v", err)
+	}
+
+	// Other unknown sections are skipped rather than exposed.
+	extended := insertSection(t, data, 1, sectionHeaderV1{SectionType: 0x100}, []byte("future"))
+	extended = extended[:len(extended)-checksumTrailerSize]
+	extended[10] &^= byte(HeaderFlagChecksum)
+	if got, err := Decode(bytes.NewReader(extended)); err != nil || !reflect.DeepEqual(got.ExtraSections, want) {
+		t.Fatalf("with unknown section: %+v, %v", got, err)
+	}
+
+	// Version 1 containers have none.
+	var v1 bytes.Buffer
+	if err := Encode(&v1, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Decode(&v1); err != nil || got.ExtraSections != nil {
+		t.Fatalf("version 1: %+v, %v", got, err)
+	}
+}
+
+func TestExtraSectionsRejects(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts []WriteOption
+		want error
+	}{
+		"reserved type": {[]WriteOption{WithExtraSection(uint16(SectionIndex), nil)}, ErrValidation},
+		"version 1":     {[]WriteOption{WithExtraSection(0xF000, nil), WithFormatVersion(VersionV1)}, ErrValidation},
+		"index":         {[]WriteOption{WithExtraSection(0xF000, nil), WithIndex(true)}, ErrValidation},
+		"too large":     {[]WriteOption{WithExtraSection(0xF000, make([]byte, 10)), WithWriteLimits(Limits{MaxMediaSectionLen: 4})}, ErrLimitExceeded},
+	} {
+		if err := Encode(io.Discard, sampleDoc(), tc.opts...); !errors.Is(err, tc.want) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	w, err := NewWriter(io.Discard, StreamHeader{}, WithExtraSection(0xF000, nil))
+	if err == nil {
+		w.Close()
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("NewWriter: %v", err)
+	}
+
+	// Decode holds extra sections to the section length limit.
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithExtraSection(0xF000, make([]byte, 100))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(&buf, WithReadLimits(Limits{MaxMediaSectionLen: 99})); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Decode: %v", err)
+	}
+}
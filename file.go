@@ -0,0 +1,192 @@
+package mdocx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// EncodeFile encodes doc to the file at path, replacing it atomically: the
+// container is written to a temporary file in the same directory, synced, and
+// renamed over path, so readers never see a partial file and a failed write
+// leaves the old file in place. A replaced file keeps its permissions; a new
+// file gets 0644.
+//
+// EncodeFile holds the lock of path (see LockFile) while it writes, and returns
+// an error wrapping ErrLocked if someone else holds it. A caller that already
+// holds the lock passes it with WithLock. With WithBackup, the file being
+// replaced is kept as a backup.
+func EncodeFile(path string, doc *Document, opts ...WriteOption) (err error) {
+	cfg := newWriteConfig(opts)
+	if cfg.lock != nil {
+		if !cfg.lock.covers(path) {
+			return fmt.Errorf("%w: lock for %s is not held", ErrLocked, path)
+		}
+	} else {
+		l, err := LockFile(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if uerr := l.Unlock(); err == nil {
+				err = uerr
+			}
+		}()
+	}
+
+	perm := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if err := Encode(tmp, doc, opts...); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if cfg.backups > 0 {
+		if err := rotateBackups(path, cfg.backupDir, cfg.backups); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DecodeFile decodes the container in the file at path. It accepts the same
+// ReadOption values as Decode. Because the file can be read at any offset, a
+// container with an integrity trailer (see WithChecksum) does not have to be
+// held in memory while the trailer is checked, as Decode does: DecodeFile
+// checks it in a first pass over the file, then decodes the sections as it
+// reads them, as with WithStreamingInput(true). Damage is therefore still
+// reported as ErrCorrupted before any section is decoded.
+func DecodeFile(path string, opts ...ReadOption) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFileChecksum(f, fi.Size()); err != nil {
+		if cfg := newReadConfig(opts); cfg.onReject != nil {
+			cfg.onReject(newRejection(err, "checksum"))
+		}
+		return nil, err
+	}
+	r := bufio.NewReaderSize(io.NewSectionReader(f, 0, fi.Size()), 64<<10)
+	return Decode(r, append(slices.Clip(opts), WithStreamingInput(true))...)
+}
+
+// checkFileChecksum checks the integrity trailer of the container of the given
+// size in ra, if it has one. A container whose fixed header cannot be read is
+// left for Decode to reject.
+func checkFileChecksum(ra io.ReaderAt, size int64) error {
+	h, err := readFixedHeader(io.NewSectionReader(ra, 0, size))
+	if err != nil || h.HeaderFlags&HeaderFlagChecksum == 0 {
+		return nil
+	}
+	if size < int64(fixedHeaderSizeV1)+checksumTrailerSize {
+		return truncated(io.ErrUnexpectedEOF)
+	}
+	end := size - checksumTrailerSize
+	crc := crc32.New(castagnoli)
+	if _, err := io.Copy(crc, io.NewSectionReader(ra, 0, end)); err != nil {
+		return err
+	}
+	return checkTrailer(io.NewSectionReader(ra, end, checksumTrailerSize), crc.Sum32())
+}
+
+// WithBackup makes EncodeFile keep the last n versions of the file it
+// replaces, as path.1 (the newest) through path.n. Older backups are removed.
+// Zero, the default, keeps none.
+func WithBackup(n int) WriteOption {
+	return func(c *writeConfig) { c.backups = n }
+}
+
+// WithBackupDir makes EncodeFile write the backups kept by WithBackup to dir
+// instead of the directory of the file. The directory must exist.
+func WithBackupDir(dir string) WriteOption {
+	return func(c *writeConfig) { c.backupDir = dir }
+}
+
+// rotateBackups shifts the backups of path in dir (default: the directory of
+// path) up by one, dropping the nth, and saves the current file as backup 1.
+// It does nothing if path does not exist.
+func rotateBackups(path, dir string, n int) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if dir == "" {
+		dir = filepath.Dir(path)
+	}
+	name := func(i int) string {
+		return filepath.Join(dir, filepath.Base(path)+"."+strconv.Itoa(i))
+	}
+	if err := os.Remove(name(n)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := n - 1; i >= 1; i-- {
+		if err := os.Rename(name(i), name(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	// The current file stays in place until it is replaced, so it is linked
+	// or copied rather than moved.
+	if err := os.Link(path, name(1)); err == nil {
+		return nil
+	}
+	return copyFile(path, name(1))
+}
+
+// copyFile copies the file src to dst, which must not exist, keeping its
+// permissions.
+func copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return err
+}
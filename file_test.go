@@ -0,0 +1,158 @@
+package mdocx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncodeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.mdocx")
+	if err := EncodeFile(path, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := sampleDoc()
+	doc.Metadata["title"] = "Edited"
+	if err := EncodeFile(path, doc); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	if err := EncodeFile(path, doc, WithLock(l)); err != nil {
+		t.Fatal(err)
+	}
+	if err := EncodeFile(filepath.Join(dir, "other.mdocx"), doc, WithLock(l)); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked for another path, got %v", err)
+	}
+	l.Unlock()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata["title"] != "Edited" {
+		t.Fatalf("title = %v", got.Metadata["title"])
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("mode = %v, %v", fi.Mode(), err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("leftover files: %v", entries)
+	}
+
+	// A failed encode leaves the old file and no temporary file.
+	if err := EncodeFile(path, &Document{}); err == nil {
+		t.Fatal("expected error for invalid document")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("leftover files after failure: %v", entries)
+	}
+}
+
+func TestEncodeFileBackup(t *testing.T) {
+	for _, sep := range []bool{false, true} {
+		dir := t.TempDir()
+		backupDir := dir
+		var opts []WriteOption
+		if sep {
+			backupDir = t.TempDir()
+			opts = append(opts, WithBackupDir(backupDir))
+		}
+		opts = append(opts, WithBackup(2))
+		path := filepath.Join(dir, "doc.mdocx")
+		for _, title := range []string{"v1", "v2", "v3", "v4"} {
+			doc := sampleDoc()
+			doc.Metadata["title"] = title
+			if err := EncodeFile(path, doc, opts...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for file, want := range map[string]string{
+			path:                                    "v4",
+			filepath.Join(backupDir, "doc.mdocx.1"): "v3",
+			filepath.Join(backupDir, "doc.mdocx.2"): "v2",
+		} {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := Decode(f)
+			f.Close()
+			if err != nil || doc.Metadata["title"] != want {
+				t.Fatalf("%s: title = %v, %v; want %s", file, doc.Metadata["title"], err, want)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(backupDir, "doc.mdocx.3")); !os.IsNotExist(err) {
+			t.Fatalf("third backup kept: %v", err)
+		}
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := copyFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(dst); err != nil || string(b) != "data" {
+		t.Fatalf("copy = %q, %v", b, err)
+	}
+	if err := copyFile(src, dst); err == nil {
+		t.Fatal("expected error for existing destination")
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.mdocx")
+	if err := EncodeFile(path, sampleDoc(), WithChecksum(true)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata["title"] != "Example" || len(got.Markdown.Files) != 2 {
+		t.Fatalf("decoded %+v", got)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)/2] ^= 0xFF
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var rejected string
+	_, err = DecodeFile(path, WithRejectHook(func(r Rejection) { rejected = r.Section }))
+	if !errors.Is(err, ErrCorrupted) || rejected != "checksum" {
+		t.Fatalf("damaged file: %v (rejected in %q)", err, rejected)
+	}
+	if err := os.WriteFile(path, b[:len(b)-4], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeFile(path); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("truncated file: %v", err)
+	}
+	if _, err := DecodeFile(filepath.Join(t.TempDir(), "missing.mdocx")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file: %v", err)
+	}
+}
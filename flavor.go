@@ -0,0 +1,61 @@
+package mdocx
+
+import "github.com/logicossoftware/go-mdocx/internal/mdscan"
+
+// Flavor names the Markdown dialect a file is written in, so that tools apply
+// the right parser extensions instead of guessing.
+type Flavor string
+
+// Well-known flavors. Other values may be recorded but are treated as
+// unspecified by this package.
+const (
+	// FlavorCommonMark is plain CommonMark, without tables, footnotes, or heading IDs.
+	FlavorCommonMark Flavor = "commonmark"
+	// FlavorGFM is GitHub Flavored Markdown: CommonMark with pipe tables
+	// and footnotes.
+	FlavorGFM Flavor = "gfm"
+	// FlavorMyST is MyST Markdown, which also has pipe tables and
+	// footnotes.
+	FlavorMyST Flavor = "myst"
+	// FlavorPandoc is Pandoc Markdown, with pipe tables, footnotes,
+	// and {#id} heading IDs.
+	FlavorPandoc Flavor = "pandoc"
+)
+
+// FlavorAttr is the attribute key under which a Markdown file records its
+// flavor, overriding the document default.
+const FlavorAttr = "flavor"
+
+// FlavorMetadataKey is the metadata key holding the default flavor of the
+// Markdown bundle.
+const FlavorMetadataKey = "flavor"
+
+// FileFlavor returns the flavor of f: its FlavorAttr attribute, else the
+// document default under FlavorMetadataKey, else "" (unspecified). Renderers,
+// exporters, and link checks in this module recognize every extension when the
+// flavor is unspecified or unknown.
+func (d *Document) FileFlavor(f MarkdownFile) Flavor {
+	if fl := f.Attributes[FlavorAttr]; fl != "" {
+		return Flavor(fl)
+	}
+	fl, _ := d.Metadata[FlavorMetadataKey].(string)
+	return Flavor(fl)
+}
+
+// SetFlavor records fl as the document's default flavor. An empty fl removes
+// the default.
+func (d *Document) SetFlavor(fl Flavor) {
+	if fl == "" {
+		delete(d.Metadata, FlavorMetadataKey)
+		return
+	}
+	if d.Metadata == nil {
+		d.Metadata = map[string]any{}
+	}
+	d.Metadata[FlavorMetadataKey] = string(fl)
+}
+
+// scanFile scans the content of f with the extensions of its flavor.
+func (d *Document) scanFile(f MarkdownFile) *mdscan.Result {
+	return mdscan.ScanExt(f.Content, mdscan.FlavorExtensions(string(d.FileFlavor(f))))
+}
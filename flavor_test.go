@@ -0,0 +1,32 @@
+package mdocx
+
+import "testing"
+
+func TestFileFlavor(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[0].Content = []byte("# Intro {#top}\n\nSee [top](#top).\n")
+	if fl := doc.FileFlavor(doc.Markdown.Files[0]); fl != "" {
+		t.Fatalf("unexpected default flavor %q", fl)
+	}
+	if broken := CheckLinks(doc); len(broken) != 0 {
+		t.Fatalf("unexpected broken links: %+v", broken)
+	}
+
+	doc.SetFlavor(FlavorCommonMark)
+	doc.Markdown.Files[1].Attributes = map[string]string{FlavorAttr: string(FlavorPandoc)}
+	if fl := doc.FileFlavor(doc.Markdown.Files[0]); fl != FlavorCommonMark {
+		t.Fatalf("flavor = %q", fl)
+	}
+	if fl := doc.FileFlavor(doc.Markdown.Files[1]); fl != FlavorPandoc {
+		t.Fatalf("attribute flavor = %q", fl)
+	}
+	// CommonMark has no {#id} heading IDs.
+	if broken := CheckLinks(doc); len(broken) != 1 || broken[0].Dest != "#top" {
+		t.Fatalf("expected #top to be broken, got %+v", broken)
+	}
+
+	doc.SetFlavor("")
+	if _, ok := doc.Metadata[FlavorMetadataKey]; ok {
+		t.Fatal("SetFlavor(\"\") kept the default")
+	}
+}
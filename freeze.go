@@ -0,0 +1,163 @@
+package mdocx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+)
+
+// FrozenDocument is a read-only snapshot of a Document, made by
+// Document.Freeze. Its accessors return copies or readers, never memory that
+// the snapshot holds, so a FrozenDocument can be shared between goroutines, or
+// handed to code that must not change it, without locking.
+type FrozenDocument struct {
+	doc  *Document
+	byID map[string]int
+}
+
+// Freeze returns a read-only snapshot of d. The snapshot holds its own copy of
+// d's contents: later changes to d do not affect it, and it cannot be used to
+// change d. Metadata values other than the maps and slices of the JSON data
+// model are not copied and must not be modified.
+func (d *Document) Freeze() *FrozenDocument {
+	f := &FrozenDocument{doc: copyDocument(d), byID: make(map[string]int, len(d.Media.Items))}
+	for i, it := range f.doc.Media.Items {
+		if _, dup := f.byID[it.ID]; !dup {
+			f.byID[it.ID] = i
+		}
+	}
+	return f
+}
+
+// Document returns a copy of the snapshot that the caller may modify.
+func (f *FrozenDocument) Document() *Document {
+	return copyDocument(f.doc)
+}
+
+// Metadata returns a copy of the document metadata, or nil if it has none.
+func (f *FrozenDocument) Metadata() map[string]any {
+	if f.doc.Metadata == nil {
+		return nil
+	}
+	return copyJSONValue(f.doc.Metadata).(map[string]any)
+}
+
+// RootPath returns the path of the primary Markdown file, if set.
+func (f *FrozenDocument) RootPath() string { return f.doc.Markdown.RootPath }
+
+// NoMedia reports whether the document has no media bundle at all (see
+// Document.NoMedia).
+func (f *FrozenDocument) NoMedia() bool { return f.doc.NoMedia }
+
+// Files returns copies of the Markdown files in bundle order.
+func (f *FrozenDocument) Files() []MarkdownFile {
+	files := make([]MarkdownFile, len(f.doc.Markdown.Files))
+	for i, mf := range f.doc.Markdown.Files {
+		files[i] = copyMarkdownFile(mf)
+	}
+	return files
+}
+
+// File returns a copy of the Markdown file with the given container path, or
+// an error wrapping ErrNotFound.
+func (f *FrozenDocument) File(path string) (MarkdownFile, error) {
+	for _, mf := range f.doc.Markdown.Files {
+		if mf.Path == path {
+			return copyMarkdownFile(mf), nil
+		}
+	}
+	return MarkdownFile{}, fmt.Errorf("%w: markdown file %q", ErrNotFound, path)
+}
+
+// Media lists the media items in bundle order without their data.
+func (f *FrozenDocument) Media() []MediaInfo {
+	out := make([]MediaInfo, len(f.doc.Media.Items))
+	for i, it := range f.doc.Media.Items {
+		out[i] = MediaInfo{
+			ID:         it.ID,
+			Path:       it.Path,
+			MIMEType:   it.MIMEType,
+			Size:       int64(len(it.Data)),
+			SHA256:     it.SHA256,
+			Attributes: maps.Clone(it.Attributes),
+		}
+	}
+	return out
+}
+
+// MediaItem returns a copy, data included, of the media item with the given
+// ID, or an error wrapping ErrNotFound. OpenMedia avoids copying the data.
+func (f *FrozenDocument) MediaItem(id string) (MediaItem, error) {
+	i, ok := f.byID[id]
+	if !ok {
+		return MediaItem{}, fmt.Errorf("%w: media item %q", ErrNotFound, id)
+	}
+	it := f.doc.Media.Items[i]
+	it.Data = bytes.Clone(it.Data)
+	it.Attributes = maps.Clone(it.Attributes)
+	return it, nil
+}
+
+// OpenMedia returns a reader over the data of the media item with the given
+// ID, or an error wrapping ErrNotFound.
+func (f *FrozenDocument) OpenMedia(id string) (io.ReadCloser, error) {
+	i, ok := f.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: media item %q", ErrNotFound, id)
+	}
+	return io.NopCloser(bytes.NewReader(f.doc.Media.Items[i].Data)), nil
+}
+
+// FS returns a read-only file system over the snapshot, as Document.FS does.
+func (f *FrozenDocument) FS() fs.FS {
+	return f.doc.FS()
+}
+
+// copyDocument returns a copy of doc that shares no memory with it, except
+// for metadata values outside the JSON data model.
+func copyDocument(doc *Document) *Document {
+	c := cloneDocument(doc)
+	if doc.Metadata != nil {
+		c.Metadata = copyJSONValue(doc.Metadata).(map[string]any)
+	}
+	for i := range c.Media.Items {
+		c.Media.Items[i].Data = bytes.Clone(c.Media.Items[i].Data)
+	}
+	return c
+}
+
+// copyMarkdownFile returns a copy of mf that shares no memory with it.
+func copyMarkdownFile(mf MarkdownFile) MarkdownFile {
+	mf.Content = bytes.Clone(mf.Content)
+	mf.MediaRefs = slices.Clone(mf.MediaRefs)
+	mf.Attributes = maps.Clone(mf.Attributes)
+	return mf
+}
+
+// copyJSONValue returns a deep copy of v's maps and slices.
+func copyJSONValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		c := make(map[string]any, len(v))
+		for k, x := range v {
+			c[k] = copyJSONValue(x)
+		}
+		return c
+	case []any:
+		if v == nil {
+			return v
+		}
+		c := make([]any, len(v))
+		for i, x := range v {
+			c[i] = copyJSONValue(x)
+		}
+		return c
+	}
+	return v
+}
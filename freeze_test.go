@@ -0,0 +1,83 @@
+package mdocx
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	doc := sampleDoc()
+	doc.Metadata["nested"] = map[string]any{"list": []any{"x"}}
+	doc.Media.Items[0].Attributes = map[string]string{"alt": "Logo"}
+	want := copyDocument(doc)
+	f := doc.Freeze()
+
+	// Changes to the document do not reach the snapshot.
+	doc.Markdown.Files[0].Content[0] = 'X'
+	doc.Media.Items[0].Data[0] = 0xff
+	doc.Media.Items[0].Attributes["alt"] = "changed"
+	doc.Metadata["nested"].(map[string]any)["list"].([]any)[0] = "y"
+	if got := f.Document(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("snapshot changed with the document: %+v", got)
+	}
+
+	// Nor do changes to what the accessors return.
+	f.Metadata()["nested"].(map[string]any)["list"].([]any)[0] = "z"
+	f.Files()[0].Content[0] = 'Y'
+	mf, err := f.File("docs/notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mf.Content[0] = 'Z'
+	it, err := f.MediaItem("logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	it.Data[0] = 0xee
+	it.Attributes["alt"] = "changed"
+	f.Media()[0].Attributes["alt"] = "changed"
+	got := f.Document()
+	got.Markdown.Files[0].Content[1] = 'W'
+	if got := f.Document(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("snapshot changed through an accessor: %+v", got)
+	}
+
+	if f.RootPath() != "docs/index.md" || f.NoMedia() {
+		t.Fatal("RootPath or NoMedia")
+	}
+	if _, err := f.File("missing.md"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("File: %v", err)
+	}
+	if _, err := f.MediaItem("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("MediaItem: %v", err)
+	}
+	if _, err := f.OpenMedia("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("OpenMedia: %v", err)
+	}
+	if b, err := fs.ReadFile(f.FS(), "assets/logo.png"); err != nil || !reflect.DeepEqual(b, want.Media.Items[0].Data) {
+		t.Fatalf("FS: %v %v", b, err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc, err := f.OpenMedia("logo")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if b, _ := io.ReadAll(rc); !reflect.DeepEqual(b, want.Media.Items[0].Data) {
+				t.Errorf("OpenMedia read %v", b)
+			}
+			_ = f.Files()
+			_ = f.Metadata()
+		}()
+	}
+	wg.Wait()
+}
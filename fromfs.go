@@ -0,0 +1,166 @@
+package mdocx
+
+import (
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fsConfig holds configuration options for FromFS.
+type fsConfig struct {
+	include []string
+	exclude []string
+}
+
+// FSOption is a functional option for configuring FromFS.
+type FSOption func(*fsConfig)
+
+// WithInclude makes FromFS take only the files matching at least one of the
+// glob patterns, with the syntax of Document.Glob, so "docs/**/*.md" selects
+// the Markdown files at any depth below docs. A pattern without a slash is
+// also matched against the file's base name, so "*.png" selects PNG files at
+// any depth.
+// Repeated calls add patterns.
+func WithInclude(patterns ...string) FSOption {
+	return func(c *fsConfig) { c.include = append(c.include, patterns...) }
+}
+
+// WithExclude makes FromFS skip the files and directories matching any of the
+// glob patterns, with the same matching rules as WithInclude. Exclusion takes
+// precedence over inclusion. Repeated calls add patterns.
+func WithExclude(patterns ...string) FSOption {
+	return func(c *fsConfig) { c.exclude = append(c.exclude, patterns...) }
+}
+
+// FromFS builds a Document from the files of fsys. Files with a ".md" or
+// ".markdown" extension (in any case) become Markdown files; all other files
+// become media items with a MIME type detected from the extension or, failing
+// that, the content, a computed SHA256, and an ID derived from the path. Files
+// keep their fsys path as container path and are added in lexical order.
+//
+// The document has no metadata or RootPath; it is not validated, so an fsys
+// without Markdown files yields a document that Encode rejects.
+func FromFS(fsys fs.FS, opts ...FSOption) (*Document, error) {
+	var cfg fsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	for _, p := range slices.Concat(cfg.include, cfg.exclude) {
+		if err := checkGlob(p); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+	}
+
+	doc := &Document{
+		Markdown: MarkdownBundle{BundleVersion: VersionV1},
+		Media:    MediaBundle{BundleVersion: VersionV1},
+	}
+	ids := make(map[string]struct{})
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		if matchesAny(cfg.exclude, p) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || (len(cfg.include) > 0 && !matchesAny(cfg.include, p)) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(path.Ext(p)) {
+		case ".md", ".markdown":
+			doc.Markdown.Files = append(doc.Markdown.Files, MarkdownFile{Path: p, Content: data})
+		default:
+			it := MediaItem{
+				ID:       uniqueMediaID(mediaIDFromPath(p), ids),
+				Path:     p,
+				MIMEType: detectMIMEType(p, data),
+				Data:     data,
+			}
+			it.SHA256 = it.computedSHA256()
+			doc.Media.Items = append(doc.Media.Items, it)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// matchesAny reports whether p matches one of the patterns, as described by
+// WithInclude.
+func matchesAny(patterns []string, p string) bool {
+	for _, pat := range patterns {
+		if matchGlob(pat, p) {
+			return true
+		}
+		if !strings.Contains(pat, "/") {
+			if ok, _ := path.Match(pat, path.Base(p)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// detectMIMEType returns the MIME type of the file p holding data, by extension
+// or by content sniffing.
+func detectMIMEType(p string, data []byte) string {
+	if mt := mime.TypeByExtension(path.Ext(p)); mt != "" {
+		return mt
+	}
+	return http.DetectContentType(data)
+}
+
+// mediaIDFromPath derives a media ID from a container path: the lowercased
+// path with each run of characters other than letters and digits replaced by
+// an underscore.
+func mediaIDFromPath(p string) string {
+	var b strings.Builder
+	b.Grow(len(p))
+	sep := false
+	for _, r := range strings.ToLower(p) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if sep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			sep = false
+			b.WriteRune(r)
+		} else {
+			sep = true
+		}
+	}
+	if b.Len() == 0 {
+		return "media"
+	}
+	return b.String()
+}
+
+// uniqueMediaID returns id, or id with the first free numeric suffix if it is
+// already in seen, and adds the result to seen.
+func uniqueMediaID(id string, seen map[string]struct{}) string {
+	out := id
+	for n := 2; ; n++ {
+		if _, dup := seen[out]; !dup {
+			break
+		}
+		out = id + "_" + strconv.Itoa(n)
+	}
+	seen[out] = struct{}{}
+	return out
+}
@@ -0,0 +1,70 @@
+package mdocx
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.md":            {Data: []byte("# Home\n")},
+		"guide/Intro.MD":      {Data: []byte("# Intro\n")},
+		"assets/logo.png":     {Data: []byte("\x89PNG\r\n\x1a\n")},
+		"assets/logo-png":     {Data: []byte("plain text")},
+		"assets/raw/data.bin": {Data: []byte{0, 1, 2}},
+		"drafts/wip.md":       {Data: []byte("# WIP\n")},
+	}
+	doc, err := FromFS(fsys, WithExclude("drafts", "*.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range doc.Markdown.Files {
+		paths = append(paths, f.Path)
+	}
+	if len(paths) != 2 || paths[0] != "guide/Intro.MD" || paths[1] != "index.md" {
+		t.Fatalf("markdown files = %v", paths)
+	}
+	if len(doc.Media.Items) != 2 {
+		t.Fatalf("media items = %+v", doc.Media.Items)
+	}
+	byPath := make(map[string]MediaItem)
+	for _, it := range doc.Media.Items {
+		byPath[it.Path] = it
+	}
+	png, txt := byPath["assets/logo.png"], byPath["assets/logo-png"]
+	if png.MIMEType != "image/png" || png.SHA256 != png.computedSHA256() {
+		t.Fatalf("unexpected PNG item: %+v", png)
+	}
+	if txt.MIMEType != "text/plain; charset=utf-8" {
+		t.Fatalf("sniffed MIME type = %q", txt.MIMEType)
+	}
+	if png.ID == txt.ID || (png.ID != "assets_logo_png" && txt.ID != "assets_logo_png") {
+		t.Fatalf("IDs not unique: %q, %q", png.ID, txt.ID)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err = FromFS(fsys, WithInclude("assets/*"), WithInclude("index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Markdown.Files) != 1 || len(doc.Media.Items) != 2 {
+		t.Fatalf("include selected %d files and %d items", len(doc.Markdown.Files), len(doc.Media.Items))
+	}
+	// "**" matches any number of directories, as in Document.Glob.
+	doc, err = FromFS(fsys, WithInclude("**/*.bin", "guide/**"), WithExclude("assets/**/raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Markdown.Files) != 1 || doc.Markdown.Files[0].Path != "guide/Intro.MD" || len(doc.Media.Items) != 0 {
+		t.Fatalf("** patterns selected %+v and %d items", doc.Markdown.Files, len(doc.Media.Items))
+	}
+	if _, err := FromFS(fsys, WithInclude("[")); err == nil {
+		t.Fatal("expected bad pattern error")
+	}
+}
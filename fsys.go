@@ -0,0 +1,199 @@
+package mdocx
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FS returns a read-only file system holding the document's Markdown files and
+// the media items that have a Path, each at its container path, so that a
+// document can be passed to html/template, http.FS, or fs.WalkDir. Directories
+// are implied by the paths.
+//
+// The returned value implements fs.ReadDirFS, fs.StatFS, and fs.GlobFS. It is a
+// snapshot of the document's file list taken when FS is called, but file
+// contents share memory with the document. Markdown files take precedence
+// over media items: a path that is already taken by an earlier file, or that
+// needs an existing file to be a directory, is left out. Modification times are
+// zero.
+func (d *Document) FS() fs.FS {
+	fsys := &docFS{
+		files: make(map[string][]byte),
+		dirs:  map[string][]fs.DirEntry{".": nil},
+	}
+	for _, f := range d.Markdown.Files {
+		fsys.add(f.Path, f.Content)
+	}
+	for _, it := range d.Media.Items {
+		if it.Path != "" {
+			fsys.add(it.Path, it.Data)
+		}
+	}
+	for _, entries := range fsys.dirs {
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	}
+	return fsys
+}
+
+// docFS is the fs.FS returned by Document.FS.
+type docFS struct {
+	files map[string][]byte
+	// dirs maps each directory to its entries, sorted by name.
+	dirs map[string][]fs.DirEntry
+}
+
+// add adds the file p and its parent directories, unless p clashes with an
+// existing file or directory.
+func (fsys *docFS) add(p string, data []byte) {
+	if !fs.ValidPath(p) || p == "." {
+		return
+	}
+	if _, ok := fsys.files[p]; ok {
+		return
+	}
+	if _, ok := fsys.dirs[p]; ok {
+		return
+	}
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if _, ok := fsys.files[dir]; ok {
+			return
+		}
+	}
+	fsys.files[p] = data
+	child := fs.FileInfoToDirEntry(fileInfo{name: path.Base(p), size: int64(len(data))})
+	for dir := path.Dir(p); ; dir = path.Dir(dir) {
+		_, exists := fsys.dirs[dir]
+		fsys.dirs[dir] = append(fsys.dirs[dir], child)
+		if exists {
+			return
+		}
+		child = fs.FileInfoToDirEntry(fileInfo{name: path.Base(dir), dir: true})
+	}
+}
+
+// Open implements fs.FS.
+func (fsys *docFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := fsys.files[name]; ok {
+		return &docFile{info: fileInfo{name: path.Base(name), size: int64(len(data))}, Reader: bytes.NewReader(data)}, nil
+	}
+	if entries, ok := fsys.dirs[name]; ok {
+		return &docDir{info: fileInfo{name: path.Base(name), dir: true}, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Stat implements fs.StatFS.
+func (fsys *docFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := fsys.files[name]; ok {
+		return fileInfo{name: path.Base(name), size: int64(len(data))}, nil
+	}
+	if _, ok := fsys.dirs[name]; ok {
+		return fileInfo{name: path.Base(name), dir: true}, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (fsys *docFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, ok := fsys.dirs[name]
+	if !ok {
+		if _, isFile := fsys.files[name]; isFile {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(entries), nil
+}
+
+// Glob implements fs.GlobFS by matching pattern against every path.
+func (fsys *docFS) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var matches []string
+	for name := range fsys.files {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	for name := range fsys.dirs {
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	slices.Sort(matches)
+	return matches, nil
+}
+
+// fileInfo describes a file or directory of a docFS.
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() any           { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// docFile is an open file of a docFS. It implements io.Seeker and io.ReaderAt,
+// as http.FileServer requires.
+type docFile struct {
+	info fileInfo
+	*bytes.Reader
+}
+
+func (f *docFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *docFile) Close() error               { return nil }
+
+// docDir is an open directory of a docFS.
+type docDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	off     int
+}
+
+func (d *docDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *docDir) Close() error               { return nil }
+
+func (d *docDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *docDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.off:]
+	if n <= 0 {
+		d.off = len(d.entries)
+		return slices.Clone(rest), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	d.off += len(rest)
+	return slices.Clone(rest), nil
+}
@@ -0,0 +1,50 @@
+package mdocx
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDocumentFS(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "clash", Path: "docs/index.md", Data: []byte("x")},
+		MediaItem{ID: "nopath", Data: []byte("y")},
+	)
+	fsys := doc.FS()
+	if err := fstest.TestFS(fsys, "docs/index.md", "docs/notes.md", "assets/logo.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fs.ReadFile(fsys, "docs/index.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(doc.Markdown.Files[0].Content) {
+		t.Fatalf("markdown file shadowed by media item: %q", b)
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !slices.Equal(names, []string{"assets", "docs"}) {
+		t.Fatalf("root entries = %v", names)
+	}
+	matches, err := fs.Glob(fsys, "docs/*.md")
+	if err != nil || !slices.Equal(matches, []string{"docs/index.md", "docs/notes.md"}) {
+		t.Fatalf("Glob = %v, %v", matches, err)
+	}
+	if _, err := fs.Stat(fsys, "missing.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := fs.Glob(fsys, "["); err == nil {
+		t.Fatal("expected bad pattern error")
+	}
+}
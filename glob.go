@@ -0,0 +1,82 @@
+package mdocx
+
+import (
+	"path"
+	"strings"
+)
+
+// Glob returns the Markdown files whose path matches pattern, in bundle order.
+//
+// Patterns use the syntax of path.Match, applied segment by segment, with one
+// addition: a segment that is exactly "**" matches zero or more whole path
+// segments. So "docs/*.md" matches the Markdown files directly in docs,
+// "docs/**/*.md" those at any depth below it, and "**/index.md" every
+// index.md. The only possible error is path.ErrBadPattern.
+func (d *Document) Glob(pattern string) ([]MarkdownFile, error) {
+	if err := checkGlob(pattern); err != nil {
+		return nil, err
+	}
+	var out []MarkdownFile
+	for _, f := range d.Markdown.Files {
+		if matchGlob(pattern, f.Path) {
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// MediaGlob returns the media items whose Path matches pattern, in bundle
+// order, with the pattern syntax of Glob. Items without a Path never match.
+func (d *Document) MediaGlob(pattern string) ([]MediaItem, error) {
+	if err := checkGlob(pattern); err != nil {
+		return nil, err
+	}
+	var out []MediaItem
+	for _, it := range d.Media.Items {
+		if it.Path != "" && matchGlob(pattern, it.Path) {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+// checkGlob reports path.ErrBadPattern if a segment of pattern is malformed.
+func checkGlob(pattern string) error {
+	for seg := range strings.SplitSeq(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether name matches the well-formed pattern, as described
+// by Document.Glob.
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, name []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			// Collapse repeated "**" and try every split of the remaining segments.
+			for len(pat) > 1 && pat[1] == "**" {
+				pat = pat[1:]
+			}
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pat[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], name[0]); !ok {
+			return false
+		}
+		pat, name = pat[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,73 @@
+package mdocx
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"docs/*.md", "docs/index.md", true},
+		{"docs/*.md", "docs/a/b.md", false},
+		{"docs/**/*.md", "docs/index.md", true},
+		{"docs/**/*.md", "docs/a/b/c.md", true},
+		{"docs/**/*.md", "other/a.md", false},
+		{"**/index.md", "index.md", true},
+		{"**/index.md", "a/b/index.md", true},
+		{"**/index.md", "a/b/index.mdx", false},
+		{"**", "a/b/c", true},
+		{"a/**", "a", true},
+		{"a/**/**/c", "a/c", true},
+		{"assets/img?.png", "assets/img1.png", true},
+		{"assets/[a-c].png", "assets/d.png", false},
+		{"a**b/x", "ab/x", true},
+		{"a**b/x", "a/b/x", false},
+	}
+	for _, c := range cases {
+		if got := matchGlob(c.pattern, c.name); got != c.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestDocumentGlob(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files = append(doc.Markdown.Files, MarkdownFile{Path: "docs/guide/intro.md"}, MarkdownFile{Path: "README.md"})
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "photo", Path: "assets/photos/a.jpg"},
+		MediaItem{ID: "nopath"},
+	)
+
+	files, err := doc.Glob("docs/**/*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[0].Path != "docs/index.md" || files[2].Path != "docs/guide/intro.md" {
+		t.Fatalf("got %v", files)
+	}
+	if files, _ := doc.Glob("*.md"); len(files) != 1 || files[0].Path != "README.md" {
+		t.Fatalf("top level: got %v", files)
+	}
+
+	items, err := doc.MediaGlob("assets/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != "logo" || items[1].ID != "photo" {
+		t.Fatalf("got %v", items)
+	}
+	if items, _ := doc.MediaGlob("**"); len(items) != 2 {
+		t.Fatalf("items without a path must not match: got %d", len(items))
+	}
+
+	if _, err := doc.Glob("docs/[*.md"); !errors.Is(err, path.ErrBadPattern) {
+		t.Fatalf("got %v", err)
+	}
+	if _, err := doc.MediaGlob("**/[x"); !errors.Is(err, path.ErrBadPattern) {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -6,4 +6,5 @@ require (
 	github.com/andybalholm/brotli v1.2.0
 	github.com/klauspost/compress v1.18.2
 	github.com/pierrec/lz4/v4 v4.1.23
+	github.com/ulikunitz/xz v0.5.17
 )
@@ -0,0 +1,604 @@
+package mdocx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Built-in gob type IDs (see encoding/gob/type.go).
+const (
+	gobTBool      = 1
+	gobTInt       = 2
+	gobTUint      = 3
+	gobTFloat     = 4
+	gobTBytes     = 5
+	gobTString    = 6
+	gobTComplex   = 7
+	gobTInterface = 8
+)
+
+// gobKind identifies the shape of a user-defined gob type.
+type gobKind int
+
+const (
+	gobKindArray gobKind = iota
+	gobKindSlice
+	gobKindStruct
+	gobKindMap
+	gobKindEncoder // GobEncoder, BinaryMarshaler, or TextMarshaler: opaque bytes
+)
+
+// gobField is a field of a gob struct type.
+type gobField struct {
+	name string
+	id   int
+}
+
+// gobType is a user-defined type parsed from a gob wireType message.
+type gobType struct {
+	kind   gobKind
+	elem   int
+	key    int
+	length int
+	fields []gobField
+}
+
+// mediaEntry describes one media item located inside a gob-encoded MediaBundle.
+// Data is not read; dataOff and dataLen locate it within the scanned stream.
+type mediaEntry struct {
+	ID         string
+	Path       string
+	MIMEType   string
+	SHA256     [32]byte
+	Attributes map[string]string
+	dataOff    int64
+	dataLen    int64
+	packed     Compression // compression of the data, see unpackEntries
+	size       int64       // decompressed length of packed data
+}
+
+// mediaIndex is the result of scanning a gob-encoded MediaBundle.
+type mediaIndex struct {
+	bundleVersion uint16
+	items         []mediaEntry
+}
+
+// gobScanner reads a gob stream from an io.ReaderAt without materializing byte
+// slices, so that large fields can be located and skipped rather than copied.
+type gobScanner struct {
+	readAhead
+	types map[int]*gobType
+}
+
+// readAhead reads r[off:end] a byte at a time through a small buffer.
+type readAhead struct {
+	r     io.ReaderAt
+	off   int64
+	end   int64
+	buf   []byte
+	bufAt int64
+}
+
+// errGobScan marks a stream that is well-formed gob but does not have the shape
+// expected by the scanner.
+var errGobScan = errors.New("unexpected gob layout")
+
+// scanMediaGob scans a gob-encoded MediaBundle held in r[0:size] and returns the
+// location of each item's Data without copying it.
+// maxItems bounds the number of entries the scanner will allocate.
+func scanMediaGob(r io.ReaderAt, size int64, maxItems int) (*mediaIndex, error) {
+	s := &gobScanner{readAhead: readAhead{r: r, end: size}, types: make(map[int]*gobType)}
+	idx, err := s.scanMediaBundle(maxItems)
+	if err != nil {
+		if errors.Is(err, ErrLimitExceeded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: media gob: %v", ErrInvalidPayload, err)
+	}
+	return idx, nil
+}
+
+func (s *gobScanner) scanMediaBundle(maxItems int) (*mediaIndex, error) {
+	id, err := s.nextValue()
+	if err != nil {
+		return nil, err
+	}
+	t := s.types[id]
+	if t == nil || t.kind != gobKindStruct {
+		return nil, errGobScan
+	}
+	idx := &mediaIndex{}
+	err = s.walkStruct(t, func(f gobField) (bool, error) {
+		switch f.name {
+		case "BundleVersion":
+			if f.id != gobTUint {
+				return false, errGobScan
+			}
+			v, err := s.uint()
+			if err != nil {
+				return false, err
+			}
+			idx.bundleVersion = uint16(v)
+			return true, nil
+		case "Items":
+			st := s.types[f.id]
+			if st == nil || st.kind != gobKindSlice {
+				return false, errGobScan
+			}
+			it := s.types[st.elem]
+			if it == nil || it.kind != gobKindStruct {
+				return false, errGobScan
+			}
+			n, err := s.count()
+			if err != nil {
+				return false, err
+			}
+			if n > maxItems {
+				return false, exceeds("MaxMediaItems", "too many media items")
+			}
+			idx.items = make([]mediaEntry, 0, n)
+			for i := 0; i < n; i++ {
+				e, err := s.scanMediaItem(it)
+				if err != nil {
+					return false, err
+				}
+				idx.items = append(idx.items, e)
+			}
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (s *gobScanner) scanMediaItem(t *gobType) (mediaEntry, error) {
+	var e mediaEntry
+	err := s.walkStruct(t, func(f gobField) (bool, error) {
+		var err error
+		switch f.name {
+		case "ID", "Path", "MIMEType":
+			if f.id != gobTString {
+				return false, errGobScan
+			}
+			var v string
+			if v, err = s.string(); err != nil {
+				return false, err
+			}
+			switch f.name {
+			case "ID":
+				e.ID = v
+			case "Path":
+				e.Path = v
+			default:
+				e.MIMEType = v
+			}
+			return true, nil
+		case "Data":
+			if f.id != gobTBytes {
+				return false, errGobScan
+			}
+			n, err := s.uint()
+			if err != nil {
+				return false, err
+			}
+			if n > uint64(s.end-s.off) {
+				return false, io.ErrUnexpectedEOF
+			}
+			e.dataOff, e.dataLen = s.off, int64(n)
+			s.off += int64(n)
+			return true, nil
+		case "SHA256":
+			at := s.types[f.id]
+			if at == nil || at.kind != gobKindArray || at.elem != gobTUint || at.length != len(e.SHA256) {
+				return false, errGobScan
+			}
+			n, err := s.uint()
+			if err != nil {
+				return false, err
+			}
+			if n != uint64(len(e.SHA256)) {
+				return false, errGobScan
+			}
+			for i := range e.SHA256 {
+				b, err := s.uint()
+				if err != nil {
+					return false, err
+				}
+				e.SHA256[i] = byte(b)
+			}
+			return true, nil
+		case "Attributes":
+			mt := s.types[f.id]
+			if mt == nil || mt.kind != gobKindMap || mt.key != gobTString || mt.elem != gobTString {
+				return false, errGobScan
+			}
+			n, err := s.count()
+			if err != nil {
+				return false, err
+			}
+			e.Attributes = make(map[string]string, n)
+			for i := 0; i < n; i++ {
+				k, err := s.string()
+				if err != nil {
+					return false, err
+				}
+				v, err := s.string()
+				if err != nil {
+					return false, err
+				}
+				e.Attributes[k] = v
+			}
+			return true, nil
+		}
+		return false, nil
+	})
+	return e, err
+}
+
+// walkStruct iterates the fields of a struct value of type t. For each field present
+// in the stream, visit is called; if it returns false the field is skipped generically.
+func (s *gobScanner) walkStruct(t *gobType, visit func(gobField) (bool, error)) error {
+	field := -1
+	for {
+		delta, err := s.uint()
+		if err != nil {
+			return err
+		}
+		if delta == 0 {
+			return nil
+		}
+		if delta > uint64(len(t.fields)) {
+			return errGobScan
+		}
+		field += int(delta)
+		if field >= len(t.fields) {
+			return errGobScan
+		}
+		f := t.fields[field]
+		handled, err := visit(f)
+		if err != nil {
+			return err
+		}
+		if !handled {
+			if err := s.skipValue(f.id, 0); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skipValue skips a value of the given type.
+func (s *gobScanner) skipValue(id int, depth int) error {
+	if depth > 64 {
+		return errGobScan
+	}
+	switch id {
+	case gobTBool, gobTInt, gobTUint, gobTFloat:
+		_, err := s.uint()
+		return err
+	case gobTComplex:
+		if _, err := s.uint(); err != nil {
+			return err
+		}
+		_, err := s.uint()
+		return err
+	case gobTBytes, gobTString:
+		return s.skipBytes()
+	case gobTInterface:
+		return errGobScan
+	}
+	t := s.types[id]
+	if t == nil {
+		return errGobScan
+	}
+	switch t.kind {
+	case gobKindArray, gobKindSlice:
+		n, err := s.count()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := s.skipValue(t.elem, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case gobKindMap:
+		n, err := s.count()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := s.skipValue(t.key, depth+1); err != nil {
+				return err
+			}
+			if err := s.skipValue(t.elem, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case gobKindStruct:
+		return s.walkStruct(t, func(f gobField) (bool, error) {
+			return true, s.skipValue(f.id, depth+1)
+		})
+	default:
+		return s.skipBytes()
+	}
+}
+
+// nextValue consumes type definition messages and returns the type ID of the
+// first value message, leaving the scanner positioned at the start of the value.
+func (s *gobScanner) nextValue() (int, error) {
+	for {
+		n, err := s.uint()
+		if err != nil {
+			return 0, err
+		}
+		if n > uint64(s.end-s.off) {
+			return 0, io.ErrUnexpectedEOF
+		}
+		msgEnd := s.off + int64(n)
+		id, err := s.int()
+		if err != nil {
+			return 0, err
+		}
+		if id >= 0 {
+			return int(id), nil
+		}
+		if err := s.wireType(int(-id)); err != nil {
+			return 0, err
+		}
+		if s.off != msgEnd {
+			return 0, errGobScan
+		}
+	}
+}
+
+// wireType parses a wireType message body defining type id.
+func (s *gobScanner) wireType(id int) error {
+	t := &gobType{}
+	err := s.walkWire(func(field int) error {
+		switch field {
+		case 0: // ArrayT
+			t.kind = gobKindArray
+			return s.walkWire(func(f int) error {
+				switch f {
+				case 0:
+					return s.skipCommonType()
+				case 1:
+					return s.intInto(&t.elem)
+				case 2:
+					return s.intInto(&t.length)
+				}
+				return errGobScan
+			})
+		case 1: // SliceT
+			t.kind = gobKindSlice
+			return s.walkWire(func(f int) error {
+				switch f {
+				case 0:
+					return s.skipCommonType()
+				case 1:
+					return s.intInto(&t.elem)
+				}
+				return errGobScan
+			})
+		case 2: // StructT
+			t.kind = gobKindStruct
+			return s.walkWire(func(f int) error {
+				switch f {
+				case 0:
+					return s.skipCommonType()
+				case 1:
+					n, err := s.count()
+					if err != nil {
+						return err
+					}
+					t.fields = make([]gobField, 0, n)
+					for i := 0; i < n; i++ {
+						var gf gobField
+						err := s.walkWire(func(ff int) error {
+							switch ff {
+							case 0:
+								v, err := s.string()
+								gf.name = v
+								return err
+							case 1:
+								return s.intInto(&gf.id)
+							}
+							return errGobScan
+						})
+						if err != nil {
+							return err
+						}
+						t.fields = append(t.fields, gf)
+					}
+					return nil
+				}
+				return errGobScan
+			})
+		case 3: // MapT
+			t.kind = gobKindMap
+			return s.walkWire(func(f int) error {
+				switch f {
+				case 0:
+					return s.skipCommonType()
+				case 1:
+					return s.intInto(&t.key)
+				case 2:
+					return s.intInto(&t.elem)
+				}
+				return errGobScan
+			})
+		case 4, 5, 6: // GobEncoderT, BinaryMarshalerT, TextMarshalerT
+			t.kind = gobKindEncoder
+			return s.walkWire(func(f int) error {
+				if f == 0 {
+					return s.skipCommonType()
+				}
+				return errGobScan
+			})
+		}
+		return errGobScan
+	})
+	if err != nil {
+		return err
+	}
+	s.types[id] = t
+	return nil
+}
+
+// walkWire walks a struct of the bootstrapped wire types, calling visit with the
+// absolute field number of each field present.
+func (s *gobScanner) walkWire(visit func(field int) error) error {
+	field := -1
+	for {
+		delta, err := s.uint()
+		if err != nil {
+			return err
+		}
+		if delta == 0 {
+			return nil
+		}
+		if delta > 16 {
+			return errGobScan
+		}
+		field += int(delta)
+		if err := visit(field); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *gobScanner) skipCommonType() error {
+	return s.walkWire(func(f int) error {
+		switch f {
+		case 0:
+			return s.skipBytes()
+		case 1:
+			_, err := s.uint()
+			return err
+		}
+		return errGobScan
+	})
+}
+
+func (s *gobScanner) intInto(dst *int) error {
+	v, err := s.int()
+	if err != nil {
+		return err
+	}
+	*dst = int(v)
+	return nil
+}
+
+// byte reads a single byte through a small read-ahead buffer.
+func (s *readAhead) byte() (byte, error) {
+	if s.off >= s.end {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if s.off < s.bufAt || s.off >= s.bufAt+int64(len(s.buf)) {
+		if err := s.fill(); err != nil {
+			return 0, err
+		}
+	}
+	b := s.buf[s.off-s.bufAt]
+	s.off++
+	return b, nil
+}
+
+func (s *readAhead) fill() error {
+	n := int64(4096)
+	if rem := s.end - s.off; rem < n {
+		n = rem
+	}
+	if int64(cap(s.buf)) < n {
+		s.buf = make([]byte, n)
+	}
+	s.buf = s.buf[:n]
+	read, err := s.r.ReadAt(s.buf, s.off)
+	if int64(read) < n {
+		if err == nil || err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	s.bufAt = s.off
+	return nil
+}
+
+// uint reads a gob-encoded unsigned integer.
+func (s *gobScanner) uint() (uint64, error) {
+	b, err := s.byte()
+	if err != nil {
+		return 0, err
+	}
+	if b <= 0x7f {
+		return uint64(b), nil
+	}
+	n := -int(int8(b))
+	if n > 8 {
+		return 0, errGobScan
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		c, err := s.byte()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// int reads a gob-encoded signed integer.
+func (s *gobScanner) int() (int64, error) {
+	u, err := s.uint()
+	if err != nil {
+		return 0, err
+	}
+	if u&1 != 0 {
+		return ^int64(u >> 1), nil
+	}
+	return int64(u >> 1), nil
+}
+
+// count reads an element count and bounds it by the remaining stream length,
+// since every element occupies at least one byte.
+func (s *gobScanner) count() (int, error) {
+	n, err := s.uint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(s.end-s.off) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+func (s *gobScanner) string() (string, error) {
+	n, err := s.count()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	for i := range b {
+		if b[i], err = s.byte(); err != nil {
+			return "", err
+		}
+	}
+	return string(b), nil
+}
+
+func (s *gobScanner) skipBytes() error {
+	n, err := s.count()
+	if err != nil {
+		return err
+	}
+	s.off += int64(n)
+	return nil
+}
@@ -0,0 +1,92 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestScanMediaGob_MatchesGobDecode(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, MediaItem{
+		ID:         "big",
+		MIMEType:   "application/octet-stream",
+		Data:       bytes.Repeat([]byte{0xAB}, 300),
+		SHA256:     [32]byte{0xFF, 0x80, 1},
+		Attributes: map[string]string{"alt": "Big", "k": "v"},
+	})
+	b, err := gobEncode(doc.Media)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := scanMediaGob(bytes.NewReader(b), int64(len(b)), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.bundleVersion != VersionV1 || len(idx.items) != len(doc.Media.Items) {
+		t.Fatalf("unexpected index: %+v", idx)
+	}
+	for i, want := range doc.Media.Items {
+		got := idx.items[i]
+		if got.ID != want.ID || got.Path != want.Path || got.MIMEType != want.MIMEType || got.SHA256 != want.SHA256 {
+			t.Fatalf("item %d mismatch: %+v", i, got)
+		}
+		if len(want.Attributes) != len(got.Attributes) || got.Attributes["alt"] != want.Attributes["alt"] {
+			t.Fatalf("item %d attributes mismatch: %v", i, got.Attributes)
+		}
+		if !bytes.Equal(b[got.dataOff:got.dataOff+got.dataLen], want.Data) {
+			t.Fatalf("item %d data location mismatch", i)
+		}
+	}
+}
+
+func TestScanMediaGob_SkipsUnknownFields(t *testing.T) {
+	type futureItem struct {
+		ID     string
+		Extra  []map[string]int
+		Data   []byte
+		Nested struct{ A, B int64 }
+	}
+	type futureBundle struct {
+		BundleVersion uint16
+		Items         []futureItem
+		Trailer       string
+	}
+	in := futureBundle{BundleVersion: 1, Trailer: "t", Items: []futureItem{{
+		ID:    "x",
+		Extra: []map[string]int{{"a": -1}},
+		Data:  []byte("hello"),
+	}}}
+	in.Items[0].Nested.A = -5
+	b, err := gobEncode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := scanMediaGob(bytes.NewReader(b), int64(len(b)), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := idx.items[0]
+	if it.ID != "x" || string(b[it.dataOff:it.dataOff+it.dataLen]) != "hello" {
+		t.Fatalf("unexpected item: %+v", it)
+	}
+}
+
+func TestScanMediaGob_Errors(t *testing.T) {
+	b, err := gobEncode(sampleDoc().Media)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanMediaGob(bytes.NewReader(b), int64(len(b)), 0); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	for _, n := range []int{0, 1, len(b) / 2, len(b) - 3} {
+		if _, err := scanMediaGob(bytes.NewReader(b[:n]), int64(n), 10); !errors.Is(err, ErrInvalidPayload) {
+			t.Fatalf("truncated at %d: expected ErrInvalidPayload, got %v", n, err)
+		}
+	}
+	wrong, _ := gobEncode(struct{ BundleVersion string }{"1"})
+	if _, err := scanMediaGob(bytes.NewReader(wrong), int64(len(wrong)), 10); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected ErrInvalidPayload, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// gobBuf accumulates gob-encoded primitives.
+type gobBuf []byte
+
+// uint appends u in gob's unsigned integer encoding: values below 128 take one
+// byte; larger values are a negated byte count followed by big-endian bytes.
+func (b *gobBuf) uint(u uint64) {
+	if u < 0x80 {
+		*b = append(*b, byte(u))
+		return
+	}
+	var tmp [8]byte
+	n := 8
+	for u > 0 {
+		n--
+		tmp[n] = byte(u)
+		u >>= 8
+	}
+	*b = append(*b, byte(-(8 - n)))
+	*b = append(*b, tmp[n:]...)
+}
+
+// int appends i in gob's signed integer encoding.
+func (b *gobBuf) int(i int64) {
+	if i < 0 {
+		b.uint(uint64(^i)<<1 | 1)
+		return
+	}
+	b.uint(uint64(i) << 1)
+}
+
+// string appends s as a length-prefixed byte string.
+func (b *gobBuf) string(s string) {
+	b.uint(uint64(len(s)))
+	*b = append(*b, s...)
+}
+
+// gobUintLen returns the encoded length of u.
+func gobUintLen(u uint64) int {
+	var b gobBuf
+	b.uint(u)
+	return len(b)
+}
+
+// splitGobMessages splits a gob stream into its type-definition messages and
+// the single value message that must end it. It returns the raw bytes of all
+// type-definition messages, the value's type ID, and the value body after the
+// type ID.
+func splitGobMessages(stream []byte) (defs []byte, typeID int, body []byte, err error) {
+	s := &gobScanner{readAhead: readAhead{r: bytes.NewReader(stream), end: int64(len(stream))}}
+	for s.off < s.end {
+		start := s.off
+		n, err := s.uint()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		msgEnd := s.off + int64(n)
+		id, err := s.int()
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		if id >= 0 {
+			if msgEnd != s.end {
+				return nil, 0, nil, fmt.Errorf("%w: trailing gob data", errGobScan)
+			}
+			return stream[:start], int(id), stream[s.off:msgEnd], nil
+		}
+		s.off = msgEnd
+	}
+	return nil, 0, nil, fmt.Errorf("%w: no gob value message", errGobScan)
+}
+
+// gobElementEncoder encodes values of type T as they appear inside a gob slice.
+// For struct types the encoding of a slice element equals the body of a
+// top-level value message after its type ID, and contains no type IDs, so it
+// can be spliced into a bundle encoded with a different encoder.
+type gobElementEncoder[T any] struct {
+	buf bytes.Buffer
+	enc *gob.Encoder
+}
+
+func newGobElementEncoder[T any]() *gobElementEncoder[T] {
+	e := &gobElementEncoder[T]{}
+	e.enc = gob.NewEncoder(&e.buf)
+	return e
+}
+
+// encode returns the element encoding of v. The result is only valid until the
+// next call.
+func (e *gobElementEncoder[T]) encode(v T) ([]byte, error) {
+	e.buf.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		return nil, err
+	}
+	_, _, body, err := splitGobMessages(e.buf.Bytes())
+	return body, err
+}
+
+// gobBundleHead is the encoded start of a bundle value message whose trailing
+// slice field is written element by element.
+type gobBundleHead struct {
+	// defs holds the type-definition messages that precede the value.
+	defs []byte
+	// fields holds the value's type ID, the fields before the slice, and the
+	// slice field delta and element count (omitted when count is zero).
+	fields []byte
+}
+
+// size returns the length of the complete gob stream given the total length of
+// the encoded elements.
+func (h gobBundleHead) size(elems int64) int64 {
+	body := int64(len(h.fields)) + elems + 1
+	return int64(len(h.defs)) + int64(gobUintLen(uint64(body))) + body
+}
+
+// prefix returns the bytes written before the first element.
+func (h gobBundleHead) prefix(elems int64) []byte {
+	var b gobBuf
+	b = append(b, h.defs...)
+	b.uint(uint64(int64(len(h.fields)) + elems + 1))
+	return append(b, h.fields...)
+}
+
+// gobBundleTrailer ends the bundle struct.
+var gobBundleTrailer = []byte{0}
+
+func (h gobBundleHead) trailer() []byte { return gobBundleTrailer }
+
+// markdownBundleHead returns the gob head of a MarkdownBundle with count files.
+func markdownBundleHead(rootPath string, count int) (gobBundleHead, error) {
+	defs, id, err := gobTypeDefs(MarkdownBundle{BundleVersion: VersionV1, Files: []MarkdownFile{{}}})
+	if err != nil {
+		return gobBundleHead{}, err
+	}
+	var b gobBuf
+	b.int(int64(id))
+	b.uint(1) // BundleVersion (field 0)
+	b.uint(uint64(VersionV1))
+	last := 0
+	if rootPath != "" {
+		b.uint(1) // RootPath (field 1)
+		b.string(rootPath)
+		last = 1
+	}
+	if count > 0 {
+		b.uint(uint64(2 - last)) // Files (field 2)
+		b.uint(uint64(count))
+	}
+	return gobBundleHead{defs: defs, fields: b}, nil
+}
+
+// mediaBundleHead returns the gob head of a MediaBundle with count items.
+func mediaBundleHead(count int) (gobBundleHead, error) {
+	defs, id, err := gobTypeDefs(MediaBundle{BundleVersion: VersionV1, Items: []MediaItem{{}}})
+	if err != nil {
+		return gobBundleHead{}, err
+	}
+	var b gobBuf
+	b.int(int64(id))
+	b.uint(1) // BundleVersion (field 0)
+	b.uint(uint64(VersionV1))
+	if count > 0 {
+		b.uint(1) // Items (field 1)
+		b.uint(uint64(count))
+	}
+	return gobBundleHead{defs: defs, fields: b}, nil
+}
+
+// gobTypeDefs returns the type-definition messages a fresh gob.Encoder emits
+// for template's type, and the type ID it assigns to it.
+func gobTypeDefs(template any) ([]byte, int, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(template); err != nil {
+		return nil, 0, err
+	}
+	defs, id, _, err := splitGobMessages(buf.Bytes())
+	return defs, id, err
+}
@@ -0,0 +1,100 @@
+package mdocx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGobBufUint(t *testing.T) {
+	cases := map[uint64][]byte{
+		0:       {0x00},
+		127:     {0x7f},
+		128:     {0xff, 0x80},
+		256:     {0xfe, 0x01, 0x00},
+		1 << 32: {0xfb, 0x01, 0x00, 0x00, 0x00, 0x00},
+	}
+	for u, want := range cases {
+		var b gobBuf
+		b.uint(u)
+		if !bytes.Equal(b, want) || gobUintLen(u) != len(want) {
+			t.Fatalf("uint(%d) = %x, want %x", u, []byte(b), want)
+		}
+	}
+	var b gobBuf
+	b.int(-1)
+	b.int(2)
+	if !bytes.Equal(b, []byte{0x01, 0x04}) {
+		t.Fatalf("int encoding: %x", []byte(b))
+	}
+}
+
+// assembleBundle builds a gob stream from a head and element encodings.
+func assembleBundle(head gobBundleHead, elems [][]byte) []byte {
+	var total int64
+	for _, e := range elems {
+		total += int64(len(e))
+	}
+	out := head.prefix(total)
+	for _, e := range elems {
+		out = append(out, e...)
+	}
+	out = append(out, gobBundleTrailer...)
+	if int64(len(out)) != head.size(total) {
+		panic("size mismatch")
+	}
+	return out
+}
+
+func TestGobBundleMatchesEncoder(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].Attributes = map[string]string{"alt": "Logo"}
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "empty"})
+
+	for _, root := range []string{"", "docs/index.md"} {
+		md := doc.Markdown
+		md.RootPath = root
+		head, err := markdownBundleHead(root, len(md.Files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc := newGobElementEncoder[MarkdownFile]()
+		var elems [][]byte
+		for _, f := range md.Files {
+			e, err := enc.encode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			elems = append(elems, append([]byte(nil), e...))
+		}
+		want, err := gobEncode(md)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := assembleBundle(head, elems); !bytes.Equal(got, want) {
+			t.Fatalf("markdown bundle (root %q):\n got %x\nwant %x", root, got, want)
+		}
+	}
+
+	for _, items := range [][]MediaItem{nil, doc.Media.Items} {
+		head, err := mediaBundleHead(len(items))
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc := newGobElementEncoder[MediaItem]()
+		var elems [][]byte
+		for _, it := range items {
+			e, err := enc.encode(it)
+			if err != nil {
+				t.Fatal(err)
+			}
+			elems = append(elems, append([]byte(nil), e...))
+		}
+		want, err := gobEncode(MediaBundle{BundleVersion: VersionV1, Items: items})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := assembleBundle(head, elems); !bytes.Equal(got, want) {
+			t.Fatalf("media bundle (%d items):\n got %x\nwant %x", len(items), got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package mdocx
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+	"io"
+)
+
+// HashingWriter is an io.Writer that forwards writes to an underlying writer
+// and computes the SHA-256 of the bytes it accepted. It lets callers obtain the
+// digest of an encoded container (for manifests, ETags, or signatures) in the
+// same pass that writes it.
+type HashingWriter struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+// NewHashingWriter returns a HashingWriter that writes to w.
+func NewHashingWriter(w io.Writer) *HashingWriter {
+	return &HashingWriter{w: w, h: sha256.New()}
+}
+
+// Write writes p to the underlying writer. Only the bytes the underlying writer
+// reports as written are hashed.
+func (hw *HashingWriter) Write(p []byte) (int, error) {
+	n, err := hw.w.Write(p)
+	hw.h.Write(p[:n])
+	hw.n += int64(n)
+	return n, err
+}
+
+// Sum returns the SHA-256 of all bytes written so far.
+func (hw *HashingWriter) Sum() [32]byte {
+	var out [32]byte
+	hw.h.Sum(out[:0])
+	return out
+}
+
+// Written returns the number of bytes written so far.
+func (hw *HashingWriter) Written() int64 {
+	return hw.n
+}
+
+// outputWriter wraps w in a writer that fails once ctx is done when ctx can be
+// canceled, in a rate-limited writer when WithWriteRateLimit was given, and in
+// a HashingWriter when WithOutputSHA256 was given. done must be called with the
+// final error; it stores the hash only on success.
+func (c writeConfig) outputWriter(ctx context.Context, w io.Writer) (io.Writer, func(err error)) {
+	if ctx.Done() != nil {
+		w = contextWriter{ctx, w}
+	}
+	if c.rateLimit != nil {
+		w = NewRateLimitedWriter(ctx, w, c.rateLimit)
+	}
+	if c.outputSHA256 == nil {
+		return w, func(error) {}
+	}
+	hw := NewHashingWriter(w)
+	return hw, func(err error) {
+		if err == nil {
+			*c.outputSHA256 = hw.Sum()
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestHashingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	hw := NewHashingWriter(&buf)
+	if _, err := hw.Write([]byte("hello ")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hw.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	if hw.Sum() != sha256.Sum256([]byte("hello world")) || hw.Written() != 11 {
+		t.Fatalf("unexpected sum or count: %d", hw.Written())
+	}
+}
+
+func TestEncodeOutputSHA256(t *testing.T) {
+	var buf bytes.Buffer
+	var sum [32]byte
+	if err := Encode(&buf, sampleDoc(), WithOutputSHA256(&sum)); err != nil {
+		t.Fatal(err)
+	}
+	if sum != sha256.Sum256(buf.Bytes()) {
+		t.Fatal("output hash does not match written bytes")
+	}
+
+	var untouched [32]byte
+	bad := sampleDoc()
+	bad.Markdown.Files = nil
+	if err := Encode(&buf, bad, WithOutputSHA256(&untouched)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	if untouched != ([32]byte{}) {
+		t.Fatal("hash must not be stored when encoding fails")
+	}
+}
@@ -0,0 +1,209 @@
+package mdocx
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Header describes a container without its bundles, as returned by
+// DecodeHeader.
+type Header struct {
+	// Version is the format version (VersionV1 or VersionV2).
+	Version uint16
+	// Flags holds the HeaderFlag bits of the fixed header.
+	Flags uint16
+	// Capabilities holds the Capability bits of a version 2 container.
+	Capabilities uint64
+	// Metadata is the document metadata, or nil if absent.
+	Metadata map[string]any
+	// MetadataLength is the length of the metadata block in bytes.
+	MetadataLength uint32
+	// Markdown and Media describe the two sections.
+	Markdown SectionInfo
+	Media    SectionInfo
+}
+
+// NoMedia reports whether the container declares that it has no media bundle.
+func (h *Header) NoMedia() bool {
+	return h.Flags&HeaderFlagNoMedia != 0
+}
+
+// SectionInfo describes a section as stored.
+type SectionInfo struct {
+	// Compression is the algorithm the payload is compressed with.
+	Compression Compression
+	// PayloadLen is the length of the payload as stored, including any
+	// uncompressed-length prefix.
+	PayloadLen uint64
+	// UncompressedLen is the declared length of the gob-encoded bundle.
+	UncompressedLen uint64
+	// ItemCompression reports whether the media items are compressed one at
+	// a time (see WithSmartMediaCompression).
+	ItemCompression bool
+	// DuplicateStubs reports whether some media items are stubs written by
+	// DuplicatesDedupe (see WithDuplicateMedia).
+	DuplicateStubs bool
+}
+
+// DecodeHeader reads the fixed header, metadata, and section headers of an
+// MDOCX container from r without reading or decompressing either bundle, for
+// listings that only need titles or sizes. It reads past the Markdown payload
+// by seeking if r implements io.Seeker and by discarding it otherwise, so a
+// truncated Markdown payload may go unnoticed; the Media payload is not read.
+//
+// Only the metadata length limit of the ReadOption values applies, and
+// MaxMediaSectionLen to sections skipped with WithIgnoreUnknownSections.
+// DecodeHeader returns the same header errors as Decode.
+func DecodeHeader(r io.Reader, opts ...ReadOption) (*Header, error) {
+	cfg := newReadConfig(opts)
+	r = cfg.input(context.Background(), r)
+	h, err := readFixedHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFixedHeaderV2(h, cfg.limits); err != nil {
+		return nil, err
+	}
+	out := &Header{Version: h.Version, Flags: h.HeaderFlags, MetadataLength: h.MetadataLength}
+	if h.Version == VersionV2 {
+		out.Capabilities = h.Reserved1
+	}
+	if h.MetadataLength > 0 {
+		mb := make([]byte, h.MetadataLength)
+		if _, err := io.ReadFull(r, mb); err != nil {
+			return nil, err
+		}
+		if out.Metadata, err = parseMetadata(h, mb); err != nil {
+			return nil, err
+		}
+	}
+
+	if h.Version == VersionV2 {
+		if err := readSectionInfosV2(r, h, out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	if out.Markdown, err = readSectionInfo(r, SectionMarkdown, cfg); err != nil {
+		return nil, err
+	}
+	skip := out.Markdown.PayloadLen
+	if out.Markdown.Compression != CompNone {
+		skip -= 8
+	}
+	if err := skipBytes(r, skip); err != nil {
+		return nil, err
+	}
+	if out.Media, err = readSectionInfo(r, SectionMedia, cfg); err != nil {
+		return nil, err
+	}
+	if _, err := checkNoMedia(h, sectionHeaderV1{PayloadLen: out.Media.PayloadLen}); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// readSectionInfosV2 reads the section table of a version 2 container with
+// fixed header h from r and fills in the Markdown and Media sections of out,
+// reading the uncompressed-length prefix of each and skipping the payloads
+// that precede the last of them.
+func readSectionInfosV2(r io.Reader, h fixedHeaderV1, out *Header) error {
+	table, err := readSectionTable(r, h)
+	if err != nil {
+		return err
+	}
+	last := 0
+	for i, sh := range table {
+		if sectionKnownV2(SectionType(sh.SectionType)) {
+			last = i
+		}
+	}
+	for i, sh := range table[:last+1] {
+		typ := SectionType(sh.SectionType)
+		if !sectionKnownV2(typ) {
+			if err := skipBytes(r, sh.PayloadLen); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := sectionInfo(r, sh, typ)
+		if err != nil {
+			return err
+		}
+		if typ == SectionMarkdown {
+			out.Markdown = info
+		} else {
+			out.Media = info
+		}
+		if i == last {
+			break
+		}
+		skip := info.PayloadLen
+		if info.Compression != CompNone {
+			skip -= 8
+		}
+		if err := skipBytes(r, skip); err != nil {
+			return err
+		}
+	}
+	_, err = checkNoMedia(h, sectionHeaderV1{PayloadLen: out.Media.PayloadLen})
+	return err
+}
+
+// readSectionInfo reads a section header of the wanted type, skipping
+// unknown sections before it with WithIgnoreUnknownSections, and, for a
+// compressed section, the uncompressed-length prefix of its payload.
+func readSectionInfo(r io.Reader, want SectionType, cfg readConfig) (SectionInfo, error) {
+	sh, err := nextSectionHeader(r, cfg.limits, cfg.ignoreUnknown)
+	if err != nil {
+		return SectionInfo{}, err
+	}
+	return sectionInfo(r, sh, want)
+}
+
+// sectionInfo checks the section header sh, already read from r, and reads
+// the uncompressed-length prefix of its payload if it is compressed.
+func sectionInfo(r io.Reader, sh sectionHeaderV1, want SectionType) (SectionInfo, error) {
+	if err := validateSectionHeader(sh, want); err != nil {
+		return SectionInfo{}, err
+	}
+	info := SectionInfo{
+		Compression:     sh.compression(),
+		PayloadLen:      sh.PayloadLen,
+		UncompressedLen: sh.PayloadLen,
+		ItemCompression: sh.SectionFlags&sectionFlagItemCompression != 0,
+		DuplicateStubs:  sh.SectionFlags&sectionFlagDuplicateStubs != 0,
+	}
+	if sh.hasUncompressedLen() {
+		if sh.PayloadLen < 8 {
+			return SectionInfo{}, fmt.Errorf("%w: payload too short for uncompressed length", ErrInvalidPayload)
+		}
+		var prefix [8]byte
+		if _, err := io.ReadFull(r, prefix[:]); err != nil {
+			return SectionInfo{}, err
+		}
+		info.UncompressedLen = binary.LittleEndian.Uint64(prefix[:])
+	}
+	return info, nil
+}
+
+// skipBytes advances r by n bytes.
+func skipBytes(r io.Reader, n uint64) error {
+	if n > math.MaxInt64 {
+		return io.ErrUnexpectedEOF
+	}
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(int64(n), io.SeekCurrent)
+		return err
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
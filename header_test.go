@@ -0,0 +1,61 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestDecodeHeader(t *testing.T) {
+	doc := sampleDoc()
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Both a seekable and a plain reader work.
+	for _, r := range []io.Reader{bytes.NewReader(data), io.MultiReader(bytes.NewReader(data))} {
+		h, err := DecodeHeader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Version != VersionV1 || h.Flags != HeaderFlagMetadataJSON || h.NoMedia() {
+			t.Fatalf("unexpected header: %+v", h)
+		}
+		if h.Metadata["title"] != "Example" {
+			t.Fatalf("metadata = %v", h.Metadata)
+		}
+		if h.Markdown.Compression != CompZSTD || h.Markdown.UncompressedLen == 0 || h.Markdown.PayloadLen == 0 {
+			t.Fatalf("markdown section = %+v", h.Markdown)
+		}
+		if h.Media.Compression != CompNone || h.Media.UncompressedLen != h.Media.PayloadLen {
+			t.Fatalf("media section = %+v", h.Media)
+		}
+		if got := 32 + uint64(h.MetadataLength) + 16 + h.Markdown.PayloadLen + 16 + h.Media.PayloadLen; got != uint64(len(data)) {
+			t.Fatalf("sections add up to %d bytes, want %d", got, len(data))
+		}
+	}
+
+	// Only the media payload may be missing.
+	mdEnd := len(data) - int(16+mustHeader(t, data).Media.PayloadLen)
+	if _, err := DecodeHeader(io.MultiReader(bytes.NewReader(data[:mdEnd+16]))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecodeHeader(io.MultiReader(bytes.NewReader(data[:mdEnd-1]))); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := DecodeHeader(bytes.NewReader([]byte("not an mdocx file at all, really"))); !errors.Is(err, ErrInvalidMagic) {
+		t.Fatalf("expected ErrInvalidMagic, got %v", err)
+	}
+}
+
+func mustHeader(t *testing.T, data []byte) *Header {
+	t.Helper()
+	h, err := DecodeHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
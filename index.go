@@ -0,0 +1,166 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// indexTrailerMagic ends a container with a footer index.
+var indexTrailerMagic = [8]byte{'M', 'D', 'O', 'C', 'X', 'I', 'D', 'X'}
+
+// indexTrailerSize is the size of the trailer after the index section: the
+// little-endian offset of the index section header, then indexTrailerMagic.
+const indexTrailerSize = 16
+
+// footerIndex is the gob payload of the footer index section. Offsets of
+// sections are file offsets of their headers; offsets of media data are
+// relative to the start of the uncompressed Media bundle.
+type footerIndex struct {
+	MarkdownOffset     uint64
+	MediaOffset        uint64
+	MediaBundleVersion uint16
+	Items              []indexItem
+}
+
+// indexItem describes a media item and the location of its data.
+type indexItem struct {
+	ID         string
+	Path       string
+	MIMEType   string
+	SHA256     [32]byte
+	Attributes map[string]string
+	Offset     uint64
+	Length     uint64
+}
+
+// WithIndex makes Encode append a footer index to the container: a trailing
+// section listing the offsets of the Markdown and Media sections and the
+// location of every media item's data, and a 16-byte trailer pointing to it
+// (see rfc.md §7.4). NewReader uses the index to open a container without
+// scanning its Media bundle, so with an uncompressed Media section any item is
+// located in constant time. Readers that do not know the index ignore it.
+// EncodeStream, Writer, and Transcode do not write an index.
+func WithIndex(v bool) WriteOption {
+	return func(c *writeConfig) { c.index = v }
+}
+
+// encodeIndex returns the index section and trailer for a container whose
+// parts are p, where mediaGob is the uncompressed Media bundle.
+func encodeIndex(p *encodedParts, mediaGob []byte, limits Limits) ([]byte, error) {
+	idx := &footerIndex{
+		MarkdownOffset:     uint64(fixedHeaderSizeV1) + uint64(len(p.metadata)),
+		MediaBundleVersion: VersionV1,
+	}
+	idx.MediaOffset = idx.MarkdownOffset + 16 + uint64(len(p.mdPayload))
+	if len(mediaGob) > 0 {
+		scan, err := payloadFormat(p.headerFlags).scanMediaBytes(mediaGob, limits.MaxMediaItems)
+		if err != nil {
+			return nil, err
+		}
+		idx.setItems(scan)
+	}
+	return idx.encode(payloadFormat(p.headerFlags), idx.MediaOffset+16+uint64(len(p.mediaPayload)))
+}
+
+// setItems replaces the item list of idx with the items of scan.
+func (idx *footerIndex) setItems(scan *mediaIndex) {
+	idx.MediaBundleVersion = scan.bundleVersion
+	idx.Items = make([]indexItem, len(scan.items))
+	for i, e := range scan.items {
+		idx.Items[i] = indexItem{
+			ID:         e.ID,
+			Path:       e.Path,
+			MIMEType:   e.MIMEType,
+			SHA256:     e.SHA256,
+			Attributes: e.Attributes,
+			Offset:     uint64(e.dataOff),
+			Length:     uint64(e.dataLen),
+		}
+	}
+}
+
+// encode returns the index section for idx, written at file offset
+// indexOffset, followed by its trailer.
+func (idx *footerIndex) encode(f PayloadFormat, indexOffset uint64) ([]byte, error) {
+	payload, err := f.encodeIndex(*idx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeSectionHeader(&buf, sectionHeaderV1{SectionType: uint16(SectionIndex), PayloadLen: uint64(len(payload))}); err != nil {
+		return nil, err
+	}
+	buf.Write(payload)
+	var trailer [indexTrailerSize]byte
+	binary.LittleEndian.PutUint64(trailer[:8], indexOffset)
+	copy(trailer[8:], indexTrailerMagic[:])
+	buf.Write(trailer[:])
+	return buf.Bytes(), nil
+}
+
+// readIndex reads the footer index of the container of the given size in ra.
+func readIndex(ra io.ReaderAt, size int64, f PayloadFormat, limits Limits) (*footerIndex, error) {
+	if size < int64(fixedHeaderSizeV1)+indexTrailerSize {
+		return nil, fmt.Errorf("%w: container too short for footer index", ErrInvalidSection)
+	}
+	var trailer [indexTrailerSize]byte
+	if _, err := ra.ReadAt(trailer[:], size-indexTrailerSize); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(trailer[8:], indexTrailerMagic[:]) {
+		return nil, fmt.Errorf("%w: footer index trailer not found", ErrInvalidSection)
+	}
+	off := binary.LittleEndian.Uint64(trailer[:8])
+	if off > uint64(size-indexTrailerSize-16) {
+		return nil, fmt.Errorf("%w: footer index offset %d out of range", ErrInvalidSection, off)
+	}
+	sh, err := readSectionHeader(io.NewSectionReader(ra, int64(off), 16))
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSectionHeader(sh, SectionIndex); err != nil {
+		return nil, err
+	}
+	if sh.SectionFlags != 0 || sh.PayloadLen != uint64(size-indexTrailerSize)-off-16 {
+		return nil, fmt.Errorf("%w: malformed footer index section", ErrInvalidSection)
+	}
+	// The index repeats the Media bundle without the data.
+	if sh.PayloadLen > limits.MaxMediaUncompressed {
+		return nil, exceeds("MaxMediaUncompressed", "footer index too large")
+	}
+	payload := make([]byte, sh.PayloadLen)
+	if _, err := ra.ReadAt(payload, int64(off)+16); err != nil {
+		return nil, err
+	}
+	idx, err := f.decodeIndex(payload, limits)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx.Items) > limits.MaxMediaItems {
+		return nil, exceeds("MaxMediaItems", "too many media items")
+	}
+	return &idx, nil
+}
+
+// mediaIndex converts the item list of idx to the form scanMediaGob returns,
+// checking that every item lies within a Media bundle of mediaLen bytes.
+func (idx *footerIndex) mediaIndex(mediaLen int64) (*mediaIndex, error) {
+	out := &mediaIndex{bundleVersion: idx.MediaBundleVersion, items: make([]mediaEntry, len(idx.Items))}
+	for i, it := range idx.Items {
+		if it.Offset > uint64(mediaLen) || it.Length > uint64(mediaLen)-it.Offset {
+			return nil, fmt.Errorf("%w: footer index entry %q out of range", ErrInvalidPayload, it.ID)
+		}
+		out.items[i] = mediaEntry{
+			ID:         it.ID,
+			Path:       it.Path,
+			MIMEType:   it.MIMEType,
+			SHA256:     it.SHA256,
+			Attributes: it.Attributes,
+			dataOff:    int64(it.Offset),
+			dataLen:    int64(it.Length),
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,103 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestIndexRoundTrip(t *testing.T) {
+	for _, comp := range []Compression{CompNone, CompZSTD} {
+		doc := sampleDoc()
+		doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "clip", Path: "media/clip.bin", MIMEType: "application/octet-stream", Data: bytes.Repeat([]byte("x"), 4096)})
+		var plain, indexed bytes.Buffer
+		if err := Encode(&plain, doc, WithMediaCompression(comp)); err != nil {
+			t.Fatal(err)
+		}
+		if err := Encode(&indexed, doc, WithMediaCompression(comp), WithIndex(true)); err != nil {
+			t.Fatal(err)
+		}
+		h, err := DecodeHeader(bytes.NewReader(indexed.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Flags&HeaderFlagIndex == 0 {
+			t.Fatalf("comp %d: INDEX flag not set", comp)
+		}
+		if _, err := Decode(bytes.NewReader(indexed.Bytes())); err != nil {
+			t.Fatalf("comp %d: Decode: %v", comp, err)
+		}
+
+		want, err := NewReader(bytes.NewReader(plain.Bytes()), int64(plain.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewReader(bytes.NewReader(indexed.Bytes()), int64(indexed.Len()))
+		if err != nil {
+			t.Fatalf("comp %d: NewReader: %v", comp, err)
+		}
+		if !reflect.DeepEqual(r.Media(), want.Media()) {
+			t.Fatalf("comp %d: Media() = %+v, want %+v", comp, r.Media(), want.Media())
+		}
+		for _, it := range doc.Media.Items {
+			rc, err := r.OpenMedia(it.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil || !bytes.Equal(data, it.Data) {
+				t.Fatalf("comp %d: %s = %v, %v", comp, it.ID, len(data), err)
+			}
+		}
+	}
+}
+
+func TestIndexCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithMediaCompression(CompNone), WithIndex(true)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	badMagic := bytes.Clone(b)
+	badMagic[len(badMagic)-1] ^= 0xff
+	if _, err := NewReader(bytes.NewReader(badMagic), int64(len(badMagic))); !errors.Is(err, ErrInvalidSection) {
+		t.Fatalf("bad magic: expected ErrInvalidSection, got %v", err)
+	}
+
+	badOffset := bytes.Clone(b)
+	off := binary.LittleEndian.Uint64(badOffset[len(badOffset)-indexTrailerSize:])
+	binary.LittleEndian.PutUint64(badOffset[len(badOffset)-indexTrailerSize:], off-1)
+	if _, err := NewReader(bytes.NewReader(badOffset), int64(len(badOffset))); err == nil {
+		t.Fatal("expected error for shifted index offset")
+	}
+
+	truncated := b[:len(b)-1]
+	if _, err := NewReader(bytes.NewReader(truncated), int64(len(truncated))); err == nil {
+		t.Fatal("expected error for truncated trailer")
+	}
+}
+
+func TestTranscodeDropsIndex(t *testing.T) {
+	var in, out bytes.Buffer
+	if err := Encode(&in, sampleDoc(), WithIndex(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Transcode(&in, &out, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	h, err := DecodeHeader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Flags&HeaderFlagIndex != 0 {
+		t.Fatal("Transcode kept the INDEX flag without the index")
+	}
+	if _, err := NewReader(bytes.NewReader(out.Bytes()), int64(out.Len())); err != nil {
+		t.Fatal(err)
+	}
+}
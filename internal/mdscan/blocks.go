@@ -0,0 +1,573 @@
+package mdscan
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// BlockKind classifies a top-level Markdown block.
+type BlockKind int
+
+const (
+	// BlockParagraph is a paragraph or raw HTML block.
+	BlockParagraph BlockKind = iota
+	// BlockHeading is an ATX or setext heading.
+	BlockHeading
+	// BlockListItem is one item of a bullet or ordered list.
+	BlockListItem
+	// BlockQuote is a run of block-quote lines.
+	BlockQuote
+	// BlockCode is a fenced or indented code block.
+	BlockCode
+	// BlockTable is a pipe table.
+	BlockTable
+)
+
+// Block is a top-level Markdown block in document order.
+type Block struct {
+	Kind BlockKind
+	// Level is the heading level (1-6) for BlockHeading and 0 otherwise.
+	Level int
+	// Text is the block's inline Markdown with markers removed and lines joined
+	// by "\n". For BlockCode it is the raw code; for BlockTable it holds the rows
+	// without the delimiter row.
+	Text string
+	// ID is the explicit {#id} of a heading, if present.
+	ID string
+	// Info is the info string of a fenced code block.
+	Info string
+	// Line is the 1-based line number where the block starts.
+	Line int
+}
+
+// Blocks splits Markdown source into top-level blocks, recognizing every
+// extension. Nested structure inside lists and block quotes is flattened into
+// the text of the enclosing block, and reference definitions and thematic
+// breaks are dropped.
+func Blocks(src []byte) []Block {
+	return BlocksExt(src, ExtAll)
+}
+
+// BlocksExt is Blocks recognizing only the extensions in ext. Without
+// ExtTables, pipe tables are paragraphs.
+func BlocksExt(src []byte, ext Extensions) []Block {
+	var (
+		out      []Block
+		cur      *Block
+		lines    []string
+		fence    []byte
+		indented bool
+	)
+	flush := func() {
+		if cur != nil {
+			cur.Text = strings.Join(lines, "\n")
+			if cur.Kind == BlockCode && indented {
+				cur.Text = strings.TrimRight(cur.Text, "\n")
+			}
+			out = append(out, *cur)
+		}
+		cur, lines, indented = nil, nil, false
+	}
+	start := func(b Block, first string) {
+		flush()
+		cur = &b
+		lines = []string{first}
+	}
+
+	lineNo := 0
+	for len(src) > 0 {
+		lineNo++
+		var line []byte
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			line, src = src[:i], src[i+1:]
+		} else {
+			line, src = src, nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+
+		if fence != nil {
+			if isFenceClose(line, fence) {
+				fence = nil
+				flush()
+			} else {
+				lines = append(lines, string(line))
+			}
+			continue
+		}
+		trimmed := strings.TrimSpace(string(line))
+		ind := leadingSpaces(line)
+		if trimmed == "" {
+			if cur != nil && cur.Kind == BlockCode && indented {
+				lines = append(lines, "")
+				continue
+			}
+			flush()
+			continue
+		}
+		if (ind < 0 || ind >= 4) && (cur == nil || indented) {
+			text := strings.TrimPrefix(string(line), "\t")
+			if ind >= 4 {
+				text = string(line[4:])
+			}
+			if cur == nil {
+				start(Block{Kind: BlockCode, Line: lineNo}, text)
+				indented = true
+			} else {
+				lines = append(lines, text)
+			}
+			continue
+		}
+		if indented {
+			flush()
+		}
+		if f := fenceOpen(line); f != nil {
+			flush()
+			fence = f
+			cur = &Block{Kind: BlockCode, Info: strings.TrimSpace(string(line[ind+len(f):])), Line: lineNo}
+			lines = []string{}
+			continue
+		}
+		if h, ok := atxHeading(line, ext); ok {
+			flush()
+			out = append(out, Block{Kind: BlockHeading, Level: h.Level, Text: h.Text, ID: h.ID, Line: lineNo})
+			continue
+		}
+		if lvl, ok := setextLevel(line); ok && cur != nil && cur.Kind == BlockParagraph {
+			cur.Kind, cur.Level = BlockHeading, lvl
+			text, id := headingID(strings.Join(lines, " "), ext)
+			cur.ID = id
+			lines = []string{text}
+			flush()
+			continue
+		}
+		if isThematicBreak(trimmed) {
+			flush()
+			continue
+		}
+		if cur == nil {
+			if _, ok := definition(line, lineNo); ok {
+				continue
+			}
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			text := strings.TrimSpace(strings.TrimLeft(trimmed, "> "))
+			if cur != nil && cur.Kind == BlockQuote {
+				lines = append(lines, text)
+			} else {
+				start(Block{Kind: BlockQuote, Line: lineNo}, text)
+			}
+			continue
+		}
+		if rest, ok := listItem(trimmed); ok {
+			start(Block{Kind: BlockListItem, Line: lineNo}, rest)
+			continue
+		}
+		if ext&ExtTables != 0 && (strings.HasPrefix(trimmed, "|") || (cur != nil && cur.Kind == BlockTable && strings.Contains(trimmed, "|"))) {
+			if cur == nil || cur.Kind != BlockTable {
+				start(Block{Kind: BlockTable, Line: lineNo}, trimmed)
+			} else if !isTableDelimiter(trimmed) {
+				lines = append(lines, trimmed)
+			}
+			continue
+		}
+		if cur != nil && cur.Kind != BlockCode && cur.Kind != BlockTable {
+			lines = append(lines, trimmed)
+			continue
+		}
+		start(Block{Kind: BlockParagraph, Line: lineNo}, trimmed)
+	}
+	flush()
+	return out
+}
+
+// isThematicBreak reports whether s is a thematic break such as "***" or "- - -".
+func isThematicBreak(s string) bool {
+	if s == "" || !strings.ContainsRune("-*_", rune(s[0])) {
+		return false
+	}
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case s[0]:
+			n++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+// listItem reports whether s starts with a list marker and returns the item text.
+func listItem(s string) (string, bool) {
+	if len(s) >= 2 && strings.ContainsRune("-*+", rune(s[0])) && (s[1] == ' ' || s[1] == '\t') {
+		return strings.TrimSpace(s[2:]), true
+	}
+	i := 0
+	for i < len(s) && i < 9 && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i > 0 && i+1 < len(s) && (s[i] == '.' || s[i] == ')') && (s[i+1] == ' ' || s[i+1] == '\t') {
+		return strings.TrimSpace(s[i+2:]), true
+	}
+	return "", false
+}
+
+// isTableDelimiter reports whether s is a table delimiter row such as "|---|:-:|".
+func isTableDelimiter(s string) bool {
+	return strings.Trim(s, "|:- \t") == "" && strings.Contains(s, "-")
+}
+
+// TableCells splits a pipe-table row into trimmed cell texts.
+func TableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+	cells := strings.Split(row, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// ReplaceImages returns s with every inline image (![alt](dest)) and HTML <img>
+// tag replaced by the result of fn. Images inside code spans are left alone.
+func ReplaceImages(s string, fn func(alt, dest string) string) string {
+	line := []byte(s)
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '`':
+			if j := codeSpanEnd(line, i); j > i {
+				i = j - 1
+			}
+		case '!':
+			if i+1 >= len(line) || line[i+1] != '[' {
+				continue
+			}
+			end := closeBracket(line, i+1)
+			if end < 0 || end+1 >= len(line) || line[end+1] != '(' {
+				continue
+			}
+			dest, stop, ok := inlineDestEnd(line, end+2)
+			if !ok {
+				continue
+			}
+			b.Write(line[last:i])
+			b.WriteString(fn(string(line[i+2:end]), dest))
+			last = stop
+			i = stop - 1
+		case '<':
+			if i+4 >= len(line) || !strings.EqualFold(string(line[i+1:i+4]), "img") {
+				continue
+			}
+			end := bytes.IndexByte(line[i:], '>')
+			if end < 0 {
+				continue
+			}
+			tag := string(line[i+1 : i+end])
+			src, _ := attrValue(tag, "src")
+			alt, _ := attrValue(tag, "alt")
+			b.Write(line[last:i])
+			b.WriteString(fn(alt, src))
+			last = i + end + 1
+			i = last - 1
+		}
+	}
+	if last == 0 {
+		return s
+	}
+	b.Write(line[last:])
+	return b.String()
+}
+
+// SpanKind is the kind of an emphasis span passed to InlineFormatter.Span.
+type SpanKind int
+
+const (
+	// SpanEmphasis is *text* or _text_.
+	SpanEmphasis SpanKind = iota
+	// SpanStrong is **text** or __text__.
+	SpanStrong
+	// SpanStrike is ~~text~~.
+	SpanStrike
+)
+
+// InlineFormatter renders the constructs that FormatInline recognizes in an
+// output format.
+type InlineFormatter interface {
+	// Text renders literal text.
+	Text(s string) string
+	// Code renders the content of a code span.
+	Code(code string) string
+	// Span renders an emphasis span whose content is already rendered.
+	Span(kind SpanKind, inner string) string
+	// Link renders a link or autolink whose text is already rendered.
+	Link(text, dest string) string
+	// Image renders an image with its plain-text alt text.
+	Image(alt, dest string) string
+}
+
+// InlineHTML renders inline Markdown as HTML. It recognizes code spans,
+// emphasis (*, _, **, __), strikethrough (~~), inline links and images, and
+// autolinks; everything else, including raw HTML, is escaped. url maps each
+// link and image destination to the URL written to the output.
+func InlineHTML(s string, url func(dest string) string) string {
+	return FormatInline(s, htmlFormatter{url})
+}
+
+// htmlFormatter is the InlineFormatter of InlineHTML.
+type htmlFormatter struct {
+	url func(dest string) string
+}
+
+func (htmlFormatter) Text(s string) string    { return html.EscapeString(s) }
+func (htmlFormatter) Code(code string) string { return "<code>" + html.EscapeString(code) + "</code>" }
+
+func (htmlFormatter) Span(kind SpanKind, inner string) string {
+	tag := "em"
+	switch kind {
+	case SpanStrike:
+		tag = "del"
+	case SpanStrong:
+		tag = "strong"
+	}
+	return "<" + tag + ">" + inner + "</" + tag + ">"
+}
+
+func (f htmlFormatter) Link(text, dest string) string {
+	return `<a href="` + html.EscapeString(f.url(dest)) + `">` + text + "</a>"
+}
+
+func (f htmlFormatter) Image(alt, dest string) string {
+	return `<img src="` + html.EscapeString(f.url(dest)) + `" alt="` + html.EscapeString(alt) + `">`
+}
+
+// FormatInline renders inline Markdown with f. It recognizes the constructs
+// InlineHTML does; raw HTML and everything else is passed to f.Text.
+func FormatInline(s string, f InlineFormatter) string {
+	var b, text strings.Builder
+	b.Grow(len(s))
+	line := []byte(s)
+	flush := func() {
+		if text.Len() > 0 {
+			b.WriteString(f.Text(text.String()))
+			text.Reset()
+		}
+	}
+	emit := func(out string) {
+		flush()
+		b.WriteString(out)
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '\\':
+			if i+1 < len(line) && isASCIIPunct(line[i+1]) {
+				i++
+				text.WriteByte(line[i])
+				continue
+			}
+			text.WriteByte(c)
+		case '`':
+			n := runLength(line, i)
+			j := codeSpanEnd(line, i)
+			if j == i {
+				text.Write(line[i : i+n])
+				i += n - 1
+				continue
+			}
+			code := string(line[i+n : j-n])
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			emit(f.Code(code))
+			i = j - 1
+		case '*', '_', '~':
+			n := runLength(line, i)
+			m := min(n, 2)
+			if c == '~' && n < 2 {
+				text.WriteByte(c)
+				continue
+			}
+			end := emphasisEnd(line, i, m)
+			if end < 0 {
+				text.Write(line[i : i+n])
+				i += n - 1
+				continue
+			}
+			kind := SpanEmphasis
+			switch {
+			case c == '~':
+				kind = SpanStrike
+			case m == 2:
+				kind = SpanStrong
+			}
+			emit(f.Span(kind, FormatInline(string(line[i+m:end]), f)))
+			i = end + m - 1
+		case '!', '[':
+			open := i
+			if c == '!' {
+				if i+1 >= len(line) || line[i+1] != '[' {
+					text.WriteByte(c)
+					continue
+				}
+				open++
+			}
+			end := closeBracket(line, open)
+			if end < 0 || end+1 >= len(line) || line[end+1] != '(' {
+				text.WriteByte(c)
+				continue
+			}
+			dest, stop, ok := inlineDestEnd(line, end+2)
+			if !ok {
+				text.WriteByte(c)
+				continue
+			}
+			inner := string(line[open+1 : end])
+			if c == '!' {
+				emit(f.Image(InlineText(inner), dest))
+			} else {
+				emit(f.Link(FormatInline(inner, f), dest))
+			}
+			i = stop - 1
+		case '<':
+			j := bytes.IndexByte(line[i:], '>')
+			if j > 1 {
+				inner := string(line[i+1 : i+j])
+				if !strings.ContainsAny(inner, " <") && (strings.Contains(inner, "://") || strings.Contains(inner, "@")) {
+					href := inner
+					if !strings.Contains(inner, "://") {
+						href = "mailto:" + inner
+					}
+					emit(f.Link(f.Text(inner), href))
+					i += j
+					continue
+				}
+			}
+			text.WriteByte(c)
+		default:
+			text.WriteByte(c)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// runLength returns the number of consecutive copies of line[i] starting at i.
+func runLength(line []byte, i int) int {
+	n := 0
+	for i+n < len(line) && line[i+n] == line[i] {
+		n++
+	}
+	return n
+}
+
+// emphasisEnd returns the index of the n delimiters that close the emphasis
+// opened at i, or -1. The closing run must have length n, or at least 3 so it
+// closes nested emphasis too, in which case its last n delimiters are used.
+// An opening run must be followed by non-space text and a closing run preceded
+// by it; '_' runs must not be inside a word.
+func emphasisEnd(line []byte, i, n int) int {
+	c := line[i]
+	if i+n >= len(line) || line[i+n] == ' ' || (c == '_' && i > 0 && isWordByte(line[i-1])) {
+		return -1
+	}
+	for j := i + n + 1; j+n <= len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			if k := codeSpanEnd(line, j); k > j {
+				j = k - 1
+			}
+			continue
+		}
+		if line[j] != c {
+			continue
+		}
+		r := runLength(line, j)
+		if line[j-1] == ' ' || (r != n && r < 3) || (c == '_' && j+r < len(line) && isWordByte(line[j+r])) {
+			j += r - 1
+			continue
+		}
+		return j + r - n
+	}
+	return -1
+}
+
+// isASCIIPunct reports whether b is an ASCII punctuation character, which a
+// backslash escapes.
+func isASCIIPunct(b byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", b) >= 0
+}
+
+// Fence is a fenced code block located in Markdown source.
+type Fence struct {
+	// Info is the info string after the opening fence.
+	Info string
+	// Code is the content between the fences, without the indentation of the
+	// opening fence.
+	Code string
+	// Indent is the indentation of the opening fence.
+	Indent string
+	// Start and End are the byte offsets of the first line of the block and
+	// of the end of its last line, including the line break. An unclosed fence
+	// ends at the end of the source.
+	Start, End int
+	// Line is the 1-based line number of the opening fence.
+	Line int
+}
+
+// Fences returns the fenced code blocks of Markdown source in document order.
+func Fences(src []byte) []Fence {
+	var (
+		out   []Fence
+		cur   Fence
+		code  []string
+		fence []byte
+	)
+	lineNo := 0
+	for off := 0; off < len(src); {
+		lineNo++
+		start := off
+		line := src[off:]
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, off = line[:i], off+i+1
+		} else {
+			off = len(src)
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+
+		if fence != nil {
+			if isFenceClose(line, fence) {
+				cur.Code, cur.End = strings.Join(code, "\n"), off
+				out = append(out, cur)
+				fence = nil
+			} else {
+				// Content lines lose up to the indentation of the fence.
+				n := 0
+				for n < len(cur.Indent) && n < len(line) && line[n] == ' ' {
+					n++
+				}
+				code = append(code, string(line[n:]))
+			}
+			continue
+		}
+		if f := fenceOpen(line); f != nil {
+			ind := leadingSpaces(line)
+			fence, code = f, nil
+			cur = Fence{Info: strings.TrimSpace(string(line[ind+len(f):])), Indent: string(line[:ind]), Start: start, Line: lineNo}
+		}
+	}
+	if fence != nil {
+		cur.Code, cur.End = strings.Join(code, "\n"), len(src)
+		out = append(out, cur)
+	}
+	return out
+}
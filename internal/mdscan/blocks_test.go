@@ -0,0 +1,88 @@
+package mdscan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	src := []byte("# Title {#top}\n\nFirst line\nsecond line.\n\nSetext\n---\n\n- one\n  more\n2. two\n\n> quoted\n> text\n\n```go\nx := 1\n```\n\n    indented\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n***\n[ref]: target.md\n")
+	got := Blocks(src)
+	want := []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Title", ID: "top", Line: 1},
+		{Kind: BlockParagraph, Text: "First line\nsecond line.", Line: 3},
+		{Kind: BlockHeading, Level: 2, Text: "Setext", Line: 6},
+		{Kind: BlockListItem, Text: "one\nmore", Line: 9},
+		{Kind: BlockListItem, Text: "two", Line: 11},
+		{Kind: BlockQuote, Text: "quoted\ntext", Line: 13},
+		{Kind: BlockCode, Text: "x := 1", Info: "go", Line: 16},
+		{Kind: BlockCode, Text: "indented", Line: 20},
+		{Kind: BlockTable, Text: "| a | b |\n| 1 | 2 |", Line: 22},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("blocks:\n got %+v\nwant %+v", got, want)
+	}
+	if cells := TableCells("| a | b |"); !reflect.DeepEqual(cells, []string{"a", "b"}) {
+		t.Fatalf("cells: %q", cells)
+	}
+}
+
+func TestReplaceImages(t *testing.T) {
+	in := "A ![logo](assets/logo.png \"Logo\") and <img src=\"x.png\" alt=\"X\"> but `![no](no.png)`."
+	got := ReplaceImages(in, func(alt, dest string) string { return "{" + alt + "=" + dest + "}" })
+	want := "A {logo=assets/logo.png} and {X=x.png} but `![no](no.png)`."
+	if got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestBlocksExt(t *testing.T) {
+	src := []byte("# Title {#top}\n\n| a | b |\n|---|---|\n")
+	got := BlocksExt(src, FlavorExtensions("commonmark"))
+	want := []Block{
+		{Kind: BlockHeading, Level: 1, Text: "Title {#top}", Line: 1},
+		{Kind: BlockParagraph, Text: "| a | b |\n|---|---|", Line: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("blocks:\n got %+v\nwant %+v", got, want)
+	}
+	if got := BlocksExt(src, FlavorExtensions("gfm")); got[0].ID != "" || got[1].Kind != BlockTable {
+		t.Fatalf("gfm blocks: %+v", got)
+	}
+	if FlavorExtensions("") != ExtAll || FlavorExtensions("djot") != ExtAll {
+		t.Fatal("unexpected extensions for unspecified flavors")
+	}
+}
+
+func TestFences(t *testing.T) {
+	src := "Intro\n\n  ```mermaid\n  graph TD\n  ```\n\n~~~\nopen\n"
+	got := Fences([]byte(src))
+	want := []Fence{
+		{Info: "mermaid", Code: "graph TD", Indent: "  ", Start: 7, End: 37, Line: 3},
+		{Code: "open", Start: 38, End: 47, Line: 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("fences:\n got %+v\nwant %+v", got, want)
+	}
+	if src[got[0].Start:got[0].End] != "  ```mermaid\n  graph TD\n  ```\n" {
+		t.Fatalf("fence bytes %q", src[got[0].Start:got[0].End])
+	}
+}
+
+func TestInlineHTML(t *testing.T) {
+	url := func(dest string) string { return "u:" + dest }
+	cases := map[string]string{
+		"Hello *World* & **bold** ~~gone~~": "Hello <em>World</em> &amp; <strong>bold</strong> <del>gone</del>",
+		"***both*** and *a **b** c*":        "<strong><em>both</em></strong> and <em>a <strong>b</strong> c</em>",
+		"snake_case_name and 2 * 3 * 4":     "snake_case_name and 2 * 3 * 4",
+		"Use `a < b` and `` x ` y ``":       "Use <code>a &lt; b</code> and <code>x ` y</code>",
+		"[*x*](a.md \"T\") ![A *b*](c.png)": `<a href="u:a.md"><em>x</em></a> <img src="u:c.png" alt="A b">`,
+		"<b>raw</b> <https://e.com/?a&b>":   `&lt;b&gt;raw&lt;/b&gt; <a href="u:https://e.com/?a&amp;b">https://e.com/?a&amp;b</a>`,
+		`\*not em\* [unclosed`:              "*not em* [unclosed",
+	}
+	for in, want := range cases {
+		if got := InlineHTML(in, url); got != want {
+			t.Errorf("InlineHTML(%q)\n got %q\nwant %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,705 @@
+// Package mdscan implements a small, allocation-conscious Markdown scanner used by
+// the mdocx package to find links, images, headings, and anchors.
+//
+// It is not a full CommonMark parser. It recognizes the constructs that matter for
+// container-level checks (inline links and images, reference definitions, ATX and
+// setext headings, explicit heading IDs, HTML id/name anchors) and skips fenced
+// code blocks and inline code spans so that examples in code are not reported.
+// Blocks additionally splits a document into top-level blocks for exporters that
+// need plain text in reading order.
+package mdscan
+
+import (
+	"bytes"
+	"strings"
+)
+
+// LinkKind distinguishes how a link destination was written.
+type LinkKind int
+
+const (
+	// KindInline is an inline link or image: [text](dest) or ![alt](dest).
+	KindInline LinkKind = iota
+	// KindDefinition is a reference definition: [label]: dest.
+	KindDefinition
+	// KindHTML is a src or href attribute of a raw HTML tag.
+	KindHTML
+)
+
+// Link is a link or image destination found in Markdown source.
+type Link struct {
+	// Kind reports whether the link is inline or a reference definition.
+	Kind LinkKind
+	// Dest is the destination as written, with angle brackets removed.
+	Dest string
+	// Text is the link text, image alt text, or definition label.
+	Text string
+	// Image reports whether the link is an image (![alt](dest)).
+	Image bool
+	// Line is the 1-based line number of the link.
+	Line int
+	// Column is the 1-based byte column of the opening bracket.
+	Column int
+}
+
+// Ref is a use of a reference label: a full ([text][label]), collapsed
+// ([label][]), or shortcut ([label]) reference link or image, a footnote
+// reference ([^label]), or a footnote definition ([^label]: text).
+type Ref struct {
+	// Label is the label as written, without brackets or the ^ of a footnote.
+	Label string
+	// Footnote reports whether the label names a footnote.
+	Footnote bool
+	// Line is the 1-based line number of the reference.
+	Line int
+	// Column is the 1-based byte column of the opening bracket.
+	Column int
+}
+
+// Heading is an ATX or setext heading.
+type Heading struct {
+	// Level is the heading level (1-6).
+	Level int
+	// Text is the raw inline text of the heading, without markers or an explicit ID.
+	Text string
+	// ID is an explicit identifier written as {#id}, if present.
+	ID string
+	// Line is the 1-based line number of the heading text.
+	Line int
+}
+
+// Extensions selects the syntax extensions to CommonMark that Scan and Blocks
+// recognize. Extensions are combined with |.
+type Extensions uint
+
+const (
+	// ExtTables recognizes pipe tables (GFM). It only affects Blocks.
+	ExtTables Extensions = 1 << iota
+	// ExtHeadingIDs recognizes explicit heading IDs written as {#id} (Pandoc).
+	ExtHeadingIDs
+	// ExtFootnotes recognizes footnote references [^label] and definitions
+	// [^label]: text (GFM). It only affects Scan.
+	ExtFootnotes
+
+	// ExtAll enables every extension. It is used by Scan and Blocks.
+	ExtAll = ExtTables | ExtHeadingIDs | ExtFootnotes
+)
+
+// FlavorExtensions returns the extensions of a Markdown flavor name such as
+// "commonmark", "gfm", "myst", or "pandoc". Unknown and empty names enable
+// every extension.
+func FlavorExtensions(flavor string) Extensions {
+	switch strings.ToLower(flavor) {
+	case "commonmark":
+		return 0
+	case "gfm", "myst":
+		return ExtTables | ExtFootnotes
+	case "pandoc":
+		return ExtAll
+	default:
+		return ExtAll
+	}
+}
+
+// Result holds everything found by Scan.
+type Result struct {
+	Links    []Link
+	Headings []Heading
+	// Anchors lists id and name attribute values found in raw HTML.
+	Anchors []string
+	// Refs lists reference links and footnote references. Shortcut references
+	// are bracketed text that may or may not have a matching definition.
+	Refs []Ref
+	// Footnotes lists footnote definitions.
+	Footnotes []Ref
+}
+
+// Scan scans Markdown source and returns the links, headings, and anchors it
+// contains, recognizing every extension.
+func Scan(src []byte) *Result {
+	return ScanExt(src, ExtAll)
+}
+
+// ScanExt is Scan recognizing only the extensions in ext.
+func ScanExt(src []byte, ext Extensions) *Result {
+	res := &Result{}
+	var fence []byte
+	prevText := ""
+	prevLine := 0
+	lineNo := 0
+	for len(src) > 0 {
+		lineNo++
+		var line []byte
+		if i := bytes.IndexByte(src, '\n'); i >= 0 {
+			line, src = src[:i], src[i+1:]
+		} else {
+			line, src = src, nil
+		}
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+
+		if fence != nil {
+			if isFenceClose(line, fence) {
+				fence = nil
+			}
+			continue
+		}
+		if f := fenceOpen(line); f != nil {
+			fence = f
+			prevText = ""
+			continue
+		}
+
+		trimmed := strings.TrimSpace(string(line))
+		if trimmed == "" {
+			prevText = ""
+			continue
+		}
+		if lvl, ok := setextLevel(line); ok {
+			// Without a preceding paragraph line this is a thematic break.
+			if prevText != "" {
+				text, id := headingID(prevText, ext)
+				res.Headings = append(res.Headings, Heading{Level: lvl, Text: text, ID: id, Line: prevLine})
+			}
+			prevText = ""
+			continue
+		}
+		if h, ok := atxHeading(line, ext); ok {
+			h.Line = lineNo
+			res.Headings = append(res.Headings, h)
+			scanInline(line, lineNo, ext, res)
+			prevText = ""
+			continue
+		}
+		if def, ok := definition(line, lineNo); ok {
+			res.Links = append(res.Links, def)
+			prevText = ""
+			continue
+		}
+		if ext&ExtFootnotes != 0 {
+			if fn, n, ok := footnoteDefinition(line, lineNo); ok {
+				res.Footnotes = append(res.Footnotes, fn)
+				// Scan the footnote text with the label blanked out.
+				rest := append(bytes.Repeat([]byte{' '}, n), line[n:]...)
+				scanInline(rest, lineNo, ext, res)
+				prevText = ""
+				continue
+			}
+		}
+		scanInline(line, lineNo, ext, res)
+		if isParagraphText(line) {
+			prevText = trimmed
+			prevLine = lineNo
+		} else {
+			prevText = ""
+		}
+	}
+	return res
+}
+
+// leadingSpaces returns the number of leading spaces, or -1 if the line starts with a tab.
+func leadingSpaces(line []byte) int {
+	n := 0
+	for n < len(line) && line[n] == ' ' {
+		n++
+	}
+	if n < len(line) && line[n] == '\t' {
+		return -1
+	}
+	return n
+}
+
+// fenceOpen returns the fence marker if line opens a fenced code block.
+func fenceOpen(line []byte) []byte {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 {
+		return nil
+	}
+	rest := line[ind:]
+	if len(rest) < 3 || (rest[0] != '`' && rest[0] != '~') {
+		return nil
+	}
+	c := rest[0]
+	n := 0
+	for n < len(rest) && rest[n] == c {
+		n++
+	}
+	if n < 3 {
+		return nil
+	}
+	if c == '`' && bytes.IndexByte(rest[n:], '`') >= 0 {
+		return nil
+	}
+	return rest[:n]
+}
+
+// isFenceClose reports whether line closes a block opened with fence.
+func isFenceClose(line, fence []byte) bool {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 {
+		return false
+	}
+	rest := bytes.TrimRight(line[ind:], " \t")
+	if len(rest) < len(fence) {
+		return false
+	}
+	for _, b := range rest {
+		if b != fence[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// setextLevel reports whether line is a setext underline and returns the heading level.
+func setextLevel(line []byte) (int, bool) {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 {
+		return 0, false
+	}
+	rest := bytes.TrimRight(line[ind:], " \t")
+	if len(rest) == 0 || (rest[0] != '=' && rest[0] != '-') {
+		return 0, false
+	}
+	for _, b := range rest {
+		if b != rest[0] {
+			return 0, false
+		}
+	}
+	if rest[0] == '=' {
+		return 1, true
+	}
+	return 2, true
+}
+
+// atxHeading parses an ATX heading line such as "## Title ##".
+func atxHeading(line []byte, ext Extensions) (Heading, bool) {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 {
+		return Heading{}, false
+	}
+	rest := line[ind:]
+	lvl := 0
+	for lvl < len(rest) && rest[lvl] == '#' {
+		lvl++
+	}
+	if lvl == 0 || lvl > 6 {
+		return Heading{}, false
+	}
+	if lvl < len(rest) && rest[lvl] != ' ' && rest[lvl] != '\t' {
+		return Heading{}, false
+	}
+	text := strings.TrimSpace(string(rest[lvl:]))
+	// Strip an optional closing sequence of '#' preceded by a space.
+	if t := strings.TrimRight(text, "#"); t != text && (t == "" || strings.HasSuffix(t, " ") || strings.HasSuffix(t, "\t")) {
+		text = strings.TrimSpace(t)
+	}
+	text, id := headingID(text, ext)
+	return Heading{Level: lvl, Text: text, ID: id}, true
+}
+
+// headingID separates an explicit ID from heading text if ext allows it.
+func headingID(text string, ext Extensions) (string, string) {
+	if ext&ExtHeadingIDs == 0 {
+		return text, ""
+	}
+	return splitExplicitID(text)
+}
+
+// splitExplicitID separates a trailing {#id} attribute from heading text.
+func splitExplicitID(text string) (string, string) {
+	if !strings.HasSuffix(text, "}") {
+		return text, ""
+	}
+	open := strings.LastIndex(text, "{")
+	if open < 0 {
+		return text, ""
+	}
+	for _, attr := range strings.Fields(text[open+1 : len(text)-1]) {
+		if strings.HasPrefix(attr, "#") && len(attr) > 1 {
+			return strings.TrimSpace(text[:open]), attr[1:]
+		}
+	}
+	return text, ""
+}
+
+// isParagraphText reports whether line can be the content line of a setext heading.
+func isParagraphText(line []byte) bool {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 {
+		return false
+	}
+	rest := line[ind:]
+	switch rest[0] {
+	case '>', '<', '|':
+		return false
+	case '-', '*', '+':
+		return len(rest) > 1 && rest[1] != ' ' && rest[1] != '\t'
+	}
+	return true
+}
+
+// definition parses a reference definition line such as `[label]: dest "title"`.
+func definition(line []byte, lineNo int) (Link, bool) {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 || ind >= len(line) || line[ind] != '[' {
+		return Link{}, false
+	}
+	end := closeBracket(line, ind)
+	if end < 0 || end+1 >= len(line) || line[end+1] != ':' {
+		return Link{}, false
+	}
+	label := string(line[ind+1 : end])
+	if strings.TrimSpace(label) == "" || strings.HasPrefix(label, "^") {
+		return Link{}, false
+	}
+	rest := strings.TrimSpace(string(line[end+2:]))
+	if rest == "" {
+		return Link{}, false
+	}
+	var dest string
+	if rest[0] == '<' {
+		i := strings.IndexByte(rest, '>')
+		if i < 0 {
+			return Link{}, false
+		}
+		dest = rest[1:i]
+	} else {
+		dest = rest
+		if i := strings.IndexAny(rest, " \t"); i >= 0 {
+			dest = rest[:i]
+		}
+	}
+	return Link{Kind: KindDefinition, Dest: dest, Text: label, Line: lineNo, Column: ind + 1}, true
+}
+
+// footnoteDefinition parses the start of a footnote definition such as
+// "[^label]: text" and returns the length of its "[^label]:" prefix.
+func footnoteDefinition(line []byte, lineNo int) (Ref, int, bool) {
+	ind := leadingSpaces(line)
+	if ind < 0 || ind > 3 || ind+2 >= len(line) || line[ind] != '[' || line[ind+1] != '^' {
+		return Ref{}, 0, false
+	}
+	end := closeBracket(line, ind)
+	if end < 0 || end+1 >= len(line) || line[end+1] != ':' {
+		return Ref{}, 0, false
+	}
+	label := string(line[ind+2 : end])
+	if strings.TrimSpace(label) == "" {
+		return Ref{}, 0, false
+	}
+	return Ref{Label: label, Footnote: true, Line: lineNo, Column: ind + 1}, end + 2, true
+}
+
+// NormalizeLabel returns the form of a reference label used for matching:
+// labels match case-insensitively, with runs of white space collapsed.
+func NormalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// closeBracket returns the index of the ']' matching the '[' at open, or -1.
+func closeBracket(line []byte, open int) int {
+	depth := 0
+	for i := open; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '`':
+			if j := codeSpanEnd(line, i); j > i {
+				i = j - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// codeSpanEnd returns the index just past the inline code span starting at i,
+// or i if the backtick run at i is not closed on this line.
+func codeSpanEnd(line []byte, i int) int {
+	n := 0
+	for i+n < len(line) && line[i+n] == '`' {
+		n++
+	}
+	for j := i + n; j < len(line); {
+		if line[j] != '`' {
+			j++
+			continue
+		}
+		m := 0
+		for j+m < len(line) && line[j+m] == '`' {
+			m++
+		}
+		if m == n {
+			return j + m
+		}
+		j += m
+	}
+	return i
+}
+
+// scanInline finds inline links, images, references, and HTML anchors in a
+// single line.
+func scanInline(line []byte, lineNo int, ext Extensions, res *Result) {
+	// label is the index of the [label] part of the last full reference, which
+	// is not a reference of its own.
+	label := -1
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '\\':
+			i++
+		case '`':
+			if j := codeSpanEnd(line, i); j > i {
+				i = j - 1
+			} else {
+				for i+1 < len(line) && line[i+1] == '`' {
+					i++
+				}
+			}
+		case '<':
+			scanHTMLTag(line, i, lineNo, res)
+		case '[':
+			image := i > 0 && line[i-1] == '!' && (i < 2 || line[i-2] != '\\')
+			end := closeBracket(line, i)
+			if end < 0 || i == label {
+				continue
+			}
+			col := i + 1
+			if image {
+				col = i
+			}
+			if end+1 >= len(line) || line[end+1] != '(' {
+				scanRef(line, i, end, lineNo, col, ext, res)
+				if end+1 < len(line) && line[end+1] == '[' {
+					label = end + 1
+				}
+				continue
+			}
+			dest, ok := inlineDest(line, end+2)
+			if !ok {
+				continue
+			}
+			res.Links = append(res.Links, Link{
+				Kind:   KindInline,
+				Dest:   dest,
+				Text:   string(line[i+1 : end]),
+				Image:  image,
+				Line:   lineNo,
+				Column: col,
+			})
+			// Continue inside the brackets so nested images are found.
+		}
+	}
+}
+
+// scanRef records the reference whose first brackets span line[open:end+1].
+func scanRef(line []byte, open, end, lineNo, col int, ext Extensions, res *Result) {
+	text := string(line[open+1 : end])
+	if end+1 < len(line) && line[end+1] == '[' {
+		if e := closeBracket(line, end+1); e > end+1 {
+			text = string(line[end+2 : e])
+			if strings.TrimSpace(text) == "" {
+				text = string(line[open+1 : end])
+			}
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	ref := Ref{Label: text, Line: lineNo, Column: col}
+	if ext&ExtFootnotes != 0 && strings.HasPrefix(text, "^") && len(text) > 1 {
+		ref.Label, ref.Footnote = text[1:], true
+	}
+	res.Refs = append(res.Refs, ref)
+}
+
+// inlineDest parses the destination of an inline link starting just after '('.
+func inlineDest(line []byte, start int) (string, bool) {
+	dest, _, ok := inlineDestEnd(line, start)
+	return dest, ok
+}
+
+// inlineDestEnd is like inlineDest and also returns the index just past the
+// closing ')' of the link.
+func inlineDestEnd(line []byte, start int) (string, int, bool) {
+	i := start
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	if i < len(line) && line[i] == '<' {
+		j := bytes.IndexByte(line[i+1:], '>')
+		if j < 0 {
+			return "", 0, false
+		}
+		dest := string(line[i+1 : i+1+j])
+		k := bytes.IndexByte(line[i+1+j:], ')')
+		if k < 0 {
+			return dest, len(line), true
+		}
+		return dest, i + 1 + j + k + 1, true
+	}
+	depth := 0
+	begin := i
+	for ; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return string(line[begin:i]), i + 1, true
+			}
+			depth--
+		case ' ', '\t':
+			if depth == 0 {
+				// Remaining text is an optional title; require a closing paren.
+				k := bytes.IndexByte(line[i:], ')')
+				if k < 0 {
+					return "", 0, false
+				}
+				return string(line[begin:i]), i + k + 1, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// scanHTMLTag records anchors (id, name) and link destinations (src, href)
+// of an HTML tag starting at i.
+func scanHTMLTag(line []byte, i, lineNo int, res *Result) {
+	end := bytes.IndexByte(line[i:], '>')
+	if end < 0 || i+1 >= len(line) || !isASCIILetter(line[i+1]) {
+		return
+	}
+	tag := string(line[i+1 : i+end])
+	for _, attr := range []string{"id", "name"} {
+		if v, ok := attrValue(tag, attr); ok && v != "" {
+			res.Anchors = append(res.Anchors, v)
+		}
+	}
+	for _, attr := range []string{"src", "href"} {
+		if v, ok := attrValue(tag, attr); ok && v != "" {
+			alt, _ := attrValue(tag, "alt")
+			res.Links = append(res.Links, Link{
+				Kind:   KindHTML,
+				Dest:   v,
+				Text:   alt,
+				Image:  attr == "src",
+				Line:   lineNo,
+				Column: i + 1,
+			})
+		}
+	}
+}
+
+// attrValue extracts the value of attribute name from the inside of an HTML tag.
+func attrValue(tag, name string) (string, bool) {
+	lower := strings.ToLower(tag)
+	for from := 0; ; {
+		k := strings.Index(lower[from:], name)
+		if k < 0 {
+			return "", false
+		}
+		k += from
+		from = k + len(name)
+		if k == 0 || (lower[k-1] != ' ' && lower[k-1] != '\t') {
+			continue
+		}
+		rest := strings.TrimLeft(tag[from:], " \t")
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t")
+		if rest == "" {
+			return "", false
+		}
+		if q := rest[0]; q == '"' || q == '\'' {
+			if e := strings.IndexByte(rest[1:], q); e >= 0 {
+				return rest[1 : 1+e], true
+			}
+			return "", false
+		}
+		if e := strings.IndexAny(rest, " \t/>"); e >= 0 {
+			return rest[:e], true
+		}
+		return rest, true
+	}
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// InlineText returns the visible text of inline Markdown, dropping emphasis markers,
+// code backticks, link destinations, and HTML tags. Image alt text is kept.
+func InlineText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	line := []byte(s)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				b.WriteByte(line[i])
+			}
+		case '*', '_', '~':
+			// Emphasis and strikethrough markers inside words are kept for '_'.
+			if c == '_' && i > 0 && i+1 < len(line) && isWordByte(line[i-1]) && isWordByte(line[i+1]) {
+				b.WriteByte(c)
+			}
+		case '`':
+			n := 0
+			for i+n < len(line) && line[i+n] == '`' {
+				n++
+			}
+			i += n - 1
+		case '!':
+			if i+1 < len(line) && line[i+1] == '[' {
+				continue
+			}
+			b.WriteByte(c)
+		case '[':
+			end := closeBracket(line, i)
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(InlineText(string(line[i+1 : end])))
+			i = end
+			if i+1 < len(line) && line[i+1] == '(' {
+				if j := bytes.IndexByte(line[i+1:], ')'); j >= 0 {
+					i += 1 + j
+				}
+			} else if i+1 < len(line) && line[i+1] == '[' {
+				if j := bytes.IndexByte(line[i+1:], ']'); j >= 0 {
+					i += 1 + j
+				}
+			}
+		case '<':
+			if j := bytes.IndexByte(line[i:], '>'); j > 0 && i+1 < len(line) && (isASCIILetter(line[i+1]) || line[i+1] == '/') {
+				inner := string(line[i+1 : i+j])
+				if strings.Contains(inner, "://") || strings.Contains(inner, "@") && !strings.Contains(inner, " ") {
+					b.WriteString(inner) // autolink
+				}
+				i += j
+				continue
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func isWordByte(b byte) bool {
+	return b >= 0x80 || isASCIILetter(b) || (b >= '0' && b <= '9')
+}
@@ -0,0 +1,111 @@
+package mdscan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanLinks(t *testing.T) {
+	src := []byte("See [other](other.md#intro) and ![logo](assets/logo.png \"Logo\").\n" +
+		"Code `[x](nope.md)` is skipped, as is \\[y](nope.md).\n" +
+		"```\n[z](nope.md)\n```\n" +
+		"[![badge](b.svg)](target.md)\n" +
+		"[ref]: <docs/ref.md> \"Title\"\n")
+	res := Scan(src)
+	var got []string
+	for _, l := range res.Links {
+		got = append(got, l.Dest)
+	}
+	want := []string{"other.md#intro", "assets/logo.png", "target.md", "b.svg", "docs/ref.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("links: got %q want %q", got, want)
+	}
+	if !res.Links[1].Image || res.Links[0].Image {
+		t.Fatal("image flag mismatch")
+	}
+	if res.Links[0].Line != 1 || res.Links[0].Column != 5 {
+		t.Fatalf("position: %d:%d", res.Links[0].Line, res.Links[0].Column)
+	}
+	if res.Links[4].Kind != KindDefinition || res.Links[4].Text != "ref" {
+		t.Fatalf("definition: %+v", res.Links[4])
+	}
+}
+
+func TestScanHeadings(t *testing.T) {
+	src := []byte("# Title #\n\nSetext One\n==========\n\n## Custom {#my-id}\n\n---\n\n```\n# not a heading\n```\n#nospace\n<a id=\"anchor\"></a>\n")
+	res := Scan(src)
+	want := []Heading{
+		{Level: 1, Text: "Title", Line: 1},
+		{Level: 1, Text: "Setext One", Line: 3},
+		{Level: 2, Text: "Custom", ID: "my-id", Line: 6},
+	}
+	if !reflect.DeepEqual(res.Headings, want) {
+		t.Fatalf("headings: got %+v want %+v", res.Headings, want)
+	}
+	if !reflect.DeepEqual(res.Anchors, []string{"anchor"}) {
+		t.Fatalf("anchors: %q", res.Anchors)
+	}
+}
+
+func TestScanHTMLLinks(t *testing.T) {
+	res := Scan([]byte(`Inline <img alt="Logo" src="assets/logo.png"> and <a href='doc.md'>doc</a>`))
+	if len(res.Links) != 2 {
+		t.Fatalf("expected 2 links, got %+v", res.Links)
+	}
+	img := res.Links[0]
+	if img.Kind != KindHTML || !img.Image || img.Dest != "assets/logo.png" || img.Text != "Logo" || img.Column != 8 {
+		t.Fatalf("unexpected img link: %+v", img)
+	}
+	if res.Links[1].Image || res.Links[1].Dest != "doc.md" {
+		t.Fatalf("unexpected anchor link: %+v", res.Links[1])
+	}
+}
+
+func TestInlineText(t *testing.T) {
+	cases := map[string]string{
+		"Hello *World*":              "Hello World",
+		"Use `code` here":            "Use code here",
+		"A [link](x.md) and ![i](y)": "A link and i",
+		"snake_case_name":            "snake_case_name",
+		"<b>bold</b>":                "bold",
+	}
+	for in, want := range cases {
+		if got := InlineText(in); got != want {
+			t.Fatalf("InlineText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestScanRefs(t *testing.T) {
+	src := []byte("A [full][Label] ref, a [collapsed][] one, and a [shortcut].\n" +
+		"Inline [links](x.md) are not refs; a note[^1] and ![img][pic].\n" +
+		"- [ ] task\n" +
+		"[^1]: Note with [another][x].\n")
+	res := Scan(src)
+	var got []string
+	for _, r := range res.Refs {
+		if r.Footnote {
+			got = append(got, "^"+r.Label)
+		} else {
+			got = append(got, r.Label)
+		}
+	}
+	want := []string{"Label", "collapsed", "shortcut", "^1", "pic", "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("refs: got %q want %q", got, want)
+	}
+	if res.Refs[0].Column != 3 || res.Refs[4].Column != 51 {
+		t.Fatalf("columns: %d, %d", res.Refs[0].Column, res.Refs[4].Column)
+	}
+	if len(res.Footnotes) != 1 || res.Footnotes[0].Label != "1" || res.Footnotes[0].Line != 4 {
+		t.Fatalf("footnotes: %+v", res.Footnotes)
+	}
+
+	plain := ScanExt(src, FlavorExtensions("commonmark"))
+	if len(plain.Footnotes) != 0 || plain.Refs[3].Footnote || plain.Refs[3].Label != "^1" {
+		t.Fatalf("commonmark: %+v %+v", plain.Refs, plain.Footnotes)
+	}
+	if NormalizeLabel("  Foo \t Bar ") != "foo bar" {
+		t.Fatal("NormalizeLabel")
+	}
+}
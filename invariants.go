@@ -0,0 +1,141 @@
+package mdocx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Check selects optional invariants that are not required by the format but
+// may be enforced on request (see rfc.md §7.3). Checks are combined with |.
+type Check uint
+
+const (
+	// CheckMediaOrder requires media items to be sorted by ID in ascending
+	// byte-wise order.
+	CheckMediaOrder Check = 1 << iota
+	// CheckMediaRefs requires every MarkdownFile.MediaRefs entry to be the ID
+	// of a media item in the document.
+	CheckMediaRefs
+	// CheckImageLinks requires every image in Markdown content, written as
+	// ![alt](dest) or <img src>, to reference a media item by an
+	// mdocx://media/<ID> URI or a relative path that resolves to the Path of a
+	// media item or Markdown file.
+	CheckImageLinks
+	// CheckOrphanMedia requires every media item to be referenced by some
+	// Markdown file, as reported by MediaUsage.
+	CheckOrphanMedia
+	// CheckPublished requires every Markdown file to have status StatusFinal
+	// (see Status), so that a published bundle contains no drafts or files
+	// still in review. It is not part of CheckAll, since it is an editorial
+	// convention rather than a property of the content.
+	CheckPublished
+
+	// CheckCrossRefs enables the checks that references between Markdown
+	// files and media items resolve in both directions.
+	CheckCrossRefs = CheckMediaRefs | CheckImageLinks | CheckOrphanMedia
+	// CheckAll enables every optional invariant.
+	CheckAll = CheckMediaOrder | CheckCrossRefs
+
+	// checksScanContent are the checks that read Markdown content.
+	checksScanContent = CheckImageLinks | CheckOrphanMedia
+)
+
+// CheckInvariants reports whether d satisfies the optional invariants selected
+// by checks. The first violation is reported as a *ValidationError wrapping
+// ErrValidation.
+func (d *Document) CheckInvariants(checks Check) error {
+	if errs := d.invariantErrors(checks); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// invariantErrors returns every violation of the optional invariants selected
+// by checks, grouped by check in declaration order.
+func (d *Document) invariantErrors(checks Check) ValidationErrors {
+	var errs ValidationErrors
+	add := func(code ErrorCode, path, field, value, format string, args ...any) {
+		errs = append(errs, newValidationError(code, path, field, value, format, args...))
+	}
+	items := d.Media.Items
+	if checks&CheckMediaOrder != 0 {
+		for i := 1; i < len(items); i++ {
+			if items[i].ID < items[i-1].ID {
+				add(CodeMediaOrder, items[i].Path, fmt.Sprintf("Media.Items[%d].ID", i), items[i].ID, "media item %q is out of order (after %q)", items[i].ID, items[i-1].ID)
+			}
+		}
+	}
+	byID := make(map[string]struct{}, len(items))
+	byPath := make(map[string]struct{}, len(items)+len(d.Markdown.Files))
+	for _, it := range items {
+		byID[it.ID] = struct{}{}
+		if it.Path != "" {
+			byPath[it.Path] = struct{}{}
+		}
+	}
+	if checks&CheckMediaRefs != 0 {
+		for i, f := range d.Markdown.Files {
+			for j, ref := range f.MediaRefs {
+				if _, ok := byID[ref]; !ok {
+					add(CodeUnknownMediaRef, f.Path, fmt.Sprintf("Markdown.Files[%d].MediaRefs[%d]", i, j), ref, "references unknown media ID %q", ref)
+				}
+			}
+		}
+	}
+	if checks&CheckImageLinks != 0 {
+		for _, f := range d.Markdown.Files {
+			byPath[f.Path] = struct{}{}
+		}
+		for i, f := range d.Markdown.Files {
+			for _, l := range d.scanFile(f).Links {
+				if !l.Image {
+					continue
+				}
+				if reason := unresolvedImage(f.Path, l.Dest, byID, byPath); reason != "" {
+					add(CodeUnresolvedImage, f.Path, fmt.Sprintf("Markdown.Files[%d].Content", i), l.Dest, "%d:%d: image %q %s", l.Line, l.Column, l.Dest, reason)
+				}
+			}
+		}
+	}
+	if checks&CheckOrphanMedia != 0 {
+		uses := d.MediaUsage().Uses
+		for i, it := range items {
+			if len(uses[it.ID]) == 0 {
+				add(CodeOrphanMedia, it.Path, fmt.Sprintf("Media.Items[%d]", i), it.ID, "media item %q is not referenced", it.ID)
+			}
+		}
+	}
+	if checks&CheckPublished != 0 {
+		for i, f := range d.Markdown.Files {
+			if st := f.Status(); st != StatusFinal {
+				add(CodeNotFinal, f.Path, fmt.Sprintf("Markdown.Files[%d].Attributes[%q]", i, StatusAttr), string(st), "file has status %q, not %q", st, StatusFinal)
+			}
+		}
+	}
+	return errs
+}
+
+// unresolvedImage returns why the image destination dest in the Markdown file
+// at from does not resolve to a known media ID or container path, or "" if it
+// does or is not a container reference.
+func unresolvedImage(from, dest string, byID, byPath map[string]struct{}) string {
+	dest = strings.TrimSpace(dest)
+	if id, ok := mediaIDFromURI(dest); ok {
+		if _, known := byID[id]; !known {
+			return fmt.Sprintf("references unknown media ID %q", id)
+		}
+		return ""
+	}
+	target, _, ok := splitRelativeLink(dest)
+	if !ok || target == "" {
+		return ""
+	}
+	resolved, err := resolveContainerLink(from, target)
+	if err != nil {
+		return "escapes the container"
+	}
+	if _, known := byPath[resolved]; !known {
+		return fmt.Sprintf("does not resolve to a media item or Markdown file (%q)", resolved)
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// unorderedDoc returns a valid document whose media items are not sorted by ID
+// and whose first file references a missing item.
+func unorderedDoc() *Document {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "banner", MIMEType: "image/png", Data: []byte{4}})
+	doc.Markdown.Files[0].MediaRefs = []string{"logo", "missing"}
+	return doc
+}
+
+func TestCheckInvariants(t *testing.T) {
+	doc := unorderedDoc()
+	if err := doc.CheckInvariants(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.CheckInvariants(CheckMediaOrder); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected order violation, got %v", err)
+	}
+	if err := doc.CheckInvariants(CheckMediaRefs); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ref violation, got %v", err)
+	}
+	doc.Media.Items[0], doc.Media.Items[1] = doc.Media.Items[1], doc.Media.Items[0]
+	doc.Markdown.Files[0].MediaRefs = []string{"banner", "logo"}
+	if err := doc.CheckInvariants(CheckAll); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksOptions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, unorderedDoc(), WithChecksOnWrite(CheckMediaOrder)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("Encode: expected ErrValidation, got %v", err)
+	}
+	files, media := feed(unorderedDoc())
+	if err := EncodeStream(context.Background(), &buf, StreamHeader{}, files, media, WithChecksOnWrite(CheckMediaRefs)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("EncodeStream: expected ErrValidation, got %v", err)
+	}
+
+	buf.Reset()
+	if err := Encode(&buf, unorderedDoc()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("checks must be opt-in: %v", err)
+	}
+	for _, c := range []Check{CheckMediaOrder, CheckMediaRefs} {
+		if _, err := Decode(bytes.NewReader(data), WithChecks(c)); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Decode(%d): expected ErrValidation, got %v", c, err)
+		}
+		if err := DecodeInto(bytes.NewReader(data), SinkFuncs{}, WithChecks(c)); !errors.Is(err, ErrValidation) {
+			t.Fatalf("DecodeInto(%d): expected ErrValidation, got %v", c, err)
+		}
+	}
+	path := writeTempContainer(t, unorderedDoc())
+	if _, err := OpenMapped(path, WithChecks(CheckAll)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("OpenMapped: expected ErrValidation, got %v", err)
+	}
+}
+
+// crossRefDoc returns a valid document with a broken relative image, an image
+// of an unknown media ID, and an orphaned media item.
+func crossRefDoc() *Document {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("![ok](../assets/logo.png) ![page](index.md)\n" +
+		"![gone](img/gone.png)\n" +
+		"<img src=\"mdocx://media/nope\"> [not an image](missing.pdf)\n")
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "spare", Path: "assets/spare.png", MIMEType: "image/png", Data: []byte{4}})
+	return doc
+}
+
+func TestCheckCrossRefs(t *testing.T) {
+	doc := crossRefDoc()
+	if err := doc.CheckInvariants(CheckMediaRefs); err != nil {
+		t.Fatal(err)
+	}
+	err := Validate(doc, WithValidateChecks(CheckCrossRefs), WithAllErrors())
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v", err)
+	}
+	want := []string{
+		`mdocx: validation failed: Markdown.Files[1].Content (docs/notes.md): 2:1: image "img/gone.png" does not resolve to a media item or Markdown file ("docs/img/gone.png")`,
+		`mdocx: validation failed: Markdown.Files[1].Content (docs/notes.md): 3:1: image "mdocx://media/nope" references unknown media ID "nope"`,
+		`mdocx: validation failed: Media.Items[1] (assets/spare.png): media item "spare" is not referenced`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors: %v", len(errs), errs.Unwrap())
+	}
+	for i := range want {
+		if errs[i].Error() != want[i] {
+			t.Fatalf("error %d:\n got %s\nwant %s", i, errs[i], want[i])
+		}
+	}
+
+	for _, c := range []Check{CheckImageLinks, CheckOrphanMedia} {
+		if err := Encode(io.Discard, crossRefDoc(), WithChecksOnWrite(c)); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Encode(%d): got %v", c, err)
+		}
+		files, media := feed(crossRefDoc())
+		if err := EncodeStream(context.Background(), io.Discard, StreamHeader{}, files, media, WithChecksOnWrite(c)); !errors.Is(err, ErrValidation) {
+			t.Fatalf("EncodeStream(%d): got %v", c, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, crossRefDoc()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	keepNone := WithMediaFilter(func(_, _, _ string, _ int64) bool { return false })
+	for _, c := range []Check{CheckImageLinks, CheckOrphanMedia} {
+		if _, err := Decode(bytes.NewReader(data), WithChecks(c), keepNone); !errors.Is(err, ErrValidation) {
+			t.Fatalf("Decode(%d): got %v", c, err)
+		}
+		if err := DecodeInto(bytes.NewReader(data), SinkFuncs{}, WithChecks(c)); !errors.Is(err, ErrValidation) {
+			t.Fatalf("DecodeInto(%d): got %v", c, err)
+		}
+	}
+
+	// Fixing the references satisfies every check.
+	doc = crossRefDoc()
+	doc.Markdown.Files[1].Content = []byte("![ok](../assets/logo.png) ![spare](mdocx://media/spare)\n")
+	doc.Markdown.Files[0].MediaRefs = []string{"logo"}
+	if err := Validate(doc, WithValidateChecks(CheckAll), WithAllErrors()); err != nil {
+		t.Fatal(err)
+	}
+}
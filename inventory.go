@@ -0,0 +1,171 @@
+package mdocx
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Inventory entry kinds.
+const (
+	InventoryContainer = "container"
+	InventoryMarkdown  = "markdown"
+	InventoryMedia     = "media"
+)
+
+// InventoryEntry records fixity information for a container or one of its parts.
+type InventoryEntry struct {
+	// Container is the slash-separated path of the container file, relative to the
+	// directory passed to InventoryDir. It is empty for Document.Inventory.
+	Container string `json:"container,omitempty"`
+	// Kind is InventoryContainer, InventoryMarkdown, or InventoryMedia.
+	Kind string `json:"kind"`
+	// Path is the container path of a Markdown file or media item.
+	Path string `json:"path,omitempty"`
+	// ID is the media item ID (media entries only).
+	ID string `json:"id,omitempty"`
+	// Size is the content length in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the lowercase hex SHA-256 of the content.
+	SHA256 string `json:"sha256"`
+}
+
+// Inventory returns fixity entries for every Markdown file and media item in d,
+// sorted by kind (Markdown first), then path, then ID. Hashes are computed from
+// the content, not taken from stored MediaItem.SHA256 values.
+func (d *Document) Inventory() []InventoryEntry {
+	out := make([]InventoryEntry, 0, len(d.Markdown.Files)+len(d.Media.Items))
+	for _, f := range d.Markdown.Files {
+		sum := sha256.Sum256(f.Content)
+		out = append(out, InventoryEntry{Kind: InventoryMarkdown, Path: f.Path, Size: int64(len(f.Content)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	for _, it := range d.Media.Items {
+		sum := it.computedSHA256()
+		out = append(out, InventoryEntry{Kind: InventoryMedia, Path: it.Path, ID: it.ID, Size: int64(len(it.Data)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	sortInventory(out)
+	return out
+}
+
+// InventoryDir walks dir recursively and returns inventory entries for every file
+// with an ".mdocx" extension: one InventoryContainer entry for the file itself
+// followed by the entries of its decoded content. Each file is read only once.
+// The first file that fails to open or decode stops the walk and its error is
+// returned, annotated with the file path.
+func InventoryDir(dir string, opts ...ReadOption) ([]InventoryEntry, error) {
+	var out []InventoryEntry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(p), FileExtension) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		entries, err := inventoryFile(p, filepath.ToSlash(rel), opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		out = append(out, entries...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortInventory(out)
+	return out, nil
+}
+
+// inventoryFile decodes one container while hashing its bytes.
+func inventoryFile(p, rel string, opts []ReadOption) ([]InventoryEntry, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	cr := &countingReader{r: io.TeeReader(f, h)}
+	doc, err := Decode(bufio.NewReader(cr), opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Hash any bytes Decode did not consume so the digest covers the whole file.
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		return nil, err
+	}
+	entries := doc.Inventory()
+	for i := range entries {
+		entries[i].Container = rel
+	}
+	container := InventoryEntry{Container: rel, Kind: InventoryContainer, Size: cr.n, SHA256: hex.EncodeToString(h.Sum(nil))}
+	return append([]InventoryEntry{container}, entries...), nil
+}
+
+// WriteInventoryCSV writes entries as CSV with the header
+// "container,kind,path,id,size,sha256".
+func WriteInventoryCSV(w io.Writer, entries []InventoryEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"container", "kind", "path", "id", "size", "sha256"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{e.Container, e.Kind, e.Path, e.ID, strconv.FormatInt(e.Size, 10), e.SHA256}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteInventoryJSON writes entries as an indented JSON array.
+func WriteInventoryJSON(w io.Writer, entries []InventoryEntry) error {
+	if entries == nil {
+		entries = []InventoryEntry{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// sortInventory orders entries by container, kind, path, and ID.
+func sortInventory(entries []InventoryEntry) {
+	rank := map[string]int{InventoryContainer: 0, InventoryMarkdown: 1, InventoryMedia: 2}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Container != b.Container {
+			return a.Container < b.Container
+		}
+		if a.Kind != b.Kind {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.ID < b.ID
+	})
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
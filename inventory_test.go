@@ -0,0 +1,86 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocumentInventory(t *testing.T) {
+	doc := sampleDoc()
+	inv := doc.Inventory()
+	if len(inv) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(inv))
+	}
+	if inv[0].Kind != InventoryMarkdown || inv[0].Path != "docs/index.md" || inv[2].Kind != InventoryMedia || inv[2].ID != "logo" {
+		t.Fatalf("unexpected order: %+v", inv)
+	}
+	sum := sha256.Sum256([]byte{1, 2, 3})
+	if inv[2].SHA256 != hex.EncodeToString(sum[:]) || inv[2].Size != 3 {
+		t.Fatalf("unexpected media entry: %+v", inv[2])
+	}
+}
+
+func TestInventoryDir(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("trailing")
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.mdocx", "sub/a.MDOCX"} {
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ignored.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := InventoryDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv) != 8 {
+		t.Fatalf("expected 8 entries, got %d", len(inv))
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if inv[0].Container != "b.mdocx" || inv[0].Kind != InventoryContainer || inv[0].Size != int64(buf.Len()) || inv[0].SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected container entry: %+v", inv[0])
+	}
+	if inv[4].Container != "sub/a.MDOCX" {
+		t.Fatalf("unexpected second container: %+v", inv[4])
+	}
+
+	var csvOut bytes.Buffer
+	if err := WriteInventoryCSV(&csvOut, inv); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	if len(lines) != 9 || lines[0] != "container,kind,path,id,size,sha256" {
+		t.Fatalf("unexpected CSV: %s", csvOut.String())
+	}
+	var jsonOut bytes.Buffer
+	if err := WriteInventoryJSON(&jsonOut, inv); err != nil {
+		t.Fatal(err)
+	}
+	var back []InventoryEntry
+	if err := json.Unmarshal(jsonOut.Bytes(), &back); err != nil || len(back) != len(inv) {
+		t.Fatalf("JSON round trip failed: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.mdocx"), []byte("nope"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := InventoryDir(dir); err == nil || !strings.Contains(err.Error(), "broken.mdocx") {
+		t.Fatalf("expected error naming broken file, got %v", err)
+	}
+}
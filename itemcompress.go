@@ -0,0 +1,215 @@
+package mdocx
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"slices"
+	"strings"
+)
+
+// ItemCompressionAttr is the media item attribute that marks, in a Media
+// section written with WithSmartMediaCompression, an item whose data is
+// compressed on its own. Its value names the algorithm: "zip", "zstd", "lz4",
+// "br", or "xz". Items without it are stored as they are. Decoders remove the
+// attribute when they decompress the data, so it is never seen on a decoded
+// item.
+const ItemCompressionAttr = "item_compression"
+
+// WithSmartMediaCompression makes Encode compress media items one at a time
+// rather than the Media section as a whole: items of a text-like type (see
+// CompressibleMIMEType) are compressed with the WithMediaCompression
+// algorithm, and others, such as JPEG, PNG, or MP4 data that is compressed
+// already, are stored as they are, so no CPU is spent compressing them again.
+// An item is also stored when compressing it does not make it smaller. The
+// section itself is then left uncompressed, which lets NewReader and
+// OpenMapped reach stored items without decompressing anything (see rfc.md
+// §5.2.4). It has no effect with CompNone or on a document with NoMedia set,
+// and Encode rejects items that already carry ItemCompressionAttr.
+// EncodeStream, Writer, and Transcode ignore it; Transcode and
+// EditSession.Commit keep the items of a container written with it compressed
+// as they are.
+func WithSmartMediaCompression(v bool) WriteOption {
+	return func(c *writeConfig) { c.smartMedia = v }
+}
+
+// CompressibleMIMEType reports whether data of media type mt is worth
+// compressing: text, JSON, XML (SVG included), and uncompressed image and
+// audio formats such as BMP, TIFF, and WAV. It reports false for other types,
+// which are mostly compressed already, and for an empty or malformed mt.
+func CompressibleMIMEType(mt string) bool {
+	mt, _, err := mime.ParseMediaType(mt)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
+		return true
+	}
+	switch mt {
+	case "application/json", "application/x-ndjson", "application/xml",
+		"application/javascript", "application/x-javascript", "application/ecmascript",
+		"application/yaml", "application/x-yaml", "application/toml",
+		"application/sql", "application/rtf", "application/postscript", "application/wasm",
+		"image/bmp", "image/x-ms-bmp", "image/tiff", "image/x-portable-pixmap",
+		"audio/wav", "audio/x-wav", "audio/wave", "audio/aiff", "audio/x-aiff",
+		"font/ttf", "font/otf":
+		return true
+	}
+	return false
+}
+
+// itemCompressionNames are the ItemCompressionAttr values of the algorithms.
+var itemCompressionNames = map[Compression]string{
+	CompZIP:  "zip",
+	CompZSTD: "zstd",
+	CompLZ4:  "lz4",
+	CompBR:   "br",
+	CompXZ:   "xz",
+}
+
+// packItems returns a copy of items in which the data of compressible items
+// is replaced by its compressed payload, in the envelope of a compressed
+// section (rfc.md §6.2), and marked with ItemCompressionAttr. items is not
+// modified.
+func packItems(ctx context.Context, items []MediaItem, comp Compression, t codecTuning) ([]MediaItem, error) {
+	name, ok := itemCompressionNames[comp]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown compression %d", ErrInvalidPayload, comp)
+	}
+	out := slices.Clone(items)
+	for i := range out {
+		it := &out[i]
+		if _, ok := it.Attributes[ItemCompressionAttr]; ok {
+			return nil, fmt.Errorf("%w: media item %q: attribute %s is reserved", ErrValidation, it.ID, ItemCompressionAttr)
+		}
+		if len(it.Data) == 0 || !CompressibleMIMEType(it.MIMEType) {
+			continue
+		}
+		_, packed, err := compressPayloadContext(ctx, comp, it.Data, t)
+		if err != nil {
+			return nil, err
+		}
+		if len(packed) >= len(it.Data) {
+			continue
+		}
+		it.Data = packed
+		it.Attributes = maps.Clone(it.Attributes)
+		if it.Attributes == nil {
+			it.Attributes = make(map[string]string, 1)
+		}
+		it.Attributes[ItemCompressionAttr] = name
+	}
+	return out, nil
+}
+
+// itemCompression returns the algorithm that attrs, the attributes of the
+// item with the given ID, mark its data as compressed with, or CompNone if
+// the data is stored.
+func itemCompression(id string, attrs map[string]string) (Compression, error) {
+	name, ok := attrs[ItemCompressionAttr]
+	if !ok {
+		return CompNone, nil
+	}
+	for comp, n := range itemCompressionNames {
+		if n == name {
+			return comp, nil
+		}
+	}
+	return CompNone, fmt.Errorf("%w: media item %q: unknown %s %q", ErrInvalidPayload, id, ItemCompressionAttr, name)
+}
+
+// unpackData decompresses data, the payload of an item compressed with comp,
+// to at most MaxSingleMediaSize bytes.
+func unpackData(ctx context.Context, comp Compression, data []byte, limits Limits) ([]byte, error) {
+	return decompressPayloadContext(ctx, comp, uint16(comp)|sectionFlagHasUncompressedLen, data, limits.MaxSingleMediaSize)
+}
+
+// withoutItemCompression returns attrs without ItemCompressionAttr, or nil if
+// nothing else is left.
+func withoutItemCompression(attrs map[string]string) map[string]string {
+	if len(attrs) == 1 {
+		return nil
+	}
+	attrs = maps.Clone(attrs)
+	delete(attrs, ItemCompressionAttr)
+	return attrs
+}
+
+// unpackItems decompresses, in place, the data of the items of a Media
+// section written with WithSmartMediaCompression, and removes their
+// ItemCompressionAttr.
+func unpackItems(ctx context.Context, items []MediaItem, limits Limits) error {
+	var total uint64
+	for i := range items {
+		it := &items[i]
+		comp, err := itemCompression(it.ID, it.Attributes)
+		if err != nil {
+			return err
+		}
+		if comp == CompNone {
+			total += uint64(len(it.Data))
+			continue
+		}
+		if it.Data, err = unpackData(ctx, comp, it.Data, limits); err != nil {
+			return fmt.Errorf("media item %q: %w", it.ID, err)
+		}
+		it.Attributes = withoutItemCompression(it.Attributes)
+		if total += uint64(len(it.Data)); total > limits.MaxMediaUncompressed {
+			return exceeds("MaxMediaUncompressed", "media items decompress to more than %d bytes", limits.MaxMediaUncompressed)
+		}
+	}
+	return nil
+}
+
+// unpackEntries notes which entries of idx, scanned from a Media section
+// written with WithSmartMediaCompression and held in r, are compressed, reads
+// their decompressed sizes from the payload prefixes, and removes their
+// ItemCompressionAttr. Their data must be read with mediaEntry.unpack.
+func (idx *mediaIndex) unpackEntries(r io.ReaderAt) error {
+	for i := range idx.items {
+		e := &idx.items[i]
+		comp, err := itemCompression(e.ID, e.Attributes)
+		if err != nil {
+			return err
+		}
+		if comp == CompNone {
+			continue
+		}
+		var prefix [8]byte
+		if e.dataLen < int64(len(prefix)) {
+			return fmt.Errorf("%w: media item %q: payload too short for uncompressed length", ErrInvalidPayload, e.ID)
+		}
+		if _, err := r.ReadAt(prefix[:], e.dataOff); err != nil {
+			return unexpectedEOF(err)
+		}
+		e.packed, e.size = comp, int64(binary.LittleEndian.Uint64(prefix[:]))
+		if e.size < 0 {
+			return fmt.Errorf("%w: media item %q: uncompressed length out of range", ErrInvalidPayload, e.ID)
+		}
+		e.Attributes = withoutItemCompression(e.Attributes)
+	}
+	return nil
+}
+
+// dataSize returns the length of e's data once decompressed.
+func (e mediaEntry) dataSize() int64 {
+	if e.packed != CompNone {
+		return e.size
+	}
+	return e.dataLen
+}
+
+// unpack returns e's data given its stored bytes.
+func (e mediaEntry) unpack(stored []byte, limits Limits) ([]byte, error) {
+	if e.packed == CompNone {
+		return stored, nil
+	}
+	data, err := unpackData(context.Background(), e.packed, stored, limits)
+	if err != nil {
+		return nil, fmt.Errorf("media item %q: %w", e.ID, err)
+	}
+	return data, nil
+}
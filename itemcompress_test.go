@@ -0,0 +1,155 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func smartDoc() *Document {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("![](mdocx://media/readme) ![](mdocx://media/empty)\n")
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "readme", Path: "assets/readme.txt", MIMEType: "text/plain; charset=utf-8", Data: bytes.Repeat([]byte("compress me "), 200), Attributes: map[string]string{"alt": "Readme"}},
+		MediaItem{ID: "empty", MIMEType: "application/json"},
+	)
+	return doc
+}
+
+func TestSmartMediaCompression(t *testing.T) {
+	for _, comp := range []Compression{CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ} {
+		doc := smartDoc()
+		want := copyDocument(doc)
+		var buf bytes.Buffer
+		if err := Encode(&buf, doc, WithMediaCompression(comp), WithSmartMediaCompression(true), WithIndex(true)); err != nil {
+			t.Fatalf("%v: %v", comp, err)
+		}
+		if it := doc.Media.Items[1]; !bytes.Equal(it.Data, want.Media.Items[1].Data) || !reflect.DeepEqual(it.Attributes, want.Media.Items[1].Attributes) {
+			t.Fatalf("%v: Encode modified the items", comp)
+		}
+		data := buf.Bytes()
+		h, err := DecodeHeader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Media.Compression != CompNone {
+			t.Errorf("%v: media section compressed with %v", comp, h.Media.Compression)
+		}
+		// The PNG is stored and the text compressed.
+		if !bytes.Contains(data, doc.Media.Items[0].Data) || bytes.Contains(data, doc.Media.Items[1].Data[:100]) {
+			t.Errorf("%v: items not packed as expected", comp)
+		}
+
+		got, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%v: %v", comp, err)
+		}
+		for i := range got.Media.Items {
+			w := want.Media.Items[i]
+			if g := got.Media.Items[i]; !bytes.Equal(g.Data, w.Data) || !reflect.DeepEqual(g.Attributes, w.Attributes) {
+				t.Errorf("%v: Decode item %q = %+v", comp, w.ID, g)
+			}
+		}
+
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%v: %v", comp, err)
+		}
+		if info := r.Media()[1]; info.Size != int64(len(want.Media.Items[1].Data)) || info.Attributes[ItemCompressionAttr] != "" {
+			t.Errorf("%v: Reader.Media = %+v", comp, info)
+		}
+		rc, err := r.OpenMedia("readme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, err := io.ReadAll(rc); err != nil || !bytes.Equal(b, want.Media.Items[1].Data) {
+			t.Errorf("%v: Reader.OpenMedia = %d bytes, %v", comp, len(b), err)
+		}
+
+		m, err := newMapped(data, newReadConfig(nil))
+		if err != nil {
+			t.Fatalf("%v: %v", comp, err)
+		}
+		sr, err := m.OpenMedia("readme")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(sr); !bytes.Equal(b, want.Media.Items[1].Data) {
+			t.Errorf("%v: Mapped.OpenMedia = %d bytes", comp, len(b))
+		}
+
+		var sunk []MediaItem
+		if err := DecodeInto(bytes.NewReader(data), SinkFuncs{OnMediaItem: func(it MediaItem) error {
+			sunk = append(sunk, it)
+			return nil
+		}}); err != nil || len(sunk) != 3 || !bytes.Equal(sunk[1].Data, want.Media.Items[1].Data) {
+			t.Errorf("%v: DecodeInto: %v", comp, err)
+		}
+
+		var sizes []int64
+		filtered, err := Decode(bytes.NewReader(data), WithMediaFilter(func(id, path, mimeType string, size int64) bool {
+			sizes = append(sizes, size)
+			return id == "readme"
+		}))
+		if err != nil || len(filtered.Media.Items) != 1 || !bytes.Equal(filtered.Media.Items[0].Data, want.Media.Items[1].Data) {
+			t.Errorf("%v: filtered Decode: %v", comp, err)
+		}
+		if sizes[1] != int64(len(want.Media.Items[1].Data)) {
+			t.Errorf("%v: filter saw size %d", comp, sizes[1])
+		}
+	}
+}
+
+func TestSmartMediaCompressionRejects(t *testing.T) {
+	doc := smartDoc()
+	doc.Media.Items[0].Attributes = map[string]string{ItemCompressionAttr: "zstd"}
+	if err := Encode(io.Discard, doc, WithSmartMediaCompression(true)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("reserved attribute: %v", err)
+	}
+
+	// Without the section flag the attribute is only an attribute.
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(&buf)
+	if err != nil || got.Media.Items[0].Attributes[ItemCompressionAttr] != "zstd" {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	// An unknown algorithm name is rejected.
+	doc = smartDoc()
+	buf.Reset()
+	if err := Encode(&buf, doc, WithSmartMediaCompression(true)); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Replace(buf.Bytes(), []byte("zstd"), []byte("zstx"), 1)
+	if _, err := Decode(bytes.NewReader(data), WithVerifyHashes(false)); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("unknown algorithm: %v", err)
+	}
+}
+
+func TestCompressibleMIMEType(t *testing.T) {
+	for mt, want := range map[string]bool{
+		"text/plain":               true,
+		"text/csv; charset=utf-8":  true,
+		"application/json":         true,
+		"application/ld+json":      true,
+		"image/svg+xml":            true,
+		"image/bmp":                true,
+		"audio/wav":                true,
+		"image/jpeg":               false,
+		"image/png":                false,
+		"video/mp4":                false,
+		"application/zip":          false,
+		"application/octet-stream": false,
+		"":                         false,
+		"not a type":               false,
+	} {
+		if got := CompressibleMIMEType(mt); got != want {
+			t.Errorf("CompressibleMIMEType(%q) = %v", mt, got)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package mdocx
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// Transform modifies a document before it is encoded. Transforms are passed to
+// Encode with WithTransforms and run in order before validation.
+type Transform func(doc *Document) error
+
+// LanguageAttr is the attribute key under which DetectLanguages records the
+// language of a Markdown file.
+const LanguageAttr = "language"
+
+// LanguageDetector returns the BCP 47 language tag of text, or false if the
+// language cannot be determined.
+type LanguageDetector func(text string) (tag string, ok bool)
+
+// DetectLanguages returns a Transform that records the natural language of each
+// Markdown file in its LanguageAttr attribute. Files that already have the
+// attribute are left alone, as are files whose language cannot be determined.
+// Code blocks and Markdown syntax are removed before detection.
+//
+// If detect is nil, DefaultLanguageDetector is used.
+func DetectLanguages(detect LanguageDetector) Transform {
+	if detect == nil {
+		detect = DefaultLanguageDetector
+	}
+	return func(doc *Document) error {
+		for i := range doc.Markdown.Files {
+			f := &doc.Markdown.Files[i]
+			if _, ok := f.Attributes[LanguageAttr]; ok {
+				continue
+			}
+			if tag, ok := detect(markdownProse(f.Content, mdscan.FlavorExtensions(string(doc.FileFlavor(*f))))); ok {
+				attrs := Attributes(f.Attributes)
+				attrs.SetString(LanguageAttr, tag)
+				f.Attributes = attrs
+			}
+		}
+		return nil
+	}
+}
+
+// markdownProse returns the visible prose of Markdown source, without code,
+// recognizing the extensions in ext.
+func markdownProse(src []byte, ext mdscan.Extensions) string {
+	var b strings.Builder
+	for _, blk := range mdscan.BlocksExt(src, ext) {
+		if blk.Kind == mdscan.BlockCode {
+			continue
+		}
+		b.WriteString(mdscan.InlineText(blk.Text))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// minDetectLetters is the minimum number of letters DefaultLanguageDetector
+// needs before it reports a language.
+const minDetectLetters = 20
+
+// DefaultLanguageDetector is a small dependency-free detector. It identifies
+// languages with a distinctive script (Chinese, Japanese, Korean, Russian,
+// Ukrainian, Greek, Arabic, Hebrew, Hindi, Thai) from the letters used, and
+// common Latin-script languages (English, German, French, Spanish, Italian,
+// Portuguese, Dutch) from function-word frequencies. Short or mixed texts are
+// reported as undetermined.
+func DefaultLanguageDetector(text string) (string, bool) {
+	counts := map[string]int{}
+	letters := 0
+	ukrainian := false
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian = true
+			}
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		}
+	}
+	if letters < minDetectLetters {
+		return "", false
+	}
+	if cjk := counts["han"] + counts["kana"]; cjk*2 > letters {
+		if counts["kana"]*10 >= cjk {
+			return "ja", true
+		}
+		return "zh", true
+	}
+	for _, script := range []string{"ko", "el", "ar", "he", "hi", "th"} {
+		if counts[script]*2 > letters {
+			return script, true
+		}
+	}
+	if counts["cyrillic"]*2 > letters {
+		if ukrainian {
+			return "uk", true
+		}
+		return "ru", true
+	}
+	if counts["latin"]*2 > letters {
+		return detectLatin(text)
+	}
+	return "", false
+}
+
+// latinStopwords lists frequent function words that are distinctive for each
+// language.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "with", "for", "this", "are", "was", "be", "on", "you"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "ein", "eine", "den", "zu", "auf", "sich", "auch", "ich", "wir"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "un", "du", "pour", "pas", "que", "qui", "dans", "sur", "avec"},
+	"es": {"el", "la", "los", "las", "y", "es", "una", "por", "para", "que", "con", "del", "se", "como", "pero", "está"},
+	"it": {"il", "la", "di", "che", "è", "e", "un", "una", "per", "non", "sono", "con", "del", "della", "gli", "anche"},
+	"pt": {"o", "a", "os", "as", "e", "é", "um", "uma", "que", "não", "com", "para", "do", "da", "em", "são"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "op", "te", "met", "voor", "zijn", "ook", "maar", "wij"},
+}
+
+// detectLatin scores text against latinStopwords and returns the best match if
+// it is clearly ahead of the runner-up.
+func detectLatin(text string) (string, bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+	freq := make(map[string]int, len(words))
+	for _, w := range words {
+		freq[w]++
+	}
+	best, second, bestLang := 0, 0, ""
+	for _, lang := range []string{"en", "de", "fr", "es", "it", "pt", "nl"} {
+		score := 0
+		for _, sw := range latinStopwords[lang] {
+			score += freq[sw]
+		}
+		switch {
+		case score > best:
+			best, second, bestLang = score, best, lang
+		case score > second:
+			second = score
+		}
+	}
+	if best < 3 || best*4 < second*5 {
+		return "", false
+	}
+	return bestLang, true
+}
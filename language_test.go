@@ -0,0 +1,54 @@
+package mdocx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDefaultLanguageDetector(t *testing.T) {
+	cases := map[string]string{
+		"The quick brown fox jumps over the lazy dog and it is in the garden with the cat.":      "en",
+		"Der schnelle braune Fuchs springt über den faulen Hund, und das ist nicht gut.":         "de",
+		"Le renard brun rapide saute par-dessus le chien paresseux et les chats dans la maison.": "fr",
+		"El rápido zorro marrón salta sobre el perro perezoso y los gatos para la casa.":         "es",
+		"Быстрая коричневая лиса прыгает через ленивую собаку в саду.":                           "ru",
+		"Швидка бура лисиця перестрибує через ледачого собаку і кота.":                           "uk",
+		"今日はとても良い天気ですね。私は公園に行きたいと思います。散歩しましょう。":                                                  "ja",
+		"今天天气很好我们一起去公园散步吧然后再去吃饭好不好呢朋友们都在等我们":                                                     "zh",
+		"오늘은 날씨가 정말 좋네요 공원에 산책하러 갑시다 친구들이 기다려요":                                                  "ko",
+		"Η γρήγορη καφέ αλεπού πηδά πάνω από τον τεμπέλη σκύλο.":                                 "el",
+	}
+	for text, want := range cases {
+		if got, ok := DefaultLanguageDetector(text); !ok || got != want {
+			t.Errorf("%q: got (%q, %v), want %q", text, got, ok, want)
+		}
+	}
+	for _, text := range []string{"Hi", "Lorem ipsum dolor sit amet consectetur adipiscing"} {
+		if got, ok := DefaultLanguageDetector(text); ok {
+			t.Errorf("%q: expected undetermined, got %q", text, got)
+		}
+	}
+}
+
+func TestDetectLanguagesTransform(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[0].Content = []byte("# Titel\n\nDas ist ein Test, und wir sind nicht sicher, ob das auch mit dem Code geht.\n\n```\nthe and of to is in that it\n```\n")
+	doc.Markdown.Files[1].Attributes = map[string]string{LanguageAttr: "fr"}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithTransforms(DetectLanguages(nil))); err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Markdown.Files[0].Attributes[LanguageAttr]; got != "de" {
+		t.Fatalf("detected %q, want de", got)
+	}
+	if got := doc.Markdown.Files[1].Attributes[LanguageAttr]; got != "fr" {
+		t.Fatalf("existing attribute overwritten: %q", got)
+	}
+	dec, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Markdown.Files[0].Attributes[LanguageAttr] != "de" {
+		t.Fatal("attribute not encoded")
+	}
+}
@@ -1,5 +1,10 @@
 package mdocx
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Limits defines size and count limits enforced during encoding and decoding.
 // These limits protect against resource exhaustion from malformed or malicious input.
 //
@@ -16,6 +21,8 @@ package mdocx
 //   - MaxMediaItems: 10,000
 //   - MaxSingleMarkdownFileSize: 256 MiB
 //   - MaxSingleMediaSize: 512 MiB
+//   - MaxPathLength: 1024 bytes
+//   - MaxPathDepth: 64 segments
 type Limits struct {
 	// MaxMetadataLen is the maximum allowed length of the metadata JSON block in bytes.
 	MaxMetadataLen uint32
@@ -35,6 +42,13 @@ type Limits struct {
 	MaxSingleMarkdownFileSize uint64
 	// MaxSingleMediaSize is the maximum size of a single media item's data.
 	MaxSingleMediaSize uint64
+	// MaxPathLength is the maximum length in bytes of a container path
+	// (Markdown file and media item paths and RootPath). Lower it, for example
+	// to 200, when bundles must extract below a Windows MAX_PATH directory.
+	MaxPathLength int
+	// MaxPathDepth is the maximum number of segments of a container path, so
+	// that "a/b/c.md" has depth 3.
+	MaxPathDepth int
 }
 
 // DefaultLimits returns the default size limits as recommended by the MDOCX specification.
@@ -55,6 +69,8 @@ func defaultLimits() Limits {
 		MaxMediaItems:             10_000,
 		MaxSingleMarkdownFileSize: 256 << 20,
 		MaxSingleMediaSize:        512 << 20,
+		MaxPathLength:             1024,
+		MaxPathDepth:              64,
 	}
 }
 
@@ -88,5 +104,96 @@ func (l Limits) withDefaults() Limits {
 	if l.MaxSingleMediaSize == 0 {
 		l.MaxSingleMediaSize = d.MaxSingleMediaSize
 	}
+	if l.MaxPathLength == 0 {
+		l.MaxPathLength = d.MaxPathLength
+	}
+	if l.MaxPathDepth == 0 {
+		l.MaxPathDepth = d.MaxPathDepth
+	}
+	return l
+}
+
+// With returns a copy of l with every non-zero field of override applied on top.
+// It allows endpoint-specific limits to be derived from a shared base without
+// copying the whole struct by hand.
+//
+// Example:
+//
+//	base := mdocx.DefaultLimits()
+//	uploads := base.With(mdocx.Limits{MaxMediaUncompressed: 64 << 20, MaxMediaItems: 100})
+func (l Limits) With(override Limits) Limits {
+	if override.MaxMetadataLen != 0 {
+		l.MaxMetadataLen = override.MaxMetadataLen
+	}
+	if override.MaxMarkdownSectionLen != 0 {
+		l.MaxMarkdownSectionLen = override.MaxMarkdownSectionLen
+	}
+	if override.MaxMediaSectionLen != 0 {
+		l.MaxMediaSectionLen = override.MaxMediaSectionLen
+	}
+	if override.MaxMarkdownUncompressed != 0 {
+		l.MaxMarkdownUncompressed = override.MaxMarkdownUncompressed
+	}
+	if override.MaxMediaUncompressed != 0 {
+		l.MaxMediaUncompressed = override.MaxMediaUncompressed
+	}
+	if override.MaxMarkdownFiles != 0 {
+		l.MaxMarkdownFiles = override.MaxMarkdownFiles
+	}
+	if override.MaxMediaItems != 0 {
+		l.MaxMediaItems = override.MaxMediaItems
+	}
+	if override.MaxSingleMarkdownFileSize != 0 {
+		l.MaxSingleMarkdownFileSize = override.MaxSingleMarkdownFileSize
+	}
+	if override.MaxSingleMediaSize != 0 {
+		l.MaxSingleMediaSize = override.MaxSingleMediaSize
+	}
+	if override.MaxPathLength != 0 {
+		l.MaxPathLength = override.MaxPathLength
+	}
+	if override.MaxPathDepth != 0 {
+		l.MaxPathDepth = override.MaxPathDepth
+	}
 	return l
 }
+
+// noLimits returns limits that never trigger, for validating documents that are
+// already in memory and are not being encoded or decoded.
+func noLimits() Limits {
+	const maxInt = int(^uint(0) >> 1)
+	return Limits{
+		MaxMetadataLen:            ^uint32(0),
+		MaxMarkdownSectionLen:     ^uint64(0),
+		MaxMediaSectionLen:        ^uint64(0),
+		MaxMarkdownUncompressed:   ^uint64(0),
+		MaxMediaUncompressed:      ^uint64(0),
+		MaxMarkdownFiles:          maxInt,
+		MaxMediaItems:             maxInt,
+		MaxSingleMarkdownFileSize: ^uint64(0),
+		MaxSingleMediaSize:        ^uint64(0),
+		MaxPathLength:             maxInt,
+		MaxPathDepth:              maxInt,
+	}
+}
+
+// checkPath reports whether the container path p is within the path length and
+// depth limits.
+func (l Limits) checkPath(p string) error {
+	if limit, reason := l.pathLimit(p); limit != "" {
+		return exceeds(limit, "%s", reason)
+	}
+	return nil
+}
+
+// pathLimit returns the name of the path limit that p exceeds and why, or two
+// empty strings if p is within the limits.
+func (l Limits) pathLimit(p string) (limit, reason string) {
+	if len(p) > l.MaxPathLength {
+		return "MaxPathLength", fmt.Sprintf("path %q is %d bytes long (limit %d)", p, len(p), l.MaxPathLength)
+	}
+	if depth := strings.Count(p, "/") + 1; depth > l.MaxPathDepth {
+		return "MaxPathDepth", fmt.Sprintf("path %q has %d segments (limit %d)", p, depth, l.MaxPathDepth)
+	}
+	return "", ""
+}
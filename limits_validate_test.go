@@ -1,6 +1,13 @@
 package mdocx
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
 
 func TestLimitsWithDefaults(t *testing.T) {
 	l := (Limits{}).withDefaults()
@@ -15,6 +22,23 @@ func TestLimitsWithDefaults(t *testing.T) {
 	}
 }
 
+func TestLimitsWith(t *testing.T) {
+	base := DefaultLimits()
+	got := base.With(Limits{MaxMediaItems: 5, MaxSingleMediaSize: 1 << 10})
+	want := base
+	want.MaxMediaItems = 5
+	want.MaxSingleMediaSize = 1 << 10
+	if got != want {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+	if base.MaxMediaItems == 5 {
+		t.Fatal("With must not modify the receiver")
+	}
+	if (Limits{}).With(Limits{}) != (Limits{}) {
+		t.Fatal("empty override must be a no-op")
+	}
+}
+
 func TestValidateContainerPath(t *testing.T) {
 	cases := []struct {
 		in   string
@@ -48,3 +72,49 @@ func TestCompressionNameUnknown(t *testing.T) {
 		t.Fatal("expected unknown")
 	}
 }
+
+func TestPathLimits(t *testing.T) {
+	long := strings.Repeat("a", 1100) + ".md"
+	deep := strings.Repeat("d/", 70) + "x.png"
+
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Path = long
+	err := Encode(io.Discard, doc)
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "MaxPathLength" {
+		t.Fatalf("long path: got %v", err)
+	}
+	if err := Encode(io.Discard, sampleDoc(), WithWriteLimits(Limits{MaxPathLength: 12})); !errors.As(err, &le) || le.Limit != "MaxPathLength" {
+		t.Fatalf("custom length: got %v", err)
+	}
+
+	doc = sampleDoc()
+	doc.Media.Items[0].Path = deep
+	if err := Validate(doc); !errors.As(err, &le) || le.Limit != "MaxPathDepth" {
+		t.Fatalf("deep path: got %v", err)
+	}
+	if err := Validate(sampleDoc(), WithValidateLimits(Limits{MaxPathDepth: 1})); !errors.As(err, &le) || le.Limit != "MaxPathDepth" {
+		t.Fatalf("custom depth: got %v", err)
+	}
+
+	doc.Markdown.Files[1].Path = long
+	err = Validate(doc, WithAllErrors())
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 || !errors.Is(errs[0], ErrLimitExceeded) || errs[0].Field != "Markdown.Files[1].Path" || errs[1].Field != "Media.Items[0].Path" {
+		t.Fatalf("all errors: got %v", err)
+	}
+
+	// Decoding enforces the reader's limits.
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), WithReadLimits(Limits{MaxPathLength: 8})); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("decode: got %v", err)
+	}
+
+	files, media := feed(sampleDoc())
+	if err := EncodeStream(context.Background(), io.Discard, StreamHeader{RootPath: "docs/index.md"}, files, media, WithWriteLimits(Limits{MaxPathDepth: 1})); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("stream: got %v", err)
+	}
+}
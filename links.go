@@ -0,0 +1,219 @@
+package mdocx
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// BrokenLink describes a link in a Markdown file whose target cannot be resolved
+// within the container.
+type BrokenLink struct {
+	// File is the container path of the Markdown file containing the link.
+	File string
+	// Line and Column give the 1-based position of the link in File.
+	Line   int
+	Column int
+	// Dest is the link destination as written.
+	Dest string
+	// Reason explains why the link could not be resolved.
+	Reason string
+}
+
+// String formats the broken link as "file:line:col: dest: reason".
+func (b BrokenLink) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", b.File, b.Line, b.Column, b.Dest, b.Reason)
+}
+
+// linkConfig holds configuration options for CheckLinks and ValidateLinks.
+type linkConfig struct {
+	slug           func(string) string
+	checkFragments bool
+}
+
+// LinkOption is a functional option for configuring link validation.
+type LinkOption func(*linkConfig)
+
+// WithSlugger sets the function used to turn heading text into fragment identifiers.
+// The function receives the visible heading text with inline Markdown removed.
+// Repeated slugs within a file are disambiguated by appending "-1", "-2", etc.
+// Default is GitHubSlug.
+func WithSlugger(fn func(heading string) string) LinkOption {
+	return func(c *linkConfig) { c.slug = fn }
+}
+
+// WithFragmentCheck controls whether "#fragment" parts of links to Markdown files
+// must resolve to a heading or anchor in the target file.
+// Default is true.
+func WithFragmentCheck(v bool) LinkOption {
+	return func(c *linkConfig) { c.checkFragments = v }
+}
+
+// GitHubSlug converts heading text into a fragment identifier using the same rules
+// as GitHub: the text is lower-cased, punctuation is removed, and spaces become hyphens.
+func GitHubSlug(heading string) string {
+	var b strings.Builder
+	b.Grow(len(heading))
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// CheckLinks returns every relative link in doc's Markdown files that does not
+// resolve to a Markdown file or media path in the container, and every fragment
+// that does not match a heading or anchor in its target Markdown file.
+//
+// Links with a URI scheme (https:, mailto:, mdocx:, ...) and protocol-relative
+// links are not checked. Destinations are resolved relative to the directory of
+// the Markdown file that contains them.
+func CheckLinks(doc *Document, opts ...LinkOption) []BrokenLink {
+	cfg := linkConfig{slug: GitHubSlug, checkFragments: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if doc == nil {
+		return nil
+	}
+
+	scans := make(map[string]*mdscan.Result, len(doc.Markdown.Files))
+	for _, f := range doc.Markdown.Files {
+		scans[f.Path] = doc.scanFile(f)
+	}
+	mediaPaths := make(map[string]struct{}, len(doc.Media.Items))
+	for _, it := range doc.Media.Items {
+		if it.Path != "" {
+			mediaPaths[it.Path] = struct{}{}
+		}
+	}
+	anchors := make(map[string]map[string]struct{})
+	anchorsFor := func(p string) map[string]struct{} {
+		a, ok := anchors[p]
+		if !ok {
+			a = fileAnchors(scans[p], cfg.slug)
+			anchors[p] = a
+		}
+		return a
+	}
+
+	var broken []BrokenLink
+	for _, f := range doc.Markdown.Files {
+		for _, l := range scans[f.Path].Links {
+			target, frag, ok := splitRelativeLink(l.Dest)
+			if !ok {
+				continue
+			}
+			bad := func(reason string) {
+				broken = append(broken, BrokenLink{File: f.Path, Line: l.Line, Column: l.Column, Dest: l.Dest, Reason: reason})
+			}
+			if target == "" {
+				target = f.Path
+			} else {
+				resolved, err := resolveContainerLink(f.Path, target)
+				if err != nil {
+					bad(err.Error())
+					continue
+				}
+				target = resolved
+			}
+			if _, ok := scans[target]; !ok {
+				if _, ok := mediaPaths[target]; !ok {
+					bad(fmt.Sprintf("target %q not found", target))
+				}
+				continue
+			}
+			if frag == "" || !cfg.checkFragments {
+				continue
+			}
+			if _, ok := anchorsFor(target)[frag]; !ok {
+				bad(fmt.Sprintf("no heading or anchor %q in %q", frag, target))
+			}
+		}
+	}
+	return broken
+}
+
+// ValidateLinks checks links like CheckLinks and returns an error wrapping
+// ErrValidation describing the first broken link, or nil if all links resolve.
+func ValidateLinks(doc *Document, opts ...LinkOption) error {
+	broken := CheckLinks(doc, opts...)
+	switch len(broken) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%w: broken link %s", ErrValidation, broken[0])
+	default:
+		return fmt.Errorf("%w: broken link %s (and %d more)", ErrValidation, broken[0], len(broken)-1)
+	}
+}
+
+// fileAnchors returns the set of fragment identifiers defined by a scanned file.
+func fileAnchors(res *mdscan.Result, slug func(string) string) map[string]struct{} {
+	set := make(map[string]struct{}, len(res.Headings)+len(res.Anchors))
+	counts := make(map[string]int, len(res.Headings))
+	for _, h := range res.Headings {
+		if h.ID != "" {
+			set[h.ID] = struct{}{}
+			continue
+		}
+		s := slug(mdscan.InlineText(h.Text))
+		if n := counts[s]; n > 0 {
+			counts[s] = n + 1
+			s = s + "-" + strconv.Itoa(n)
+		} else {
+			counts[s] = 1
+		}
+		set[s] = struct{}{}
+	}
+	for _, a := range res.Anchors {
+		set[a] = struct{}{}
+	}
+	return set
+}
+
+// splitRelativeLink splits a link destination into its unescaped path and fragment.
+// It reports false for destinations that are not container-relative, such as
+// absolute URLs, protocol-relative URLs, and absolute paths.
+func splitRelativeLink(dest string) (target, frag string, ok bool) {
+	dest = strings.TrimSpace(dest)
+	if dest == "" || strings.HasPrefix(dest, "/") {
+		return "", "", false
+	}
+	if i := strings.IndexAny(dest, ":/?#"); i > 0 && dest[i] == ':' {
+		return "", "", false
+	}
+	if i := strings.IndexByte(dest, '#'); i >= 0 {
+		dest, frag = dest[:i], dest[i+1:]
+		if f, err := url.PathUnescape(frag); err == nil {
+			frag = f
+		}
+	}
+	if i := strings.IndexByte(dest, '?'); i >= 0 {
+		dest = dest[:i]
+	}
+	if p, err := url.PathUnescape(dest); err == nil {
+		dest = p
+	}
+	return dest, frag, true
+}
+
+// resolveContainerLink resolves a relative link target against the directory of from.
+func resolveContainerLink(from, target string) (string, error) {
+	p := path.Join(path.Dir(from), target)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("link escapes the container")
+	}
+	return p, nil
+}
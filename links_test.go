@@ -0,0 +1,86 @@
+package mdocx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func linkDoc() *Document {
+	return &Document{
+		Markdown: MarkdownBundle{
+			BundleVersion: VersionV1,
+			Files: []MarkdownFile{
+				{Path: "docs/index.md", Content: []byte("# Index\n\n" +
+					"[ok](guide.md#getting-started)\n" +
+					"[dup](guide.md#setup-1)\n" +
+					"[self](#index)\n" +
+					"[img](../assets/logo.png)\n" +
+					"[ext](https://example.com/x.md#nope)\n" +
+					"[custom](guide.md#custom-id)\n" +
+					"[html](guide.md#raw)\n" +
+					"[encoded](guide.md#caf%C3%A9)\n")},
+				{Path: "docs/guide.md", Content: []byte("# Getting *Started*\n\n## Setup\n\n## Setup\n\n## Other {#custom-id}\n\n<a name=\"raw\"></a>\n\n## Café\n")},
+			},
+		},
+		Media: MediaBundle{
+			BundleVersion: VersionV1,
+			Items:         []MediaItem{{ID: "logo", Path: "assets/logo.png", Data: []byte{1}}},
+		},
+	}
+}
+
+func TestCheckLinks_AllResolve(t *testing.T) {
+	if broken := CheckLinks(linkDoc()); len(broken) != 0 {
+		t.Fatalf("unexpected broken links: %v", broken)
+	}
+	if err := ValidateLinks(linkDoc()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckLinks_Broken(t *testing.T) {
+	doc := linkDoc()
+	doc.Markdown.Files[0].Content = []byte("[a](guide.md#missing)\n[b](missing.md)\n[c](../../up.md)\n[d](#nope)\n")
+	broken := CheckLinks(doc)
+	if len(broken) != 4 {
+		t.Fatalf("expected 4 broken links, got %v", broken)
+	}
+	if broken[0].File != "docs/index.md" || broken[0].Line != 1 || broken[0].Column != 1 {
+		t.Fatalf("unexpected position: %+v", broken[0])
+	}
+	if !strings.Contains(broken[2].Reason, "escapes") {
+		t.Fatalf("unexpected reason: %s", broken[2].Reason)
+	}
+	err := ValidateLinks(doc)
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "3 more") {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+
+	if broken := CheckLinks(doc, WithFragmentCheck(false)); len(broken) != 2 {
+		t.Fatalf("expected 2 broken links without fragment checks, got %v", broken)
+	}
+}
+
+func TestCheckLinks_CustomSlugger(t *testing.T) {
+	doc := linkDoc()
+	doc.Markdown.Files[0].Content = []byte("[a](guide.md#GETTING-STARTED)\n")
+	upper := func(s string) string { return strings.ToUpper(GitHubSlug(s)) }
+	if broken := CheckLinks(doc, WithSlugger(upper)); len(broken) != 0 {
+		t.Fatalf("unexpected broken links: %v", broken)
+	}
+}
+
+func TestGitHubSlug(t *testing.T) {
+	cases := map[string]string{
+		"Getting Started":     "getting-started",
+		"What's new in v1.2?": "whats-new-in-v12",
+		"snake_case & more":   "snake_case--more",
+		"Café Ünïcode":        "café-ünïcode",
+	}
+	for in, want := range cases {
+		if got := GitHubSlug(in); got != want {
+			t.Fatalf("GitHubSlug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
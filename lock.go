@@ -0,0 +1,83 @@
+package mdocx
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockSuffix is appended to a container path to name its lock file.
+const lockSuffix = ".lock"
+
+// FileLock is an advisory lock on a container file, taken with LockFile.
+type FileLock struct {
+	path string
+	mu   sync.Mutex
+	held bool
+}
+
+// LockFile takes the advisory lock of the container file at path by creating
+// the lock file path+".lock", and returns an error wrapping ErrLocked if that
+// file already exists. The lock file names the host and process holding it.
+//
+// The lock is an ordinary file so that it also works in synced and network
+// folders, where other machines see it once it has been synced. It only
+// guards against programs that take it too, such as EncodeFile. A lock left
+// behind by a process that crashed must be removed by hand.
+func LockFile(path string) (*FileLock, error) {
+	lockPath := path + lockSuffix
+	f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			holder, _ := os.ReadFile(lockPath)
+			return nil, fmt.Errorf("%w: %s held by %s", ErrLocked, lockPath, strings.TrimSpace(string(holder)))
+		}
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	_, err = fmt.Fprintf(f, "pid %d on %s since %s\n", os.Getpid(), host, time.Now().UTC().Format(time.RFC3339))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(lockPath)
+		return nil, err
+	}
+	return &FileLock{path: path, held: true}, nil
+}
+
+// Path returns the path of the locked container file.
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+// Unlock releases the lock by removing its lock file. Calling Unlock on a
+// released lock does nothing.
+func (l *FileLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.held {
+		return nil
+	}
+	l.held = false
+	return os.Remove(l.path + lockSuffix)
+}
+
+// covers reports whether l is held and locks the file at path.
+func (l *FileLock) covers(path string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held && filepath.Clean(l.path) == filepath.Clean(path)
+}
+
+// WithLock tells EncodeFile that the caller holds l, the lock of the file being
+// written, so that EncodeFile does not try to take it. Use it to keep a file
+// locked across a decode, edit, and encode.
+func WithLock(l *FileLock) WriteOption {
+	return func(c *writeConfig) { c.lock = l }
+}
@@ -0,0 +1,38 @@
+package mdocx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.mdocx")
+	l, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Path() != path {
+		t.Fatalf("Path = %q", l.Path())
+	}
+	_, err = LockFile(path)
+	if !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "pid ") {
+		t.Fatalf("expected ErrLocked naming the holder, got %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("second Unlock: %v", err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock file left behind: %v", err)
+	}
+	l2, err := LockFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2.Unlock()
+}
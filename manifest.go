@@ -0,0 +1,107 @@
+package mdocx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Manifest lists the parts of a document without their content. Its JSON form
+// has the "summary" and "details" objects of the .expected.json files of the
+// cross-language test suite (see examples/validate), without content previews.
+type Manifest struct {
+	Summary ManifestSummary `json:"summary"`
+	Details ManifestDetails `json:"details"`
+}
+
+// ManifestSummary holds the counts and total sizes of a document.
+type ManifestSummary struct {
+	HasMetadata        bool `json:"has_metadata"`
+	MarkdownFileCount  int  `json:"markdown_file_count"`
+	MediaItemCount     int  `json:"media_item_count"`
+	TotalMarkdownBytes int  `json:"total_markdown_bytes"`
+	TotalMediaBytes    int  `json:"total_media_bytes"`
+}
+
+// ManifestDetails lists the metadata, Markdown files, and media items of a
+// document in bundle order.
+type ManifestDetails struct {
+	Metadata      map[string]any         `json:"metadata,omitempty"`
+	MarkdownFiles []ManifestMarkdownFile `json:"markdown_files"`
+	MediaItems    []ManifestMediaItem    `json:"media_items"`
+}
+
+// ManifestMarkdownFile describes a Markdown file.
+type ManifestMarkdownFile struct {
+	Path          string            `json:"path"`
+	ContentLength int               `json:"content_length"`
+	ContentSHA256 string            `json:"content_sha256"`
+	MediaRefs     []string          `json:"media_refs,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+}
+
+// ManifestMediaItem describes a media item. SHA256Stored is empty when the
+// item has no stored hash, in which case SHA256Valid is true.
+type ManifestMediaItem struct {
+	ID             string            `json:"id"`
+	Path           string            `json:"path,omitempty"`
+	MIMEType       string            `json:"mime_type,omitempty"`
+	DataLength     int               `json:"data_length"`
+	SHA256Stored   string            `json:"sha256_stored,omitempty"`
+	SHA256Computed string            `json:"sha256_computed"`
+	SHA256Valid    bool              `json:"sha256_valid"`
+	Attributes     map[string]string `json:"attributes,omitempty"`
+}
+
+// Manifest returns the manifest of d. Hashes are computed from the content.
+func (d *Document) Manifest() Manifest {
+	m := Manifest{
+		Summary: ManifestSummary{
+			HasMetadata:       d.Metadata != nil,
+			MarkdownFileCount: len(d.Markdown.Files),
+			MediaItemCount:    len(d.Media.Items),
+		},
+		Details: ManifestDetails{
+			Metadata:      d.Metadata,
+			MarkdownFiles: make([]ManifestMarkdownFile, 0, len(d.Markdown.Files)),
+			MediaItems:    make([]ManifestMediaItem, 0, len(d.Media.Items)),
+		},
+	}
+	for _, f := range d.Markdown.Files {
+		sum := sha256.Sum256(f.Content)
+		m.Summary.TotalMarkdownBytes += len(f.Content)
+		m.Details.MarkdownFiles = append(m.Details.MarkdownFiles, ManifestMarkdownFile{
+			Path:          f.Path,
+			ContentLength: len(f.Content),
+			ContentSHA256: hex.EncodeToString(sum[:]),
+			MediaRefs:     f.MediaRefs,
+			Attributes:    f.Attributes,
+		})
+	}
+	for _, it := range d.Media.Items {
+		sum := it.computedSHA256()
+		e := ManifestMediaItem{
+			ID:             it.ID,
+			Path:           it.Path,
+			MIMEType:       it.MIMEType,
+			DataLength:     len(it.Data),
+			SHA256Computed: hex.EncodeToString(sum[:]),
+			SHA256Valid:    true,
+			Attributes:     it.Attributes,
+		}
+		if it.SHA256 != ([32]byte{}) {
+			e.SHA256Stored = hex.EncodeToString(it.SHA256[:])
+			e.SHA256Valid = it.SHA256 == sum
+		}
+		m.Summary.TotalMediaBytes += len(it.Data)
+		m.Details.MediaItems = append(m.Details.MediaItems, e)
+	}
+	return m
+}
+
+// ManifestJSON returns the manifest of d as JSON indented with two spaces, as
+// in the test suite. The output is stable: parts are listed in bundle order
+// and object keys are sorted.
+func (d *Document) ManifestJSON() ([]byte, error) {
+	return json.MarshalIndent(d.Manifest(), "", "  ")
+}
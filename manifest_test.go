@@ -0,0 +1,51 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestManifestJSON(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].SHA256 = doc.Media.Items[0].computedSHA256()
+	b, err := doc.ManifestJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := doc.ManifestJSON()
+	if !bytes.Equal(b, again) {
+		t.Fatal("manifest JSON is not stable")
+	}
+	if bytes.Contains(b, doc.Markdown.Files[0].Content) {
+		t.Fatal("manifest contains file content")
+	}
+
+	var got struct {
+		Summary map[string]any `json:"summary"`
+		Details struct {
+			Metadata      map[string]any   `json:"metadata"`
+			MarkdownFiles []map[string]any `json:"markdown_files"`
+			MediaItems    []map[string]any `json:"media_items"`
+		} `json:"details"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Summary["markdown_file_count"] != 2.0 || got.Summary["media_item_count"] != 1.0 || got.Summary["total_media_bytes"] != 3.0 {
+		t.Fatalf("unexpected summary: %v", got.Summary)
+	}
+	if got.Details.Metadata["title"] != "Example" || len(got.Details.MarkdownFiles) != 2 {
+		t.Fatalf("unexpected details: %s", b)
+	}
+	item := got.Details.MediaItems[0]
+	if item["id"] != "logo" || item["sha256_valid"] != true || item["sha256_stored"] != item["sha256_computed"] {
+		t.Fatalf("unexpected media item: %v", item)
+	}
+
+	empty := &Document{}
+	b, err = empty.ManifestJSON()
+	if err != nil || !bytes.Contains(b, []byte(`"media_items": []`)) {
+		t.Fatalf("empty manifest: %s, %v", b, err)
+	}
+}
@@ -0,0 +1,289 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Mapped is a read-only MDOCX container backed by a memory-mapped file.
+//
+// OpenMapped parses the fixed header, metadata, Markdown bundle, and an index of
+// the media bundle up front. Media data is never copied: OpenMedia returns an
+// io.SectionReader window over the mapping. If the media section is compressed it
+// is decompressed once at open into a single scratch buffer, so containers meant
+// to be served this way should be written with WithMediaCompression(CompNone).
+//
+// A Mapped is safe for concurrent use by multiple goroutines.
+// Close must not be called while other methods are in use.
+type Mapped struct {
+	data     []byte
+	unmap    func() error
+	metadata map[string]any
+	markdown MarkdownBundle
+	media    []byte
+	items    []mediaEntry
+	byID     map[string]int
+	byPath   map[string]int
+	limits   Limits
+}
+
+// OpenMapped memory-maps the MDOCX file at path and parses its structure.
+// It accepts the same ReadOption values as Decode. Hash verification reads every
+// media item once at open; disable it with WithVerifyHashes(false) for large
+// containers whose integrity is checked elsewhere.
+//
+// On platforms without mmap support the file is read into memory instead.
+func OpenMapped(path string, opts ...ReadOption) (*Mapped, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, unmap, err := mmapFile(f, st.Size())
+	if err != nil {
+		return nil, err
+	}
+	m, err := newMapped(data, newReadConfig(opts))
+	if err != nil {
+		_ = unmap()
+		return nil, err
+	}
+	m.unmap = unmap
+	return m, nil
+}
+
+// newMapped parses an MDOCX container held entirely in data.
+func newMapped(data []byte, cfg readConfig) (*Mapped, error) {
+	r := bytes.NewReader(data)
+	h, err := readFixedHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFixedHeader(h, cfg.limits); err != nil {
+		return nil, err
+	}
+	m := &Mapped{data: data, limits: cfg.limits}
+	off := int64(fixedHeaderSizeV1)
+	if h.MetadataLength > 0 {
+		mb, err := sliceAt(data, off, uint64(h.MetadataLength))
+		if err != nil {
+			return nil, err
+		}
+		if m.metadata, err = parseMetadata(h, mb); err != nil {
+			return nil, err
+		}
+		off += int64(h.MetadataLength)
+	}
+
+	mdSec, mdPayload, err := mappedSection(data, &off, SectionMarkdown, cfg.limits.MaxMarkdownSectionLen)
+	if err != nil {
+		return nil, err
+	}
+	if m.markdown, err = decodeMarkdownPayload(context.Background(), mdSec, mdPayload, payloadFormat(h.HeaderFlags), cfg.limits); err != nil {
+		return nil, err
+	}
+
+	mediaSec, mediaPayload, err := mappedSection(data, &off, SectionMedia, cfg.limits.MaxMediaSectionLen)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkNoMedia(h, mediaSec); err != nil {
+		return nil, err
+	}
+	bundleVersion := VersionV1
+	if len(mediaPayload) > 0 {
+		if m.media, err = decompressPayload(mediaSec.compression(), mediaSec.SectionFlags, mediaPayload, cfg.limits.MaxMediaUncompressed); err != nil {
+			return nil, err
+		}
+		idx, err := payloadFormat(h.HeaderFlags).scanMediaBytes(m.media, cfg.limits.MaxMediaItems)
+		if err != nil {
+			return nil, err
+		}
+		if mediaSec.SectionFlags&sectionFlagItemCompression != 0 {
+			if err := idx.unpackEntries(bytes.NewReader(m.media)); err != nil {
+				return nil, err
+			}
+		}
+		bundleVersion = idx.bundleVersion
+		m.items = idx.items
+	}
+
+	if err := m.validate(bundleVersion, cfg); err != nil {
+		return nil, err
+	}
+	m.byID = make(map[string]int, len(m.items))
+	m.byPath = make(map[string]int, len(m.items))
+	for i, it := range m.items {
+		m.byID[it.ID] = i
+		if it.Path != "" {
+			m.byPath[it.Path] = i
+		}
+	}
+	return m, nil
+}
+
+// mappedSection reads the section header at *off, validates it, and returns the
+// payload as a subslice of data. *off is advanced past the payload.
+func mappedSection(data []byte, off *int64, want SectionType, maxLen uint64) (sectionHeaderV1, []byte, error) {
+	hb, err := sliceAt(data, *off, 16)
+	if err != nil {
+		return sectionHeaderV1{}, nil, err
+	}
+	sh, err := readSectionHeader(bytes.NewReader(hb))
+	if err != nil {
+		return sh, nil, err
+	}
+	if err := validateSectionHeader(sh, want); err != nil {
+		return sh, nil, err
+	}
+	if sh.PayloadLen > maxLen {
+		if want == SectionMarkdown {
+			return sh, nil, exceeds("MaxMarkdownSectionLen", "markdown section too large")
+		}
+		return sh, nil, exceeds("MaxMediaSectionLen", "media section too large")
+	}
+	payload, err := sliceAt(data, *off+16, sh.PayloadLen)
+	if err != nil {
+		return sh, nil, err
+	}
+	*off += 16 + int64(sh.PayloadLen)
+	return sh, payload, nil
+}
+
+// sliceAt returns data[off:off+n] or io.ErrUnexpectedEOF if it is out of range.
+func sliceAt(data []byte, off int64, n uint64) ([]byte, error) {
+	if off > int64(len(data)) || n > uint64(int64(len(data))-off) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data[off : off+int64(n)], nil
+}
+
+// validate applies document validation to the parsed structure without
+// materializing media data.
+func (m *Mapped) validate(bundleVersion uint16, cfg readConfig) error {
+	doc := &Document{
+		Metadata: m.metadata,
+		Markdown: m.markdown,
+		Media:    MediaBundle{BundleVersion: bundleVersion, Items: make([]MediaItem, len(m.items))},
+	}
+	for i, it := range m.items {
+		doc.Media.Items[i] = MediaItem{ID: it.ID, Path: it.Path}
+	}
+	if err := validateDocument(doc, cfg.limits, false); err != nil {
+		return err
+	}
+	if err := doc.CheckInvariants(cfg.checks); err != nil {
+		return err
+	}
+	for _, it := range m.items {
+		if uint64(it.dataSize()) > cfg.limits.MaxSingleMediaSize {
+			return exceeds("MaxSingleMediaSize", "media item %q too large", it.ID)
+		}
+		if cfg.verifies(it.ID) && it.SHA256 != ([32]byte{}) {
+			data, err := it.unpack(m.media[it.dataOff:it.dataOff+it.dataLen], cfg.limits)
+			if err != nil {
+				return err
+			}
+			computed := sha256.Sum256(data)
+			if subtle.ConstantTimeCompare(computed[:], it.SHA256[:]) != 1 {
+				return fmt.Errorf("%w: media item %q SHA256 mismatch", ErrValidation, it.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// Close releases the memory mapping. Readers returned by OpenMedia must not be
+// used after Close.
+func (m *Mapped) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	err := m.unmap()
+	m.unmap = nil
+	m.data, m.media = nil, nil
+	return err
+}
+
+// Metadata returns the document metadata, or nil if the container has none.
+func (m *Mapped) Metadata() map[string]any {
+	return m.metadata
+}
+
+// Markdown returns the decoded Markdown bundle.
+// The returned bundle shares memory with m and must not be modified.
+func (m *Mapped) Markdown() MarkdownBundle {
+	return m.markdown
+}
+
+// MarkdownFile returns the Markdown file with the given container path.
+func (m *Mapped) MarkdownFile(path string) (MarkdownFile, bool) {
+	for _, f := range m.markdown.Files {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return MarkdownFile{}, false
+}
+
+// Media lists the media items in bundle order without their data.
+func (m *Mapped) Media() []MediaInfo {
+	out := make([]MediaInfo, len(m.items))
+	for i, it := range m.items {
+		out[i] = it.info()
+	}
+	return out
+}
+
+// OpenMedia returns a reader over the data of the media item with the given ID.
+// It returns ErrNotFound if no such item exists.
+func (m *Mapped) OpenMedia(id string) (*io.SectionReader, error) {
+	i, ok := m.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: media item %q", ErrNotFound, id)
+	}
+	return m.section(i)
+}
+
+// OpenMediaPath returns a reader over the data of the media item with the given
+// container path. It returns ErrNotFound if no item has that path.
+func (m *Mapped) OpenMediaPath(path string) (*io.SectionReader, error) {
+	i, ok := m.byPath[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: media path %q", ErrNotFound, path)
+	}
+	return m.section(i)
+}
+
+func (m *Mapped) section(i int) (*io.SectionReader, error) {
+	it := m.items[i]
+	if it.packed != CompNone {
+		data, err := it.unpack(m.media[it.dataOff:it.dataOff+it.dataLen], m.limits)
+		if err != nil {
+			return nil, err
+		}
+		return io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data))), nil
+	}
+	return io.NewSectionReader(bytes.NewReader(m.media), it.dataOff, it.dataLen), nil
+}
+
+// info returns the MediaInfo for an indexed entry.
+func (e mediaEntry) info() MediaInfo {
+	return MediaInfo{
+		ID:         e.ID,
+		Path:       e.Path,
+		MIMEType:   e.MIMEType,
+		Size:       e.dataSize(),
+		SHA256:     e.SHA256,
+		Attributes: e.Attributes,
+	}
+}
@@ -0,0 +1,114 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTempContainer(t *testing.T, doc *Document, opts ...WriteOption) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, opts...); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(t.TempDir(), "doc.mdocx")
+	if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestOpenMapped(t *testing.T) {
+	for _, comp := range []Compression{CompNone, CompZSTD} {
+		t.Run("comp="+compressionName(comp), func(t *testing.T) {
+			doc := sampleDoc()
+			doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "clip", MIMEType: "video/mp4", Data: bytes.Repeat([]byte("v"), 5000)})
+			p := writeTempContainer(t, doc, WithMediaCompression(comp))
+
+			m, err := OpenMapped(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+
+			if !reflect.DeepEqual(m.Metadata(), doc.Metadata) {
+				t.Fatalf("metadata mismatch: %v", m.Metadata())
+			}
+			if !reflect.DeepEqual(m.Markdown(), doc.Markdown) {
+				t.Fatal("markdown mismatch")
+			}
+			if f, ok := m.MarkdownFile("docs/notes.md"); !ok || string(f.Content) != "Some notes\n" {
+				t.Fatal("MarkdownFile lookup failed")
+			}
+			infos := m.Media()
+			if len(infos) != 2 || infos[1].ID != "clip" || infos[1].Size != 5000 || infos[1].SHA256 != doc.Media.Items[1].SHA256 {
+				t.Fatalf("unexpected media listing: %+v", infos)
+			}
+			sr, err := m.OpenMedia("clip")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(sr)
+			if err != nil || !bytes.Equal(got, doc.Media.Items[1].Data) {
+				t.Fatalf("media data mismatch: %v", err)
+			}
+			sr, err = m.OpenMediaPath("assets/logo.png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var three [3]byte
+			if _, err := sr.ReadAt(three[:], 0); err != nil || !bytes.Equal(three[:], []byte{1, 2, 3}) {
+				t.Fatalf("ReadAt mismatch: %v %v", three, err)
+			}
+			if _, err := m.OpenMedia("nope"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+			if _, err := m.OpenMediaPath("nope"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenMapped_Errors(t *testing.T) {
+	doc := sampleDoc()
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	// Corrupt the last byte of the logo data so its hash no longer matches.
+	i := bytes.LastIndex(b, []byte{1, 2, 3})
+	bad := append([]byte(nil), b...)
+	bad[i+2] = 9
+	if _, err := newMapped(bad, newReadConfig(nil)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	if _, err := newMapped(bad, newReadConfig([]ReadOption{WithVerifyHashes(false)})); err != nil {
+		t.Fatalf("expected success without hash verification, got %v", err)
+	}
+
+	if _, err := newMapped(b[:len(b)-1], newReadConfig(nil)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if _, err := newMapped(b, newReadConfig([]ReadOption{WithReadLimits(Limits{MaxSingleMediaSize: 1})})); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	if _, err := newMapped(b, newReadConfig([]ReadOption{WithReadLimits(Limits{MaxMediaSectionLen: 1})})); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	magic := append([]byte(nil), b...)
+	magic[0] = 'X'
+	if _, err := newMapped(magic, newReadConfig(nil)); !errors.Is(err, ErrInvalidMagic) {
+		t.Fatalf("expected ErrInvalidMagic, got %v", err)
+	}
+	if _, err := OpenMapped(filepath.Join(t.TempDir(), "missing.mdocx")); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -3,9 +3,11 @@ package mdocx
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -76,7 +78,7 @@ func TestWireRoundtrip(t *testing.T) {
 }
 
 func TestEncodeDecodeRoundTrip_AllCompressions(t *testing.T) {
-	comps := []Compression{CompNone, CompZIP, CompZSTD, CompLZ4, CompBR}
+	comps := []Compression{CompNone, CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ}
 	for _, comp := range comps {
 		t.Run("comp="+compressionName(comp), func(t *testing.T) {
 			doc := sampleDoc()
@@ -151,7 +153,7 @@ func TestDecode_UnsupportedVersion(t *testing.T) {
 		t.Fatal(err)
 	}
 	b := buf.Bytes()
-	binary.LittleEndian.PutUint16(b[8:10], 2)
+	binary.LittleEndian.PutUint16(b[8:10], 3)
 	_, err := Decode(bytes.NewReader(b))
 	if !errors.Is(err, ErrUnsupportedVersion) {
 		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
@@ -208,6 +210,38 @@ func TestDecode_MetadataInvalidJSON(t *testing.T) {
 	}
 }
 
+func TestMetadataError(t *testing.T) {
+	h := fixedHeaderV1{HeaderFlags: HeaderFlagMetadataJSON}
+	mb := []byte("{\n  \"title\": \"x\",\n  \"notes\": \"" + strings.Repeat("a", 100) + "\"\n  \"bad\": 1\n" + strings.Repeat(" ", 100) + "}")
+	_, err := parseMetadata(h, mb)
+	var me *MetadataError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected *MetadataError, got %v", err)
+	}
+	var syntax *json.SyntaxError
+	if !errors.Is(err, ErrInvalidHeader) || !errors.As(err, &syntax) || Code(err) != CodeInvalidMetadata {
+		t.Fatalf("error %v does not match ErrInvalidHeader and *json.SyntaxError", err)
+	}
+	if me.Line != 4 || me.Column != 3 || int(me.Offset) != bytes.Index(mb, []byte(`"bad"`)) {
+		t.Fatalf("position: line %d column %d offset %d", me.Line, me.Column, me.Offset)
+	}
+	if !strings.HasPrefix(me.Snippet, "...") || !strings.HasSuffix(me.Snippet, "...") || !strings.Contains(me.Snippet, `"bad": 1`) || strings.Contains(me.Snippet, "\n") {
+		t.Fatalf("snippet %q", me.Snippet)
+	}
+	if len(me.Snippet) > metadataSnippetLen+6 {
+		t.Fatalf("snippet too long: %d", len(me.Snippet))
+	}
+
+	_, err = parseMetadata(h, []byte("[1, 2]"))
+	if !errors.As(err, &me) || me.Offset != 0 || me.Snippet != "[1, 2]" {
+		t.Fatalf("type error: %v", err)
+	}
+	_, err = parseMetadata(h, []byte("{\"a\": \"\xff\x01"))
+	if !errors.As(err, &me) || me.Snippet != "{\"a\": \"\uFFFD " {
+		t.Fatalf("truncated: %v", err)
+	}
+}
+
 func TestDecode_SectionTypeMismatch(t *testing.T) {
 	doc := sampleDoc()
 	var buf bytes.Buffer
@@ -360,7 +394,7 @@ func TestDecompressPayload_UncompressedLenLimitExceeded(t *testing.T) {
 }
 
 func TestCompressPayload_UnknownCompression(t *testing.T) {
-	_, _, err := compressPayload(Compression(99), []byte("x"))
+	_, _, err := compressPayload(Compression(99), []byte("x"), defaultCodecTuning())
 	if err == nil {
 		t.Fatal("expected error")
 	}
@@ -430,7 +464,82 @@ func compressionName(c Compression) string {
 		return "lz4"
 	case CompBR:
 		return "br"
+	case CompXZ:
+		return "xz"
 	default:
 		return "unknown"
 	}
 }
+
+func TestNoMediaRoundTrip(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media = MediaBundle{}
+	doc.NoMedia = true
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	h, err := readFixedHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.HeaderFlags&HeaderFlagNoMedia == 0 {
+		t.Fatal("NO_MEDIA flag not written")
+	}
+	got, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.NoMedia || got.Media.BundleVersion != VersionV1 || len(got.Media.Items) != 0 {
+		t.Fatalf("unexpected media: %+v NoMedia=%v", got.Media, got.NoMedia)
+	}
+
+	var plain bytes.Buffer
+	if err := Encode(&plain, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Decode(bytes.NewReader(plain.Bytes())); err != nil || got.NoMedia {
+		t.Fatalf("NoMedia must be false without the flag: %v", err)
+	}
+
+	doc.Media.Items = []MediaItem{{ID: "x", Data: []byte{1}}}
+	if err := Encode(&bytes.Buffer{}, doc); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation for items with NoMedia, got %v", err)
+	}
+
+	// A flagged container with a media payload is malformed.
+	b := plain.Bytes()
+	b[10] |= byte(HeaderFlagNoMedia)
+	if _, err := Decode(bytes.NewReader(b)); !errors.Is(err, ErrInvalidSection) {
+		t.Fatalf("expected ErrInvalidSection, got %v", err)
+	}
+}
+
+func TestEncodeQuota(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	size := uint64(buf.Len())
+
+	buf.Reset()
+	if err := Encode(&buf, sampleDoc(), WithQuota(size)); err != nil {
+		t.Fatalf("exact quota: %v", err)
+	}
+	if uint64(buf.Len()) != size {
+		t.Fatalf("size changed: %d != %d", buf.Len(), size)
+	}
+
+	buf.Reset()
+	err := Encode(&buf, sampleDoc(), WithQuota(size-1))
+	var qe *QuotaError
+	if !errors.As(err, &qe) || !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected QuotaError, got %v", err)
+	}
+	if qe.Needed != size || qe.Remaining != size-1 {
+		t.Fatalf("unexpected error fields: %+v", qe)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("wrote %d bytes despite quota", buf.Len())
+	}
+}
@@ -0,0 +1,158 @@
+// Package mdocxhttp serves the contents of an MDOCX document over HTTP.
+//
+// Handler serves each Markdown file and each media item that has a Path at its
+// container path, so that relative links between them keep working in a
+// browser.
+package mdocxhttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// Renderer renders a Markdown file to a complete HTML page.
+type Renderer func(f mdocx.MarkdownFile) ([]byte, error)
+
+// config holds configuration options for Handler.
+type config struct {
+	render  Renderer
+	modTime time.Time
+}
+
+// Option is a functional option for configuring Handler.
+type Option func(*config)
+
+// WithRenderer makes Handler serve Markdown files as the HTML produced by r.
+// The Markdown source stays available by adding the query parameter "raw" to
+// the URL. By default Markdown files are served as text/markdown.
+func WithRenderer(r Renderer) Option {
+	return func(c *config) { c.render = r }
+}
+
+// WithModTime sets the Last-Modified time of every response, enabling
+// If-Modified-Since requests. By default no Last-Modified header is sent.
+func WithModTime(t time.Time) Option {
+	return func(c *config) { c.modTime = t }
+}
+
+// entry is a file served by Handler.
+type entry struct {
+	data        []byte
+	contentType string
+	etag        string
+	// markdown is set for Markdown files.
+	markdown *mdocx.MarkdownFile
+}
+
+// handler is the http.Handler returned by Handler.
+type handler struct {
+	cfg   config
+	files map[string]*entry
+	root  string
+}
+
+// Handler returns an http.Handler that serves the Markdown files and media
+// items of doc at their container paths; "/" serves the root Markdown file.
+// Use http.StripPrefix to mount it below another path.
+//
+// Media items are served with their MIMEType and Markdown files as
+// "text/markdown; charset=utf-8". Every response carries a strong ETag derived
+// from the SHA-256 of its body (the item's SHA256 field for media), and
+// conditional and Range requests are supported, so audio and video can be
+// seeked. Media items come with "Content-Security-Policy: sandbox", so that
+// an HTML or SVG item from an untrusted container cannot run scripts on the
+// serving origin. Only GET and HEAD are allowed. Media items without a Path
+// are not served, and a Markdown file takes precedence over a media item at
+// the same path.
+//
+// doc must not be modified while the handler is in use.
+func Handler(doc *mdocx.Document, opts ...Option) http.Handler {
+	h := &handler{files: make(map[string]*entry)}
+	for _, opt := range opts {
+		opt(&h.cfg)
+	}
+	for i := range doc.Markdown.Files {
+		f := &doc.Markdown.Files[i]
+		if _, dup := h.files[f.Path]; dup {
+			continue
+		}
+		h.files[f.Path] = &entry{
+			data:        f.Content,
+			contentType: "text/markdown; charset=utf-8",
+			etag:        etag(sha256.Sum256(f.Content)),
+			markdown:    f,
+		}
+	}
+	for _, it := range doc.Media.Items {
+		if it.Path == "" {
+			continue
+		}
+		if _, dup := h.files[it.Path]; dup {
+			continue
+		}
+		sum := it.SHA256
+		if sum == ([32]byte{}) {
+			sum = sha256.Sum256(it.Data)
+		}
+		ct := it.MIMEType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		h.files[it.Path] = &entry{data: it.Data, contentType: ct, etag: etag(sum)}
+	}
+	h.root = doc.Markdown.RootPath
+	if h.root == "" {
+		h.root, _ = doc.Metadata["root"].(string)
+	}
+	if h.root == "" && len(doc.Markdown.Files) > 0 {
+		h.root = doc.Markdown.Files[0].Path
+	}
+	return h
+}
+
+// etag formats a SHA-256 sum as a strong entity tag.
+func etag(sum [32]byte) string {
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	p := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if p == "" {
+		p = h.root
+	}
+	e, ok := h.files[p]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	data, contentType, tag := e.data, e.contentType, e.etag
+	if e.markdown != nil && h.cfg.render != nil && !r.URL.Query().Has("raw") {
+		page, err := h.cfg.render(*e.markdown)
+		if err != nil {
+			http.Error(w, "mdocxhttp: render "+p+": "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, contentType, tag = page, "text/html; charset=utf-8", etag(sha256.Sum256(page))
+	}
+	hdr := w.Header()
+	hdr.Set("Content-Type", contentType)
+	hdr.Set("ETag", tag)
+	hdr.Set("X-Content-Type-Options", "nosniff")
+	if e.markdown == nil {
+		hdr.Set("Content-Security-Policy", "sandbox")
+	}
+	http.ServeContent(w, r, path.Base(p), h.cfg.modTime, bytes.NewReader(data))
+}
@@ -0,0 +1,122 @@
+package mdocxhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func testDoc() *mdocx.Document {
+	return &mdocx.Document{
+		Markdown: mdocx.MarkdownBundle{
+			BundleVersion: mdocx.VersionV1,
+			RootPath:      "docs/index.md",
+			Files: []mdocx.MarkdownFile{
+				{Path: "docs/notes.md", Content: []byte("Notes\n")},
+				{Path: "docs/index.md", Content: []byte("# Title\n\n![clip](../media/clip.mp4)\n")},
+			},
+		},
+		Media: mdocx.MediaBundle{
+			BundleVersion: mdocx.VersionV1,
+			Items: []mdocx.MediaItem{
+				{ID: "clip", Path: "media/clip.mp4", MIMEType: "video/mp4", Data: []byte("0123456789")},
+				{ID: "hidden", MIMEType: "image/png", Data: []byte{1}},
+			},
+		},
+	}
+}
+
+func get(t *testing.T, h http.Handler, method, target string, hdr map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(testDoc())
+
+	rec := get(t, h, "GET", "/", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "# Title") {
+		t.Fatalf("root: %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/markdown; charset=utf-8" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	rec = get(t, h, "GET", "/media/clip.mp4", map[string]string{"Range": "bytes=2-5"})
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "2345" {
+		t.Fatalf("range: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "video/mp4" || rec.Header().Get("Content-Range") != "bytes 2-5/10" {
+		t.Fatalf("range headers: %v", rec.Header())
+	}
+	tag := rec.Header().Get("ETag")
+	if len(tag) != 66 || tag[0] != '"' {
+		t.Fatalf("ETag = %q", tag)
+	}
+	if rec := get(t, h, "GET", "/media/clip.mp4", map[string]string{"If-None-Match": tag}); rec.Code != http.StatusNotModified {
+		t.Fatalf("conditional: %d", rec.Code)
+	}
+
+	for _, p := range []string{"/missing.md", "/hidden", "/docs", "/../docs/index.md/x"} {
+		if rec := get(t, h, "GET", p, nil); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: %d", p, rec.Code)
+		}
+	}
+	if rec := get(t, h, "GET", "/docs/../docs/notes.md", nil); rec.Code != http.StatusOK || rec.Body.String() != "Notes\n" {
+		t.Fatalf("cleaned path: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := get(t, h, "POST", "/", nil); rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Fatalf("POST: %d", rec.Code)
+	}
+}
+
+func TestHandlerActiveMedia(t *testing.T) {
+	doc := testDoc()
+	doc.Media.Items = append(doc.Media.Items, mdocx.MediaItem{ID: "page", Path: "media/page.html", MIMEType: "text/html", Data: []byte("<script>alert(1)</script>")})
+	h := Handler(doc, WithRenderer(func(f mdocx.MarkdownFile) ([]byte, error) { return f.Content, nil }))
+
+	rec := get(t, h, "GET", "/media/page.html", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Security-Policy") != "sandbox" {
+		t.Fatalf("HTML media item: %d %v", rec.Code, rec.Header())
+	}
+	if rec := get(t, h, "GET", "/", nil); rec.Header().Get("Content-Security-Policy") != "" {
+		t.Fatalf("rendered page: %v", rec.Header())
+	}
+}
+
+func TestHandlerRenderer(t *testing.T) {
+	render := func(f mdocx.MarkdownFile) ([]byte, error) {
+		if f.Path == "docs/notes.md" {
+			return nil, errors.New("boom")
+		}
+		return []byte("<h1>" + f.Path + "</h1>"), nil
+	}
+	mod := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	h := Handler(testDoc(), WithRenderer(render), WithModTime(mod))
+
+	rec := get(t, h, "GET", "/docs/index.md", nil)
+	if rec.Body.String() != "<h1>docs/index.md</h1>" || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("rendered: %q %v", rec.Body.String(), rec.Header())
+	}
+	if rec.Header().Get("Last-Modified") != "Wed, 01 May 2024 00:00:00 GMT" {
+		t.Fatalf("Last-Modified = %q", rec.Header().Get("Last-Modified"))
+	}
+	raw := get(t, h, "GET", "/docs/index.md?raw", nil)
+	if !strings.HasPrefix(raw.Body.String(), "# Title") || raw.Header().Get("ETag") == rec.Header().Get("ETag") {
+		t.Fatalf("raw: %q, ETag %q", raw.Body.String(), raw.Header().Get("ETag"))
+	}
+	if rec := get(t, h, "GET", "/docs/notes.md", nil); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("render error: %d", rec.Code)
+	}
+}
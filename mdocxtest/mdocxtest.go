@@ -0,0 +1,170 @@
+// Package mdocxtest generates synthetic MDOCX containers of a given shape,
+// for load tests, capacity planning, and benchmarks of services that embed
+// the decoder.
+//
+// A Shape sets the number and size of the Markdown files and media items and
+// how well the media data compresses; the section compression is chosen with
+// the usual write options. The same Shape always yields the same document, so
+// that results can be reproduced and compared across releases.
+package mdocxtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+// Shape describes a synthetic document.
+type Shape struct {
+	// Files is the number of Markdown files. At least one is required.
+	Files int
+	// FileSize is the size in bytes of each Markdown file. A file is larger
+	// if its heading and media references need more room.
+	FileSize int
+	// Media is the number of media items. Each is referenced from one
+	// Markdown file, in turn.
+	Media int
+	// MediaSize is the size in bytes of each item's data.
+	MediaSize int
+	// Compressible is the fraction, from 0 to 1, of media items whose data is
+	// text (image/svg+xml) that compresses well. The others hold random bytes
+	// (image/jpeg), like already-compressed photos. Compressible items are
+	// spread evenly over the bundle.
+	Compressible float64
+	// Seed seeds the generator. Different seeds give different content of the
+	// same shape.
+	Seed uint64
+}
+
+// Document returns a document of shape s. Media items have their SHA256 set.
+func (s Shape) Document() (*mdocx.Document, error) {
+	switch {
+	case s.Files < 1:
+		return nil, errors.New("mdocxtest: Shape.Files must be at least 1")
+	case s.FileSize < 0 || s.Media < 0 || s.MediaSize < 0:
+		return nil, errors.New("mdocxtest: Shape sizes and counts must not be negative")
+	case !(s.Compressible >= 0 && s.Compressible <= 1):
+		return nil, errors.New("mdocxtest: Shape.Compressible must be between 0 and 1")
+	}
+	rng := rand.New(rand.NewPCG(s.Seed, 0x6d646f6378))
+	doc := &mdocx.Document{
+		Metadata: map[string]any{"title": fmt.Sprintf("Synthetic %d×%d files, %d×%d media", s.Files, s.FileSize, s.Media, s.MediaSize)},
+		Markdown: mdocx.MarkdownBundle{BundleVersion: mdocx.VersionV1, Files: make([]mdocx.MarkdownFile, s.Files)},
+		Media:    mdocx.MediaBundle{BundleVersion: mdocx.VersionV1, Items: make([]mdocx.MediaItem, s.Media)},
+	}
+
+	for i := range doc.Media.Items {
+		it := &doc.Media.Items[i]
+		it.ID = "m" + pad(i, s.Media)
+		if compressible(i, s.Compressible) {
+			it.Path, it.MIMEType, it.Data = "media/"+it.ID+".svg", "image/svg+xml", svgData(rng, s.MediaSize)
+		} else {
+			it.Path, it.MIMEType, it.Data = "media/"+it.ID+".jpg", "image/jpeg", randomData(rng, s.MediaSize)
+		}
+		it.SHA256 = sha256.Sum256(it.Data)
+	}
+
+	for i := range doc.Markdown.Files {
+		f := &doc.Markdown.Files[i]
+		f.Path = "docs/" + pad(i, s.Files) + ".md"
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "# Document %d\n\n", i+1)
+		for j := i; j < s.Media; j += s.Files {
+			id := doc.Media.Items[j].ID
+			fmt.Fprintf(&b, "![%s](mdocx://media/%s)\n\n", id, id)
+			f.MediaRefs = append(f.MediaRefs, id)
+		}
+		head := b.Len()
+		for b.Len() < s.FileSize {
+			writeParagraph(&b, rng)
+		}
+		f.Content = b.Bytes()[:max(head, s.FileSize)]
+	}
+	doc.Markdown.RootPath = doc.Markdown.Files[0].Path
+	return doc, nil
+}
+
+// Generate encodes a document of shape s to w with the given options, such as
+// mdocx.WithMediaCompression. Shapes beyond the default limits need
+// mdocx.WithWriteLimits.
+func Generate(w io.Writer, s Shape, opts ...mdocx.WriteOption) error {
+	doc, err := s.Document()
+	if err != nil {
+		return err
+	}
+	return mdocx.Encode(w, doc, opts...)
+}
+
+// compressible reports whether item i is one of the compressible fraction c
+// of the items, spread evenly.
+func compressible(i int, c float64) bool {
+	return int(float64(i+1)*c) > int(float64(i)*c)
+}
+
+// pad formats i with as many digits as the largest index below n, and at
+// least four, so that paths sort in order.
+func pad(i, n int) string {
+	s := strconv.Itoa(i)
+	width := max(4, len(strconv.Itoa(n-1)))
+	for len(s) < width {
+		s = "0" + s
+	}
+	return s
+}
+
+// words is the vocabulary of the generated text.
+var words = []string{
+	"container", "markdown", "media", "section", "bundle", "payload", "header",
+	"archive", "document", "render", "stream", "decode", "encode", "index",
+	"the", "a", "of", "and", "to", "in", "is", "for", "with", "each", "every",
+	"file", "item", "image", "table", "link", "note", "chapter", "reference",
+}
+
+// writeParagraph writes a paragraph of random words, wrapped at about 72
+// columns, and a blank line.
+func writeParagraph(b *bytes.Buffer, rng *rand.Rand) {
+	col := 0
+	for range 20 + rng.IntN(60) {
+		w := words[rng.IntN(len(words))]
+		if col > 0 && col+len(w) > 72 {
+			b.WriteByte('\n')
+			col = 0
+		} else if col > 0 {
+			b.WriteByte(' ')
+			col++
+		}
+		b.WriteString(w)
+		col += len(w)
+	}
+	b.WriteString(".\n\n")
+}
+
+// svgData returns n bytes of SVG-like text.
+func svgData(rng *rand.Rand, n int) []byte {
+	var b bytes.Buffer
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 1000 1000">` + "\n")
+	for b.Len() < n {
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="#%06x"/>`+"\n",
+			rng.IntN(1000), rng.IntN(1000), 1+rng.IntN(200), 1+rng.IntN(200), rng.IntN(8)*0x202020)
+	}
+	return b.Bytes()[:n]
+}
+
+// randomData returns n random bytes.
+func randomData(rng *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	for i := 0; i < n; i += 8 {
+		v := rng.Uint64()
+		for j := i; j < min(i+8, n); j++ {
+			b[j] = byte(v)
+			v >>= 8
+		}
+	}
+	return b
+}
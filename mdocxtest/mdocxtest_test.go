@@ -0,0 +1,94 @@
+package mdocxtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/logicossoftware/go-mdocx"
+)
+
+func TestGenerate(t *testing.T) {
+	shape := Shape{Files: 3, FileSize: 2000, Media: 4, MediaSize: 5000, Compressible: 0.5, Seed: 7}
+	var a, b bytes.Buffer
+	if err := Generate(&a, shape, mdocx.WithSmartMediaCompression(true)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Generate(&b, shape, mdocx.WithSmartMediaCompression(true)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatal("the same shape generated different containers")
+	}
+
+	doc, err := mdocx.Decode(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Markdown.Files) != 3 || len(doc.Media.Items) != 4 {
+		t.Fatalf("decoded %d files and %d items", len(doc.Markdown.Files), len(doc.Media.Items))
+	}
+	svg := 0
+	for _, it := range doc.Media.Items {
+		if len(it.Data) != 5000 {
+			t.Errorf("%s: %d bytes", it.ID, len(it.Data))
+		}
+		if it.MIMEType == "image/svg+xml" {
+			svg++
+		}
+	}
+	if svg != 2 {
+		t.Errorf("%d compressible items, want 2", svg)
+	}
+	for _, f := range doc.Markdown.Files {
+		if len(f.Content) != 2000 {
+			t.Errorf("%s: %d bytes", f.Path, len(f.Content))
+		}
+	}
+	if refs := doc.Markdown.Files[0].MediaRefs; len(refs) != 2 || !strings.Contains(string(doc.Markdown.Files[0].Content), "mdocx://media/"+refs[1]) {
+		t.Errorf("file 0 refs = %v", refs)
+	}
+	if broken := mdocx.CheckReferences(doc); len(broken) != 0 {
+		t.Errorf("broken references: %v", broken)
+	}
+
+	// Another seed gives other content of the same size.
+	other, err := Shape{Files: 3, FileSize: 2000, Media: 4, MediaSize: 5000, Compressible: 0.5, Seed: 8}.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other.Media.Items[1].Data, doc.Media.Items[1].Data) {
+		t.Error("seed does not change the content")
+	}
+
+	for _, bad := range []Shape{{}, {Files: 1, Media: -1}, {Files: 1, Compressible: 2}} {
+		if _, err := bad.Document(); err == nil {
+			t.Errorf("%+v: no error", bad)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []mdocx.WriteOption
+	}{
+		{"none", []mdocx.WriteOption{mdocx.WithMarkdownCompression(mdocx.CompNone), mdocx.WithMediaCompression(mdocx.CompNone)}},
+		{"zstd", nil},
+		{"smart", []mdocx.WriteOption{mdocx.WithSmartMediaCompression(true)}},
+	} {
+		var buf bytes.Buffer
+		shape := Shape{Files: 50, FileSize: 8 << 10, Media: 20, MediaSize: 64 << 10, Compressible: 0.3}
+		if err := Generate(&buf, shape, bc.opts...); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bc.name, func(b *testing.B) {
+			b.SetBytes(int64(buf.Len()))
+			for b.Loop() {
+				if _, err := mdocx.Decode(bytes.NewReader(buf.Bytes())); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
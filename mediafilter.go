@@ -0,0 +1,68 @@
+package mdocx
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// MediaFilter reports whether a media item should be kept by Decode and
+// DecodeInto, given its ID, container path, MIME type, and data size in bytes.
+type MediaFilter func(id, path, mimeType string, size int64) bool
+
+// WithMediaFilter makes Decode and DecodeInto drop the media items for which f
+// returns false, for callers that only need part of the media, such as a text
+// preview that skips all video. Dropped items are located in the decompressed
+// Media section without copying their data and are neither validated nor
+// delivered; optional invariants (see WithChecks) still see every item.
+func WithMediaFilter(f MediaFilter) ReadOption {
+	return func(c *readConfig) { c.mediaFilter = f }
+}
+
+// keeps reports whether the media item described by e passes the media filter.
+func (c readConfig) keeps(e *mediaEntry) bool {
+	return c.mediaFilter == nil || c.mediaFilter(e.ID, e.Path, e.MIMEType, e.dataSize())
+}
+
+// decodeFilteredMedia decodes the media items of the MediaBundle in mediaGob,
+// serialized in format f, that pass the media filter, copying only their
+// data. It also returns the IDs and paths of all items in bundle order.
+// itemComp reports whether the section was written with
+// WithSmartMediaCompression.
+func decodeFilteredMedia(mediaGob []byte, f PayloadFormat, itemComp bool, cfg readConfig) (MediaBundle, []MediaItem, error) {
+	idx, err := f.scanMediaBytes(mediaGob, cfg.limits.MaxMediaItems)
+	if err != nil {
+		return MediaBundle{}, nil, err
+	}
+	if itemComp {
+		if err := idx.unpackEntries(bytes.NewReader(mediaGob)); err != nil {
+			return MediaBundle{}, nil, err
+		}
+	}
+	if idx.bundleVersion != VersionV1 {
+		return MediaBundle{}, nil, fmt.Errorf("%w: Media.BundleVersion must be %d", ErrValidation, VersionV1)
+	}
+	media := MediaBundle{BundleVersion: idx.bundleVersion}
+	all := make([]MediaItem, len(idx.items))
+	for i := range idx.items {
+		e := &idx.items[i]
+		all[i] = MediaItem{ID: e.ID, Path: e.Path}
+		if !cfg.keeps(e) {
+			continue
+		}
+		data := mediaGob[e.dataOff : e.dataOff+e.dataLen]
+		if e.packed == CompNone {
+			data = bytes.Clone(data)
+		} else if data, err = e.unpack(data, cfg.limits); err != nil {
+			return MediaBundle{}, nil, err
+		}
+		media.Items = append(media.Items, MediaItem{
+			ID:         e.ID,
+			Path:       e.Path,
+			MIMEType:   e.MIMEType,
+			Data:       data,
+			SHA256:     e.SHA256,
+			Attributes: e.Attributes,
+		})
+	}
+	return media, all, nil
+}
@@ -0,0 +1,51 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeMediaFilter(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "clip", Path: "assets/clip.mp4", MIMEType: "video/mp4", Data: []byte("frames")})
+	doc.Markdown.Files[0].MediaRefs = []string{"clip"}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	noVideo := WithMediaFilter(func(id, path, mimeType string, size int64) bool {
+		return !strings.HasPrefix(mimeType, "video/")
+	})
+
+	got, err := Decode(bytes.NewReader(buf.Bytes()), noVideo, WithChecks(CheckMediaRefs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Media.Items) != 1 || got.Media.Items[0].ID != "logo" || !bytes.Equal(got.Media.Items[0].Data, []byte{1, 2, 3}) {
+		t.Fatalf("unexpected media items: %+v", got.Media.Items)
+	}
+
+	var delivered []string
+	err = DecodeInto(bytes.NewReader(buf.Bytes()), SinkFuncs{OnMediaItem: func(it MediaItem) error {
+		delivered = append(delivered, it.ID)
+		return nil
+	}}, noVideo)
+	if err != nil || len(delivered) != 1 || delivered[0] != "logo" {
+		t.Fatalf("DecodeInto delivered %v, %v", delivered, err)
+	}
+
+	// Dropped items are not validated.
+	doc.Media.Items[1].SHA256 = [32]byte{1}
+	buf.Reset()
+	if err := Encode(&buf, doc, WithVerifyHashesOnWrite(false)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), noVideo); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,122 @@
+package mdocx
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// Ref is a reference to media found in Markdown content.
+type Ref struct {
+	// ID is the media ID of an mdocx://media/<ID> reference, or "" for a
+	// relative asset link.
+	ID string
+	// Path is the target of a relative asset link, unescaped and without query
+	// or fragment, as written relative to the Markdown file. It is "" for
+	// mdocx://media/ references.
+	Path string
+	// Dest is the link destination as written.
+	Dest string
+	// Image reports whether the reference is an image: ![alt](dest) or <img>.
+	Image bool
+	// Line and Column give the 1-based position of the reference.
+	Line   int
+	Column int
+}
+
+// ScanMediaRefs returns the media references in Markdown content, in document
+// order. It recognizes every Markdown extension.
+//
+// A reference is an mdocx://media/<ID> link, image, reference definition, or
+// HTML src/href attribute, or a relative asset link: a relative destination
+// that is an image or does not name a Markdown (".md" or ".markdown") file.
+// Links inside code are ignored. An mdocx://media/ URI with an empty or badly
+// escaped ID fails with ErrValidation.
+func ScanMediaRefs(content []byte) ([]Ref, error) {
+	return scanMediaRefs(mdscan.Scan(content))
+}
+
+// scanMediaRefs returns the media references among the links of res.
+func scanMediaRefs(res *mdscan.Result) ([]Ref, error) {
+	var refs []Ref
+	for _, l := range res.Links {
+		dest := strings.TrimSpace(l.Dest)
+		r := Ref{Dest: l.Dest, Image: l.Image, Line: l.Line, Column: l.Column}
+		if strings.HasPrefix(dest, mediaURIPrefix) {
+			id := strings.TrimPrefix(dest, mediaURIPrefix)
+			if i := strings.IndexAny(id, "?#"); i >= 0 {
+				id = id[:i]
+			}
+			id, err := url.PathUnescape(id)
+			if err != nil || id == "" {
+				return nil, fmt.Errorf("%w: line %d: invalid media URI %q", ErrValidation, l.Line, l.Dest)
+			}
+			r.ID = id
+			refs = append(refs, r)
+			continue
+		}
+		target, _, ok := splitRelativeLink(dest)
+		if !ok || target == "" {
+			continue
+		}
+		if !l.Image && isMarkdownPath(target) {
+			continue
+		}
+		r.Path = target
+		refs = append(refs, r)
+	}
+	return refs, nil
+}
+
+// isMarkdownPath reports whether p names a Markdown file by its extension.
+func isMarkdownPath(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// populateMediaRefs adds to each Markdown file's MediaRefs the IDs of the
+// media items its content references, keeping existing entries and their
+// order. IDs from mdocx://media/ URIs are added as written; relative links are
+// added only if they resolve to the Path of a media item.
+func populateMediaRefs(doc *Document) error {
+	byPath := make(map[string]string, len(doc.Media.Items))
+	for _, it := range doc.Media.Items {
+		if it.Path != "" {
+			byPath[it.Path] = it.ID
+		}
+	}
+	for i := range doc.Markdown.Files {
+		f := &doc.Markdown.Files[i]
+		refs, err := scanMediaRefs(doc.scanFile(*f))
+		if err != nil {
+			return fmt.Errorf("markdown file %q: %w", f.Path, err)
+		}
+		seen := make(map[string]bool, len(f.MediaRefs))
+		for _, id := range f.MediaRefs {
+			seen[id] = true
+		}
+		for _, r := range refs {
+			id := r.ID
+			if id == "" {
+				resolved, err := resolveContainerLink(f.Path, r.Path)
+				if err != nil {
+					continue
+				}
+				if id = byPath[resolved]; id == "" {
+					continue
+				}
+			}
+			if !seen[id] {
+				seen[id] = true
+				f.MediaRefs = append(f.MediaRefs, id)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestScanMediaRefs(t *testing.T) {
+	src := []byte("# Title\n" +
+		"![Logo](mdocx://media/logo) and [clip](mdocx://media/a%20b?t=1#x)\n" +
+		"See [other](other.md#top) and [manual](files/manual.pdf).\n" +
+		"![Chart](../img/chart.png \"Chart\") <img src=\"img/x.svg\">\n" +
+		"`![code](mdocx://media/ignored)` [web](https://example.com/a.png)\n" +
+		"\n" +
+		"[ref]: mdocx://media/logo\n")
+	refs, err := ScanMediaRefs(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Ref{
+		{ID: "logo", Dest: "mdocx://media/logo", Image: true, Line: 2, Column: 1},
+		{ID: "a b", Dest: "mdocx://media/a%20b?t=1#x", Line: 2, Column: 33},
+		{Path: "files/manual.pdf", Dest: "files/manual.pdf", Line: 3, Column: 31},
+		{Path: "../img/chart.png", Dest: "../img/chart.png", Image: true, Line: 4, Column: 1},
+		{Path: "img/x.svg", Dest: "img/x.svg", Image: true, Line: 4, Column: 36},
+		{ID: "logo", Dest: "mdocx://media/logo", Line: 7, Column: 1},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Fatalf("refs:\n got %+v\nwant %+v", refs, want)
+	}
+
+	for _, bad := range []string{"![x](mdocx://media/)", "[x](mdocx://media/%zz)"} {
+		if _, err := ScanMediaRefs([]byte(bad)); !errors.Is(err, ErrValidation) {
+			t.Fatalf("%q: got %v", bad, err)
+		}
+	}
+	if refs, err := ScanMediaRefs([]byte("no links here\n")); err != nil || len(refs) != 0 {
+		t.Fatalf("got %v, %v", refs, err)
+	}
+}
+
+func TestWithAutoMediaRefs(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[0].MediaRefs = []string{"manual"}
+	doc.Markdown.Files[1].Content = []byte("![Logo](../assets/logo.png)\n[Doc](../assets/manual.pdf) [missing](nope.png)\n![again](mdocx://media/logo)\n")
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "manual", Path: "assets/manual.pdf", MIMEType: "application/pdf", Data: []byte("%PDF")})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithAutoMediaRefs(true), WithChecksOnWrite(CheckMediaRefs)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := doc.Markdown.Files[0].MediaRefs, []string{"manual", "logo"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("index.md refs = %v, want %v", got, want)
+	}
+	if got, want := doc.Markdown.Files[1].MediaRefs, []string{"logo", "manual"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("notes.md refs = %v, want %v", got, want)
+	}
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Markdown.Files[1].MediaRefs, []string{"logo", "manual"}) {
+		t.Fatalf("decoded refs = %v", got.Markdown.Files[1].MediaRefs)
+	}
+
+	// Without the option MediaRefs is left alone.
+	doc = sampleDoc()
+	doc.Markdown.Files[0].MediaRefs = nil
+	if err := Encode(io.Discard, doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Markdown.Files[0].MediaRefs != nil {
+		t.Fatalf("MediaRefs = %v", doc.Markdown.Files[0].MediaRefs)
+	}
+
+	// Dangling URI references are added and caught by CheckMediaRefs.
+	doc = sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("![gone](mdocx://media/gone)\n")
+	if err := Encode(io.Discard, doc, WithAutoMediaRefs(true), WithChecksOnWrite(CheckMediaRefs)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("got %v", err)
+	}
+
+	doc = sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("![bad](mdocx://media/)\n")
+	if err := Encode(io.Discard, doc, WithAutoMediaRefs(true)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("got %v", err)
+	}
+}
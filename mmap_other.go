@@ -0,0 +1,17 @@
+//go:build !unix
+
+package mdocx
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the first size bytes of f into memory on platforms without mmap.
+func mmapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
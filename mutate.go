@@ -0,0 +1,169 @@
+package mdocx
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// editConfig returns the configuration of a mutator from the options of
+// Validate. Only WithValidateLimits and WithValidateHashes apply.
+func editConfig(opts []ValidateOption) validateConfig {
+	cfg := validateConfig{limits: defaultLimits(), verifyHashes: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.limits = cfg.limits.withDefaults()
+	return cfg
+}
+
+// AddFile appends f to d's Markdown files. It checks f the way Validate would
+// and fails, leaving d unchanged, with a *ValidationError if f's path is
+// invalid or already taken by another Markdown file, its content is not valid
+// UTF-8, or a size, count, or path limit would be exceeded. Of the options of
+// Validate, only WithValidateLimits applies.
+func (d *Document) AddFile(f MarkdownFile, opts ...ValidateOption) error {
+	cfg := editConfig(opts)
+	i := len(d.Markdown.Files)
+	field := fmt.Sprintf("Markdown.Files[%d]", i)
+	if i >= cfg.limits.MaxMarkdownFiles {
+		return newValidationError(CodeLimitExceeded, f.Path, "Markdown.Files", "MaxMarkdownFiles", "too many markdown files (%d)", i+1)
+	}
+	if slices.ContainsFunc(d.Markdown.Files, func(g MarkdownFile) bool { return g.Path == f.Path }) {
+		return newValidationError(CodeDuplicatePath, f.Path, field+".Path", f.Path, "duplicate markdown path")
+	}
+	if err := checkFileEdit(field, f, cfg.limits); err != nil {
+		return err
+	}
+	if d.Markdown.BundleVersion == 0 {
+		d.Markdown.BundleVersion = VersionV1
+	}
+	d.Markdown.Files = append(d.Markdown.Files, f)
+	return nil
+}
+
+// ReplaceFile replaces the Markdown file whose path is f.Path with f. It
+// returns an error wrapping ErrNotFound if there is no such file, and fails like
+// AddFile if f is invalid; d is then unchanged.
+func (d *Document) ReplaceFile(f MarkdownFile, opts ...ValidateOption) error {
+	cfg := editConfig(opts)
+	i := slices.IndexFunc(d.Markdown.Files, func(g MarkdownFile) bool { return g.Path == f.Path })
+	if i < 0 {
+		return fmt.Errorf("%w: markdown file %q", ErrNotFound, f.Path)
+	}
+	if err := checkFileEdit(fmt.Sprintf("Markdown.Files[%d]", i), f, cfg.limits); err != nil {
+		return err
+	}
+	d.Markdown.Files[i] = f
+	return nil
+}
+
+// checkFileEdit checks a Markdown file that is to be stored at field.
+func checkFileEdit(field string, f MarkdownFile, limits Limits) error {
+	if err := validateContainerPath(f.Path); err != nil {
+		return newValidationError(CodeInvalidPath, f.Path, field+".Path", f.Path, "%v", err)
+	}
+	if limit, reason := limits.pathLimit(f.Path); limit != "" {
+		return newValidationError(CodeLimitExceeded, f.Path, field+".Path", limit, "%s", reason)
+	}
+	if !utf8.Valid(f.Content) {
+		return newValidationError(CodeInvalidUTF8, f.Path, field+".Content", "", "not valid UTF-8")
+	}
+	if uint64(len(f.Content)) > limits.MaxSingleMarkdownFileSize {
+		return newValidationError(CodeLimitExceeded, f.Path, field+".Content", "MaxSingleMarkdownFileSize", "too large (%d bytes)", len(f.Content))
+	}
+	return nil
+}
+
+// AddMediaItem appends it to d's media items. It checks it the way Validate
+// would and fails, leaving d unchanged, with a *ValidationError if its ID is
+// empty or already taken, its path is invalid, its non-zero SHA256 does not
+// match its data, d has NoMedia set, or a size, count, or path limit would be
+// exceeded. Of the options of Validate, only WithValidateLimits and
+// WithValidateHashes apply.
+func (d *Document) AddMediaItem(it MediaItem, opts ...ValidateOption) error {
+	cfg := editConfig(opts)
+	i := len(d.Media.Items)
+	field := fmt.Sprintf("Media.Items[%d]", i)
+	switch {
+	case d.NoMedia:
+		return newValidationError(CodeMediaWithNoMedia, it.Path, "Media.Items", "", "must be empty when NoMedia is set")
+	case i >= cfg.limits.MaxMediaItems:
+		return newValidationError(CodeLimitExceeded, it.Path, "Media.Items", "MaxMediaItems", "too many media items (%d)", i+1)
+	case strings.TrimSpace(it.ID) == "":
+		return newValidationError(CodeEmptyID, it.Path, field+".ID", "", "empty ID")
+	case slices.ContainsFunc(d.Media.Items, func(m MediaItem) bool { return m.ID == it.ID }):
+		return newValidationError(CodeDuplicateID, it.Path, field+".ID", it.ID, "duplicate media ID %q", it.ID)
+	}
+	if it.Path != "" {
+		if err := validateContainerPath(it.Path); err != nil {
+			return newValidationError(CodeInvalidPath, it.Path, field+".Path", it.Path, "%v", err)
+		}
+		if limit, reason := cfg.limits.pathLimit(it.Path); limit != "" {
+			return newValidationError(CodeLimitExceeded, it.Path, field+".Path", limit, "%s", reason)
+		}
+	}
+	if uint64(len(it.Data)) > cfg.limits.MaxSingleMediaSize {
+		return newValidationError(CodeLimitExceeded, it.Path, field+".Data", "MaxSingleMediaSize", "too large (%d bytes)", len(it.Data))
+	}
+	if !mediaHashOK(&it, cfg.verifyHashes) {
+		return newValidationError(CodeHashMismatch, it.Path, field+".SHA256", "", "does not match Data")
+	}
+	if d.Media.BundleVersion == 0 {
+		d.Media.BundleVersion = VersionV1
+	}
+	d.Media.Items = append(d.Media.Items, it)
+	return nil
+}
+
+// RemoveMediaItem removes the media item with the given ID and drops the ID
+// from the MediaRefs of every Markdown file, so that CheckMediaRefs keeps
+// holding. Links to the item in Markdown content are left alone. It returns an
+// error wrapping ErrNotFound if there is no such item.
+func (d *Document) RemoveMediaItem(id string) error {
+	i := slices.IndexFunc(d.Media.Items, func(m MediaItem) bool { return m.ID == id })
+	if i < 0 {
+		return fmt.Errorf("%w: media item %q", ErrNotFound, id)
+	}
+	d.Media.Items = slices.Delete(d.Media.Items, i, i+1)
+	for j := range d.Markdown.Files {
+		f := &d.Markdown.Files[j]
+		if slices.Contains(f.MediaRefs, id) {
+			f.MediaRefs = slices.DeleteFunc(slices.Clone(f.MediaRefs), func(ref string) bool { return ref == id })
+		}
+	}
+	return nil
+}
+
+// RecomputeHashes sets the SHA256 of the media items with the given IDs, or
+// of every item if none are given, to the hash of their current data, for
+// workflows that patch media bytes outside the library and must refresh the
+// stored hashes to match. Stubs left by EncodeTiered, which hold no data, are
+// skipped. It returns the IDs of the items whose hash changed, in bundle
+// order, or an error wrapping ErrNotFound, leaving d unchanged, if an ID names
+// no item.
+func (d *Document) RecomputeHashes(ids ...string) ([]string, error) {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !slices.ContainsFunc(d.Media.Items, func(m MediaItem) bool { return m.ID == id }) {
+			return nil, fmt.Errorf("%w: media item %q", ErrNotFound, id)
+		}
+		want[id] = true
+	}
+	var changed []string
+	for i := range d.Media.Items {
+		it := &d.Media.Items[i]
+		if len(ids) > 0 && !want[it.ID] {
+			continue
+		}
+		if _, stub := it.Attributes[ColdSHA256Attr]; stub && it.Data == nil {
+			continue
+		}
+		if sum := it.computedSHA256(); sum != it.SHA256 {
+			it.SHA256 = sum
+			changed = append(changed, it.ID)
+		}
+	}
+	return changed, nil
+}
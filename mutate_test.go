@@ -0,0 +1,119 @@
+package mdocx
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentMutators(t *testing.T) {
+	doc := &Document{}
+	if err := doc.AddFile(MarkdownFile{Path: "index.md", Content: []byte("# Hi\n")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddMediaItem(MediaItem{ID: "logo", Path: "logo.png", MIMEType: "image/png", Data: []byte{1}}); err != nil {
+		t.Fatal(err)
+	}
+	doc.Markdown.Files[0].MediaRefs = []string{"logo"}
+	if err := Validate(doc, WithValidateChecks(CheckMediaRefs)); err != nil {
+		t.Fatalf("built document is invalid: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		err   error
+		code  ErrorCode
+		field string
+	}{
+		{"duplicate path", doc.AddFile(MarkdownFile{Path: "index.md"}), CodeDuplicatePath, "Markdown.Files[1].Path"},
+		{"bad path", doc.AddFile(MarkdownFile{Path: "../x.md"}), CodeInvalidPath, "Markdown.Files[1].Path"},
+		{"bad UTF-8", doc.AddFile(MarkdownFile{Path: "b.md", Content: []byte{0xff}}), CodeInvalidUTF8, "Markdown.Files[1].Content"},
+		{"too many files", doc.AddFile(MarkdownFile{Path: "b.md"}, WithValidateLimits(Limits{MaxMarkdownFiles: 1})), CodeLimitExceeded, "Markdown.Files"},
+		{"replace bad UTF-8", doc.ReplaceFile(MarkdownFile{Path: "index.md", Content: []byte{0xff}}), CodeInvalidUTF8, "Markdown.Files[0].Content"},
+		{"duplicate ID", doc.AddMediaItem(MediaItem{ID: "logo"}), CodeDuplicateID, "Media.Items[1].ID"},
+		{"empty ID", doc.AddMediaItem(MediaItem{ID: " "}), CodeEmptyID, "Media.Items[1].ID"},
+		{"hash", doc.AddMediaItem(MediaItem{ID: "x", Data: []byte{1}, SHA256: [32]byte{1}}), CodeHashMismatch, "Media.Items[1].SHA256"},
+		{"too large", doc.AddMediaItem(MediaItem{ID: "x", Data: make([]byte, 10)}, WithValidateLimits(Limits{MaxSingleMediaSize: 5})), CodeLimitExceeded, "Media.Items[1].Data"},
+	}
+	for _, c := range cases {
+		var ve *ValidationError
+		if !errors.As(c.err, &ve) || ve.Code != c.code || ve.Field != c.field {
+			t.Errorf("%s: got %#v", c.name, c.err)
+		}
+	}
+	if !errors.Is(cases[3].err, ErrLimitExceeded) || !errors.Is(cases[0].err, ErrValidation) {
+		t.Fatal("mutator errors do not match their sentinels")
+	}
+	if len(doc.Markdown.Files) != 1 || len(doc.Media.Items) != 1 || string(doc.Markdown.Files[0].Content) != "# Hi\n" {
+		t.Fatal("failed mutations modified the document")
+	}
+	// With hash verification off, a mismatching hash is accepted.
+	if err := doc.AddMediaItem(MediaItem{ID: "x", Data: []byte{1}, SHA256: [32]byte{1}}, WithValidateHashes(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.ReplaceFile(MarkdownFile{Path: "index.md", Content: []byte("new")}); err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.Markdown.Files[0].Content) != "new" {
+		t.Fatal("file not replaced")
+	}
+	if err := doc.ReplaceFile(MarkdownFile{Path: "missing.md"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v", err)
+	}
+
+	doc.Markdown.Files[0].MediaRefs = []string{"logo", "x"}
+	if err := doc.RemoveMediaItem("logo"); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Media.Items) != 1 || !reflect.DeepEqual(doc.Markdown.Files[0].MediaRefs, []string{"x"}) {
+		t.Fatalf("after remove: %+v, refs %v", doc.Media.Items, doc.Markdown.Files[0].MediaRefs)
+	}
+	if err := doc.RemoveMediaItem("logo"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v", err)
+	}
+
+	doc.NoMedia = true
+	if err := doc.AddMediaItem(MediaItem{ID: "y"}); Code(err) != CodeMediaWithNoMedia {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestRecomputeHashes(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "a", Data: []byte("a")},
+		MediaItem{ID: "b", Data: []byte("b")},
+		MediaItem{ID: "stub", Attributes: map[string]string{ColdSHA256Attr: "00"}},
+	)
+	for i := range doc.Media.Items {
+		doc.Media.Items[i].SHA256 = doc.Media.Items[i].computedSHA256()
+	}
+	doc.Media.Items[3].SHA256 = [32]byte{}
+	doc.Media.Items[1].Data[0] = 'x'
+	doc.Media.Items[2].Data[0] = 'y'
+
+	if _, err := doc.RecomputeHashes("a", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unknown ID: %v", err)
+	}
+	if doc.Media.Items[1].SHA256 == doc.Media.Items[1].computedSHA256() {
+		t.Fatal("failed call changed the document")
+	}
+	changed, err := doc.RecomputeHashes("a")
+	if err != nil || !reflect.DeepEqual(changed, []string{"a"}) {
+		t.Fatalf("RecomputeHashes(a) = %v, %v", changed, err)
+	}
+	if err := Validate(doc); !errors.Is(err, ErrValidation) {
+		t.Fatalf("b should still mismatch: %v", err)
+	}
+	changed, err = doc.RecomputeHashes()
+	if err != nil || !reflect.DeepEqual(changed, []string{"b"}) {
+		t.Fatalf("RecomputeHashes() = %v, %v", changed, err)
+	}
+	if doc.Media.Items[3].SHA256 != ([32]byte{}) {
+		t.Fatal("stub was hashed")
+	}
+	if err := Validate(doc); err != nil {
+		t.Fatal(err)
+	}
+}
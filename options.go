@@ -1,9 +1,26 @@
 package mdocx
 
+import (
+	"context"
+	"io"
+	"time"
+)
+
 // readConfig holds configuration options for Decode.
 type readConfig struct {
-	limits       Limits
-	verifyHashes bool
+	limits        Limits
+	verifyHashes  bool
+	rateLimit     *RateLimiter
+	stats         *DecodeStats
+	onReject      func(Rejection)
+	anyOrder      bool
+	ignoreUnknown bool
+	checks        Check
+	sampling      *hashSampling
+	mediaFilter   MediaFilter
+	streaming     bool
+	coldTier      *Reader
+	maxDuration   time.Duration
 }
 
 // ReadOption is a functional option for configuring Decode behavior.
@@ -28,13 +45,82 @@ func WithVerifyHashes(v bool) ReadOption {
 	return func(c *readConfig) { c.verifyHashes = v }
 }
 
+// WithReadRateLimit makes Decode and DecodeInto read their input no faster
+// than l allows. Share one RateLimiter between jobs to cap their combined
+// bandwidth.
+func WithReadRateLimit(l *RateLimiter) ReadOption {
+	return func(c *readConfig) { c.rateLimit = l }
+}
+
+// WithStatsCollector makes Decode record per-stage timings and byte counts in
+// s. Other decoding functions ignore it.
+func WithStatsCollector(s *DecodeStats) ReadOption {
+	return func(c *readConfig) { c.stats = s }
+}
+
+// WithChecks makes Decode, DecodeInto, and OpenMapped enforce the optional
+// invariants selected by c, failing with ErrValidation on a violation.
+// No optional invariants are enforced by default.
+func WithChecks(c Check) ReadOption {
+	return func(cfg *readConfig) { cfg.checks = c }
+}
+
+// WithHashSampling makes readers verify the SHA256 of only a fraction of the
+// media items, for trusted-but-verify deployments where checking every hash on
+// every open is too expensive. Items are selected deterministically from their
+// ID and seed: the selection for consecutive seeds covers consecutive slices of
+// the ID space, so a caller that passes an incrementing seed (an open counter,
+// or the number of days since some epoch) verifies every item at least once
+// every ceil(1/fraction) opens. A fraction of 1 or more verifies every item.
+//
+// It applies to Decode, DecodeInto, OpenMapped, and NewReader, and has no
+// effect when hashes are not verified (WithVerifyHashes(false)).
+func WithHashSampling(fraction float64, seed uint64) ReadOption {
+	return func(c *readConfig) { c.sampling = &hashSampling{fraction: fraction, seed: seed} }
+}
+
+// input wraps r in a rate-limited reader when WithReadRateLimit was given, and
+// in a reader that fails once ctx is done when ctx can be canceled.
+func (c readConfig) input(ctx context.Context, r io.Reader) io.Reader {
+	if c.rateLimit != nil {
+		r = NewRateLimitedReader(ctx, r, c.rateLimit)
+	}
+	if ctx.Done() != nil {
+		r = contextReader{ctx, r}
+	}
+	return r
+}
+
 // writeConfig holds configuration options for Encode.
 type writeConfig struct {
 	limits           Limits
 	verifyHashes     bool
 	autoPopulate     bool
+	autoMediaRefs    bool
 	mdCompression    Compression
 	mediaCompression Compression
+	smartMedia       bool
+	format           PayloadFormat
+	version          uint16
+	extraSections    []ExtraSection
+	duplicates       DuplicatePolicy
+	onDuplicate      func(DuplicateMedia)
+	codecs           codecTuning
+	levels           map[SectionType]int
+	outputSHA256     *[32]byte
+	spoolDir         string
+	checkpointDir    string
+	attrSchema       AttrSchema
+	transforms       []Transform
+	quota            *uint64
+	rateLimit        *RateLimiter
+	checks           Check
+	index            bool
+	checksum         bool
+	lock             *FileLock
+	backups          int
+	backupDir        string
+	maxDuration      time.Duration
 }
 
 // WriteOption is a functional option for configuring Encode behavior.
@@ -62,6 +148,17 @@ func WithAutoPopulateSHA256(v bool) WriteOption {
 	return func(c *writeConfig) { c.autoPopulate = v }
 }
 
+// WithAutoMediaRefs controls whether Encode fills in MarkdownFile.MediaRefs
+// from the media each file's content references (see ScanMediaRefs), so they
+// need not be maintained by hand. Existing entries are kept and new IDs are
+// appended in order of first reference; relative links count only if they
+// resolve to the Path of a media item. doc is modified in place, before
+// validation, so WithChecksOnWrite(CheckMediaRefs) also covers the added
+// IDs. Default is false.
+func WithAutoMediaRefs(v bool) WriteOption {
+	return func(c *writeConfig) { c.autoMediaRefs = v }
+}
+
 // WithMarkdownCompression sets the compression algorithm for the Markdown section.
 // Default is CompZSTD. Use CompNone to disable compression.
 //
@@ -69,7 +166,8 @@ func WithAutoPopulateSHA256(v bool) WriteOption {
 //   - CompZSTD: Recommended default, good speed/ratio balance
 //   - CompZIP: Maximum interoperability with other tools
 //   - CompLZ4: Maximum encode/decode speed
-//   - CompBR: Maximum compression ratio (slower)
+//   - CompBR: High compression ratio (slower)
+//   - CompXZ: Maximum compression ratio for archival (slowest)
 func WithMarkdownCompression(comp Compression) WriteOption {
 	return func(c *writeConfig) { c.mdCompression = comp }
 }
@@ -77,7 +175,116 @@ func WithMarkdownCompression(comp Compression) WriteOption {
 // WithMediaCompression sets the compression algorithm for the Media section.
 // Default is CompZSTD. Use CompNone to disable compression.
 // Note that media files (images, video) are often already compressed,
-// so compression may not provide significant size reduction.
+// so compression may not provide significant size reduction; see
+// WithSmartMediaCompression.
 func WithMediaCompression(comp Compression) WriteOption {
 	return func(c *writeConfig) { c.mediaCompression = comp }
 }
+
+// WithBrotliQuality sets the quality of CompBR sections, from 0 (fastest) to
+// 11 (smallest). Default is 6. Qualities above 9 are very slow on large
+// bundles. Encoding fails with ErrValidation if q is out of range.
+func WithBrotliQuality(q int) WriteOption {
+	return func(c *writeConfig) { c.codecs.brotliQuality = q }
+}
+
+// WithBrotliWindow sets the base 2 logarithm of the CompBR window size, from 10
+// (1 KiB) to 24 (16 MiB). Default is 0, which lets the encoder choose. Larger
+// windows find matches further apart at the cost of decoder memory.
+func WithBrotliWindow(bits int) WriteOption {
+	return func(c *writeConfig) { c.codecs.brotliWindow = bits }
+}
+
+// WithLZ4BlockSize sets the block size of CompLZ4 sections in bytes: 64 KiB,
+// 256 KiB, 1 MiB, or 4 MiB. Default is 0, which selects 4 MiB. Encoding fails
+// with ErrValidation for any other size.
+func WithLZ4BlockSize(n int) WriteOption {
+	return func(c *writeConfig) { c.codecs.lz4BlockSize = n }
+}
+
+// WithCompressionLevel sets the compression level of section, SectionMarkdown
+// or SectionMedia, for the algorithm it is compressed with:
+//   - CompZSTD: Zstandard levels 1 (fastest) to 22 (smallest), mapped onto the
+//     encoder's speed settings; 3 is the default
+//   - CompBR: Brotli quality 0 to 11, as WithBrotliQuality
+//   - CompZIP: DEFLATE levels 0 (stored) to 9 (smallest); 6 is the default
+//   - CompLZ4: 0 to 9, as WithLZ4Level
+//
+// The level overrides WithBrotliQuality and WithLZ4Level for that section.
+// It has no effect on CompNone and CompXZ sections, nor on other section
+// types or SuggestCompression trials. Encoding fails with ErrValidation if
+// level is out of range for the section's algorithm.
+func WithCompressionLevel(section SectionType, level int) WriteOption {
+	return func(c *writeConfig) {
+		if c.levels == nil {
+			c.levels = make(map[SectionType]int)
+		}
+		c.levels[section] = level
+	}
+}
+
+// sectionCodecs returns the codec settings for sections of type typ.
+func (c writeConfig) sectionCodecs(typ SectionType) codecTuning {
+	t := c.codecs
+	if level, ok := c.levels[typ]; ok {
+		t.level, t.hasLevel = level, true
+	}
+	return t
+}
+
+// WithLZ4Level sets the compression level of CompLZ4 sections. Level 0
+// (default) uses the fast compressor; levels 1-9 use the high-compression
+// compressor, trading encode speed for smaller output. Decoding speed is not
+// affected.
+func WithLZ4Level(level int) WriteOption {
+	return func(c *writeConfig) { c.codecs.lz4Level = level }
+}
+
+// WithOutputSHA256 makes Encode compute the SHA-256 of the bytes it writes and
+// store it in dst when encoding succeeds. The hash is computed during the write
+// pass, so the output never has to be read back.
+func WithOutputSHA256(dst *[32]byte) WriteOption {
+	return func(c *writeConfig) { c.outputSHA256 = dst }
+}
+
+// WithSpoolDir sets the directory for the temporary files EncodeStream uses to
+// spool sections before writing them. The default is os.TempDir().
+func WithSpoolDir(dir string) WriteOption {
+	return func(c *writeConfig) { c.spoolDir = dir }
+}
+
+// WithAttrSchema makes Encode and EncodeStream check the attributes of every
+// Markdown file and media item against s. A mismatch fails with ErrValidation.
+func WithAttrSchema(s AttrSchema) WriteOption {
+	return func(c *writeConfig) { c.attrSchema = s }
+}
+
+// WithTransforms makes Encode run the given transforms on the document, in
+// order, before validating it. Transforms modify the document in place.
+// EncodeStream does not run transforms.
+func WithTransforms(t ...Transform) WriteOption {
+	return func(c *writeConfig) { c.transforms = append(c.transforms, t...) }
+}
+
+// WithQuota makes Encode fail with a *QuotaError, before writing anything, if
+// the encoded container would exceed bytesRemaining bytes. The check uses the
+// exact size of the compressed sections, so a container that passes it fits.
+// EncodeStream and Transcode ignore this option.
+func WithQuota(bytesRemaining uint64) WriteOption {
+	return func(c *writeConfig) { c.quota = &bytesRemaining }
+}
+
+// WithWriteRateLimit makes Encode, EncodeStream, and Transcode write their
+// output no faster than l allows. EncodeStream also stops waiting when its
+// context is canceled. Share one RateLimiter between jobs to cap their
+// combined bandwidth.
+func WithWriteRateLimit(l *RateLimiter) WriteOption {
+	return func(c *writeConfig) { c.rateLimit = l }
+}
+
+// WithChecksOnWrite makes Encode and EncodeStream enforce the optional
+// invariants selected by c before writing, failing with ErrValidation on a
+// violation. No optional invariants are enforced by default.
+func WithChecksOnWrite(c Check) WriteOption {
+	return func(cfg *writeConfig) { cfg.checks = c }
+}
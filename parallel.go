@@ -0,0 +1,71 @@
+package mdocx
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// parallelMinBytes is the amount of content below which validation checks run
+// sequentially; smaller documents do not repay the cost of the worker pool.
+var parallelMinBytes int64 = 1 << 20
+
+// contentChecks holds the results of the expensive per-part validation checks.
+type contentChecks struct {
+	// utf8OK reports, per Markdown file, whether its content is valid UTF-8.
+	utf8OK []bool
+	// hashOK reports, per media item, whether its data matches its non-zero
+	// SHA256 (always true when the item's hash is not verified).
+	hashOK []bool
+}
+
+// checkContent runs the UTF-8 and hash checks of doc on a worker pool bounded
+// by GOMAXPROCS. The results are consumed in order by validateDocument, so
+// errors are reported exactly as by a sequential pass.
+func checkContent(doc *Document, verify func(id string) bool) contentChecks {
+	files, items := doc.Markdown.Files, doc.Media.Items
+	c := contentChecks{utf8OK: make([]bool, len(files)), hashOK: make([]bool, len(items))}
+	var total int64
+	for i := range files {
+		total += int64(len(files[i].Content))
+	}
+	if verify != nil {
+		for i := range items {
+			total += int64(len(items[i].Data))
+		}
+	}
+	check := func(i int) {
+		if i < len(files) {
+			c.utf8OK[i] = utf8.Valid(files[i].Content)
+			return
+		}
+		i -= len(files)
+		c.hashOK[i] = mediaHashOK(&items[i], verify != nil && verify(items[i].ID))
+	}
+	n := len(files) + len(items)
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if total < parallelMinBytes || workers < 2 {
+		for i := range n {
+			check(i)
+		}
+		return c
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				check(i)
+			}
+		}()
+	}
+	wg.Wait()
+	return c
+}
@@ -0,0 +1,52 @@
+package mdocx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParallelValidation(t *testing.T) {
+	old := parallelMinBytes
+	parallelMinBytes = 0
+	defer func() { parallelMinBytes = old }()
+
+	doc := largeDoc(500)
+	for i := range doc.Media.Items {
+		doc.Media.Items[i].SHA256 = doc.Media.Items[i].computedSHA256()
+	}
+	if err := validateDocument(doc, noLimits(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first failing part in document order is reported.
+	doc.Media.Items[400].Data = []byte("changed")
+	doc.Media.Items[123].Data = []byte("changed")
+	err := validateDocument(doc, noLimits(), true)
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), `"img-00123"`) {
+		t.Fatalf("expected mismatch of img-00123, got %v", err)
+	}
+	if err := validateDocument(doc, noLimits(), false); err != nil {
+		t.Fatalf("hashes checked with verification off: %v", err)
+	}
+	doc.Markdown.Files[300].Content = []byte{0xff}
+	doc.Markdown.Files[7].Content = []byte{0xfe}
+	err = validateDocument(doc, noLimits(), true)
+	if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "page-00007") {
+		t.Fatalf("expected UTF-8 error for page 7, got %v", err)
+	}
+}
+
+func BenchmarkValidateDocumentHashes(b *testing.B) {
+	doc := largeDoc(256)
+	for i := range doc.Media.Items {
+		doc.Media.Items[i].Data = make([]byte, 256<<10)
+		doc.Media.Items[i].SHA256 = doc.Media.Items[i].computedSHA256()
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := validateDocument(doc, noLimits(), true); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package mdocx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// PayloadFormat selects how the Markdown and Media bundles, and the footer
+// index, of a container are serialized.
+type PayloadFormat uint8
+
+const (
+	// PayloadGob serializes them with encoding/gob (rfc.md §7), the default.
+	PayloadGob PayloadFormat = iota
+	// PayloadCBOR serializes them as CBOR (RFC 8949) maps keyed by field name
+	// (rfc.md §7.6), which implementations without a gob decoder can read
+	// with any CBOR library. Containers using it have HeaderFlagCBOR set.
+	PayloadCBOR
+)
+
+// String returns "gob" or "cbor".
+func (f PayloadFormat) String() string {
+	switch f {
+	case PayloadGob:
+		return "gob"
+	case PayloadCBOR:
+		return "cbor"
+	}
+	return fmt.Sprintf("PayloadFormat(%d)", uint8(f))
+}
+
+// WithPayloadFormat selects the serialization of the bundles written by
+// Encode, EncodeStream, and Writer. The default is PayloadGob. Decoders read
+// both, going by HeaderFlagCBOR. EditSession keeps the format of the container
+// it edits, and Transcode copies it.
+func WithPayloadFormat(f PayloadFormat) WriteOption {
+	return func(c *writeConfig) { c.format = f }
+}
+
+// PayloadFormat returns the serialization of the container's bundles.
+func (h *Header) PayloadFormat() PayloadFormat {
+	return payloadFormat(h.Flags)
+}
+
+// payloadFormat returns the format selected by a container's header flags.
+func payloadFormat(headerFlags uint16) PayloadFormat {
+	if headerFlags&HeaderFlagCBOR != 0 {
+		return PayloadCBOR
+	}
+	return PayloadGob
+}
+
+// check returns an error wrapping ErrValidation for an unknown format.
+func (f PayloadFormat) check() error {
+	if f > PayloadCBOR {
+		return fmt.Errorf("%w: unknown payload format %d", ErrValidation, f)
+	}
+	return nil
+}
+
+// headerFlags returns the header flags that select f.
+func (f PayloadFormat) headerFlags() uint16 {
+	if f == PayloadCBOR {
+		return HeaderFlagCBOR
+	}
+	return 0
+}
+
+func (f PayloadFormat) encodeMarkdown(b MarkdownBundle) ([]byte, error) {
+	if f == PayloadCBOR {
+		return cborEncodeMarkdown(b), nil
+	}
+	return gobEncodeMarkdown(b)
+}
+
+func (f PayloadFormat) encodeMedia(b MediaBundle) ([]byte, error) {
+	if f == PayloadCBOR {
+		return cborEncodeMedia(b), nil
+	}
+	return gobEncodeMedia(b)
+}
+
+func (f PayloadFormat) encodeIndex(idx footerIndex) ([]byte, error) {
+	if f == PayloadCBOR {
+		return cborEncodeIndex(idx), nil
+	}
+	return gobEncode(idx)
+}
+
+func (f PayloadFormat) decodeMarkdown(data []byte, limits Limits) (MarkdownBundle, error) {
+	if f == PayloadCBOR {
+		return decodeMarkdownCBOR(data, limits.MaxMarkdownFiles)
+	}
+	var b MarkdownBundle
+	err := gobDecode(data, &b)
+	return b, err
+}
+
+func (f PayloadFormat) decodeMedia(data []byte, limits Limits) (MediaBundle, error) {
+	if f == PayloadCBOR {
+		return decodeMediaCBOR(data, limits.MaxMediaItems)
+	}
+	var b MediaBundle
+	err := gobDecode(data, &b)
+	return b, err
+}
+
+func (f PayloadFormat) decodeIndex(data []byte, limits Limits) (footerIndex, error) {
+	if f == PayloadCBOR {
+		return decodeIndexCBOR(data, limits.MaxMediaItems)
+	}
+	var idx footerIndex
+	if err := gobDecode(data, &idx); err != nil {
+		return idx, fmt.Errorf("%w: footer index: %v", ErrInvalidPayload, err)
+	}
+	return idx, nil
+}
+
+// scanMedia locates the items of the Media bundle held in r[0:size] without
+// reading their data.
+func (f PayloadFormat) scanMedia(r io.ReaderAt, size int64, maxItems int) (*mediaIndex, error) {
+	if f == PayloadCBOR {
+		return scanMediaCBOR(r, size, maxItems)
+	}
+	return scanMediaGob(r, size, maxItems)
+}
+
+// scanMediaBytes is scanMedia for a bundle held in memory.
+func (f PayloadFormat) scanMediaBytes(b []byte, maxItems int) (*mediaIndex, error) {
+	return f.scanMedia(bytes.NewReader(b), int64(len(b)), maxItems)
+}
+
+// bundleHead is the encoded start of a bundle whose list of files or items is
+// written element by element, as EncodeStream does.
+type bundleHead interface {
+	// size returns the length of the complete bundle given the total length
+	// of the encoded elements.
+	size(elems int64) int64
+	// prefix returns the bytes written before the first element.
+	prefix(elems int64) []byte
+	// trailer returns the bytes written after the last element.
+	trailer() []byte
+}
+
+func (f PayloadFormat) markdownHead(rootPath string, count int) (bundleHead, error) {
+	if f == PayloadCBOR {
+		return cborMarkdownHead(rootPath, count), nil
+	}
+	return markdownBundleHead(rootPath, count)
+}
+
+func (f PayloadFormat) mediaHead(count int) (bundleHead, error) {
+	if f == PayloadCBOR {
+		return cborMediaHead(count), nil
+	}
+	return mediaBundleHead(count)
+}
+
+// elementEncoder encodes the files or items of a bundle one at a time.
+type elementEncoder[T any] interface {
+	// encode returns the encoding of v, valid until the next call.
+	encode(v T) ([]byte, error)
+}
+
+// cborElementEncoder is an elementEncoder for PayloadCBOR.
+type cborElementEncoder[T any] func(T) []byte
+
+func (e cborElementEncoder[T]) encode(v T) ([]byte, error) { return e(v), nil }
+
+// newElementEncoder returns the encoder of bundle elements of type T in
+// format f; cbor encodes an element in PayloadCBOR.
+func newElementEncoder[T any](f PayloadFormat, cbor func(T) []byte) elementEncoder[T] {
+	if f == PayloadCBOR {
+		return cborElementEncoder[T](cbor)
+	}
+	return newGobElementEncoder[T]()
+}
@@ -0,0 +1,107 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// EncodePlan describes the container Encode would write for a document.
+type EncodePlan struct {
+	// Size is the total size of the container in bytes.
+	Size uint64
+	// HeaderFlags are the fixed header flags that would be set.
+	HeaderFlags uint16
+	// MetadataLen is the length of the metadata JSON block.
+	MetadataLen uint64
+	// MarkdownFiles and MediaItems count the parts of the document after
+	// transforms have run.
+	MarkdownFiles, MediaItems int
+	// Markdown and Media describe the two sections.
+	Markdown, Media SectionPlan
+	// Limits are the effective limits the document was checked against.
+	Limits Limits
+}
+
+// SectionPlan describes one section of a planned container.
+type SectionPlan struct {
+	// Compression is the algorithm the section would be compressed with.
+	Compression Compression
+	// UncompressedLen is the length of the gob-encoded bundle.
+	UncompressedLen uint64
+	// PayloadLen is the length of the section payload as written, including
+	// any uncompressed-length prefix.
+	PayloadLen uint64
+}
+
+// Ratio returns PayloadLen/UncompressedLen, or 0 for an empty section.
+func (s SectionPlan) Ratio() float64 {
+	if s.UncompressedLen == 0 {
+		return 0
+	}
+	return float64(s.PayloadLen) / float64(s.UncompressedLen)
+}
+
+// Plan reports what Encode would do with doc and opts without writing any
+// bytes, so that tools can show a summary before saving. It applies the same
+// transforms, validation, and limit checks as Encode, and compresses the
+// sections to obtain their exact sizes.
+//
+// Plan does not modify doc: transforms and hash population run on a copy.
+// (Media data is shared with the copy, so transforms must not modify it in
+// place.) If the container would exceed the quota set with WithQuota, Plan
+// returns the plan together with a *QuotaError.
+func Plan(doc *Document, opts ...WriteOption) (*EncodePlan, error) {
+	cfg := newWriteConfig(opts)
+	if doc == nil {
+		return nil, fmt.Errorf("%w: document is nil", ErrValidation)
+	}
+	doc = cloneDocument(doc)
+	parts, err := prepareEncode(context.Background(), doc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	plan := &EncodePlan{
+		Size:          parts.size(),
+		HeaderFlags:   parts.headerFlags,
+		MetadataLen:   uint64(len(parts.metadata)),
+		MarkdownFiles: len(doc.Markdown.Files),
+		MediaItems:    len(doc.Media.Items),
+		Markdown: SectionPlan{
+			Compression:     cfg.mdCompression,
+			UncompressedLen: parts.mdGobLen,
+			PayloadLen:      uint64(len(parts.mdPayload)),
+		},
+		Media: SectionPlan{
+			Compression:     cfg.mediaCompression,
+			UncompressedLen: parts.mediaGobLen,
+			PayloadLen:      uint64(len(parts.mediaPayload)),
+		},
+		Limits: cfg.limits,
+	}
+	if doc.NoMedia {
+		plan.Media.Compression = CompNone
+	}
+	return plan, cfg.checkQuota(plan.Size)
+}
+
+// cloneDocument returns a copy of doc that can be modified without affecting
+// it, except for media data, which is shared.
+func cloneDocument(doc *Document) *Document {
+	c := *doc
+	c.Metadata = maps.Clone(doc.Metadata)
+	c.Markdown.Files = slices.Clone(doc.Markdown.Files)
+	for i := range c.Markdown.Files {
+		f := &c.Markdown.Files[i]
+		f.Content = bytes.Clone(f.Content)
+		f.MediaRefs = slices.Clone(f.MediaRefs)
+		f.Attributes = maps.Clone(f.Attributes)
+	}
+	c.Media.Items = slices.Clone(doc.Media.Items)
+	for i := range c.Media.Items {
+		c.Media.Items[i].Attributes = maps.Clone(c.Media.Items[i].Attributes)
+	}
+	return &c
+}
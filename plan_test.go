@@ -0,0 +1,55 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanMatchesEncode(t *testing.T) {
+	doc := sampleDoc()
+	orig := sampleDoc()
+	upper := func(d *Document) error {
+		d.Markdown.Files[0].Content = bytes.ToUpper(d.Markdown.Files[0].Content)
+		d.Metadata["planned"] = true
+		return nil
+	}
+	opts := []WriteOption{WithMediaCompression(CompNone), WithTransforms(upper)}
+	plan, err := Plan(doc, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, orig) {
+		t.Fatal("Plan modified the document")
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, opts...); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Size != uint64(buf.Len()) {
+		t.Fatalf("planned %d bytes, wrote %d", plan.Size, buf.Len())
+	}
+	if plan.Markdown.Compression != CompZSTD || plan.Media.Compression != CompNone {
+		t.Fatalf("compression: %+v %+v", plan.Markdown, plan.Media)
+	}
+	if plan.Media.PayloadLen != plan.Media.UncompressedLen || plan.Media.Ratio() != 1 {
+		t.Fatalf("media section: %+v", plan.Media)
+	}
+	if plan.MarkdownFiles != 2 || plan.MediaItems != 1 || plan.HeaderFlags != HeaderFlagMetadataJSON {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+
+	plan, err = Plan(sampleDoc(), WithQuota(10))
+	var qe *QuotaError
+	if !errors.As(err, &qe) || plan == nil || qe.Needed != plan.Size {
+		t.Fatalf("expected plan with QuotaError, got %v, %v", plan, err)
+	}
+
+	bad := sampleDoc()
+	bad.Markdown.Files = nil
+	if _, err := Plan(bad); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+}
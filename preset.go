@@ -0,0 +1,162 @@
+package mdocx
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// PresetMetadataKey is the metadata key in which Preset.New records the name
+// of the preset a document was created from.
+const PresetMetadataKey = "preset"
+
+// Preset bundles the conventions of one kind of document: default metadata,
+// required metadata keys, the root file path, attribute types, and optional
+// invariants. Presets let applications built on MDOCX share conventions in
+// code. Use RegisterPreset to add one and LookupPreset to find one by name.
+type Preset struct {
+	// Name identifies the preset, e.g. "book".
+	Name string
+	// Description is a short human-readable summary.
+	Description string
+	// Metadata holds default metadata values copied into new documents.
+	Metadata map[string]any
+	// RequiredMetadata lists metadata keys that must be present and non-empty.
+	RequiredMetadata []string
+	// RootPath is the conventional path of the primary Markdown file.
+	// If set, documents must contain a file at this path.
+	RootPath string
+	// Attrs declares the types of file and media item attributes.
+	Attrs AttrSchema
+	// Checks selects optional invariants the documents must satisfy.
+	Checks Check
+}
+
+var (
+	presetsMu sync.RWMutex
+	presets   = map[string]Preset{}
+)
+
+func init() {
+	for _, p := range []Preset{
+		{
+			Name:             "notebook",
+			Description:      "Personal or lab notes, one file per entry",
+			Metadata:         map[string]any{"kind": "notebook"},
+			RequiredMetadata: []string{"title"},
+			RootPath:         "index.md",
+			Attrs:            AttrSchema{"date": AttrTime, "updated": AttrTime, "tags": AttrStrings},
+		},
+		{
+			Name:             "book",
+			Description:      "A book with chapters in reading order and fully referenced media",
+			Metadata:         map[string]any{"kind": "book"},
+			RequiredMetadata: []string{"title", "creator"},
+			RootPath:         "index.md",
+			Attrs:            AttrSchema{"chapter": AttrInt, LanguageAttr: AttrString, AltTextAttr: AttrString},
+			Checks:           CheckAll,
+		},
+		{
+			Name:             "kb-article",
+			Description:      "A knowledge-base article with its images",
+			Metadata:         map[string]any{"kind": "kb-article"},
+			RequiredMetadata: []string{"title", "tags"},
+			RootPath:         "article.md",
+			Attrs:            AttrSchema{"updated": AttrTime, LanguageAttr: AttrString, AltTextAttr: AttrString},
+			Checks:           CheckMediaRefs,
+		},
+	} {
+		if err := RegisterPreset(p); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterPreset adds p to the registry. It fails with ErrValidation if the
+// name is empty or already registered, or if RootPath is not a valid container
+// path. It is safe for concurrent use.
+func RegisterPreset(p Preset) error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: preset name is empty", ErrValidation)
+	}
+	if p.RootPath != "" {
+		if err := validateContainerPath(p.RootPath); err != nil {
+			return fmt.Errorf("%w: preset %q RootPath: %v", ErrValidation, p.Name, err)
+		}
+	}
+	presetsMu.Lock()
+	defer presetsMu.Unlock()
+	if _, dup := presets[p.Name]; dup {
+		return fmt.Errorf("%w: preset %q is already registered", ErrValidation, p.Name)
+	}
+	presets[p.Name] = p
+	return nil
+}
+
+// LookupPreset returns a copy of the registered preset with the given name.
+func LookupPreset(name string) (Preset, bool) {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	p, ok := presets[name]
+	p.Metadata = maps.Clone(p.Metadata)
+	p.RequiredMetadata = slices.Clone(p.RequiredMetadata)
+	p.Attrs = maps.Clone(p.Attrs)
+	return p, ok
+}
+
+// Presets returns the names of the registered presets in sorted order.
+func Presets() []string {
+	presetsMu.RLock()
+	defer presetsMu.RUnlock()
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns an empty document following p: its metadata holds p's defaults
+// and the preset name, its bundles have VersionV1, and RootPath is set. Add at
+// least one Markdown file (at RootPath, if set) before encoding it.
+func (p Preset) New() *Document {
+	meta := maps.Clone(p.Metadata)
+	if meta == nil {
+		meta = make(map[string]any)
+	}
+	meta[PresetMetadataKey] = p.Name
+	return &Document{
+		Metadata: meta,
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, RootPath: p.RootPath},
+		Media:    MediaBundle{BundleVersion: VersionV1},
+	}
+}
+
+// Validate checks doc against the conventions of p. It does not repeat the
+// format validation done by Encode and Decode. Violations wrap ErrValidation.
+func (p Preset) Validate(doc *Document) error {
+	for _, k := range p.RequiredMetadata {
+		if v, ok := doc.Metadata[k]; !ok || v == nil || v == "" {
+			return fmt.Errorf("%w: preset %q requires metadata %q", ErrValidation, p.Name, k)
+		}
+	}
+	if p.RootPath != "" && !slices.ContainsFunc(doc.Markdown.Files, func(f MarkdownFile) bool { return f.Path == p.RootPath }) {
+		return fmt.Errorf("%w: preset %q requires root file %q", ErrValidation, p.Name, p.RootPath)
+	}
+	if err := p.Attrs.validateDocument(doc); err != nil {
+		return err
+	}
+	return doc.CheckInvariants(p.Checks)
+}
+
+// WriteOptions returns the write options that make Encode enforce p: a
+// transform running Validate, the attribute schema, and the invariant checks.
+func (p Preset) WriteOptions() []WriteOption {
+	return []WriteOption{
+		WithTransforms(func(doc *Document) error { return p.Validate(doc) }),
+		WithAttrSchema(p.Attrs),
+		WithChecksOnWrite(p.Checks),
+	}
+}
@@ -0,0 +1,59 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	if got, want := Presets(), []string{"book", "kb-article", "notebook"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Presets() = %v, want %v", got, want)
+	}
+	p, ok := LookupPreset("kb-article")
+	if !ok {
+		t.Fatal("kb-article not registered")
+	}
+	doc := p.New()
+	if doc.Metadata[PresetMetadataKey] != "kb-article" || doc.Markdown.RootPath != "article.md" {
+		t.Fatalf("unexpected new document: %+v", doc)
+	}
+	doc.Metadata["title"] = "How to"
+	doc.Markdown.Files = []MarkdownFile{{Path: "article.md", Content: []byte("# How to\n"), MediaRefs: []string{"shot"}}}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, p.WriteOptions()...); !errors.Is(err, ErrValidation) {
+		t.Fatalf("missing tags: expected ErrValidation, got %v", err)
+	}
+	doc.Metadata["tags"] = []any{"howto"}
+	if err := Encode(&buf, doc, p.WriteOptions()...); !errors.Is(err, ErrValidation) {
+		t.Fatalf("dangling MediaRefs: expected ErrValidation, got %v", err)
+	}
+	doc.Media.Items = []MediaItem{{ID: "shot", MIMEType: "image/png", Data: []byte{1}, Attributes: map[string]string{"alt": "A screenshot"}}}
+	doc.Markdown.Files[0].Attributes = map[string]string{"updated": "yesterday"}
+	if err := Encode(&buf, doc, p.WriteOptions()...); !errors.Is(err, ErrValidation) {
+		t.Fatalf("bad attribute: expected ErrValidation, got %v", err)
+	}
+	doc.Markdown.Files[0].Attributes["updated"] = "2024-05-01T00:00:00Z"
+	if err := Encode(&buf, doc, p.WriteOptions()...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterPreset(t *testing.T) {
+	if err := RegisterPreset(Preset{Name: "book"}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("duplicate: expected ErrValidation, got %v", err)
+	}
+	if err := RegisterPreset(Preset{}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("empty name: expected ErrValidation, got %v", err)
+	}
+	if err := RegisterPreset(Preset{Name: "bad-root", RootPath: "../x.md"}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("bad root: expected ErrValidation, got %v", err)
+	}
+	p, _ := LookupPreset("book")
+	p.Attrs["chapter"] = AttrString
+	if q, _ := LookupPreset("book"); q.Attrs["chapter"] != AttrInt {
+		t.Fatal("LookupPreset returned shared state")
+	}
+}
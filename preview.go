@@ -0,0 +1,219 @@
+package mdocx
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// CoverMetadataKey is the metadata key naming the cover image of a document,
+// as the ID or container path of a media item.
+const CoverMetadataKey = "cover"
+
+// DocumentPreview is the summary of a container returned by Preview.
+type DocumentPreview struct {
+	// Title is the metadata "title", else the text of the first heading of the
+	// root Markdown file.
+	Title string
+	// RootPath is the path of the root Markdown file: Markdown.RootPath, or
+	// the first file if it is unset.
+	RootPath string
+	// Markdown holds the first bytes of the root Markdown file, cut at a
+	// character boundary.
+	Markdown []byte
+	// Truncated reports whether Markdown is shorter than the file.
+	Truncated bool
+	// MarkdownFiles and MediaItems count the files and items of the container.
+	MarkdownFiles int
+	MediaItems    int
+	// Cover holds the data of the cover image, or nil if there is none. The
+	// cover is the media item named by metadata "cover", or if that is unset,
+	// the first item with an image/* MIME type.
+	Cover []byte
+	// CoverMIMEType is the MIME type of Cover.
+	CoverMIMEType string
+}
+
+// previewConfig holds configuration options for Preview.
+type previewConfig struct {
+	markdownBytes int
+	read          []ReadOption
+}
+
+// PreviewOption is a functional option for configuring Preview.
+type PreviewOption func(*previewConfig)
+
+// WithPreviewBytes sets how many bytes of the root Markdown file Preview
+// returns. Default is 4096.
+func WithPreviewBytes(n int) PreviewOption {
+	return func(c *previewConfig) { c.markdownBytes = n }
+}
+
+// WithPreviewReadOptions sets the ReadOption values, such as limits, that
+// Preview reads the container with.
+func WithPreviewReadOptions(opts ...ReadOption) PreviewOption {
+	return func(c *previewConfig) { c.read = opts }
+}
+
+// Preview reads just enough of the container in r to show it in a file
+// manager or preview pane: its title, the start of its root Markdown file, its
+// file and item counts, and its cover image.
+//
+// If r also implements io.ReaderAt and io.Seeker, as *os.File does, the
+// container is read from the current offset to the end with NewReader, and of
+// the media data only the cover is read (see Reader for the limits of this
+// with a compressed Media section). Otherwise the whole container is read,
+// but only the cover is kept in memory. Either way, other media items are
+// neither copied nor hash-verified.
+func Preview(r io.Reader, opts ...PreviewOption) (*DocumentPreview, error) {
+	cfg := previewConfig{markdownBytes: 4096}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		return previewAt(ra, cfg)
+	}
+	return previewStream(r, cfg)
+}
+
+// previewAt is Preview for seekable sources.
+func previewAt(ra interface {
+	io.ReaderAt
+	io.Seeker
+}, cfg previewConfig) (*DocumentPreview, error) {
+	start, err := ra.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ra.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	rd, err := NewReader(io.NewSectionReader(ra, start, end-start), end-start, cfg.read...)
+	if err != nil {
+		return nil, err
+	}
+	p := newPreview(rd.Metadata(), rd.Markdown(), cfg.markdownBytes)
+	media := rd.Media()
+	p.MediaItems = len(media)
+	ref := metadataString(rd.Metadata(), CoverMetadataKey)
+	for _, it := range media {
+		if !isCover(ref, it.ID, it.Path, it.MIMEType) {
+			continue
+		}
+		rc, err := rd.OpenMedia(it.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		if p.Cover, err = io.ReadAll(rc); err != nil {
+			return nil, err
+		}
+		p.CoverMIMEType = it.MIMEType
+		break
+	}
+	return p, nil
+}
+
+// previewStream is Preview for plain readers.
+func previewStream(r io.Reader, cfg previewConfig) (*DocumentPreview, error) {
+	var (
+		metadata map[string]any
+		markdown = MarkdownBundle{BundleVersion: VersionV1}
+		ref      string
+		items    int
+		chosen   bool
+		cover    *MediaItem
+	)
+	keep := func(id, path, mimeType string, size int64) bool {
+		items++
+		if chosen || !isCover(ref, id, path, mimeType) {
+			return false
+		}
+		chosen = true
+		return true
+	}
+	sink := SinkFuncs{
+		OnMetadata: func(m map[string]any, rootPath string) error {
+			metadata, markdown.RootPath = m, rootPath
+			ref = metadataString(m, CoverMetadataKey)
+			return nil
+		},
+		OnMarkdownFile: func(f MarkdownFile) error {
+			markdown.Files = append(markdown.Files, f)
+			return nil
+		},
+		OnMediaItem: func(it MediaItem) error {
+			it.Data = append([]byte(nil), it.Data...)
+			cover = &it
+			return nil
+		},
+	}
+	opts := append(append([]ReadOption(nil), cfg.read...), WithMediaFilter(keep))
+	if err := DecodeInto(r, sink, opts...); err != nil {
+		return nil, err
+	}
+	p := newPreview(metadata, markdown, cfg.markdownBytes)
+	p.MediaItems = items
+	if cover != nil {
+		p.Cover, p.CoverMIMEType = cover.Data, cover.MIMEType
+	}
+	return p, nil
+}
+
+// newPreview fills in the Markdown parts of a preview, keeping n bytes of the
+// root file.
+func newPreview(metadata map[string]any, markdown MarkdownBundle, n int) *DocumentPreview {
+	p := &DocumentPreview{
+		Title:         metadataString(metadata, "title"),
+		RootPath:      markdown.RootPath,
+		MarkdownFiles: len(markdown.Files),
+	}
+	var root *MarkdownFile
+	for i := range markdown.Files {
+		if markdown.Files[i].Path == p.RootPath || (p.RootPath == "" && i == 0) {
+			root = &markdown.Files[i]
+			break
+		}
+	}
+	if root == nil {
+		return p
+	}
+	p.RootPath = root.Path
+	if p.Title == "" {
+		doc := &Document{Metadata: metadata}
+		if hs := doc.scanFile(*root).Headings; len(hs) > 0 {
+			p.Title = mdscan.InlineText(hs[0].Text)
+		}
+	}
+	content := root.Content
+	if len(content) > n {
+		cut := max(n, 0)
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content, p.Truncated = content[:cut], true
+	}
+	p.Markdown = append([]byte(nil), content...)
+	return p
+}
+
+// isCover reports whether a media item is the cover named by ref, or when ref
+// is empty, whether it is an image.
+func isCover(ref, id, path, mimeType string) bool {
+	if ref != "" {
+		return id == ref || (path != "" && path == ref)
+	}
+	return strings.HasPrefix(mimeType, "image/")
+}
+
+// metadataString returns the string value of metadata key, or "".
+func metadataString(metadata map[string]any, key string) string {
+	s, _ := metadata[key].(string)
+	return s
+}
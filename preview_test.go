@@ -0,0 +1,78 @@
+package mdocx
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	doc := sampleDoc()
+	delete(doc.Metadata, "title")
+	doc.Markdown.Files[0].Content = []byte("# Héllo\n\nBody text\n")
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "cover", Path: "assets/cover.jpg", MIMEType: "image/jpeg", Data: []byte{9, 9}},
+		MediaItem{ID: "clip", MIMEType: "video/mp4", Data: bytes.Repeat([]byte{7}, 1000)},
+	)
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	sources := map[string]func() io.Reader{
+		"seekable": func() io.Reader { return bytes.NewReader(buf.Bytes()) },
+		"stream":   func() io.Reader { return io.MultiReader(bytes.NewReader(buf.Bytes())) },
+	}
+	for name, src := range sources {
+		p, err := Preview(src(), WithPreviewBytes(4))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// "# H" plus the 2-byte "é" would be 5 bytes, so the cut falls before it.
+		if p.Title != "Héllo" || p.RootPath != "docs/index.md" || string(p.Markdown) != "# H" || !p.Truncated {
+			t.Fatalf("%s: %+v", name, p)
+		}
+		if p.MarkdownFiles != 2 || p.MediaItems != 3 {
+			t.Fatalf("%s: counts %d, %d", name, p.MarkdownFiles, p.MediaItems)
+		}
+		if !bytes.Equal(p.Cover, []byte{1, 2, 3}) || p.CoverMIMEType != "image/png" {
+			t.Fatalf("%s: default cover = %v %q", name, p.Cover, p.CoverMIMEType)
+		}
+	}
+
+	doc.Metadata[CoverMetadataKey] = "assets/cover.jpg"
+	doc.Metadata["title"] = "Titled"
+	buf.Reset()
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range sources {
+		p, err := Preview(src())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.Title != "Titled" || p.Truncated || string(p.Markdown) != string(doc.Markdown.Files[0].Content) {
+			t.Fatalf("%s: %+v", name, p)
+		}
+		if !bytes.Equal(p.Cover, []byte{9, 9}) || p.CoverMIMEType != "image/jpeg" {
+			t.Fatalf("%s: named cover = %v %q", name, p.Cover, p.CoverMIMEType)
+		}
+	}
+}
+
+func TestPreviewReadsLittle(t *testing.T) {
+	doc := sampleDoc()
+	big := bytes.Repeat([]byte("video"), 100000)
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "clip", MIMEType: "video/mp4", Data: big})
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	ra := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	p, err := Preview(io.NewSectionReader(ra, 0, int64(buf.Len())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Cover == nil || ra.n >= int64(len(big)) {
+		t.Fatalf("Preview read %d bytes, cover %v", ra.n, p.Cover)
+	}
+}
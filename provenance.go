@@ -0,0 +1,210 @@
+package mdocx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Media item attribute keys holding provenance records, in the spirit of
+// content-provenance schemes such as C2PA: where an item was captured and a
+// hash chain of the edits made to it since.
+const (
+	// ProvenanceVersionAttr holds the version of the provenance schema the
+	// other attributes follow, ProvenanceVersion.
+	ProvenanceVersionAttr = "provenance_version"
+	// ProvenanceDeviceAttr holds the capture device, such as a camera model.
+	ProvenanceDeviceAttr = "provenance_device"
+	// ProvenanceCapturedAttr holds the capture time, in RFC 3339 form.
+	ProvenanceCapturedAttr = "provenance_captured"
+	// ProvenanceEditsAttr holds the edit history as a JSON array of
+	// ProvenanceEdit objects, oldest first.
+	ProvenanceEditsAttr = "provenance_edits"
+)
+
+// ProvenanceVersion is the provenance schema version this package writes and
+// reads.
+const ProvenanceVersion = 1
+
+// Provenance is the provenance record of a media item.
+type Provenance struct {
+	// Version is the schema version, ProvenanceVersion.
+	Version int
+	// Device is the capture device, if recorded.
+	Device string
+	// Captured is the capture time, if recorded.
+	Captured time.Time
+	// Edits is the edit history, oldest first. The capture itself is the first
+	// edit of items recorded with RecordCapture.
+	Edits []ProvenanceEdit
+}
+
+// ProvenanceEdit records one change to a media item's data.
+type ProvenanceEdit struct {
+	// Tool identifies the program or user agent that made the change.
+	Tool string `json:"tool"`
+	// Time is when the change was recorded.
+	Time time.Time `json:"time"`
+	// Operation is a short human-readable summary of the change.
+	Operation string `json:"operation"`
+	// Before is the hex SHA-256 of the data before the change, or "" for the
+	// first edit.
+	Before string `json:"before,omitempty"`
+	// After is the hex SHA-256 of the data after the change.
+	After string `json:"after"`
+	// Link is the hex SHA-256 of the previous edit's Link and this edit's
+	// other fields, chaining the history so that no edit can be altered,
+	// removed, or reordered without breaking every later link.
+	Link string `json:"link"`
+}
+
+// Provenance returns the provenance record of m. It returns an error wrapping
+// ErrNotFound if m has none, and one wrapping ErrValidation if the record is
+// malformed or uses an unsupported schema version.
+func (m MediaItem) Provenance() (*Provenance, error) {
+	a := Attributes(m.Attributes)
+	v, err := a.Int(ProvenanceVersionAttr)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: media item %q has no provenance", ErrNotFound, m.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if v != ProvenanceVersion {
+		return nil, fmt.Errorf("%w: media item %q: unsupported provenance version %d", ErrValidation, m.ID, v)
+	}
+	p := &Provenance{Version: int(v), Device: a[ProvenanceDeviceAttr]}
+	if _, ok := a[ProvenanceCapturedAttr]; ok {
+		if p.Captured, err = a.Time(ProvenanceCapturedAttr); err != nil {
+			return nil, err
+		}
+	}
+	if raw, ok := a[ProvenanceEditsAttr]; ok {
+		if err := json.Unmarshal([]byte(raw), &p.Edits); err != nil {
+			return nil, fmt.Errorf("%w: media item %q: %s: %v", ErrValidation, m.ID, ProvenanceEditsAttr, err)
+		}
+	}
+	return p, nil
+}
+
+// RecordCapture starts the provenance record of m, replacing any existing
+// one: it sets the capture device and time and an edit history holding one
+// "capture" edit for m's current data. A zero at is replaced with the current
+// time.
+func (m *MediaItem) RecordCapture(device string, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	a := Attributes(m.Attributes)
+	delete(a, ProvenanceEditsAttr)
+	a.SetInt(ProvenanceVersionAttr, ProvenanceVersion)
+	if device != "" {
+		a.SetString(ProvenanceDeviceAttr, device)
+	} else {
+		delete(a, ProvenanceDeviceAttr)
+	}
+	a.SetTime(ProvenanceCapturedAttr, at)
+	m.Attributes = a
+	return m.RecordEdit(ProvenanceEdit{Tool: device, Time: at, Operation: "capture"})
+}
+
+// RecordEdit appends e to the edit history of m, starting a record if m has
+// none. Call it after changing m.Data: Before is filled with the previous
+// edit's After, After with the hash of the current data, and Link with the
+// chain hash; Time is filled with the current time when it is zero. It returns
+// an error wrapping ErrValidation if m's existing record is malformed.
+func (m *MediaItem) RecordEdit(e ProvenanceEdit) error {
+	p, err := m.Provenance()
+	if errors.Is(err, ErrNotFound) {
+		p, err = &Provenance{Version: ProvenanceVersion}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.Before, e.After = "", m.provenanceHash()
+	prevLink := ""
+	if n := len(p.Edits); n > 0 {
+		e.Before, prevLink = p.Edits[n-1].After, p.Edits[n-1].Link
+	}
+	e.Link = e.chainLink(prevLink)
+	b, err := json.Marshal(append(p.Edits, e))
+	if err != nil {
+		return err
+	}
+	a := Attributes(m.Attributes)
+	a.SetInt(ProvenanceVersionAttr, ProvenanceVersion)
+	a.SetString(ProvenanceEditsAttr, string(b))
+	m.Attributes = a
+	return nil
+}
+
+// VerifyProvenance checks the edit history of m: the first edit has no
+// Before, each later edit's Before matches the previous edit's After, every
+// Link matches its chain hash, and the last edit's After matches the current
+// data, so that a mismatch means the data or the history was changed without
+// recording an edit. An item without provenance, or with an empty history,
+// verifies trivially. Failures wrap ErrValidation.
+//
+// For items stubbed out by EncodeTiered, the data is represented by the hash
+// recorded in the stub.
+func (m MediaItem) VerifyProvenance() error {
+	p, err := m.Provenance()
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	prev := ProvenanceEdit{}
+	for i, e := range p.Edits {
+		if e.Before != prev.After {
+			return fmt.Errorf("%w: media item %q: provenance edit %d (%s) does not follow the one before", ErrValidation, m.ID, i, e.Operation)
+		}
+		if e.Link != e.chainLink(prev.Link) {
+			return fmt.Errorf("%w: media item %q: provenance edit %d (%s) has a broken link", ErrValidation, m.ID, i, e.Operation)
+		}
+		prev = e
+	}
+	if len(p.Edits) > 0 && prev.After != m.provenanceHash() {
+		return fmt.Errorf("%w: media item %q changed after its last provenance edit", ErrValidation, m.ID)
+	}
+	return nil
+}
+
+// VerifyProvenance runs MediaItem.VerifyProvenance on every media item of d
+// and returns the first failure.
+func (d *Document) VerifyProvenance() error {
+	for _, it := range d.Media.Items {
+		if err := it.VerifyProvenance(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// provenanceHash returns the hex SHA-256 of m's data, or for a stub left by
+// EncodeTiered, the one recorded in the stub.
+func (m MediaItem) provenanceHash() string {
+	if sum, ok := m.Attributes[ColdSHA256Attr]; ok && m.Data == nil {
+		return sum
+	}
+	sum := m.computedSHA256()
+	return hex.EncodeToString(sum[:])
+}
+
+// chainLink returns the Link of e following an edit with Link prev.
+func (e ProvenanceEdit) chainLink(prev string) string {
+	e.Link = ""
+	b, _ := json.Marshal(e) // a Time that cannot be marshaled fails RecordEdit later
+	h := sha256.New()
+	h.Write([]byte(prev))
+	h.Write([]byte{'\n'})
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,101 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+	doc := sampleDoc()
+	it := &doc.Media.Items[0]
+	if _, err := it.Provenance(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Provenance without a record: %v", err)
+	}
+	if err := it.VerifyProvenance(); err != nil {
+		t.Fatal(err)
+	}
+
+	captured := time.Date(2026, 3, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+	if err := it.RecordCapture("Example Cam X100", captured); err != nil {
+		t.Fatal(err)
+	}
+	it.Data = append(it.Data, 4)
+	if err := it.VerifyProvenance(); !errors.Is(err, ErrValidation) {
+		t.Fatalf("unrecorded edit: %v", err)
+	}
+	if err := it.RecordEdit(ProvenanceEdit{Tool: "cropper/1.0", Operation: "crop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The record survives a round trip.
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.VerifyProvenance(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := got.Media.Items[0].Provenance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != ProvenanceVersion || p.Device != "Example Cam X100" || !p.Captured.Equal(captured) || len(p.Edits) != 2 {
+		t.Fatalf("Provenance() = %+v", p)
+	}
+	if e := p.Edits[1]; e.Operation != "crop" || e.Before != p.Edits[0].After || e.Before == e.After || e.Time.IsZero() {
+		t.Fatalf("edit = %+v", e)
+	}
+
+	// Tampering with the history breaks the chain.
+	for name, tamper := range map[string]func(string) string{
+		"altered":  func(s string) string { return strings.Replace(s, "cropper/1.0", "cropper/2.0", 1) },
+		"version":  nil,
+		"garbled":  func(string) string { return "[" },
+		"reversed": nil,
+	} {
+		m := got.Media.Items[0]
+		m.Attributes = maps.Clone(m.Attributes)
+		switch name {
+		case "version":
+			m.Attributes[ProvenanceVersionAttr] = "2"
+		case "reversed":
+			p, _ := m.Provenance()
+			p.Edits[0], p.Edits[1] = p.Edits[1], p.Edits[0]
+			b, _ := json.Marshal(p.Edits)
+			m.Attributes[ProvenanceEditsAttr] = string(b)
+		default:
+			m.Attributes[ProvenanceEditsAttr] = tamper(m.Attributes[ProvenanceEditsAttr])
+		}
+		if err := m.VerifyProvenance(); !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestProvenanceColdStub(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].Data = bytes.Repeat([]byte{7}, 1024)
+	if err := doc.Media.Items[0].RecordCapture("", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	var hot, cold bytes.Buffer
+	if err := EncodeTiered(&hot, &cold, doc, 100); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(&hot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.VerifyProvenance(); err != nil {
+		t.Fatalf("stub: %v", err)
+	}
+}
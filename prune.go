@@ -0,0 +1,35 @@
+package mdocx
+
+// PruneReport describes the media items removed by PruneUnusedMedia.
+type PruneReport struct {
+	// Removed lists the IDs of the removed items, in bundle order.
+	Removed []string
+	// BytesSaved is the total size of the removed items' data.
+	BytesSaved int64
+}
+
+// PruneUnusedMedia removes the media items that no Markdown file references,
+// as reported by MediaUsage, and reports what was removed. An item named by
+// the metadata "cover" (see CoverMetadataKey) is kept even if unreferenced.
+// The remaining items keep their order. It is meant for repacking a bundle
+// after Markdown files have been deleted or rewritten.
+func (d *Document) PruneUnusedMedia() PruneReport {
+	var rep PruneReport
+	usage := d.MediaUsage()
+	if len(usage.Unused) == 0 {
+		return rep
+	}
+	cover := metadataString(d.Metadata, CoverMetadataKey)
+	kept := d.Media.Items[:0]
+	for _, it := range d.Media.Items {
+		if len(usage.Uses[it.ID]) > 0 || (cover != "" && isCover(cover, it.ID, it.Path, it.MIMEType)) {
+			kept = append(kept, it)
+			continue
+		}
+		rep.Removed = append(rep.Removed, it.ID)
+		rep.BytesSaved += int64(len(it.Data))
+	}
+	clear(d.Media.Items[len(kept):])
+	d.Media.Items = kept
+	return rep
+}
@@ -0,0 +1,39 @@
+package mdocx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPruneUnusedMedia(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[0].MediaRefs = nil
+	doc.Markdown.Files[1].Content = []byte("![chart](../assets/chart.png)\n")
+	doc.Metadata[CoverMetadataKey] = "assets/cover.jpg"
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "old", Path: "assets/old.png", MIMEType: "image/png", Data: []byte("12345")},
+		MediaItem{ID: "chart", Path: "assets/chart.png", MIMEType: "image/png", Data: []byte{7}},
+		MediaItem{ID: "cover", Path: "assets/cover.jpg", MIMEType: "image/jpeg", Data: []byte{8}},
+		MediaItem{ID: "draft", MIMEType: "text/plain", Data: []byte("abc")},
+	)
+
+	rep := doc.PruneUnusedMedia()
+	if want := (PruneReport{Removed: []string{"old", "draft"}, BytesSaved: 8}); !reflect.DeepEqual(rep, want) {
+		t.Fatalf("got %+v, want %+v", rep, want)
+	}
+	var ids []string
+	for _, it := range doc.Media.Items {
+		ids = append(ids, it.ID)
+	}
+	if want := []string{"logo", "chart", "cover"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("kept %v, want %v", ids, want)
+	}
+	if err := Encode(&bytes.Buffer{}, doc, WithChecksOnWrite(CheckMediaRefs|CheckImageLinks)); err != nil {
+		t.Fatal(err)
+	}
+
+	if rep := doc.PruneUnusedMedia(); rep.Removed != nil || rep.BytesSaved != 0 {
+		t.Fatalf("second prune: %+v", rep)
+	}
+}
@@ -0,0 +1,264 @@
+package mdocx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a compiled attribute predicate, as accepted by Document.Query.
+// A Selector is safe for concurrent use.
+type Selector struct {
+	root selectorNode
+}
+
+// QueryResult holds the Markdown files and media items selected by
+// Document.Query, in bundle order.
+type QueryResult struct {
+	Files []MarkdownFile
+	Media []MediaItem
+}
+
+// ParseSelector compiles a selector: a boolean expression over attributes,
+// such as
+//
+//	status=final && language=en
+//	(status=draft || status=review) && !archived
+//	owner!="Jane Doe"
+//
+// Terms are key=value (the attribute is set to value), key!=value (the
+// attribute is missing or set to another value), and key alone (the attribute
+// is set, to any value). Terms combine with && and ||, negate with !, and
+// group with parentheses; && binds tighter than ||. Keys and unquoted values
+// are runs of letters, digits, and the characters _ - . : / * +. Values with
+// other characters are written as Go string literals in double quotes.
+// Comparisons are exact and case-sensitive.
+//
+// Errors wrap ErrValidation and give the offset of the problem.
+func ParseSelector(selector string) (*Selector, error) {
+	p := &selectorParser{s: selector}
+	p.skipSpace()
+	if p.pos == len(p.s) {
+		return nil, p.errorf("empty selector")
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.s) {
+		return nil, p.errorf("unexpected %q", p.s[p.pos:p.pos+1])
+	}
+	return &Selector{root: root}, nil
+}
+
+// Match reports whether attrs satisfy s. A nil map has no attributes.
+func (s *Selector) Match(attrs map[string]string) bool {
+	return s.root.match(attrs)
+}
+
+// Query returns the Markdown files and media items of d whose Attributes
+// match selector (see ParseSelector), so that workflow tools can select, for
+// example, the final documents in one language without writing a filter loop.
+// The returned files and items share their contents with d.
+func (d *Document) Query(selector string) (*QueryResult, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	res := &QueryResult{}
+	for _, f := range d.Markdown.Files {
+		if s.Match(f.Attributes) {
+			res.Files = append(res.Files, f)
+		}
+	}
+	for _, it := range d.Media.Items {
+		if s.Match(it.Attributes) {
+			res.Media = append(res.Media, it)
+		}
+	}
+	return res, nil
+}
+
+// selectorNode is a node of a compiled selector.
+type selectorNode interface {
+	match(attrs map[string]string) bool
+}
+
+type (
+	selectorOr  []selectorNode
+	selectorAnd []selectorNode
+	selectorNot struct{ x selectorNode }
+	selectorHas struct{ key string }
+	selectorEq  struct{ key, value string }
+)
+
+func (n selectorOr) match(attrs map[string]string) bool {
+	for _, x := range n {
+		if x.match(attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n selectorAnd) match(attrs map[string]string) bool {
+	for _, x := range n {
+		if !x.match(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n selectorNot) match(attrs map[string]string) bool { return !n.x.match(attrs) }
+
+func (n selectorHas) match(attrs map[string]string) bool {
+	_, ok := attrs[n.key]
+	return ok
+}
+
+func (n selectorEq) match(attrs map[string]string) bool {
+	v, ok := attrs[n.key]
+	return ok && v == n.value
+}
+
+// selectorParser is a recursive-descent parser for ParseSelector.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func (p *selectorParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: selector %q: %s at offset %d", ErrValidation, p.s, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *selectorParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// consume skips tok, and any space after it, if it comes next.
+func (p *selectorParser) consume(tok string) bool {
+	if !strings.HasPrefix(p.s[p.pos:], tok) {
+		return false
+	}
+	p.pos += len(tok)
+	p.skipSpace()
+	return true
+}
+
+func (p *selectorParser) or() (selectorNode, error) {
+	var terms selectorOr
+	for {
+		x, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *selectorParser) and() (selectorNode, error) {
+	var terms selectorAnd
+	for {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, x)
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+func (p *selectorParser) unary() (selectorNode, error) {
+	switch {
+	case p.consume("!"):
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return selectorNot{x}, nil
+	case p.consume("("):
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	}
+	key := p.word()
+	if key == "" {
+		if p.pos == len(p.s) {
+			return nil, p.errorf("unexpected end")
+		}
+		return nil, p.errorf("expected attribute key")
+	}
+	p.skipSpace()
+	var negate bool
+	switch {
+	case p.consume("!="):
+		negate = true
+	case p.consume("="):
+	default:
+		return selectorHas{key}, nil
+	}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	var n selectorNode = selectorEq{key, value}
+	if negate {
+		n = selectorNot{n}
+	}
+	return n, nil
+}
+
+// word returns the key or unquoted value that comes next, if any.
+func (p *selectorParser) word() string {
+	start := p.pos
+	for p.pos < len(p.s) && isSelectorWordByte(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *selectorParser) value() (string, error) {
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		lit, err := strconv.QuotedPrefix(p.s[p.pos:])
+		if err != nil {
+			return "", p.errorf("malformed quoted value")
+		}
+		p.pos += len(lit)
+		return strconv.Unquote(lit)
+	}
+	v := p.word()
+	if v == "" {
+		return "", p.errorf("expected value")
+	}
+	return v, nil
+}
+
+func isSelectorWordByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("_-.:/*+", c) >= 0
+}
@@ -0,0 +1,79 @@
+package mdocx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSelectorMatch(t *testing.T) {
+	attrs := map[string]string{"status": "final", "language": "en", "owner": "Jane Doe", "x.y/z": ""}
+	cases := []struct {
+		selector string
+		want     bool
+	}{
+		{"status=final", true},
+		{"status=draft", false},
+		{"status = final && language=en", true},
+		{"status=final && language=de", false},
+		{"status=draft || language=en", true},
+		{"status=draft || language=de && owner", false},
+		{"(status=draft || language=en) && owner", true},
+		{"!archived", true},
+		{"!!status", true},
+		{"archived != yes", true},
+		{"status!=final", false},
+		{`owner="Jane Doe"`, true},
+		{`owner!="Jane Doe"`, false},
+		{"x.y/z", true},
+		{`x.y/z=""`, true},
+		{"!(status=final && language=en)", false},
+	}
+	for _, c := range cases {
+		s, err := ParseSelector(c.selector)
+		if err != nil {
+			t.Errorf("ParseSelector(%q): %v", c.selector, err)
+			continue
+		}
+		if got := s.Match(attrs); got != c.want {
+			t.Errorf("%q matched %v, want %v", c.selector, got, c.want)
+		}
+	}
+	if s, _ := ParseSelector("!status"); !s.Match(nil) {
+		t.Error("!status should match nil attributes")
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, sel := range []string{"", "  ", "status=", "status=final &&", "(a", "a)", "a = \"x", "&& a", "a b", "a=b=c", "a || || b"} {
+		_, err := ParseSelector(sel)
+		if !errors.Is(err, ErrValidation) || !strings.Contains(err.Error(), "offset") {
+			t.Errorf("ParseSelector(%q) = %v", sel, err)
+		}
+	}
+}
+
+func TestDocumentQuery(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[0].Attributes = map[string]string{"status": "final", "language": "en"}
+	doc.Markdown.Files[1].Attributes = map[string]string{"status": "draft", "language": "en"}
+	doc.Media.Items[0].Attributes = map[string]string{"status": "final"}
+
+	res, err := doc.Query("status=final")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || res.Files[0].Path != "docs/index.md" || len(res.Media) != 1 {
+		t.Fatalf("status=final: %+v", res)
+	}
+	res, err = doc.Query("status=final && language=en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || len(res.Media) != 0 {
+		t.Fatalf("status=final && language=en: %+v", res)
+	}
+	if _, err := doc.Query("status=("); !errors.Is(err, ErrValidation) {
+		t.Fatalf("bad selector: %v", err)
+	}
+}
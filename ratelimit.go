@@ -0,0 +1,140 @@
+package mdocx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Function variables for testing injection.
+var (
+	rateNow   = time.Now
+	rateSleep = func(ctx context.Context, d time.Duration) error {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+			return nil
+		}
+	}
+)
+
+// RateLimiter is a token bucket that limits the throughput of the readers and
+// writers built on it. One RateLimiter may be shared by any number of readers
+// and writers, across goroutines, to cap their combined bandwidth.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens (bytes) per second
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter that allows bytesPerSec bytes per second
+// on average and bursts of up to burst bytes. A burst of zero or less defaults
+// to bytesPerSec/10 (at least 4 KiB), which keeps individual waits short.
+// NewRateLimiter panics if bytesPerSec is not positive.
+func NewRateLimiter(bytesPerSec int64, burst int) *RateLimiter {
+	if bytesPerSec <= 0 {
+		panic(fmt.Sprintf("mdocx: invalid rate %d bytes/s", bytesPerSec))
+	}
+	if burst <= 0 {
+		burst = int(max(bytesPerSec/10, 4<<10))
+	}
+	return &RateLimiter{rate: float64(bytesPerSec), burst: burst, tokens: float64(burst), last: rateNow()}
+}
+
+// WaitN blocks until n bytes may pass, or until ctx is done. Requests larger
+// than the burst size are admitted in burst-sized steps.
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	for n > 0 {
+		step := min(n, l.burst)
+		if d := l.reserve(step); d > 0 {
+			if err := rateSleep(ctx, d); err != nil {
+				return err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+// reserve takes n tokens, possibly going into debt, and returns how long the
+// caller must wait for the debt to be repaid.
+func (l *RateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := rateNow()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(float64(l.burst), l.tokens+elapsed.Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+type rateLimitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *RateLimiter
+}
+
+// NewRateLimitedReader returns a reader that reads from r no faster than l
+// allows. Reads are capped at l's burst size and wait after reading for the
+// bytes they returned. A Read returns ctx's error once ctx is done.
+func NewRateLimitedReader(ctx context.Context, r io.Reader, l *RateLimiter) io.Reader {
+	return &rateLimitedReader{ctx: ctx, r: r, l: l}
+}
+
+func (rr *rateLimitedReader) Read(p []byte) (int, error) {
+	if err := rr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(p) > rr.l.burst {
+		p = p[:rr.l.burst]
+	}
+	n, err := rr.r.Read(p)
+	if werr := rr.l.WaitN(rr.ctx, n); werr != nil && err == nil {
+		err = werr
+	}
+	return n, err
+}
+
+type rateLimitedWriter struct {
+	ctx context.Context
+	w   io.Writer
+	l   *RateLimiter
+}
+
+// NewRateLimitedWriter returns a writer that writes to w no faster than l
+// allows. Large writes are split into burst-sized chunks, each written after
+// waiting for its tokens. A Write returns ctx's error once ctx is done.
+func NewRateLimitedWriter(ctx context.Context, w io.Writer, l *RateLimiter) io.Writer {
+	return &rateLimitedWriter{ctx: ctx, w: w, l: l}
+}
+
+func (rw *rateLimitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), rw.l.burst)]
+		if err := rw.l.WaitN(rw.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := rw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
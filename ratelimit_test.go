@@ -0,0 +1,83 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces the rate limiter's clock and sleep for the duration of a test.
+func fakeClock(t *testing.T) *time.Time {
+	t.Helper()
+	now := time.Unix(1000, 0)
+	oldNow, oldSleep := rateNow, rateSleep
+	rateNow = func() time.Time { return now }
+	rateSleep = func(ctx context.Context, d time.Duration) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		now = now.Add(d)
+		return nil
+	}
+	t.Cleanup(func() { rateNow, rateSleep = oldNow, oldSleep })
+	return &now
+}
+
+func TestRateLimiterWaitN(t *testing.T) {
+	now := fakeClock(t)
+	start := *now
+	l := NewRateLimiter(1000, 100)
+	// The initial burst is free; the remaining 900 bytes take 0.9s.
+	if err := l.WaitN(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	if got := now.Sub(start); got != 900*time.Millisecond {
+		t.Fatalf("waited %v, want 900ms", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.WaitN(ctx, 500); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestRateLimitedEncodeDecode(t *testing.T) {
+	now := fakeClock(t)
+	start := *now
+	l := NewRateLimiter(100, 10)
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithWriteRateLimit(l)); err != nil {
+		t.Fatal(err)
+	}
+	written := buf.Len()
+	if got, want := now.Sub(start), time.Duration(written-10)*10*time.Millisecond; got != want {
+		t.Fatalf("encode of %d bytes took %v, want %v", written, got, want)
+	}
+
+	start = *now
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), WithReadRateLimit(l)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := now.Sub(start), time.Duration(written)*10*time.Millisecond; got != want {
+		t.Fatalf("decode of %d bytes took %v, want %v", written, got, want)
+	}
+}
+
+func TestRateLimitedReaderCapsReads(t *testing.T) {
+	fakeClock(t)
+	r := NewRateLimitedReader(context.Background(), bytes.NewReader(make([]byte, 100)), NewRateLimiter(1000, 16))
+	p := make([]byte, 64)
+	n, err := r.Read(p)
+	if err != nil || n != 16 {
+		t.Fatalf("Read = %d, %v; want 16 bytes", n, err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || len(rest) != 84 {
+		t.Fatalf("ReadAll = %d, %v", len(rest), err)
+	}
+}
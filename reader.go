@@ -0,0 +1,261 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Reader gives random access to an MDOCX container stored in an io.ReaderAt,
+// such as an *os.File or a blob in object storage.
+//
+// NewReader reads the fixed header, metadata, and Markdown bundle, and builds
+// an index of the media bundle, but never reads media data: OpenMedia reads an
+// item's bytes only when asked. This only holds for containers whose Media
+// section is uncompressed (WithMediaCompression(CompNone)); a compressed Media
+// section is decompressed into memory by NewReader. If the container has a
+// footer index (see WithIndex), NewReader takes the media index from it instead
+// of scanning the media bundle. Items compressed on their own (see
+// WithSmartMediaCompression) are decompressed into memory by OpenMedia.
+//
+// A Reader is safe for concurrent use if the underlying io.ReaderAt is.
+type Reader struct {
+	metadata map[string]any
+	markdown MarkdownBundle
+	media    io.ReaderAt
+	items    []mediaEntry
+	byID     map[string]int
+	byPath   map[string]int
+	verify   func(id string) bool
+	limits   Limits
+}
+
+// NewReader parses the container of the given size held in ra. It accepts the
+// same ReadOption values as Decode. SHA-256 hashes are not verified here but
+// by the readers OpenMedia returns, unless disabled with WithVerifyHashes(false).
+func NewReader(ra io.ReaderAt, size int64, opts ...ReadOption) (*Reader, error) {
+	cfg := newReadConfig(opts)
+	sr := io.NewSectionReader(ra, 0, size)
+	h, err := readFixedHeader(sr)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFixedHeader(h, cfg.limits); err != nil {
+		return nil, err
+	}
+	format := payloadFormat(h.HeaderFlags)
+	var footer *footerIndex
+	if h.HeaderFlags&HeaderFlagIndex != 0 {
+		end := size
+		if h.HeaderFlags&HeaderFlagChecksum != 0 {
+			end -= checksumTrailerSize
+		}
+		if footer, err = readIndex(ra, end, format, cfg.limits); err != nil {
+			return nil, err
+		}
+		if footer.MarkdownOffset != uint64(fixedHeaderSizeV1)+uint64(h.MetadataLength) {
+			return nil, fmt.Errorf("%w: footer index Markdown offset mismatch", ErrInvalidSection)
+		}
+	}
+	r := &Reader{verify: cfg.verifies, limits: cfg.limits}
+	if h.MetadataLength > 0 {
+		mb := make([]byte, h.MetadataLength)
+		if _, err := io.ReadFull(sr, mb); err != nil {
+			return nil, err
+		}
+		if r.metadata, err = parseMetadata(h, mb); err != nil {
+			return nil, err
+		}
+	}
+
+	mdSec, mdPayload, err := readSection(sr, SectionMarkdown, cfg.limits, false)
+	if err != nil {
+		return nil, err
+	}
+	if r.markdown, err = decodeMarkdownPayload(context.Background(), mdSec, mdPayload, format, cfg.limits); err != nil {
+		return nil, err
+	}
+
+	mediaSec, err := readSectionHeader(sr)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSectionHeader(mediaSec, SectionMedia); err != nil {
+		return nil, err
+	}
+	if mediaSec.PayloadLen > cfg.limits.MaxMediaSectionLen {
+		return nil, exceeds("MaxMediaSectionLen", "media section too large")
+	}
+	if _, err := checkNoMedia(h, mediaSec); err != nil {
+		return nil, err
+	}
+	off, _ := sr.Seek(0, io.SeekCurrent)
+	if footer != nil && footer.MediaOffset != uint64(off)-16 {
+		return nil, fmt.Errorf("%w: footer index Media offset mismatch", ErrInvalidSection)
+	}
+	if mediaSec.PayloadLen > uint64(size-off) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	mediaLen := int64(mediaSec.PayloadLen)
+	bundleVersion := VersionV1
+	if mediaLen > 0 {
+		if mediaSec.compression() == CompNone && mediaSec.SectionFlags&sectionFlagHasUncompressedLen == 0 {
+			if mediaSec.PayloadLen > cfg.limits.MaxMediaUncompressed {
+				return nil, exceeds("MaxMediaUncompressed", "uncompressed length %d exceeds limit", mediaSec.PayloadLen)
+			}
+			r.media = io.NewSectionReader(ra, off, mediaLen)
+		} else {
+			payload := make([]byte, mediaLen)
+			if _, err := ra.ReadAt(payload, off); err != nil {
+				return nil, err
+			}
+			raw, err := decompressPayload(mediaSec.compression(), mediaSec.SectionFlags, payload, cfg.limits.MaxMediaUncompressed)
+			if err != nil {
+				return nil, err
+			}
+			r.media, mediaLen = bytes.NewReader(raw), int64(len(raw))
+		}
+		var idx *mediaIndex
+		if footer != nil {
+			idx, err = footer.mediaIndex(mediaLen)
+		} else {
+			idx, err = format.scanMedia(r.media, mediaLen, cfg.limits.MaxMediaItems)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if mediaSec.SectionFlags&sectionFlagItemCompression != 0 {
+			if err := idx.unpackEntries(r.media); err != nil {
+				return nil, err
+			}
+		}
+		bundleVersion, r.items = idx.bundleVersion, idx.items
+	}
+
+	doc := &Document{
+		Metadata: r.metadata,
+		Markdown: r.markdown,
+		Media:    MediaBundle{BundleVersion: bundleVersion, Items: make([]MediaItem, len(r.items))},
+	}
+	for i, it := range r.items {
+		doc.Media.Items[i] = MediaItem{ID: it.ID, Path: it.Path}
+	}
+	if err := validateDocument(doc, cfg.limits, false); err != nil {
+		return nil, err
+	}
+	if err := doc.CheckInvariants(cfg.checks); err != nil {
+		return nil, err
+	}
+	r.byID = make(map[string]int, len(r.items))
+	r.byPath = make(map[string]int, len(r.items))
+	for i, it := range r.items {
+		if uint64(it.dataSize()) > cfg.limits.MaxSingleMediaSize {
+			return nil, exceeds("MaxSingleMediaSize", "media item %q too large", it.ID)
+		}
+		r.byID[it.ID] = i
+		if it.Path != "" {
+			r.byPath[it.Path] = i
+		}
+	}
+	return r, nil
+}
+
+// Metadata returns the document metadata, or nil if the container has none.
+func (r *Reader) Metadata() map[string]any {
+	return r.metadata
+}
+
+// Markdown returns the decoded Markdown bundle.
+// The returned bundle shares memory with r and must not be modified.
+func (r *Reader) Markdown() MarkdownBundle {
+	return r.markdown
+}
+
+// ReadMarkdown returns the content of the Markdown file with the given
+// container path, or an error wrapping ErrNotFound. The returned slice shares
+// memory with r and must not be modified.
+func (r *Reader) ReadMarkdown(path string) ([]byte, error) {
+	for _, f := range r.markdown.Files {
+		if f.Path == path {
+			return f.Content, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: markdown file %q", ErrNotFound, path)
+}
+
+// Media lists the media items in bundle order without their data.
+func (r *Reader) Media() []MediaInfo {
+	out := make([]MediaInfo, len(r.items))
+	for i, it := range r.items {
+		out[i] = it.info()
+	}
+	return out
+}
+
+// OpenMedia returns a reader over the data of the media item with the given
+// ID, or an error wrapping ErrNotFound. If the item has a stored SHA256 and
+// verification is enabled, the reader returns an error wrapping ErrValidation
+// instead of io.EOF when the data does not match it.
+func (r *Reader) OpenMedia(id string) (io.ReadCloser, error) {
+	i, ok := r.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: media item %q", ErrNotFound, id)
+	}
+	return r.open(i)
+}
+
+// OpenMediaPath is like OpenMedia but looks the item up by container path.
+func (r *Reader) OpenMediaPath(path string) (io.ReadCloser, error) {
+	i, ok := r.byPath[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: media path %q", ErrNotFound, path)
+	}
+	return r.open(i)
+}
+
+func (r *Reader) open(i int) (io.ReadCloser, error) {
+	it := r.items[i]
+	var data io.Reader = io.NewSectionReader(r.media, it.dataOff, it.dataLen)
+	if it.packed != CompNone {
+		stored := make([]byte, it.dataLen)
+		if _, err := io.ReadFull(data, stored); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		b, err := it.unpack(stored, r.limits)
+		if err != nil {
+			return nil, err
+		}
+		data = bytes.NewReader(b)
+	}
+	if !r.verify(it.ID) || it.SHA256 == ([32]byte{}) {
+		return io.NopCloser(data), nil
+	}
+	return &verifyingReader{r: data, h: sha256.New(), want: it.SHA256, id: it.ID}, nil
+}
+
+// verifyingReader hashes the bytes read through it and checks the hash at EOF.
+type verifyingReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want [32]byte
+	id   string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF {
+		var sum [32]byte
+		v.h.Sum(sum[:0])
+		if subtle.ConstantTimeCompare(sum[:], v.want[:]) != 1 {
+			return n, fmt.Errorf("%w: media item %q SHA256 mismatch", ErrValidation, v.id)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error { return nil }
@@ -0,0 +1,103 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// countingReaderAt records the number of bytes read through it.
+type countingReaderAt struct {
+	r *bytes.Reader
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestReader(t *testing.T) {
+	doc := sampleDoc()
+	big := bytes.Repeat([]byte("video"), 100000)
+	doc.Media.Items = append(doc.Media.Items, MediaItem{ID: "clip", Path: "media/clip.mp4", MIMEType: "video/mp4", Data: big})
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	ra := &countingReaderAt{r: bytes.NewReader(buf.Bytes())}
+	r, err := NewReader(ra, int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ra.n >= int64(len(big)) {
+		t.Fatalf("NewReader read %d bytes, including media data", ra.n)
+	}
+	md, err := r.ReadMarkdown("docs/notes.md")
+	if err != nil || !bytes.Equal(md, doc.Markdown.Files[1].Content) {
+		t.Fatalf("ReadMarkdown = %q, %v", md, err)
+	}
+	if _, err := r.ReadMarkdown("nope.md"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if infos := r.Media(); len(infos) != 2 || infos[1].Size != int64(len(big)) {
+		t.Fatalf("Media() = %+v", infos)
+	}
+
+	rc, err := r.OpenMediaPath("assets/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Fatalf("logo = %v, %v", data, err)
+	}
+	if ra.n >= int64(len(big)) {
+		t.Fatal("reading one item read the others")
+	}
+	if _, err := r.OpenMedia("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// Corrupt the clip's data: opening still works, reading it fails.
+	corrupt := bytes.Clone(buf.Bytes())
+	i := bytes.Index(corrupt, big)
+	corrupt[i+10] ^= 0xff
+	r, err = NewReader(bytes.NewReader(corrupt), int64(len(corrupt)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, _ = r.OpenMedia("clip")
+	if _, err := io.ReadAll(rc); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	r, _ = NewReader(bytes.NewReader(corrupt), int64(len(corrupt)), WithVerifyHashes(false))
+	rc, _ = r.OpenMedia("clip")
+	if _, err := io.ReadAll(rc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaderCompressedMedia(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := r.OpenMedia("logo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(rc); err != nil || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Fatalf("logo = %v, %v", data, err)
+	}
+	if _, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())-1); err == nil {
+		t.Fatal("expected error for truncated container")
+	}
+}
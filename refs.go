@@ -0,0 +1,62 @@
+package mdocx
+
+import (
+	"fmt"
+
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// CheckReferences returns the reference-link and footnote mistakes in doc's
+// Markdown files that only show once the files are rendered: footnote
+// references ([^label]) without a matching definition, and reference
+// definitions ([label]: dest) that no link or image uses. Dest of each result
+// is the bracketed label, such as "[^note]" or "[logo]".
+//
+// As in CommonMark, definitions are scoped to the file that contains them and
+// labels match case-insensitively with white space collapsed. Footnotes are
+// only checked in files whose flavor has them (every flavor but CommonMark).
+func CheckReferences(doc *Document) []BrokenLink {
+	if doc == nil {
+		return nil
+	}
+	var broken []BrokenLink
+	for _, f := range doc.Markdown.Files {
+		res := doc.scanFile(f)
+		used := make(map[string]bool, len(res.Refs))
+		footnotes := make(map[string]bool, len(res.Footnotes))
+		for _, fn := range res.Footnotes {
+			footnotes[mdscan.NormalizeLabel(fn.Label)] = true
+		}
+		for _, ref := range res.Refs {
+			label := mdscan.NormalizeLabel(ref.Label)
+			if !ref.Footnote {
+				used[label] = true
+				continue
+			}
+			if !footnotes[label] {
+				broken = append(broken, BrokenLink{File: f.Path, Line: ref.Line, Column: ref.Column, Dest: "[^" + ref.Label + "]", Reason: "undefined footnote"})
+			}
+		}
+		for _, l := range res.Links {
+			if l.Kind == mdscan.KindDefinition && !used[mdscan.NormalizeLabel(l.Text)] {
+				broken = append(broken, BrokenLink{File: f.Path, Line: l.Line, Column: l.Column, Dest: "[" + l.Text + "]", Reason: "unused reference definition"})
+			}
+		}
+	}
+	return broken
+}
+
+// ValidateReferences checks references like CheckReferences and returns an
+// error wrapping ErrValidation describing the first problem, or nil if there
+// is none.
+func ValidateReferences(doc *Document) error {
+	broken := CheckReferences(doc)
+	switch len(broken) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("%w: reference %s", ErrValidation, broken[0])
+	default:
+		return fmt.Errorf("%w: reference %s (and %d more)", ErrValidation, broken[0], len(broken)-1)
+	}
+}
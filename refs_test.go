@@ -0,0 +1,46 @@
+package mdocx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckReferences(t *testing.T) {
+	doc := &Document{
+		Markdown: MarkdownBundle{
+			BundleVersion: VersionV1,
+			Files: []MarkdownFile{
+				{Path: "a.md", Content: []byte("See [the guide][Guide] and [logo].[^ok] Also[^missing].\n\n" +
+					"[guide]: guide.md\n[logo]: logo.png\n[unused]: nowhere.md\n\n[^ok]: Fine.\n")},
+				// Definitions do not carry over between files.
+				{Path: "b.md", Content: []byte("Uses [guide] and[^ok].\n")},
+				{Path: "c.md", Content: []byte("Plain[^1] text.\n"), Attributes: map[string]string{FlavorAttr: string(FlavorCommonMark)}},
+			},
+		},
+	}
+	broken := CheckReferences(doc)
+	var got []string
+	for _, b := range broken {
+		got = append(got, b.String())
+	}
+	want := []string{
+		"a.md:1:45: [^missing]: undefined footnote",
+		"a.md:5:1: [unused]: unused reference definition",
+		"b.md:1:17: [^ok]: undefined footnote",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CheckReferences = %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("CheckReferences[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+	err := ValidateReferences(doc)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	if err := ValidateReferences(sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+}
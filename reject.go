@@ -0,0 +1,46 @@
+package mdocx
+
+import "errors"
+
+// Rejection describes why Decode rejected a container, in a form suited to
+// counting rejections by cause.
+type Rejection struct {
+	// Kind classifies the error, as in BatchResult.ErrorKind: for example
+	// "invalid_magic", "limit_exceeded", "truncated", or "validation".
+	Kind string
+	// Section is the part of the container being read when the error
+	// occurred: "header", "metadata", "markdown", "media", "checksum", or
+	// "document" for validation of the decoded document.
+	Section string
+	// Limit is the name of the Limits field that was exceeded, such as
+	// "MaxMediaSectionLen", if Kind is "limit_exceeded".
+	Limit string
+	// Err is the error Decode returned.
+	Err error
+}
+
+// WithRejectHook sets a function that Decode and DecodeContext call with a
+// description of the failure each time they return an error, so that
+// operators of upload endpoints can tell attacks from legitimate oversized
+// documents and tune Limits. fn is called synchronously before Decode returns
+// and must be safe for concurrent use if the option is shared.
+func WithRejectHook(fn func(Rejection)) ReadOption {
+	return func(c *readConfig) { c.onReject = fn }
+}
+
+// newRejection describes err, which occurred while reading section.
+func newRejection(err error, section string) Rejection {
+	r := Rejection{Kind: errorKind(err), Section: section, Err: err}
+	var le *LimitError
+	switch {
+	case errors.As(err, &le):
+		r.Limit = le.Limit
+	case r.Kind == "limit_exceeded" && section == "markdown":
+		// The decompressed-size check is shared by both sections and does
+		// not name its limit.
+		r.Limit = "MaxMarkdownUncompressed"
+	case r.Kind == "limit_exceeded" && section == "media":
+		r.Limit = "MaxMediaUncompressed"
+	}
+	return r
+}
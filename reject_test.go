@@ -0,0 +1,65 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRejectHook(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithMediaCompression(CompZSTD)); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+		l    Limits
+		want Rejection
+	}{
+		{name: "magic", data: []byte("not a container at all, really!!"), want: Rejection{Kind: "invalid_magic", Section: "header"}},
+		{name: "media section", data: valid, l: Limits{MaxMediaSectionLen: 1}, want: Rejection{Kind: "limit_exceeded", Section: "media", Limit: "MaxMediaSectionLen"}},
+		{name: "media uncompressed", data: valid, l: Limits{MaxMediaUncompressed: 1}, want: Rejection{Kind: "limit_exceeded", Section: "media", Limit: "MaxMediaUncompressed"}},
+		{name: "file size", data: valid, l: Limits{MaxSingleMarkdownFileSize: 1}, want: Rejection{Kind: "limit_exceeded", Section: "document", Limit: "MaxSingleMarkdownFileSize"}},
+		{name: "truncated", data: valid[:len(valid)-3], want: Rejection{Kind: "truncated", Section: "media"}},
+	}
+	for _, tt := range tests {
+		var got []Rejection
+		_, err := Decode(bytes.NewReader(tt.data), WithReadLimits(tt.l), WithRejectHook(func(r Rejection) { got = append(got, r) }))
+		if err == nil {
+			t.Fatalf("%s: expected error", tt.name)
+		}
+		if len(got) != 1 {
+			t.Fatalf("%s: hook called %d times", tt.name, len(got))
+		}
+		if got[0].Err != err {
+			t.Fatalf("%s: Err = %v, want %v", tt.name, got[0].Err, err)
+		}
+		got[0].Err = nil
+		if got[0] != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.name, got[0], tt.want)
+		}
+	}
+
+	called := false
+	if _, err := Decode(bytes.NewReader(valid), WithRejectHook(func(Rejection) { called = true })); err != nil || called {
+		t.Fatalf("valid container: err %v, hook called %v", err, called)
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Decode(bytes.NewReader(buf.Bytes()), WithReadLimits(Limits{MaxMarkdownSectionLen: 1}))
+	var le *LimitError
+	if !errors.As(err, &le) || le.Limit != "MaxMarkdownSectionLen" || !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got %v", err)
+	}
+	if err.Error() != "mdocx: limit exceeded: markdown section too large" {
+		t.Fatalf("message = %q", err.Error())
+	}
+}
@@ -0,0 +1,55 @@
+package mdocx
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// hashSampling selects the media items whose hashes are verified.
+type hashSampling struct {
+	fraction float64
+	seed     uint64
+}
+
+// selects reports whether the item with the given ID falls in the window of
+// the ID space assigned to the seed. Windows of consecutive seeds are adjacent,
+// so they cover the whole space every ceil(1/fraction) seeds.
+func (s *hashSampling) selects(id string) bool {
+	if s.fraction >= 1 {
+		return true
+	}
+	if !(s.fraction > 0) {
+		return false
+	}
+	// Fractions just below 1 round up to the whole space, which does not fit
+	// in the 32-bit window.
+	w := uint64(math.Ceil(s.fraction * (1 << 32)))
+	if w >= 1<<32 {
+		return true
+	}
+	width := uint32(w)
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	// FNV's high bits vary little between similar IDs; mix them in first.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	pos := uint32(x >> 32)
+	start := uint32(s.seed) * width
+	return pos-start < width
+}
+
+// verifies reports whether the hash of the media item with the given ID should
+// be verified.
+func (c readConfig) verifies(id string) bool {
+	return c.verifyHashes && (c.sampling == nil || c.sampling.selects(id))
+}
+
+// verifier returns c.verifies, or nil if no hash is verified.
+func (c readConfig) verifier() func(id string) bool {
+	if !c.verifyHashes {
+		return nil
+	}
+	return c.verifies
+}
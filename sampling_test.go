@@ -0,0 +1,95 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestHashSamplingCoverage(t *testing.T) {
+	const n = 1000
+	s := hashSampling{fraction: 0.1}
+	covered := make(map[string]bool)
+	for seed := uint64(0); seed < 10; seed++ {
+		s.seed = seed
+		picked := 0
+		for i := 0; i < n; i++ {
+			id := fmt.Sprintf("img-%05d", i)
+			if s.selects(id) != s.selects(id) {
+				t.Fatalf("selection of %s is not deterministic", id)
+			}
+			if s.selects(id) {
+				picked++
+				covered[id] = true
+			}
+		}
+		if picked < n/20 || picked > n/5 {
+			t.Fatalf("seed %d selected %d of %d items", seed, picked, n)
+		}
+	}
+	if len(covered) != n {
+		t.Fatalf("10 consecutive seeds covered %d of %d items", len(covered), n)
+	}
+
+	if (&hashSampling{fraction: 0}).selects("a") {
+		t.Fatal("fraction 0 selected an item")
+	}
+	if !(&hashSampling{fraction: 1, seed: 7}).selects("a") {
+		t.Fatal("fraction 1 skipped an item")
+	}
+	near := hashSampling{fraction: 1 - 1e-12, seed: 3}
+	for i := 0; i < n; i++ {
+		if id := fmt.Sprintf("img-%05d", i); !near.selects(id) {
+			t.Fatalf("fraction just below 1 skipped %s", id)
+		}
+	}
+}
+
+func TestDecodeHashSampling(t *testing.T) {
+	doc := sampleDoc()
+	doc.Media.Items[0].SHA256 = [32]byte{1}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithVerifyHashesOnWrite(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := hashSampling{fraction: 0.25}
+	hits := 0
+	for seed := uint64(0); seed < 4; seed++ {
+		s.seed = seed
+		_, err := Decode(bytes.NewReader(buf.Bytes()), WithHashSampling(s.fraction, seed))
+		if s.selects("logo") {
+			hits++
+			if !errors.Is(err, ErrValidation) {
+				t.Fatalf("seed %d: expected ErrValidation, got %v", seed, err)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("seed %d: unsampled item verified: %v", seed, err)
+			}
+		}
+
+		r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()), WithHashSampling(s.fraction, seed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, err := r.OpenMedia("logo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.ReadAll(rc)
+		rc.Close()
+		if errors.Is(err, ErrValidation) != s.selects("logo") {
+			t.Fatalf("seed %d: Reader verification = %v", seed, err)
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("four seeds sampled the item %d times, want 1", hits)
+	}
+
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), WithHashSampling(1, 0), WithVerifyHashes(false)); err != nil {
+		t.Fatalf("sampling verified hashes with verification off: %v", err)
+	}
+}
@@ -0,0 +1,659 @@
+package mdocx
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/logicossoftware/go-mdocx/internal/mdscan"
+)
+
+// ScrubPolicy configures Scrub.
+type ScrubPolicy struct {
+	// AllowMIMETypes lists the MIME types of the media items to keep, as
+	// path.Match patterns such as "image/png" or "audio/*", matched against
+	// the lowercased type without parameters. Items of other types are
+	// removed; an item without a MIMEType has type "application/octet-stream".
+	// If nil, items of every type are kept.
+	AllowMIMETypes []string
+	// KeepImageMetadata keeps EXIF and XMP metadata in JPEG, PNG, and WebP
+	// images. By default it is removed.
+	KeepImageMetadata bool
+	// Tool identifies the scrubber in the audit event Scrub records.
+	// Defaults to "mdocx-scrub".
+	Tool string
+	// Limits bounds the container Scrub reads and writes. Zero values are
+	// replaced with safe defaults.
+	Limits Limits
+	// Quarantine makes Scrub reject a container that has anything to scrub
+	// instead of writing a scrubbed copy: it writes nothing and returns the
+	// report with a *QuarantineError, so that the original can be held for
+	// review.
+	Quarantine bool
+}
+
+// DefaultScrubPolicy returns a policy for mail and file gateways: it keeps
+// raster images, audio, video, and plain text, and removes image metadata.
+// SVG images are removed because they can carry scripts.
+func DefaultScrubPolicy() ScrubPolicy {
+	return ScrubPolicy{
+		AllowMIMETypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif", "audio/*", "video/*", "text/plain", "text/csv"},
+	}
+}
+
+// ScrubReport describes what Scrub changed. It marshals to JSON for security
+// tooling that logs and alerts on what was found.
+type ScrubReport struct {
+	// Fingerprint is the Fingerprint of the document before it was scrubbed,
+	// which identifies the original of a quarantined container.
+	Fingerprint string `json:"fingerprint"`
+	// Files lists the paths of the Markdown files whose content was changed.
+	Files []string `json:"files,omitempty"`
+	// Removed counts the active HTML elements, event handler attributes, and
+	// script URLs removed from Markdown content.
+	Removed int `json:"removed"`
+	// DroppedMedia lists the IDs of the media items removed by
+	// AllowMIMETypes, in bundle order.
+	DroppedMedia []string `json:"dropped_media,omitempty"`
+	// StrippedMedia lists the IDs of the images whose metadata was removed,
+	// in bundle order.
+	StrippedMedia []string `json:"stripped_media,omitempty"`
+	// Findings lists everything that was removed, and why: the Markdown
+	// findings of each file in the order of the files, then the media
+	// findings in bundle order.
+	Findings []ScrubFinding `json:"findings,omitempty"`
+}
+
+// Changed reports whether Scrub changed anything.
+func (r *ScrubReport) Changed() bool {
+	return len(r.Files) > 0 || len(r.DroppedMedia) > 0 || len(r.StrippedMedia) > 0
+}
+
+// Kinds of ScrubFinding.
+const (
+	// FindingActiveElement is a script, iframe, or other active HTML element
+	// removed with its content.
+	FindingActiveElement = "active_element"
+	// FindingEventHandler is an on* event handler attribute removed from an
+	// HTML tag.
+	FindingEventHandler = "event_handler"
+	// FindingScriptURL is a javascript:, vbscript:, or non-image data: URL
+	// removed from an HTML attribute or autolink, or replaced by "#" in a
+	// link destination.
+	FindingScriptURL = "script_url"
+	// FindingMediaType is a media item removed because its MIME type is not
+	// allowed.
+	FindingMediaType = "media_type"
+	// FindingImageMetadata is EXIF or XMP metadata removed from an image.
+	FindingImageMetadata = "image_metadata"
+)
+
+// ScrubFinding describes one thing Scrub removed.
+type ScrubFinding struct {
+	// Kind is one of the Finding constants, such as FindingActiveElement.
+	Kind string `json:"kind"`
+	// Path is the path of the Markdown file, or of the media item if it has
+	// one.
+	Path string `json:"path,omitempty"`
+	// ID is the ID of the media item, for media findings.
+	ID string `json:"id,omitempty"`
+	// Reason describes what was found, such as "script element" or
+	// "script URL in href attribute".
+	Reason string `json:"reason"`
+	// Excerpt is the removed Markdown text, for Markdown findings. It is at
+	// most scrubExcerptLen bytes, with invalid UTF-8 and control characters
+	// replaced, and ends with "..." where it was truncated.
+	Excerpt string `json:"excerpt,omitempty"`
+}
+
+// scrubExcerptLen is the maximum length of ScrubFinding.Excerpt, excluding
+// the truncation mark.
+const scrubExcerptLen = 200
+
+// QuarantineError is returned by Scrub when the policy has Quarantine set and
+// the container has something to scrub. It matches ErrValidation with
+// errors.Is.
+type QuarantineError struct {
+	// Report describes what Scrub would have removed.
+	Report *ScrubReport
+}
+
+func (e *QuarantineError) Error() string {
+	return fmt.Sprintf("mdocx: container quarantined: %d findings", len(e.Report.Findings))
+}
+
+// Unwrap returns ErrValidation.
+func (e *QuarantineError) Unwrap() error { return ErrValidation }
+
+// Scrub reads the container from in, removes active content according to
+// policy, and writes the result to out, so that mail and file gateways can
+// pass containers through a policy filter. See Document.Scrub for what is
+// removed. The output is written with opts, which may set compression or
+// WithChecksum, and is written even if nothing changed. If the container has
+// an audit log, an event recording the scrub is appended, so that
+// VerifyAuditLog keeps holding. With policy.Quarantine, a container that has
+// anything to scrub is not written; Scrub returns the report and a
+// *QuarantineError instead.
+//
+// The container is decoded with policy.Limits and hash verification, so an
+// invalid or oversized container fails before anything is written.
+func Scrub(in io.Reader, out io.Writer, policy ScrubPolicy, opts ...WriteOption) (*ScrubReport, error) {
+	doc, err := Decode(in, WithReadLimits(policy.Limits), WithStreamingInput(true))
+	if err != nil {
+		return nil, err
+	}
+	rep := doc.Scrub(policy)
+	if rep.Changed() && policy.Quarantine {
+		return rep, &QuarantineError{Report: rep}
+	}
+	if rep.Changed() {
+		events, err := doc.AuditLog()
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			tool := policy.Tool
+			if tool == "" {
+				tool = "mdocx-scrub"
+			}
+			op := fmt.Sprintf("scrub: %d removed from %d files, %d media dropped, %d stripped", rep.Removed, len(rep.Files), len(rep.DroppedMedia), len(rep.StrippedMedia))
+			if err := doc.AppendAuditEvent(AuditEvent{Tool: tool, Operation: op, Before: rep.Fingerprint}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := Encode(out, doc, append([]WriteOption{WithWriteLimits(policy.Limits)}, opts...)...); err != nil {
+		return nil, err
+	}
+	return rep, nil
+}
+
+// Scrub removes active content from d in place according to policy:
+//
+//   - script, iframe, frame, frameset, object, embed, and applet elements,
+//     with their content, are removed from Markdown content;
+//   - on* event handler attributes are removed from the remaining HTML tags,
+//     and href, src, and similar attributes holding javascript:, vbscript:,
+//     or non-image data: URLs are removed;
+//   - such URLs are replaced by "#" in Markdown links and reference
+//     definitions, and autolinks to them are removed;
+//   - media items whose MIME type is not allowed are removed, along with their
+//     IDs in MediaRefs (links to them in content are left alone);
+//   - EXIF and XMP metadata is removed from JPEG, PNG, and WebP images,
+//     unless policy.KeepImageMetadata is set, and their SHA256 is recomputed.
+//
+// Fenced code blocks are left alone, since their content is shown rather than
+// interpreted; inline code spans are scrubbed like other text. Scrub does not
+// parse HTML fully: it is a filter for common active content, not a
+// guarantee that a renderer that allows raw HTML is safe.
+//
+// The report lists each removal as a ScrubFinding.
+func (d *Document) Scrub(policy ScrubPolicy) *ScrubReport {
+	rep := &ScrubReport{Fingerprint: d.Fingerprint()}
+	for i := range d.Markdown.Files {
+		f := &d.Markdown.Files[i]
+		content, findings := scrubMarkdown(f.Content)
+		if len(findings) > 0 {
+			f.Content = content
+			rep.Files = append(rep.Files, f.Path)
+			rep.Removed += len(findings)
+			for _, fd := range findings {
+				fd.Path = f.Path
+				rep.Findings = append(rep.Findings, fd)
+			}
+		}
+	}
+	if policy.AllowMIMETypes != nil {
+		var drop []string
+		for _, it := range d.Media.Items {
+			if !mimeAllowed(policy.AllowMIMETypes, it.MIMEType) {
+				drop = append(drop, it.ID)
+				rep.Findings = append(rep.Findings, ScrubFinding{Kind: FindingMediaType, Path: it.Path, ID: it.ID, Reason: fmt.Sprintf("MIME type %q is not allowed", it.MIMEType)})
+			}
+		}
+		for _, id := range drop {
+			d.RemoveMediaItem(id)
+		}
+		rep.DroppedMedia = drop
+	}
+	if !policy.KeepImageMetadata {
+		for i := range d.Media.Items {
+			it := &d.Media.Items[i]
+			if data, ok := stripImageMetadata(it.Data); ok {
+				it.Data = data
+				it.SHA256 = sha256.Sum256(data)
+				rep.StrippedMedia = append(rep.StrippedMedia, it.ID)
+				rep.Findings = append(rep.Findings, ScrubFinding{Kind: FindingImageMetadata, Path: it.Path, ID: it.ID, Reason: "EXIF or XMP metadata"})
+			}
+		}
+	}
+	return rep
+}
+
+// mimeAllowed reports whether the MIME type mt matches one of patterns.
+func mimeAllowed(patterns []string, mt string) bool {
+	mt, _, _ = strings.Cut(mt, ";")
+	mt = strings.ToLower(strings.TrimSpace(mt))
+	if mt == "" {
+		mt = "application/octet-stream"
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), mt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// activeTagRE matches an opening or closing tag of an active element.
+	activeTagRE = regexp.MustCompile(`(?i)<(/?)(script|iframe|frameset|frame|object|embed|applet)\b[^>]*>`)
+	// activeCloseRE matches the closing tag of each active element that has one.
+	activeCloseRE = map[string]*regexp.Regexp{
+		"script":   regexp.MustCompile(`(?i)</script\s*>`),
+		"iframe":   regexp.MustCompile(`(?i)</iframe\s*>`),
+		"frameset": regexp.MustCompile(`(?i)</frameset\s*>`),
+		"object":   regexp.MustCompile(`(?i)</object\s*>`),
+		"applet":   regexp.MustCompile(`(?i)</applet\s*>`),
+	}
+	// Browsers accept a slash or a closing quote as well as whitespace
+	// before an attribute, as in <svg/onload=x> and <img src="a"onerror=x>.
+	// The attribute regexps capture that separator so that a closing quote
+	// can be kept.
+	htmlTagRE   = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9:-]*(?:[\s/][^<>]*)?>`)
+	eventAttrRE = regexp.MustCompile(`(?i)([\s/"'])[\s/]*(on[a-z]+)\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+)`)
+	urlAttrRE   = regexp.MustCompile(`(?i)([\s/"'])[\s/]*(href|src|action|formaction|xlink:href|background|poster|data)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	autolinkRE  = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*>`)
+	refDefRE    = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:[ \t]*(<[^>\n]*>|\S+)`)
+)
+
+// scrubFindings collects the findings in one Markdown file.
+type scrubFindings []ScrubFinding
+
+// add records the removal of excerpt.
+func (fs *scrubFindings) add(kind, reason, excerpt string) {
+	*fs = append(*fs, ScrubFinding{Kind: kind, Reason: reason, Excerpt: scrubExcerpt(excerpt)})
+}
+
+// scrubExcerpt returns s made safe to log, truncated to scrubExcerptLen bytes.
+func scrubExcerpt(s string) string {
+	truncated := len(s) > scrubExcerptLen
+	if truncated {
+		s = s[:scrubExcerptLen]
+		for len(s) > 0 && !utf8.RuneStart(s[len(s)-1]) {
+			s = s[:len(s)-1]
+		}
+		if len(s) > 0 {
+			s = s[:len(s)-1]
+		}
+	}
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, strings.ToValidUTF8(s, "\uFFFD"))
+	if truncated {
+		s += "..."
+	}
+	return s
+}
+
+// scrubMarkdown returns src with active content removed outside fenced code
+// blocks, and the constructs removed, without their Path.
+func scrubMarkdown(src []byte) ([]byte, []ScrubFinding) {
+	var b bytes.Buffer
+	var findings scrubFindings
+	last := 0
+	scrub := func(s string) {
+		b.WriteString(scrubText(s, &findings))
+	}
+	for _, fence := range mdscan.Fences(src) {
+		scrub(string(src[last:fence.Start]))
+		b.Write(src[fence.Start:fence.End])
+		last = fence.End
+	}
+	scrub(string(src[last:]))
+	if len(findings) == 0 {
+		return src, nil
+	}
+	return b.Bytes(), findings
+}
+
+// scrubText scrubs Markdown text that contains no fenced code, adding what it
+// removes to fs.
+func scrubText(s string, fs *scrubFindings) string {
+	s = removeActiveElements(s, fs)
+	s = htmlTagRE.ReplaceAllStringFunc(s, func(tag string) string {
+		tag = removeAttrs(tag, eventAttrRE, func(m []string) bool {
+			fs.add(FindingEventHandler, strings.ToLower(m[2])+" attribute", strings.TrimLeft(m[0], " \t\n\r\f/\"'"))
+			return true
+		})
+		return removeAttrs(tag, urlAttrRE, func(m []string) bool {
+			if !scriptURL(m[3]) {
+				return false
+			}
+			fs.add(FindingScriptURL, "script URL in "+strings.ToLower(m[2])+" attribute", strings.TrimLeft(m[0], " \t\n\r\f/\"'"))
+			return true
+		})
+	})
+	s = autolinkRE.ReplaceAllStringFunc(s, func(link string) string {
+		if !scriptURL(link[1 : len(link)-1]) {
+			return link
+		}
+		fs.add(FindingScriptURL, "script URL in autolink", link)
+		return ""
+	})
+	return replaceScriptDests(s, fs)
+}
+
+// removeAttrs removes from tag the attributes matched by re for which remove
+// returns true, given the submatches. A quote before an attribute closes the
+// previous value and is kept; a quote that ends a value may in turn be the
+// separator of the next attribute, so matching resumes on it.
+func removeAttrs(tag string, re *regexp.Regexp, remove func(m []string) bool) string {
+	for pos := 0; pos < len(tag); {
+		loc := re.FindStringSubmatchIndex(tag[pos:])
+		if loc == nil {
+			break
+		}
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			m[i] = tag[pos+loc[2*i] : pos+loc[2*i+1]]
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if !remove(m) {
+			pos = end
+			if c := tag[end-1]; c == '"' || c == '\'' {
+				pos--
+			}
+			continue
+		}
+		keep := ""
+		if sep := m[1]; sep == `"` || sep == "'" {
+			keep = sep
+		}
+		tag, pos = tag[:start]+keep+tag[end:], start
+	}
+	return tag
+}
+
+// removeActiveElements removes active elements and their content from s,
+// adding them to fs. An element that is not closed extends to the end of s,
+// as it would in a browser.
+func removeActiveElements(s string, fs *scrubFindings) string {
+	var b strings.Builder
+	n := 0
+	for {
+		loc := activeTagRE.FindStringSubmatchIndex(s)
+		if loc == nil {
+			break
+		}
+		n++
+		b.WriteString(s[:loc[0]])
+		closing := loc[3] > loc[2]
+		name := strings.ToLower(s[loc[4]:loc[5]])
+		closeRE := activeCloseRE[name]
+		rest := s[loc[1]:]
+		if !closing && closeRE != nil {
+			if end := closeRE.FindStringIndex(rest); end != nil {
+				rest = rest[end[1]:]
+			} else {
+				rest = ""
+			}
+		}
+		reason := name + " element"
+		if closing {
+			reason = "stray " + name + " closing tag"
+		}
+		fs.add(FindingActiveElement, reason, s[loc[0]:len(s)-len(rest)])
+		s = rest
+	}
+	if n == 0 {
+		return s
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// replaceScriptDests replaces script URLs in the destinations of inline links
+// and reference definitions with "#", adding them to fs.
+func replaceScriptDests(s string, fs *scrubFindings) string {
+	type span struct {
+		start, end int
+		reason     string
+	}
+	var spans []span
+	for i := 0; ; {
+		j := strings.Index(s[i:], "](")
+		if j < 0 {
+			break
+		}
+		start := i + j + 2
+		for start < len(s) && (s[start] == ' ' || s[start] == '\t' || s[start] == '\n') {
+			start++
+		}
+		end := linkDestEnd(s, start)
+		dest := s[start:end]
+		if strings.HasPrefix(dest, "<") {
+			dest = strings.Trim(dest, "<>")
+		}
+		if scriptURL(dest) {
+			spans = append(spans, span{start, end, "script URL in link destination"})
+		}
+		i = max(end, i+j+2)
+	}
+	for _, m := range refDefRE.FindAllStringSubmatchIndex(s, -1) {
+		if scriptURL(strings.Trim(s[m[2]:m[3]], "<>")) {
+			spans = append(spans, span{m[2], m[3], "script URL in reference definition"})
+		}
+	}
+	if len(spans) == 0 {
+		return s
+	}
+	slices.SortFunc(spans, func(a, b span) int { return cmp.Compare(a.start, b.start) })
+	var out strings.Builder
+	last := 0
+	for _, sp := range spans {
+		if sp.start < last {
+			continue // a definition that also contains "]("
+		}
+		out.WriteString(s[last:sp.start])
+		out.WriteByte('#')
+		fs.add(FindingScriptURL, sp.reason, s[sp.start:sp.end])
+		last = sp.end
+	}
+	out.WriteString(s[last:])
+	return out.String()
+}
+
+// linkDestEnd returns the end of the inline link destination starting at
+// start: after the closing > of a bracketed destination, or before the first
+// space or unbalanced closing parenthesis.
+func linkDestEnd(s string, start int) int {
+	if start < len(s) && s[start] == '<' {
+		if k := strings.IndexAny(s[start:], ">\n"); k >= 0 && s[start+k] == '>' {
+			return start + k + 1
+		}
+		return start
+	}
+	depth := 0
+	k := start
+	for k < len(s) {
+		c := s[k]
+		switch {
+		case c == '\\' && k+1 < len(s):
+			k += 2
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth == 0 {
+				return k
+			}
+			depth--
+		case c <= ' ':
+			return k
+		}
+		k++
+	}
+	return k
+}
+
+// scriptURL reports whether u, as written in Markdown or an HTML attribute,
+// is a URL that runs script when followed: javascript:, vbscript:, or a data:
+// URL other than a raster image. Character references are decoded and
+// whitespace and control characters, which browsers ignore in the scheme,
+// are removed first.
+func scriptURL(u string) bool {
+	u = html.UnescapeString(strings.Trim(u, `"'`))
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, u)
+	u = strings.ToLower(u)
+	switch {
+	case strings.HasPrefix(u, "javascript:"), strings.HasPrefix(u, "vbscript:"):
+		return true
+	case strings.HasPrefix(u, "data:"):
+		for _, safe := range []string{"data:image/png", "data:image/jpeg", "data:image/gif", "data:image/webp", "data:image/avif"} {
+			if strings.HasPrefix(u, safe) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// stripImageMetadata returns data with EXIF and XMP metadata removed if it is
+// a JPEG, PNG, or WebP image that has some. It reports false, and data is
+// unchanged, for other data, images without metadata, and malformed images.
+func stripImageMetadata(data []byte) ([]byte, bool) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return stripJPEGMetadata(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNGMetadata(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebPMetadata(data)
+	}
+	return data, false
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripJPEGMetadata removes APP1 segments holding EXIF or XMP data.
+func stripJPEGMetadata(data []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	stripped := false
+	for i := 2; ; {
+		if i+2 > len(data) || data[i] != 0xFF {
+			return data, false
+		}
+		m := data[i+1]
+		switch {
+		case m == 0xFF: // fill byte
+			out = append(out, 0xFF)
+			i++
+			continue
+		case m == 0xD9 || m == 0xDA: // EOI, or SOS followed by entropy-coded data
+			out = append(out, data[i:]...)
+			return out, stripped
+		case m == 0x01 || (m >= 0xD0 && m <= 0xD7): // markers without a length
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+		if i+4 > len(data) {
+			return data, false
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end < i+4 || end > len(data) {
+			return data, false
+		}
+		seg := data[i+4 : end]
+		if m == 0xE1 && (bytes.HasPrefix(seg, []byte("Exif\x00")) || bytes.HasPrefix(seg, []byte("http://ns.adobe.com/xap/1.0/\x00"))) {
+			stripped = true
+		} else {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+}
+
+// stripPNGMetadata removes eXIf chunks and iTXt chunks holding XMP.
+func stripPNGMetadata(data []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	stripped := false
+	for i := len(pngSignature); i < len(data); {
+		if i+12 > len(data) {
+			return data, false
+		}
+		n := binary.BigEndian.Uint32(data[i:])
+		if uint64(n) > uint64(len(data)-i-12) {
+			return data, false
+		}
+		end := i + 12 + int(n)
+		typ := string(data[i+4 : i+8])
+		if typ == "eXIf" || (typ == "iTXt" && bytes.HasPrefix(data[i+8:end-4], []byte("XML:com.adobe.xmp\x00"))) {
+			stripped = true
+		} else {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, stripped
+}
+
+// stripWebPMetadata removes EXIF and XMP chunks and clears their flags in the
+// VP8X chunk.
+func stripWebPMetadata(data []byte) ([]byte, bool) {
+	const exifFlag, xmpFlag = 0x08, 0x04
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	stripped := false
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return data, false
+		}
+		n := uint64(binary.LittleEndian.Uint32(data[i+4:]))
+		padded := n + n&1
+		if padded > uint64(len(data)-i-8) {
+			return data, false
+		}
+		end := i + 8 + int(padded)
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+			stripped = true
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if n > 0 {
+				out[start+8] &^= exifFlag | xmpFlag
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	if !stripped {
+		return data, false
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, true
+}
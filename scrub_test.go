@@ -0,0 +1,249 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestScrubMarkdown(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"a<script>alert(1)</script>b", "ab"},
+		{"a<SCRIPT type=x>\nalert(1)\n</Script >b", "ab"},
+		{"a<iframe src=x></iframe>b<embed src=y>c", "abc"},
+		{"a<script>never closed", "a"},
+		{"stray </script> tag", "stray  tag"},
+		{`<img src="a.png" onerror="alert(1)" alt=x>`, `<img src="a.png" alt=x>`},
+		{`<img/src=x/onerror=alert(1)>`, `<img/src=x>`},
+		{`<svg/onload=alert(1)>`, `<svg>`},
+		{`<img src="x"onerror="alert(1)">`, `<img src="x">`},
+		{`<img src='x'onerror='a()'onload='b()'alt='y'>`, `<img src='x'alt='y'>`},
+		{`<img alt="onerror=x">`, `<img alt="">`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a title="t"href="https://example.com"src="javascript:x">y</a>`, `<a title="t"href="https://example.com">y</a>`},
+		{`<a/href=javascript:alert(1)>x</a>`, `<a>x</a>`},
+		{`<a href='&#106;avascript:alert(1)'>x</a>`, `<a>x</a>`},
+		{`<a href="https://example.com">x</a>`, `<a href="https://example.com">x</a>`},
+		{"[x](javascript:alert(1)) after", "[x](#) after"},
+		{"[x](<java\tscript:alert(1)>)", "[x](#)"},
+		{"[x](https://example.com/a_(b))", "[x](https://example.com/a_(b))"},
+		{"![i](data:image/png;base64,AAAA)", "![i](data:image/png;base64,AAAA)"},
+		{"[x](data:text/html;base64,AAAA)", "[x](#)"},
+		{"[ref]: vbscript:msgbox\n", "[ref]: #\n"},
+		{"see <javascript:alert(1)> and <https://example.com>", "see  and <https://example.com>"},
+		{"```html\n<script>shown</script>\n```\n<script>x</script>", "```html\n<script>shown</script>\n```\n"},
+	}
+	for _, c := range cases {
+		got, findings := scrubMarkdown([]byte(c.in))
+		if string(got) != c.want {
+			t.Errorf("scrubMarkdown(%q) = %q, want %q", c.in, got, c.want)
+		}
+		if (len(findings) > 0) != (c.in != c.want) {
+			t.Errorf("scrubMarkdown(%q) found %+v", c.in, findings)
+		}
+	}
+}
+
+// jpegWithEXIF returns a minimal JPEG stream with an EXIF APP1 segment.
+func jpegWithEXIF() (withEXIF, without []byte) {
+	seg := func(marker byte, payload string) []byte {
+		b := []byte{0xFF, marker, 0, 0}
+		binary.BigEndian.PutUint16(b[2:], uint16(len(payload)+2))
+		return append(b, payload...)
+	}
+	soi := []byte{0xFF, 0xD8}
+	app0 := seg(0xE0, "JFIF\x00\x01\x01")
+	app1 := seg(0xE1, "Exif\x00\x00GPS")
+	sos := append(seg(0xDA, "\x01\x02"), 0x12, 0x34, 0xFF, 0xD9)
+	withEXIF = slices.Concat(soi, app0, app1, sos)
+	without = slices.Concat(soi, app0, sos)
+	return withEXIF, without
+}
+
+func pngChunk(typ, data string) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	b = append(b, typ...)
+	b = append(b, data...)
+	return append(b, 0, 0, 0, 0) // CRC is not checked
+}
+
+func webpChunk(typ, data string) []byte {
+	b := append([]byte(typ), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func riff(chunks ...[]byte) []byte {
+	body := slices.Concat(chunks...)
+	b := []byte("RIFF\x00\x00\x00\x00WEBP")
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)+4))
+	return append(b, body...)
+}
+
+func TestStripImageMetadata(t *testing.T) {
+	jpg, jpgClean := jpegWithEXIF()
+	png := slices.Concat(pngSignature, pngChunk("IHDR", "hdr"), pngChunk("eXIf", "MM"), pngChunk("iTXt", "XML:com.adobe.xmp\x00x"), pngChunk("IEND", ""))
+	pngClean := slices.Concat(pngSignature, pngChunk("IHDR", "hdr"), pngChunk("IEND", ""))
+	webp := riff(webpChunk("VP8X", "\x0c\x00\x00\x00"), webpChunk("VP8 ", "img"), webpChunk("EXIF", "MM"), webpChunk("XMP ", "<x/>"))
+	webpClean := riff(webpChunk("VP8X", "\x00\x00\x00\x00"), webpChunk("VP8 ", "img"))
+
+	for _, c := range []struct {
+		name     string
+		in, want []byte
+		stripped bool
+	}{
+		{"jpeg", jpg, jpgClean, true},
+		{"jpeg without exif", jpgClean, jpgClean, false},
+		{"png", png, pngClean, true},
+		{"webp", webp, webpClean, true},
+		{"truncated jpeg", jpg[:10], jpg[:10], false},
+		{"other", []byte("GIF89a"), []byte("GIF89a"), false},
+	} {
+		got, ok := stripImageMetadata(c.in)
+		if ok != c.stripped || !bytes.Equal(got, c.want) {
+			t.Errorf("%s: got %q, %v; want %q, %v", c.name, got, ok, c.want, c.stripped)
+		}
+	}
+}
+
+func TestScrub(t *testing.T) {
+	jpg, jpgClean := jpegWithEXIF()
+	doc := &Document{
+		Metadata: map[string]any{"title": "Mail"},
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, Files: []MarkdownFile{
+			{Path: "index.md", Content: []byte("# Hi\n<script>steal()</script>\n![p](photo.jpg)\n"), MediaRefs: []string{"photo", "icon"}},
+			{Path: "clean.md", Content: []byte("plain\n")},
+		}},
+		Media: MediaBundle{BundleVersion: VersionV1, Items: []MediaItem{
+			{ID: "photo", Path: "photo.jpg", MIMEType: "image/jpeg", Data: jpg},
+			{ID: "icon", Path: "icon.svg", MIMEType: "image/svg+xml", Data: []byte("<svg onload=x/>")},
+		}},
+	}
+	if err := doc.AppendAuditEvent(AuditEvent{Tool: "test", Operation: "create"}); err != nil {
+		t.Fatal(err)
+	}
+	var in, out bytes.Buffer
+	if err := Encode(&in, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	rep, err := Scrub(&in, &out, DefaultScrubPolicy(), WithChecksum(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(rep.Files, []string{"index.md"}) || rep.Removed != 1 || !slices.Equal(rep.DroppedMedia, []string{"icon"}) || !slices.Equal(rep.StrippedMedia, []string{"photo"}) {
+		t.Fatalf("report = %+v", rep)
+	}
+
+	got, err := Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := string(got.Markdown.Files[0].Content); strings.Contains(c, "script") || !strings.Contains(c, "![p](photo.jpg)") {
+		t.Errorf("content = %q", c)
+	}
+	if !slices.Equal(got.Markdown.Files[0].MediaRefs, []string{"photo"}) {
+		t.Errorf("MediaRefs = %q", got.Markdown.Files[0].MediaRefs)
+	}
+	if len(got.Media.Items) != 1 || !bytes.Equal(got.Media.Items[0].Data, jpgClean) || got.Media.Items[0].SHA256 != sha256.Sum256(jpgClean) {
+		t.Errorf("media = %+v", got.Media.Items)
+	}
+	if err := got.VerifyAuditLog(); err != nil {
+		t.Errorf("audit log: %v", err)
+	}
+	if events, _ := got.AuditLog(); len(events) != 2 || events[1].Tool != "mdocx-scrub" {
+		t.Errorf("audit log = %+v", events)
+	}
+
+	// A clean container passes through unchanged in content.
+	var again bytes.Buffer
+	out.Reset()
+	if err := Encode(&out, got); err != nil {
+		t.Fatal(err)
+	}
+	rep, err = Scrub(&out, &again, DefaultScrubPolicy())
+	if err != nil || rep.Changed() {
+		t.Errorf("second scrub: %+v, %v", rep, err)
+	}
+}
+
+func TestScrubFindings(t *testing.T) {
+	doc := &Document{
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, Files: []MarkdownFile{
+			{Path: "a.md", Content: []byte("<script>x()</script><img src=x onerror=\"y()\">[l](javascript:z)\n" + "<iframe>" + strings.Repeat("\x01é", 200))},
+		}},
+		Media: MediaBundle{BundleVersion: VersionV1, Items: []MediaItem{
+			{ID: "icon", Path: "icon.svg", MIMEType: "image/svg+xml", Data: []byte("<svg/>")},
+		}},
+	}
+	fp := doc.Fingerprint()
+	rep := doc.Scrub(DefaultScrubPolicy())
+	if rep.Fingerprint != fp {
+		t.Errorf("Fingerprint = %q, want %q", rep.Fingerprint, fp)
+	}
+	want := []ScrubFinding{
+		{Kind: FindingActiveElement, Path: "a.md", Reason: "script element", Excerpt: "<script>x()</script>"},
+		{Kind: FindingActiveElement, Path: "a.md", Reason: "iframe element"},
+		{Kind: FindingEventHandler, Path: "a.md", Reason: "onerror attribute", Excerpt: `onerror="y()"`},
+		{Kind: FindingScriptURL, Path: "a.md", Reason: "script URL in link destination", Excerpt: "javascript:z"},
+		{Kind: FindingMediaType, Path: "icon.svg", ID: "icon", Reason: `MIME type "image/svg+xml" is not allowed`},
+	}
+	if len(rep.Findings) != len(want) {
+		t.Fatalf("findings = %+v", rep.Findings)
+	}
+	for i, f := range rep.Findings {
+		if i == 1 {
+			// The unclosed iframe runs to the end and is truncated.
+			if !strings.HasPrefix(f.Excerpt, "<iframe> é") || !strings.HasSuffix(f.Excerpt, "...") || !utf8.ValidString(f.Excerpt) || len(f.Excerpt) > scrubExcerptLen+3 {
+				t.Errorf("excerpt = %q", f.Excerpt)
+			}
+			f.Excerpt = ""
+		}
+		if f != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, f, want[i])
+		}
+	}
+	b, err := json.Marshal(rep)
+	if err != nil || !strings.Contains(string(b), `"kind":"script_url"`) {
+		t.Errorf("JSON = %s, %v", b, err)
+	}
+}
+
+func TestScrubQuarantine(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("<script>x()</script>")
+	var in, out bytes.Buffer
+	if err := Encode(&in, doc); err != nil {
+		t.Fatal(err)
+	}
+	policy := DefaultScrubPolicy()
+	policy.Quarantine = true
+	rep, err := Scrub(bytes.NewReader(in.Bytes()), &out, policy)
+	var qe *QuarantineError
+	if !errors.As(err, &qe) || !errors.Is(err, ErrValidation) || qe.Report != rep || len(rep.Findings) != 1 {
+		t.Fatalf("Scrub = %+v, %v", rep, err)
+	}
+	if out.Len() != 0 {
+		t.Error("quarantined container was written")
+	}
+
+	// A clean container passes.
+	doc.Markdown.Files[1].Content = []byte("clean")
+	in.Reset()
+	if err := Encode(&in, doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Scrub(&in, &out, policy); err != nil || out.Len() == 0 {
+		t.Fatalf("clean container: %v", err)
+	}
+}
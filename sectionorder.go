@@ -0,0 +1,56 @@
+package mdocx
+
+import (
+	"fmt"
+	"io"
+)
+
+// WithSectionOrderTolerance makes Decode and DecodeInto accept containers
+// whose Markdown and Media sections appear in either order, as some
+// third-party writers produce, and skip sections of unknown types that appear
+// before both have been read. Whichever of the two sections comes first is
+// buffered until the other has been read. Default is false: the sections must
+// appear in the order required by rfc.md §3.
+func WithSectionOrderTolerance(v bool) ReadOption {
+	return func(c *readConfig) { c.anyOrder = v }
+}
+
+// readSectionsAnyOrder reads the Markdown and Media sections from r in either
+// order, skipping sections of other types, and returns their headers and
+// payloads. Every section is checked against the limit of its type; unknown
+// sections against MaxMediaSectionLen.
+func readSectionsAnyOrder(r io.Reader, limits Limits) (mdSec sectionHeaderV1, mdPayload []byte, mediaSec sectionHeaderV1, mediaPayload []byte, err error) {
+	var haveMD, haveMedia bool
+	for !haveMD || !haveMedia {
+		sh, err := readSectionHeader(r)
+		if err != nil {
+			return mdSec, nil, mediaSec, nil, err
+		}
+		typ := SectionType(sh.SectionType)
+		switch {
+		case typ == SectionMarkdown && !haveMD, typ == SectionMedia && !haveMedia:
+			payload, err := readSectionPayload(r, sh, typ, limits)
+			if err != nil {
+				return mdSec, nil, mediaSec, nil, err
+			}
+			if typ == SectionMarkdown {
+				mdSec, mdPayload, haveMD = sh, payload, true
+			} else {
+				mediaSec, mediaPayload, haveMedia = sh, payload, true
+			}
+		case typ == SectionMarkdown || typ == SectionMedia:
+			return mdSec, nil, mediaSec, nil, fmt.Errorf("%w: duplicate section type %d", ErrInvalidSection, typ)
+		default:
+			if sh.PayloadLen > limits.MaxMediaSectionLen {
+				return mdSec, nil, mediaSec, nil, exceeds("MaxMediaSectionLen", "section %d too large", typ)
+			}
+			if _, err := io.CopyN(io.Discard, r, int64(sh.PayloadLen)); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return mdSec, nil, mediaSec, nil, err
+			}
+		}
+	}
+	return mdSec, mdPayload, mediaSec, mediaPayload, nil
+}
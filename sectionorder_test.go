@@ -0,0 +1,65 @@
+package mdocx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// splitSections splits an encoded container into its head (fixed header and
+// metadata), Markdown section, and Media section.
+func splitSections(t *testing.T, b []byte) (head, md, media []byte) {
+	t.Helper()
+	h, err := readFixedHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := int(fixedHeaderSizeV1) + int(h.MetadataLength)
+	mdEnd := off + 16 + int(binary.LittleEndian.Uint64(b[off+4:]))
+	mediaEnd := mdEnd + 16 + int(binary.LittleEndian.Uint64(b[mdEnd+4:]))
+	return b[:off], b[off:mdEnd], b[mdEnd:mediaEnd]
+}
+
+func TestSectionOrderTolerance(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	head, md, media := splitSections(t, buf.Bytes())
+	var unknown bytes.Buffer
+	writeSectionHeader(&unknown, sectionHeaderV1{SectionType: 9, PayloadLen: 3})
+	unknown.Write([]byte{1, 2, 3})
+
+	swapped := bytes.Join([][]byte{head, media, unknown.Bytes(), md}, nil)
+	if _, err := Decode(bytes.NewReader(swapped)); !errors.Is(err, ErrInvalidSection) {
+		t.Fatalf("strict: expected ErrInvalidSection, got %v", err)
+	}
+	doc, err := Decode(bytes.NewReader(swapped), WithSectionOrderTolerance(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Markdown.Files) != 2 || len(doc.Media.Items) != 1 {
+		t.Fatalf("decoded %+v", doc)
+	}
+	var files, items int
+	sink := SinkFuncs{
+		OnMarkdownFile: func(MarkdownFile) error { files++; return nil },
+		OnMediaItem:    func(MediaItem) error { items++; return nil },
+	}
+	if err := DecodeInto(bytes.NewReader(swapped), sink, WithSectionOrderTolerance(true)); err != nil || files != 2 || items != 1 {
+		t.Fatalf("DecodeInto: %v, %d files, %d items", err, files, items)
+	}
+
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), WithSectionOrderTolerance(true)); err != nil {
+		t.Fatalf("canonical order: %v", err)
+	}
+	dup := bytes.Join([][]byte{head, media, media, md}, nil)
+	if _, err := Decode(bytes.NewReader(dup), WithSectionOrderTolerance(true)); !errors.Is(err, ErrInvalidSection) {
+		t.Fatalf("duplicate: expected ErrInvalidSection, got %v", err)
+	}
+	cut := swapped[:len(head)+len(media)+len(unknown.Bytes())-1]
+	if _, err := Decode(bytes.NewReader(cut), WithSectionOrderTolerance(true)); err == nil {
+		t.Fatal("expected error for truncated unknown section")
+	}
+}
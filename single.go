@@ -0,0 +1,82 @@
+package mdocx
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// SinglePath is the path of the Markdown file written by EncodeSingle.
+const SinglePath = "index.md"
+
+// Meta is document metadata, as stored in Document.Metadata.
+type Meta map[string]any
+
+// EncodeSingle encodes a container holding one Markdown file and the given
+// media items, for the common case of a single document with a few images,
+// such as a clipboard payload. The file is stored at SinglePath and is the
+// root file. Media items are completed before encoding: an empty ID is derived
+// from the item's Path (or "media"), made unique with a numeric suffix, and an
+// empty MIMEType is detected from the Path's extension or the data. The media
+// slice is not modified. meta may be nil.
+//
+// MediaRefs are filled in from the Markdown content, as with
+// WithAutoMediaRefs(true); opts are applied after this default and may
+// override it or set any other WriteOption.
+func EncodeSingle(markdown []byte, media []MediaItem, meta Meta, opts ...WriteOption) ([]byte, error) {
+	doc := &Document{
+		Markdown: MarkdownBundle{
+			BundleVersion: VersionV1,
+			RootPath:      SinglePath,
+			Files:         []MarkdownFile{{Path: SinglePath, Content: markdown}},
+		},
+		Media: MediaBundle{BundleVersion: VersionV1, Items: make([]MediaItem, len(media))},
+	}
+	if meta != nil {
+		doc.Metadata = meta
+	}
+	seen := make(map[string]struct{}, len(media))
+	for _, it := range media {
+		if it.ID != "" {
+			seen[it.ID] = struct{}{}
+		}
+	}
+	for i, it := range media {
+		if it.ID == "" {
+			base := "media"
+			if it.Path != "" {
+				base = mediaIDFromPath(it.Path)
+			}
+			it.ID = uniqueMediaID(base, seen)
+		}
+		if it.MIMEType == "" {
+			it.MIMEType = detectMIMEType(it.Path, it.Data)
+		}
+		doc.Media.Items[i] = it
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, append([]WriteOption{WithAutoMediaRefs(true)}, opts...)...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeSingle decodes a container and returns the content of its root file
+// (RootPath, or its first Markdown file), all of its media items, and its
+// metadata. Any valid container is accepted; other Markdown files are ignored.
+// Use Decode to read multi-file containers in full.
+func DecodeSingle(data []byte, opts ...ReadOption) (markdown []byte, media []MediaItem, meta Meta, err error) {
+	doc, err := Decode(bytes.NewReader(data), opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	root := doc.Markdown.RootPath
+	if root == "" {
+		root = doc.Markdown.Files[0].Path
+	}
+	for _, f := range doc.Markdown.Files {
+		if f.Path == root {
+			return f.Content, doc.Media.Items, doc.Metadata, nil
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("%w: root file %q", ErrNotFound, root)
+}
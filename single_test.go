@@ -0,0 +1,76 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestEncodeSingle(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	media := []MediaItem{
+		{Path: "img/logo.png", Data: png},
+		{Path: "img/logo.png!", Data: []byte("GIF89a")},
+		{ID: "clip", Data: []byte("text")},
+	}
+	data, err := EncodeSingle([]byte("# Note\n\n![logo](img/logo.png)\n"), media, Meta{"title": "Note"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media[0].ID != "" || media[0].MIMEType != "" {
+		t.Fatal("EncodeSingle modified its media argument")
+	}
+
+	md, items, meta, err := DecodeSingle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(md) != "# Note\n\n![logo](img/logo.png)\n" || meta["title"] != "Note" {
+		t.Fatalf("DecodeSingle = %q, %v", md, meta)
+	}
+	var got []string
+	for _, it := range items {
+		got = append(got, it.ID+" "+it.MIMEType)
+	}
+	want := []string{"img_logo_png image/png", "img_logo_png_2 image/gif", "clip text/plain; charset=utf-8"}
+	if !slices.Equal(got, want) {
+		t.Errorf("media = %q, want %q", got, want)
+	}
+
+	doc, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := doc.Markdown.Files[0]; doc.Markdown.RootPath != SinglePath || f.Path != SinglePath || !slices.Equal(f.MediaRefs, []string{"img_logo_png"}) {
+		t.Errorf("document = %+v", doc.Markdown)
+	}
+
+	if _, err := EncodeSingle([]byte{0xff}, nil, nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("invalid UTF-8: err = %v", err)
+	}
+	if _, err := EncodeSingle([]byte("x"), nil, nil, WithAutoMediaRefs(false)); err != nil {
+		t.Errorf("options: %v", err)
+	}
+}
+
+func TestDecodeSingleRoot(t *testing.T) {
+	doc := &Document{
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, RootPath: "b.md", Files: []MarkdownFile{
+			{Path: "a.md", Content: []byte("a")},
+			{Path: "b.md", Content: []byte("b")},
+		}},
+		Media: MediaBundle{BundleVersion: VersionV1},
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	md, _, meta, err := DecodeSingle(buf.Bytes())
+	if err != nil || string(md) != "b" || meta != nil {
+		t.Errorf("DecodeSingle = %q, %v, %v", md, meta, err)
+	}
+	if _, _, _, err := DecodeSingle([]byte("junk")); err == nil {
+		t.Error("DecodeSingle accepted junk")
+	}
+}
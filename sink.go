@@ -0,0 +1,200 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DocumentSink receives the parts of a container from DecodeInto, in file
+// order. Any method may return ErrStop to end decoding early without error;
+// any other error aborts decoding and is returned by DecodeInto.
+type DocumentSink interface {
+	// Metadata is called once, before any file, with the document metadata
+	// (nil if absent) and the Markdown bundle's RootPath.
+	Metadata(metadata map[string]any, rootPath string) error
+	// MarkdownFile is called for each Markdown file in bundle order.
+	MarkdownFile(f MarkdownFile) error
+	// MediaItem is called for each media item in bundle order, after all
+	// Markdown files. it.Data shares memory with the decoded section and must
+	// not be modified.
+	MediaItem(it MediaItem) error
+}
+
+// SinkFuncs adapts optional functions to the DocumentSink interface.
+// Nil fields ignore the corresponding part.
+type SinkFuncs struct {
+	OnMetadata     func(metadata map[string]any, rootPath string) error
+	OnMarkdownFile func(f MarkdownFile) error
+	OnMediaItem    func(it MediaItem) error
+}
+
+// Metadata implements DocumentSink.
+func (s SinkFuncs) Metadata(metadata map[string]any, rootPath string) error {
+	if s.OnMetadata == nil {
+		return nil
+	}
+	return s.OnMetadata(metadata, rootPath)
+}
+
+// MarkdownFile implements DocumentSink.
+func (s SinkFuncs) MarkdownFile(f MarkdownFile) error {
+	if s.OnMarkdownFile == nil {
+		return nil
+	}
+	return s.OnMarkdownFile(f)
+}
+
+// MediaItem implements DocumentSink.
+func (s SinkFuncs) MediaItem(it MediaItem) error {
+	if s.OnMediaItem == nil {
+		return nil
+	}
+	return s.OnMediaItem(it)
+}
+
+// DecodeInto reads an MDOCX container from r and delivers its parts to sink
+// instead of building a Document, enabling single-pass conversions.
+//
+// The Markdown bundle is decoded and validated as a whole before any part is
+// delivered. Media items are then located in the decompressed Media section
+// and delivered one at a time without copying their data; each item is
+// validated (including its SHA256, unless disabled with WithVerifyHashes)
+// just before it is delivered. An invalid item therefore aborts decoding after
+// earlier parts have been delivered.
+//
+// DecodeInto accepts the same ReadOption values as Decode and returns the same
+// errors; media items dropped by WithMediaFilter are not delivered. If the sink skips the media, returning ErrStop from the last
+// MarkdownFile call avoids reading the Media section at all.
+func DecodeInto(r io.Reader, sink DocumentSink, opts ...ReadOption) error {
+	cfg := newReadConfig(opts)
+	ctx, release := withBudget(context.Background(), cfg.maxDuration)
+	err := release(decodeInto(ctx, cfg.input(ctx, r), sink, cfg))
+	if errors.Is(err, ErrStop) {
+		return nil
+	}
+	return err
+}
+
+// decodeInto is DecodeInto checking ctx between sections and media items.
+func decodeInto(ctx context.Context, r io.Reader, sink DocumentSink, cfg readConfig) error {
+	h, err := readFixedHeader(r)
+	if err != nil {
+		return err
+	}
+	if err := checkFixedHeaderV2(h, cfg.limits); err != nil {
+		return err
+	}
+	var metadata map[string]any
+	if h.MetadataLength > 0 {
+		mb := make([]byte, h.MetadataLength)
+		if _, err := io.ReadFull(r, mb); err != nil {
+			return err
+		}
+		if metadata, err = parseMetadata(h, mb); err != nil {
+			return err
+		}
+	}
+
+	var (
+		mdSec, mediaSec         sectionHeaderV1
+		mdPayload, mediaPayload []byte
+	)
+	if h.Version == VersionV2 {
+		mdSec, mdPayload, mediaSec, mediaPayload, err = readSectionsV2(r, h, cfg.limits, nil)
+	} else if cfg.anyOrder {
+		mdSec, mdPayload, mediaSec, mediaPayload, err = readSectionsAnyOrder(r, cfg.limits)
+	} else {
+		mdSec, mdPayload, err = readSection(r, SectionMarkdown, cfg.limits, cfg.ignoreUnknown)
+	}
+	if err != nil {
+		return err
+	}
+	format := payloadFormat(h.HeaderFlags)
+	markdown, err := decodeMarkdownPayload(ctx, mdSec, mdPayload, format, cfg.limits)
+	if err != nil {
+		return err
+	}
+	if err := validateMarkdownBundle(markdown, cfg.limits); err != nil {
+		return err
+	}
+	if err := sink.Metadata(metadata, markdown.RootPath); err != nil {
+		return err
+	}
+	for _, f := range markdown.Files {
+		if err := sink.MarkdownFile(f); err != nil {
+			return err
+		}
+	}
+
+	if !cfg.anyOrder && h.Version == VersionV1 {
+		if mediaSec, mediaPayload, err = readSection(r, SectionMedia, cfg.limits, cfg.ignoreUnknown); err != nil {
+			return err
+		}
+	}
+	if _, err := checkNoMedia(h, mediaSec); err != nil {
+		return err
+	}
+	if len(mediaPayload) == 0 {
+		return (&Document{Metadata: metadata, Markdown: markdown}).CheckInvariants(cfg.checks)
+	}
+	mediaGob, err := decompressPayloadContext(ctx, mediaSec.compression(), mediaSec.SectionFlags, mediaPayload, cfg.limits.MaxMediaUncompressed)
+	if err != nil {
+		return err
+	}
+	idx, err := format.scanMediaBytes(mediaGob, cfg.limits.MaxMediaItems)
+	if err != nil {
+		return err
+	}
+	if mediaSec.SectionFlags&sectionFlagItemCompression != 0 {
+		if err := idx.unpackEntries(bytes.NewReader(mediaGob)); err != nil {
+			return err
+		}
+	}
+	if idx.bundleVersion != VersionV1 {
+		return fmt.Errorf("%w: Media.BundleVersion must be %d", ErrValidation, VersionV1)
+	}
+	if cfg.checks != 0 {
+		items := make([]MediaItem, len(idx.items))
+		for i, e := range idx.items {
+			items[i] = MediaItem{ID: e.ID, Path: e.Path}
+		}
+		if err := (&Document{Metadata: metadata, Markdown: markdown, Media: MediaBundle{Items: items}}).CheckInvariants(cfg.checks); err != nil {
+			return err
+		}
+	}
+	seenIDs := make(map[string]struct{}, len(idx.items))
+	for i, e := range idx.items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !cfg.keeps(&e) {
+			continue
+		}
+		data, err := e.unpack(mediaGob[e.dataOff:e.dataOff+e.dataLen:e.dataOff+e.dataLen], cfg.limits)
+		if err != nil {
+			return err
+		}
+		it := MediaItem{
+			ID:         e.ID,
+			Path:       e.Path,
+			MIMEType:   e.MIMEType,
+			Data:       data,
+			SHA256:     e.SHA256,
+			Attributes: e.Attributes,
+		}
+		if err := validateMediaItem(i, it, cfg.limits, cfg.verifies(it.ID)); err != nil {
+			return err
+		}
+		if _, dup := seenIDs[it.ID]; dup {
+			return fmt.Errorf("%w: duplicate media ID %q", ErrValidation, it.ID)
+		}
+		seenIDs[it.ID] = struct{}{}
+		if err := sink.MediaItem(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
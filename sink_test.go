@@ -0,0 +1,85 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// collectSink rebuilds a Document from DecodeInto callbacks.
+type collectSink struct {
+	doc Document
+}
+
+func (c *collectSink) Metadata(m map[string]any, root string) error {
+	c.doc.Metadata = m
+	c.doc.Markdown = MarkdownBundle{BundleVersion: VersionV1, RootPath: root}
+	c.doc.Media = MediaBundle{BundleVersion: VersionV1}
+	return nil
+}
+
+func (c *collectSink) MarkdownFile(f MarkdownFile) error {
+	c.doc.Markdown.Files = append(c.doc.Markdown.Files, f)
+	return nil
+}
+
+func (c *collectSink) MediaItem(it MediaItem) error {
+	c.doc.Media.Items = append(c.doc.Media.Items, it)
+	return nil
+}
+
+func TestDecodeInto(t *testing.T) {
+	for _, comp := range []Compression{CompNone, CompZSTD} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, sampleDoc(), WithMediaCompression(comp)); err != nil {
+			t.Fatal(err)
+		}
+		want, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sink collectSink
+		if err := DecodeInto(bytes.NewReader(buf.Bytes()), &sink); err != nil {
+			t.Fatalf("%s: %v", compressionName(comp), err)
+		}
+		if !reflect.DeepEqual(&sink.doc, want) {
+			t.Fatalf("%s: got %+v want %+v", compressionName(comp), sink.doc, want)
+		}
+	}
+}
+
+func TestDecodeIntoStopAndErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+
+	files := 0
+	stop := SinkFuncs{
+		OnMarkdownFile: func(MarkdownFile) error { files++; return ErrStop },
+		OnMediaItem:    func(MediaItem) error { t.Fatal("media delivered after ErrStop"); return nil },
+	}
+	if err := DecodeInto(bytes.NewReader(buf.Bytes()), stop); err != nil || files != 1 {
+		t.Fatalf("ErrStop: err=%v files=%d", err, files)
+	}
+
+	boom := errors.New("boom")
+	fail := SinkFuncs{OnMediaItem: func(MediaItem) error { return boom }}
+	if err := DecodeInto(bytes.NewReader(buf.Bytes()), fail); !errors.Is(err, boom) {
+		t.Fatalf("expected sink error, got %v", err)
+	}
+
+	doc := sampleDoc()
+	doc.Media.Items[0].SHA256[0] ^= 0xff
+	buf.Reset()
+	if err := Encode(&buf, doc, WithVerifyHashesOnWrite(false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeInto(bytes.NewReader(buf.Bytes()), SinkFuncs{}); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation for hash mismatch, got %v", err)
+	}
+	if err := DecodeInto(bytes.NewReader(buf.Bytes()), SinkFuncs{}, WithVerifyHashes(false)); err != nil {
+		t.Fatalf("verification disabled: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package mdocx
+
+import "time"
+
+// DecodeStats records where Decode spent its time and how many bytes it
+// processed. Pass one to WithStatsCollector; Decode overwrites it on every call,
+// including failed ones, in which case stages after the failure are zero.
+//
+// Durations of stages that read from the input include the time spent waiting
+// for it.
+type DecodeStats struct {
+	// Header is the time spent reading and checking the fixed header.
+	Header time.Duration
+	// Metadata is the time spent reading and parsing the metadata JSON.
+	Metadata time.Duration
+	// MarkdownDecompress is the time spent reading and decompressing the
+	// Markdown section.
+	MarkdownDecompress time.Duration
+	// MarkdownGob is the time spent gob-decoding the Markdown bundle.
+	MarkdownGob time.Duration
+	// MediaDecompress is the time spent reading and decompressing the Media
+	// section.
+	MediaDecompress time.Duration
+	// MediaGob is the time spent gob-decoding the Media bundle.
+	MediaGob time.Duration
+	// Validation is the time spent validating the document, including hash
+	// verification.
+	Validation time.Duration
+	// Total is the time spent in Decode.
+	Total time.Duration
+
+	// BytesRead is the number of container bytes consumed from the input.
+	BytesRead uint64
+	// MetadataBytes is the length of the metadata block.
+	MetadataBytes uint64
+	// MarkdownCompressed and MarkdownUncompressed are the Markdown section
+	// payload sizes before and after decompression.
+	MarkdownCompressed, MarkdownUncompressed uint64
+	// MediaCompressed and MediaUncompressed are the Media section payload sizes
+	// before and after decompression.
+	MediaCompressed, MediaUncompressed uint64
+}
+
+// stageClock attributes elapsed time to successive stages.
+type stageClock struct {
+	start, mark time.Time
+}
+
+func newStageClock() stageClock {
+	now := time.Now()
+	return stageClock{start: now, mark: now}
+}
+
+// lap adds the time since the previous lap to d.
+func (c *stageClock) lap(d *time.Duration) {
+	now := time.Now()
+	*d += now.Sub(c.mark)
+	c.mark = now
+}
+
+// total returns the time since the clock was created.
+func (c *stageClock) total() time.Duration {
+	return time.Since(c.start)
+}
@@ -0,0 +1,38 @@
+package mdocx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeStats(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	var st DecodeStats
+	if _, err := Decode(bytes.NewReader(buf.Bytes()), WithStatsCollector(&st)); err != nil {
+		t.Fatal(err)
+	}
+	if st.BytesRead != uint64(buf.Len()) {
+		t.Fatalf("BytesRead = %d, want %d", st.BytesRead, buf.Len())
+	}
+	if st.MetadataBytes == 0 || st.MarkdownCompressed == 0 || st.MarkdownUncompressed == 0 {
+		t.Fatalf("missing markdown sizes: %+v", st)
+	}
+	if st.MediaCompressed != st.MediaUncompressed {
+		t.Fatalf("uncompressed media sizes: %+v", st)
+	}
+	sum := st.Header + st.Metadata + st.MarkdownDecompress + st.MarkdownGob + st.MediaDecompress + st.MediaGob + st.Validation
+	if st.Total <= 0 || sum > st.Total {
+		t.Fatalf("stage times %v exceed total %v", sum, st.Total)
+	}
+
+	// A failed decode resets the collector.
+	if _, err := Decode(bytes.NewReader(buf.Bytes()[:40]), WithStatsCollector(&st)); err == nil {
+		t.Fatal("expected error for truncated input")
+	}
+	if st.MarkdownCompressed != 0 || st.BytesRead == 0 {
+		t.Fatalf("stats not reset on failure: %+v", st)
+	}
+}
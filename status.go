@@ -0,0 +1,96 @@
+package mdocx
+
+import (
+	"fmt"
+	"slices"
+)
+
+// StatusAttr is the Markdown file attribute key holding the file's editorial
+// status.
+const StatusAttr = "status"
+
+// Status is the editorial status of a Markdown file, stored in its StatusAttr
+// attribute. Files move from draft to review to final, and may be sent back a
+// step: see CanTransition.
+type Status string
+
+// Editorial statuses.
+const (
+	// StatusDraft is a file being written. Files without a status are drafts.
+	StatusDraft Status = "draft"
+	// StatusReview is a file waiting for review.
+	StatusReview Status = "review"
+	// StatusFinal is a file approved for publication.
+	StatusFinal Status = "final"
+)
+
+// statusTransitions lists the statuses each status may change to.
+var statusTransitions = map[Status][]Status{
+	StatusDraft:  {StatusReview},
+	StatusReview: {StatusDraft, StatusFinal},
+	StatusFinal:  {StatusReview},
+}
+
+// Valid reports whether s is one of the Status constants.
+func (s Status) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransition reports whether a file with status s may change to status to:
+// draft to review, review back to draft or on to final, and final back to
+// review to reopen it. Keeping the same status is always allowed, and a file
+// with a status that is not Valid may only be reset to draft.
+func (s Status) CanTransition(to Status) bool {
+	if s == to && s.Valid() {
+		return true
+	}
+	if !s.Valid() {
+		return to == StatusDraft
+	}
+	return slices.Contains(statusTransitions[s], to)
+}
+
+// Status returns the editorial status of f: its StatusAttr attribute, or
+// StatusDraft if it has none. The value is returned as stored, so it may not
+// be Valid.
+func (f MarkdownFile) Status() Status {
+	if s, ok := f.Attributes[StatusAttr]; ok {
+		return Status(s)
+	}
+	return StatusDraft
+}
+
+// SetStatus changes the status of the Markdown file at path to to. It returns
+// an error wrapping ErrNotFound if there is no such file, and one wrapping
+// ErrValidation if to is not Valid or the change is not allowed by
+// CanTransition.
+func (d *Document) SetStatus(path string, to Status) error {
+	if !to.Valid() {
+		return fmt.Errorf("%w: unknown status %q", ErrValidation, to)
+	}
+	for i := range d.Markdown.Files {
+		f := &d.Markdown.Files[i]
+		if f.Path != path {
+			continue
+		}
+		if from := f.Status(); !from.CanTransition(to) {
+			return fmt.Errorf("%w: %s: cannot change status from %s to %s", ErrValidation, path, from, to)
+		}
+		(*Attributes)(&f.Attributes).SetString(StatusAttr, string(to))
+		return nil
+	}
+	return fmt.Errorf("%w: markdown file %q", ErrNotFound, path)
+}
+
+// FilesByStatus returns the Markdown files with status s, in bundle order.
+// Files without a status are listed under StatusDraft.
+func (d *Document) FilesByStatus(s Status) []MarkdownFile {
+	var out []MarkdownFile
+	for _, f := range d.Markdown.Files {
+		if f.Status() == s {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package mdocx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	cases := []struct {
+		from, to Status
+		want     bool
+	}{
+		{StatusDraft, StatusReview, true},
+		{StatusDraft, StatusFinal, false},
+		{StatusReview, StatusFinal, true},
+		{StatusReview, StatusDraft, true},
+		{StatusFinal, StatusReview, true},
+		{StatusFinal, StatusDraft, false},
+		{StatusFinal, StatusFinal, true},
+		{"approved", StatusDraft, true},
+		{"approved", StatusFinal, false},
+		{"approved", "approved", false},
+	}
+	for _, c := range cases {
+		if got := c.from.CanTransition(c.to); got != c.want {
+			t.Errorf("%q.CanTransition(%q) = %v, want %v", c.from, c.to, got, c.want)
+		}
+	}
+}
+
+func TestDocumentStatus(t *testing.T) {
+	doc := sampleDoc()
+	if got := doc.FilesByStatus(StatusDraft); len(got) != 2 {
+		t.Fatalf("files without status should be drafts, got %d", len(got))
+	}
+	if err := doc.SetStatus("docs/index.md", StatusFinal); !errors.Is(err, ErrValidation) {
+		t.Fatalf("draft to final: %v", err)
+	}
+	if err := doc.SetStatus("docs/index.md", "done"); !errors.Is(err, ErrValidation) {
+		t.Fatalf("unknown status: %v", err)
+	}
+	if err := doc.SetStatus("missing.md", StatusReview); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing file: %v", err)
+	}
+	for _, st := range []Status{StatusReview, StatusFinal} {
+		if err := doc.SetStatus("docs/index.md", st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := doc.Markdown.Files[0].Attributes[StatusAttr]; got != "final" {
+		t.Fatalf("status attribute = %q", got)
+	}
+	if got := doc.FilesByStatus(StatusFinal); len(got) != 1 || got[0].Path != "docs/index.md" {
+		t.Fatalf("final files = %+v", got)
+	}
+
+	err := doc.CheckInvariants(CheckPublished)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Code != CodeNotFinal || ve.Path != "docs/notes.md" || ve.Value != "draft" {
+		t.Fatalf("CheckPublished: %v", err)
+	}
+	if err := doc.CheckInvariants(CheckAll); err != nil {
+		t.Fatalf("CheckAll should not include CheckPublished: %v", err)
+	}
+	for _, st := range []Status{StatusReview, StatusFinal} {
+		if err := doc.SetStatus("docs/notes.md", st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := doc.CheckInvariants(CheckPublished); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,418 @@
+package mdocx
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// StreamHeader holds the document-level fields of a container written by
+// EncodeStream.
+type StreamHeader struct {
+	// Metadata is the optional document metadata.
+	Metadata map[string]any
+	// RootPath optionally specifies the primary Markdown file path.
+	RootPath string
+	// NoMedia writes the container without a media bundle (see
+	// Document.NoMedia). No media items may be received when it is set.
+	NoMedia bool
+}
+
+// EncodeStream writes an MDOCX container to w whose Markdown files and media
+// items are received from files and media, for producers that generate content
+// on the fly. Both channels are read concurrently until they are closed; a nil
+// channel is treated as closed. Items are written in the order received.
+//
+// Each file and item is validated and gob-encoded as it arrives and then
+// spooled to a temporary file (see WithSpoolDir), so memory use is bounded by
+// the largest single item rather than by the container. Sections are
+// compressed from the spool once both channels are closed, and nothing is
+// written to w before then.
+//
+// EncodeStream accepts the same WriteOption values as Encode and applies the
+// same validation. If ctx is canceled, or validation fails, EncodeStream stops
+// reading and returns the error; the caller is responsible for unblocking any
+// producers still sending on the channels.
+func EncodeStream(ctx context.Context, w io.Writer, hdr StreamHeader, files <-chan MarkdownFile, media <-chan MediaItem, opts ...WriteOption) (err error) {
+	cfg := newWriteConfig(opts)
+	ctx, release := withBudget(ctx, cfg.maxDuration)
+	defer func() { err = release(err) }()
+	w, done := cfg.outputWriter(ctx, w)
+	defer func() { done(err) }()
+
+	st, err := newStreamState(hdr, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { st.close(err) }()
+	for files != nil || media != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case f, ok := <-files:
+			if !ok {
+				files = nil
+				continue
+			}
+			if st.resumedFile(f.Path) {
+				continue
+			}
+			if err := st.addFile(f); err != nil {
+				return err
+			}
+		case it, ok := <-media:
+			if !ok {
+				media = nil
+				continue
+			}
+			if st.resumedItem(it.ID) {
+				continue
+			}
+			if err := st.addItem(it); err != nil {
+				return err
+			}
+		}
+	}
+	return st.finish(w)
+}
+
+// streamState accumulates the spooled sections of a container whose parts are
+// added one at a time, for EncodeStream and Writer.
+type streamState struct {
+	cfg           writeConfig
+	hdr           StreamHeader
+	metadataBytes []byte
+	headerFlags   uint16
+	mdSpool       *spool
+	mediaSpool    *spool
+	mdEnc         elementEncoder[MarkdownFile]
+	mediaEnc      elementEncoder[MediaItem]
+	seenPaths     map[string]struct{}
+	seenIDs       map[string]struct{}
+	// Only paths, refs, IDs, and, for the checks that scan content, Markdown
+	// content and attributes are kept for the optional invariant checks.
+	refFiles []MarkdownFile
+	refItems []MediaItem
+	// ckpt is set with WithCheckpointDir.
+	ckpt *checkpoint
+}
+
+// newStreamState validates hdr and creates the spool files, or opens those of
+// the checkpoint set with WithCheckpointDir. The caller must call remove or
+// close when done.
+func newStreamState(hdr StreamHeader, cfg writeConfig) (*streamState, error) {
+	if err := cfg.format.check(); err != nil {
+		return nil, err
+	}
+	if (cfg.version != 0 && cfg.version != VersionV1) || len(cfg.extraSections) > 0 {
+		return nil, fmt.Errorf("%w: streaming encoders write version 1 only", ErrValidation)
+	}
+	if cfg.duplicates != DuplicatesAllow {
+		return nil, fmt.Errorf("%w: streaming encoders do not support duplicate media policies", ErrValidation)
+	}
+	if hdr.RootPath != "" {
+		if err := validateContainerPath(hdr.RootPath); err != nil {
+			return nil, fmt.Errorf("%w: RootPath: %v", ErrValidation, err)
+		}
+		if err := cfg.limits.checkPath(hdr.RootPath); err != nil {
+			return nil, err
+		}
+	}
+	st := &streamState{
+		cfg:       cfg,
+		hdr:       hdr,
+		mdEnc:     newElementEncoder(cfg.format, cborMarkdownFile),
+		mediaEnc:  newElementEncoder(cfg.format, cborMediaItem),
+		seenPaths: make(map[string]struct{}),
+		seenIDs:   make(map[string]struct{}),
+	}
+	var err error
+	if st.metadataBytes, st.headerFlags, err = encodeMetadata(hdr.Metadata, cfg.limits); err != nil {
+		return nil, err
+	}
+	if hdr.NoMedia {
+		st.headerFlags |= HeaderFlagNoMedia
+	}
+	st.headerFlags |= cfg.format.headerFlags()
+	if cfg.checkpointDir != "" {
+		if err := st.openCheckpoint(cfg.checkpointDir); err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+	if st.mdSpool, err = newSpool(cfg.spoolDir); err != nil {
+		return nil, err
+	}
+	if st.mediaSpool, err = newSpool(cfg.spoolDir); err != nil {
+		st.mdSpool.remove()
+		return nil, err
+	}
+	return st, nil
+}
+
+// remove deletes the spool files, and the checkpoint if any.
+func (st *streamState) remove() {
+	st.mdSpool.remove()
+	st.mediaSpool.remove()
+	if st.ckpt != nil {
+		_ = st.ckpt.journal.Close()
+		_ = os.Remove(st.ckpt.journal.Name())
+	}
+}
+
+// close ends the use of st after an encode that returned err. A checkpoint is
+// kept, with the spool files it refers to, if err is non-nil; everything else
+// is removed.
+func (st *streamState) close(err error) {
+	if err == nil || st.ckpt == nil {
+		st.remove()
+		return
+	}
+	_ = st.mdSpool.f.Close()
+	_ = st.mediaSpool.f.Close()
+	_ = st.ckpt.journal.Close()
+}
+
+// addFile validates f and spools its encoding.
+func (st *streamState) addFile(f MarkdownFile) error {
+	i := st.mdSpool.count
+	if i >= st.cfg.limits.MaxMarkdownFiles {
+		return exceeds("MaxMarkdownFiles", "too many markdown files")
+	}
+	if err := validateMarkdownFile(i, f, st.cfg.limits); err != nil {
+		return err
+	}
+	if err := st.cfg.attrSchema.Validate(f.Attributes); err != nil {
+		return fmt.Errorf("markdown file %q: %w", f.Path, err)
+	}
+	if _, dup := st.seenPaths[f.Path]; dup {
+		return fmt.Errorf("%w: duplicate markdown path %q", ErrValidation, f.Path)
+	}
+	st.seenPaths[f.Path] = struct{}{}
+	ref := MarkdownFile{Path: f.Path, MediaRefs: f.MediaRefs}
+	if st.cfg.checks&checksScanContent != 0 {
+		ref.Content, ref.Attributes = f.Content, f.Attributes
+	}
+	if st.cfg.checks != 0 {
+		st.refFiles = append(st.refFiles, ref)
+	}
+	b, err := st.mdEnc.encode(f)
+	if err != nil {
+		return err
+	}
+	if err := st.mdSpool.add(b); err != nil {
+		return err
+	}
+	return st.record(checkpointRecord{File: &ref})
+}
+
+// checkItem applies the checks of validateMediaItem(it, verify) and those
+// that depend on the rest of the stream, and records its ID and path.
+func (st *streamState) checkItem(it MediaItem, verify bool) error {
+	i := st.mediaSpool.count
+	if st.hdr.NoMedia {
+		return fmt.Errorf("%w: NoMedia is set but media item %q was received", ErrValidation, it.ID)
+	}
+	if i >= st.cfg.limits.MaxMediaItems {
+		return exceeds("MaxMediaItems", "too many media items")
+	}
+	if err := validateMediaItem(i, it, st.cfg.limits, verify); err != nil {
+		return err
+	}
+	if err := st.cfg.attrSchema.Validate(it.Attributes); err != nil {
+		return fmt.Errorf("media item %q: %w", it.ID, err)
+	}
+	if _, dup := st.seenIDs[it.ID]; dup {
+		return fmt.Errorf("%w: duplicate media ID %q", ErrValidation, it.ID)
+	}
+	st.seenIDs[it.ID] = struct{}{}
+	if st.cfg.checks != 0 {
+		st.refItems = append(st.refItems, MediaItem{ID: it.ID, Path: it.Path})
+	}
+	return nil
+}
+
+// addItem validates it and spools its encoding.
+func (st *streamState) addItem(it MediaItem) error {
+	if st.cfg.autoPopulate && it.SHA256 == ([32]byte{}) {
+		it.SHA256 = it.computedSHA256()
+	}
+	if err := st.checkItem(it, st.cfg.verifyHashes); err != nil {
+		return err
+	}
+	b, err := st.mediaEnc.encode(it)
+	if err != nil {
+		return err
+	}
+	if err := st.mediaSpool.add(b); err != nil {
+		return err
+	}
+	return st.record(checkpointRecord{ItemID: it.ID, ItemPath: it.Path})
+}
+
+// finish writes the container to w.
+func (st *streamState) finish(w io.Writer) error {
+	if st.mdSpool.count == 0 {
+		return fmt.Errorf("%w: Markdown.Files must not be empty", ErrValidation)
+	}
+	refs := &Document{
+		Metadata: st.hdr.Metadata,
+		Markdown: MarkdownBundle{Files: st.refFiles},
+		Media:    MediaBundle{Items: st.refItems},
+	}
+	if err := refs.CheckInvariants(st.cfg.checks); err != nil {
+		return err
+	}
+
+	mdHead, err := st.cfg.format.markdownHead(st.hdr.RootPath, st.mdSpool.count)
+	if err != nil {
+		return err
+	}
+	mediaHead, err := st.cfg.format.mediaHead(st.mediaSpool.count)
+	if err != nil {
+		return err
+	}
+
+	h := fixedHeaderV1{
+		Magic:          Magic,
+		Version:        VersionV1,
+		HeaderFlags:    st.headerFlags,
+		FixedHdrSize:   fixedHeaderSizeV1,
+		MetadataLength: uint32(len(st.metadataBytes)),
+	}
+	if err := writeFixedHeader(w, h); err != nil {
+		return err
+	}
+	if _, err := w.Write(st.metadataBytes); err != nil {
+		return err
+	}
+	if err := writeSpooledSection(w, SectionMarkdown, st.cfg.mdCompression, mdHead, st.mdSpool, 0, st.cfg); err != nil {
+		return err
+	}
+	if st.hdr.NoMedia {
+		return writeSectionHeader(w, sectionHeaderV1{SectionType: uint16(SectionMedia)})
+	}
+	return writeSpooledSection(w, SectionMedia, st.cfg.mediaCompression, mediaHead, st.mediaSpool, 0, st.cfg)
+}
+
+// writeSpooledSection writes a section whose payload is head followed by the
+// elements in sp and the head's trailer, compressing it with comp, and adds
+// flags to its SectionFlags. Compressed payloads are staged
+// in a second spool file so that PayloadLen is known before the header is
+// written.
+func writeSpooledSection(w io.Writer, typ SectionType, comp Compression, head bundleHead, sp *spool, flags uint16, cfg writeConfig) error {
+	gobLen := head.size(sp.n)
+	writeGob := func(dst io.Writer) error {
+		if _, err := dst.Write(head.prefix(sp.n)); err != nil {
+			return err
+		}
+		if err := sp.copyTo(dst); err != nil {
+			return err
+		}
+		_, err := dst.Write(head.trailer())
+		return err
+	}
+	if comp == CompNone {
+		if err := writeSectionHeader(w, sectionHeaderV1{SectionType: uint16(typ), SectionFlags: flags, PayloadLen: uint64(gobLen)}); err != nil {
+			return err
+		}
+		return writeGob(w)
+	}
+
+	staged, err := newSpool(cfg.spoolDir)
+	if err != nil {
+		return err
+	}
+	defer staged.remove()
+	cw, err := newCompressWriter(comp, staged, cfg.sectionCodecs(typ))
+	if err != nil {
+		return err
+	}
+	if err := writeGob(cw); err != nil {
+		_ = cw.Close()
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	sh := sectionHeaderV1{
+		SectionType:  uint16(typ),
+		SectionFlags: uint16(comp) | sectionFlagHasUncompressedLen | flags,
+		PayloadLen:   8 + uint64(staged.n),
+	}
+	if err := writeSectionHeader(w, sh); err != nil {
+		return err
+	}
+	var prefix [8]byte
+	binary.LittleEndian.PutUint64(prefix[:], uint64(gobLen))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	return staged.copyTo(w)
+}
+
+// spool is an append-only temporary file.
+type spool struct {
+	f     *os.File
+	bw    *bufio.Writer
+	n     int64
+	count int
+}
+
+func newSpool(dir string) (*spool, error) {
+	f, err := os.CreateTemp(dir, "mdocx-spool-*")
+	if err != nil {
+		return nil, err
+	}
+	return &spool{f: f, bw: bufio.NewWriterSize(f, 64<<10)}, nil
+}
+
+// add appends one element.
+func (s *spool) add(b []byte) error {
+	if _, err := s.Write(b); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+func (s *spool) Write(b []byte) (int, error) {
+	n, err := s.bw.Write(b)
+	s.n += int64(n)
+	return n, err
+}
+
+// rewind flushes the spool and returns its file positioned at the start.
+func (s *spool) rewind() (*os.File, error) {
+	if err := s.bw.Flush(); err != nil {
+		return nil, err
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s.f, nil
+}
+
+// copyTo writes the spooled bytes to w.
+func (s *spool) copyTo(w io.Writer) error {
+	f, err := s.rewind()
+	if err != nil {
+		return err
+	}
+	// Hide any ReadFrom method of w: some compressors do not support mixing
+	// Write and ReadFrom calls.
+	n, err := io.Copy(struct{ io.Writer }{w}, f)
+	if err == nil && n != s.n {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// remove closes and deletes the spool file.
+func (s *spool) remove() {
+	_ = s.f.Close()
+	_ = os.Remove(s.f.Name())
+}
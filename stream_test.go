@@ -0,0 +1,102 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// feed returns closed, buffered channels holding the files and items of doc.
+func feed(doc *Document) (<-chan MarkdownFile, <-chan MediaItem) {
+	files := make(chan MarkdownFile, len(doc.Markdown.Files))
+	media := make(chan MediaItem, len(doc.Media.Items))
+	for _, f := range doc.Markdown.Files {
+		files <- f
+	}
+	for _, it := range doc.Media.Items {
+		media <- it
+	}
+	close(files)
+	close(media)
+	return files, media
+}
+
+func TestEncodeStreamMatchesEncode(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.RootPath = "docs/index.md"
+	hdr := StreamHeader{Metadata: doc.Metadata, RootPath: doc.Markdown.RootPath}
+
+	var want bytes.Buffer
+	if err := Encode(&want, doc, WithMarkdownCompression(CompNone), WithMediaCompression(CompNone)); err != nil {
+		t.Fatal(err)
+	}
+	files, media := feed(doc)
+	var got bytes.Buffer
+	if err := EncodeStream(context.Background(), &got, hdr, files, media, WithMarkdownCompression(CompNone), WithMediaCompression(CompNone), WithSpoolDir(t.TempDir())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatal("EncodeStream output differs from Encode")
+	}
+
+	for _, comp := range []Compression{CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ} {
+		files, media := feed(doc)
+		var buf bytes.Buffer
+		if err := EncodeStream(context.Background(), &buf, hdr, files, media, WithMarkdownCompression(comp), WithMediaCompression(comp)); err != nil {
+			t.Fatalf("%s: %v", compressionName(comp), err)
+		}
+		dec, err := Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: decode: %v", compressionName(comp), err)
+		}
+		if !reflect.DeepEqual(dec.Markdown, doc.Markdown) || !reflect.DeepEqual(dec.Media, doc.Media) {
+			t.Fatalf("%s: content changed", compressionName(comp))
+		}
+	}
+}
+
+func TestEncodeStreamNoMediaAndNilChannel(t *testing.T) {
+	doc := sampleDoc()
+	files, _ := feed(doc)
+	var buf bytes.Buffer
+	if err := EncodeStream(context.Background(), &buf, StreamHeader{NoMedia: true}, files, nil); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dec.NoMedia || len(dec.Markdown.Files) != len(doc.Markdown.Files) {
+		t.Fatalf("unexpected document: NoMedia=%v files=%d", dec.NoMedia, len(dec.Markdown.Files))
+	}
+}
+
+func TestEncodeStreamErrors(t *testing.T) {
+	ctx := context.Background()
+
+	dup := sampleDoc()
+	dup.Markdown.Files[1].Path = dup.Markdown.Files[0].Path
+	files, media := feed(dup)
+	if err := EncodeStream(ctx, &bytes.Buffer{}, StreamHeader{}, files, media); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation for duplicate path, got %v", err)
+	}
+
+	if err := EncodeStream(ctx, &bytes.Buffer{}, StreamHeader{}, nil, nil); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected ErrValidation for no markdown, got %v", err)
+	}
+
+	files, media = feed(sampleDoc())
+	err := EncodeStream(ctx, &bytes.Buffer{}, StreamHeader{}, files, media, WithWriteLimits(Limits{MaxMediaItems: 1, MaxMarkdownFiles: 5}))
+	if err != nil {
+		t.Fatalf("within limits: %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	block := make(chan MarkdownFile)
+	if err := EncodeStream(canceled, &bytes.Buffer{}, StreamHeader{}, block, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
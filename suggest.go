@@ -0,0 +1,170 @@
+package mdocx
+
+import (
+	"fmt"
+	"time"
+)
+
+// CompressionTrial is the outcome of compressing a section sample with one
+// algorithm.
+type CompressionTrial struct {
+	Compression Compression
+	// SampleSize is the number of uncompressed gob bytes compressed.
+	SampleSize int
+	// CompressedSize is the size of the resulting section payload, including
+	// the uncompressed length prefix of compressed payloads.
+	CompressedSize int
+	// Ratio is CompressedSize divided by SampleSize.
+	Ratio float64
+	// Duration is the time taken to compress the sample.
+	Duration time.Duration
+}
+
+// CompressionSuggestion reports the trade-offs between compression algorithms
+// for a document, as measured by SuggestCompression.
+type CompressionSuggestion struct {
+	// Markdown and Media hold one trial per algorithm, in Compression order.
+	// Media is nil for documents with NoMedia set.
+	Markdown []CompressionTrial
+	Media    []CompressionTrial
+	// MarkdownCompression and MediaCompression are the suggested algorithms,
+	// suitable for WithMarkdownCompression and WithMediaCompression.
+	MarkdownCompression Compression
+	MediaCompression    Compression
+}
+
+// suggestConfig holds configuration options for SuggestCompression.
+type suggestConfig struct {
+	sampleBytes int
+	write       []WriteOption
+}
+
+// SuggestOption is a functional option for configuring SuggestCompression.
+type SuggestOption func(*suggestConfig)
+
+// WithSuggestSampleBytes sets how many bytes of each section SuggestCompression
+// compresses per algorithm. Larger samples give more accurate ratios at the
+// cost of time. Default is 1 MiB.
+func WithSuggestSampleBytes(n int) SuggestOption {
+	return func(c *suggestConfig) { c.sampleBytes = n }
+}
+
+// WithSuggestWriteOptions sets the WriteOption values, such as
+// WithBrotliQuality, used to configure the trial encoders.
+func WithSuggestWriteOptions(opts ...WriteOption) SuggestOption {
+	return func(c *suggestConfig) { c.write = opts }
+}
+
+// suggestAlgorithms are the algorithms SuggestCompression tries.
+var suggestAlgorithms = []Compression{CompNone, CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ}
+
+const (
+	// suggestMinSaving is the fraction of a sample a compressor must save to
+	// be suggested over CompNone.
+	suggestMinSaving = 0.05
+	// suggestSizeSlack is the fraction by which a faster compressor's output
+	// may exceed the smallest output and still be suggested.
+	suggestSizeSlack = 0.02
+)
+
+// SuggestCompression trial-compresses representative samples of the Markdown
+// and Media sections of doc with every algorithm and reports the size and time
+// of each trial, so callers can pick compression without encoding the whole
+// document several times. doc is not modified or validated.
+//
+// A section larger than the sample size is sampled as four evenly spaced
+// chunks. The suggested algorithm for a section is the fastest one whose output
+// is within 2% of the smallest; CompNone is suggested if no algorithm saves at
+// least 5%, as is typical for media that is already compressed.
+func SuggestCompression(doc *Document, opts ...SuggestOption) (*CompressionSuggestion, error) {
+	cfg := suggestConfig{sampleBytes: 1 << 20}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("%w: document is nil", ErrValidation)
+	}
+	if cfg.sampleBytes <= 0 {
+		return nil, fmt.Errorf("%w: sample size must be positive", ErrValidation)
+	}
+	wcfg := newWriteConfig(cfg.write)
+	codecs := wcfg.codecs
+
+	mdGob, err := wcfg.format.encodeMarkdown(doc.Markdown)
+	if err != nil {
+		return nil, err
+	}
+	s := &CompressionSuggestion{}
+	if s.Markdown, s.MarkdownCompression, err = suggestSection(sampleBytes(mdGob, cfg.sampleBytes), codecs); err != nil {
+		return nil, err
+	}
+	if doc.NoMedia {
+		return s, nil
+	}
+	mediaGob, err := wcfg.format.encodeMedia(doc.Media)
+	if err != nil {
+		return nil, err
+	}
+	if s.Media, s.MediaCompression, err = suggestSection(sampleBytes(mediaGob, cfg.sampleBytes), codecs); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// sampleBytes returns b if it holds at most n bytes, or else four evenly spaced
+// chunks of b totalling n bytes.
+func sampleBytes(b []byte, n int) []byte {
+	if len(b) <= n {
+		return b
+	}
+	const chunks = 4
+	size := n / chunks
+	if size == 0 {
+		return b[:n]
+	}
+	out := make([]byte, 0, chunks*size)
+	for i := range chunks {
+		off := i * (len(b) - size) / (chunks - 1)
+		out = append(out, b[off:off+size]...)
+	}
+	return out
+}
+
+// suggestSection compresses sample with every algorithm and returns the trials
+// and the suggested algorithm.
+func suggestSection(sample []byte, codecs codecTuning) ([]CompressionTrial, Compression, error) {
+	trials := make([]CompressionTrial, 0, len(suggestAlgorithms))
+	smallest := len(sample)
+	for _, comp := range suggestAlgorithms {
+		start := time.Now()
+		_, payload, err := compressPayload(comp, sample, codecs)
+		if err != nil {
+			return nil, 0, err
+		}
+		tr := CompressionTrial{
+			Compression:    comp,
+			SampleSize:     len(sample),
+			CompressedSize: len(payload),
+			Duration:       time.Since(start),
+		}
+		if len(sample) > 0 {
+			tr.Ratio = float64(tr.CompressedSize) / float64(len(sample))
+		}
+		trials = append(trials, tr)
+		smallest = min(smallest, tr.CompressedSize)
+	}
+
+	if len(sample) == 0 || float64(smallest) > float64(len(sample))*(1-suggestMinSaving) {
+		return trials, CompNone, nil
+	}
+	best := -1
+	for i, tr := range trials {
+		if tr.Compression == CompNone || float64(tr.CompressedSize) > float64(smallest)*(1+suggestSizeSlack) {
+			continue
+		}
+		if best < 0 || tr.Duration < trials[best].Duration {
+			best = i
+		}
+	}
+	return trials, trials[best].Compression, nil
+}
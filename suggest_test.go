@@ -0,0 +1,84 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestSuggestCompression(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = bytes.Repeat([]byte("The quick brown fox jumps over the lazy dog.\n"), 2000)
+	noise := make([]byte, 64<<10)
+	rand.New(rand.NewSource(1)).Read(noise)
+	doc.Media.Items[0].Data = noise
+
+	s, err := SuggestCompression(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Markdown) != len(suggestAlgorithms) || len(s.Media) != len(suggestAlgorithms) {
+		t.Fatalf("trials: %d markdown, %d media", len(s.Markdown), len(s.Media))
+	}
+	for i, tr := range s.Markdown {
+		if tr.Compression != suggestAlgorithms[i] || tr.SampleSize == 0 || tr.CompressedSize == 0 || tr.Ratio <= 0 {
+			t.Fatalf("trial %d: %+v", i, tr)
+		}
+	}
+	if s.Markdown[0].CompressedSize != s.Markdown[0].SampleSize {
+		t.Fatalf("CompNone trial: %+v", s.Markdown[0])
+	}
+	if s.MarkdownCompression == CompNone {
+		t.Fatal("repetitive Markdown should be compressed")
+	}
+	if s.MediaCompression != CompNone {
+		t.Fatalf("random media: suggested %d", s.MediaCompression)
+	}
+
+	// Suggestions are valid write options.
+	if err := Encode(&bytes.Buffer{}, doc, WithMarkdownCompression(s.MarkdownCompression), WithMediaCompression(s.MediaCompression)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = SuggestCompression(doc, WithSuggestSampleBytes(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Markdown[0].SampleSize != 4096 || s.Media[0].SampleSize != 4096 {
+		t.Fatalf("sample sizes %d, %d", s.Markdown[0].SampleSize, s.Media[0].SampleSize)
+	}
+
+	doc.NoMedia = true
+	doc.Media = MediaBundle{}
+	if s, err = SuggestCompression(doc); err != nil || s.Media != nil || s.MediaCompression != CompNone {
+		t.Fatalf("NoMedia: %+v, %v", s, err)
+	}
+
+	if _, err := SuggestCompression(nil); !errors.Is(err, ErrValidation) {
+		t.Fatalf("nil doc: %v", err)
+	}
+	if _, err := SuggestCompression(sampleDoc(), WithSuggestSampleBytes(0)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("zero sample: %v", err)
+	}
+	if _, err := SuggestCompression(sampleDoc(), WithSuggestWriteOptions(WithBrotliQuality(12))); !errors.Is(err, ErrValidation) {
+		t.Fatalf("bad tuning: %v", err)
+	}
+}
+
+func TestSampleBytes(t *testing.T) {
+	b := make([]byte, 100)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	if got := sampleBytes(b, 200); len(got) != 100 {
+		t.Fatalf("short input: %d bytes", len(got))
+	}
+	got := sampleBytes(b, 8)
+	if want := []byte{0, 1, 32, 33, 65, 66, 98, 99}; !bytes.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := sampleBytes(b, 3); !bytes.Equal(got, b[:3]) {
+		t.Fatalf("tiny sample: %v", got)
+	}
+}
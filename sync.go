@@ -0,0 +1,110 @@
+package mdocx
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// HashSet is a set of SHA-256 hashes of media data. Two endpoints
+// synchronizing containers exchange hash sets to find out which media items
+// need transferring: the receiver sends the HashSet of the media it already
+// has, and the sender transfers only the items MissingFrom reports. For a
+// small edit to a large bundle that is a few items rather than the whole
+// Media section. MarshalBinary and UnmarshalBinary give it a compact wire form.
+type HashSet map[[32]byte]struct{}
+
+// Contains reports whether s holds sum.
+func (s HashSet) Contains(sum [32]byte) bool {
+	_, ok := s[sum]
+	return ok
+}
+
+// MarshalBinary encodes s as its hashes in ascending byte order, 32 bytes
+// each, so that equal sets encode to equal bytes.
+func (s HashSet) MarshalBinary() ([]byte, error) {
+	sums := slices.SortedFunc(maps.Keys(s), func(a, b [32]byte) int { return bytes.Compare(a[:], b[:]) })
+	out := make([]byte, 0, 32*len(sums))
+	for _, sum := range sums {
+		out = append(out, sum[:]...)
+	}
+	return out, nil
+}
+
+// UnmarshalBinary replaces *s with the hashes encoded in data by
+// MarshalBinary. It returns an error wrapping ErrInvalidPayload if the length
+// of data is not a multiple of 32.
+func (s *HashSet) UnmarshalBinary(data []byte) error {
+	if len(data)%32 != 0 {
+		return fmt.Errorf("%w: hash set of %d bytes is not a multiple of 32", ErrInvalidPayload, len(data))
+	}
+	set := make(HashSet, len(data)/32)
+	for b := range slices.Chunk(data, 32) {
+		set[[32]byte(b)] = struct{}{}
+	}
+	*s = set
+	return nil
+}
+
+// HashSet returns the hashes of the data of d's media items. A stored SHA256
+// is trusted as it is; a zero one is computed from the data.
+func (d *Document) HashSet() HashSet {
+	set := make(HashSet, len(d.Media.Items))
+	for _, it := range d.Media.Items {
+		set[itemSum(it.SHA256, it.Data)] = struct{}{}
+	}
+	return set
+}
+
+// MissingFrom returns the media items of d whose data is not in remote, the
+// HashSet of the other endpoint, in bundle order. Of several items with the
+// same data only the first is returned, since the other endpoint needs the
+// data once; it can match the rest, and the items it already has, by SHA256.
+func (d *Document) MissingFrom(remote HashSet) []MediaItem {
+	var missing []MediaItem
+	sent := make(HashSet)
+	for _, it := range d.Media.Items {
+		sum := itemSum(it.SHA256, it.Data)
+		if remote.Contains(sum) || sent.Contains(sum) {
+			continue
+		}
+		sent[sum] = struct{}{}
+		missing = append(missing, it)
+	}
+	return missing
+}
+
+// HashSet returns the hashes of the data of the media items in the container,
+// as Document.HashSet does. Only the items without a stored SHA256 are read.
+func (r *Reader) HashSet() (HashSet, error) {
+	set := make(HashSet, len(r.items))
+	for i, it := range r.items {
+		if it.SHA256 != ([32]byte{}) {
+			set[it.SHA256] = struct{}{}
+			continue
+		}
+		rc, err := r.open(i)
+		if err != nil {
+			return nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		set[[32]byte(h.Sum(nil))] = struct{}{}
+	}
+	return set, nil
+}
+
+// itemSum returns stored, or the SHA-256 of data if stored is zero.
+func itemSum(stored [32]byte, data []byte) [32]byte {
+	if stored != ([32]byte{}) {
+		return stored
+	}
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,69 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+)
+
+func TestMediaSync(t *testing.T) {
+	local := sampleDoc()
+	local.Media.Items = append(local.Media.Items,
+		MediaItem{ID: "chart", Path: "assets/chart.svg", MIMEType: "image/svg+xml", Data: []byte("<svg/>")},
+		MediaItem{ID: "old", Path: "assets/old.bin", MIMEType: "application/octet-stream", Data: []byte("old")},
+	)
+	remote := local.HashSet()
+	if len(remote) != 3 || !remote.Contains(local.Media.Items[0].computedSHA256()) {
+		t.Fatalf("HashSet = %v", remote)
+	}
+
+	// The logo is edited, and two new items share their data.
+	edited := sampleDoc()
+	edited.Media.Items = []MediaItem{
+		{ID: "logo", Path: "assets/logo.png", MIMEType: "image/png", Data: []byte{4, 5, 6}},
+		local.Media.Items[1],
+		{ID: "a", Path: "assets/a.txt", MIMEType: "text/plain", Data: []byte("shared")},
+		{ID: "b", Path: "assets/b.txt", MIMEType: "text/plain", Data: []byte("shared")},
+	}
+	var ids []string
+	for _, it := range edited.MissingFrom(remote) {
+		ids = append(ids, it.ID)
+	}
+	if want := []string{"logo", "a"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("MissingFrom = %v, want %v", ids, want)
+	}
+	if got := edited.MissingFrom(edited.HashSet()); len(got) != 0 {
+		t.Fatalf("MissingFrom own set = %v", got)
+	}
+
+	// The wire form round-trips and is independent of map order.
+	b, err := remote.MarshalBinary()
+	if err != nil || len(b) != 3*32 {
+		t.Fatalf("MarshalBinary = %d bytes, %v", len(b), err)
+	}
+	if b2, _ := maps.Clone(remote).MarshalBinary(); !bytes.Equal(b, b2) {
+		t.Fatal("MarshalBinary is not deterministic")
+	}
+	var back HashSet
+	if err := back.UnmarshalBinary(b); err != nil || !maps.Equal(back, remote) {
+		t.Fatalf("UnmarshalBinary = %v, %v", back, err)
+	}
+	if err := back.UnmarshalBinary(b[:40]); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("UnmarshalBinary of 40 bytes: %v", err)
+	}
+
+	// A Reader computes the same set, hashing items without a stored SHA256.
+	var buf bytes.Buffer
+	if err := Encode(&buf, local, WithAutoPopulateSHA256(false)); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set, err := r.HashSet(); err != nil || !maps.Equal(set, remote) {
+		t.Fatalf("Reader.HashSet = %v, %v", set, err)
+	}
+}
@@ -0,0 +1,148 @@
+package mdocx
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// Attribute keys of the stubs EncodeTiered leaves in the hot container for
+// items moved to the cold container.
+const (
+	// ColdSHA256Attr holds the hex SHA-256 of the item's data.
+	ColdSHA256Attr = "cold_sha256"
+	// ColdSizeAttr holds the size of the item's data in bytes.
+	ColdSizeAttr = "cold_size"
+)
+
+// ColdTierPath is the path of the Markdown file of a cold container, which
+// lists the items it holds.
+const ColdTierPath = "cold-tier.md"
+
+// EncodeTiered encodes doc as two containers, for storage tiering of large
+// corpora: media items larger than threshold bytes go to the cold container
+// written to cold, and everything else to the hot container written to hot.
+// In the hot container each moved item is replaced by a stub with the same ID,
+// Path, MIMEType, and Attributes, no data, and the ColdSHA256Attr and
+// ColdSizeAttr attributes describing the data. The cold container holds the
+// moved items and a Markdown file at ColdTierPath linking to each of them.
+// Decode with WithColdTier, or Document.ResolveColdTier, reunites them.
+//
+// opts apply to both containers. The cold container is written with
+// WithMediaCompression(CompNone) and WithIndex(true) unless opts say
+// otherwise, so that a Reader can fetch items from it without decompressing
+// the others. Transforms set with WithTransforms run once, on doc, before it
+// is split; as with Encode they modify doc in place. Nothing is written to
+// cold if no item exceeds threshold.
+func EncodeTiered(hot, cold io.Writer, doc *Document, threshold int64, opts ...WriteOption) error {
+	if doc == nil {
+		return fmt.Errorf("%w: document is nil", ErrValidation)
+	}
+	cfg := newWriteConfig(opts)
+	for _, t := range cfg.transforms {
+		if err := t(doc); err != nil {
+			return err
+		}
+	}
+	noTransforms := func(c *writeConfig) { c.transforms = nil }
+
+	hotDoc := *doc
+	hotDoc.Media.Items = make([]MediaItem, len(doc.Media.Items))
+	coldDoc := &Document{
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, RootPath: ColdTierPath},
+		Media:    MediaBundle{BundleVersion: VersionV1},
+	}
+	var list strings.Builder
+	list.WriteString("# Cold media tier\n\n")
+	var ids []string
+	for i, it := range doc.Media.Items {
+		if int64(len(it.Data)) <= threshold {
+			hotDoc.Media.Items[i] = it
+			continue
+		}
+		sum := it.computedSHA256()
+		if it.SHA256 != ([32]byte{}) && cfg.verifyHashes && subtle.ConstantTimeCompare(sum[:], it.SHA256[:]) != 1 {
+			return fmt.Errorf("%w: media item %q SHA256 mismatch", ErrValidation, it.ID)
+		}
+		it.SHA256 = sum
+		coldDoc.Media.Items = append(coldDoc.Media.Items, it)
+		ids = append(ids, it.ID)
+		fmt.Fprintf(&list, "- [%s](%s%s)\n", it.ID, mediaURIPrefix, it.ID)
+
+		stub := MediaItem{ID: it.ID, Path: it.Path, MIMEType: it.MIMEType, Attributes: maps.Clone(it.Attributes)}
+		(*Attributes)(&stub.Attributes).SetString(ColdSHA256Attr, hex.EncodeToString(sum[:]))
+		(*Attributes)(&stub.Attributes).SetInt(ColdSizeAttr, int64(len(it.Data)))
+		hotDoc.Media.Items[i] = stub
+	}
+
+	if len(ids) > 0 {
+		coldDoc.Markdown.Files = []MarkdownFile{{Path: ColdTierPath, Content: []byte(list.String()), MediaRefs: ids}}
+		coldOpts := append([]WriteOption{WithMediaCompression(CompNone), WithIndex(true)}, opts...)
+		if err := Encode(cold, coldDoc, append(coldOpts, noTransforms)...); err != nil {
+			return err
+		}
+	}
+	return Encode(hot, &hotDoc, append(opts[:len(opts):len(opts)], noTransforms)...)
+}
+
+// WithColdTier makes Decode reunite the stubs in a hot container written by
+// EncodeTiered with their data from cold, the matching cold container, as
+// Document.ResolveColdTier does.
+func WithColdTier(cold *Reader) ReadOption {
+	return func(c *readConfig) { c.coldTier = cold }
+}
+
+// ResolveColdTier replaces the stubs in d, media items with the
+// ColdSHA256Attr attribute, with the items of the same ID in cold, the cold
+// container written with d by EncodeTiered. Each item's data is checked
+// against the size and hash recorded in its stub, its SHA256 is set, and the
+// stub attributes are removed. It returns an error wrapping ErrNotFound if an
+// item is missing from cold and one wrapping ErrValidation if its data does
+// not match the stub. d is unchanged if an error is returned.
+func (d *Document) ResolveColdTier(cold *Reader) error {
+	resolved := make(map[int]MediaItem)
+	for i, it := range d.Media.Items {
+		sumHex, ok := it.Attributes[ColdSHA256Attr]
+		if !ok {
+			continue
+		}
+		want, err := hex.DecodeString(sumHex)
+		if err != nil || len(want) != sha256.Size {
+			return fmt.Errorf("%w: media item %q: malformed %s attribute", ErrValidation, it.ID, ColdSHA256Attr)
+		}
+		size, err := strconv.ParseInt(it.Attributes[ColdSizeAttr], 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("%w: media item %q: malformed %s attribute", ErrValidation, it.ID, ColdSizeAttr)
+		}
+		rc, err := cold.OpenMedia(it.ID)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, size+1))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		it.Data = data
+		it.SHA256 = it.computedSHA256()
+		if int64(len(data)) != size || subtle.ConstantTimeCompare(it.SHA256[:], want) != 1 {
+			return fmt.Errorf("%w: media item %q does not match its cold tier stub", ErrValidation, it.ID)
+		}
+		it.Attributes = maps.Clone(it.Attributes)
+		delete(it.Attributes, ColdSHA256Attr)
+		delete(it.Attributes, ColdSizeAttr)
+		if len(it.Attributes) == 0 {
+			it.Attributes = nil
+		}
+		resolved[i] = it
+	}
+	for i, it := range resolved {
+		d.Media.Items[i] = it
+	}
+	return nil
+}
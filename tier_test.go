@@ -0,0 +1,75 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeTiered(t *testing.T) {
+	doc := sampleDoc()
+	big := MediaItem{ID: "video", Path: "media/video.mp4", MIMEType: "video/mp4", Data: bytes.Repeat([]byte("frame"), 1000), Attributes: map[string]string{"duration": "3s"}}
+	doc.Media.Items = append(doc.Media.Items, big)
+	doc.Markdown.Files[1].Content = []byte("[clip](mdocx://media/video)\n")
+	want := sampleDoc()
+	want.Media.Items = append(want.Media.Items, big)
+	want.Markdown.Files[1].Content = doc.Markdown.Files[1].Content
+
+	var hot, cold bytes.Buffer
+	if err := EncodeTiered(&hot, &cold, doc, 100, WithChecksOnWrite(CheckAll)); err != nil {
+		t.Fatal(err)
+	}
+	if hot.Len() > 1000 {
+		t.Errorf("hot container is %d bytes", hot.Len())
+	}
+
+	// The hot container decodes on its own, with stubs.
+	stubbed, err := Decode(bytes.NewReader(hot.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := stubbed.Media.Items[1]
+	if len(stub.Data) != 0 || stub.Attributes[ColdSizeAttr] != "5000" || stub.Attributes["duration"] != "3s" {
+		t.Fatalf("stub = %+v", stub)
+	}
+
+	r, err := NewReader(bytes.NewReader(cold.Bytes()), int64(cold.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := r.Media(); len(info) != 1 || info[0].ID != "video" {
+		t.Fatalf("cold media = %+v", info)
+	}
+	got, err := Decode(bytes.NewReader(hot.Bytes()), WithColdTier(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range want.Media.Items {
+		want.Media.Items[i].SHA256 = want.Media.Items[i].computedSHA256()
+	}
+	if !reflect.DeepEqual(got.Media, want.Media) {
+		t.Fatalf("media = %+v\nwant %+v", got.Media, want.Media)
+	}
+	if !reflect.DeepEqual(doc.Media.Items[1], big) {
+		t.Error("EncodeTiered modified doc")
+	}
+
+	// A stub whose data does not match is rejected and left in place.
+	stubbed.Media.Items[1].Attributes[ColdSizeAttr] = "4999"
+	if err := stubbed.ResolveColdTier(r); !errors.Is(err, ErrValidation) || len(stubbed.Media.Items[1].Data) != 0 {
+		t.Fatalf("mismatched stub: %v", err)
+	}
+	stubbed.Media.Items[1].ID = "other"
+	stubbed.Media.Items[1].Attributes[ColdSizeAttr] = "5000"
+	if err := stubbed.ResolveColdTier(r); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing item: %v", err)
+	}
+
+	// Nothing goes to the cold container when everything is small.
+	hot.Reset()
+	cold.Reset()
+	if err := EncodeTiered(&hot, &cold, sampleDoc(), 100); err != nil || cold.Len() != 0 {
+		t.Fatalf("all hot: %d cold bytes, %v", cold.Len(), err)
+	}
+}
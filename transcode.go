@@ -0,0 +1,81 @@
+package mdocx
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Transcode copies the MDOCX container read from r to w, re-compressing each
+// section with the compression selected by WithMarkdownCompression and
+// WithMediaCompression (CompZSTD by default).
+//
+// Sections are decompressed and recompressed as opaque gob payloads: bundles are
+// not gob-decoded and the document is not validated, which makes bulk migrations
+// much faster than Decode followed by Encode. Sections that already use the
+// requested compression are copied unchanged. The fixed header and metadata are
+// copied verbatim, except that HeaderFlagIndex and HeaderFlagChecksum are
+// cleared: bytes after the Media section, such as a footer index or integrity
+// trailer, are not copied.
+//
+// Limits set with WithWriteLimits bound the section sizes read from r, as they
+// would for Decode. WithOutputSHA256 is honored; other write options are ignored.
+func Transcode(r io.Reader, w io.Writer, opts ...WriteOption) (err error) {
+	cfg := newWriteConfig(opts)
+	w, done := cfg.outputWriter(context.Background(), w)
+	defer func() { done(err) }()
+
+	h, err := readFixedHeader(r)
+	if err != nil {
+		return err
+	}
+	if err := checkFixedHeader(h, cfg.limits); err != nil {
+		return err
+	}
+	metadata := make([]byte, h.MetadataLength)
+	if _, err := io.ReadFull(r, metadata); err != nil {
+		return err
+	}
+	h.HeaderFlags &^= HeaderFlagIndex | HeaderFlagChecksum
+	if err := writeFixedHeader(w, h); err != nil {
+		return err
+	}
+	if _, err := w.Write(metadata); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		typ    SectionType
+		maxGob uint64
+		compTo Compression
+		name   string
+	}{
+		{SectionMarkdown, cfg.limits.MaxMarkdownUncompressed, cfg.mdCompression, "markdown"},
+		{SectionMedia, cfg.limits.MaxMediaUncompressed, cfg.mediaCompression, "media"},
+	}
+	for _, s := range sections {
+		sh, payload, err := readSection(r, s.typ, cfg.limits, false)
+		if err != nil {
+			return err
+		}
+		if len(payload) > 0 && sh.compression() != s.compTo {
+			raw, err := decompressPayload(sh.compression(), sh.SectionFlags, payload, s.maxGob)
+			if err != nil {
+				return fmt.Errorf("%s section: %w", s.name, err)
+			}
+			keep := sh.SectionFlags & (sectionFlagItemCompression | sectionFlagDuplicateStubs)
+			if sh.SectionFlags, payload, err = compressPayload(s.compTo, raw, cfg.sectionCodecs(s.typ)); err != nil {
+				return err
+			}
+			sh.SectionFlags |= keep
+			sh.PayloadLen = uint64(len(payload))
+		}
+		if err := writeSectionHeader(w, sh); err != nil {
+			return err
+		}
+		if _, err := w.Write(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
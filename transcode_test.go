@@ -0,0 +1,63 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTranscode(t *testing.T) {
+	var src bytes.Buffer
+	if err := Encode(&src, sampleDoc(), WithMarkdownCompression(CompNone), WithMediaCompression(CompLZ4)); err != nil {
+		t.Fatal(err)
+	}
+	want, err := Decode(bytes.NewReader(src.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, comp := range []Compression{CompNone, CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ} {
+		var dst bytes.Buffer
+		if err := Transcode(bytes.NewReader(src.Bytes()), &dst, WithMarkdownCompression(comp), WithMediaCompression(comp)); err != nil {
+			t.Fatalf("%s: %v", compressionName(comp), err)
+		}
+		mdSec, err := readSectionHeader(bytes.NewReader(dst.Bytes()[fixedHeaderSizeV1+binaryMetadataLen(t, dst.Bytes()):]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mdSec.compression() != comp {
+			t.Fatalf("%s: markdown compression is %d", compressionName(comp), mdSec.compression())
+		}
+		got, err := Decode(bytes.NewReader(dst.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: decode: %v", compressionName(comp), err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: document changed", compressionName(comp))
+		}
+	}
+}
+
+func TestTranscodeErrors(t *testing.T) {
+	if err := Transcode(bytes.NewReader([]byte("not an mdocx file at all, clearly")), &bytes.Buffer{}); !errors.Is(err, ErrInvalidMagic) {
+		t.Fatalf("expected ErrInvalidMagic, got %v", err)
+	}
+	var src bytes.Buffer
+	if err := Encode(&src, sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	err := Transcode(bytes.NewReader(src.Bytes()), &bytes.Buffer{}, WithMarkdownCompression(CompNone), WithWriteLimits(Limits{MaxMarkdownUncompressed: 1}))
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+// binaryMetadataLen returns the metadata length recorded in an encoded header.
+func binaryMetadataLen(t *testing.T, b []byte) uint32 {
+	t.Helper()
+	h, err := readFixedHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.MetadataLength
+}
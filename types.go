@@ -4,13 +4,30 @@ import "crypto/sha256"
 
 // Version constants for the MDOCX format.
 const (
-	// VersionV1 is the current and only supported MDOCX format version.
+	// VersionV1 is the MDOCX format version written by default, and the
+	// version of the Markdown and Media bundles of every container.
 	VersionV1 uint16 = 1
+	// VersionV2 is the format version that lists the sections in a table
+	// after the metadata (see WithFormatVersion).
+	VersionV2 uint16 = 2
 
 	// fixedHeaderSizeV1 is the size in bytes of the fixed header for v1 files.
 	fixedHeaderSizeV1 uint32 = 32
 )
 
+// File type identification constants (see rfc.md §13).
+const (
+	// FileExtension is the filename extension of MDOCX containers.
+	FileExtension = ".mdocx"
+	// MediaType is the preferred content type of MDOCX containers.
+	MediaType = "application/vnd.mdocx"
+	// MediaTypeFallback is the unregistered content type some systems use
+	// instead of MediaType.
+	MediaTypeFallback = "application/x-mdocx"
+	// TypeIdentifier is the Apple Uniform Type Identifier of MDOCX containers.
+	TypeIdentifier = "com.logicossoftware.mdocx"
+)
+
 // Magic is the 8-byte MDOCX file signature.
 // It consists of "MDOCX\r\n" followed by 0x1A (SUB character).
 // The magic bytes are designed to detect common file transfer issues:
@@ -23,6 +40,20 @@ const (
 	// HeaderFlagMetadataJSON indicates that the metadata block contains UTF-8 JSON.
 	// This flag MUST be set when metadata is present.
 	HeaderFlagMetadataJSON uint16 = 0x0001
+	// HeaderFlagNoMedia indicates that the container intentionally has no media
+	// bundle. The Media section is still present but its payload MUST be empty.
+	// Without this flag, an empty Media payload is ambiguous and readers treat it
+	// as an empty bundle.
+	HeaderFlagNoMedia uint16 = 0x0002
+	// HeaderFlagIndex indicates that a footer index (see WithIndex) follows the
+	// Media section. Readers that do not use the index may ignore it.
+	HeaderFlagIndex uint16 = 0x0004
+	// HeaderFlagChecksum indicates that the container ends with an integrity
+	// trailer (see WithChecksum).
+	HeaderFlagChecksum uint16 = 0x0008
+	// HeaderFlagCBOR indicates that the bundles and the footer index are
+	// serialized as CBOR rather than gob (see WithPayloadFormat).
+	HeaderFlagCBOR uint16 = 0x0010
 )
 
 // SectionType identifies the type of a section in an MDOCX file.
@@ -34,6 +65,13 @@ const (
 	SectionMarkdown SectionType = 1
 	// SectionMedia identifies the Media bundle section (must appear second).
 	SectionMedia SectionType = 2
+	// SectionIndex identifies the footer index section, which follows the
+	// Media section when HeaderFlagIndex is set.
+	SectionIndex SectionType = 3
+	// SectionPrivateUse is the first of the section types, up to 0xFFFF,
+	// reserved for application-defined sections of version 2 containers (see
+	// ExtraSection). This specification will not assign them.
+	SectionPrivateUse SectionType = 0xF000
 )
 
 // Compression identifies the compression algorithm used for a section payload.
@@ -52,6 +90,9 @@ const (
 	CompLZ4 Compression = 0x3
 	// CompBR indicates Brotli compression (prioritizes ratio over speed).
 	CompBR Compression = 0x4
+	// CompXZ indicates XZ (LZMA2) compression (maximum ratio at the highest
+	// CPU cost, for archival bundles).
+	CompXZ Compression = 0x5
 )
 
 // Internal section flag masks.
@@ -60,6 +101,12 @@ const (
 	sectionFlagCompressionMask uint16 = 0x000F
 	// sectionFlagHasUncompressedLen indicates the payload has an 8-byte uncompressed length prefix.
 	sectionFlagHasUncompressedLen uint16 = 0x0010
+	// sectionFlagItemCompression marks a Media section whose items may be
+	// compressed one at a time (see WithSmartMediaCompression).
+	sectionFlagItemCompression uint16 = 0x0020
+	// sectionFlagDuplicateStubs marks a Media section with stubs written by
+	// DuplicatesDedupe (see WithDuplicateMedia).
+	sectionFlagDuplicateStubs uint16 = 0x0040
 )
 
 // MarkdownBundle contains one or more Markdown files.
@@ -117,6 +164,23 @@ type MediaItem struct {
 	Attributes map[string]string
 }
 
+// MediaInfo describes a media item without its data.
+// It is returned by APIs that list media without loading item contents.
+type MediaInfo struct {
+	// ID is the media item's unique identifier.
+	ID string
+	// Path is the optional container path of the item.
+	Path string
+	// MIMEType is the declared media type.
+	MIMEType string
+	// Size is the length of the item's data in bytes.
+	Size int64
+	// SHA256 is the stored hash of the item's data (zero if not stored).
+	SHA256 [32]byte
+	// Attributes holds the item's key-value metadata.
+	Attributes map[string]string
+}
+
 // computedSHA256 returns the SHA-256 hash of the media item's data.
 func (m MediaItem) computedSHA256() [32]byte {
 	return sha256.Sum256(m.Data)
@@ -142,4 +206,14 @@ type Document struct {
 	// Media contains the media items bundle.
 	// BundleVersion must be set to VersionV1. Items may be empty.
 	Media MediaBundle
+	// NoMedia records that the container has no media bundle at all, as opposed
+	// to an encoded empty one. Decode sets it when HeaderFlagNoMedia is present,
+	// in which case Media is an empty VersionV1 bundle. When set, Encode writes
+	// an empty Media payload with HeaderFlagNoMedia; Media.Items must be empty and
+	// Media.BundleVersion is not checked.
+	NoMedia bool
+	// ExtraSections holds the application-defined sections of the container,
+	// in file order. Decode sets it from the private use sections of a version
+	// 2 container, and Encode writes it back (see WithExtraSection).
+	ExtraSections []ExtraSection
 }
@@ -0,0 +1,36 @@
+package mdocx
+
+import "io"
+
+// WithIgnoreUnknownSections makes Decode, DecodeContext, DecodeInto, and
+// DecodeHeader skip sections of types they do not know in version 1
+// containers, wherever a section header is expected: before the Markdown
+// section, between it and the Media section, and before the footer index.
+// Containers written by newer writers that add sections can then still be
+// read, as long as the sections they add are ones a reader may do without.
+// Skipped sections are checked against MaxMediaSectionLen. Unknown sections
+// after the last section are never read, except that in a container with an
+// integrity trailer they make the trailer check fail. Version 2 containers
+// skip unknown sections regardless, and still reject unknown critical ones.
+// Default is false: an unknown section is rejected with ErrInvalidSection.
+func WithIgnoreUnknownSections(v bool) ReadOption {
+	return func(c *readConfig) { c.ignoreUnknown = v }
+}
+
+// nextSectionHeader reads the next section header from r. If skipUnknown is
+// set, sections of types other than Markdown, Media, and Index are skipped.
+func nextSectionHeader(r io.Reader, limits Limits, skipUnknown bool) (sectionHeaderV1, error) {
+	for {
+		sh, err := readSectionHeader(r)
+		if err != nil || !skipUnknown {
+			return sh, err
+		}
+		switch SectionType(sh.SectionType) {
+		case SectionMarkdown, SectionMedia, SectionIndex:
+			return sh, nil
+		}
+		if err := skipSection(r, sh, limits); err != nil {
+			return sh, err
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestIgnoreUnknownSections(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, sampleDoc(), WithIndex(true), WithChecksum(true)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	head, md, media := splitSections(t, data)
+	index := data[len(head)+len(md)+len(media) : len(data)-checksumTrailerSize]
+	var unknown bytes.Buffer
+	writeSectionHeader(&unknown, sectionHeaderV1{SectionType: 7, SectionFlags: 0xffff, PayloadLen: 5})
+	unknown.WriteString("extra")
+
+	// Unknown sections wherever a section header is expected, with the
+	// trailer recomputed.
+	sum := newChecksumWriter(io.Discard)
+	var newer bytes.Buffer
+	for _, part := range [][]byte{head, unknown.Bytes(), md, unknown.Bytes(), media, unknown.Bytes(), index} {
+		newer.Write(part)
+		sum.Write(part)
+	}
+	sum.w = &newer
+	if err := sum.writeTrailer(); err != nil {
+		t.Fatal(err)
+	}
+	data = newer.Bytes()
+
+	if _, err := Decode(bytes.NewReader(data)); !errors.Is(err, ErrInvalidSection) {
+		t.Fatalf("strict: %v", err)
+	}
+	for _, streaming := range []bool{false, true} {
+		doc, err := Decode(bytes.NewReader(data), WithIgnoreUnknownSections(true), WithStreamingInput(streaming))
+		if err != nil {
+			t.Fatalf("streaming %v: %v", streaming, err)
+		}
+		if len(doc.Markdown.Files) != 2 || len(doc.Media.Items) != 1 {
+			t.Fatalf("streaming %v: decoded %+v", streaming, doc)
+		}
+	}
+	var items int
+	if err := DecodeInto(bytes.NewReader(data), SinkFuncs{OnMediaItem: func(MediaItem) error { items++; return nil }}, WithIgnoreUnknownSections(true)); err != nil || items != 1 {
+		t.Fatalf("DecodeInto: %v, %d items", err, items)
+	}
+	if h, err := DecodeHeader(bytes.NewReader(data), WithIgnoreUnknownSections(true)); err != nil || h.Media.PayloadLen != uint64(len(media)-16) {
+		t.Fatalf("DecodeHeader = %+v, %v", h, err)
+	}
+
+	// Skipped sections are still held to the section length limit.
+	limits := Limits{MaxMediaSectionLen: 4}
+	if _, err := Decode(bytes.NewReader(data), WithIgnoreUnknownSections(true), WithReadLimits(limits)); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("over limit: %v", err)
+	}
+	cut := bytes.Join([][]byte{head, unknown.Bytes()[:10]}, nil)
+	cut[10] &^= byte(HeaderFlagChecksum)
+	if _, err := Decode(bytes.NewReader(cut), WithIgnoreUnknownSections(true)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("truncated: %v", err)
+	}
+}
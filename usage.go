@@ -0,0 +1,131 @@
+package mdocx
+
+import (
+	"net/url"
+	"strings"
+)
+
+// mediaURIPrefix is the recommended URI prefix for referencing media by ID.
+const mediaURIPrefix = "mdocx://media/"
+
+// MediaUse records a single reference to a media item from a Markdown file.
+type MediaUse struct {
+	// File is the container path of the referencing Markdown file.
+	File string
+	// Line and Column give the 1-based position of the reference in File.
+	// Both are zero for references declared only in MarkdownFile.MediaRefs.
+	Line   int
+	Column int
+	// Declared reports whether the reference comes from MarkdownFile.MediaRefs
+	// rather than from the Markdown content.
+	Declared bool
+}
+
+// MediaUsageReport maps media items to the Markdown files that reference them.
+type MediaUsageReport struct {
+	// Uses maps every media ID in the document to its references, in file order.
+	// Items that are never referenced map to an empty slice.
+	Uses map[string][]MediaUse
+	// Unused lists the IDs of media items with no references, in bundle order.
+	Unused []string
+}
+
+// MediaUsage reports where each media item is referenced from the Markdown files.
+//
+// A media item is referenced by:
+//   - an mdocx://media/<ID> link, image, or HTML src/href attribute
+//   - a relative link whose resolved container path equals the item's Path
+//   - an entry in MarkdownFile.MediaRefs (reported with Declared set)
+//
+// References to unknown IDs or paths are ignored; use CheckLinks to find them.
+func (d *Document) MediaUsage() *MediaUsageReport {
+	rep := &MediaUsageReport{Uses: make(map[string][]MediaUse, len(d.Media.Items))}
+	byPath := make(map[string]string, len(d.Media.Items))
+	for _, it := range d.Media.Items {
+		rep.Uses[it.ID] = []MediaUse{}
+		if it.Path != "" {
+			byPath[it.Path] = it.ID
+		}
+	}
+	for _, f := range d.Markdown.Files {
+		for _, l := range d.scanFile(f).Links {
+			id, ok := resolveMediaLink(f.Path, l.Dest, byPath)
+			if !ok {
+				continue
+			}
+			if _, known := rep.Uses[id]; known {
+				rep.Uses[id] = append(rep.Uses[id], MediaUse{File: f.Path, Line: l.Line, Column: l.Column})
+			}
+		}
+		for _, id := range f.MediaRefs {
+			if _, known := rep.Uses[id]; known {
+				rep.Uses[id] = append(rep.Uses[id], MediaUse{File: f.Path, Declared: true})
+			}
+		}
+	}
+	for _, it := range d.Media.Items {
+		if len(rep.Uses[it.ID]) == 0 {
+			rep.Unused = append(rep.Unused, it.ID)
+		}
+	}
+	return rep
+}
+
+// mediaIDFromURI returns the media ID of an mdocx://media/<ID> URI.
+func mediaIDFromURI(dest string) (string, bool) {
+	if !strings.HasPrefix(dest, mediaURIPrefix) {
+		return "", false
+	}
+	id := strings.TrimPrefix(dest, mediaURIPrefix)
+	if i := strings.IndexAny(id, "?#"); i >= 0 {
+		id = id[:i]
+	}
+	if u, err := url.PathUnescape(id); err == nil {
+		id = u
+	}
+	return id, id != ""
+}
+
+// resolveMediaLink resolves a link destination found in the Markdown file at from
+// to a media ID, either through an mdocx://media/ URI or through byPath, which maps
+// media container paths to IDs. The returned ID is not checked for existence when
+// it comes from a URI.
+func resolveMediaLink(from, dest string, byPath map[string]string) (string, bool) {
+	dest = strings.TrimSpace(dest)
+	if id, ok := mediaIDFromURI(dest); ok {
+		return id, true
+	}
+	target, _, ok := splitRelativeLink(dest)
+	if !ok || target == "" {
+		return "", false
+	}
+	resolved, err := resolveContainerLink(from, target)
+	if err != nil {
+		return "", false
+	}
+	id, ok := byPath[resolved]
+	return id, ok
+}
+
+// ResolveMedia returns the media item referenced by the link destination dest
+// found in the Markdown file at container path from. dest may be an
+// mdocx://media/<ID> URI or a relative path that resolves to an item's Path.
+// It reports false if dest does not reference a media item in d.
+func (d *Document) ResolveMedia(from, dest string) (*MediaItem, bool) {
+	byPath := make(map[string]string, len(d.Media.Items))
+	for _, it := range d.Media.Items {
+		if it.Path != "" {
+			byPath[it.Path] = it.ID
+		}
+	}
+	id, ok := resolveMediaLink(from, dest, byPath)
+	if !ok {
+		return nil, false
+	}
+	for i := range d.Media.Items {
+		if d.Media.Items[i].ID == id {
+			return &d.Media.Items[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,73 @@
+package mdocx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMediaUsage(t *testing.T) {
+	doc := sampleDoc()
+	doc.Markdown.Files[1].Content = []byte("Also ![logo](../assets/logo.png)\n<img src=\"mdocx://media/logo\">\n")
+	doc.Media.Items = append(doc.Media.Items,
+		MediaItem{ID: "unused", Path: "assets/unused.png", Data: []byte{4}},
+		MediaItem{ID: "declared", Data: []byte{5}},
+	)
+	doc.Markdown.Files[1].MediaRefs = []string{"declared", "missing"}
+
+	rep := doc.MediaUsage()
+	want := []MediaUse{
+		{File: "docs/index.md", Line: 3, Column: 1},
+		{File: "docs/index.md", Declared: true},
+		{File: "docs/notes.md", Line: 1, Column: 6},
+		{File: "docs/notes.md", Line: 2, Column: 1},
+	}
+	if !reflect.DeepEqual(rep.Uses["logo"], want) {
+		t.Fatalf("logo uses: got %+v want %+v", rep.Uses["logo"], want)
+	}
+	if got := rep.Uses["declared"]; len(got) != 1 || !got[0].Declared {
+		t.Fatalf("declared uses: %+v", got)
+	}
+	if _, ok := rep.Uses["missing"]; ok {
+		t.Fatal("unknown IDs must not be reported")
+	}
+	if !reflect.DeepEqual(rep.Unused, []string{"unused"}) {
+		t.Fatalf("unused: %v", rep.Unused)
+	}
+	if rep.Uses["unused"] == nil {
+		t.Fatal("unused items must map to an empty slice")
+	}
+}
+
+func TestMediaIDFromURI(t *testing.T) {
+	cases := []struct {
+		in   string
+		id   string
+		want bool
+	}{
+		{"mdocx://media/logo", "logo", true},
+		{"mdocx://media/a%20b#frag", "a b", true},
+		{"mdocx://media/", "", false},
+		{"assets/logo.png", "", false},
+	}
+	for _, tc := range cases {
+		id, ok := mediaIDFromURI(tc.in)
+		if id != tc.id || ok != tc.want {
+			t.Fatalf("%q: got (%q, %v)", tc.in, id, ok)
+		}
+	}
+}
+
+func TestResolveMedia(t *testing.T) {
+	doc := sampleDoc()
+	for _, dest := range []string{"../assets/logo.png", "mdocx://media/logo"} {
+		it, ok := doc.ResolveMedia("docs/index.md", dest)
+		if !ok || it.ID != "logo" {
+			t.Fatalf("%q: got (%v, %v)", dest, it, ok)
+		}
+	}
+	for _, dest := range []string{"assets/logo.png", "mdocx://media/missing", "https://example.com/logo.png"} {
+		if _, ok := doc.ResolveMedia("docs/index.md", dest); ok {
+			t.Fatalf("%q: expected no match", dest)
+		}
+	}
+}
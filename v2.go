@@ -0,0 +1,231 @@
+package mdocx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Capability bits of a version 2 fixed header (rfc.md §12.1). A writer sets
+// the bit of every feature a reader must implement to decode the container,
+// and readers reject containers with bits they do not know with
+// ErrUnsupportedVersion rather than misreading them.
+const (
+	// CapabilityCBOR means the bundles are serialized as CBOR
+	// (HeaderFlagCBOR).
+	CapabilityCBOR uint64 = 1 << 0
+	// CapabilityItemCompression means the media items are compressed one by
+	// one (WithSmartMediaCompression).
+	CapabilityItemCompression uint64 = 1 << 1
+	// CapabilityDuplicateStubs means some media items are stubs that share
+	// the data of another item (DuplicatesDedupe).
+	CapabilityDuplicateStubs uint64 = 1 << 2
+
+	knownCapabilities = CapabilityCBOR | CapabilityItemCompression | CapabilityDuplicateStubs
+)
+
+// sectionFlagCritical marks a version 2 section that readers must not skip:
+// a reader that does not know the section's type rejects the container.
+const sectionFlagCritical uint16 = 0x8000
+
+// maxSectionsV2 bounds the section count of a version 2 container.
+const maxSectionsV2 = 1024
+
+// WithFormatVersion selects the container format version Encode writes:
+// VersionV1, the default, or VersionV2, which lists the sections in a table
+// after the metadata so that readers can skip sections they do not know (see
+// rfc.md §12.1). Version 2 containers cannot have a footer index, and
+// EncodeStream and Writer write version 1 only; both are reported as
+// ErrValidation. Decode, DecodeContext, DecodeInto, and DecodeHeader read
+// both versions; the other readers return ErrUnsupportedVersion for version 2.
+func WithFormatVersion(v uint16) WriteOption {
+	return func(c *writeConfig) { c.version = v }
+}
+
+// formatVersion returns the format version Encode writes, VersionV2 if there
+// are extra sections and no version was chosen, or an error wrapping
+// ErrValidation if it cannot write the container that way.
+func (c writeConfig) formatVersion(extras int) (uint16, error) {
+	v := c.version
+	switch {
+	case v == 0 && extras > 0:
+		v = VersionV2
+	case v == 0:
+		v = VersionV1
+	case v != VersionV1 && v != VersionV2:
+		return 0, fmt.Errorf("%w: unknown format version %d", ErrValidation, c.version)
+	}
+	if v == VersionV1 && extras > 0 {
+		return 0, fmt.Errorf("%w: extra sections need version 2", ErrValidation)
+	}
+	if v == VersionV2 && c.index {
+		return 0, fmt.Errorf("%w: version 2 containers cannot have a footer index", ErrValidation)
+	}
+	return v, nil
+}
+
+// capabilities returns the capability bits of a version 2 container made of p.
+func (p *encodedParts) capabilities() uint64 {
+	var caps uint64
+	if p.headerFlags&HeaderFlagCBOR != 0 {
+		caps |= CapabilityCBOR
+	}
+	if p.mediaFlags&sectionFlagItemCompression != 0 {
+		caps |= CapabilityItemCompression
+	}
+	if p.mediaFlags&sectionFlagDuplicateStubs != 0 {
+		caps |= CapabilityDuplicateStubs
+	}
+	return caps
+}
+
+// checkFixedHeaderV2 is checkFixedHeader for readers that also read version 2
+// containers. In version 2, Reserved0 holds the section count and Reserved1
+// the capability bits.
+func checkFixedHeaderV2(h fixedHeaderV1, limits Limits) error {
+	if h.Version != VersionV2 || h.Magic != Magic || h.FixedHdrSize != fixedHeaderSizeV1 {
+		return checkFixedHeader(h, limits)
+	}
+	if unknown := h.Reserved1 &^ knownCapabilities; unknown != 0 {
+		return fmt.Errorf("%w: unknown capabilities %#x", ErrUnsupportedVersion, unknown)
+	}
+	if h.Reserved0 < 2 || h.Reserved0 > maxSectionsV2 {
+		return fmt.Errorf("%w: section count %d", ErrInvalidHeader, h.Reserved0)
+	}
+	if h.HeaderFlags&HeaderFlagIndex != 0 {
+		return fmt.Errorf("%w: INDEX flag set in version 2", ErrInvalidHeader)
+	}
+	if h.MetadataLength > limits.MaxMetadataLen {
+		return exceeds("MaxMetadataLen", "metadata length %d", h.MetadataLength)
+	}
+	return nil
+}
+
+// readSectionTable reads the section table of a version 2 container whose
+// fixed header h and metadata have been read from r. It checks that the
+// Markdown and Media sections each appear once and that every section can be
+// read or skipped.
+func readSectionTable(r io.Reader, h fixedHeaderV1) ([]sectionHeaderV1, error) {
+	table := make([]sectionHeaderV1, 0, min(h.Reserved0, 16))
+	var haveMD, haveMedia bool
+	for range h.Reserved0 {
+		sh, err := readSectionHeader(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch typ := SectionType(sh.SectionType); {
+		case typ == SectionMarkdown && !haveMD:
+			haveMD = true
+		case typ == SectionMedia && !haveMedia:
+			haveMedia = true
+		case typ == SectionMarkdown || typ == SectionMedia:
+			return nil, fmt.Errorf("%w: duplicate section type %d", ErrInvalidSection, typ)
+		case !sectionKnownV2(typ) && sh.SectionFlags&sectionFlagCritical != 0:
+			return nil, fmt.Errorf("%w: unknown critical section type %d", ErrInvalidSection, typ)
+		case sh.Reserved != 0:
+			return nil, fmt.Errorf("%w: reserved must be 0", ErrInvalidSection)
+		}
+		table = append(table, sh)
+	}
+	if !haveMD || !haveMedia {
+		return nil, fmt.Errorf("%w: section table lacks the Markdown or Media section", ErrInvalidSection)
+	}
+	return table, nil
+}
+
+// sectionKnownV2 reports whether typ is a section type this package reads in
+// version 2 containers.
+func sectionKnownV2(typ SectionType) bool {
+	return typ == SectionMarkdown || typ == SectionMedia
+}
+
+// readSectionsV2 reads the section table of a version 2 container from r and
+// then the payloads of its sections, in table order, checking them against the
+// section limits. Private use sections are appended to extras, unless it is
+// nil; sections of other types are skipped.
+func readSectionsV2(r io.Reader, h fixedHeaderV1, limits Limits, extras *[]ExtraSection) (mdSec sectionHeaderV1, mdPayload []byte, mediaSec sectionHeaderV1, mediaPayload []byte, err error) {
+	table, err := readSectionTable(r, h)
+	if err != nil {
+		return mdSec, nil, mediaSec, nil, err
+	}
+	for _, sh := range table {
+		switch typ := SectionType(sh.SectionType); typ {
+		case SectionMarkdown:
+			mdSec = sh
+			if mdPayload, err = readSectionPayload(r, sh, typ, limits); err != nil {
+				return mdSec, nil, mediaSec, nil, unexpectedEOF(err)
+			}
+		case SectionMedia:
+			mediaSec = sh
+			if mediaPayload, err = readSectionPayload(r, sh, typ, limits); err != nil {
+				return mdSec, nil, mediaSec, nil, unexpectedEOF(err)
+			}
+		default:
+			if extras != nil && typ >= SectionPrivateUse {
+				payload, err := readExtraSection(r, sh, limits)
+				if err != nil {
+					return mdSec, nil, mediaSec, nil, err
+				}
+				*extras = append(*extras, ExtraSection{Type: sh.SectionType, Payload: payload})
+				continue
+			}
+			if err := skipSection(r, sh, limits); err != nil {
+				return mdSec, nil, mediaSec, nil, err
+			}
+		}
+	}
+	return mdSec, mdPayload, mediaSec, mediaPayload, nil
+}
+
+// skipSection discards the payload of a section this package does not read,
+// after checking its length against MaxMediaSectionLen.
+func skipSection(r io.Reader, sh sectionHeaderV1, limits Limits) error {
+	if sh.PayloadLen > limits.MaxMediaSectionLen {
+		return exceeds("MaxMediaSectionLen", "section %d too large", sh.SectionType)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(sh.PayloadLen)); err != nil {
+		return unexpectedEOF(err)
+	}
+	return nil
+}
+
+// readExtraSection reads the payload of a private use section, growing memory
+// with the bytes received as readSectionPayload does.
+func readExtraSection(r io.Reader, sh sectionHeaderV1, limits Limits) ([]byte, error) {
+	if sh.PayloadLen > limits.MaxMediaSectionLen {
+		return nil, exceeds("MaxMediaSectionLen", "section %d too large", sh.SectionType)
+	}
+	var buf bytes.Buffer
+	buf.Grow(int(min(sh.PayloadLen, payloadReadChunk)))
+	if _, err := io.CopyN(&buf, r, int64(sh.PayloadLen)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sectionLimit returns the PayloadLen limit of a section of type typ and the
+// limit's name.
+func sectionLimit(typ SectionType, limits Limits) (uint64, string) {
+	if typ == SectionMarkdown {
+		return limits.MaxMarkdownSectionLen, "MaxMarkdownSectionLen"
+	}
+	return limits.MaxMediaSectionLen, "MaxMediaSectionLen"
+}
+
+// writeSectionTable writes the fixed header, metadata, and section table of a
+// version 2 container whose sections are described by table.
+func writeSectionTable(w io.Writer, h fixedHeaderV1, metadata []byte, table []sectionHeaderV1) error {
+	h.Version, h.Reserved0 = VersionV2, uint32(len(table))
+	if err := writeFixedHeader(w, h); err != nil {
+		return err
+	}
+	if _, err := w.Write(metadata); err != nil {
+		return err
+	}
+	for _, sh := range table {
+		if err := writeSectionHeader(w, sh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package mdocx
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// insertSection returns the version 2 container data with a section of
+// header sh and payload added at position i of its section table.
+func insertSection(t *testing.T, data []byte, i int, sh sectionHeaderV1, payload []byte) []byte {
+	t.Helper()
+	h, err := readFixedHeader(bytes.NewReader(data))
+	if err != nil || h.Version != VersionV2 {
+		t.Fatalf("not a version 2 container: %v", err)
+	}
+	tableOff := int(fixedHeaderSizeV1) + int(h.MetadataLength)
+	payloadOff := tableOff + 16*int(h.Reserved0)
+	for j := range i {
+		payloadOff += int(binary.LittleEndian.Uint64(data[tableOff+16*j+4:]))
+	}
+	sh.PayloadLen = uint64(len(payload))
+	var entry bytes.Buffer
+	writeSectionHeader(&entry, sh)
+
+	var out bytes.Buffer
+	h.Reserved0++
+	writeFixedHeader(&out, h)
+	out.Write(data[fixedHeaderSizeV1 : tableOff+16*i])
+	out.Write(entry.Bytes())
+	out.Write(data[tableOff+16*i : payloadOff])
+	out.Write(payload)
+	out.Write(data[payloadOff:])
+	return out.Bytes()
+}
+
+func TestFormatVersion2(t *testing.T) {
+	doc := sampleDoc()
+	opts := []WriteOption{WithFormatVersion(VersionV2), WithPayloadFormat(PayloadCBOR), WithSmartMediaCompression(true), WithChecksum(true)}
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, opts...); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	h, err := DecodeHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Version != VersionV2 || h.Capabilities != CapabilityCBOR|CapabilityItemCompression || h.Metadata["title"] != "Example" {
+		t.Fatalf("DecodeHeader = %+v", h)
+	}
+	if h.Markdown.UncompressedLen == 0 || h.Media.PayloadLen == 0 {
+		t.Fatalf("sections = %+v, %+v", h.Markdown, h.Media)
+	}
+	if got := 32 + uint64(h.MetadataLength) + 2*16 + h.Markdown.PayloadLen + h.Media.PayloadLen + checksumTrailerSize; got != uint64(len(data)) {
+		t.Fatalf("sections add up to %d bytes, want %d", got, len(data))
+	}
+
+	for _, streaming := range []bool{false, true} {
+		got, err := Decode(bytes.NewReader(data), WithStreamingInput(streaming))
+		if err != nil {
+			t.Fatalf("streaming %v: %v", streaming, err)
+		}
+		if !reflect.DeepEqual(got.Markdown, doc.Markdown) || !reflect.DeepEqual(got.Media, doc.Media) {
+			t.Fatalf("streaming %v: Decode = %+v", streaming, got)
+		}
+	}
+	var items int
+	if err := DecodeInto(bytes.NewReader(data), SinkFuncs{OnMediaItem: func(MediaItem) error { items++; return nil }}); err != nil || items != 1 {
+		t.Fatalf("DecodeInto: %d items, %v", items, err)
+	}
+
+	// A damaged byte is caught by the trailer.
+	bad := bytes.Clone(data)
+	bad[len(bad)-20] ^= 0xff
+	if _, err := Decode(bytes.NewReader(bad)); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("damaged: %v", err)
+	}
+
+	// Random-access readers do not read version 2.
+	if _, err := NewReader(bytes.NewReader(data), int64(len(data))); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("NewReader: %v", err)
+	}
+}
+
+func TestFormatVersion2UnknownSections(t *testing.T) {
+	doc := sampleDoc()
+	var buf bytes.Buffer
+	if err := Encode(&buf, doc, WithFormatVersion(VersionV2)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// Unknown sections before, between, and after the bundles are skipped.
+	for i := range 3 {
+		extended := insertSection(t, data, i, sectionHeaderV1{SectionType: 0x100}, []byte("opaque"))
+		got, err := Decode(bytes.NewReader(extended))
+		if err != nil || !reflect.DeepEqual(got.Media, doc.Media) {
+			t.Fatalf("at %d: Decode = %v", i, err)
+		}
+		if err := DecodeInto(bytes.NewReader(extended), SinkFuncs{}); err != nil {
+			t.Fatalf("at %d: DecodeInto = %v", i, err)
+		}
+		h, err := DecodeHeader(io.MultiReader(bytes.NewReader(extended)))
+		if err != nil || h.Media.PayloadLen == 0 {
+			t.Fatalf("at %d: DecodeHeader = %+v, %v", i, h, err)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		data []byte
+		want error
+	}{
+		"critical":   {insertSection(t, data, 2, sectionHeaderV1{SectionType: 0x100, SectionFlags: sectionFlagCritical}, nil), ErrInvalidSection},
+		"duplicate":  {insertSection(t, data, 2, sectionHeaderV1{SectionType: uint16(SectionMarkdown)}, nil), ErrInvalidSection},
+		"reserved":   {insertSection(t, data, 2, sectionHeaderV1{SectionType: 0x100, Reserved: 1}, nil), ErrInvalidSection},
+		"capability": {func() []byte { b := bytes.Clone(data); b[24] |= 0x80; return b }(), ErrUnsupportedVersion},
+		"count":      {func() []byte { b := bytes.Clone(data); b[20] = 1; return b }(), ErrInvalidHeader},
+		"version 3":  {func() []byte { b := bytes.Clone(data); b[8] = 3; return b }(), ErrUnsupportedVersion},
+	} {
+		if _, err := Decode(bytes.NewReader(tc.data)); !errors.Is(err, tc.want) {
+			t.Errorf("%s: Decode = %v", name, err)
+		}
+		if _, err := DecodeHeader(bytes.NewReader(tc.data)); !errors.Is(err, tc.want) {
+			t.Errorf("%s: DecodeHeader = %v", name, err)
+		}
+	}
+	if _, err := Decode(bytes.NewReader(data[:len(data)-1])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("truncated: %v", err)
+	}
+}
+
+func TestFormatVersion2Rejects(t *testing.T) {
+	for name, opts := range map[string][]WriteOption{
+		"version 3": {WithFormatVersion(3)},
+		"index":     {WithFormatVersion(VersionV2), WithIndex(true)},
+	} {
+		if err := Encode(io.Discard, sampleDoc(), opts...); !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	files, media := feed(sampleDoc())
+	if err := EncodeStream(context.Background(), io.Discard, StreamHeader{}, files, media, WithFormatVersion(VersionV2)); !errors.Is(err, ErrValidation) {
+		t.Errorf("EncodeStream: %v", err)
+	}
+}
@@ -8,6 +8,61 @@ import (
 	"unicode/utf8"
 )
 
+// validateConfig holds configuration options for Validate.
+type validateConfig struct {
+	limits       Limits
+	verifyHashes bool
+	checks       Check
+	allErrors    bool
+}
+
+// ValidateOption is a functional option for configuring Validate.
+type ValidateOption func(*validateConfig)
+
+// WithValidateLimits sets the size limits Validate checks against.
+// Zero values in l will be replaced with safe defaults.
+func WithValidateLimits(l Limits) ValidateOption {
+	return func(c *validateConfig) { c.limits = l }
+}
+
+// WithValidateHashes controls whether Validate checks that non-zero
+// MediaItem.SHA256 fields match the data. Default is true.
+func WithValidateHashes(v bool) ValidateOption {
+	return func(c *validateConfig) { c.verifyHashes = v }
+}
+
+// WithValidateChecks makes Validate also enforce the given optional invariants.
+// Default is none.
+func WithValidateChecks(c Check) ValidateOption {
+	return func(cfg *validateConfig) { cfg.checks = c }
+}
+
+// Validate checks doc the way Encode does before writing it, so that a
+// document can be checked while it is being assembled. It returns an error
+// wrapping ErrValidation or ErrLimitExceeded describing the first problem, or
+// nil if doc is valid. With WithAllErrors, it returns every problem as
+// ValidationErrors instead. Unlike Encode, it never modifies doc: missing SHA256
+// hashes are left zero.
+func Validate(doc *Document, opts ...ValidateOption) error {
+	cfg := validateConfig{limits: defaultLimits(), verifyHashes: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.limits = cfg.limits.withDefaults()
+	if cfg.allErrors {
+		if errs := collectValidationErrors(doc, cfg.limits, cfg.verifyHashes); len(errs) > 0 {
+			return errs
+		}
+		if errs := doc.invariantErrors(cfg.checks); len(errs) > 0 {
+			return errs
+		}
+		return nil
+	} else if err := validateDocument(doc, cfg.limits, cfg.verifyHashes); err != nil {
+		return err
+	}
+	return doc.CheckInvariants(cfg.checks)
+}
+
 // validateDocument validates a Document against the MDOCX specification and configured limits.
 // It checks:
 //   - BundleVersion fields are VersionV1
@@ -17,75 +72,167 @@ import (
 //   - Content is valid UTF-8
 //   - Size limits are not exceeded
 //   - SHA256 hashes match (if verifyHashes is true and hashes are non-zero)
+//
+// The UTF-8 and hash checks of documents within the count limits run in
+// parallel (see checkContent).
 func validateDocument(doc *Document, limits Limits, verifyHashes bool) error {
+	var verify func(id string) bool
+	if verifyHashes {
+		verify = func(string) bool { return true }
+	}
+	return validateDocumentVerifying(doc, limits, verify)
+}
+
+// validateDocumentVerifying is validateDocument verifying the hashes of the
+// media items for whose ID verify reports true. A nil verify verifies none.
+func validateDocumentVerifying(doc *Document, limits Limits, verify func(id string) bool) error {
 	if doc == nil {
 		return fmt.Errorf("%w: document is nil", ErrValidation)
 	}
-	if doc.Markdown.BundleVersion != VersionV1 {
-		return fmt.Errorf("%w: Markdown.BundleVersion must be %d", ErrValidation, VersionV1)
+	if doc.NoMedia {
+		verify = nil
 	}
-	if len(doc.Markdown.Files) == 0 {
-		return fmt.Errorf("%w: Markdown.Files must not be empty", ErrValidation)
+	var checks contentChecks
+	if len(doc.Markdown.Files) <= limits.MaxMarkdownFiles && len(doc.Media.Items) <= limits.MaxMediaItems {
+		checks = checkContent(doc, verify)
 	}
-	if len(doc.Markdown.Files) > limits.MaxMarkdownFiles {
-		return fmt.Errorf("%w: too many markdown files", ErrLimitExceeded)
+	// One set serves both uniqueness checks, so large documents allocate it once.
+	seen := make(map[string]struct{}, max(len(doc.Markdown.Files), len(doc.Media.Items)))
+	if err := validateMarkdownBundleSeen(doc.Markdown, limits, seen, checks.utf8OK); err != nil {
+		return err
 	}
-	// Validate RootPath if set
-	if doc.Markdown.RootPath != "" {
-		if err := validateContainerPath(doc.Markdown.RootPath); err != nil {
-			return fmt.Errorf("%w: Markdown.RootPath: %v", ErrValidation, err)
-		}
-	}
-	seenPaths := make(map[string]struct{}, len(doc.Markdown.Files))
-	for i := range doc.Markdown.Files {
-		f := doc.Markdown.Files[i]
-		if err := validateContainerPath(f.Path); err != nil {
-			return fmt.Errorf("%w: markdown file %d path: %v", ErrValidation, i, err)
-		}
-		if _, ok := seenPaths[f.Path]; ok {
-			return fmt.Errorf("%w: duplicate markdown path %q", ErrValidation, f.Path)
-		}
-		seenPaths[f.Path] = struct{}{}
-		if !utf8.Valid(f.Content) {
-			return fmt.Errorf("%w: markdown file %q content is not valid UTF-8", ErrValidation, f.Path)
-		}
-		if uint64(len(f.Content)) > limits.MaxSingleMarkdownFileSize {
-			return fmt.Errorf("%w: markdown file %q too large", ErrLimitExceeded, f.Path)
+	if doc.NoMedia {
+		if len(doc.Media.Items) > 0 {
+			return fmt.Errorf("%w: NoMedia is set but Media.Items is not empty", ErrValidation)
 		}
+		return nil
 	}
 	if doc.Media.BundleVersion != VersionV1 {
 		return fmt.Errorf("%w: Media.BundleVersion must be %d", ErrValidation, VersionV1)
 	}
 	if len(doc.Media.Items) > limits.MaxMediaItems {
-		return fmt.Errorf("%w: too many media items", ErrLimitExceeded)
+		return exceeds("MaxMediaItems", "too many media items")
 	}
-	seenIDs := make(map[string]struct{}, len(doc.Media.Items))
+	clear(seen)
 	for i := range doc.Media.Items {
-		it := doc.Media.Items[i]
-		if strings.TrimSpace(it.ID) == "" {
-			return fmt.Errorf("%w: media item %d has empty ID", ErrValidation, i)
+		it := &doc.Media.Items[i]
+		if err := checkMediaItem(i, *it, limits, checks.hashOK[i]); err != nil {
+			return err
 		}
-		if _, ok := seenIDs[it.ID]; ok {
+		if _, ok := seen[it.ID]; ok {
 			return fmt.Errorf("%w: duplicate media ID %q", ErrValidation, it.ID)
 		}
-		seenIDs[it.ID] = struct{}{}
-		if it.Path != "" {
-			if err := validateContainerPath(it.Path); err != nil {
-				return fmt.Errorf("%w: media item %q path: %v", ErrValidation, it.ID, err)
-			}
+		seen[it.ID] = struct{}{}
+	}
+	return nil
+}
+
+// validateMarkdownBundle checks the version, file count, RootPath, and files of
+// a Markdown bundle.
+func validateMarkdownBundle(b MarkdownBundle, limits Limits) error {
+	return validateMarkdownBundleSeen(b, limits, make(map[string]struct{}, len(b.Files)), nil)
+}
+
+// validateMarkdownBundleSeen is validateMarkdownBundle using the empty set seen
+// to detect duplicate paths. utf8OK, if non-nil, holds the precomputed UTF-8
+// check of each file.
+func validateMarkdownBundleSeen(b MarkdownBundle, limits Limits, seen map[string]struct{}, utf8OK []bool) error {
+	if b.BundleVersion != VersionV1 {
+		return fmt.Errorf("%w: Markdown.BundleVersion must be %d", ErrValidation, VersionV1)
+	}
+	if len(b.Files) == 0 {
+		return fmt.Errorf("%w: Markdown.Files must not be empty", ErrValidation)
+	}
+	if len(b.Files) > limits.MaxMarkdownFiles {
+		return exceeds("MaxMarkdownFiles", "too many markdown files")
+	}
+	// Validate RootPath if set
+	if b.RootPath != "" {
+		if err := validateContainerPath(b.RootPath); err != nil {
+			return fmt.Errorf("%w: Markdown.RootPath: %v", ErrValidation, err)
 		}
-		if uint64(len(it.Data)) > limits.MaxSingleMediaSize {
-			return fmt.Errorf("%w: media item %q too large", ErrLimitExceeded, it.ID)
+		if err := limits.checkPath(b.RootPath); err != nil {
+			return err
 		}
-		if verifyHashes {
-			if it.SHA256 != ([32]byte{}) {
-				computed := it.computedSHA256()
-				if subtle.ConstantTimeCompare(computed[:], it.SHA256[:]) != 1 {
-					return fmt.Errorf("%w: media item %q SHA256 mismatch", ErrValidation, it.ID)
-				}
-			}
+	}
+	for i := range b.Files {
+		f := &b.Files[i]
+		var err error
+		if utf8OK != nil {
+			err = checkMarkdownFile(i, *f, limits, utf8OK[i])
+		} else {
+			err = validateMarkdownFile(i, *f, limits)
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := seen[f.Path]; ok {
+			return fmt.Errorf("%w: duplicate markdown path %q", ErrValidation, f.Path)
+		}
+		seen[f.Path] = struct{}{}
+	}
+	return nil
+}
+
+// validateMarkdownFile checks a single Markdown file (at index i) for path
+// validity, UTF-8 content, and size. Uniqueness is checked by the caller.
+func validateMarkdownFile(i int, f MarkdownFile, limits Limits) error {
+	return checkMarkdownFile(i, f, limits, utf8.Valid(f.Content))
+}
+
+// checkMarkdownFile is validateMarkdownFile with the UTF-8 check precomputed.
+func checkMarkdownFile(i int, f MarkdownFile, limits Limits, utf8OK bool) error {
+	if err := validateContainerPath(f.Path); err != nil {
+		return fmt.Errorf("%w: markdown file %d path: %v", ErrValidation, i, err)
+	}
+	if err := limits.checkPath(f.Path); err != nil {
+		return err
+	}
+	if !utf8OK {
+		return fmt.Errorf("%w: markdown file %q content is not valid UTF-8", ErrValidation, f.Path)
+	}
+	if uint64(len(f.Content)) > limits.MaxSingleMarkdownFileSize {
+		return exceeds("MaxSingleMarkdownFileSize", "markdown file %q too large", f.Path)
+	}
+	return nil
+}
+
+// validateMediaItem checks a single media item (at index i) for a non-empty ID,
+// path validity, size, and, if verifyHashes is set, a matching SHA256.
+// Uniqueness is checked by the caller.
+func validateMediaItem(i int, it MediaItem, limits Limits, verifyHashes bool) error {
+	return checkMediaItem(i, it, limits, mediaHashOK(&it, verifyHashes))
+}
+
+// mediaHashOK reports whether it's data matches its SHA256, if verifyHashes is
+// set and the hash is non-zero.
+func mediaHashOK(it *MediaItem, verifyHashes bool) bool {
+	if !verifyHashes || it.SHA256 == ([32]byte{}) {
+		return true
+	}
+	computed := it.computedSHA256()
+	return subtle.ConstantTimeCompare(computed[:], it.SHA256[:]) == 1
+}
+
+// checkMediaItem is validateMediaItem with the hash check precomputed.
+func checkMediaItem(i int, it MediaItem, limits Limits, hashOK bool) error {
+	if strings.TrimSpace(it.ID) == "" {
+		return fmt.Errorf("%w: media item %d has empty ID", ErrValidation, i)
+	}
+	if it.Path != "" {
+		if err := validateContainerPath(it.Path); err != nil {
+			return fmt.Errorf("%w: media item %q path: %v", ErrValidation, it.ID, err)
+		}
+		if err := limits.checkPath(it.Path); err != nil {
+			return err
 		}
 	}
+	if uint64(len(it.Data)) > limits.MaxSingleMediaSize {
+		return exceeds("MaxSingleMediaSize", "media item %q too large", it.ID)
+	}
+	if !hashOK {
+		return fmt.Errorf("%w: media item %q SHA256 mismatch", ErrValidation, it.ID)
+	}
 	return nil
 }
 
@@ -95,25 +242,53 @@ func validateDocument(doc *Document, limits Limits, verifyHashes bool) error {
 //   - Must use forward slashes only
 //   - Must be normalized (no "./" or "//")
 //   - Must not escape (no ".." segments)
+//
+// It does not allocate for valid paths, since it runs once per file and item.
 func validateContainerPath(p string) error {
 	if strings.TrimSpace(p) == "" {
 		return fmt.Errorf("path is empty")
 	}
-	if strings.HasPrefix(p, "/") {
+	if p[0] == '/' {
 		return fmt.Errorf("path must not be absolute")
 	}
-	if strings.Contains(p, "\\") {
+	if strings.IndexByte(p, '\\') >= 0 {
 		return fmt.Errorf("path must use forward slashes")
 	}
-	clean := path.Clean(p)
-	if clean != p {
-		return fmt.Errorf("path must be normalized: %q", clean)
+	if !isCleanPath(p) {
+		return fmt.Errorf("path must be normalized: %q", path.Clean(p))
 	}
-	if clean == "." {
+	if p == "." {
 		return fmt.Errorf("path must not be current directory")
 	}
-	if clean == ".." || strings.HasPrefix(clean, "../") {
+	if p == ".." || strings.HasPrefix(p, "../") {
 		return fmt.Errorf("path must not escape")
 	}
 	return nil
 }
+
+// isCleanPath reports whether the relative path p equals path.Clean(p): it has
+// no empty or "." segments (other than p == "."), and ".." segments only at the
+// start.
+func isCleanPath(p string) bool {
+	if p == "." {
+		return true
+	}
+	leading := true
+	for rest := p; ; {
+		seg, tail, more := strings.Cut(rest, "/")
+		switch seg {
+		case "", ".":
+			return false
+		case "..":
+			if !leading {
+				return false
+			}
+		default:
+			leading = false
+		}
+		if !more {
+			return true
+		}
+		rest = tail
+	}
+}
@@ -1,6 +1,11 @@
 package mdocx
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"path"
+	"testing"
+)
 
 func TestValidateDocument_MoreFailures(t *testing.T) {
 	l := defaultLimits()
@@ -64,3 +69,90 @@ func TestValidateDocument_MoreFailures(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateContainerPathMatchesClean(t *testing.T) {
+	paths := []string{
+		"a", "a/b.md", "a//b", "a/./b", "./a", "a/", "a/..", "a/../b", "a/../..",
+		".", "..", "../a", "../../a", "../a/./b", "..a/b", "a/..b", "a/.b/c", "...",
+	}
+	for _, p := range paths {
+		if got, want := isCleanPath(p), path.Clean(p) == p; got != want {
+			t.Errorf("isCleanPath(%q) = %v, want %v", p, got, want)
+		}
+	}
+	for p, ok := range map[string]bool{"a/b.md": true, "..a/b": true, "a//b": false, ".": false, "../a": false, "a/../..": false} {
+		if err := validateContainerPath(p); (err == nil) != ok {
+			t.Errorf("validateContainerPath(%q) = %v", p, err)
+		}
+	}
+}
+
+func TestValidateContainerPathAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = validateContainerPath("../shared/a.md"[3:])
+		_ = validateContainerPath("docs/chapters/01-introduction.md")
+	})
+	if allocs != 0 {
+		t.Fatalf("validateContainerPath allocated %v times per run", allocs)
+	}
+}
+
+// largeDoc returns a valid document with n Markdown files and n media items.
+func largeDoc(n int) *Document {
+	doc := &Document{
+		Markdown: MarkdownBundle{BundleVersion: VersionV1, Files: make([]MarkdownFile, n)},
+		Media:    MediaBundle{BundleVersion: VersionV1, Items: make([]MediaItem, n)},
+	}
+	for i := range n {
+		doc.Markdown.Files[i] = MarkdownFile{Path: fmt.Sprintf("docs/section-%03d/page-%05d.md", i%100, i), Content: []byte("# Page\n\nText.\n")}
+		doc.Media.Items[i] = MediaItem{ID: fmt.Sprintf("img-%05d", i), Path: fmt.Sprintf("assets/img-%05d.png", i), MIMEType: "image/png", Data: []byte{byte(i)}}
+	}
+	return doc
+}
+
+func BenchmarkValidateDocument(b *testing.B) {
+	doc := largeDoc(20000)
+	limits := noLimits()
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := validateDocument(doc, limits, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkValidateContainerPath(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = validateContainerPath("docs/section-042/page-04242.md")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(sampleDoc()); err != nil {
+		t.Fatal(err)
+	}
+	d := sampleDoc()
+	d.Media.Items[0].SHA256[0] = 1
+	if err := Validate(d); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected hash mismatch, got %v", err)
+	}
+	if err := Validate(d, WithValidateHashes(false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(sampleDoc(), WithValidateLimits(Limits{MaxMediaItems: 1, MaxSingleMediaSize: 2})); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+
+	d = sampleDoc()
+	d.Media.Items = append(d.Media.Items, MediaItem{ID: "a", Data: []byte{1}})
+	if err := Validate(d); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(d, WithValidateChecks(CheckMediaOrder)); !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected media order violation, got %v", err)
+	}
+	if d.Media.Items[1].SHA256 != ([32]byte{}) {
+		t.Fatal("Validate populated a hash")
+	}
+}
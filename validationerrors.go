@@ -0,0 +1,178 @@
+package mdocx
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError is one problem found by Validate with WithAllErrors.
+type ValidationError struct {
+	// Path is the container path of the Markdown file or media item concerned,
+	// if it has one.
+	Path string
+	// Field names the offending field, such as "Markdown.Files[2].Path" or
+	// "Media.Items[0].SHA256". It is empty for problems with the whole document.
+	Field string
+	// Reason describes the problem.
+	Reason string
+	// Code identifies the kind of problem independently of Reason, for
+	// translation. See LocalizeError.
+	Code ErrorCode
+	// Value is the offending value, such as a duplicate media ID, or for
+	// CodeLimitExceeded the name of the exceeded Limits field.
+	Value string
+	// Err is ErrValidation, or ErrLimitExceeded for a size or count limit.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	b.WriteString(": ")
+	if e.Field != "" {
+		b.WriteString(e.Field)
+		if e.Path != "" {
+			fmt.Fprintf(&b, " (%s)", e.Path)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Reason)
+	return b.String()
+}
+
+// Unwrap returns e.Err.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors lists every problem found by Validate with WithAllErrors,
+// in document order. errors.Is reports whether any of them matches.
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	switch len(errs) {
+	case 0:
+		return "mdocx: no validation errors"
+	case 1:
+		return errs[0].Error()
+	default:
+		return fmt.Sprintf("%s (and %d more)", errs[0], len(errs)-1)
+	}
+}
+
+// Unwrap returns the individual errors.
+func (errs ValidationErrors) Unwrap() []error {
+	out := make([]error, len(errs))
+	for i, e := range errs {
+		out[i] = e
+	}
+	return out
+}
+
+// WithAllErrors makes Validate check the whole document instead of stopping at
+// the first problem, and return every problem it finds as ValidationErrors.
+// Optional invariants are only checked, as usual, if there is no other problem;
+// every violation of them is then reported, so that WithValidateChecks
+// (CheckCrossRefs) lists all broken references and orphaned media at once.
+func WithAllErrors() ValidateOption {
+	return func(c *validateConfig) { c.allErrors = true }
+}
+
+// newValidationError returns a *ValidationError wrapping ErrLimitExceeded for
+// CodeLimitExceeded and ErrValidation otherwise.
+func newValidationError(code ErrorCode, path, field, value, format string, args ...any) *ValidationError {
+	kind := ErrValidation
+	if code == CodeLimitExceeded {
+		kind = ErrLimitExceeded
+	}
+	return &ValidationError{Path: path, Field: field, Reason: fmt.Sprintf(format, args...), Code: code, Value: value, Err: kind}
+}
+
+// collectValidationErrors applies the checks of validateDocument to doc and
+// returns every problem instead of the first.
+func collectValidationErrors(doc *Document, limits Limits, verifyHashes bool) ValidationErrors {
+	var errs ValidationErrors
+	add := func(code ErrorCode, path, field, value, format string, args ...any) {
+		errs = append(errs, newValidationError(code, path, field, value, format, args...))
+	}
+	if doc == nil {
+		add(CodeNilDocument, "", "", "", "document is nil")
+		return errs
+	}
+
+	md := doc.Markdown
+	if md.BundleVersion != VersionV1 {
+		add(CodeBundleVersion, "", "Markdown.BundleVersion", "", "must be %d", VersionV1)
+	}
+	if len(md.Files) == 0 {
+		add(CodeNoMarkdownFiles, "", "Markdown.Files", "", "must not be empty")
+	}
+	if len(md.Files) > limits.MaxMarkdownFiles {
+		add(CodeLimitExceeded, "", "Markdown.Files", "MaxMarkdownFiles", "too many markdown files (%d > %d)", len(md.Files), limits.MaxMarkdownFiles)
+	}
+	if md.RootPath != "" {
+		if err := validateContainerPath(md.RootPath); err != nil {
+			add(CodeInvalidPath, md.RootPath, "Markdown.RootPath", md.RootPath, "%v", err)
+		} else if limit, reason := limits.pathLimit(md.RootPath); limit != "" {
+			add(CodeLimitExceeded, md.RootPath, "Markdown.RootPath", limit, "%s", reason)
+		}
+	}
+	seen := make(map[string]struct{}, max(len(md.Files), len(doc.Media.Items)))
+	for i, f := range md.Files {
+		field := fmt.Sprintf("Markdown.Files[%d]", i)
+		if err := validateContainerPath(f.Path); err != nil {
+			add(CodeInvalidPath, f.Path, field+".Path", f.Path, "%v", err)
+		} else if limit, reason := limits.pathLimit(f.Path); limit != "" {
+			add(CodeLimitExceeded, f.Path, field+".Path", limit, "%s", reason)
+		} else if _, ok := seen[f.Path]; ok {
+			add(CodeDuplicatePath, f.Path, field+".Path", f.Path, "duplicate markdown path")
+		}
+		seen[f.Path] = struct{}{}
+		if !utf8.Valid(f.Content) {
+			add(CodeInvalidUTF8, f.Path, field+".Content", "", "not valid UTF-8")
+		}
+		if uint64(len(f.Content)) > limits.MaxSingleMarkdownFileSize {
+			add(CodeLimitExceeded, f.Path, field+".Content", "MaxSingleMarkdownFileSize", "too large (%d bytes)", len(f.Content))
+		}
+	}
+
+	media := doc.Media
+	if doc.NoMedia {
+		if len(media.Items) > 0 {
+			add(CodeMediaWithNoMedia, "", "Media.Items", "", "must be empty when NoMedia is set")
+		}
+		return errs
+	}
+	if media.BundleVersion != VersionV1 {
+		add(CodeBundleVersion, "", "Media.BundleVersion", "", "must be %d", VersionV1)
+	}
+	if len(media.Items) > limits.MaxMediaItems {
+		add(CodeLimitExceeded, "", "Media.Items", "MaxMediaItems", "too many media items (%d > %d)", len(media.Items), limits.MaxMediaItems)
+	}
+	clear(seen)
+	for i := range media.Items {
+		it := &media.Items[i]
+		field := fmt.Sprintf("Media.Items[%d]", i)
+		if strings.TrimSpace(it.ID) == "" {
+			add(CodeEmptyID, it.Path, field+".ID", "", "empty ID")
+		} else if _, ok := seen[it.ID]; ok {
+			add(CodeDuplicateID, it.Path, field+".ID", it.ID, "duplicate media ID %q", it.ID)
+		}
+		seen[it.ID] = struct{}{}
+		if it.Path != "" {
+			if err := validateContainerPath(it.Path); err != nil {
+				add(CodeInvalidPath, it.Path, field+".Path", it.Path, "%v", err)
+			} else if limit, reason := limits.pathLimit(it.Path); limit != "" {
+				add(CodeLimitExceeded, it.Path, field+".Path", limit, "%s", reason)
+			}
+		}
+		if uint64(len(it.Data)) > limits.MaxSingleMediaSize {
+			add(CodeLimitExceeded, it.Path, field+".Data", "MaxSingleMediaSize", "too large (%d bytes)", len(it.Data))
+		}
+		if !mediaHashOK(it, verifyHashes) {
+			add(CodeHashMismatch, it.Path, field+".SHA256", "", "does not match Data")
+		}
+	}
+	return errs
+}
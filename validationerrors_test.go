@@ -0,0 +1,63 @@
+package mdocx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateAllErrors(t *testing.T) {
+	if err := Validate(sampleDoc(), WithAllErrors()); err != nil {
+		t.Fatal(err)
+	}
+
+	d := sampleDoc()
+	d.Markdown.Files = append(d.Markdown.Files,
+		MarkdownFile{Path: "docs/index.md", Content: []byte("dup")},
+		MarkdownFile{Path: "../escape.md", Content: []byte{0xff}},
+	)
+	d.Media.Items = append(d.Media.Items,
+		MediaItem{ID: "logo", Path: "/abs.png", Data: []byte{1}},
+		MediaItem{ID: " ", Data: []byte{2}},
+	)
+	d.Media.Items[0].SHA256[0] ^= 1
+
+	err := Validate(d, WithAllErrors())
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %T %v", err, err)
+	}
+	want := []string{
+		"Markdown.Files[2].Path",
+		"Markdown.Files[3].Path",
+		"Markdown.Files[3].Content",
+		"Media.Items[0].SHA256",
+		"Media.Items[1].ID",
+		"Media.Items[1].Path",
+		"Media.Items[2].ID",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors: %v", len(errs), errs.Unwrap())
+	}
+	for i, f := range want {
+		if errs[i].Field != f {
+			t.Fatalf("errs[%d] = %v, want field %s", i, errs[i], f)
+		}
+	}
+	if errs[0].Path != "docs/index.md" || errs[0].Error() != "mdocx: validation failed: Markdown.Files[2].Path (docs/index.md): duplicate markdown path" {
+		t.Fatalf("errs[0] = %q", errs[0].Error())
+	}
+	if !errors.Is(err, ErrValidation) || errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("errors.Is mismatch")
+	}
+	if err := Validate(d); err == nil || errors.As(err, new(ValidationErrors)) {
+		t.Fatalf("fail-fast Validate = %v", err)
+	}
+
+	err = Validate(sampleDoc(), WithAllErrors(), WithValidateLimits(Limits{MaxSingleMarkdownFileSize: 1}))
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+	if err := Validate(nil, WithAllErrors()); !errors.Is(err, ErrValidation) {
+		t.Fatalf("nil document: %v", err)
+	}
+}
@@ -104,7 +104,7 @@ func validateSectionHeader(sh sectionHeaderV1, expected SectionType) error {
 	}
 	comp := sh.compression()
 	switch comp {
-	case CompNone, CompZIP, CompZSTD, CompLZ4, CompBR:
+	case CompNone, CompZIP, CompZSTD, CompLZ4, CompBR, CompXZ:
 	default:
 		return fmt.Errorf("%w: unknown compression %d", ErrInvalidSection, comp)
 	}
@@ -0,0 +1,192 @@
+package mdocx
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+// Writer writes an MDOCX container whose Markdown files and media items are
+// added one at a time, so that large media can be streamed from disk without
+// holding the document in memory. Media data passed to AddMediaItemFromReader
+// is copied straight into a spool file (see WithSpoolDir) and never buffered
+// whole.
+//
+// Nothing is written to the destination until Close, which assembles and
+// compresses the sections from the spool. Writer applies the same validation
+// as EncodeStream. After any method returns an error the Writer is unusable:
+// later calls, including Close, return that error. Close or Abort must be
+// called to delete the spool files.
+type Writer struct {
+	w   io.Writer
+	st  *streamState
+	err error
+}
+
+// NewWriter returns a Writer that will write a container with the
+// document-level fields in hdr to w. It accepts the same WriteOption values as
+// EncodeStream.
+func NewWriter(w io.Writer, hdr StreamHeader, opts ...WriteOption) (*Writer, error) {
+	st, err := newStreamState(hdr, newWriteConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, st: st}, nil
+}
+
+// AddMarkdownFile adds f to the Markdown bundle.
+func (w *Writer) AddMarkdownFile(f MarkdownFile) error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.fail(w.st.addFile(f))
+}
+
+// AddMediaItem adds it to the Media bundle.
+func (w *Writer) AddMediaItem(it MediaItem) error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.fail(w.st.addItem(it))
+}
+
+// AddMediaItemFromReader adds it to the Media bundle with the data read from r
+// instead of it.Data, which is ignored. size is the exact number of bytes r
+// will provide; if it is negative, r is first copied to a spool file to learn
+// its size. The SHA-256 of the data is computed while copying and, if it.SHA256
+// is non-zero, verified against it (unless disabled with
+// WithVerifyHashesOnWrite).
+func (w *Writer) AddMediaItemFromReader(it MediaItem, r io.Reader, size int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.fail(w.st.addItemFrom(it, r, size))
+}
+
+// Close writes the container to the destination and deletes the spool files.
+// With WithCheckpointDir, the checkpoint is kept if Close fails, so that a new
+// Writer can resume from it and try again.
+func (w *Writer) Close() (err error) {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = fs.ErrClosed
+	defer func() { w.st.close(err) }()
+	out, done := w.st.cfg.outputWriter(context.Background(), w.w)
+	defer func() { done(err) }()
+	return w.st.finish(out)
+}
+
+// Abort deletes the spool files, and any checkpoint, without writing anything. Later calls return
+// fs.ErrClosed. It does nothing if the Writer is already closed.
+func (w *Writer) Abort() {
+	if w.err == fs.ErrClosed {
+		return
+	}
+	w.err = fs.ErrClosed
+	w.st.remove()
+}
+
+// fail records err, if any, as the Writer's sticky error.
+func (w *Writer) fail(err error) error {
+	if err != nil {
+		w.err = err
+		w.st.close(err)
+	}
+	return err
+}
+
+// addItemFrom validates it and spools its encoding with the data read from r.
+// The element is encoded by hand so that the data can be copied without
+// holding it in memory.
+func (st *streamState) addItemFrom(it MediaItem, r io.Reader, size int64) error {
+	it.Data = nil
+	if err := st.checkItem(it, false); err != nil {
+		return err
+	}
+	limit := st.cfg.limits.MaxSingleMediaSize
+	if size < 0 {
+		staged, err := newSpool(st.cfg.spoolDir)
+		if err != nil {
+			return err
+		}
+		defer staged.remove()
+		if _, err := io.Copy(staged, io.LimitReader(r, int64(min(limit, 1<<62))+1)); err != nil {
+			return err
+		}
+		if size = staged.n; uint64(size) <= limit {
+			if r, err = staged.rewind(); err != nil {
+				return err
+			}
+		}
+	}
+	if uint64(size) > limit {
+		return exceeds("MaxSingleMediaSize", "media item %q too large", it.ID)
+	}
+
+	var b gobBuf
+	last := -1
+	field := func(i int) {
+		b.uint(uint64(i - last))
+		last = i
+	}
+	for i, s := range []string{it.ID, it.Path, it.MIMEType} {
+		if s != "" {
+			field(i)
+			b.string(s)
+		}
+	}
+	if size > 0 {
+		field(3) // Data
+		b.uint(uint64(size))
+	}
+	if _, err := st.mediaSpool.Write(b); err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(st.mediaSpool, h), io.LimitReader(r, size))
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("media item %q: %w", it.ID, io.ErrUnexpectedEOF)
+	}
+	var sum [32]byte
+	h.Sum(sum[:0])
+	if it.SHA256 == ([32]byte{}) {
+		if st.cfg.autoPopulate {
+			it.SHA256 = sum
+		}
+	} else if st.cfg.verifyHashes && subtle.ConstantTimeCompare(sum[:], it.SHA256[:]) != 1 {
+		return fmt.Errorf("%w: media item %q SHA256 mismatch", ErrValidation, it.ID)
+	}
+
+	b = b[:0]
+	field(4) // SHA256 (arrays are always sent)
+	b.uint(32)
+	for _, c := range it.SHA256 {
+		b.uint(uint64(c))
+	}
+	if len(it.Attributes) > 0 {
+		field(5) // Attributes
+		keys := make([]string, 0, len(it.Attributes))
+		for k := range it.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.uint(uint64(len(keys)))
+		for _, k := range keys {
+			b.string(k)
+			b.string(it.Attributes[k])
+		}
+	}
+	b = append(b, 0) // end of struct
+	if err := st.mediaSpool.add(b); err != nil {
+		return err
+	}
+	return st.record(checkpointRecord{ItemID: it.ID, ItemPath: it.Path})
+}
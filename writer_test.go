@@ -0,0 +1,131 @@
+package mdocx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddItemFromMatchesGob(t *testing.T) {
+	items := []MediaItem{
+		{ID: "a", Path: "x/a.bin", MIMEType: "application/octet-stream", Data: bytes.Repeat([]byte{0xff, 1}, 200), Attributes: map[string]string{"alt": "A"}},
+		{ID: "b", Data: []byte{}},
+		{ID: "c", MIMEType: "text/plain", Data: []byte("hi")},
+	}
+	for _, autoPopulate := range []bool{true, false} {
+		for _, it := range items {
+			st, err := newStreamState(StreamHeader{}, newWriteConfig([]WriteOption{WithSpoolDir(t.TempDir()), WithAutoPopulateSHA256(autoPopulate)}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer st.remove()
+			size := int64(len(it.Data))
+			if it.ID == "c" {
+				size = -1
+			}
+			if err := st.addItemFrom(it, bytes.NewReader(it.Data), size); err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := st.mediaSpool.copyTo(&got); err != nil {
+				t.Fatal(err)
+			}
+			want := it
+			if autoPopulate {
+				want.SHA256 = it.computedSHA256()
+			}
+			wantBytes, err := newGobElementEncoder[MediaItem]().encode(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), wantBytes) {
+				t.Fatalf("item %q (autoPopulate=%v): encoding differs\n got %x\nwant %x", it.ID, autoPopulate, got.Bytes(), wantBytes)
+			}
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	doc := sampleDoc()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, StreamHeader{Metadata: doc.Metadata, RootPath: doc.Markdown.RootPath}, WithSpoolDir(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range doc.Markdown.Files {
+		if err := w.AddMarkdownFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logo := doc.Media.Items[0]
+	data := logo.Data
+	logo.Data = nil
+	if err := w.AddMediaItemFromReader(logo, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddMediaItem(MediaItem{ID: "notes", MIMEType: "text/plain", Data: []byte("n")}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatal("Writer wrote before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("second Close: %v", err)
+	}
+	dec, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dec.Media.Items) != 2 || !reflect.DeepEqual(dec.Media.Items[0].Data, data) || dec.Media.Items[0].SHA256 != sampleDoc().Media.Items[0].computedSHA256() {
+		t.Fatalf("unexpected media: %+v", dec.Media.Items)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	newW := func(opts ...WriteOption) *Writer {
+		w, err := NewWriter(io.Discard, StreamHeader{}, append(opts, WithSpoolDir(t.TempDir()))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	w := newW()
+	err := w.AddMediaItemFromReader(MediaItem{ID: "x"}, strings.NewReader("abc"), 5)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("short reader: %v", err)
+	}
+	if err2 := w.AddMarkdownFile(MarkdownFile{Path: "a.md"}); err2 != err {
+		t.Fatalf("error is not sticky: %v", err2)
+	}
+	if err2 := w.Close(); err2 != err {
+		t.Fatalf("Close: %v", err2)
+	}
+
+	w = newW()
+	if err := w.AddMediaItemFromReader(MediaItem{ID: "x", SHA256: [32]byte{1}}, strings.NewReader("abc"), 3); !errors.Is(err, ErrValidation) {
+		t.Fatalf("hash mismatch: %v", err)
+	}
+
+	w = newW(WithWriteLimits(Limits{MaxSingleMediaSize: 2}))
+	if err := w.AddMediaItemFromReader(MediaItem{ID: "x"}, strings.NewReader("abc"), -1); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("too large: %v", err)
+	}
+
+	w = newW()
+	if err := w.Close(); !errors.Is(err, ErrValidation) {
+		t.Fatalf("empty: %v", err)
+	}
+	w = newW()
+	w.Abort()
+	if err := w.AddMarkdownFile(MarkdownFile{Path: "a.md"}); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("after Abort: %v", err)
+	}
+}